@@ -11,6 +11,7 @@ import (
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
 	fakecomp "github.com/cloudfoundry/bosh-agent/agent/compiler/fakes"
+	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
 )
 
@@ -39,19 +40,27 @@ func getCompileActionArguments() (blobID string, multiDigest boshcrypto.Multiple
 var _ = Describe("CompilePackageAction", func() {
 	var (
 		compiler *fakecomp.FakeCompiler
+		progress *faketask.FakeProgressReporter
+		cancelCh chan struct{}
 		action   CompilePackageAction
 	)
 
 	BeforeEach(func() {
 		compiler = fakecomp.NewFakeCompiler()
+		progress = &faketask.FakeProgressReporter{}
+		cancelCh = make(chan struct{})
 		action = NewCompilePackage(compiler)
 	})
 
+	runWithCompileActionArguments := func() (map[string]interface{}, error) {
+		blobID, multiDigest, name, version, deps := getCompileActionArguments()
+		return action.Run(progress, cancelCh, blobID, multiDigest, name, version, deps)
+	}
+
 	AssertActionIsAsynchronous(action)
 	AssertActionIsNotPersistent(action)
 	AssertActionIsLoggable(action)
 
-	AssertActionIsNotCancelable(action)
 	AssertActionIsNotResumable(action)
 
 	Describe("Run", func() {
@@ -111,7 +120,7 @@ var _ = Describe("CompilePackageAction", func() {
 				},
 			}
 
-			value, err := action.Run(getCompileActionArguments())
+			value, err := runWithCompileActionArguments()
 			Expect(err).ToNot(HaveOccurred())
 			Expect(value).To(Equal(expectedValue))
 
@@ -119,14 +128,33 @@ var _ = Describe("CompilePackageAction", func() {
 
 			// Using ConsistOf since package dependencies are specified as a hash (no order)
 			Expect(compiler.CompileDeps).To(ConsistOf(expectedDeps))
+
+			Expect(compiler.CompileProgress).To(Equal(progress))
 		})
 
 		It("returns error when compile fails", func() {
 			compiler.CompileErr = errors.New("fake-compile-error")
 
-			_, err := action.Run(getCompileActionArguments())
+			_, err := runWithCompileActionArguments()
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(ContainSubstring("fake-compile-error"))
 		})
+
+		It("passes cancel signal of its task to compiler", func() {
+			compiler.CompileDigest = boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "some checksum")
+
+			_, err := runWithCompileActionArguments()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(compiler.CompileCancelCh).ToNot(BeClosed())
+			close(cancelCh)
+			Expect(compiler.CompileCancelCh).To(BeClosed())
+		})
+	})
+
+	Describe("Cancel", func() {
+		It("allows task to be cancelled", func() {
+			Expect(action.Cancel()).ToNot(HaveOccurred())
+		})
 	})
 })
@@ -1,6 +1,8 @@
 package action
 
 import (
+	"code.cloudfoundry.org/clock"
+
 	boshappl "github.com/cloudfoundry/bosh-agent/agent/applier"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshcomp "github.com/cloudfoundry/bosh-agent/agent/compiler"
@@ -31,6 +33,7 @@ func NewFactory(
 	jobSupervisor boshjobsuper.JobSupervisor,
 	specService boshas.V1Service,
 	jobScriptProvider boshscript.JobScriptProvider,
+	timeService clock.Clock,
 	logger boshlog.Logger,
 ) (factory Factory) {
 	compressor := platform.GetCompressor()
@@ -38,6 +41,7 @@ func NewFactory(
 	dirProvider := platform.GetDirProvider()
 	vitalsService := platform.GetVitalsService()
 	certManager := platform.GetCertManager()
+	diskFreezer := NewDiskFreezer(platform, jobScriptProvider, specService, timeService, logger)
 
 	factory = concreteFactory{
 		availableActions: map[string]Action{
@@ -48,6 +52,7 @@ func NewFactory(
 			// Task management
 			"get_task":    NewGetTask(taskService),
 			"cancel_task": NewCancelTask(taskService),
+			"list_tasks":  NewListTasks(taskService),
 
 			// VM admin
 			"ssh":             NewSSH(settingsService, platform, dirProvider, logger),
@@ -60,8 +65,8 @@ func NewFactory(
 			"start":      NewStart(jobSupervisor, applier, specService),
 			"stop":       NewStop(jobSupervisor),
 			"drain":      NewDrain(notifier, specService, jobScriptProvider, jobSupervisor, logger),
-			"get_state":  NewGetState(settingsService, specService, jobSupervisor, vitalsService),
-			"run_errand": NewRunErrand(specService, dirProvider.JobsDir(), platform.GetRunner(), logger),
+			"get_state":  NewGetState(settingsService, specService, jobSupervisor, vitalsService, platform.GetFilesystemCheckHistory()),
+			"run_errand": NewRunErrand(specService, dirProvider.JobsDir(), platform.GetRunner(), platform.GetFs(), compressor, blobstore, logger),
 			"run_script": NewRunScript(jobScriptProvider, specService, logger),
 
 			// Compilation
@@ -72,9 +77,12 @@ func NewFactory(
 			"upload_blob": NewUploadBlobAction(blobManager),
 
 			// Disk management
+			"freeze_disk":  NewFreezeDisk(settingsService, platform, diskFreezer, dirProvider),
 			"list_disk":    NewListDisk(settingsService, platform, logger),
 			"migrate_disk": NewMigrateDisk(platform, dirProvider),
 			"mount_disk":   NewMountDisk(settingsService, platform, dirProvider, logger),
+			"resize_disk":  NewResizeDisk(settingsService, platform, dirProvider),
+			"thaw_disk":    NewThawDisk(diskFreezer),
 			"unmount_disk": NewUnmountDisk(settingsService, platform),
 
 			// ARP cache management
@@ -1,6 +1,9 @@
 package action_test
 
 import (
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -37,6 +40,7 @@ var _ = Describe("concreteFactory", func() {
 		jobSupervisor     *fakejobsuper.FakeJobSupervisor
 		specService       *fakeas.FakeV1Service
 		jobScriptProvider boshscript.JobScriptProvider
+		timeService       *fakeclock.FakeClock
 		factory           Factory
 		logger            boshlog.Logger
 	)
@@ -53,6 +57,7 @@ var _ = Describe("concreteFactory", func() {
 		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 		specService = fakeas.NewFakeV1Service()
 		jobScriptProvider = &fakescript.FakeJobScriptProvider{}
+		timeService = fakeclock.NewFakeClock(time.Now())
 		logger = boshlog.NewLogger(boshlog.LevelNone)
 
 		factory = NewFactory(
@@ -67,6 +72,7 @@ var _ = Describe("concreteFactory", func() {
 			jobSupervisor,
 			specService,
 			jobScriptProvider,
+			timeService,
 			logger,
 		)
 	})
@@ -93,7 +99,7 @@ var _ = Describe("concreteFactory", func() {
 	It("fetch_logs", func() {
 		action, err := factory.Create("fetch_logs")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewFetchLogs(platform.GetCompressor(), platform.GetCopier(), blobstore, platform.GetDirProvider())))
+		Expect(action).To(BeAssignableToTypeOf(FetchLogsAction{}))
 	})
 
 	It("get_task", func() {
@@ -108,10 +114,16 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewCancelTask(taskService)))
 	})
 
+	It("list_tasks", func() {
+		action, err := factory.Create("list_tasks")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewListTasks(taskService)))
+	})
+
 	It("get_state", func() {
 		action, err := factory.Create("get_state")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewGetState(settingsService, specService, jobSupervisor, platform.GetVitalsService())))
+		Expect(action).To(Equal(NewGetState(settingsService, specService, jobSupervisor, platform.GetVitalsService(), platform.GetFilesystemCheckHistory())))
 	})
 
 	It("list_disk", func() {
@@ -132,6 +144,26 @@ var _ = Describe("concreteFactory", func() {
 		Expect(action).To(Equal(NewMountDisk(settingsService, platform, platform.GetDirProvider(), logger)))
 	})
 
+	It("freeze_disk", func() {
+		action, err := factory.Create("freeze_disk")
+		Expect(err).ToNot(HaveOccurred())
+		diskFreezer := NewDiskFreezer(platform, jobScriptProvider, specService, timeService, logger)
+		Expect(action).To(Equal(NewFreezeDisk(settingsService, platform, diskFreezer, platform.GetDirProvider())))
+	})
+
+	It("thaw_disk", func() {
+		action, err := factory.Create("thaw_disk")
+		Expect(err).ToNot(HaveOccurred())
+		diskFreezer := NewDiskFreezer(platform, jobScriptProvider, specService, timeService, logger)
+		Expect(action).To(Equal(NewThawDisk(diskFreezer)))
+	})
+
+	It("resize_disk", func() {
+		action, err := factory.Create("resize_disk")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action).To(Equal(NewResizeDisk(settingsService, platform, platform.GetDirProvider())))
+	})
+
 	It("ping", func() {
 		action, err := factory.Create("ping")
 		Expect(err).ToNot(HaveOccurred())
@@ -189,7 +221,7 @@ var _ = Describe("concreteFactory", func() {
 	It("compile_package", func() {
 		action, err := factory.Create("compile_package")
 		Expect(err).ToNot(HaveOccurred())
-		Expect(action).To(Equal(NewCompilePackage(compiler)))
+		Expect(action).To(BeAssignableToTypeOf(CompilePackageAction{}))
 	})
 
 	It("run_errand", func() {
@@ -0,0 +1,263 @@
+package action
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	DefaultDiskFreezeTimeout = 60 * time.Second
+	MaxDiskFreezeTimeout     = 10 * time.Minute
+
+	preSnapshotScriptsTimeout  = 5 * time.Minute
+	postSnapshotScriptsTimeout = 5 * time.Minute
+	diskFreezeCommandTimeout   = time.Minute
+
+	preSnapshotScriptName  = "pre-snapshot"
+	postSnapshotScriptName = "post-snapshot"
+)
+
+// DiskFreezer is shared by freeze_disk and thaw_disk actions
+// so that disk is automatically thawed if thaw_disk never arrives
+type DiskFreezer struct {
+	platform       boshplatform.Platform
+	scriptProvider boshscript.JobScriptProvider
+	specService    boshas.V1Service
+	timeService    clock.Clock
+
+	frozenLock sync.Mutex
+	frozen     *frozenDisk
+
+	logTag string
+	logger boshlog.Logger
+}
+
+type frozenDisk struct {
+	mountPoint string
+	thawed     chan struct{}
+}
+
+func NewDiskFreezer(
+	platform boshplatform.Platform,
+	scriptProvider boshscript.JobScriptProvider,
+	specService boshas.V1Service,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) *DiskFreezer {
+	return &DiskFreezer{
+		platform:       platform,
+		scriptProvider: scriptProvider,
+		specService:    specService,
+		timeService:    timeService,
+
+		logTag: "DiskFreezer",
+		logger: logger,
+	}
+}
+
+// Freeze runs pre-snapshot job scripts and then freezes filesystem
+// mounted at mountPoint until Thaw is called or timeout expires
+func (f *DiskFreezer) Freeze(mountPoint string, timeout time.Duration) error {
+	f.frozenLock.Lock()
+	defer f.frozenLock.Unlock()
+
+	if f.frozen != nil {
+		return bosherr.Errorf("Persistent disk mounted at '%s' is already frozen", f.frozen.mountPoint)
+	}
+
+	err := f.runPreSnapshotScripts()
+	if err != nil {
+		f.resumeJobs()
+		return bosherr.WrapError(err, "Running pre-snapshot scripts")
+	}
+
+	err = f.freeze(mountPoint)
+	if err != nil {
+		f.resumeJobs()
+		return bosherr.WrapError(err, "Freezing persistent disk")
+	}
+
+	frozen := &frozenDisk{mountPoint: mountPoint, thawed: make(chan struct{})}
+	f.frozen = frozen
+
+	go f.autoThaw(frozen, f.timeService.NewTimer(timeout))
+
+	return nil
+}
+
+// Thaw unfreezes filesystem and runs post-snapshot job scripts.
+// It succeeds without doing anything if nothing is frozen.
+func (f *DiskFreezer) Thaw() error {
+	f.frozenLock.Lock()
+
+	if f.frozen == nil {
+		f.frozenLock.Unlock()
+		f.logger.Debug(f.logTag, "Persistent disk is not frozen, skipping thaw")
+		return nil
+	}
+
+	err := f.thaw()
+	f.frozenLock.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return f.runPostSnapshotScripts()
+}
+
+func (f *DiskFreezer) autoThaw(frozen *frozenDisk, timer clock.Timer) {
+	select {
+	case <-frozen.thawed:
+		timer.Stop()
+
+	case <-timer.C():
+		f.frozenLock.Lock()
+
+		if f.frozen != frozen {
+			f.frozenLock.Unlock()
+			return
+		}
+
+		f.logger.Warn(f.logTag, "Persistent disk mounted at '%s' was not thawed in time, thawing automatically", frozen.mountPoint)
+
+		err := f.thaw()
+		f.frozenLock.Unlock()
+
+		if err == nil {
+			err = f.runPostSnapshotScripts()
+		}
+
+		if err != nil {
+			f.logger.Error(f.logTag, "Failed to automatically thaw persistent disk: %s", err.Error())
+		}
+	}
+}
+
+// runPreSnapshotScripts gives up on jobs that take too long to quiesce
+// since snapshots should not wait for them indefinitely
+func (f *DiskFreezer) runPreSnapshotScripts() error {
+	return f.runScripts(preSnapshotScriptName, preSnapshotScriptsTimeout)
+}
+
+// runPostSnapshotScripts is called without frozenLock held
+// so that hanging jobs do not keep disk from being frozen again
+func (f *DiskFreezer) runPostSnapshotScripts() error {
+	err := f.runScripts(postSnapshotScriptName, postSnapshotScriptsTimeout)
+	if err != nil {
+		return bosherr.WrapError(err, "Running post-snapshot scripts")
+	}
+
+	return nil
+}
+
+// freeze always leaves filesystem thawed when it fails
+// since fsfreeze may have frozen it before failing or timing out
+func (f *DiskFreezer) freeze(mountPoint string) error {
+	resultCh := make(chan error, 1)
+	go func() { resultCh <- f.platform.FreezePersistentDisk(mountPoint) }()
+
+	timer := f.timeService.NewTimer(diskFreezeCommandTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-resultCh:
+		if err != nil {
+			f.unfreeze(mountPoint)
+			return err
+		}
+
+		return nil
+
+	case <-timer.C():
+		go func() {
+			<-resultCh
+			f.unfreeze(mountPoint)
+		}()
+
+		return bosherr.Errorf("Timed out freezing filesystem at '%s' after %s", mountPoint, diskFreezeCommandTimeout)
+	}
+}
+
+func (f *DiskFreezer) unfreeze(mountPoint string) {
+	// Fails when filesystem did not get frozen in the first place
+	err := f.platform.ThawPersistentDisk(mountPoint)
+	if err != nil {
+		f.logger.Debug(f.logTag, "Failed to unfreeze persistent disk mounted at '%s': %s", mountPoint, err.Error())
+	}
+}
+
+// thaw must be called with frozenLock held;
+// post-snapshot scripts are left to the caller
+func (f *DiskFreezer) thaw() error {
+	frozen := f.frozen
+
+	err := f.platform.ThawPersistentDisk(frozen.mountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Thawing persistent disk")
+	}
+
+	f.frozen = nil
+	close(frozen.thawed)
+
+	return nil
+}
+
+// resumeJobs gives jobs that already quiesced a chance
+// to continue when disk could not be frozen
+func (f *DiskFreezer) resumeJobs() {
+	err := f.runScripts(postSnapshotScriptName, postSnapshotScriptsTimeout)
+	if err != nil {
+		f.logger.Error(f.logTag, "Failed to run post-snapshot scripts: %s", err.Error())
+	}
+}
+
+func (f *DiskFreezer) runScripts(scriptName string, timeout time.Duration) error {
+	script, err := f.newScript(scriptName)
+	if err != nil {
+		return err
+	}
+
+	resultCh := make(chan error, 1)
+	go func() { resultCh <- script.Run() }()
+
+	timer := f.timeService.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case err = <-resultCh:
+		return err
+
+	case <-timer.C():
+		err = script.Cancel()
+		if err != nil {
+			f.logger.Warn(f.logTag, "Failed to cancel %s scripts: %s", scriptName, err.Error())
+		}
+
+		return bosherr.Errorf("Timed out after %s", timeout)
+	}
+}
+
+func (f *DiskFreezer) newScript(scriptName string) (boshscript.CancellableScript, error) {
+	currentSpec, err := f.specService.Get()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting current spec")
+	}
+
+	var scripts []boshscript.Script
+
+	for _, job := range currentSpec.Jobs() {
+		script := f.scriptProvider.NewScript(job.BundleName(), scriptName)
+		scripts = append(scripts, script)
+	}
+
+	return f.scriptProvider.NewParallelScript(scriptName, scripts), nil
+}
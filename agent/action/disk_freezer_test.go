@@ -0,0 +1,296 @@
+package action_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	"github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
+	fakeapplyspec "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	boshscript "github.com/cloudfoundry/bosh-agent/agent/script"
+	fakescript "github.com/cloudfoundry/bosh-agent/agent/script/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("DiskFreezer", func() {
+	var (
+		platform              *fakeplatform.FakePlatform
+		fakeJobScriptProvider *fakescript.FakeJobScriptProvider
+		specService           *fakeapplyspec.FakeV1Service
+		timeService           *fakeclock.FakeClock
+		preSnapshotScript     *fakescript.FakeCancellableScript
+		postSnapshotScript    *fakescript.FakeCancellableScript
+		diskFreezer           *DiskFreezer
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		fakeJobScriptProvider = &fakescript.FakeJobScriptProvider{}
+		specService = fakeapplyspec.NewFakeV1Service()
+		specService.Spec.RenderedTemplatesArchiveSpec = &applyspec.RenderedTemplatesArchiveSpec{}
+		specService.Spec.JobSpec.JobTemplateSpecs = []applyspec.JobTemplateSpec{{Name: "fake-job-1"}, {Name: "fake-job-2"}}
+		timeService = fakeclock.NewFakeClock(time.Now())
+
+		preSnapshotScript = &fakescript.FakeCancellableScript{}
+		postSnapshotScript = &fakescript.FakeCancellableScript{}
+
+		fakeJobScriptProvider.NewScriptStub = func(jobName, scriptName string) boshscript.Script {
+			script := &fakescript.FakeScript{}
+			script.TagReturns(jobName)
+			return script
+		}
+
+		fakeJobScriptProvider.NewParallelScriptStub = func(scriptName string, scripts []boshscript.Script) boshscript.CancellableScript {
+			switch scriptName {
+			case "pre-snapshot":
+				return preSnapshotScript
+			case "post-snapshot":
+				return postSnapshotScript
+			default:
+				panic("Non-matching script created")
+			}
+		}
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		diskFreezer = NewDiskFreezer(platform, fakeJobScriptProvider, specService, timeService, logger)
+	})
+
+	Describe("Freeze", func() {
+		It("runs pre-snapshot scripts for all jobs and then freezes filesystem", func() {
+			err := diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeJobScriptProvider.NewScriptCallCount()).To(Equal(2))
+			jobName, scriptName := fakeJobScriptProvider.NewScriptArgsForCall(0)
+			Expect(jobName).To(Equal("fake-job-1"))
+			Expect(scriptName).To(Equal("pre-snapshot"))
+			jobName, scriptName = fakeJobScriptProvider.NewScriptArgsForCall(1)
+			Expect(jobName).To(Equal("fake-job-2"))
+			Expect(scriptName).To(Equal("pre-snapshot"))
+
+			Expect(preSnapshotScript.RunCallCount()).To(Equal(1))
+			Expect(postSnapshotScript.RunCallCount()).To(Equal(0))
+			Expect(platform.FreezePersistentDiskMountPoints).To(Equal([]string{"/fake-store"}))
+		})
+
+		It("returns error when disk is already frozen", func() {
+			err := diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("already frozen"))
+			Expect(platform.FreezePersistentDiskMountPoints).To(HaveLen(1))
+		})
+
+		It("does not freeze and resumes jobs when pre-snapshot scripts fail", func() {
+			preSnapshotScript.RunReturns(errors.New("fake-pre-snapshot-err"))
+
+			err := diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-pre-snapshot-err"))
+
+			Expect(platform.FreezePersistentDiskMountPoints).To(BeEmpty())
+			Expect(postSnapshotScript.RunCallCount()).To(Equal(1))
+		})
+
+		It("gives up on pre-snapshot scripts that do not finish in time", func() {
+			release := make(chan struct{})
+			defer close(release)
+
+			preSnapshotScript.RunStub = func() error {
+				<-release
+				return nil
+			}
+
+			errCh := make(chan error, 1)
+			go func() { errCh <- diskFreezer.Freeze("/fake-store", time.Minute) }()
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+			timeService.Increment(5 * time.Minute)
+
+			var err error
+			Eventually(errCh).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Running pre-snapshot scripts: Timed out after 5m0s"))
+
+			Expect(preSnapshotScript.CancelCallCount()).To(Equal(1))
+			Expect(platform.FreezePersistentDiskMountPoints).To(BeEmpty())
+			Expect(postSnapshotScript.RunCallCount()).To(Equal(1))
+		})
+
+		It("resumes jobs and unfreezes filesystem when freezing fails", func() {
+			platform.FreezePersistentDiskErr = errors.New("fake-freeze-err")
+
+			err := diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-freeze-err"))
+			Expect(postSnapshotScript.RunCallCount()).To(Equal(1))
+			Expect(platform.ThawPersistentDiskMountPoints).To(Equal([]string{"/fake-store"}))
+
+			platform.FreezePersistentDiskErr = nil
+
+			err = diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("gives up on freezing that does not finish in time and unfreezes filesystem once it does", func() {
+			freezing := make(chan struct{})
+			release := make(chan struct{})
+			unfrozen := make(chan struct{})
+
+			platform.FreezePersistentDiskCallBack = func() {
+				close(freezing)
+				<-release
+			}
+			platform.ThawPersistentDiskCallBack = func() { close(unfrozen) }
+
+			errCh := make(chan error, 1)
+			go func() { errCh <- diskFreezer.Freeze("/fake-store", time.Minute) }()
+
+			Eventually(freezing).Should(BeClosed())
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+			timeService.Increment(time.Minute)
+
+			var err error
+			Eventually(errCh).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out freezing filesystem at '/fake-store' after 1m0s"))
+			Expect(postSnapshotScript.RunCallCount()).To(Equal(1))
+
+			close(release)
+
+			Eventually(unfrozen).Should(BeClosed())
+			Expect(platform.ThawPersistentDiskMountPoints).To(Equal([]string{"/fake-store"}))
+		})
+
+		It("thaws automatically when timeout expires", func() {
+			err := diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.WaitForWatcherAndIncrement(time.Minute)
+
+			Eventually(postSnapshotScript.RunCallCount).Should(Equal(1))
+			Expect(platform.ThawPersistentDiskMountPoints).To(Equal([]string{"/fake-store"}))
+
+			err = diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).ToNot(HaveOccurred())
+		})
+	})
+
+	Describe("Thaw", func() {
+		It("does nothing when disk is not frozen", func() {
+			err := diskFreezer.Thaw()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.ThawPersistentDiskMountPoints).To(BeEmpty())
+			Expect(fakeJobScriptProvider.NewParallelScriptCallCount()).To(Equal(0))
+		})
+
+		Context("when disk is frozen", func() {
+			BeforeEach(func() {
+				err := diskFreezer.Freeze("/fake-store", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			It("thaws filesystem and then runs post-snapshot scripts", func() {
+				err := diskFreezer.Thaw()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(platform.ThawPersistentDiskMountPoints).To(Equal([]string{"/fake-store"}))
+				Expect(postSnapshotScript.RunCallCount()).To(Equal(1))
+			})
+
+			It("does not thaw again when timeout expires", func() {
+				err := diskFreezer.Thaw()
+				Expect(err).ToNot(HaveOccurred())
+
+				timeService.Increment(time.Minute)
+
+				Consistently(postSnapshotScript.RunCallCount).Should(Equal(1))
+				Expect(platform.ThawPersistentDiskMountPoints).To(HaveLen(1))
+			})
+
+			It("keeps disk frozen when thawing fails so that it can be retried", func() {
+				platform.ThawPersistentDiskErr = errors.New("fake-thaw-err")
+
+				err := diskFreezer.Thaw()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-thaw-err"))
+				Expect(postSnapshotScript.RunCallCount()).To(Equal(0))
+
+				platform.ThawPersistentDiskErr = nil
+
+				err = diskFreezer.Thaw()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(platform.ThawPersistentDiskMountPoints).To(HaveLen(2))
+				Expect(postSnapshotScript.RunCallCount()).To(Equal(1))
+			})
+
+			It("gives up on post-snapshot scripts that do not finish in time", func() {
+				release := make(chan struct{})
+				defer close(release)
+
+				postSnapshotScript.RunStub = func() error {
+					<-release
+					return nil
+				}
+
+				errCh := make(chan error, 1)
+				go func() { errCh <- diskFreezer.Thaw() }()
+
+				Eventually(postSnapshotScript.RunCallCount).Should(Equal(1))
+
+				// Timeout may start a bit after scripts started running
+				var err error
+				Eventually(func() bool {
+					timeService.Increment(5 * time.Minute)
+
+					select {
+					case err = <-errCh:
+						return true
+					default:
+						return false
+					}
+				}).Should(BeTrue())
+
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Running post-snapshot scripts: Timed out after 5m0s"))
+				Expect(postSnapshotScript.CancelCallCount()).To(Equal(1))
+			})
+
+			It("allows freezing again while post-snapshot scripts are still running", func() {
+				release := make(chan struct{})
+
+				postSnapshotScript.RunStub = func() error {
+					<-release
+					return nil
+				}
+
+				errCh := make(chan error, 1)
+				go func() { errCh <- diskFreezer.Thaw() }()
+
+				Eventually(postSnapshotScript.RunCallCount).Should(Equal(1))
+
+				err := diskFreezer.Freeze("/fake-store", time.Minute)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(platform.FreezePersistentDiskMountPoints).To(HaveLen(2))
+
+				close(release)
+				Eventually(errCh).Should(Receive(BeNil()))
+			})
+
+			It("returns error when post-snapshot scripts fail", func() {
+				postSnapshotScript.RunReturns(errors.New("fake-post-snapshot-err"))
+
+				err := diskFreezer.Thaw()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-post-snapshot-err"))
+			})
+		})
+	})
+})
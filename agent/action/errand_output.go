@@ -0,0 +1,80 @@
+package action
+
+import (
+	"io"
+	"sync"
+)
+
+// errandOutput keeps the last size bytes written to it in a ring buffer
+// while passing everything through to the full log
+type errandOutput struct {
+	log io.Writer
+
+	buf     []byte
+	written int64
+	lock    sync.Mutex
+}
+
+func newErrandOutput(log io.Writer, size int) *errandOutput {
+	return &errandOutput{log: log, buf: make([]byte, size)}
+}
+
+func (o *errandOutput) Write(p []byte) (int, error) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	// Failing to save full log must not fail the errand itself
+	_, _ = o.log.Write(p)
+
+	size := len(o.buf)
+	chunk := p
+
+	if len(chunk) > size {
+		o.written += int64(len(chunk) - size)
+		chunk = chunk[len(chunk)-size:]
+	}
+
+	for len(chunk) > 0 {
+		n := copy(o.buf[o.written%int64(size):], chunk)
+		o.written += int64(n)
+		chunk = chunk[n:]
+	}
+
+	return len(p), nil
+}
+
+// Tail returns up to last n bytes of output and
+// the number of bytes that were written before them
+func (o *errandOutput) Tail(n int) (string, int64) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+
+	size := int64(len(o.buf))
+
+	if int64(n) > size {
+		n = int(size)
+	}
+
+	if int64(n) > o.written {
+		n = int(o.written)
+	}
+
+	tail := make([]byte, 0, n)
+
+	for offset := o.written - int64(n); offset < o.written; {
+		start := offset % size
+		end := start + o.written - offset
+		if end > size {
+			end = size
+		}
+		tail = append(tail, o.buf[start:end]...)
+		offset += end - start
+	}
+
+	return string(tail), o.written - int64(n)
+}
+
+func (o *errandOutput) String() string {
+	tail, _ := o.Tail(len(o.buf))
+	return tail
+}
@@ -2,12 +2,14 @@ package fakes
 
 import (
 	boshaction "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 )
 
 type FakeRunner struct {
 	RunAction          boshaction.Action
 	RunPayload         []byte
 	RunProtocolVersion boshaction.ProtocolVersion
+	RunTask            boshtask.Task
 	RunValue           interface{}
 	RunErr             error
 
@@ -17,10 +19,11 @@ type FakeRunner struct {
 	ResumeErr     error
 }
 
-func (runner *FakeRunner) Run(action boshaction.Action, payload []byte, version boshaction.ProtocolVersion) (interface{}, error) {
+func (runner *FakeRunner) Run(action boshaction.Action, payload []byte, version boshaction.ProtocolVersion, task boshtask.Task) (interface{}, error) {
 	runner.RunAction = action
 	runner.RunPayload = payload
 	runner.RunProtocolVersion = version
+	runner.RunTask = task
 	return runner.RunValue, runner.RunErr
 }
 
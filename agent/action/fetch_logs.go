@@ -3,12 +3,19 @@ package action
 import (
 	"errors"
 
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
 )
 
+const (
+	FetchLogsPhaseCopying     = "copying logs"
+	FetchLogsPhaseCompressing = "compressing"
+	FetchLogsPhaseUploading   = "uploading"
+)
+
 type FetchLogsAction struct {
 	compressor  boshcmd.Compressor
 	copier      boshcmd.Copier
@@ -41,7 +48,7 @@ func (a FetchLogsAction) IsLoggable() bool {
 	return true
 }
 
-func (a FetchLogsAction) Run(logType string, filters []string) (value map[string]string, err error) {
+func (a FetchLogsAction) Run(progress boshtask.ProgressReporter, cancelSignal boshtask.CancelSignal, logType string, filters []string) (value map[string]string, err error) {
 	var logsDir string
 
 	switch logType {
@@ -60,38 +67,90 @@ func (a FetchLogsAction) Run(logType string, filters []string) (value map[string
 		return
 	}
 
+	progress.ReportProgress(boshtask.Progress{Phase: FetchLogsPhaseCopying, Percent: 0})
+
 	tmpDir, err := a.copier.FilteredCopyToTemp(logsDir, filters)
 	if err != nil {
-		err = bosherr.WrapError(err, "Copying filtered files to temp directory")
-		return
+		return nil, bosherr.WrapError(err, "Copying filtered files to temp directory")
 	}
 
 	defer a.copier.CleanUp(tmpDir)
 
-	tarball, err := a.compressor.CompressFilesInDir(tmpDir)
+	if boshtask.IsCancelled(cancelSignal) {
+		return nil, boshtask.CancelledErr
+	}
+
+	progress.ReportProgress(boshtask.Progress{Phase: FetchLogsPhaseCompressing, Percent: 30})
+
+	tarball, err := a.compress(tmpDir, cancelSignal)
 	if err != nil {
-		err = bosherr.WrapError(err, "Making logs tarball")
-		return
+		return nil, err
 	}
 
 	defer func() {
 		_ = a.compressor.CleanUp(tarball)
 	}()
 
+	if boshtask.IsCancelled(cancelSignal) {
+		return nil, boshtask.CancelledErr
+	}
+
+	progress.ReportProgress(boshtask.Progress{Phase: FetchLogsPhaseUploading, Percent: 70})
+
+	// Uploading cannot be interrupted so cancellation is checked once it finishes
 	blobID, multidigestSha, err := a.blobstore.Create(tarball)
 	if err != nil {
-		err = bosherr.WrapError(err, "Create file on blobstore")
-		return
+		return nil, bosherr.WrapError(err, "Create file on blobstore")
 	}
 
-	value = map[string]string{"blobstore_id": blobID, "sha1": multidigestSha.String()}
-	return
+	// Nobody is going to download logs once fetching them was cancelled
+	if boshtask.IsCancelled(cancelSignal) {
+		_ = a.blobstore.Delete(blobID)
+		return nil, boshtask.CancelledErr
+	}
+
+	return map[string]string{"blobstore_id": blobID, "sha1": multidigestSha.String()}, nil
 }
 
 func (a FetchLogsAction) Resume() (interface{}, error) {
 	return nil, errors.New("not supported")
 }
 
+// Cancel allows the task to be cancelled; fetching stops once task's cancel signal is closed
 func (a FetchLogsAction) Cancel() error {
-	return errors.New("not supported")
+	return nil
+}
+
+// compress stops waiting for compressor once fetching logs is cancelled;
+// copied logs are removed right away so that tar exits early
+func (a FetchLogsAction) compress(tmpDir string, cancelSignal boshtask.CancelSignal) (string, error) {
+	type compressResult struct {
+		tarball string
+		err     error
+	}
+
+	resultCh := make(chan compressResult, 1)
+
+	go func() {
+		tarball, err := a.compressor.CompressFilesInDir(tmpDir)
+		resultCh <- compressResult{tarball: tarball, err: err}
+	}()
+
+	select {
+	case result := <-resultCh:
+		if result.err != nil {
+			return "", bosherr.WrapError(result.err, "Making logs tarball")
+		}
+		return result.tarball, nil
+
+	case <-cancelSignal:
+		a.copier.CleanUp(tmpDir)
+
+		result := <-resultCh
+		if result.err == nil {
+			_ = a.compressor.CleanUp(result.tarball)
+		}
+
+		return "", boshtask.CancelledErr
+	}
 }
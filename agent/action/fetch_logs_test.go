@@ -1,12 +1,15 @@
 package action_test
 
 import (
+	"errors"
 	"path/filepath"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
 	fakeblobstore "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
@@ -20,14 +23,21 @@ var _ = Describe("FetchLogsAction", func() {
 		copier      *fakecmd.FakeCopier
 		blobstore   *fakeblobstore.FakeDigestBlobstore
 		dirProvider boshdirs.Provider
+		progress    *faketask.FakeProgressReporter
+		cancelCh    chan struct{}
 		action      FetchLogsAction
 	)
 
 	BeforeEach(func() {
 		compressor = fakecmd.NewFakeCompressor()
+		compressor.CompressFilesInDirTarballPath = "/fake-logs.tgz"
 		blobstore = &fakeblobstore.FakeDigestBlobstore{}
 		dirProvider = boshdirs.NewProvider("/fake/dir")
 		copier = fakecmd.NewFakeCopier()
+		copier.FilteredCopyToTempTempDir = "/fake-temp-dir"
+		progress = &faketask.FakeProgressReporter{}
+		cancelCh = make(chan struct{})
+
 		action = NewFetchLogs(compressor, copier, blobstore, dirProvider)
 	})
 
@@ -36,19 +46,16 @@ var _ = Describe("FetchLogsAction", func() {
 	AssertActionIsLoggable(action)
 
 	AssertActionIsNotResumable(action)
-	AssertActionIsNotCancelable(action)
 
 	Describe("Run", func() {
 		testLogs := func(logType string, filters []string, expectedFilters []string) {
-			copier.FilteredCopyToTempTempDir = "/fake-temp-dir"
-			compressor.CompressFilesInDirTarballPath = "logs_test.tar"
 			multidigestSha := boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "sec_dep_sha1"))
 			sha1 := multidigestSha.String()
 			blobstore.CreateStub = func(fileName string) (blobID string, digest boshcrypto.MultipleDigest, err error) {
 				return "my-blob-id", multidigestSha, nil
 			}
 
-			logs, err := action.Run(logType, filters)
+			logs, err := action.Run(progress, cancelCh, logType, filters)
 			Expect(err).ToNot(HaveOccurred())
 
 			var expectedPath string
@@ -62,16 +69,18 @@ var _ = Describe("FetchLogsAction", func() {
 			Expect(copier.FilteredCopyToTempDir).To(boshassert.MatchPath(expectedPath))
 			Expect(copier.FilteredCopyToTempFilters).To(Equal(expectedFilters))
 
-			Expect(copier.FilteredCopyToTempTempDir).To(Equal(compressor.CompressFilesInDirDir))
-			Expect(copier.CleanUpTempDir).To(Equal(compressor.CompressFilesInDirDir))
+			Expect(compressor.CompressFilesInDirDir).To(Equal("/fake-temp-dir"))
+			Expect(copier.CleanUpTempDir).To(Equal("/fake-temp-dir"))
 
-			Expect(compressor.CompressFilesInDirTarballPath).To(Equal(blobstore.CreateArgsForCall(0)))
+			Expect(blobstore.CreateArgsForCall(0)).To(Equal("/fake-logs.tgz"))
 
 			boshassert.MatchesJSONString(GinkgoT(), logs, `{"blobstore_id":"my-blob-id","sha1":"`+sha1+`"}`)
+
+			Expect(progress.Phases()).To(Equal([]string{"copying logs", "compressing", "uploading"}))
 		}
 
 		It("logs errs if given invalid log type", func() {
-			_, err := action.Run("other-logs", []string{})
+			_, err := action.Run(progress, cancelCh, "other-logs", []string{})
 			Expect(err).To(HaveOccurred())
 		})
 
@@ -99,26 +108,83 @@ var _ = Describe("FetchLogsAction", func() {
 			testLogs("job", filters, expectedFilters)
 		})
 
-		It("cleans up compressed package after uploading it to blobstore", func() {
-			var beforeCleanUpTarballPath, afterCleanUpTarballPath string
-
-			compressor.CompressFilesInDirTarballPath = "/fake-compressed-logs.tar"
+		It("cleans up compressed logs after uploading them to blobstore", func() {
+			var tarballCleanedUpDuringUpload bool
 
 			blobstore.CreateStub = func(fileName string) (blobID string, digest boshcrypto.MultipleDigest, err error) {
-				beforeCleanUpTarballPath = compressor.CleanUpTarballPath
-
+				tarballCleanedUpDuringUpload = compressor.CleanUpTarballPath != ""
 				return "my-blob-id", boshcrypto.MultipleDigest{}, nil
 			}
 
-			_, err := action.Run("job", []string{})
+			_, err := action.Run(progress, cancelCh, "job", []string{})
 			Expect(err).ToNot(HaveOccurred())
 
-			// Logs are not cleaned up before blobstore upload
-			Expect(beforeCleanUpTarballPath).To(Equal(""))
+			Expect(tarballCleanedUpDuringUpload).To(BeFalse())
+			Expect(compressor.CleanUpTarballPath).To(Equal("/fake-logs.tgz"))
+		})
+
+		It("returns error and cleans up when compressing logs fails", func() {
+			compressor.CompressFilesInDirErr = errors.New("fake-compress-err")
+
+			_, err := action.Run(progress, cancelCh, "job", []string{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-compress-err"))
+
+			Expect(copier.CleanUpTempDir).To(Equal("/fake-temp-dir"))
+			Expect(blobstore.CreateCallCount()).To(Equal(0))
+		})
+
+		Context("when cancelled before compressing", func() {
+			It("does not compress or upload logs", func() {
+				close(cancelCh)
+
+				_, err := action.Run(progress, cancelCh, "job", []string{})
+				Expect(err).To(Equal(boshtask.CancelledErr))
+
+				Expect(compressor.CompressFilesInDirDir).To(BeEmpty())
+				Expect(blobstore.CreateCallCount()).To(Equal(0))
+				Expect(copier.CleanUpTempDir).To(Equal("/fake-temp-dir"))
+			})
+		})
+
+		Context("when cancelled while compressing", func() {
+			BeforeEach(func() {
+				compressor.CompressFilesInDirCallBack = func() {
+					close(cancelCh)
+				}
+			})
+
+			It("cleans up copied logs and tarball and does not upload logs", func() {
+				_, err := action.Run(progress, cancelCh, "job", []string{})
+				Expect(err).To(Equal(boshtask.CancelledErr))
+
+				Expect(compressor.CleanUpTarballPath).To(Equal("/fake-logs.tgz"))
+				Expect(copier.CleanUpTempDir).To(Equal("/fake-temp-dir"))
+				Expect(blobstore.CreateCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when cancelled while uploading", func() {
+			It("waits for upload to finish, deletes uploaded blob and cleans up", func() {
+				blobstore.CreateStub = func(fileName string) (string, boshcrypto.MultipleDigest, error) {
+					close(cancelCh)
+					return "my-blob-id", boshcrypto.MultipleDigest{}, nil
+				}
+
+				_, err := action.Run(progress, cancelCh, "job", []string{})
+				Expect(err).To(Equal(boshtask.CancelledErr))
+
+				Expect(blobstore.DeleteArgsForCall(0)).To(Equal("my-blob-id"))
+				Expect(compressor.CleanUpTarballPath).To(Equal("/fake-logs.tgz"))
+				Expect(copier.CleanUpTempDir).To(Equal("/fake-temp-dir"))
+				Expect(progress.Phases()).To(Equal([]string{"copying logs", "compressing", "uploading"}))
+			})
+		})
+	})
 
-			// Deleted after it was uploaded
-			afterCleanUpTarballPath = compressor.CleanUpTarballPath
-			Expect(afterCleanUpTarballPath).To(Equal("/fake-compressed-logs.tar"))
+	Describe("Cancel", func() {
+		It("allows task to be cancelled", func() {
+			Expect(action.Cancel()).ToNot(HaveOccurred())
 		})
 	})
 })
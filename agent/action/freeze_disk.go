@@ -0,0 +1,97 @@
+package action
+
+import (
+	"errors"
+	"time"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type FreezeDiskAction struct {
+	settingsService boshsettings.Service
+	platform        boshplatform.Platform
+	diskFreezer     *DiskFreezer
+	dirProvider     boshdirs.Provider
+}
+
+func NewFreezeDisk(
+	settingsService boshsettings.Service,
+	platform boshplatform.Platform,
+	diskFreezer *DiskFreezer,
+	dirProvider boshdirs.Provider,
+) (freezeDisk FreezeDiskAction) {
+	freezeDisk.settingsService = settingsService
+	freezeDisk.platform = platform
+	freezeDisk.diskFreezer = diskFreezer
+	freezeDisk.dirProvider = dirProvider
+	return
+}
+
+func (a FreezeDiskAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return true
+}
+
+func (a FreezeDiskAction) IsPersistent() bool {
+	return false
+}
+
+func (a FreezeDiskAction) IsLoggable() bool {
+	return true
+}
+
+// Run freezes persistent disk for at most timeoutSeconds
+// (capped at MaxDiskFreezeTimeout) before it is thawed automatically
+func (a FreezeDiskAction) Run(diskCid string, timeoutSeconds ...int) (interface{}, error) {
+	err := a.settingsService.LoadSettings()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Refreshing the settings")
+	}
+
+	settings := a.settingsService.GetSettings()
+
+	diskSettings, found := settings.PersistentDiskSettings(diskCid)
+	if !found {
+		return nil, bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCid)
+	}
+
+	mounted, err := a.platform.IsPersistentDiskMounted(diskSettings)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Checking whether persistent disk is mounted")
+	}
+
+	if !mounted {
+		return nil, bosherr.Errorf("Persistent disk '%s' must be mounted to be frozen", diskCid)
+	}
+
+	timeout := DefaultDiskFreezeTimeout
+	if len(timeoutSeconds) > 0 && timeoutSeconds[0] > 0 {
+		timeout = time.Duration(timeoutSeconds[0]) * time.Second
+	}
+
+	if timeout > MaxDiskFreezeTimeout {
+		timeout = MaxDiskFreezeTimeout
+	}
+
+	mountPoint, err := persistentDiskMountPoint(a.platform, a.dirProvider, diskCid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.diskFreezer.Freeze(mountPoint, timeout)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Freezing persistent disk")
+	}
+
+	return map[string]string{}, nil
+}
+
+func (a FreezeDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a FreezeDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
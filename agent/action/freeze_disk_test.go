@@ -0,0 +1,146 @@
+package action_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakeapplyspec "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	fakescript "github.com/cloudfoundry/bosh-agent/agent/script/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("FreezeDiskAction", func() {
+	var (
+		settingsService *fakesettings.FakeSettingsService
+		platform        *fakeplatform.FakePlatform
+		timeService     *fakeclock.FakeClock
+		snapshotScript  *fakescript.FakeCancellableScript
+		action          FreezeDiskAction
+	)
+
+	BeforeEach(func() {
+		settingsService = &fakesettings.FakeSettingsService{}
+		platform = fakeplatform.NewFakePlatform()
+		timeService = fakeclock.NewFakeClock(time.Now())
+
+		fakeJobScriptProvider := &fakescript.FakeJobScriptProvider{}
+		snapshotScript = &fakescript.FakeCancellableScript{}
+		fakeJobScriptProvider.NewParallelScriptReturns(snapshotScript)
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		diskFreezer := NewDiskFreezer(platform, fakeJobScriptProvider, fakeapplyspec.NewFakeV1Service(), timeService, logger)
+
+		dirProvider := boshdirs.NewProvider("/fake-base-dir")
+		action = NewFreezeDisk(settingsService, platform, diskFreezer, dirProvider)
+	})
+
+	AssertActionIsAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	Describe("Run", func() {
+		BeforeEach(func() {
+			settingsService.Settings.Disks.Persistent = map[string]interface{}{
+				"fake-disk-cid": map[string]interface{}{
+					"path":      "fake-device-path",
+					"volume_id": "fake-volume-id",
+				},
+			}
+			platform.MountedDevicePaths = []string{"fake-device-path"}
+		})
+
+		It("freezes persistent disk mounted at store directory", func() {
+			result, err := action.Run("fake-disk-cid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(map[string]string{}))
+
+			Expect(settingsService.SettingsWereLoaded).To(BeTrue())
+			Expect(platform.FreezePersistentDiskMountPoints).To(Equal([]string{"/fake-base-dir/store"}))
+		})
+
+		It("freezes named persistent disk at its own mount point", func() {
+			err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{
+				DiskCID:    "fake-disk-cid",
+				Name:       "wal",
+				MountPoint: "/fake-base-dir/store/wal",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = action.Run("fake-disk-cid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platform.FreezePersistentDiskMountPoints).To(Equal([]string{"/fake-base-dir/store/wal"}))
+		})
+
+		It("thaws automatically after default timeout", func() {
+			_, err := action.Run("fake-disk-cid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(snapshotScript.RunCallCount()).To(Equal(1))
+
+			timeService.WaitForWatcherAndIncrement(DefaultDiskFreezeTimeout - time.Second)
+			Consistently(snapshotScript.RunCallCount).Should(Equal(1))
+
+			timeService.Increment(time.Second)
+			Eventually(snapshotScript.RunCallCount).Should(Equal(2))
+			Expect(platform.ThawPersistentDiskMountPoints).To(Equal([]string{"/fake-base-dir/store"}))
+		})
+
+		It("thaws automatically after requested timeout", func() {
+			_, err := action.Run("fake-disk-cid", 5)
+			Expect(err).NotTo(HaveOccurred())
+
+			timeService.WaitForWatcherAndIncrement(5 * time.Second)
+			Eventually(snapshotScript.RunCallCount).Should(Equal(2))
+		})
+
+		It("limits requested timeout", func() {
+			_, err := action.Run("fake-disk-cid", 3600)
+			Expect(err).NotTo(HaveOccurred())
+
+			timeService.WaitForWatcherAndIncrement(MaxDiskFreezeTimeout)
+			Eventually(snapshotScript.RunCallCount).Should(Equal(2))
+		})
+
+		It("returns error when disk is not mounted", func() {
+			platform.MountedDevicePaths = nil
+
+			_, err := action.Run("fake-disk-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Persistent disk 'fake-disk-cid' must be mounted to be frozen"))
+			Expect(platform.FreezePersistentDiskMountPoints).To(BeEmpty())
+		})
+
+		It("returns error when freezing fails", func() {
+			platform.FreezePersistentDiskErr = errors.New("fake-freeze-err")
+
+			_, err := action.Run("fake-disk-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-freeze-err"))
+		})
+
+		It("returns error when disk cannot be found", func() {
+			_, err := action.Run("fake-unknown-disk-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Persistent disk with volume id 'fake-unknown-disk-cid' could not be found"))
+		})
+
+		It("returns error when settings cannot be loaded", func() {
+			settingsService.LoadSettingsError = errors.New("fake-load-settings-err")
+
+			_, err := action.Run("fake-disk-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-load-settings-err"))
+		})
+	})
+})
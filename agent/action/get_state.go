@@ -5,6 +5,7 @@ import (
 
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -15,6 +16,8 @@ type GetStateAction struct {
 	specService     boshas.V1Service
 	jobSupervisor   boshjobsuper.JobSupervisor
 	vitalsService   boshvitals.Service
+
+	filesystemCheckHistory boshdisk.FilesystemCheckHistory
 }
 
 func NewGetState(
@@ -22,11 +25,13 @@ func NewGetState(
 	specService boshas.V1Service,
 	jobSupervisor boshjobsuper.JobSupervisor,
 	vitalsService boshvitals.Service,
+	filesystemCheckHistory boshdisk.FilesystemCheckHistory,
 ) (action GetStateAction) {
 	action.settingsService = settingsService
 	action.specService = specService
 	action.jobSupervisor = jobSupervisor
 	action.vitalsService = vitalsService
+	action.filesystemCheckHistory = filesystemCheckHistory
 	return
 }
 
@@ -50,6 +55,8 @@ type GetStateV1ApplySpec struct {
 	Vitals    *boshvitals.Vitals     `json:"vitals,omitempty"`
 	Processes []boshjobsuper.Process `json:"processes,omitempty"`
 	VM        boshsettings.VM        `json:"vm"`
+
+	FilesystemChecks []boshdisk.FilesystemCheck `json:"filesystem_checks,omitempty"`
 }
 
 func (a GetStateAction) Run(filters ...string) (GetStateV1ApplySpec, error) {
@@ -74,15 +81,21 @@ func (a GetStateAction) Run(filters ...string) (GetStateV1ApplySpec, error) {
 		return GetStateV1ApplySpec{}, bosherr.WrapError(err, "Getting processes status")
 	}
 
+	filesystemChecks, err := a.filesystemCheckHistory.List()
+	if err != nil {
+		return GetStateV1ApplySpec{}, bosherr.WrapError(err, "Getting filesystem checks")
+	}
+
 	settings := a.settingsService.GetSettings()
 
 	value := GetStateV1ApplySpec{
-		spec,
-		settings.AgentID,
-		a.jobSupervisor.Status(),
-		vitalsReference,
-		processes,
-		settings.VM,
+		V1ApplySpec:      spec,
+		AgentID:          settings.AgentID,
+		JobState:         a.jobSupervisor.Status(),
+		Vitals:           vitalsReference,
+		Processes:        processes,
+		VM:               settings.VM,
+		FilesystemChecks: filesystemChecks,
 	}
 
 	if value.NetworkSpecs == nil {
@@ -11,11 +11,13 @@ import (
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	fakevitals "github.com/cloudfoundry/bosh-agent/platform/vitals/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
 
 var _ = Describe("GetState", func() {
@@ -24,6 +26,8 @@ var _ = Describe("GetState", func() {
 		specService     *fakeas.FakeV1Service
 		jobSupervisor   *fakejobsuper.FakeJobSupervisor
 		vitalsService   *fakevitals.FakeService
+		fs              *fakesys.FakeFileSystem
+		history         boshdisk.FilesystemCheckHistory
 		action          GetStateAction
 	)
 
@@ -32,7 +36,9 @@ var _ = Describe("GetState", func() {
 		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
 		specService = fakeas.NewFakeV1Service()
 		vitalsService = fakevitals.NewFakeService()
-		action = NewGetState(settingsService, specService, jobSupervisor, vitalsService)
+		fs = fakesys.NewFakeFileSystem()
+		history = boshdisk.NewFilesystemCheckHistory(fs, "/fake-bosh")
+		action = NewGetState(settingsService, specService, jobSupervisor, vitalsService, history)
 	})
 
 	AssertActionIsNotAsynchronous(action)
@@ -127,6 +133,29 @@ var _ = Describe("GetState", func() {
 					boshassert.MatchesJSONMap(GinkgoT(), state.VM, expectedVM)
 				})
 
+				It("returns persistent disk filesystem checks", func() {
+					check := boshdisk.FilesystemCheck{
+						DiskCID:        "fake-disk-cid",
+						CheckedAt:      1306076861,
+						FileSystemType: boshdisk.FileSystemExt4,
+						ErrorsFound:    true,
+						Repaired:       true,
+					}
+					Expect(history.Record(check)).To(Succeed())
+
+					state, err := action.Run()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(state.FilesystemChecks).To(Equal([]boshdisk.FilesystemCheck{check}))
+				})
+
+				It("returns error when filesystem checks cannot be read", func() {
+					Expect(fs.WriteFileString("/fake-bosh/filesystem_checks.json", "bad-json")).To(Succeed())
+
+					_, err := action.Run()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Getting filesystem checks"))
+				})
+
 				Describe("non-populated field formatting", func() {
 					It("returns network as empty hash if not set", func() {
 						specService.Spec = boshas.V1ApplySpec{NetworkSpecs: nil}
@@ -44,6 +44,24 @@ var _ = Describe("GetTask", func() {
 			`{"agent_task_id":"fake-task-id","state":"running"}`)
 	})
 
+	It("returns progress of a running task", func() {
+		taskService.StartedTasks["fake-task-id"] = boshtask.Task{
+			ID:    "fake-task-id",
+			State: boshtask.StateRunning,
+			Progress: &boshtask.Progress{
+				Phase:            "fake-phase",
+				Percent:          42.5,
+				BytesTransferred: 1024,
+			},
+		}
+
+		taskValue, err := action.Run("fake-task-id")
+		Expect(err).ToNot(HaveOccurred())
+
+		boshassert.MatchesJSONString(GinkgoT(), taskValue,
+			`{"agent_task_id":"fake-task-id","state":"running","progress":{"phase":"fake-phase","percent":42.5,"bytes_transferred":1024}}`)
+	})
+
 	It("returns a failed task", func() {
 		taskService.StartedTasks["fake-task-id"] = boshtask.Task{
 			ID:    "fake-task-id",
@@ -42,7 +42,9 @@ func (a ListDiskAction) IsLoggable() bool {
 	return true
 }
 
-func (a ListDiskAction) Run() (interface{}, error) {
+// Run lists CIDs of mounted disks; when disk name is given
+// only named disk mounted with that name is listed
+func (a ListDiskAction) Run(name ...string) (interface{}, error) {
 	err := a.settingsService.LoadSettings()
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Refreshing the settings")
@@ -51,9 +53,28 @@ func (a ListDiskAction) Run() (interface{}, error) {
 	settings := a.settingsService.GetSettings()
 	diskIDs := []string{}
 
+	namedDiskCid := ""
+
+	if len(name) > 0 && name[0] != "" {
+		managedDisk, found, err := a.platform.GetManagedDisks().FindByName(name[0])
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Finding named persistent disk")
+		}
+
+		if !found {
+			return diskIDs, nil
+		}
+
+		namedDiskCid = managedDisk.DiskCID
+	}
+
 	for diskID := range settings.Disks.Persistent {
 		var isMounted bool
 
+		if namedDiskCid != "" && diskID != namedDiskCid {
+			continue
+		}
+
 		diskSettings, _ := settings.PersistentDiskSettings(diskID)
 		isMounted, err := a.platform.IsPersistentDiskMounted(diskSettings)
 		if err != nil {
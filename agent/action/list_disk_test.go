@@ -59,6 +59,46 @@ var _ = Describe("ListDisk", func() {
 		Expect(settingsService.SettingsWereLoaded).To(BeTrue())
 	})
 
+	Context("when disk name is given", func() {
+		BeforeEach(func() {
+			platform.MountedDevicePaths = []string{"/dev/sdb", "/dev/sdc"}
+
+			settingsService.Settings.Disks = boshsettings.Disks{
+				Persistent: map[string]interface{}{
+					"volume-2": "/dev/sdb",
+					"volume-3": "/dev/sdc",
+				},
+			}
+
+			err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{
+				DiskCID:    "volume-3",
+				Name:       "wal",
+				MountPoint: "/var/vcap/store/wal",
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("lists only named disk mounted with that name", func() {
+			value, err := action.Run("wal")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal([]string{"volume-3"}))
+		})
+
+		It("lists nothing when no disk was mounted with that name", func() {
+			value, err := action.Run("data")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal([]string{}))
+		})
+
+		It("does not list named disk when it is not mounted", func() {
+			platform.MountedDevicePaths = []string{"/dev/sdb"}
+
+			value, err := action.Run("wal")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(value).To(Equal([]string{}))
+		})
+	})
+
 	Context("when unable to loadsettings", func() {
 		BeforeEach(func() {
 			settingsService.LoadSettingsError = bosherrors.Error("fake loadsettings error")
@@ -0,0 +1,79 @@
+package action
+
+import (
+	"errors"
+	"time"
+
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type ListTasksAction struct {
+	taskService boshtask.Service
+}
+
+type ListTasksEntry struct {
+	AgentTaskID string         `json:"agent_task_id"`
+	Method      string         `json:"method"`
+	State       boshtask.State `json:"state"`
+	StartedAt   *time.Time     `json:"started_at"`
+	FinishedAt  *time.Time     `json:"finished_at"`
+	Duration    float64        `json:"duration"`
+}
+
+func NewListTasks(taskService boshtask.Service) (listTasks ListTasksAction) {
+	listTasks.taskService = taskService
+	return
+}
+
+func (a ListTasksAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return false
+}
+
+func (a ListTasksAction) IsPersistent() bool {
+	return false
+}
+
+func (a ListTasksAction) IsLoggable() bool {
+	return true
+}
+
+func (a ListTasksAction) Run() ([]ListTasksEntry, error) {
+	records, err := a.taskService.ListTasks()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing tasks")
+	}
+
+	entries := []ListTasksEntry{}
+
+	for _, record := range records {
+		entry := ListTasksEntry{
+			AgentTaskID: record.TaskID,
+			Method:      record.Method,
+			State:       record.State,
+			Duration:    record.Duration.Seconds(),
+		}
+
+		if !record.StartedAt.IsZero() {
+			startedAt := record.StartedAt
+			entry.StartedAt = &startedAt
+		}
+
+		if !record.FinishedAt.IsZero() {
+			finishedAt := record.FinishedAt
+			entry.FinishedAt = &finishedAt
+		}
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func (a ListTasksAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a ListTasksAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,79 @@
+package action_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
+	boshassert "github.com/cloudfoundry/bosh-utils/assert"
+)
+
+var _ = Describe("ListTasks", func() {
+	var (
+		taskService *faketask.FakeService
+		action      ListTasksAction
+	)
+
+	BeforeEach(func() {
+		taskService = faketask.NewFakeService()
+		action = NewListTasks(taskService)
+	})
+
+	AssertActionIsNotAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	It("returns running and finished tasks", func() {
+		startedAt := time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC)
+		finishedAt := startedAt.Add(90 * time.Second)
+
+		taskService.ListTasksRecords = []boshtask.Record{
+			{
+				TaskID:     "fake-task-id-1",
+				Method:     "fake-method-1",
+				State:      boshtask.StateDone,
+				StartedAt:  startedAt,
+				FinishedAt: finishedAt,
+				Duration:   90 * time.Second,
+			},
+			{
+				TaskID:    "fake-task-id-2",
+				Method:    "fake-method-2",
+				State:     boshtask.StateRunning,
+				StartedAt: finishedAt,
+				Duration:  500 * time.Millisecond,
+			},
+		}
+
+		value, err := action.Run()
+		Expect(err).ToNot(HaveOccurred())
+
+		boshassert.MatchesJSONString(GinkgoT(), value,
+			`[{"agent_task_id":"fake-task-id-1","method":"fake-method-1","state":"done",`+
+				`"started_at":"2017-03-01T10:00:00Z","finished_at":"2017-03-01T10:01:30Z","duration":90},`+
+				`{"agent_task_id":"fake-task-id-2","method":"fake-method-2","state":"running",`+
+				`"started_at":"2017-03-01T10:01:30Z","finished_at":null,"duration":0.5}]`)
+	})
+
+	It("returns an empty list when there are no tasks", func() {
+		value, err := action.Run()
+		Expect(err).ToNot(HaveOccurred())
+		boshassert.MatchesJSONString(GinkgoT(), value, `[]`)
+	})
+
+	It("returns error when listing tasks fails", func() {
+		taskService.ListTasksErr = errors.New("fake-list-error")
+
+		_, err := action.Run()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-list-error"))
+	})
+})
@@ -3,6 +3,7 @@ package action
 import (
 	"errors"
 
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -34,13 +35,26 @@ func (a MigrateDiskAction) IsLoggable() bool {
 	return true
 }
 
-func (a MigrateDiskAction) Run() (value interface{}, err error) {
-	err = a.platform.MigratePersistentDisk(a.dirProvider.StoreDir(), a.dirProvider.StoreMigrationDir())
+func (a MigrateDiskAction) Run(progress boshtask.ProgressReporter) (value interface{}, err error) {
+	progress.ReportProgress(boshtask.Progress{Phase: "migrating", Percent: 0})
+
+	reportCopyProgress := func(copiedBytes, totalBytes uint64) {
+		percent := float64(100)
+		if totalBytes > 0 {
+			percent = float64(copiedBytes) * 100 / float64(totalBytes)
+		}
+
+		progress.ReportProgress(boshtask.Progress{Phase: "migrating", Percent: percent, BytesTransferred: copiedBytes})
+	}
+
+	err = a.platform.MigratePersistentDisk(a.dirProvider.StoreDir(), a.dirProvider.StoreMigrationDir(), reportCopyProgress)
 	if err != nil {
 		err = bosherr.WrapError(err, "Migrating persistent disk")
 		return
 	}
 
+	progress.ReportProgress(boshtask.Progress{Phase: "migrated", Percent: 100})
+
 	value = map[string]string{}
 	return
 }
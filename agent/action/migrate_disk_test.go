@@ -1,10 +1,14 @@
 package action_test
 
 import (
+	"errors"
+
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
@@ -35,11 +39,37 @@ var _ = Describe("Testing with Ginkgo", func() {
 	AssertActionIsNotCancelable(action)
 
 	It("migrate disk action run", func() {
-		value, err := action.Run()
+		progress := &faketask.FakeProgressReporter{}
+
+		value, err := action.Run(progress)
 		Expect(err).ToNot(HaveOccurred())
 		boshassert.MatchesJSONString(GinkgoT(), value, "{}")
+		Expect(progress.Phases()).To(Equal([]string{"migrating", "migrated"}))
 
 		Expect(platform.MigratePersistentDiskFromMountPoint).To(boshassert.MatchPath("/foo/store"))
 		Expect(platform.MigratePersistentDiskToMountPoint).To(boshassert.MatchPath("/foo/store_migration_target"))
 	})
+
+	It("reports copy progress while migrating", func() {
+		platform.MigratePersistentDiskProgress = [][2]uint64{{0, 200}, {50, 200}, {200, 200}}
+		progress := &faketask.FakeProgressReporter{}
+
+		_, err := action.Run(progress)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(progress.Reported).To(Equal([]boshtask.Progress{
+			{Phase: "migrating", Percent: 0},
+			{Phase: "migrating", Percent: 0, BytesTransferred: 0},
+			{Phase: "migrating", Percent: 25, BytesTransferred: 50},
+			{Phase: "migrating", Percent: 100, BytesTransferred: 200},
+			{Phase: "migrated", Percent: 100},
+		}))
+	})
+
+	It("returns error when migrating fails", func() {
+		platform.MigratePersistentDiskErr = errors.New("fake-migrate-err")
+
+		_, err := action.Run(&faketask.FakeProgressReporter{})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-migrate-err"))
+	})
 })
@@ -2,8 +2,11 @@ package action
 
 import (
 	"errors"
+	"path/filepath"
+	"regexp"
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -14,6 +17,9 @@ type diskMounter interface {
 	MountPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error
 }
 
+// Disk name is used as a directory name
+var mountDiskNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.\-]*$`)
+
 type MountDiskAction struct {
 	settingsService    boshsettings.Service
 	diskMounter        diskMounter
@@ -47,7 +53,19 @@ func (a MountDiskAction) IsLoggable() bool {
 	return true
 }
 
-func (a MountDiskAction) Run(diskCid string) (interface{}, error) {
+// Run mounts disk at store directory unless disk name is given;
+// named disks are mounted in their own directories at <store directory>/<name>
+func (a MountDiskAction) Run(diskCid string, name ...string) (interface{}, error) {
+	mountPoint := a.dirProvider.StoreDir()
+
+	if len(name) > 0 && name[0] != "" {
+		if !mountDiskNameRegexp.MatchString(name[0]) {
+			return nil, bosherr.Errorf("Invalid persistent disk name '%s'", name[0])
+		}
+
+		mountPoint = filepath.Join(a.dirProvider.StoreDir(), name[0])
+	}
+
 	err := a.settingsService.LoadSettings()
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Refreshing the settings")
@@ -60,8 +78,6 @@ func (a MountDiskAction) Run(diskCid string) (interface{}, error) {
 		return nil, bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCid)
 	}
 
-	mountPoint := a.dirProvider.StoreDir()
-
 	err = a.diskMounter.MountPersistentDisk(diskSettings, mountPoint)
 	if err != nil {
 		return nil, bosherr.WrapError(err, "Mounting persistent disk")
@@ -77,3 +93,17 @@ func (a MountDiskAction) Resume() (interface{}, error) {
 func (a MountDiskAction) Cancel() error {
 	return errors.New("not supported")
 }
+
+// persistentDiskMountPoint returns where disk was mounted by mount_disk
+func persistentDiskMountPoint(platform boshplatform.Platform, dirProvider boshdirs.Provider, diskCid string) (string, error) {
+	managedDisk, found, err := platform.GetManagedDisks().Find(diskCid)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Finding managed disk")
+	}
+
+	if found {
+		return managedDisk.MountPoint, nil
+	}
+
+	return dirProvider.StoreDir(), nil
+}
@@ -63,6 +63,25 @@ var _ = Describe("MountDiskAction", func() {
 						}))
 						Expect(platform.MountPersistentDiskMountPoint).To(boshassert.MatchPath("/fake-base-dir/store"))
 					})
+
+					It("mounts named disk in its own directory under store directory", func() {
+						result, err := action.Run("fake-disk-cid", "wal")
+						Expect(err).NotTo(HaveOccurred())
+						Expect(result).To(Equal(map[string]string{}))
+
+						Expect(platform.MountPersistentDiskSettings.ID).To(Equal("fake-disk-cid"))
+						Expect(platform.MountPersistentDiskMountPoint).To(boshassert.MatchPath("/fake-base-dir/store/wal"))
+					})
+				})
+
+				Context("when disk name is not valid", func() {
+					It("returns error without mounting disk", func() {
+						_, err := action.Run("fake-disk-cid", "../wal")
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(Equal("Invalid persistent disk name '../wal'"))
+
+						Expect(platform.MountPersistentDiskMountPoint).To(BeEmpty())
+					})
 				})
 
 				Context("when mounting fails", func() {
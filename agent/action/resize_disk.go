@@ -0,0 +1,73 @@
+package action
+
+import (
+	"errors"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type ResizeDiskAction struct {
+	settingsService boshsettings.Service
+	platform        boshplatform.Platform
+	dirProvider     boshdirs.Provider
+}
+
+func NewResizeDisk(
+	settingsService boshsettings.Service,
+	platform boshplatform.Platform,
+	dirProvider boshdirs.Provider,
+) (resizeDisk ResizeDiskAction) {
+	resizeDisk.settingsService = settingsService
+	resizeDisk.platform = platform
+	resizeDisk.dirProvider = dirProvider
+	return
+}
+
+func (a ResizeDiskAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return true
+}
+
+func (a ResizeDiskAction) IsPersistent() bool {
+	return false
+}
+
+func (a ResizeDiskAction) IsLoggable() bool {
+	return true
+}
+
+func (a ResizeDiskAction) Run(diskCid string) (interface{}, error) {
+	err := a.settingsService.LoadSettings()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Refreshing the settings")
+	}
+
+	settings := a.settingsService.GetSettings()
+
+	diskSettings, found := settings.PersistentDiskSettings(diskCid)
+	if !found {
+		return nil, bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskCid)
+	}
+
+	mountPoint, err := persistentDiskMountPoint(a.platform, a.dirProvider, diskCid)
+	if err != nil {
+		return nil, err
+	}
+
+	err = a.platform.ResizePersistentDisk(diskSettings, mountPoint)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Resizing persistent disk")
+	}
+
+	return map[string]string{}, nil
+}
+
+func (a ResizeDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a ResizeDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
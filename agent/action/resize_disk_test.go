@@ -0,0 +1,96 @@
+package action_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+)
+
+var _ = Describe("ResizeDiskAction", func() {
+	var (
+		settingsService *fakesettings.FakeSettingsService
+		platform        *fakeplatform.FakePlatform
+		action          ResizeDiskAction
+	)
+
+	BeforeEach(func() {
+		settingsService = &fakesettings.FakeSettingsService{}
+		platform = fakeplatform.NewFakePlatform()
+		dirProvider := boshdirs.NewProvider("/fake-base-dir")
+		action = NewResizeDisk(settingsService, platform, dirProvider)
+	})
+
+	AssertActionIsAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	Describe("Run", func() {
+		BeforeEach(func() {
+			settingsService.Settings.Disks.Persistent = map[string]interface{}{
+				"fake-disk-cid": map[string]interface{}{
+					"path":      "fake-device-path",
+					"volume_id": "fake-volume-id",
+				},
+			}
+		})
+
+		It("resizes persistent disk mounted at store directory", func() {
+			result, err := action.Run("fake-disk-cid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(map[string]string{}))
+
+			Expect(settingsService.SettingsWereLoaded).To(BeTrue())
+			Expect(platform.ResizePersistentDiskSettings).To(Equal(boshsettings.DiskSettings{
+				ID:       "fake-disk-cid",
+				VolumeID: "fake-volume-id",
+				Path:     "fake-device-path",
+			}))
+			Expect(platform.ResizePersistentDiskMountPoint).To(Equal("/fake-base-dir/store"))
+		})
+
+		It("resizes named persistent disk at its own mount point", func() {
+			err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{
+				DiskCID:    "fake-disk-cid",
+				Name:       "wal",
+				MountPoint: "/fake-base-dir/store/wal",
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			_, err = action.Run("fake-disk-cid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platform.ResizePersistentDiskMountPoint).To(Equal("/fake-base-dir/store/wal"))
+		})
+
+		It("returns error when resizing fails", func() {
+			platform.ResizePersistentDiskErr = errors.New("fake-resize-err")
+
+			_, err := action.Run("fake-disk-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-resize-err"))
+		})
+
+		It("returns error when disk cannot be found", func() {
+			_, err := action.Run("fake-unknown-disk-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Persistent disk with volume id 'fake-unknown-disk-cid' could not be found"))
+		})
+
+		It("returns error when settings cannot be loaded", func() {
+			settingsService.LoadSettingsError = errors.New("fake-load-settings-err")
+
+			_, err := action.Run("fake-disk-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-load-settings-err"))
+		})
+	})
+})
@@ -2,22 +2,42 @@ package action
 
 import (
 	"errors"
+	"os"
 	"path"
 	"time"
 
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	"github.com/cloudfoundry/bosh-agent/agent/script/cmd"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
-const runErrandActionLogTag = "runErrandAction"
+const (
+	runErrandActionLogTag = "runErrandAction"
+
+	RunErrandPhaseRunning = "running errand"
+
+	// Amount of output kept in memory and returned in the errand result;
+	// full output is uploaded to the blobstore
+	errandOutputSize = 1024 * 1024
+
+	// Amount of latest output reported through get_task
+	errandOutputReportSize = 16 * 1024
+
+	errandOutputReportInterval = 1 * time.Second
+)
 
 type RunErrandAction struct {
 	specService boshas.V1Service
 	jobsDir     string
 	cmdRunner   boshsys.CmdRunner
+	fs          boshsys.FileSystem
+	compressor  boshcmd.Compressor
+	blobstore   boshblob.DigestBlobstore
 	logger      boshlog.Logger
 
 	cancelCh chan struct{}
@@ -27,12 +47,18 @@ func NewRunErrand(
 	specService boshas.V1Service,
 	jobsDir string,
 	cmdRunner boshsys.CmdRunner,
+	fs boshsys.FileSystem,
+	compressor boshcmd.Compressor,
+	blobstore boshblob.DigestBlobstore,
 	logger boshlog.Logger,
 ) RunErrandAction {
 	return RunErrandAction{
 		specService: specService,
 		jobsDir:     jobsDir,
 		cmdRunner:   cmdRunner,
+		fs:          fs,
+		compressor:  compressor,
+		blobstore:   blobstore,
 		logger:      logger,
 
 		// Initialize channel in a constructor to avoid race
@@ -57,9 +83,17 @@ type ErrandResult struct {
 	Stdout     string `json:"stdout"`
 	Stderr     string `json:"stderr"`
 	ExitStatus int    `json:"exit_code"`
+
+	// Full errand output; missing if logs could not be uploaded
+	Logs *ErrandLogs `json:"logs,omitempty"`
+}
+
+type ErrandLogs struct {
+	BlobstoreID string `json:"blobstore_id"`
+	Sha1        string `json:"sha1"`
 }
 
-func (a RunErrandAction) Run(errandName ...string) (ErrandResult, error) {
+func (a RunErrandAction) Run(progress boshtask.ProgressReporter, errandName ...string) (ErrandResult, error) {
 	currentSpec, err := a.specService.Get()
 	if err != nil {
 		return ErrandResult{}, bosherr.WrapError(err, "Getting current spec")
@@ -88,7 +122,39 @@ func (a RunErrandAction) Run(errandName ...string) (ErrandResult, error) {
 		templateName = errandName[0]
 	}
 
+	logsDir, err := a.fs.TempDir("bosh-errand-logs")
+	if err != nil {
+		return ErrandResult{}, bosherr.WrapError(err, "Creating errand logs directory")
+	}
+
+	defer func() {
+		_ = a.fs.RemoveAll(logsDir)
+	}()
+
+	stdoutLog, err := a.fs.OpenFile(path.Join(logsDir, templateName+".stdout.log"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(0640))
+	if err != nil {
+		return ErrandResult{}, bosherr.WrapError(err, "Opening errand stdout log")
+	}
+
+	defer func() {
+		_ = stdoutLog.Close()
+	}()
+
+	stderrLog, err := a.fs.OpenFile(path.Join(logsDir, templateName+".stderr.log"), os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(0640))
+	if err != nil {
+		return ErrandResult{}, bosherr.WrapError(err, "Opening errand stderr log")
+	}
+
+	defer func() {
+		_ = stderrLog.Close()
+	}()
+
+	stdout := newErrandOutput(stdoutLog, errandOutputSize)
+	stderr := newErrandOutput(stderrLog, errandOutputSize)
+
 	command := cmd.BuildCommand(path.Join(a.jobsDir, templateName, "bin", "run"))
+	command.Stdout = stdout
+	command.Stderr = stderr
 
 	process, err := a.cmdRunner.RunComplexCommandAsync(command)
 	if err != nil {
@@ -97,11 +163,16 @@ func (a RunErrandAction) Run(errandName ...string) (ErrandResult, error) {
 
 	var result boshsys.Result
 
+	ticker := time.NewTicker(errandOutputReportInterval)
+	defer ticker.Stop()
+
 	// Can only wait once on a process but cancelling can happen multiple times
 	for processExitedCh := process.Wait(); processExitedCh != nil; {
 		select {
 		case result = <-processExitedCh:
 			processExitedCh = nil
+		case <-ticker.C:
+			a.reportOutput(progress, stdout, stderr)
 		case <-a.cancelCh:
 			// Ignore possible TerminateNicely error since we cannot return it
 			err := process.TerminateNicely(10 * time.Second)
@@ -111,17 +182,49 @@ func (a RunErrandAction) Run(errandName ...string) (ErrandResult, error) {
 		}
 	}
 
+	a.reportOutput(progress, stdout, stderr)
+
 	if result.Error != nil && result.ExitStatus == -1 {
 		return ErrandResult{}, bosherr.WrapError(result.Error, "Running errand script")
 	}
 
 	return ErrandResult{
-		Stdout:     result.Stdout,
-		Stderr:     result.Stderr,
+		Stdout:     stdout.String(),
+		Stderr:     stderr.String(),
 		ExitStatus: result.ExitStatus,
+		Logs:       a.uploadLogs(logsDir),
 	}, nil
 }
 
+func (a RunErrandAction) reportOutput(progress boshtask.ProgressReporter, stdout, stderr *errandOutput) {
+	output := &boshtask.Output{}
+	output.Stdout, output.StdoutOffset = stdout.Tail(errandOutputReportSize)
+	output.Stderr, output.StderrOffset = stderr.Tail(errandOutputReportSize)
+
+	progress.ReportProgress(boshtask.Progress{Phase: RunErrandPhaseRunning, Output: output})
+}
+
+// uploadLogs does not fail the errand since its exit code is what matters
+func (a RunErrandAction) uploadLogs(logsDir string) *ErrandLogs {
+	tarball, err := a.compressor.CompressFilesInDir(logsDir)
+	if err != nil {
+		a.logger.Error(runErrandActionLogTag, "Failed to compress errand logs: %s", err.Error())
+		return nil
+	}
+
+	defer func() {
+		_ = a.compressor.CleanUp(tarball)
+	}()
+
+	blobID, digest, err := a.blobstore.Create(tarball)
+	if err != nil {
+		a.logger.Error(runErrandActionLogTag, "Failed to upload errand logs: %s", err.Error())
+		return nil
+	}
+
+	return &ErrandLogs{BlobstoreID: blobID, Sha1: digest.String()}
+}
+
 func (a RunErrandAction) Resume() (interface{}, error) {
 	return nil, errors.New("not supported")
 }
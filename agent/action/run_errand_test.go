@@ -2,6 +2,8 @@ package action_test
 
 import (
 	"errors"
+	"runtime"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo"
@@ -12,32 +14,59 @@ import (
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
 	boshenv "github.com/cloudfoundry/bosh-agent/agent/script/pathenv"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
+	fakeblobstore "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
+	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
+	fakecmd "github.com/cloudfoundry/bosh-utils/fileutil/fakes"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
-	"runtime"
 )
 
 var _ = Describe("RunErrand", func() {
 	var (
-		specService *fakeas.FakeV1Service
-		cmdRunner   *fakesys.FakeCmdRunner
-		action      RunErrandAction
-		errandName  string
-		fullCommand string
+		specService  *fakeas.FakeV1Service
+		cmdRunner    *fakesys.FakeCmdRunner
+		fs           *fakesys.FakeFileSystem
+		compressor   *fakecmd.FakeCompressor
+		blobstore    *fakeblobstore.FakeDigestBlobstore
+		progress     *faketask.FakeProgressReporter
+		action       RunErrandAction
+		errandName   string
+		fullCommand  string
+		expectedLogs *ErrandLogs
 	)
 
 	BeforeEach(func() {
 		specService = fakeas.NewFakeV1Service()
 		cmdRunner = fakesys.NewFakeCmdRunner()
+		fs = fakesys.NewFakeFileSystem()
+		compressor = fakecmd.NewFakeCompressor()
+		blobstore = &fakeblobstore.FakeDigestBlobstore{}
+		progress = &faketask.FakeProgressReporter{}
 		logger := boshlog.NewLogger(boshlog.LevelNone)
-		action = NewRunErrand(specService, "/fake-jobs-dir", cmdRunner, logger)
+		action = NewRunErrand(specService, "/fake-jobs-dir", cmdRunner, fs, compressor, blobstore, logger)
 		errandName = "fake-job-name"
 		if runtime.GOOS == "windows" {
 			fullCommand = "powershell /fake-jobs-dir/fake-job-name/bin/run"
 		} else {
 			fullCommand = "/fake-jobs-dir/fake-job-name/bin/run"
 		}
+
+		fs.TempDirDir = "/fake-errand-logs-dir"
+		compressor.CompressFilesInDirTarballPath = "/fake-errand-logs.tgz"
+
+		logsDigest := boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "fake-logs-sha1"))
+		blobstore.CreateReturns("fake-logs-blob-id", logsDigest, nil)
+		expectedLogs = &ErrandLogs{BlobstoreID: "fake-logs-blob-id", Sha1: logsDigest.String()}
+
+		// Errand script writes its output directly to the writers it was given
+		cmdRunner.SetCmdCallback(fullCommand, func() {
+			cmd := cmdRunner.RunComplexCommands[len(cmdRunner.RunComplexCommands)-1]
+			_, _ = cmd.Stdout.Write([]byte("fake-stdout"))
+			_, _ = cmd.Stderr.Write([]byte("fake-stderr"))
+		})
 	})
 
 	AssertActionIsAsynchronous(action)
@@ -55,21 +84,20 @@ var _ = Describe("RunErrand", func() {
 					specService.Spec = currentSpec
 					cmdRunner.AddProcess(fullCommand, &fakesys.FakeProcess{
 						WaitResult: boshsys.Result{
-							Stdout:     "fake-stdout",
-							Stderr:     "fake-stderr",
 							ExitStatus: 0,
 						},
 					})
 				})
 
 				It("returns errand result without error after running an errand", func() {
-					result, err := action.Run()
+					result, err := action.Run(progress)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(result).To(Equal(
 						ErrandResult{
 							Stdout:     "fake-stdout",
 							Stderr:     "fake-stderr",
 							ExitStatus: 0,
+							Logs:       expectedLogs,
 						},
 					))
 				})
@@ -93,27 +121,26 @@ var _ = Describe("RunErrand", func() {
 					BeforeEach(func() {
 						cmdRunner.AddProcess(fullCommand, &fakesys.FakeProcess{
 							WaitResult: boshsys.Result{
-								Stdout:     "fake-stdout",
-								Stderr:     "fake-stderr",
 								ExitStatus: 0,
 							},
 						})
 					})
 
 					It("returns errand result without error after running an errand", func() {
-						result, err := action.Run(errandName)
+						result, err := action.Run(progress, errandName)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(result).To(Equal(
 							ErrandResult{
 								Stdout:     "fake-stdout",
 								Stderr:     "fake-stderr",
 								ExitStatus: 0,
+								Logs:       expectedLogs,
 							},
 						))
 					})
 
 					It("runs errand script with properly configured environment", func() {
-						_, err := action.Run(errandName)
+						_, err := action.Run(progress, errandName)
 						Expect(err).ToNot(HaveOccurred())
 						cmd := cmdRunner.RunComplexCommands[0]
 						env := map[string]string{"PATH": boshenv.Path()}
@@ -125,8 +152,6 @@ var _ = Describe("RunErrand", func() {
 					BeforeEach(func() {
 						cmdRunner.AddProcess(fullCommand, &fakesys.FakeProcess{
 							WaitResult: boshsys.Result{
-								Stdout:     "fake-stdout",
-								Stderr:     "fake-stderr",
 								ExitStatus: 123,
 								Error:      errors.New("fake-bosh-error"), // not used
 							},
@@ -134,13 +159,14 @@ var _ = Describe("RunErrand", func() {
 					})
 
 					It("returns errand result without an error", func() {
-						result, err := action.Run(errandName)
+						result, err := action.Run(progress, errandName)
 						Expect(err).ToNot(HaveOccurred())
 						Expect(result).To(Equal(
 							ErrandResult{
 								Stdout:     "fake-stdout",
 								Stderr:     "fake-stderr",
 								ExitStatus: 123,
+								Logs:       expectedLogs,
 							},
 						))
 					})
@@ -157,7 +183,7 @@ var _ = Describe("RunErrand", func() {
 					})
 
 					It("returns error because script failed to execute", func() {
-						result, err := action.Run(errandName)
+						result, err := action.Run(progress, errandName)
 						Expect(err).To(HaveOccurred())
 						Expect(err.Error()).To(ContainSubstring("fake-bosh-error"))
 						Expect(result).To(Equal(ErrandResult{}))
@@ -171,32 +197,137 @@ var _ = Describe("RunErrand", func() {
 				})
 
 				It("returns error stating the errand cannot be found", func() {
-					_, err := action.Run(errandName)
+					_, err := action.Run(progress, errandName)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(Equal("Could not find errand fake-job-name"))
 				})
 
 				It("does not run errand script", func() {
-					_, err := action.Run(errandName)
+					_, err := action.Run(progress, errandName)
 					Expect(err).To(HaveOccurred())
 					Expect(len(cmdRunner.RunComplexCommands)).To(Equal(0))
 				})
 			})
 		})
 
+		Context("when errand produces output", func() {
+			BeforeEach(func() {
+				currentSpec := boshas.V1ApplySpec{}
+				currentSpec.JobSpec.Template = "fake-job-name"
+				specService.Spec = currentSpec
+				cmdRunner.AddProcess(fullCommand, &fakesys.FakeProcess{
+					WaitResult: boshsys.Result{ExitStatus: 0},
+				})
+			})
+
+			It("reports latest output with offsets through progress", func() {
+				_, err := action.Run(progress)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(progress.Reported).ToNot(BeEmpty())
+				last := progress.Reported[len(progress.Reported)-1]
+				Expect(last.Phase).To(Equal("running errand"))
+				Expect(last.Output).To(Equal(&boshtask.Output{
+					Stdout:       "fake-stdout",
+					StdoutOffset: 0,
+					Stderr:       "fake-stderr",
+					StderrOffset: 0,
+				}))
+			})
+
+			It("reports only the tail of long output", func() {
+				longOutput := strings.Repeat("a", 20*1024) + "fake-tail"
+				cmdRunner.SetCmdCallback(fullCommand, func() {
+					cmd := cmdRunner.RunComplexCommands[0]
+					_, _ = cmd.Stdout.Write([]byte(longOutput))
+				})
+
+				result, err := action.Run(progress)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Stdout).To(Equal(longOutput))
+
+				last := progress.Reported[len(progress.Reported)-1]
+				Expect(last.Output.Stdout).To(HaveLen(16 * 1024))
+				Expect(last.Output.Stdout).To(HaveSuffix("fake-tail"))
+				Expect(last.Output.StdoutOffset).To(Equal(int64(len(longOutput) - 16*1024)))
+			})
+
+			It("keeps the tail of output in the errand result when output is too long", func() {
+				cmdRunner.SetCmdCallback(fullCommand, func() {
+					cmd := cmdRunner.RunComplexCommands[0]
+					_, _ = cmd.Stdout.Write([]byte("fake-head"))
+					_, _ = cmd.Stdout.Write([]byte(strings.Repeat("a", 1024*1024)))
+					_, _ = cmd.Stdout.Write([]byte("fake-tail"))
+				})
+
+				result, err := action.Run(progress)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Stdout).To(HaveLen(1024 * 1024))
+				Expect(result.Stdout).ToNot(ContainSubstring("fake-head"))
+				Expect(result.Stdout).To(HaveSuffix("fake-tail"))
+			})
+
+			It("saves full output to log files and uploads them to the blobstore", func() {
+				var stdoutLog, stderrLog string
+
+				compressor.CompressFilesInDirCallBack = func() {
+					stdoutLog, _ = fs.ReadFileString("/fake-errand-logs-dir/fake-job-name.stdout.log")
+					stderrLog, _ = fs.ReadFileString("/fake-errand-logs-dir/fake-job-name.stderr.log")
+				}
+
+				result, err := action.Run(progress)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result.Logs).To(Equal(expectedLogs))
+
+				Expect(compressor.CompressFilesInDirDir).To(Equal("/fake-errand-logs-dir"))
+				Expect(stdoutLog).To(Equal("fake-stdout"))
+				Expect(stderrLog).To(Equal("fake-stderr"))
+				Expect(blobstore.CreateArgsForCall(0)).To(Equal("/fake-errand-logs.tgz"))
+			})
+
+			It("cleans up log files and tarball", func() {
+				_, err := action.Run(progress)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.FileExists("/fake-errand-logs-dir")).To(BeFalse())
+				Expect(compressor.CleanUpTarballPath).To(Equal("/fake-errand-logs.tgz"))
+			})
+
+			It("returns errand result without logs when uploading logs fails", func() {
+				blobstore.CreateReturns("", boshcrypto.MultipleDigest{}, errors.New("fake-create-err"))
+
+				result, err := action.Run(progress)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(ErrandResult{
+					Stdout:     "fake-stdout",
+					Stderr:     "fake-stderr",
+					ExitStatus: 0,
+				}))
+			})
+
+			It("returns error when log directory cannot be created", func() {
+				fs.TempDirError = errors.New("fake-temp-dir-err")
+
+				_, err := action.Run(progress)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-temp-dir-err"))
+				Expect(cmdRunner.RunComplexCommands).To(BeEmpty())
+			})
+		})
+
 		Context("when apply spec could not be retrieved", func() {
 			BeforeEach(func() {
 				specService.GetErr = errors.New("fake-get-error")
 			})
 
 			It("returns error stating that job template is required", func() {
-				_, err := action.Run(errandName)
+				_, err := action.Run(progress, errandName)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-get-error"))
 			})
 
 			It("does not run errand script", func() {
-				_, err := action.Run(errandName)
+				_, err := action.Run(progress, errandName)
 				Expect(err).To(HaveOccurred())
 				Expect(len(cmdRunner.RunComplexCommands)).To(Equal(0))
 			})
@@ -225,8 +356,6 @@ var _ = Describe("RunErrand", func() {
 				process := &fakesys.FakeProcess{
 					TerminatedNicelyCallBack: func(p *fakesys.FakeProcess) {
 						p.WaitCh <- boshsys.Result{
-							Stdout:     "fake-stdout",
-							Stderr:     "fake-stderr",
 							ExitStatus: 0,
 						}
 					},
@@ -237,7 +366,7 @@ var _ = Describe("RunErrand", func() {
 				err := action.Cancel()
 				Expect(err).ToNot(HaveOccurred())
 
-				_, err = action.Run(errandName)
+				_, err = action.Run(progress, errandName)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(process.TerminateNicelyKillGracePeriod).To(Equal(10 * time.Second))
@@ -248,8 +377,6 @@ var _ = Describe("RunErrand", func() {
 					cmdRunner.AddProcess(fullCommand, &fakesys.FakeProcess{
 						TerminatedNicelyCallBack: func(p *fakesys.FakeProcess) {
 							p.WaitCh <- boshsys.Result{
-								Stdout:     "fake-stdout",
-								Stderr:     "fake-stderr",
 								ExitStatus: 0,
 							}
 						},
@@ -260,13 +387,14 @@ var _ = Describe("RunErrand", func() {
 					err := action.Cancel()
 					Expect(err).ToNot(HaveOccurred())
 
-					result, err := action.Run(errandName)
+					result, err := action.Run(progress, errandName)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(result).To(Equal(
 						ErrandResult{
 							Stdout:     "fake-stdout",
 							Stderr:     "fake-stderr",
 							ExitStatus: 0,
+							Logs:       expectedLogs,
 						},
 					))
 				})
@@ -277,8 +405,6 @@ var _ = Describe("RunErrand", func() {
 					cmdRunner.AddProcess(fullCommand, &fakesys.FakeProcess{
 						TerminatedNicelyCallBack: func(p *fakesys.FakeProcess) {
 							p.WaitCh <- boshsys.Result{
-								Stdout:     "fake-stdout",
-								Stderr:     "fake-stderr",
 								ExitStatus: 123,
 								Error:      errors.New("fake-bosh-error"), // not used
 							}
@@ -290,13 +416,14 @@ var _ = Describe("RunErrand", func() {
 					err := action.Cancel()
 					Expect(err).ToNot(HaveOccurred())
 
-					result, err := action.Run(errandName)
+					result, err := action.Run(progress, errandName)
 					Expect(err).ToNot(HaveOccurred())
 					Expect(result).To(Equal(
 						ErrandResult{
 							Stdout:     "fake-stdout",
 							Stderr:     "fake-stderr",
 							ExitStatus: 123,
+							Logs:       expectedLogs,
 						},
 					))
 				})
@@ -318,7 +445,7 @@ var _ = Describe("RunErrand", func() {
 					err := action.Cancel()
 					Expect(err).ToNot(HaveOccurred())
 
-					result, err := action.Run(errandName)
+					result, err := action.Run(progress, errandName)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-bosh-error"))
 					Expect(result).To(Equal(ErrandResult{}))
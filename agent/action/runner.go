@@ -5,11 +5,20 @@ import (
 	"encoding/json"
 	"reflect"
 
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
 
+var (
+	progressReporterType = reflect.TypeOf((*boshtask.ProgressReporter)(nil)).Elem()
+	cancelSignalType     = reflect.TypeOf(boshtask.CancelSignal(nil))
+)
+
 type Runner interface {
-	Run(action Action, payload []byte, protocolVersion ProtocolVersion) (value interface{}, err error)
+	// Run passes task running the action as boshtask.ProgressReporter and its boshtask.CancelSignal
+	// to actions whose Run method accepts them right after the optional ProtocolVersion argument;
+	// zero task discards progress and is never cancelled
+	Run(action Action, payload []byte, protocolVersion ProtocolVersion, task boshtask.Task) (value interface{}, err error)
 	Resume(action Action, payload []byte) (value interface{}, err error)
 }
 
@@ -19,7 +28,7 @@ func NewRunner() Runner {
 
 type concreteRunner struct{}
 
-func (r concreteRunner) Run(action Action, payloadBytes []byte, protocolVersion ProtocolVersion) (value interface{}, err error) {
+func (r concreteRunner) Run(action Action, payloadBytes []byte, protocolVersion ProtocolVersion, task boshtask.Task) (value interface{}, err error) {
 	payloadArgs, err := r.extractJSONArguments(payloadBytes)
 	if err != nil {
 		err = bosherr.WrapError(err, "Extracting json arguments")
@@ -39,7 +48,7 @@ func (r concreteRunner) Run(action Action, payloadBytes []byte, protocolVersion
 		return
 	}
 
-	methodArgs, err := r.extractMethodArgs(runMethodType, protocolVersion, payloadArgs)
+	methodArgs, err := r.extractMethodArgs(runMethodType, protocolVersion, task, payloadArgs)
 	if err != nil {
 		err = bosherr.WrapError(err, "Extracting method arguments from payload")
 		return
@@ -88,7 +97,7 @@ func (r concreteRunner) invalidReturnTypes(methodType reflect.Type) (valid bool)
 	return
 }
 
-func (r concreteRunner) extractMethodArgs(runMethodType reflect.Type, protocolVersion ProtocolVersion, args []interface{}) (methodArgs []reflect.Value, err error) {
+func (r concreteRunner) extractMethodArgs(runMethodType reflect.Type, protocolVersion ProtocolVersion, task boshtask.Task, args []interface{}) (methodArgs []reflect.Value, err error) {
 	numberOfArgs := runMethodType.NumIn()
 	numberOfReqArgs := numberOfArgs
 
@@ -108,6 +117,18 @@ func (r concreteRunner) extractMethodArgs(runMethodType reflect.Type, protocolVe
 		}
 	}
 
+	if numberOfArgs > argsOffset && runMethodType.In(argsOffset) == progressReporterType {
+		methodArgs = append(methodArgs, reflect.ValueOf(task))
+		numberOfReqArgs--
+		argsOffset++
+	}
+
+	if numberOfArgs > argsOffset && runMethodType.In(argsOffset) == cancelSignalType {
+		methodArgs = append(methodArgs, reflect.ValueOf(task.CancelSignal()))
+		numberOfReqArgs--
+		argsOffset++
+	}
+
 	if len(args) < numberOfReqArgs {
 		err = bosherr.Errorf("Not enough arguments, expected %d, got %d", numberOfReqArgs, len(args))
 		return
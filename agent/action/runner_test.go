@@ -2,7 +2,9 @@ package action_test
 
 import (
 	"errors"
+	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
 	"github.com/stretchr/testify/assert"
 
 	. "github.com/onsi/ginkgo"
@@ -10,6 +12,10 @@ import (
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
 	fakeaction "github.com/cloudfoundry/bosh-agent/agent/action/fakes"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
 )
 
 type valueType struct {
@@ -248,6 +254,73 @@ func (a *actionWithProtocolVersion) Cancel() error {
 	return nil
 }
 
+type actionWithProgressReporter struct {
+	ProtocolVersion ProtocolVersion
+	SubAction       string
+}
+
+func (a *actionWithProgressReporter) IsAsynchronous(_ ProtocolVersion) bool {
+	return true
+}
+
+func (a *actionWithProgressReporter) IsPersistent() bool {
+	return false
+}
+
+func (a *actionWithProgressReporter) IsLoggable() bool {
+	return true
+}
+
+func (a *actionWithProgressReporter) Run(protocolVersion ProtocolVersion, progress boshtask.ProgressReporter, subAction string) (valueType, error) {
+	a.ProtocolVersion = protocolVersion
+	a.SubAction = subAction
+
+	progress.ReportProgress(boshtask.Progress{Phase: subAction, Percent: 50})
+
+	return valueType{}, nil
+}
+
+func (a *actionWithProgressReporter) Resume() (interface{}, error) {
+	return nil, nil
+}
+
+func (a *actionWithProgressReporter) Cancel() error {
+	return nil
+}
+
+type actionWithCancelSignal struct {
+	Progress     boshtask.ProgressReporter
+	CancelSignal boshtask.CancelSignal
+	SubAction    string
+}
+
+func (a *actionWithCancelSignal) IsAsynchronous(_ ProtocolVersion) bool {
+	return true
+}
+
+func (a *actionWithCancelSignal) IsPersistent() bool {
+	return false
+}
+
+func (a *actionWithCancelSignal) IsLoggable() bool {
+	return true
+}
+
+func (a *actionWithCancelSignal) Run(progress boshtask.ProgressReporter, cancelSignal boshtask.CancelSignal, subAction string) (valueType, error) {
+	a.Progress = progress
+	a.CancelSignal = cancelSignal
+	a.SubAction = subAction
+	return valueType{}, nil
+}
+
+func (a *actionWithCancelSignal) Resume() (interface{}, error) {
+	return nil, nil
+}
+
+func (a *actionWithCancelSignal) Cancel() error {
+	return nil
+}
+
 var _ = Describe("concreteRunner", func() {
 	It("runner run parses the payload", func() {
 		runner := NewRunner()
@@ -266,7 +339,7 @@ var _ = Describe("concreteRunner", func() {
 				]
 			}`
 
-		value, err := runner.Run(action, []byte(payload), 0)
+		value, err := runner.Run(action, []byte(payload), 0, boshtask.Task{})
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(Equal("fake-run-error"))
 
@@ -287,7 +360,7 @@ var _ = Describe("concreteRunner", func() {
 		action := &actionWithGoodRunMethod{Value: expectedValue}
 		payload := `{"arguments":["setup"]}`
 
-		_, err := runner.Run(action, []byte(payload), 0)
+		_, err := runner.Run(action, []byte(payload), 0, boshtask.Task{})
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -299,7 +372,7 @@ var _ = Describe("concreteRunner", func() {
 		action := &actionWithGoodRunMethod{Value: expectedValue}
 		payload := `{"arguments":[123, "setup", {"user":"rob","pwd":"rob123","id":12}]}`
 
-		_, err := runner.Run(action, []byte(payload), 0)
+		_, err := runner.Run(action, []byte(payload), 0, boshtask.Task{})
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -320,7 +393,7 @@ var _ = Describe("concreteRunner", func() {
 					"bool_type":false
 				}]
 			}`
-		_, err := runner.Run(action, []byte(payload), 0)
+		_, err := runner.Run(action, []byte(payload), 0, boshtask.Task{})
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(action.Arg.IntType).To(Equal(int(-1024000)))
@@ -342,7 +415,7 @@ var _ = Describe("concreteRunner", func() {
 		action := &actionWithOptionalRunArgument{Value: expectedValue, Err: expectedErr}
 		payload := `{"arguments":["setup", {"user":"rob","pwd":"rob123","id":12}, {"user":"bob","pwd":"bob123","id":13}]}`
 
-		value, err := runner.Run(action, []byte(payload), 0)
+		value, err := runner.Run(action, []byte(payload), 0, boshtask.Task{})
 
 		Expect(value).To(Equal(expectedValue))
 		Expect(err).To(Equal(expectedErr))
@@ -359,7 +432,7 @@ var _ = Describe("concreteRunner", func() {
 		action := &actionWithOptionalRunArgument{}
 		payload := `{"arguments":["setup"]}`
 
-		runner.Run(action, []byte(payload), 0)
+		runner.Run(action, []byte(payload), 0, boshtask.Task{})
 
 		Expect(action.SubAction).To(Equal("setup"))
 		Expect(action.OptionalArgs).To(Equal([]argsType{}))
@@ -367,19 +440,19 @@ var _ = Describe("concreteRunner", func() {
 
 	It("runner run errs when action does not implement run", func() {
 		runner := NewRunner()
-		_, err := runner.Run(&actionWithoutRunMethod{}, []byte(`{"arguments":[]}`), 0)
+		_, err := runner.Run(&actionWithoutRunMethod{}, []byte(`{"arguments":[]}`), 0, boshtask.Task{})
 		Expect(err).To(HaveOccurred())
 	})
 
 	It("runner run errs when actions run does not return two values", func() {
 		runner := NewRunner()
-		_, err := runner.Run(&actionWithOneRunReturnValue{}, []byte(`{"arguments":[]}`), 0)
+		_, err := runner.Run(&actionWithOneRunReturnValue{}, []byte(`{"arguments":[]}`), 0, boshtask.Task{})
 		Expect(err).To(HaveOccurred())
 	})
 
 	It("runner run errs when actions run second return type is not error", func() {
 		runner := NewRunner()
-		_, err := runner.Run(&actionWithSecondReturnValueNotError{}, []byte(`{"arguments":[]}`), 0)
+		_, err := runner.Run(&actionWithSecondReturnValueNotError{}, []byte(`{"arguments":[]}`), 0, boshtask.Task{})
 		Expect(err).To(HaveOccurred())
 	})
 
@@ -405,20 +478,84 @@ var _ = Describe("concreteRunner", func() {
 		action := &actionWithProtocolVersion{}
 		payload := `{"arguments":["setup"]}`
 
-		_, err := runner.Run(action, []byte(payload), 1)
+		_, err := runner.Run(action, []byte(payload), 1, boshtask.Task{})
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(action.ProtocolVersion).To(Equal(ProtocolVersion(1)))
 		Expect(action.SubAction).To(Equal("setup"))
 	})
 
+	It("passes task as progress reporter to run method after protocol version", func() {
+		runner := NewRunner()
+		task := boshtask.Task{ProgressChan: make(chan boshtask.Progress, 1)}
+
+		action := &actionWithProgressReporter{}
+		payload := `{"arguments":["fake-phase"]}`
+
+		_, err := runner.Run(action, []byte(payload), 2, task)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(action.ProtocolVersion).To(Equal(ProtocolVersion(2)))
+		Expect(action.SubAction).To(Equal("fake-phase"))
+		Expect(task.ProgressChan).To(Receive(Equal(boshtask.Progress{Phase: "fake-phase", Percent: 50})))
+	})
+
+	It("passes cancel signal of the task to run method after progress reporter", func() {
+		runner := NewRunner()
+
+		taskService := boshtask.NewAsyncTaskService(
+			&fakeuuid.FakeGenerator{},
+			boshtask.NewConcurrencyPolicy(boshtask.Options{}),
+			faketask.NewFakeHistory(),
+			time.Hour,
+			fakeclock.NewFakeClock(time.Now()),
+			boshlog.NewLogger(boshlog.LevelNone),
+		)
+		task := taskService.CreateTaskWithID("fake-task-id", nil, nil, nil)
+
+		action := &actionWithCancelSignal{}
+		payload := `{"arguments":["fake-sub-action"]}`
+
+		_, err := runner.Run(action, []byte(payload), 2, task)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(action.Progress).To(Equal(task))
+		Expect(action.SubAction).To(Equal("fake-sub-action"))
+
+		Expect(action.CancelSignal).ToNot(BeClosed())
+		Expect(task.Cancel()).ToNot(HaveOccurred())
+		Expect(action.CancelSignal).To(BeClosed())
+	})
+
+	It("passes cancel signal that is never closed when task is not given", func() {
+		runner := NewRunner()
+
+		action := &actionWithCancelSignal{}
+		payload := `{"arguments":["fake-sub-action"]}`
+
+		_, err := runner.Run(action, []byte(payload), 2, boshtask.Task{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action.CancelSignal).To(BeNil())
+	})
+
+	It("passes a reporter that discards progress when no task is given", func() {
+		runner := NewRunner()
+
+		action := &actionWithProgressReporter{}
+		payload := `{"arguments":["fake-phase"]}`
+
+		_, err := runner.Run(action, []byte(payload), 2, boshtask.Task{})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(action.SubAction).To(Equal("fake-phase"))
+	})
+
 	It("passes protocol version to run method from request ProtocolVersion not the payload", func() {
 		runner := NewRunner()
 
 		action := &actionWithProtocolVersion{}
 		payload := `{"protocol":98,"arguments":["setup"]}`
 
-		_, err := runner.Run(action, []byte(payload), 1)
+		_, err := runner.Run(action, []byte(payload), 1, boshtask.Task{})
 		Expect(err).ToNot(HaveOccurred())
 
 		Expect(action.ProtocolVersion).To(Equal(ProtocolVersion(1)))
@@ -0,0 +1,49 @@
+package action
+
+import (
+	"errors"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type ThawDiskAction struct {
+	diskFreezer *DiskFreezer
+}
+
+func NewThawDisk(diskFreezer *DiskFreezer) (thawDisk ThawDiskAction) {
+	thawDisk.diskFreezer = diskFreezer
+	return
+}
+
+// IsAsynchronous is false so that thawing is not queued
+// behind tasks that may hang writing to the frozen disk
+func (a ThawDiskAction) IsAsynchronous(_ ProtocolVersion) bool {
+	return false
+}
+
+func (a ThawDiskAction) IsPersistent() bool {
+	return false
+}
+
+func (a ThawDiskAction) IsLoggable() bool {
+	return true
+}
+
+// Run does not look up diskCid since thawing
+// must succeed even if settings cannot be loaded
+func (a ThawDiskAction) Run(diskCid string) (interface{}, error) {
+	err := a.diskFreezer.Thaw()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Thawing persistent disk")
+	}
+
+	return map[string]string{}, nil
+}
+
+func (a ThawDiskAction) Resume() (interface{}, error) {
+	return nil, errors.New("not supported")
+}
+
+func (a ThawDiskAction) Cancel() error {
+	return errors.New("not supported")
+}
@@ -0,0 +1,73 @@
+package action_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/action"
+	fakeapplyspec "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
+	fakescript "github.com/cloudfoundry/bosh-agent/agent/script/fakes"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("ThawDiskAction", func() {
+	var (
+		platform    *fakeplatform.FakePlatform
+		diskFreezer *DiskFreezer
+		action      ThawDiskAction
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+
+		fakeJobScriptProvider := &fakescript.FakeJobScriptProvider{}
+		fakeJobScriptProvider.NewParallelScriptReturns(&fakescript.FakeCancellableScript{})
+
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		timeService := fakeclock.NewFakeClock(time.Now())
+		diskFreezer = NewDiskFreezer(platform, fakeJobScriptProvider, fakeapplyspec.NewFakeV1Service(), timeService, logger)
+
+		action = NewThawDisk(diskFreezer)
+	})
+
+	AssertActionIsNotAsynchronous(action)
+	AssertActionIsNotPersistent(action)
+	AssertActionIsLoggable(action)
+
+	AssertActionIsNotResumable(action)
+	AssertActionIsNotCancelable(action)
+
+	Describe("Run", func() {
+		It("thaws frozen persistent disk", func() {
+			err := diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+
+			result, err := action.Run("fake-disk-cid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal(map[string]string{}))
+			Expect(platform.ThawPersistentDiskMountPoints).To(Equal([]string{"/fake-store"}))
+		})
+
+		It("succeeds when persistent disk is not frozen", func() {
+			_, err := action.Run("fake-disk-cid")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platform.ThawPersistentDiskMountPoints).To(BeEmpty())
+		})
+
+		It("returns error when thawing fails", func() {
+			err := diskFreezer.Freeze("/fake-store", time.Minute)
+			Expect(err).NotTo(HaveOccurred())
+
+			platform.ThawPersistentDiskErr = errors.New("fake-thaw-err")
+
+			_, err = action.Run("fake-disk-cid")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-thaw-err"))
+		})
+	})
+})
@@ -35,10 +35,23 @@ func (a UnmountDiskAction) IsLoggable() bool {
 	return true
 }
 
+// Run accepts either disk CID or the name named disk was mounted with
 func (a UnmountDiskAction) Run(diskID string) (value interface{}, err error) {
 	settings := a.settingsService.GetSettings()
 
 	diskSettings, found := settings.PersistentDiskSettings(diskID)
+	if !found {
+		managedDisk, foundByName, findErr := a.platform.GetManagedDisks().FindByName(diskID)
+		if findErr != nil {
+			err = bosherr.WrapError(findErr, "Finding named persistent disk")
+			return
+		}
+
+		if foundByName {
+			diskSettings, found = settings.PersistentDiskSettings(managedDisk.DiskCID)
+		}
+	}
+
 	if !found {
 		err = bosherr.Errorf("Persistent disk with volume id '%s' could not be found", diskID)
 		return
@@ -64,7 +64,7 @@ var _ = Describe("UnmountDiskAction", func() {
 
 		result, err := action.Run("vol-123")
 		Expect(err).ToNot(HaveOccurred())
-		boshassert.MatchesJSONString(GinkgoT(), result, `{"message":"Unmounted partition of {ID:vol-123 DeviceID: VolumeID:2 Lun:0 HostDeviceID:fake-host-device-id Path:/dev/sdf FileSystemType:ext4 MountOptions:[]}"}`)
+		boshassert.MatchesJSONString(GinkgoT(), result, `{"message":"Unmounted partition of {ID:vol-123 DeviceID: VolumeID:2 Lun:0 HostDeviceID:fake-host-device-id Path:/dev/sdf FileSystemType:ext4 MountOptions:[] Encryption:{Type: Key: KeyPath:} MkfsOptions:[] FileSystemLabel:}"}`)
 
 		Expect(platform.UnmountPersistentDiskSettings).To(Equal(expectedDiskSettings))
 	})
@@ -74,7 +74,7 @@ var _ = Describe("UnmountDiskAction", func() {
 
 		result, err := action.Run("vol-123")
 		Expect(err).ToNot(HaveOccurred())
-		boshassert.MatchesJSONString(GinkgoT(), result, `{"message":"Partition of {ID:vol-123 DeviceID: VolumeID:2 Lun:0 HostDeviceID:fake-host-device-id Path:/dev/sdf FileSystemType:ext4 MountOptions:[]} is not mounted"}`)
+		boshassert.MatchesJSONString(GinkgoT(), result, `{"message":"Partition of {ID:vol-123 DeviceID: VolumeID:2 Lun:0 HostDeviceID:fake-host-device-id Path:/dev/sdf FileSystemType:ext4 MountOptions:[] Encryption:{Type: Key: KeyPath:} MkfsOptions:[] FileSystemLabel:} is not mounted"}`)
 
 		Expect(platform.UnmountPersistentDiskSettings).To(Equal(expectedDiskSettings))
 	})
@@ -83,4 +83,33 @@ var _ = Describe("UnmountDiskAction", func() {
 		_, err := action.Run("vol-456")
 		Expect(err).To(HaveOccurred())
 	})
+
+	It("unmounts named disk by the name it was mounted with", func() {
+		err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{
+			DiskCID:    "vol-123",
+			Name:       "wal",
+			MountPoint: "/var/vcap/store/wal",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		platform.UnmountPersistentDiskDidUnmount = true
+
+		_, err = action.Run("wal")
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(platform.UnmountPersistentDiskSettings).To(Equal(expectedDiskSettings))
+	})
+
+	It("returns error when named disk is no longer attached", func() {
+		err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{
+			DiskCID:    "vol-456",
+			Name:       "wal",
+			MountPoint: "/var/vcap/store/wal",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = action.Run("wal")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Persistent disk with volume id 'wal' could not be found"))
+	})
 })
@@ -65,12 +65,26 @@ func (a UpdateSettingsAction) Run(newUpdateSettings boshsettings.UpdateSettings)
 		return "", err
 	}
 
+	updateSettingsPath := filepath.Join(a.platform.GetDirProvider().BoshDir(), "update_settings.json")
+
+	if newUpdateSettings.Swap != nil {
+		err = a.platform.SetupSwap(currentSettings.Env.ResolveSwap(newUpdateSettings.Swap))
+		if err != nil {
+			return "", bosherr.WrapError(err, "Setting up swap")
+		}
+	} else {
+		// Director does not send swap so swap updated earlier has to be kept
+		newUpdateSettings.Swap, err = a.previousSwap(updateSettingsPath)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	updateSettingsJSON, err := json.Marshal(newUpdateSettings)
 	if err != nil {
 		return "", bosherr.WrapError(err, "Marshalling updateSettings json")
 	}
 
-	updateSettingsPath := filepath.Join(a.platform.GetDirProvider().BoshDir(), "update_settings.json")
 	err = a.platform.GetFs().WriteFile(updateSettingsPath, updateSettingsJSON)
 	if err != nil {
 		return "", bosherr.WrapError(err, "writing update settings json")
@@ -79,6 +93,26 @@ func (a UpdateSettingsAction) Run(newUpdateSettings boshsettings.UpdateSettings)
 	return "updated", nil
 }
 
+func (a UpdateSettingsAction) previousSwap(updateSettingsPath string) (*boshsettings.Swap, error) {
+	if !a.platform.GetFs().FileExists(updateSettingsPath) {
+		return nil, nil
+	}
+
+	contents, err := a.platform.GetFs().ReadFile(updateSettingsPath)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading update_settings.json")
+	}
+
+	var previousUpdateSettings boshsettings.UpdateSettings
+
+	err = json.Unmarshal(contents, &previousUpdateSettings)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling update_settings.json")
+	}
+
+	return previousUpdateSettings.Swap, nil
+}
+
 func (a UpdateSettingsAction) Resume() (interface{}, error) {
 	return nil, errors.New("not supported")
 }
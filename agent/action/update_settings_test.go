@@ -8,6 +8,7 @@ import (
 
 	. "github.com/cloudfoundry/bosh-agent/agent/action"
 	"github.com/cloudfoundry/bosh-agent/platform/cert/fakes"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
@@ -83,6 +84,108 @@ var _ = Describe("UpdateSettings", func() {
 		})
 	})
 
+	Context("when swap is given", func() {
+		var swapSizeInMB uint64
+
+		BeforeEach(func() {
+			swapSizeInMB = 512
+			newUpdateSettings.Swap = &boshsettings.Swap{Type: boshdisk.SwapTypeFile, SizeInMB: &swapSizeInMB}
+		})
+
+		It("sets up swap", func() {
+			_, err := action.Run(newUpdateSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.SetupSwapSwaps).To(Equal([]boshsettings.Swap{*newUpdateSettings.Swap}))
+		})
+
+		It("keeps swap in the updated settings file so that it is set up after reboot", func() {
+			_, err := action.Run(newUpdateSettings)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := platform.GetFs().ReadFileString(filepath.Join(platform.GetDirProvider().BoshDir(), "update_settings.json"))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(ContainSubstring(`"swap":{"type":"file","size":512}`))
+		})
+
+		It("returns error when swap cannot be set up", func() {
+			platform.SetupSwapErr = errors.New("fake-swap-err")
+
+			_, err := action.Run(newUpdateSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Setting up swap: fake-swap-err"))
+		})
+	})
+
+	Context("when swap is given without a type", func() {
+		BeforeEach(func() {
+			envSwapSizeInMB := uint64(1024)
+			settingsService.Settings.Env.Bosh.SwapType = boshdisk.SwapTypeZram
+			settingsService.Settings.Env.Bosh.SwapSizeInMB = &envSwapSizeInMB
+
+			swapSizeInMB := uint64(512)
+			newUpdateSettings.Swap = &boshsettings.Swap{SizeInMB: &swapSizeInMB}
+		})
+
+		It("sets up swap of the type from env", func() {
+			_, err := action.Run(newUpdateSettings)
+			Expect(err).ToNot(HaveOccurred())
+
+			swapSizeInMB := uint64(512)
+			Expect(platform.SetupSwapSwaps).To(Equal([]boshsettings.Swap{
+				{Type: boshdisk.SwapTypeZram, SizeInMB: &swapSizeInMB},
+			}))
+		})
+	})
+
+	It("does not set up swap when it is not given", func() {
+		_, err := action.Run(newUpdateSettings)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(platform.SetupSwapSwaps).To(BeEmpty())
+	})
+
+	Context("when swap was updated before", func() {
+		var updateSettingsPath string
+
+		BeforeEach(func() {
+			updateSettingsPath = filepath.Join(platform.GetDirProvider().BoshDir(), "update_settings.json")
+			err := platform.GetFs().WriteFileString(updateSettingsPath, `{"trusted_certs":"fake-old-cert","swap":{"type":"zram","size":256}}`)
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("keeps previously updated swap when swap is not given", func() {
+			newUpdateSettings.TrustedCerts = "fake-new-cert"
+
+			_, err := action.Run(newUpdateSettings)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(platform.SetupSwapSwaps).To(BeEmpty())
+
+			contents, err := platform.GetFs().ReadFileString(updateSettingsPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(ContainSubstring(`"trusted_certs":"fake-new-cert"`))
+			Expect(contents).To(ContainSubstring(`"swap":{"type":"zram","size":256}`))
+		})
+
+		It("replaces previously updated swap when swap is given", func() {
+			swapSizeInMB := uint64(512)
+			newUpdateSettings.Swap = &boshsettings.Swap{Type: boshdisk.SwapTypeFile, SizeInMB: &swapSizeInMB}
+
+			_, err := action.Run(newUpdateSettings)
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := platform.GetFs().ReadFileString(updateSettingsPath)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(ContainSubstring(`"swap":{"type":"file","size":512}`))
+		})
+
+		It("returns error when previously updated settings cannot be read", func() {
+			platform.Fs.RegisterReadFileError(updateSettingsPath, errors.New("fake-read-err"))
+
+			_, err := action.Run(newUpdateSettings)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-read-err"))
+		})
+	})
+
 	It("loads settings", func() {
 		_, err := action.Run(newUpdateSettings)
 		Expect(err).ToNot(HaveOccurred())
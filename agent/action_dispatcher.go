@@ -69,6 +69,7 @@ func (dispatcher concreteActionDispatcher) ResumePreviouslyDispatchedTasks() {
 			func(_ boshtask.Task) error { return action.Cancel() },
 			dispatcher.removeInfo,
 		)
+		task.Method = taskInfo.Method
 
 		dispatcher.taskService.StartTask(task)
 	}
@@ -102,8 +103,9 @@ func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 	var task boshtask.Task
 	var err error
 
+	// Task is assigned before it is started, so runTask reports progress to the created task
 	runTask := func() (interface{}, error) {
-		return dispatcher.actionRunner.Run(action, req.GetPayload(), boshaction.ProtocolVersion(req.ProtocolVersion))
+		return dispatcher.actionRunner.Run(action, req.GetPayload(), boshaction.ProtocolVersion(req.ProtocolVersion), task)
 	}
 
 	cancelTask := func(_ boshtask.Task) error { return action.Cancel() }
@@ -141,6 +143,8 @@ func (dispatcher concreteActionDispatcher) dispatchAsynchronousAction(
 		}
 	}
 
+	task.Method = req.Method
+
 	dispatcher.taskService.StartTask(task)
 
 	return boshhandler.NewValueResponse(boshtask.StateValue{
@@ -155,7 +159,7 @@ func (dispatcher concreteActionDispatcher) dispatchSynchronousAction(
 ) boshhandler.Response {
 	dispatcher.logger.Info(actionDispatcherLogTag, "Running sync action %s", req.Method)
 
-	value, err := dispatcher.actionRunner.Run(action, req.GetPayload(), boshaction.ProtocolVersion(req.ProtocolVersion))
+	value, err := dispatcher.actionRunner.Run(action, req.GetPayload(), boshaction.ProtocolVersion(req.ProtocolVersion), boshtask.Task{})
 	if err != nil {
 		err = bosherr.WrapErrorf(err, "Action Failed %s", req.Method)
 		dispatcher.logger.Error(actionDispatcherLogTag, err.Error())
@@ -329,6 +329,20 @@ func init() {
 
 				ItAllowsToCancelTask()
 
+				It("passes the task to the action runner so that the action can report progress", func() {
+					dispatcher.Dispatch(req)
+
+					_, err := taskService.StartedTasks["fake-generated-task-id"].Func()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(actionRunner.RunTask.ID).To(Equal("fake-generated-task-id"))
+				})
+
+				It("records the request method on the task so that it can be scheduled", func() {
+					dispatcher.Dispatch(req)
+					Expect(taskService.StartedTasks["fake-generated-task-id"].Method).To(Equal("fake-action"))
+				})
+
 				It("adds task to task manager before task starts so that it could be resumed if agent is restarted", func() {
 					dispatcher.Dispatch(req)
 					taskInfos, _ := taskManager.GetInfos()
@@ -389,6 +403,8 @@ func init() {
 
 				dispatcher.ResumePreviouslyDispatchedTasks()
 				Expect(len(taskService.StartedTasks)).To(Equal(2))
+				Expect(taskService.StartedTasks["fake-task-id-1"].Method).To(Equal("fake-action-1"))
+				Expect(taskService.StartedTasks["fake-task-id-2"].Method).To(Equal("fake-action-2"))
 
 				{ // Check that first task executes first action
 					actionRunner.ResumeValue = "fake-resume-value-1"
@@ -23,6 +23,7 @@ const (
 type Agent struct {
 	logger            boshlog.Logger
 	mbusHandler       boshhandler.Handler
+	alertSink         boshalert.Sink
 	platform          boshplatform.Platform
 	actionDispatcher  ActionDispatcher
 	heartbeatInterval time.Duration
@@ -31,11 +32,14 @@ type Agent struct {
 	settingsService   boshsettings.Service
 	uuidGenerator     boshuuid.Generator
 	timeService       clock.Clock
+
+	networkDriftReconciler *NetworkDriftReconciler
 }
 
 func New(
 	logger boshlog.Logger,
 	mbusHandler boshhandler.Handler,
+	alertSink boshalert.Sink,
 	platform boshplatform.Platform,
 	actionDispatcher ActionDispatcher,
 	jobSupervisor boshjobsuper.JobSupervisor,
@@ -44,10 +48,12 @@ func New(
 	settingsService boshsettings.Service,
 	uuidGenerator boshuuid.Generator,
 	timeService clock.Clock,
+	networkDriftReconciler *NetworkDriftReconciler,
 ) Agent {
 	return Agent{
 		logger:            logger,
 		mbusHandler:       mbusHandler,
+		alertSink:         alertSink,
 		platform:          platform,
 		actionDispatcher:  actionDispatcher,
 		heartbeatInterval: heartbeatInterval,
@@ -56,6 +62,8 @@ func New(
 		settingsService:   settingsService,
 		uuidGenerator:     uuidGenerator,
 		timeService:       timeService,
+
+		networkDriftReconciler: networkDriftReconciler,
 	}
 }
 
@@ -68,6 +76,17 @@ func (a Agent) Run() error {
 
 	go a.generateHeartbeats(errCh)
 
+	networkDriftStopCh := make(chan struct{})
+	defer close(networkDriftStopCh)
+
+	go a.networkDriftReconciler.Run(networkDriftStopCh)
+
+	// Disks are mounted by bootstrap by now
+	diskTrimStopCh := make(chan struct{})
+	defer close(diskTrimStopCh)
+
+	a.platform.StartDiskTrimming(diskTrimStopCh)
+
 	go func() {
 		err := a.jobSupervisor.MonitorJobFailures(a.handleJobFailure(errCh))
 		if err != nil {
@@ -98,6 +117,8 @@ func (a Agent) generateHeartbeats(errCh chan error) {
 
 	// Send initial heartbeat
 	a.sendAndRecordHeartbeat(errCh)
+	a.sendFilesystemCheckAlerts(errCh)
+	a.sendNetworkDriftAlerts(errCh)
 
 	tickChan := time.Tick(a.heartbeatInterval)
 
@@ -105,10 +126,86 @@ func (a Agent) generateHeartbeats(errCh chan error) {
 		select {
 		case <-tickChan:
 			a.sendAndRecordHeartbeat(errCh)
+			a.sendFilesystemCheckAlerts(errCh)
+			a.sendNetworkDriftAlerts(errCh)
+		}
+	}
+}
+
+// sendFilesystemCheckAlerts reports errors found on persistent disk
+// filesystems before they were mounted; each check is reported once
+func (a Agent) sendFilesystemCheckAlerts(errCh chan error) {
+	history := a.platform.GetFilesystemCheckHistory()
+
+	checks, err := history.List()
+	if err != nil {
+		a.logger.Error(agentLogTag, "Listing filesystem checks: %s", err.Error())
+		return
+	}
+
+	for _, check := range checks {
+		alertAdapter := boshalert.NewFilesystemCheckAdapter(check, a.uuidGenerator)
+		if alertAdapter.IsIgnorable() {
+			continue
+		}
+
+		alert, err := alertAdapter.Alert()
+		if err != nil {
+			a.logger.Error(agentLogTag, "Adapting filesystem check alert: %s", err.Error())
+			continue
+		}
+
+		err = a.alertSink.Send(alert)
+		if err != nil {
+			a.logger.Error(agentLogTag, "Sending filesystem check alert to sinks: %s", err.Error())
+		}
+
+		err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Alert, alert)
+		if err != nil {
+			errCh <- bosherr.WrapError(err, "Sending filesystem check alert")
+			return
+		}
+
+		err = history.MarkAlerted(check.DiskCID)
+		if err != nil {
+			a.logger.Error(agentLogTag, "Marking filesystem check as alerted: %s", err.Error())
 		}
 	}
 }
 
+// sendNetworkDriftAlerts reports drift found by the last network check;
+// drift is reported again only after it was repaired or went away
+func (a Agent) sendNetworkDriftAlerts(errCh chan error) {
+	check, found := a.networkDriftReconciler.LastCheck()
+	if !found {
+		return
+	}
+
+	alertAdapter := boshalert.NewNetworkDriftAdapter(check, a.uuidGenerator)
+	if alertAdapter.IsIgnorable() {
+		return
+	}
+
+	alert, err := alertAdapter.Alert()
+	if err != nil {
+		a.logger.Error(agentLogTag, "Adapting network drift alert: %s", err.Error())
+		return
+	}
+
+	err = a.alertSink.Send(alert)
+	if err != nil {
+		a.logger.Error(agentLogTag, "Sending network drift alert to sinks: %s", err.Error())
+	}
+
+	err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Alert, alert)
+	if err != nil {
+		errCh <- bosherr.WrapError(err, "Sending network drift alert")
+		return
+	}
+
+	a.networkDriftReconciler.MarkAlerted(check.CheckedAt)
+}
+
 func (a Agent) sendAndRecordHeartbeat(errCh chan error) {
 	status := a.jobSupervisor.Status()
 	heartbeat, err := a.getHeartbeat(status)
@@ -170,6 +267,12 @@ func (a Agent) handleJobFailure(errCh chan error) boshjobsuper.JobFailureHandler
 			errCh <- bosherr.WrapError(err, "Adapting monit alert")
 		}
 
+		// Sinks keep alerts that message bus may not deliver, e.g. HTTPS mbus drops them
+		err = a.alertSink.Send(alert)
+		if err != nil {
+			a.logger.Error(agentLogTag, "Sending monit alert to sinks: %s", err.Error())
+		}
+
 		err = a.mbusHandler.Send(boshhandler.HealthMonitor, boshhandler.Alert, alert)
 		if err != nil {
 			errCh <- bosherr.WrapError(err, "Sending monit alert")
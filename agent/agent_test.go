@@ -11,13 +11,16 @@ import (
 
 	"code.cloudfoundry.org/clock/fakeclock"
 	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	fakealert "github.com/cloudfoundry/bosh-agent/agent/alert/fakes"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	fakeas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec/fakes"
 	fakeagent "github.com/cloudfoundry/bosh-agent/agent/fakes"
 	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
 	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
 	fakembus "github.com/cloudfoundry/bosh-agent/mbus/fakes"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
@@ -29,6 +32,7 @@ func init() {
 		var (
 			logger           boshlog.Logger
 			handler          *fakembus.FakeHandler
+			alertSink        *fakealert.FakeSink
 			platform         *fakeplatform.FakePlatform
 			actionDispatcher *fakeagent.FakeActionDispatcher
 			jobSupervisor    *fakejobsuper.FakeJobSupervisor
@@ -36,12 +40,14 @@ func init() {
 			settingsService  *fakesettings.FakeSettingsService
 			uuidGenerator    *fakeuuid.FakeGenerator
 			timeService      *fakeclock.FakeClock
+			driftReconciler  *NetworkDriftReconciler
 			agent            Agent
 		)
 
 		BeforeEach(func() {
 			logger = boshlog.NewLogger(boshlog.LevelNone)
 			handler = &fakembus.FakeHandler{}
+			alertSink = &fakealert.FakeSink{}
 			platform = fakeplatform.NewFakePlatform()
 			actionDispatcher = &fakeagent.FakeActionDispatcher{}
 			jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
@@ -49,9 +55,11 @@ func init() {
 			settingsService = &fakesettings.FakeSettingsService{}
 			uuidGenerator = &fakeuuid.FakeGenerator{}
 			timeService = fakeclock.NewFakeClock(time.Now())
+			driftReconciler = NewNetworkDriftReconciler(platform, settingsService, nil, 0, false, timeService, logger)
 			agent = New(
 				logger,
 				handler,
+				alertSink,
 				platform,
 				actionDispatcher,
 				jobSupervisor,
@@ -60,6 +68,7 @@ func init() {
 				settingsService,
 				uuidGenerator,
 				timeService,
+				driftReconciler,
 			)
 		})
 
@@ -89,6 +98,14 @@ func init() {
 				Expect(resumedBeforeStartingToDispatch).To(BeTrue())
 			})
 
+			It("starts disk trimming and stops it once agent stops running", func() {
+				err := agent.Run()
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(platform.StartDiskTrimmingStopCh).ToNot(BeNil())
+				Expect(platform.StartDiskTrimmingStopCh).To(BeClosed())
+			})
+
 			Context("when heartbeats can be sent", func() {
 				BeforeEach(func() {
 					handler.KeepOnRunning()
@@ -131,6 +148,7 @@ func init() {
 					agent = New(
 						logger,
 						handler,
+						alertSink,
 						platform,
 						actionDispatcher,
 						jobSupervisor,
@@ -139,6 +157,7 @@ func init() {
 						settingsService,
 						uuidGenerator,
 						timeService,
+						driftReconciler,
 					)
 
 					// Immediately exit after sending initial heartbeat
@@ -248,6 +267,154 @@ func init() {
 					Topic:   boshhandler.Alert,
 					Message: expectedAlert,
 				}))
+				Expect(alertSink.SentAlerts()).To(Equal([]boshalert.Alert{expectedAlert}))
+			})
+
+			It("sends filesystem check alerts to health manager once", func() {
+				handler.KeepOnRunning()
+
+				history := platform.GetFilesystemCheckHistory()
+				err := history.Record(boshdisk.FilesystemCheck{
+					DiskCID:        "fake-disk-cid",
+					CheckedAt:      1306076861,
+					FileSystemType: boshdisk.FileSystemExt4,
+					ErrorsFound:    true,
+					Repaired:       true,
+				})
+				Expect(err).ToNot(HaveOccurred())
+
+				uuidGenerator.GeneratedUUID = "fake-alert-id"
+
+				sentHeartbeats := 0
+				handler.SendCallback = func(input fakembus.SendInput) {
+					if input.Topic == boshhandler.Heartbeat {
+						sentHeartbeats++
+						if sentHeartbeats == 3 {
+							handler.SendErr = errors.New("stop")
+						}
+					}
+				}
+
+				err = agent.Run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("stop"))
+
+				expectedAlert := boshalert.Alert{
+					ID:        "fake-alert-id",
+					Severity:  boshalert.SeverityWarning,
+					Title:     "Filesystem on persistent disk fake-disk-cid was repaired",
+					Summary:   "ext4 filesystem errors were repaired before mounting",
+					CreatedAt: int64(1306076861),
+				}
+
+				var sentAlerts []interface{}
+				for _, input := range handler.SendInputs() {
+					if input.Topic == boshhandler.Alert {
+						sentAlerts = append(sentAlerts, input.Message)
+					}
+				}
+				Expect(sentAlerts).To(Equal([]interface{}{expectedAlert}))
+				Expect(alertSink.SentAlerts()).To(Equal([]boshalert.Alert{expectedAlert}))
+
+				checks, err := history.List()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(checks[0].Alerted).To(BeTrue())
+			})
+
+			It("sends network drift alerts to health manager once", func() {
+				handler.KeepOnRunning()
+
+				timeService = fakeclock.NewFakeClock(time.Unix(1306076861, 0))
+				driftReconciler = NewNetworkDriftReconciler(platform, settingsService, nil, 0, false, timeService, logger)
+				agent = New(
+					logger,
+					handler,
+					alertSink,
+					platform,
+					actionDispatcher,
+					jobSupervisor,
+					specService,
+					5*time.Millisecond,
+					settingsService,
+					uuidGenerator,
+					timeService,
+					driftReconciler,
+				)
+
+				platform.DetectNetworkDriftDrift = boshnet.Drift{ConfigsChanged: true}
+				driftReconciler.Reconcile(false)
+
+				uuidGenerator.GeneratedUUID = "fake-alert-id"
+
+				sentHeartbeats := 0
+				handler.SendCallback = func(input fakembus.SendInput) {
+					if input.Topic == boshhandler.Heartbeat {
+						sentHeartbeats++
+						if sentHeartbeats == 3 {
+							handler.SendErr = errors.New("stop")
+						}
+					}
+				}
+
+				err := agent.Run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("stop"))
+
+				expectedAlert := boshalert.Alert{
+					ID:        "fake-alert-id",
+					Severity:  boshalert.SeverityCritical,
+					Title:     "Network configuration drifted",
+					Summary:   "network configuration files were modified",
+					CreatedAt: int64(1306076861),
+				}
+
+				var sentAlerts []interface{}
+				for _, input := range handler.SendInputs() {
+					if input.Topic == boshhandler.Alert {
+						sentAlerts = append(sentAlerts, input.Message)
+					}
+				}
+				Expect(sentAlerts).To(Equal([]interface{}{expectedAlert}))
+				Expect(alertSink.SentAlerts()).To(Equal([]boshalert.Alert{expectedAlert}))
+
+				check, found := driftReconciler.LastCheck()
+				Expect(found).To(BeTrue())
+				Expect(check.Alerted).To(BeTrue())
+			})
+
+			It("keeps sending alerts to health manager when alert sinks fail", func() {
+				handler.KeepOnRunning()
+
+				jobSupervisor.JobFailureAlert = &boshalert.MonitAlert{
+					ID:      "fake-monit-alert",
+					Service: "fake-service",
+					Event:   "fake-event",
+					Action:  "fake-action",
+					Date:    "Sun, 22 May 2011 20:07:41 +0500",
+				}
+
+				alertSink.SendErr = errors.New("fake-sink-err")
+
+				handler.SendCallback = func(input fakembus.SendInput) {
+					if input.Topic == boshhandler.Alert {
+						handler.SendErr = errors.New("stop")
+					}
+				}
+
+				err := agent.Run()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("stop"))
+				Expect(err.Error()).ToNot(ContainSubstring("fake-sink-err"))
+
+				Expect(alertSink.SentAlerts()).To(HaveLen(1))
+
+				var alertTopics int
+				for _, input := range handler.SendInputs() {
+					if input.Topic == boshhandler.Alert {
+						alertTopics++
+					}
+				}
+				Expect(alertTopics).To(Equal(1))
 			})
 		})
 	})
@@ -0,0 +1,49 @@
+package alert
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const asyncSinkLogTag = "asyncSink"
+
+type asyncSink struct {
+	sink   Sink
+	queue  chan Alert
+	logger boshlog.Logger
+}
+
+// NewAsyncSink delivers alerts to sink in the background so that slow
+// destinations do not hold up the agent; alerts are rejected
+// while queueSize alerts are waiting to be delivered
+func NewAsyncSink(sink Sink, queueSize int, logger boshlog.Logger) Sink {
+	s := asyncSink{
+		sink:   sink,
+		queue:  make(chan Alert, queueSize),
+		logger: logger,
+	}
+
+	go s.deliver()
+
+	return s
+}
+
+func (s asyncSink) Send(alert Alert) error {
+	select {
+	case s.queue <- alert:
+		return nil
+	default:
+		return bosherr.Errorf("Dropping alert '%s' since alert queue is full", alert.ID)
+	}
+}
+
+func (s asyncSink) deliver() {
+	defer s.logger.HandlePanic("Async Alert Sink")
+
+	for alert := range s.queue {
+		err := s.sink.Send(alert)
+		if err != nil {
+			s.logger.Error(asyncSinkLogTag, "Delivering alert '%s': %s", alert.ID, err.Error())
+		}
+	}
+}
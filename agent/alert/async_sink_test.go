@@ -0,0 +1,67 @@
+package alert_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	fakealert "github.com/cloudfoundry/bosh-agent/agent/alert/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type blockingSink struct {
+	received chan Alert
+	release  chan struct{}
+}
+
+func (s blockingSink) Send(alert Alert) error {
+	s.received <- alert
+	<-s.release
+	return nil
+}
+
+var _ = Describe("asyncSink", func() {
+	var logger boshlog.Logger
+
+	BeforeEach(func() {
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+	})
+
+	It("delivers alerts in the order they were sent", func() {
+		sink := &fakealert.FakeSink{}
+		asyncSink := NewAsyncSink(sink, 10, logger)
+
+		Expect(asyncSink.Send(Alert{ID: "fake-id-1"})).To(Succeed())
+		Expect(asyncSink.Send(Alert{ID: "fake-id-2"})).To(Succeed())
+
+		Eventually(sink.SentAlerts).Should(Equal([]Alert{{ID: "fake-id-1"}, {ID: "fake-id-2"}}))
+	})
+
+	It("keeps delivering alerts after sink fails", func() {
+		sink := &fakealert.FakeSink{SendErr: errors.New("fake-send-err")}
+		asyncSink := NewAsyncSink(sink, 10, logger)
+
+		Expect(asyncSink.Send(Alert{ID: "fake-id-1"})).To(Succeed())
+		Expect(asyncSink.Send(Alert{ID: "fake-id-2"})).To(Succeed())
+
+		Eventually(sink.SentAlerts).Should(HaveLen(2))
+	})
+
+	It("does not wait for alerts to be delivered and rejects alerts once queue is full", func() {
+		sink := blockingSink{received: make(chan Alert, 10), release: make(chan struct{})}
+		defer close(sink.release)
+
+		asyncSink := NewAsyncSink(sink, 1, logger)
+
+		Expect(asyncSink.Send(Alert{ID: "fake-id-1"})).To(Succeed())
+		Eventually(sink.received).Should(Receive(Equal(Alert{ID: "fake-id-1"})))
+
+		Expect(asyncSink.Send(Alert{ID: "fake-id-2"})).To(Succeed())
+
+		err := asyncSink.Send(Alert{ID: "fake-id-3"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Dropping alert 'fake-id-3' since alert queue is full"))
+	})
+})
@@ -0,0 +1,29 @@
+package fakes
+
+import (
+	"sync"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+)
+
+type FakeSink struct {
+	SendErr error
+
+	sentAlerts []boshalert.Alert
+	lock       sync.Mutex
+}
+
+func (s *FakeSink) Send(alert boshalert.Alert) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.sentAlerts = append(s.sentAlerts, alert)
+	return s.SendErr
+}
+
+func (s *FakeSink) SentAlerts() []boshalert.Alert {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]boshalert.Alert{}, s.sentAlerts...)
+}
@@ -0,0 +1,49 @@
+package alert
+
+import (
+	"fmt"
+
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+)
+
+type filesystemCheckAdapter struct {
+	check         boshdisk.FilesystemCheck
+	uuidGenerator boshuuid.Generator
+}
+
+func NewFilesystemCheckAdapter(check boshdisk.FilesystemCheck, uuidGenerator boshuuid.Generator) Adapter {
+	return filesystemCheckAdapter{
+		check:         check,
+		uuidGenerator: uuidGenerator,
+	}
+}
+
+// IsIgnorable is true for clean filesystems and for checks that were already reported
+func (a filesystemCheckAdapter) IsIgnorable() bool {
+	return !a.check.ErrorsFound || a.check.Alerted
+}
+
+func (a filesystemCheckAdapter) Alert() (Alert, error) {
+	id, err := a.uuidGenerator.Generate()
+	if err != nil {
+		return Alert{}, bosherr.WrapError(err, "Generating alert id")
+	}
+
+	alert := Alert{
+		ID:        id,
+		Severity:  SeverityCritical,
+		Title:     fmt.Sprintf("Filesystem errors found on persistent disk %s", a.check.DiskCID),
+		Summary:   fmt.Sprintf("%s filesystem has errors that were not repaired", a.check.FileSystemType),
+		CreatedAt: a.check.CheckedAt,
+	}
+
+	if a.check.Repaired {
+		alert.Severity = SeverityWarning
+		alert.Title = fmt.Sprintf("Filesystem on persistent disk %s was repaired", a.check.DiskCID)
+		alert.Summary = fmt.Sprintf("%s filesystem errors were repaired before mounting", a.check.FileSystemType)
+	}
+
+	return alert, nil
+}
@@ -0,0 +1,81 @@
+package alert_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+)
+
+var _ = Describe("filesystemCheckAdapter", func() {
+	var (
+		check         boshdisk.FilesystemCheck
+		uuidGenerator *fakeuuid.FakeGenerator
+	)
+
+	BeforeEach(func() {
+		check = boshdisk.FilesystemCheck{
+			DiskCID:        "fake-disk-cid",
+			CheckedAt:      1306076861,
+			FileSystemType: boshdisk.FileSystemExt4,
+			ErrorsFound:    true,
+		}
+		uuidGenerator = &fakeuuid.FakeGenerator{GeneratedUUID: "fake-uuid"}
+	})
+
+	Describe("IsIgnorable", func() {
+		It("ignores clean filesystems", func() {
+			check.ErrorsFound = false
+			Expect(NewFilesystemCheckAdapter(check, uuidGenerator).IsIgnorable()).To(BeTrue())
+		})
+
+		It("ignores checks that were already alerted", func() {
+			check.Alerted = true
+			Expect(NewFilesystemCheckAdapter(check, uuidGenerator).IsIgnorable()).To(BeTrue())
+		})
+
+		It("does not ignore found errors", func() {
+			Expect(NewFilesystemCheckAdapter(check, uuidGenerator).IsIgnorable()).To(BeFalse())
+		})
+	})
+
+	Describe("Alert", func() {
+		It("returns critical alert when errors were not repaired", func() {
+			alert, err := NewFilesystemCheckAdapter(check, uuidGenerator).Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityCritical,
+				Title:     "Filesystem errors found on persistent disk fake-disk-cid",
+				Summary:   "ext4 filesystem has errors that were not repaired",
+				CreatedAt: 1306076861,
+			}))
+		})
+
+		It("returns warning alert when errors were repaired", func() {
+			check.Repaired = true
+
+			alert, err := NewFilesystemCheckAdapter(check, uuidGenerator).Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityWarning,
+				Title:     "Filesystem on persistent disk fake-disk-cid was repaired",
+				Summary:   "ext4 filesystem errors were repaired before mounting",
+				CreatedAt: 1306076861,
+			}))
+		})
+
+		It("returns error when alert id cannot be generated", func() {
+			uuidGenerator.GenerateError = errors.New("fake-uuid-err")
+
+			_, err := NewFilesystemCheckAdapter(check, uuidGenerator).Alert()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-uuid-err"))
+		})
+	})
+})
@@ -0,0 +1,84 @@
+package alert
+
+import (
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// syslogWriteAttempts allows one reconnect when connection
+// to syslog was dropped since it was last written to
+const syslogWriteAttempts = 2
+
+type lazySyslogWriter struct {
+	dial   func() (SyslogWriter, error)
+	writer SyslogWriter
+	lock   sync.Mutex
+}
+
+// NewLazySyslogWriter connects to syslog on first write instead of when agent starts
+// so that alert sinks do not depend on syslog daemon being up before agent;
+// failed writes are retried with a new connection
+func NewLazySyslogWriter(dial func() (SyslogWriter, error)) SyslogWriter {
+	return &lazySyslogWriter{dial: dial}
+}
+
+func (w *lazySyslogWriter) Alert(m string) error {
+	return w.write(func(writer SyslogWriter) error { return writer.Alert(m) })
+}
+
+func (w *lazySyslogWriter) Crit(m string) error {
+	return w.write(func(writer SyslogWriter) error { return writer.Crit(m) })
+}
+
+func (w *lazySyslogWriter) Err(m string) error {
+	return w.write(func(writer SyslogWriter) error { return writer.Err(m) })
+}
+
+func (w *lazySyslogWriter) Warning(m string) error {
+	return w.write(func(writer SyslogWriter) error { return writer.Warning(m) })
+}
+
+func (w *lazySyslogWriter) Close() error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.disconnect()
+}
+
+func (w *lazySyslogWriter) write(writeFunc func(SyslogWriter) error) error {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	var err error
+
+	for i := 0; i < syslogWriteAttempts; i++ {
+		if w.writer == nil {
+			w.writer, err = w.dial()
+			if err != nil {
+				err = bosherr.WrapError(err, "Connecting to syslog")
+				continue
+			}
+		}
+
+		err = writeFunc(w.writer)
+		if err == nil {
+			return nil
+		}
+
+		_ = w.disconnect()
+	}
+
+	return err
+}
+
+func (w *lazySyslogWriter) disconnect() error {
+	if w.writer == nil {
+		return nil
+	}
+
+	err := w.writer.Close()
+	w.writer = nil
+
+	return err
+}
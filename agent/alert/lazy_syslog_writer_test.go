@@ -0,0 +1,87 @@
+package alert_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+)
+
+var _ = Describe("lazySyslogWriter", func() {
+	var (
+		dialedWriters []*fakeSyslogWriter
+		dialErrs      []error
+		writer        SyslogWriter
+	)
+
+	BeforeEach(func() {
+		dialedWriters = nil
+		dialErrs = nil
+
+		writer = NewLazySyslogWriter(func() (SyslogWriter, error) {
+			if len(dialErrs) > 0 {
+				err := dialErrs[0]
+				dialErrs = dialErrs[1:]
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			dialedWriter := &fakeSyslogWriter{}
+			dialedWriters = append(dialedWriters, dialedWriter)
+			return dialedWriter, nil
+		})
+	})
+
+	It("does not connect until alert is written", func() {
+		Expect(dialedWriters).To(BeEmpty())
+
+		Expect(writer.Crit("fake-message")).To(Succeed())
+
+		Expect(dialedWriters).To(HaveLen(1))
+		Expect(dialedWriters[0].Priorities).To(Equal([]string{"crit"}))
+		Expect(dialedWriters[0].Messages).To(Equal([]string{"fake-message"}))
+	})
+
+	It("reuses connection for subsequent writes", func() {
+		Expect(writer.Alert("fake-message-1")).To(Succeed())
+		Expect(writer.Err("fake-message-2")).To(Succeed())
+		Expect(writer.Warning("fake-message-3")).To(Succeed())
+
+		Expect(dialedWriters).To(HaveLen(1))
+		Expect(dialedWriters[0].Priorities).To(Equal([]string{"alert", "err", "warning"}))
+	})
+
+	It("reconnects and retries when write fails", func() {
+		Expect(writer.Crit("fake-message-1")).To(Succeed())
+		dialedWriters[0].WriteErr = errors.New("fake-write-err")
+
+		Expect(writer.Crit("fake-message-2")).To(Succeed())
+
+		Expect(dialedWriters).To(HaveLen(2))
+		Expect(dialedWriters[0].Closed).To(BeTrue())
+		Expect(dialedWriters[1].Messages).To(Equal([]string{"fake-message-2"}))
+	})
+
+	It("returns error when connecting keeps failing and connects again on next write", func() {
+		dialErrs = []error{errors.New("fake-dial-err-1"), errors.New("fake-dial-err-2")}
+
+		err := writer.Crit("fake-message-1")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Connecting to syslog"))
+		Expect(err.Error()).To(ContainSubstring("fake-dial-err-2"))
+
+		Expect(writer.Crit("fake-message-2")).To(Succeed())
+		Expect(dialedWriters).To(HaveLen(1))
+		Expect(dialedWriters[0].Messages).To(Equal([]string{"fake-message-2"}))
+	})
+
+	It("closes connection", func() {
+		Expect(writer.Crit("fake-message")).To(Succeed())
+
+		Expect(writer.Close()).To(Succeed())
+		Expect(dialedWriters[0].Closed).To(BeTrue())
+	})
+})
@@ -0,0 +1,31 @@
+package alert
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+type multiSink struct {
+	sinks []Sink
+}
+
+// NewMultiSink sends every alert to all sinks even if some of them fail
+func NewMultiSink(sinks []Sink) Sink {
+	return multiSink{sinks: sinks}
+}
+
+func (s multiSink) Send(alert Alert) error {
+	var errs []error
+
+	for _, sink := range s.sinks {
+		err := sink.Send(alert)
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return bosherr.WrapError(bosherr.NewMultiError(errs...), "Sending alert to sinks")
+	}
+
+	return nil
+}
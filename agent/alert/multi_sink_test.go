@@ -0,0 +1,50 @@
+package alert_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	fakealert "github.com/cloudfoundry/bosh-agent/agent/alert/fakes"
+)
+
+var _ = Describe("multiSink", func() {
+	var (
+		sink1 *fakealert.FakeSink
+		sink2 *fakealert.FakeSink
+		sink  Sink
+		alert Alert
+	)
+
+	BeforeEach(func() {
+		sink1 = &fakealert.FakeSink{}
+		sink2 = &fakealert.FakeSink{}
+		sink = NewMultiSink([]Sink{sink1, sink2})
+		alert = Alert{ID: "fake-id", Severity: SeverityCritical}
+	})
+
+	It("sends alert to all sinks", func() {
+		err := sink.Send(alert)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(sink1.SentAlerts()).To(Equal([]Alert{alert}))
+		Expect(sink2.SentAlerts()).To(Equal([]Alert{alert}))
+	})
+
+	It("sends alert to remaining sinks and returns errors when some sinks fail", func() {
+		sink1.SendErr = errors.New("fake-send-err")
+
+		err := sink.Send(alert)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-send-err"))
+
+		Expect(sink2.SentAlerts()).To(Equal([]Alert{alert}))
+	})
+
+	It("does nothing when there are no sinks", func() {
+		err := NewMultiSink(nil).Send(alert)
+		Expect(err).ToNot(HaveOccurred())
+	})
+})
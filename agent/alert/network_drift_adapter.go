@@ -0,0 +1,52 @@
+package alert
+
+import (
+	"fmt"
+
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
+)
+
+type networkDriftAdapter struct {
+	check         boshnet.DriftCheck
+	uuidGenerator boshuuid.Generator
+}
+
+func NewNetworkDriftAdapter(check boshnet.DriftCheck, uuidGenerator boshuuid.Generator) Adapter {
+	return networkDriftAdapter{
+		check:         check,
+		uuidGenerator: uuidGenerator,
+	}
+}
+
+// IsIgnorable is true when networking did not drift and for drift that was already reported
+func (a networkDriftAdapter) IsIgnorable() bool {
+	return !a.check.Drift.Detected() || a.check.Alerted
+}
+
+func (a networkDriftAdapter) Alert() (Alert, error) {
+	id, err := a.uuidGenerator.Generate()
+	if err != nil {
+		return Alert{}, bosherr.WrapError(err, "Generating alert id")
+	}
+
+	alert := Alert{
+		ID:        id,
+		Severity:  SeverityCritical,
+		Title:     "Network configuration drifted",
+		Summary:   a.check.Drift.String(),
+		CreatedAt: a.check.CheckedAt,
+	}
+
+	if a.check.RepairErr != nil {
+		alert.Summary = fmt.Sprintf("%s; repair failed: %s", alert.Summary, a.check.RepairErr.Error())
+	}
+
+	if a.check.Repaired {
+		alert.Severity = SeverityWarning
+		alert.Title = "Network configuration drift was repaired"
+	}
+
+	return alert, nil
+}
@@ -0,0 +1,91 @@
+package alert_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
+)
+
+var _ = Describe("networkDriftAdapter", func() {
+	var (
+		check         boshnet.DriftCheck
+		uuidGenerator *fakeuuid.FakeGenerator
+	)
+
+	BeforeEach(func() {
+		check = boshnet.DriftCheck{
+			CheckedAt: 1306076861,
+			Drift: boshnet.Drift{
+				ConfigsChanged: true,
+				DNSErr:         errors.New("fake-dns-err"),
+			},
+		}
+		uuidGenerator = &fakeuuid.FakeGenerator{GeneratedUUID: "fake-uuid"}
+	})
+
+	Describe("IsIgnorable", func() {
+		It("ignores checks without drift", func() {
+			check.Drift = boshnet.Drift{}
+			Expect(NewNetworkDriftAdapter(check, uuidGenerator).IsIgnorable()).To(BeTrue())
+		})
+
+		It("ignores checks that were already alerted", func() {
+			check.Alerted = true
+			Expect(NewNetworkDriftAdapter(check, uuidGenerator).IsIgnorable()).To(BeTrue())
+		})
+
+		It("does not ignore detected drift", func() {
+			Expect(NewNetworkDriftAdapter(check, uuidGenerator).IsIgnorable()).To(BeFalse())
+		})
+	})
+
+	Describe("Alert", func() {
+		It("returns critical alert when drift was not repaired", func() {
+			alert, err := NewNetworkDriftAdapter(check, uuidGenerator).Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityCritical,
+				Title:     "Network configuration drifted",
+				Summary:   "network configuration files were modified; fake-dns-err",
+				CreatedAt: 1306076861,
+			}))
+		})
+
+		It("includes repair error in the summary", func() {
+			check.RepairErr = errors.New("fake-repair-err")
+
+			alert, err := NewNetworkDriftAdapter(check, uuidGenerator).Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert.Severity).To(Equal(SeverityCritical))
+			Expect(alert.Summary).To(Equal("network configuration files were modified; fake-dns-err; repair failed: fake-repair-err"))
+		})
+
+		It("returns warning alert when drift was repaired", func() {
+			check.Repaired = true
+
+			alert, err := NewNetworkDriftAdapter(check, uuidGenerator).Alert()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(alert).To(Equal(Alert{
+				ID:        "fake-uuid",
+				Severity:  SeverityWarning,
+				Title:     "Network configuration drift was repaired",
+				Summary:   "network configuration files were modified; fake-dns-err",
+				CreatedAt: 1306076861,
+			}))
+		})
+
+		It("returns error when alert id cannot be generated", func() {
+			uuidGenerator.GenerateError = errors.New("fake-uuid-err")
+
+			_, err := NewNetworkDriftAdapter(check, uuidGenerator).Alert()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-uuid-err"))
+		})
+	})
+})
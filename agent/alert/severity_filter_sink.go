@@ -0,0 +1,20 @@
+package alert
+
+type severityFilterSink struct {
+	sink        Sink
+	minSeverity SeverityLevel
+}
+
+// NewSeverityFilterSink only passes alerts that are at least as severe as minSeverity.
+// Lower severity levels are more severe.
+func NewSeverityFilterSink(sink Sink, minSeverity SeverityLevel) Sink {
+	return severityFilterSink{sink: sink, minSeverity: minSeverity}
+}
+
+func (s severityFilterSink) Send(alert Alert) error {
+	if alert.Severity < SeverityAlert || alert.Severity > s.minSeverity {
+		return nil
+	}
+
+	return s.sink.Send(alert)
+}
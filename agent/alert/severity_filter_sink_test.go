@@ -0,0 +1,44 @@
+package alert_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	fakealert "github.com/cloudfoundry/bosh-agent/agent/alert/fakes"
+)
+
+var _ = Describe("severityFilterSink", func() {
+	var (
+		delegate *fakealert.FakeSink
+		sink     Sink
+	)
+
+	BeforeEach(func() {
+		delegate = &fakealert.FakeSink{}
+		sink = NewSeverityFilterSink(delegate, SeverityError)
+	})
+
+	It("passes alerts that are at least as severe as minimum severity", func() {
+		for _, severity := range []SeverityLevel{SeverityAlert, SeverityCritical, SeverityError} {
+			err := sink.Send(Alert{ID: "fake-id", Severity: severity})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(delegate.SentAlerts()).To(Equal([]Alert{
+			{ID: "fake-id", Severity: SeverityAlert},
+			{ID: "fake-id", Severity: SeverityCritical},
+			{ID: "fake-id", Severity: SeverityError},
+		}))
+	})
+
+	It("drops less severe and ignored alerts", func() {
+		err := sink.Send(Alert{ID: "fake-id", Severity: SeverityWarning})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = sink.Send(Alert{ID: "fake-id", Severity: SeverityIgnored})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(delegate.SentAlerts()).To(BeEmpty())
+	})
+})
@@ -0,0 +1,109 @@
+package alert
+
+import (
+	"crypto/x509"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cloudfoundry/bosh-utils/httpclient"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	webhookMaxAttempts = 3
+	webhookRetryDelay  = 1 * time.Second
+
+	// Retrying a webhook takes seconds so alerts are queued instead of
+	// making agent wait; enough for a burst of job failures
+	webhookQueueSize = 100
+)
+
+type SinkFactory struct {
+	fs     boshsys.FileSystem
+	logger boshlog.Logger
+}
+
+func NewSinkFactory(fs boshsys.FileSystem, logger boshlog.Logger) SinkFactory {
+	return SinkFactory{fs: fs, logger: logger}
+}
+
+// New returns a sink that sends alerts to all configured sinks;
+// it does nothing when no sinks are configured
+func (f SinkFactory) New(opts Options) (Sink, error) {
+	var sinks []Sink
+
+	for i, sinkOpts := range opts.Sinks {
+		sink, err := f.newSink(sinkOpts)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Building alert sink %d", i)
+		}
+
+		minSeverity, err := ParseSeverity(sinkOpts.MinSeverity)
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Building alert sink %d", i)
+		}
+
+		sinks = append(sinks, NewSeverityFilterSink(sink, minSeverity))
+	}
+
+	return NewMultiSink(sinks), nil
+}
+
+func (f SinkFactory) newSink(opts SinkOptions) (Sink, error) {
+	switch opts.Type {
+	case SinkTypeSyslog:
+		if opts.Syslog.Format != "" && opts.Syslog.Format != SyslogFormatJSON && opts.Syslog.Format != SyslogFormatCEF {
+			return nil, bosherr.Errorf("Unknown syslog alert format '%s'", opts.Syslog.Format)
+		}
+
+		writer, err := NewSyslogWriter(opts.Syslog)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewSyslogSink(writer, opts.Syslog.Format), nil
+
+	case SinkTypeWebhook:
+		if opts.Webhook.URL == "" {
+			return nil, bosherr.Error("Webhook alert sink requires URL")
+		}
+
+		client, err := f.webhookClient(opts.Webhook)
+		if err != nil {
+			return nil, err
+		}
+
+		return NewAsyncSink(NewWebhookSink(opts.Webhook.URL, client), webhookQueueSize, f.logger), nil
+
+	case SinkTypeSpool:
+		if opts.Spool.Path == "" {
+			return nil, bosherr.Error("Spool alert sink requires path")
+		}
+
+		return NewSpoolSink(opts.Spool.Path, f.fs), nil
+
+	default:
+		return nil, bosherr.Errorf("Unknown alert sink type '%s'", opts.Type)
+	}
+}
+
+func (f SinkFactory) webhookClient(opts WebhookSinkOptions) (httpclient.Client, error) {
+	var certPool *x509.CertPool
+
+	if opts.CACertPath != "" {
+		caPEM, err := f.fs.ReadFile(opts.CACertPath)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Reading webhook CA certificate")
+		}
+
+		certPool = x509.NewCertPool()
+		if !certPool.AppendCertsFromPEM(caPEM) {
+			return nil, bosherr.Error("Parsing webhook CA certificate")
+		}
+	}
+
+	client := httpclient.CreateDefaultClient(certPool)
+
+	return httpclient.NewRetryClient(client, webhookMaxAttempts, webhookRetryDelay, f.logger), nil
+}
@@ -0,0 +1,105 @@
+package alert_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("SinkFactory", func() {
+	var (
+		fs      *fakesys.FakeFileSystem
+		factory SinkFactory
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		factory = NewSinkFactory(fs, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	It("returns sink that does nothing when no sinks are configured", func() {
+		sink, err := factory.New(Options{})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = sink.Send(Alert{ID: "fake-id", Severity: SeverityAlert})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("returns sink filtering alerts by configured severity", func() {
+		sink, err := factory.New(Options{
+			Sinks: []SinkOptions{
+				{Type: "spool", MinSeverity: "critical", Spool: SpoolSinkOptions{Path: "/fake-spool"}},
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = sink.Send(Alert{ID: "fake-error-id", Severity: SeverityError})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(fs.FileExists("/fake-spool")).To(BeFalse())
+
+		err = sink.Send(Alert{ID: "fake-critical-id", Severity: SeverityCritical})
+		Expect(err).ToNot(HaveOccurred())
+
+		contents, err := fs.ReadFileString("/fake-spool")
+		Expect(err).ToNot(HaveOccurred())
+
+		var spooled Alert
+		Expect(json.Unmarshal([]byte(contents), &spooled)).To(Succeed())
+		Expect(spooled.ID).To(Equal("fake-critical-id"))
+	})
+
+	It("builds webhook sink", func() {
+		_, err := factory.New(Options{
+			Sinks: []SinkOptions{{Type: "webhook", Webhook: WebhookSinkOptions{URL: "https://fake-url"}}},
+		})
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("returns error when webhook CA certificate cannot be parsed", func() {
+		fs.WriteFileString("/fake-ca", "not-a-cert")
+
+		_, err := factory.New(Options{
+			Sinks: []SinkOptions{{Type: "webhook", Webhook: WebhookSinkOptions{URL: "https://fake-url", CACertPath: "/fake-ca"}}},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing webhook CA certificate"))
+	})
+
+	It("returns error when required settings are missing", func() {
+		_, err := factory.New(Options{Sinks: []SinkOptions{{Type: "webhook"}}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Webhook alert sink requires URL"))
+
+		_, err = factory.New(Options{Sinks: []SinkOptions{{Type: "spool"}}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Spool alert sink requires path"))
+	})
+
+	It("returns error when syslog format is unknown", func() {
+		_, err := factory.New(Options{
+			Sinks: []SinkOptions{{Type: "syslog", Syslog: SyslogSinkOptions{Format: "fake-format"}}},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unknown syslog alert format 'fake-format'"))
+	})
+
+	It("returns error when sink type is unknown", func() {
+		_, err := factory.New(Options{Sinks: []SinkOptions{{Type: "fake-type"}}})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Building alert sink 0"))
+		Expect(err.Error()).To(ContainSubstring("Unknown alert sink type 'fake-type'"))
+	})
+
+	It("returns error when severity is unknown", func() {
+		_, err := factory.New(Options{
+			Sinks: []SinkOptions{{Type: "spool", MinSeverity: "fake-severity", Spool: SpoolSinkOptions{Path: "/fake-spool"}}},
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unknown alert severity 'fake-severity'"))
+	})
+})
@@ -0,0 +1,81 @@
+package alert
+
+import (
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// Sink delivers alerts to a destination other than the health monitor
+// so that they are kept even when message bus does not forward them
+type Sink interface {
+	Send(Alert) error
+}
+
+const (
+	SinkTypeSyslog  = "syslog"
+	SinkTypeWebhook = "webhook"
+	SinkTypeSpool   = "spool"
+)
+
+type Options struct {
+	Sinks []SinkOptions
+}
+
+type SinkOptions struct {
+	// Type is one of syslog, webhook or spool
+	Type string
+
+	// MinSeverity is one of alert, critical, error or warning;
+	// less severe alerts are not sent to the sink. Defaults to warning.
+	MinSeverity string
+
+	Syslog  SyslogSinkOptions
+	Webhook WebhookSinkOptions
+	Spool   SpoolSinkOptions
+}
+
+type SyslogSinkOptions struct {
+	// Network and Address of remote syslog server, e.g. "udp" and "10.0.0.5:514";
+	// local syslog is used when empty
+	Network string
+	Address string
+
+	// Tag defaults to vcap.agent.alert
+	Tag string
+
+	// Format is either json (default) or cef
+	Format string
+}
+
+type WebhookSinkOptions struct {
+	URL string
+
+	// Verify webhook server certificate against this CA instead of system CAs
+	CACertPath string
+}
+
+type SpoolSinkOptions struct {
+	// Path of the file alerts are appended to as JSON lines
+	Path string
+}
+
+var severityNames = map[string]SeverityLevel{
+	"alert":    SeverityAlert,
+	"critical": SeverityCritical,
+	"error":    SeverityError,
+	"warning":  SeverityWarning,
+}
+
+func ParseSeverity(name string) (SeverityLevel, error) {
+	if name == "" {
+		return SeverityWarning, nil
+	}
+
+	severity, found := severityNames[strings.ToLower(name)]
+	if !found {
+		return 0, bosherr.Errorf("Unknown alert severity '%s'", name)
+	}
+
+	return severity, nil
+}
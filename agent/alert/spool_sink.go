@@ -0,0 +1,51 @@
+package alert
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type spoolSink struct {
+	path string
+	fs   boshsys.FileSystem
+	lock *sync.Mutex
+}
+
+// NewSpoolSink appends each alert as a single JSON line to the file at path
+func NewSpoolSink(path string, fs boshsys.FileSystem) Sink {
+	return spoolSink{path: path, fs: fs, lock: &sync.Mutex{}}
+}
+
+func (s spoolSink) Send(alert Alert) error {
+	line, err := json.Marshal(alert)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling alert")
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	err = s.fs.MkdirAll(filepath.Dir(s.path), os.FileMode(0750))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating alert spool directory")
+	}
+
+	file, err := s.fs.OpenFile(s.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, os.FileMode(0640))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening alert spool %s", s.path)
+	}
+
+	defer file.Close()
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing alert to spool %s", s.path)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package alert_test
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("spoolSink", func() {
+	var (
+		tmpDir    string
+		spoolPath string
+		sink      Sink
+	)
+
+	BeforeEach(func() {
+		var err error
+		tmpDir, err = ioutil.TempDir("", "alert-spool")
+		Expect(err).ToNot(HaveOccurred())
+
+		spoolPath = filepath.Join(tmpDir, "alerts", "alerts.jsonl")
+		fs := boshsys.NewOsFileSystem(boshlog.NewLogger(boshlog.LevelNone))
+		sink = NewSpoolSink(spoolPath, fs)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(tmpDir)
+	})
+
+	It("appends each alert as a JSON line", func() {
+		err := sink.Send(Alert{ID: "fake-id-1", Severity: SeverityCritical, CreatedAt: 1})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = sink.Send(Alert{ID: "fake-id-2", Severity: SeverityWarning, CreatedAt: 2})
+		Expect(err).ToNot(HaveOccurred())
+
+		contents, err := ioutil.ReadFile(spoolPath)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(contents)).To(Equal(
+			`{"id":"fake-id-1","severity":2,"title":"","summary":"","created_at":1}` + "\n" +
+				`{"id":"fake-id-2","severity":4,"title":"","summary":"","created_at":2}` + "\n",
+		))
+	})
+
+	It("returns error when spool cannot be opened", func() {
+		fs := fakesys.NewFakeFileSystem()
+		fs.OpenFileErr = errors.New("fake-open-err")
+
+		err := NewSpoolSink("/fake-spool", fs).Send(Alert{ID: "fake-id"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-open-err"))
+	})
+})
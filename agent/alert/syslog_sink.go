@@ -0,0 +1,121 @@
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+const (
+	SyslogFormatJSON = "json"
+	SyslogFormatCEF  = "cef"
+
+	defaultSyslogTag = "vcap.agent.alert"
+
+	cefVendor  = "CloudFoundry"
+	cefProduct = "BOSH Agent"
+	cefVersion = "1.0"
+)
+
+// SyslogWriter is satisfied by *syslog.Writer
+type SyslogWriter interface {
+	Alert(m string) error
+	Crit(m string) error
+	Err(m string) error
+	Warning(m string) error
+	Close() error
+}
+
+type syslogSink struct {
+	writer SyslogWriter
+	format string
+}
+
+func NewSyslogSink(writer SyslogWriter, format string) Sink {
+	return syslogSink{writer: writer, format: format}
+}
+
+func (s syslogSink) Send(alert Alert) error {
+	message, err := s.message(alert)
+	if err != nil {
+		return err
+	}
+
+	switch alert.Severity {
+	case SeverityAlert:
+		err = s.writer.Alert(message)
+	case SeverityCritical:
+		err = s.writer.Crit(message)
+	case SeverityError:
+		err = s.writer.Err(message)
+	default:
+		err = s.writer.Warning(message)
+	}
+
+	if err != nil {
+		return bosherr.WrapError(err, "Writing alert to syslog")
+	}
+
+	return nil
+}
+
+func (s syslogSink) message(alert Alert) (string, error) {
+	switch s.format {
+	case SyslogFormatCEF:
+		return formatCEF(alert), nil
+
+	case SyslogFormatJSON, "":
+		bytes, err := json.Marshal(alert)
+		if err != nil {
+			return "", bosherr.WrapError(err, "Marshalling alert")
+		}
+		return string(bytes), nil
+
+	default:
+		return "", bosherr.Errorf("Unknown syslog alert format '%s'", s.format)
+	}
+}
+
+// formatCEF follows ArcSight Common Event Format:
+// CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+func formatCEF(alert Alert) string {
+	return fmt.Sprintf(
+		"CEF:0|%s|%s|%s|%s|%s|%d|rt=%d msg=%s",
+		escapeCEFHeader(cefVendor),
+		escapeCEFHeader(cefProduct),
+		escapeCEFHeader(cefVersion),
+		escapeCEFHeader(alert.ID),
+		escapeCEFHeader(alert.Title),
+		cefSeverity(alert.Severity),
+		alert.CreatedAt*1000,
+		escapeCEFExtension(alert.Summary),
+	)
+}
+
+func cefSeverity(severity SeverityLevel) int {
+	switch severity {
+	case SeverityAlert:
+		return 10
+	case SeverityCritical:
+		return 8
+	case SeverityError:
+		return 6
+	default:
+		return 4
+	}
+}
+
+var (
+	cefHeaderEscaper    = strings.NewReplacer(`\`, `\\`, `|`, `\|`, "\n", " ", "\r", " ")
+	cefExtensionEscaper = strings.NewReplacer(`\`, `\\`, `=`, `\=`, "\n", `\n`, "\r", `\r`)
+)
+
+func escapeCEFHeader(value string) string {
+	return cefHeaderEscaper.Replace(value)
+}
+
+func escapeCEFExtension(value string) string {
+	return cefExtensionEscaper.Replace(value)
+}
@@ -0,0 +1,99 @@
+package alert_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+)
+
+type fakeSyslogWriter struct {
+	Priorities []string
+	Messages   []string
+	WriteErr   error
+	Closed     bool
+}
+
+func (w *fakeSyslogWriter) Alert(m string) error   { return w.write("alert", m) }
+func (w *fakeSyslogWriter) Crit(m string) error    { return w.write("crit", m) }
+func (w *fakeSyslogWriter) Err(m string) error     { return w.write("err", m) }
+func (w *fakeSyslogWriter) Warning(m string) error { return w.write("warning", m) }
+func (w *fakeSyslogWriter) Close() error           { w.Closed = true; return nil }
+
+func (w *fakeSyslogWriter) write(priority, m string) error {
+	w.Priorities = append(w.Priorities, priority)
+	w.Messages = append(w.Messages, m)
+	return w.WriteErr
+}
+
+var _ = Describe("syslogSink", func() {
+	var (
+		writer *fakeSyslogWriter
+		alert  Alert
+	)
+
+	BeforeEach(func() {
+		writer = &fakeSyslogWriter{}
+		alert = Alert{
+			ID:        "fake-id",
+			Severity:  SeverityCritical,
+			Title:     "nats (10.0.0.1) - does not exist - restart",
+			Summary:   "process is not running",
+			CreatedAt: 1306076861,
+		}
+	})
+
+	It("writes alert as JSON with syslog priority matching severity", func() {
+		err := NewSyslogSink(writer, "").Send(alert)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(writer.Priorities).To(Equal([]string{"crit"}))
+		Expect(writer.Messages[0]).To(MatchJSON(`{
+			"id": "fake-id",
+			"severity": 2,
+			"title": "nats (10.0.0.1) - does not exist - restart",
+			"summary": "process is not running",
+			"created_at": 1306076861
+		}`))
+	})
+
+	It("maps each severity to syslog priority", func() {
+		sink := NewSyslogSink(writer, SyslogFormatJSON)
+
+		for _, severity := range []SeverityLevel{SeverityAlert, SeverityCritical, SeverityError, SeverityWarning} {
+			err := sink.Send(Alert{Severity: severity})
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		Expect(writer.Priorities).To(Equal([]string{"alert", "crit", "err", "warning"}))
+	})
+
+	It("writes alert in CEF format escaping special characters", func() {
+		alert.Title = `a|b\c`
+		alert.Summary = "x=y\nz"
+
+		err := NewSyslogSink(writer, SyslogFormatCEF).Send(alert)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(writer.Messages).To(Equal([]string{
+			`CEF:0|CloudFoundry|BOSH Agent|1.0|fake-id|a\|b\\c|8|rt=1306076861000 msg=x\=y\nz`,
+		}))
+	})
+
+	It("returns error when format is unknown", func() {
+		err := NewSyslogSink(writer, "fake-format").Send(alert)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unknown syslog alert format 'fake-format'"))
+		Expect(writer.Messages).To(BeEmpty())
+	})
+
+	It("returns error when writing fails", func() {
+		writer.WriteErr = errors.New("fake-write-err")
+
+		err := NewSyslogSink(writer, SyslogFormatCEF).Send(alert)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("fake-write-err"))
+	})
+})
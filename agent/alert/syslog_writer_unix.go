@@ -0,0 +1,24 @@
+//+build !windows
+
+package alert
+
+import (
+	"log/syslog"
+)
+
+func NewSyslogWriter(opts SyslogSinkOptions) (SyslogWriter, error) {
+	tag := opts.Tag
+	if tag == "" {
+		tag = defaultSyslogTag
+	}
+
+	return NewLazySyslogWriter(func() (SyslogWriter, error) {
+		// Dial with empty network connects to local syslog daemon
+		writer, err := syslog.Dial(opts.Network, opts.Address, syslog.LOG_WARNING|syslog.LOG_DAEMON, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		return writer, nil
+	}), nil
+}
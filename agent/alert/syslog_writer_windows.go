@@ -0,0 +1,11 @@
+//+build windows
+
+package alert
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+func NewSyslogWriter(opts SyslogSinkOptions) (SyslogWriter, error) {
+	return nil, bosherr.Error("Syslog alert sink is not supported on Windows")
+}
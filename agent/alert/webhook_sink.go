@@ -0,0 +1,51 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	"github.com/cloudfoundry/bosh-utils/httpclient"
+)
+
+type webhookSink struct {
+	url    string
+	client httpclient.Client
+}
+
+func NewWebhookSink(url string, client httpclient.Client) Sink {
+	return webhookSink{url: url, client: client}
+}
+
+func (s webhookSink) Send(alert Alert) error {
+	body, err := json.Marshal(alert)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling alert")
+	}
+
+	request, err := http.NewRequest("POST", s.url, bytes.NewReader(body))
+	if err != nil {
+		return bosherr.WrapError(err, "Building webhook request")
+	}
+
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		return bosherr.WrapError(err, "Posting alert to webhook")
+	}
+
+	defer response.Body.Close()
+
+	// Drain body so that connection can be reused
+	_, _ = io.Copy(ioutil.Discard, response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return bosherr.Errorf("Posting alert to webhook: unexpected status %d", response.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,68 @@
+package alert_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/alert"
+)
+
+var _ = Describe("webhookSink", func() {
+	var (
+		server         *httptest.Server
+		responseStatus int
+		requests       chan *http.Request
+		bodies         chan []byte
+		alert          Alert
+	)
+
+	BeforeEach(func() {
+		responseStatus = http.StatusNoContent
+		requests = make(chan *http.Request, 1)
+		bodies = make(chan []byte, 1)
+
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := ioutil.ReadAll(r.Body)
+			requests <- r
+			bodies <- body
+			w.WriteHeader(responseStatus)
+		}))
+
+		alert = Alert{ID: "fake-id", Severity: SeverityError, Title: "fake-title", CreatedAt: 10}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("posts alert as JSON", func() {
+		err := NewWebhookSink(server.URL+"/alerts", http.DefaultClient).Send(alert)
+		Expect(err).ToNot(HaveOccurred())
+
+		request := <-requests
+		Expect(request.Method).To(Equal("POST"))
+		Expect(request.URL.Path).To(Equal("/alerts"))
+		Expect(request.Header.Get("Content-Type")).To(Equal("application/json"))
+		Expect(<-bodies).To(MatchJSON(`{"id":"fake-id","severity":3,"title":"fake-title","summary":"","created_at":10}`))
+	})
+
+	It("returns error when webhook responds with non-successful status", func() {
+		responseStatus = http.StatusBadGateway
+
+		err := NewWebhookSink(server.URL, http.DefaultClient).Send(alert)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("unexpected status 502"))
+	})
+
+	It("returns error when webhook cannot be reached", func() {
+		server.Close()
+
+		err := NewWebhookSink(server.URL, http.DefaultClient).Send(alert)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Posting alert to webhook"))
+	})
+})
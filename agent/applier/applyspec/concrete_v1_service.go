@@ -67,15 +67,19 @@ func (s concreteV1Service) PopulateDHCPNetworks(spec V1ApplySpec, settings boshs
 			return V1ApplySpec{}, bosherr.Errorf("Network '%s' is not found in settings", networkName)
 		}
 
-		if !network.IsDHCP() {
-			continue
+		if network.IsDHCP() {
+			networkSpec = networkSpec.PopulateIPInfo(
+				network.IP,
+				network.Netmask,
+				network.Gateway,
+			)
+		}
+
+		if len(network.SecondaryAddresses) > 0 {
+			networkSpec = networkSpec.PopulateSecondaryAddresses(network.SecondaryAddresses)
 		}
 
-		spec.NetworkSpecs[networkName] = networkSpec.PopulateIPInfo(
-			network.IP,
-			network.Netmask,
-			network.Gateway,
-		)
+		spec.NetworkSpecs[networkName] = networkSpec
 	}
 
 	return spec, nil
@@ -185,6 +185,33 @@ func init() {
 				})
 			})
 
+			Context("when networks have secondary addresses", func() {
+				BeforeEach(func() {
+					manualSetting.SecondaryAddresses = []boshsettings.Address{
+						{IP: "fake-secondary-ip", Netmask: "fake-secondary-netmask"},
+					}
+					settings.Networks["static-net1"] = manualSetting
+
+					unresolvedSpec.NetworkSpecs["static-net1"] = staticSpec
+				})
+
+				It("returns spec with secondary addresses of networks", func() {
+					spec, err := service.PopulateDHCPNetworks(unresolvedSpec, settings)
+					Expect(err).ToNot(HaveOccurred())
+					Expect(spec.NetworkSpecs["static-net1"]).To(Equal(NetworkSpec{
+						Fields: map[string]interface{}{
+							"ip":      "fake-net1-ip",
+							"netmask": "fake-net1-netmask",
+							"gateway": "fake-net1-gateway",
+							"mac":     "fake-net1-mac",
+							"secondary_addresses": []interface{}{
+								map[string]interface{}{"ip": "fake-secondary-ip", "netmask": "fake-secondary-netmask"},
+							},
+						},
+					}))
+				})
+			})
+
 			Context("when associated network cannot be found in settings", func() {
 				BeforeEach(func() {
 					settings.Networks["net-present-in-settings"] = manualSetting
@@ -7,5 +7,8 @@ import (
 type V1Service interface {
 	Get() (V1ApplySpec, error)
 	Set(V1ApplySpec) error
+
+	// PopulateDHCPNetworks fills in addresses resolved via DHCP
+	// and secondary addresses of networks from settings
 	PopulateDHCPNetworks(V1ApplySpec, boshsettings.Settings) (V1ApplySpec, error)
 }
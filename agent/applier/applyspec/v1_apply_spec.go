@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 
 	"github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 )
 
 type V1ApplySpec struct {
@@ -93,6 +94,25 @@ func (s NetworkSpec) PopulateIPInfo(ip, netmask, gateway string) NetworkSpec {
 	return s
 }
 
+// PopulateSecondaryAddresses keeps the same shape as network settings
+// so that every address assigned to the instance is reported in get_state
+func (s NetworkSpec) PopulateSecondaryAddresses(addresses []boshsettings.Address) NetworkSpec {
+	if s.Fields == nil {
+		s.Fields = map[string]interface{}{}
+	}
+
+	secondaryAddresses := []interface{}{}
+	for _, address := range addresses {
+		secondaryAddresses = append(secondaryAddresses, map[string]interface{}{
+			"ip":      address.IP,
+			"netmask": address.Netmask,
+		})
+	}
+
+	s.Fields["secondary_addresses"] = secondaryAddresses
+	return s
+}
+
 func (s *NetworkSpec) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &s.Fields)
 }
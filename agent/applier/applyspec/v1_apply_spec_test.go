@@ -8,6 +8,7 @@ import (
 
 	. "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	models "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	"github.com/cloudfoundry/bosh-utils/crypto"
 )
 
@@ -354,4 +355,25 @@ var _ = Describe("NetworkSpec", func() {
 			}))
 		})
 	})
+
+	Describe("PopulateSecondaryAddresses", func() {
+		It("populates network spec with secondary addresses", func() {
+			networkSpec := NetworkSpec{
+				Fields: map[string]interface{}{"ip": "fake-ip"},
+			}
+
+			networkSpec = networkSpec.PopulateSecondaryAddresses([]boshsettings.Address{
+				{IP: "fake-secondary-ip", Netmask: "fake-secondary-netmask"},
+			})
+
+			Expect(networkSpec).To(Equal(NetworkSpec{
+				Fields: map[string]interface{}{
+					"ip": "fake-ip",
+					"secondary_addresses": []interface{}{
+						map[string]interface{}{"ip": "fake-secondary-ip", "netmask": "fake-secondary-netmask"},
+					},
+				},
+			}))
+		})
+	})
 })
@@ -9,6 +9,7 @@ import (
 
 	applyspec "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -20,6 +21,8 @@ type Bootstrap interface {
 	Run() error
 }
 
+const bootstrapLogTag = "Bootstrap"
+
 type bootstrap struct {
 	fs              boshsys.FileSystem
 	platform        boshplatform.Platform
@@ -101,14 +104,20 @@ func (boot bootstrap) Run() (err error) {
 		return bosherr.WrapError(err, "Setting up NTP servers")
 	}
 
-	if err = boot.platform.SetupRawEphemeralDisks(settings.RawEphemeralDiskSettings()); err != nil {
-		return bosherr.WrapError(err, "Setting up raw ephemeral disk")
+	updateSettings, err := boot.loadUpdateSettings()
+	if err != nil {
+		return err
 	}
 
-	ephemeralDiskPath := boot.platform.GetEphemeralDiskPath(settings.EphemeralDiskSettings())
-	desiredSwapSizeInBytes := settings.Env.GetSwapSizeInBytes()
-	if err = boot.platform.SetupEphemeralDiskWithPath(ephemeralDiskPath, desiredSwapSizeInBytes); err != nil {
-		return bosherr.WrapError(err, "Setting up ephemeral disk")
+	swap := settings.Env.ResolveSwap(updateSettings.Swap)
+
+	ephemeralDiskPath, err := boot.setupEphemeralDisks(settings, swap)
+	if err != nil {
+		return err
+	}
+
+	if err = boot.platform.SetupSwap(swap); err != nil {
+		return bosherr.WrapError(err, "Setting up swap")
 	}
 
 	if err = boot.platform.SetupRootDisk(ephemeralDiskPath); err != nil {
@@ -163,6 +172,11 @@ func (boot bootstrap) Run() (err error) {
 		}
 	}
 
+	// Named disks are mounted after store directory since they are nested in it
+	if err = boot.mountManagedDisks(settings); err != nil {
+		return bosherr.WrapError(err, "Mounting managed disks")
+	}
+
 	v1Spec, err := boot.specService.Get()
 	if err != nil {
 		return bosherr.WrapError(err, "Cannot get v1spec from SpecService")
@@ -210,25 +224,50 @@ func (boot bootstrap) Run() (err error) {
 	return nil
 }
 
+// setupEphemeralDisks returns path of ephemeral disk device
+// which is empty when ephemeral data lives on root device
+func (boot bootstrap) setupEphemeralDisks(settings boshsettings.Settings, swap boshsettings.Swap) (string, error) {
+	ephemeralDiskSettings := settings.EphemeralDiskSettings()
+	ephemeralDiskPath := boot.platform.GetEphemeralDiskPath(ephemeralDiskSettings)
+
+	if pool := settings.Env.EphemeralDiskPool; pool.Enabled {
+		err := boot.platform.SetupPooledEphemeralDisk(ephemeralDiskPath, settings.RawEphemeralDiskSettings(), pool, ephemeralDiskSettings.MountOptions...)
+		if err != nil {
+			return "", bosherr.WrapError(err, "Setting up pooled ephemeral disk")
+		}
+
+		return ephemeralDiskPath, nil
+	}
+
+	if err := boot.platform.SetupRawEphemeralDisks(settings.RawEphemeralDiskSettings()); err != nil {
+		return "", bosherr.WrapError(err, "Setting up raw ephemeral disk")
+	}
+
+	desiredSwapSizeInBytes := swap.GetSizeInBytes()
+
+	// Swap partition is only carved out when swap is not backed by file or zram
+	if swap.Type != boshdisk.SwapTypePartition {
+		noSwapSizeInBytes := uint64(0)
+		desiredSwapSizeInBytes = &noSwapSizeInBytes
+	}
+
+	if err := boot.platform.SetupEphemeralDiskWithPath(ephemeralDiskPath, desiredSwapSizeInBytes, ephemeralDiskSettings.MountOptions...); err != nil {
+		return "", bosherr.WrapError(err, "Setting up ephemeral disk")
+	}
+
+	return ephemeralDiskPath, nil
+}
+
 func (boot bootstrap) comparePersistentDisk() error {
 	settings := boot.settingsService.GetSettings()
-	updateSettingsPath := filepath.Join(boot.platform.GetDirProvider().BoshDir(), "update_settings.json")
 
 	if err := boot.checkLastMountedCid(settings); err != nil {
 		return err
 	}
 
-	var updateSettings boshsettings.UpdateSettings
-
-	if boot.platform.GetFs().FileExists(updateSettingsPath) {
-		contents, err := boot.platform.GetFs().ReadFile(updateSettingsPath)
-		if err != nil {
-			return bosherr.WrapError(err, "Reading update_settings.json")
-		}
-
-		if err = json.Unmarshal(contents, &updateSettings); err != nil {
-			return bosherr.WrapError(err, "Unmarshalling update_settings.json")
-		}
+	updateSettings, err := boot.loadUpdateSettings()
+	if err != nil {
+		return err
 	}
 
 	for _, diskAssociation := range updateSettings.DiskAssociations {
@@ -237,8 +276,16 @@ func (boot bootstrap) comparePersistentDisk() error {
 		}
 	}
 
-	if len(settings.Disks.Persistent) > 1 {
-		if len(settings.Disks.Persistent) > len(updateSettings.DiskAssociations) {
+	namedDiskCount, err := boot.attachedNamedDiskCount(settings, updateSettings)
+	if err != nil {
+		return err
+	}
+
+	// Named disks are attached on top of the disks checked before they were introduced
+	unnamedDiskCount := len(settings.Disks.Persistent) - namedDiskCount
+
+	if unnamedDiskCount > 1 {
+		if unnamedDiskCount > len(updateSettings.DiskAssociations) {
 			return errors.New("Unexpected disk attached")
 		}
 	}
@@ -246,6 +293,90 @@ func (boot bootstrap) comparePersistentDisk() error {
 	return nil
 }
 
+// attachedNamedDiskCount counts attached named disks tracked by the agent
+// which are not already accounted for by disk associations
+func (boot bootstrap) attachedNamedDiskCount(settings boshsettings.Settings, updateSettings boshsettings.UpdateSettings) (int, error) {
+	managedDisks, err := boot.platform.GetManagedDisks().List()
+	if err != nil {
+		return 0, bosherr.WrapError(err, "Listing managed disks")
+	}
+
+	count := 0
+
+	for _, managedDisk := range managedDisks {
+		if _, ok := settings.PersistentDiskSettings(managedDisk.DiskCID); !ok {
+			continue
+		}
+
+		associated := false
+
+		for _, diskAssociation := range updateSettings.DiskAssociations {
+			if diskAssociation.DiskCID == managedDisk.DiskCID {
+				associated = true
+				break
+			}
+		}
+
+		if !associated {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (boot bootstrap) loadUpdateSettings() (boshsettings.UpdateSettings, error) {
+	var updateSettings boshsettings.UpdateSettings
+
+	updateSettingsPath := filepath.Join(boot.platform.GetDirProvider().BoshDir(), "update_settings.json")
+
+	if !boot.platform.GetFs().FileExists(updateSettingsPath) {
+		return updateSettings, nil
+	}
+
+	contents, err := boot.platform.GetFs().ReadFile(updateSettingsPath)
+	if err != nil {
+		return updateSettings, bosherr.WrapError(err, "Reading update_settings.json")
+	}
+
+	if err = json.Unmarshal(contents, &updateSettings); err != nil {
+		return updateSettings, bosherr.WrapError(err, "Unmarshalling update_settings.json")
+	}
+
+	return updateSettings, nil
+}
+
+func (boot bootstrap) mountManagedDisks(settings boshsettings.Settings) error {
+	managedDisks, err := boot.platform.GetManagedDisks().List()
+	if err != nil {
+		return bosherr.WrapError(err, "Listing managed disks")
+	}
+
+	for _, managedDisk := range managedDisks {
+		diskSettings, found := settings.PersistentDiskSettings(managedDisk.DiskCID)
+		if !found {
+			boot.logger.Warn(bootstrapLogTag, "Managed disk %s is no longer attached", managedDisk.DiskCID)
+			continue
+		}
+
+		isPartitioned, err := boot.platform.IsPersistentDiskMountable(diskSettings)
+		if err != nil {
+			return bosherr.WrapError(err, "Checking if persistent disk is partitioned")
+		}
+
+		if !isPartitioned {
+			continue
+		}
+
+		err = boot.platform.MountPersistentDisk(diskSettings, managedDisk.MountPoint)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Mounting persistent disk %s", managedDisk.DiskCID)
+		}
+	}
+
+	return nil
+}
+
 func (boot bootstrap) setUserPasswords(env boshsettings.Env) error {
 	password := env.GetPassword()
 
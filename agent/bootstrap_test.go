@@ -8,6 +8,8 @@ import (
 	"path/filepath"
 	"time"
 
+	"code.cloudfoundry.org/clock"
+
 	. "github.com/cloudfoundry/bosh-agent/agent"
 	fakeinf "github.com/cloudfoundry/bosh-agent/infrastructure/fakes"
 	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
@@ -238,6 +240,14 @@ var _ = Describe("bootstrap", func() {
 			}))
 		})
 
+		It("sets up ephemeral disk with mount options from env", func() {
+			settingsService.Settings.Env.EphemeralDiskMountOptions = []string{"discard"}
+
+			err := bootstrap()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platform.SetupEphemeralDiskWithPathMountOptions).To(Equal([]string{"discard"}))
+		})
+
 		It("returns error if setting ephemeral disk fails", func() {
 			platform.SetupEphemeralDiskWithPathErr = errors.New("fake-setup-ephemeral-disk-err")
 			err := bootstrap()
@@ -245,6 +255,136 @@ var _ = Describe("bootstrap", func() {
 			Expect(err.Error()).To(ContainSubstring("fake-setup-ephemeral-disk-err"))
 		})
 
+		Context("when ephemeral disks are pooled", func() {
+			BeforeEach(func() {
+				settingsService.Settings.Env.EphemeralDiskPool = boshsettings.EphemeralDiskPool{Enabled: true, Striped: true}
+				settingsService.Settings.Env.EphemeralDiskMountOptions = []string{"discard"}
+				settingsService.Settings.Disks = boshsettings.Disks{
+					Ephemeral:    "fake-ephemeral-disk-setting",
+					RawEphemeral: []boshsettings.DiskSettings{{Path: "/dev/nvme1n1"}, {Path: "/dev/nvme2n1"}},
+				}
+
+				platform.GetEphemeralDiskPathRealPath = "/dev/sdb"
+			})
+
+			It("sets up pooled ephemeral disk from ephemeral and raw ephemeral disks", func() {
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(platform.SetupPooledEphemeralDiskEphemeralDiskPath).To(Equal("/dev/sdb"))
+				Expect(platform.SetupPooledEphemeralDiskRawDevices).To(Equal([]boshsettings.DiskSettings{{Path: "/dev/nvme1n1"}, {Path: "/dev/nvme2n1"}}))
+				Expect(platform.SetupPooledEphemeralDiskPool).To(Equal(boshsettings.EphemeralDiskPool{Enabled: true, Striped: true}))
+				Expect(platform.SetupPooledEphemeralDiskMountOptions).To(Equal([]string{"discard"}))
+			})
+
+			It("does not set up ephemeral disks separately", func() {
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(platform.SetupRawEphemeralDisksCallCount).To(Equal(0))
+				Expect(platform.SetupEphemeralDiskWithPathDevicePath).To(BeEmpty())
+			})
+
+			It("backs swap by file since swap partition cannot be carved out of pooled disks", func() {
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(platform.SetupSwapSwaps).To(HaveLen(1))
+				Expect(platform.SetupSwapSwaps[0].Type).To(Equal(boshdisk.SwapTypeFile))
+			})
+
+			It("returns error if setting up pooled ephemeral disk fails", func() {
+				platform.SetupPooledEphemeralDiskErr = errors.New("fake-pool-err")
+
+				err := bootstrap()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Setting up pooled ephemeral disk: fake-pool-err"))
+			})
+		})
+
+		Context("when swap is backed by file", func() {
+			var swapSize uint64
+
+			BeforeEach(func() {
+				swapSize = 2048
+				settingsService.Settings.Env.Bosh.SwapSizeInMB = &swapSize
+				settingsService.Settings.Env.Bosh.SwapType = boshdisk.SwapTypeFile
+			})
+
+			It("does not carve swap partition out of ephemeral disk", func() {
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*platform.SetupEphemeralDiskWithPathSwapSize).To(Equal(uint64(0)))
+			})
+
+			It("sets up swap from env", func() {
+				err := bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(platform.SetupSwapSwaps).To(Equal([]boshsettings.Swap{
+					{Type: boshdisk.SwapTypeFile, SizeInMB: &swapSize},
+				}))
+			})
+
+			It("sets up swap from update settings when it was updated after deploy", func() {
+				updateSettingsPath := filepath.Join(platform.GetDirProvider().BoshDir(), "update_settings.json")
+				err := platform.GetFs().WriteFileString(updateSettingsPath, `{"swap":{"type":"zram","size":512}}`)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*platform.SetupEphemeralDiskWithPathSwapSize).To(Equal(uint64(0)))
+
+				updatedSwapSize := uint64(512)
+				Expect(platform.SetupSwapSwaps).To(Equal([]boshsettings.Swap{
+					{Type: boshdisk.SwapTypeZram, SizeInMB: &updatedSwapSize},
+				}))
+			})
+
+			It("carves swap partition out of ephemeral disk when swap was updated to partition after deploy", func() {
+				updateSettingsPath := filepath.Join(platform.GetDirProvider().BoshDir(), "update_settings.json")
+				err := platform.GetFs().WriteFileString(updateSettingsPath, `{"swap":{"type":"partition","size":512}}`)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*platform.SetupEphemeralDiskWithPathSwapSize).To(Equal(uint64(512 * 1024 * 1024)))
+
+				updatedSwapSize := uint64(512)
+				Expect(platform.SetupSwapSwaps).To(Equal([]boshsettings.Swap{
+					{Type: boshdisk.SwapTypePartition, SizeInMB: &updatedSwapSize},
+				}))
+			})
+
+			It("keeps swap type from env when swap was updated without a type", func() {
+				updateSettingsPath := filepath.Join(platform.GetDirProvider().BoshDir(), "update_settings.json")
+				err := platform.GetFs().WriteFileString(updateSettingsPath, `{"swap":{"size":512}}`)
+				Expect(err).NotTo(HaveOccurred())
+
+				err = bootstrap()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(*platform.SetupEphemeralDiskWithPathSwapSize).To(Equal(uint64(0)))
+
+				updatedSwapSize := uint64(512)
+				Expect(platform.SetupSwapSwaps).To(Equal([]boshsettings.Swap{
+					{Type: boshdisk.SwapTypeFile, SizeInMB: &updatedSwapSize},
+				}))
+			})
+		})
+
+		It("sets up partition swap by default", func() {
+			err := bootstrap()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(platform.SetupSwapSwaps).To(Equal([]boshsettings.Swap{{Type: boshdisk.SwapTypePartition}}))
+		})
+
+		It("returns error if setting up swap fails", func() {
+			platform.SetupSwapErr = errors.New("fake-setup-swap-err")
+
+			err := bootstrap()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Setting up swap: fake-setup-swap-err"))
+		})
+
 		It("sets up raw ephemeral disks if paths exist", func() {
 			settingsService.Settings.Disks = boshsettings.Disks{
 				RawEphemeral: []boshsettings.DiskSettings{{Path: "/dev/xvdb"}, {Path: "/dev/xvdc"}},
@@ -604,6 +744,59 @@ var _ = Describe("bootstrap", func() {
 							Expect(err).ToNot(HaveOccurred())
 						})
 					})
+
+					Context("when named disks are managed as well", func() {
+						BeforeEach(func() {
+							settingsService.Settings.Disks = boshsettings.Disks{
+								Persistent: map[string]interface{}{
+									"i-am-a-disk-cid":  "/dev/sdb",
+									"i-am-a-named-cid": "/dev/sdc",
+								},
+							}
+
+							err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{
+								DiskCID:    "i-am-a-named-cid",
+								Name:       "wal",
+								MountPoint: "/var/vcap/store/wal",
+							})
+							Expect(err).ToNot(HaveOccurred())
+
+							platform.IsPersistentDiskMountableResult = true
+						})
+
+						It("mounts named disk at its mount point after store disk", func() {
+							err := bootstrap()
+							Expect(err).ToNot(HaveOccurred())
+
+							Expect(platform.MountPersistentDiskSettings.ID).To(Equal("i-am-a-named-cid"))
+							Expect(platform.MountPersistentDiskMountPoint).To(Equal("/var/vcap/store/wal"))
+						})
+
+						It("returns an error when another disk is attached", func() {
+							settingsService.Settings.Disks.Persistent["i-am-unexpected"] = "/dev/sdd"
+
+							err := bootstrap()
+							Expect(err).To(HaveOccurred())
+							Expect(err.Error()).To(ContainSubstring("Unexpected disk attached"))
+						})
+
+						It("returns an error when unknown disk is attached in place of named disk", func() {
+							delete(settingsService.Settings.Disks.Persistent, "i-am-a-named-cid")
+							settingsService.Settings.Disks.Persistent["i-am-unexpected"] = "/dev/sdd"
+
+							err := bootstrap()
+							Expect(err).To(HaveOccurred())
+							Expect(err.Error()).To(ContainSubstring("Unexpected disk attached"))
+						})
+
+						It("returns an error when named disk cannot be mounted", func() {
+							platform.MountPersistentDiskErr = errors.New("fake-mount-err")
+
+							err := bootstrap()
+							Expect(err).To(HaveOccurred())
+							Expect(err.Error()).To(ContainSubstring("fake-mount-err"))
+						})
+					})
 				})
 			})
 
@@ -911,9 +1104,9 @@ var _ = Describe("bootstrap", func() {
 			compressor := boshcmd.NewTarballCompressor(runner, fs)
 			copier := boshcmd.NewGenericCpCopier(fs, logger)
 
-			sigarCollector := boshsigar.NewSigarStatsCollector(&sigar.ConcreteSigar{})
+			sigarCollector := boshsigar.NewSigarStatsCollector(&sigar.ConcreteSigar{}, fs)
 
-			vitalsService := boshvitals.NewService(sigarCollector, dirProvider)
+			vitalsService := boshvitals.NewService(sigarCollector, dirProvider, boshsettings.NewManagedDisks(fs, dirProvider.BoshDir()), nil, logger)
 
 			ipResolver := boship.NewResolver(boship.NetworkInterfaceToAddrsFunc)
 
@@ -951,6 +1144,7 @@ var _ = Describe("bootstrap", func() {
 				vitalsService,
 				linuxCdutil,
 				diskManager,
+				boshdisk.NewTrimScheduler(diskManager.FakeTrimmer, diskManager.FakeMounter, clock.NewClock(), logger),
 				ubuntuNetManager,
 				ubuntuCertManager,
 				monitRetryStrategy,
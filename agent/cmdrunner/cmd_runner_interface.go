@@ -17,4 +17,8 @@ type CmdResult struct {
 
 type CmdRunner interface {
 	RunCommand(jobName, taskName string, cmd boshsys.Command) (*CmdResult, error)
+
+	// RunCommandWithCancel terminates the whole process group of the command
+	// once cancelCh is closed and returns task.CancelledErr
+	RunCommandWithCancel(jobName, taskName string, cmd boshsys.Command, cancelCh <-chan struct{}) (*CmdResult, error)
 }
@@ -5,8 +5,10 @@ import (
 	"fmt"
 	"os"
 	"path"
+	"time"
 	"unicode/utf8"
 
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
@@ -14,6 +16,8 @@ import (
 const (
 	fileOpenFlag int         = os.O_RDWR | os.O_CREATE | os.O_TRUNC
 	fileOpenPerm os.FileMode = os.FileMode(0640)
+
+	cancelKillGracePeriod = 10 * time.Second
 )
 
 type FileLoggingCmdRunner struct {
@@ -62,6 +66,55 @@ func NewFileLoggingCmdRunner(
 }
 
 func (f FileLoggingCmdRunner) RunCommand(jobName string, taskName string, cmd boshsys.Command) (*CmdResult, error) {
+	return f.runCommand(jobName, taskName, cmd, func(cmd boshsys.Command) (int, error) {
+		// Stdout/stderr are redirected to the files
+		_, _, exitStatus, err := f.cmdRunner.RunComplexCommand(cmd)
+		return exitStatus, err
+	})
+}
+
+func (f FileLoggingCmdRunner) RunCommandWithCancel(jobName string, taskName string, cmd boshsys.Command, cancelCh <-chan struct{}) (*CmdResult, error) {
+	var startErr error
+	isCancelled := false
+
+	result, err := f.runCommand(jobName, taskName, cmd, func(cmd boshsys.Command) (int, error) {
+		process, err := f.cmdRunner.RunComplexCommandAsync(cmd)
+		if err != nil {
+			startErr = err
+			return -1, err
+		}
+
+		var result boshsys.Result
+
+		// Can only wait once on a process but cancelling can happen multiple times
+		for processExitedCh := process.Wait(); processExitedCh != nil; {
+			select {
+			case result = <-processExitedCh:
+				processExitedCh = nil
+			case <-cancelCh:
+				// Kills the whole process group after the grace period;
+				// closed channel is not selected again while waiting for the process to exit
+				_ = process.TerminateNicely(cancelKillGracePeriod)
+				isCancelled = true
+				cancelCh = nil
+			}
+		}
+
+		return result.ExitStatus, result.Error
+	})
+
+	if startErr != nil {
+		return nil, bosherr.WrapErrorf(startErr, "Starting command for task %s", taskName)
+	}
+
+	if isCancelled {
+		return nil, boshtask.CancelledErr
+	}
+
+	return result, err
+}
+
+func (f FileLoggingCmdRunner) runCommand(jobName string, taskName string, cmd boshsys.Command, run func(boshsys.Command) (int, error)) (*CmdResult, error) {
 	logsDir := path.Join(f.baseDir, jobName)
 
 	err := f.fs.RemoveAll(logsDir)
@@ -97,8 +150,7 @@ func (f FileLoggingCmdRunner) RunCommand(jobName string, taskName string, cmd bo
 
 	cmd.Stderr = stderrFile
 
-	// Stdout/stderr are redirected to the files
-	_, _, exitStatus, runErr := f.cmdRunner.RunComplexCommand(cmd)
+	exitStatus, runErr := run(cmd)
 
 	stdout, isStdoutTruncated, err := f.getTruncatedOutput(stdoutFile, f.truncateLength)
 	if err != nil {
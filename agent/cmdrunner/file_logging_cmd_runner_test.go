@@ -3,11 +3,13 @@ package cmdrunner_test
 import (
 	"errors"
 	"os"
+	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
@@ -293,4 +295,73 @@ var _ = Describe("FileLoggingCmdRunner", func() {
 			})
 		})
 	})
+
+	Describe("RunCommandWithCancel", func() {
+		var (
+			process  *fakesys.FakeProcess
+			cancelCh chan struct{}
+		)
+
+		BeforeEach(func() {
+			process = &fakesys.FakeProcess{}
+			cmdRunner.AddProcess("fake-cmd fake-args", process)
+			cancelCh = make(chan struct{})
+		})
+
+		It("runs command asynchronously with log files as stdout and stderr", func() {
+			_, err := runner.RunCommandWithCancel("fake-log-dir-name", "fake-log-file-name", cmd, cancelCh)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunComplexCommands).To(HaveLen(1))
+			Expect(cmdRunner.RunComplexCommands[0].Stdout).ToNot(BeNil())
+			Expect(cmdRunner.RunComplexCommands[0].Stderr).ToNot(BeNil())
+			Expect(process.Waited).To(BeTrue())
+			Expect(fs.FileExists("/fake-base-dir/fake-log-dir-name/fake-log-file-name.stdout.log")).To(BeTrue())
+			Expect(fs.FileExists("/fake-base-dir/fake-log-dir-name/fake-log-file-name.stderr.log")).To(BeTrue())
+		})
+
+		It("returns result with exit status when command succeeds", func() {
+			process.WaitResult = boshsys.Result{ExitStatus: 0}
+
+			result, err := runner.RunCommandWithCancel("fake-log-dir-name", "fake-log-file-name", cmd, cancelCh)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result.ExitStatus).To(Equal(0))
+		})
+
+		It("returns script error when command fails", func() {
+			process.WaitResult = boshsys.Result{ExitStatus: 1, Error: errors.New("fake-result-error")}
+
+			result, err := runner.RunCommandWithCancel("fake-log-dir-name", "fake-log-file-name", cmd, cancelCh)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Command exited with 1"))
+			Expect(result).To(BeNil())
+		})
+
+		It("returns error when command cannot be started", func() {
+			process.StartErr = errors.New("fake-start-error")
+
+			result, err := runner.RunCommandWithCancel("fake-log-dir-name", "fake-log-file-name", cmd, cancelCh)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-start-error"))
+			Expect(result).To(BeNil())
+		})
+
+		Context("when cancelled", func() {
+			BeforeEach(func() {
+				process.TerminatedNicelyCallBack = func(p *fakesys.FakeProcess) {
+					p.WaitCh <- boshsys.Result{ExitStatus: 143, Error: errors.New("fake-terminated-error")}
+				}
+				close(cancelCh)
+			})
+
+			It("terminates process group nicely and returns cancelled error", func() {
+				result, err := runner.RunCommandWithCancel("fake-log-dir-name", "fake-log-file-name", cmd, cancelCh)
+				Expect(err).To(Equal(boshtask.CancelledErr))
+				Expect(result).To(BeNil())
+
+				Expect(process.TerminatedNicely).To(BeTrue())
+				Expect(process.TerminateNicelyKillGracePeriod).To(Equal(10 * time.Second))
+			})
+		})
+	})
 })
@@ -2,11 +2,14 @@ package compiler
 
 import (
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
 )
 
 type Compiler interface {
-	Compile(pkg Package, deps []boshmodels.Package) (blobID string, digest boshcrypto.Digest, err error)
+	// Compile stops and cleans up after itself with task.CancelledErr
+	// once cancelCh is closed
+	Compile(pkg Package, deps []boshmodels.Package, progress boshtask.ProgressReporter, cancelCh <-chan struct{}) (blobID string, digest boshcrypto.Digest, err error)
 }
 
 type Package struct {
@@ -3,11 +3,12 @@
 package compiler
 
 import (
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
-func (c concreteCompiler) runPackagingCommand(compilePath, enablePath string, pkg Package) error {
+func (c concreteCompiler) runPackagingCommand(compilePath, enablePath string, pkg Package, cancelCh <-chan struct{}) error {
 	command := boshsys.Command{
 		Name: "bash",
 		Args: []string{"-x", PackagingScriptName},
@@ -19,8 +20,10 @@ func (c concreteCompiler) runPackagingCommand(compilePath, enablePath string, pk
 		},
 		WorkingDir: compilePath,
 	}
-	_, err := c.runner.RunCommand("compilation", PackagingScriptName, command)
-	if err != nil {
+	_, err := c.runner.RunCommandWithCancel("compilation", PackagingScriptName, command, cancelCh)
+	if err == boshtask.CancelledErr {
+		return err
+	} else if err != nil {
 		return bosherr.WrapError(err, "Running packaging script")
 	}
 	return nil
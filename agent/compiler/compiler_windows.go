@@ -3,11 +3,12 @@ package compiler
 import (
 	"fmt"
 
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
-func (c concreteCompiler) runPackagingCommand(compilePath, enablePath string, pkg Package) error {
+func (c concreteCompiler) runPackagingCommand(compilePath, enablePath string, pkg Package, cancelCh <-chan struct{}) error {
 	command := boshsys.Command{
 		Name: "powershell",
 		Args: []string{"-command", fmt.Sprintf("iex (get-content -raw %s)", PackagingScriptName)},
@@ -20,8 +21,10 @@ func (c concreteCompiler) runPackagingCommand(compilePath, enablePath string, pk
 		WorkingDir: compilePath,
 	}
 
-	_, err := c.runner.RunCommand("compilation", PackagingScriptName, command)
-	if err != nil {
+	_, err := c.runner.RunCommandWithCancel("compilation", PackagingScriptName, command, cancelCh)
+	if err == boshtask.CancelledErr {
+		return err
+	} else if err != nil {
 		return bosherr.WrapError(err, "Running packaging script")
 	}
 	return nil
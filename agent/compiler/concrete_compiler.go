@@ -9,6 +9,7 @@ import (
 	boshmodels "github.com/cloudfoundry/bosh-agent/agent/applier/models"
 	"github.com/cloudfoundry/bosh-agent/agent/applier/packages"
 	boshcmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshblob "github.com/cloudfoundry/bosh-utils/blobstore"
 	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
@@ -18,6 +19,15 @@ import (
 
 const PackagingScriptName = "packaging"
 
+const (
+	ProgressPhaseInstallingDependencies = "installing dependencies"
+	ProgressPhaseFetchingSource         = "fetching source"
+	ProgressPhasePackaging              = "running packaging script"
+	ProgressPhaseCompressing            = "compressing"
+	ProgressPhaseUploading              = "uploading"
+	ProgressPhaseUploaded               = "uploaded"
+)
+
 type CompileDirProvider interface {
 	CompileDir() string
 }
@@ -52,13 +62,22 @@ func NewConcreteCompiler(
 	}
 }
 
-func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package) (blobID string, digest boshcrypto.Digest, err error) {
+func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package, progress boshtask.ProgressReporter, cancelCh <-chan struct{}) (blobID string, digest boshcrypto.Digest, err error) {
 	err = c.packageApplier.KeepOnly([]boshmodels.Package{})
 	if err != nil {
 		return "", nil, bosherr.WrapError(err, "Removing packages")
 	}
 
-	for _, dep := range deps {
+	for i, dep := range deps {
+		if boshtask.IsCancelled(cancelCh) {
+			return "", nil, boshtask.CancelledErr
+		}
+
+		progress.ReportProgress(boshtask.Progress{
+			Phase:   ProgressPhaseInstallingDependencies,
+			Percent: float64(20*i) / float64(len(deps)),
+		})
+
 		err := c.packageApplier.Apply(dep)
 		if err != nil {
 			return "", nil, bosherr.WrapErrorf(err, "Installing dependent package: '%s'", dep.Name)
@@ -67,6 +86,12 @@ func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package) (blobI
 
 	compilePath := path.Join(c.compileDirProvider.CompileDir(), pkg.Name)
 
+	if boshtask.IsCancelled(cancelCh) {
+		return "", nil, boshtask.CancelledErr
+	}
+
+	progress.ReportProgress(boshtask.Progress{Phase: ProgressPhaseFetchingSource, Percent: 20})
+
 	err = c.fetchAndUncompress(pkg, compilePath)
 	if err != nil {
 		return "", nil, bosherr.WrapErrorf(err, "Fetching package %s", pkg.Name)
@@ -101,14 +126,34 @@ func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package) (blobI
 		return "", nil, bosherr.WrapError(err, "Enabling new package bundle")
 	}
 
+	// Partially compiled package must not be left installed after cancellation
+	defer func() {
+		if err == boshtask.CancelledErr {
+			_ = compiledPkgBundle.Disable()
+			_ = compiledPkgBundle.Uninstall()
+			_ = c.packageApplier.KeepOnly([]boshmodels.Package{})
+		}
+	}()
+
 	scriptPath := path.Join(compilePath, PackagingScriptName)
 
 	if c.fs.FileExists(scriptPath) {
-		if err := c.runPackagingCommand(compilePath, enablePath, pkg); err != nil {
+		progress.ReportProgress(boshtask.Progress{Phase: ProgressPhasePackaging, Percent: 30})
+
+		err = c.runPackagingCommand(compilePath, enablePath, pkg, cancelCh)
+		if err == boshtask.CancelledErr {
+			return "", nil, err
+		} else if err != nil {
 			return "", nil, bosherr.WrapError(err, "Running packaging script")
 		}
 	}
 
+	if boshtask.IsCancelled(cancelCh) {
+		return "", nil, boshtask.CancelledErr
+	}
+
+	progress.ReportProgress(boshtask.Progress{Phase: ProgressPhaseCompressing, Percent: 80})
+
 	tmpPackageTar, err := c.compressor.CompressFilesInDir(installPath)
 	if err != nil {
 		return "", nil, bosherr.WrapError(err, "Compressing compiled package")
@@ -129,11 +174,34 @@ func (c concreteCompiler) Compile(pkg Package, deps []boshmodels.Package) (blobI
 		return "", nil, bosherr.WrapError(err, "Calculating compiled package digest")
 	}
 
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return "", nil, bosherr.WrapError(err, "Getting compiled package size")
+	}
+
+	if boshtask.IsCancelled(cancelCh) {
+		return "", nil, boshtask.CancelledErr
+	}
+
+	progress.ReportProgress(boshtask.Progress{Phase: ProgressPhaseUploading, Percent: 90})
+
 	uploadedBlobID, _, err := c.blobstore.Create(tmpPackageTar)
 	if err != nil {
 		return "", nil, bosherr.WrapError(err, "Uploading compiled package")
 	}
 
+	// Director will never learn about the blob if task was cancelled during upload
+	if boshtask.IsCancelled(cancelCh) {
+		_ = c.blobstore.Delete(uploadedBlobID)
+		return "", nil, boshtask.CancelledErr
+	}
+
+	progress.ReportProgress(boshtask.Progress{
+		Phase:            ProgressPhaseUploaded,
+		Percent:          100,
+		BytesTransferred: uint64(fileInfo.Size()),
+	})
+
 	err = compiledPkgBundle.Disable()
 	if err != nil {
 		return "", nil, bosherr.WrapError(err, "Disabling compiled package")
@@ -14,6 +14,8 @@ import (
 	fakepackages "github.com/cloudfoundry/bosh-agent/agent/applier/packages/fakes"
 	fakecmdrunner "github.com/cloudfoundry/bosh-agent/agent/cmdrunner/fakes"
 	. "github.com/cloudfoundry/bosh-agent/agent/compiler"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	fakeblobstore "github.com/cloudfoundry/bosh-utils/blobstore/fakes"
 	boshcrypto "github.com/cloudfoundry/bosh-utils/crypto"
 	fakecmd "github.com/cloudfoundry/bosh-utils/fileutil/fakes"
@@ -67,6 +69,8 @@ func init() {
 			runner         *fakecmdrunner.FakeFileLoggingCmdRunner
 			packageApplier *fakepackages.FakeApplier
 			packagesBc     *fakebc.FakeBundleCollection
+			progress       *faketask.FakeProgressReporter
+			cancelCh       chan struct{}
 		)
 
 		BeforeEach(func() {
@@ -76,6 +80,8 @@ func init() {
 			runner = fakecmdrunner.NewFakeFileLoggingCmdRunner()
 			packageApplier = fakepackages.NewFakeApplier()
 			packagesBc = fakebc.NewFakeBundleCollection()
+			progress = &faketask.FakeProgressReporter{}
+			cancelCh = make(chan struct{})
 
 			compiler = NewConcreteCompiler(
 				compressor,
@@ -115,20 +121,40 @@ func init() {
 			It("returns blob id and sha1 of created compiled package", func() {
 				blobstore.CreateReturns("fake-blob-id", boshcrypto.MultipleDigest{}, nil)
 
-				blobID, digest, err := compiler.Compile(pkg, pkgDeps)
+				blobID, digest, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 
 				Expect(blobID).To(Equal("fake-blob-id"))
 				Expect(digest).To(Equal(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA1, "978ad524a02039f261773fe93d94973ae7de6470")))
 			})
 
+			It("reports progress for each compilation phase", func() {
+				blobstore.CreateReturns("fake-blob-id", boshcrypto.MultipleDigest{}, nil)
+				compressor.DecompressFileToDirCallBack = func() {
+					fs.WriteFileString("/fake-compile-dir/pkg_name/"+PackagingScriptName, "")
+				}
+
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(progress.Reported).To(Equal([]boshtask.Progress{
+					{Phase: "installing dependencies", Percent: 0},
+					{Phase: "installing dependencies", Percent: 10},
+					{Phase: "fetching source", Percent: 20},
+					{Phase: "running packaging script", Percent: 30},
+					{Phase: "compressing", Percent: 80},
+					{Phase: "uploading", Percent: 90},
+					{Phase: "uploaded", Percent: 100, BytesTransferred: uint64(len("fake-contents"))},
+				}))
+			})
+
 			It("returns blob id and correct sha algo of created compiled package", func() {
 				blobstore.CreateReturns("fake-blob-id", boshcrypto.MultipleDigest{}, nil)
 
 				// Currently algo of source package is used for compilation pkg algo
 				pkg.Sha1 = boshcrypto.MustNewMultipleDigest(boshcrypto.NewDigest(boshcrypto.DigestAlgorithmSHA256, "fakesha"))
 
-				_, digest, err := compiler.Compile(pkg, pkgDeps)
+				_, digest, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 				// echo -n fake-contents|shasum -a 256
 				Expect(digest.String()).To(Equal("sha256:d12d3a3ee8dcdc9e7ea3416fd618298ea50abde2cf434313c6c3edb213f441cd"))
@@ -139,7 +165,7 @@ func init() {
 			})
 
 			It("cleans up all packages before and after applying dependent packages", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(packageApplier.ActionsCalled).To(Equal([]string{"KeepOnly", "Apply", "Apply", "KeepOnly"}))
 				Expect(packageApplier.KeptOnlyPackages).To(BeEmpty())
@@ -148,7 +174,7 @@ func init() {
 			It("returns an error if cleaning up packages fails", func() {
 				packageApplier.KeepOnlyErr = errors.New("fake-keep-only-error")
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-keep-only-error"))
 			})
@@ -161,7 +187,7 @@ func init() {
 					return nil
 				}
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-remove-error"))
 			})
@@ -174,7 +200,7 @@ func init() {
 					return nil
 				}
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-mkdir-error"))
 			})
@@ -187,7 +213,7 @@ func init() {
 					return nil
 				}
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-remove-error"))
 			})
@@ -195,7 +221,7 @@ func init() {
 			It("returns an error if creating temporary compile target directory during uncompression fails", func() {
 				fs.RegisterMkdirAllError("/fake-compile-dir/pkg_name-bosh-agent-unpack", errors.New("fake-mkdir-error"))
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-mkdir-error"))
 			})
@@ -203,25 +229,25 @@ func init() {
 			It("returns an error if target directory is empty during uncompression", func() {
 				pkg.BlobstoreID = ""
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("Blobstore ID for package '%s' is empty", pkg.Name))
 			})
 
 			It("installs dependent packages", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(packageApplier.AppliedPackages).To(Equal(pkgDeps))
 			})
 
 			It("cleans up the compile directory", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(fs.FileExists("/fake-compile-dir/pkg_name")).To(BeFalse())
 			})
 
 			It("installs, enables and later cleans up bundle", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(bundle.ActionsCalled).To(Equal([]string{
 					"InstallWithoutContents",
@@ -243,7 +269,7 @@ func init() {
 					return nil
 				}
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-remove-error"))
 			})
@@ -258,7 +284,7 @@ func init() {
 				})
 
 				It("runs packaging script ", func() {
-					_, _, err := compiler.Compile(pkg, pkgDeps)
+					_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 					Expect(err).ToNot(HaveOccurred())
 
 					expectedCmd := boshsys.Command{
@@ -286,23 +312,46 @@ func init() {
 					Expect(runner.RunCommandTaskName).To(Equal(PackagingScriptName))
 				})
 
+				It("runs packaging script with cancel channel", func() {
+					_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
+					Expect(err).ToNot(HaveOccurred())
+					close(cancelCh)
+					Expect(runner.RunCommandCancelCh).To(BeClosed())
+				})
+
+				It("returns cancelled error and cleans up bundle when packaging script is cancelled", func() {
+					runner.RunCommandErr = boshtask.CancelledErr
+
+					_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
+					Expect(err).To(Equal(boshtask.CancelledErr))
+
+					Expect(bundle.ActionsCalled).To(Equal([]string{
+						"InstallWithoutContents",
+						"Enable",
+						"Disable",
+						"Uninstall",
+					}))
+					Expect(fs.FileExists("/fake-compile-dir/pkg_name")).To(BeFalse())
+					Expect(blobstore.CreateCallCount()).To(Equal(0))
+				})
+
 				It("propagates the error from packaging script", func() {
 					runner.RunCommandErr = errors.New("fake-packaging-error")
 
-					_, _, err := compiler.Compile(pkg, pkgDeps)
+					_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 					Expect(err).To(HaveOccurred())
 					Expect(err.Error()).To(ContainSubstring("fake-packaging-error"))
 				})
 			})
 
 			It("does not run packaging script when script does not exist", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(runner.RunCommands).To(BeEmpty())
 			})
 
 			It("compresses compiled package", func() {
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 
 				// archive was downloaded from the blobstore and decompress to this temp dir
@@ -321,7 +370,7 @@ func init() {
 			It("uploads compressed package to blobstore", func() {
 				compressor.CompressFilesInDirTarballPath = "/tmp/compressed-compiled-package"
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 				Expect(blobstore.CreateArgsForCall(0)).To(Equal("/tmp/compressed-compiled-package"))
 			})
@@ -329,11 +378,42 @@ func init() {
 			It("returs error if uploading compressed package fails", func() {
 				blobstore.CreateReturns("", boshcrypto.MultipleDigest{}, errors.New("fake-create-err"))
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).To(HaveOccurred())
 				Expect(err.Error()).To(ContainSubstring("fake-create-err"))
 			})
 
+			Context("when cancelled", func() {
+				It("returns cancelled error before installing dependencies", func() {
+					close(cancelCh)
+
+					_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
+					Expect(err).To(Equal(boshtask.CancelledErr))
+					Expect(packageApplier.AppliedPackages).To(BeEmpty())
+					Expect(blobstore.CreateCallCount()).To(Equal(0))
+				})
+
+				It("deletes compiled package blob when cancelled during upload", func() {
+					blobstore.CreateStub = func(fileName string) (string, boshcrypto.MultipleDigest, error) {
+						close(cancelCh)
+						return "fake-blob-id", boshcrypto.MultipleDigest{}, nil
+					}
+
+					_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
+					Expect(err).To(Equal(boshtask.CancelledErr))
+
+					Expect(blobstore.DeleteCallCount()).To(Equal(1))
+					Expect(blobstore.DeleteArgsForCall(0)).To(Equal("fake-blob-id"))
+					Expect(compressor.CleanUpTarballPath).To(Equal("/tmp/compressed-compiled-package"))
+					Expect(bundle.ActionsCalled).To(Equal([]string{
+						"InstallWithoutContents",
+						"Enable",
+						"Disable",
+						"Uninstall",
+					}))
+				})
+			})
+
 			It("cleans up compressed package after uploading it to blobstore", func() {
 				var beforeCleanUpTarballPath, afterCleanUpTarballPath string
 
@@ -342,7 +422,7 @@ func init() {
 					return "my-blob-id", boshcrypto.MultipleDigest{}, nil
 				}
 
-				_, _, err := compiler.Compile(pkg, pkgDeps)
+				_, _, err := compiler.Compile(pkg, pkgDeps, progress, cancelCh)
 				Expect(err).ToNot(HaveOccurred())
 
 				// Compressed package is not cleaned up before blobstore upload
@@ -0,0 +1,144 @@
+package agent
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const networkDriftReconcilerLogTag = "NetworkDriftReconciler"
+
+// NetworkDriftReconciler periodically compares networking with networks
+// configured during bootstrap since something on the box might rewrite
+// configuration files or drop addresses after agent configured them
+type NetworkDriftReconciler struct {
+	platform                    boshplatform.Platform
+	settingsService             boshsettings.Service
+	interfaceAddressesValidator boship.InterfaceAddressesValidator
+	interval                    time.Duration
+	repair                      bool
+	timeService                 clock.Clock
+	logger                      boshlog.Logger
+
+	checkLock sync.RWMutex
+	check     boshnet.DriftCheck
+	checked   bool
+}
+
+// NewNetworkDriftReconciler does not check networking when interval is not positive;
+// when repair is set networking is reapplied once drift is detected
+func NewNetworkDriftReconciler(
+	platform boshplatform.Platform,
+	settingsService boshsettings.Service,
+	interfaceAddressesValidator boship.InterfaceAddressesValidator,
+	interval time.Duration,
+	repair bool,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) *NetworkDriftReconciler {
+	return &NetworkDriftReconciler{
+		platform:                    platform,
+		settingsService:             settingsService,
+		interfaceAddressesValidator: interfaceAddressesValidator,
+		interval:                    interval,
+		repair:                      repair,
+		timeService:                 timeService,
+		logger:                      logger,
+	}
+}
+
+// Run reconciles networking every interval until stopCh is closed
+func (r *NetworkDriftReconciler) Run(stopCh <-chan struct{}) {
+	if r.interval <= 0 {
+		return
+	}
+
+	defer r.logger.HandlePanic("Network Drift Reconciler")
+
+	ticker := r.timeService.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C():
+			r.Reconcile(r.repair)
+		case <-stopCh:
+			return
+		}
+	}
+}
+
+// Reconcile records whether networking drifted; when repair is set
+// networking is reapplied right away instead of waiting for an operator
+func (r *NetworkDriftReconciler) Reconcile(repair bool) {
+	networks := r.settingsService.GetSettings().Networks
+
+	drift, err := r.platform.DetectNetworkDrift(networks, r.interfaceAddressesValidator)
+	if err != nil {
+		r.logger.Error(networkDriftReconcilerLogTag, "Detecting network drift: %s", err.Error())
+		return
+	}
+
+	check := boshnet.DriftCheck{
+		CheckedAt: r.timeService.Now().Unix(),
+		Drift:     drift,
+	}
+
+	if drift.Detected() {
+		r.logger.Warn(networkDriftReconcilerLogTag, "Network drift detected: %s", drift.String())
+
+		if repair {
+			check.RepairErr = r.platform.RepairNetworking(networks)
+			check.Repaired = check.RepairErr == nil
+
+			if check.RepairErr != nil {
+				r.logger.Error(networkDriftReconcilerLogTag, "Repairing network drift: %s", check.RepairErr.Error())
+			} else {
+				r.logger.Info(networkDriftReconcilerLogTag, "Repaired network drift")
+			}
+		}
+	}
+
+	r.recordCheck(check)
+}
+
+// LastCheck is not found until networking was checked at least once
+func (r *NetworkDriftReconciler) LastCheck() (boshnet.DriftCheck, bool) {
+	r.checkLock.RLock()
+	defer r.checkLock.RUnlock()
+
+	return r.check, r.checked
+}
+
+// MarkAlerted only marks check that was alerted on
+// since a newer check might have been recorded in the meantime
+func (r *NetworkDriftReconciler) MarkAlerted(checkedAt int64) {
+	r.checkLock.Lock()
+	defer r.checkLock.Unlock()
+
+	if r.check.CheckedAt == checkedAt {
+		r.check.Alerted = true
+	}
+}
+
+func (r *NetworkDriftReconciler) recordCheck(check boshnet.DriftCheck) {
+	r.checkLock.Lock()
+	defer r.checkLock.Unlock()
+
+	// Drift that was reported and is still not repaired is not reported again;
+	// every repair is reported since it restarts networking
+	previous := r.check
+	if previous.Alerted && previous.Drift.Detected() && !previous.Repaired && !check.Repaired {
+		check.Alerted = check.Drift.Detected()
+	}
+
+	r.check = check
+	r.checked = true
+}
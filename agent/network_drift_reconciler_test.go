@@ -0,0 +1,200 @@
+package agent_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	fakeplatform "github.com/cloudfoundry/bosh-agent/platform/fakes"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	fakeip "github.com/cloudfoundry/bosh-agent/platform/net/ip/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	fakesettings "github.com/cloudfoundry/bosh-agent/settings/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("NetworkDriftReconciler", func() {
+	var (
+		platform        *fakeplatform.FakePlatform
+		settingsService *fakesettings.FakeSettingsService
+		validator       boship.InterfaceAddressesValidator
+		timeService     *fakeclock.FakeClock
+		networks        boshsettings.Networks
+		reconciler      *NetworkDriftReconciler
+	)
+
+	BeforeEach(func() {
+		platform = fakeplatform.NewFakePlatform()
+		networks = boshsettings.Networks{"fake-net": boshsettings.Network{IP: "1.2.3.4"}}
+		settingsService = &fakesettings.FakeSettingsService{
+			Settings: boshsettings.Settings{Networks: networks},
+		}
+		validator = boship.NewInterfaceAddressesValidator(&fakeip.FakeInterfaceAddressesProvider{})
+		timeService = fakeclock.NewFakeClock(time.Unix(1306076861, 0))
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		reconciler = NewNetworkDriftReconciler(platform, settingsService, validator, time.Minute, true, timeService, logger)
+	})
+
+	Describe("Reconcile", func() {
+		It("does not have a check until networking was checked", func() {
+			_, found := reconciler.LastCheck()
+			Expect(found).To(BeFalse())
+		})
+
+		It("compares networking with networks from settings", func() {
+			reconciler.Reconcile(false)
+
+			Expect(platform.DetectNetworkDriftNetworks).To(Equal(networks))
+			Expect(platform.DetectNetworkDriftValidator).To(Equal(validator))
+
+			check, found := reconciler.LastCheck()
+			Expect(found).To(BeTrue())
+			Expect(check).To(Equal(boshnet.DriftCheck{CheckedAt: 1306076861}))
+			Expect(platform.RepairNetworkingCalled).To(BeFalse())
+		})
+
+		It("records drift without repairing it when repair is not requested", func() {
+			platform.DetectNetworkDriftDrift = boshnet.Drift{ConfigsChanged: true}
+
+			reconciler.Reconcile(false)
+
+			check, _ := reconciler.LastCheck()
+			Expect(check.Drift).To(Equal(boshnet.Drift{ConfigsChanged: true}))
+			Expect(check.Repaired).To(BeFalse())
+			Expect(platform.RepairNetworkingCalled).To(BeFalse())
+		})
+
+		It("repairs drift when repair is requested", func() {
+			platform.DetectNetworkDriftDrift = boshnet.Drift{ConfigsChanged: true}
+
+			reconciler.Reconcile(true)
+
+			Expect(platform.RepairNetworkingNetworks).To(Equal(networks))
+
+			check, _ := reconciler.LastCheck()
+			Expect(check.Repaired).To(BeTrue())
+			Expect(check.RepairErr).ToNot(HaveOccurred())
+		})
+
+		It("records repair error", func() {
+			platform.DetectNetworkDriftDrift = boshnet.Drift{ConfigsChanged: true}
+			platform.RepairNetworkingErr = errors.New("fake-repair-err")
+
+			reconciler.Reconcile(true)
+
+			check, _ := reconciler.LastCheck()
+			Expect(check.Repaired).To(BeFalse())
+			Expect(check.RepairErr).To(MatchError("fake-repair-err"))
+		})
+
+		It("keeps previous check when drift cannot be detected", func() {
+			reconciler.Reconcile(false)
+
+			platform.DetectNetworkDriftErr = errors.New("fake-detect-err")
+			timeService.Increment(time.Minute)
+
+			reconciler.Reconcile(false)
+
+			check, _ := reconciler.LastCheck()
+			Expect(check.CheckedAt).To(Equal(int64(1306076861)))
+		})
+
+		It("does not report drift again until it goes away", func() {
+			platform.DetectNetworkDriftDrift = boshnet.Drift{ConfigsChanged: true}
+
+			reconciler.Reconcile(false)
+			reconciler.MarkAlerted(1306076861)
+
+			timeService.Increment(time.Minute)
+			reconciler.Reconcile(false)
+
+			check, _ := reconciler.LastCheck()
+			Expect(check.Alerted).To(BeTrue())
+
+			platform.DetectNetworkDriftDrift = boshnet.Drift{}
+			reconciler.Reconcile(false)
+
+			platform.DetectNetworkDriftDrift = boshnet.Drift{ConfigsChanged: true}
+			reconciler.Reconcile(false)
+
+			check, _ = reconciler.LastCheck()
+			Expect(check.Alerted).To(BeFalse())
+		})
+
+		It("does not mark newer check that was not alerted on", func() {
+			platform.DetectNetworkDriftDrift = boshnet.Drift{ConfigsChanged: true}
+
+			reconciler.Reconcile(false)
+			alertedCheck, _ := reconciler.LastCheck()
+
+			platform.DetectNetworkDriftDrift = boshnet.Drift{DNSErr: errors.New("fake-dns-err")}
+			timeService.Increment(time.Minute)
+			reconciler.Reconcile(false)
+
+			reconciler.MarkAlerted(alertedCheck.CheckedAt)
+
+			check, _ := reconciler.LastCheck()
+			Expect(check.CheckedAt).To(Equal(int64(1306076921)))
+			Expect(check.Alerted).To(BeFalse())
+		})
+
+		It("reports every repair", func() {
+			platform.DetectNetworkDriftDrift = boshnet.Drift{ConfigsChanged: true}
+
+			reconciler.Reconcile(true)
+			reconciler.MarkAlerted(1306076861)
+
+			timeService.Increment(time.Minute)
+			reconciler.Reconcile(true)
+
+			check, _ := reconciler.LastCheck()
+			Expect(check.Repaired).To(BeTrue())
+			Expect(check.Alerted).To(BeFalse())
+		})
+	})
+
+	Describe("Run", func() {
+		It("checks networking every interval until stopped", func() {
+			platform.DetectNetworkDriftDrift = boshnet.Drift{ConfigsChanged: true}
+
+			stopCh := make(chan struct{})
+			doneCh := make(chan struct{})
+			go func() {
+				reconciler.Run(stopCh)
+				close(doneCh)
+			}()
+
+			Eventually(timeService.WatcherCount).Should(Equal(1))
+
+			_, found := reconciler.LastCheck()
+			Expect(found).To(BeFalse())
+
+			timeService.Increment(time.Minute)
+
+			Eventually(func() bool {
+				check, _ := reconciler.LastCheck()
+				return check.Repaired
+			}).Should(BeTrue())
+
+			close(stopCh)
+			Eventually(doneCh).Should(BeClosed())
+		})
+
+		It("does not check networking when interval is not positive", func() {
+			logger := boshlog.NewLogger(boshlog.LevelNone)
+			reconciler = NewNetworkDriftReconciler(platform, settingsService, validator, 0, true, timeService, logger)
+
+			reconciler.Run(make(chan struct{}))
+
+			Expect(timeService.WatcherCount()).To(Equal(0))
+			_, found := reconciler.LastCheck()
+			Expect(found).To(BeFalse())
+		})
+	})
+})
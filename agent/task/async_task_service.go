@@ -1,38 +1,55 @@
 package task
 
 import (
+	"sort"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshuuid "github.com/cloudfoundry/bosh-utils/uuid"
 )
 
-// Access to the currentTasks map should always be performed in the semaphore
+// Access to the currentTasks map and the scheduler should always be performed in the semaphore
 // Use the taskSem channel for that
 
 type asyncTaskService struct {
-	uuidGen boshuuid.Generator
-	logger  boshlog.Logger
+	uuidGen     boshuuid.Generator
+	history     History
+	retention   time.Duration
+	timeService clock.Clock
+	logger      boshlog.Logger
 
 	currentTasks map[string]Task
-	taskChan     chan Task
+	scheduler    *taskScheduler
 	taskSem      chan func()
 }
 
-func NewAsyncTaskService(uuidGen boshuuid.Generator, logger boshlog.Logger) (service Service) {
-	s := asyncTaskService{
+func NewAsyncTaskService(
+	uuidGen boshuuid.Generator,
+	policy ConcurrencyPolicy,
+	history History,
+	retention time.Duration,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) (service Service) {
+	s := &asyncTaskService{
 		uuidGen:      uuidGen,
+		history:      history,
+		retention:    retention,
+		timeService:  timeService,
 		logger:       logger,
 		currentTasks: make(map[string]Task),
-		taskChan:     make(chan Task),
+		scheduler:    newTaskScheduler(policy),
 		taskSem:      make(chan func()),
 	}
 
-	go s.processTasks()
 	go s.processSemFuncs()
 
 	return s
 }
 
-func (service asyncTaskService) CreateTask(
+func (service *asyncTaskService) CreateTask(
 	taskFunc Func,
 	cancelFunc CancelFunc,
 	endFunc EndFunc,
@@ -45,34 +62,37 @@ func (service asyncTaskService) CreateTask(
 	return service.CreateTaskWithID(uuid, taskFunc, cancelFunc, endFunc), nil
 }
 
-func (service asyncTaskService) CreateTaskWithID(
+func (service *asyncTaskService) CreateTaskWithID(
 	id string,
 	taskFunc Func,
 	cancelFunc CancelFunc,
 	endFunc EndFunc,
 ) Task {
 	return Task{
-		ID:         id,
-		State:      StateRunning,
-		Func:       taskFunc,
-		CancelFunc: cancelFunc,
-		EndFunc:    endFunc,
+		ID:           id,
+		State:        StateRunning,
+		Func:         taskFunc,
+		CancelFunc:   cancelFunc,
+		EndFunc:      endFunc,
+		ProgressChan: make(chan Progress, 1),
+		canceller:    newCanceller(),
 	}
 }
 
-func (service asyncTaskService) StartTask(task Task) {
-	taskChan := make(chan Task)
+func (service *asyncTaskService) StartTask(task Task) {
+	doneChan := make(chan struct{})
 
 	service.taskSem <- func() {
 		service.currentTasks[task.ID] = task
-		taskChan <- task
+		service.scheduler.Enqueue(task)
+		service.runReadyTasks()
+		close(doneChan)
 	}
 
-	recordedTask := <-taskChan
-	service.taskChan <- recordedTask
+	<-doneChan
 }
 
-func (service asyncTaskService) FindTaskWithID(id string) (Task, bool) {
+func (service *asyncTaskService) FindTaskWithID(id string) (Task, bool) {
 	taskChan := make(chan Task)
 	foundChan := make(chan bool)
 
@@ -85,7 +105,55 @@ func (service asyncTaskService) FindTaskWithID(id string) (Task, bool) {
 	return <-taskChan, <-foundChan
 }
 
-func (service asyncTaskService) processSemFuncs() {
+func (service *asyncTaskService) ListTasks() ([]Record, error) {
+	recordsChan := make(chan []Record)
+
+	service.taskSem <- func() {
+		service.evictExpiredTasks()
+
+		now := service.timeService.Now()
+
+		var records []Record
+		for _, task := range service.currentTasks {
+			record := task.Record()
+			if task.State == StateRunning && !task.StartedAt.IsZero() {
+				record.Duration = now.Sub(task.StartedAt)
+			}
+			records = append(records, record)
+		}
+		recordsChan <- records
+	}
+
+	records := <-recordsChan
+
+	historyRecords, err := service.history.Records()
+	if err != nil {
+		return nil, err
+	}
+
+	inMemory := map[string]bool{}
+	for _, record := range records {
+		inMemory[record.TaskID] = true
+	}
+
+	for _, record := range historyRecords {
+		if !inMemory[record.TaskID] {
+			records = append(records, record)
+		}
+	}
+
+	// Tasks that have not started yet are listed last
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].StartedAt.IsZero() || records[j].StartedAt.IsZero() {
+			return !records[i].StartedAt.IsZero()
+		}
+		return records[i].StartedAt.Before(records[j].StartedAt)
+	})
+
+	return records, nil
+}
+
+func (service *asyncTaskService) processSemFuncs() {
 	defer service.logger.HandlePanic("Task Service Process Sem Funcs")
 
 	for {
@@ -94,33 +162,95 @@ func (service asyncTaskService) processSemFuncs() {
 	}
 }
 
-func (service asyncTaskService) processTasks() {
-	defer service.logger.HandlePanic("Task Service Process Tasks")
+// runReadyTasks must be called from within the semaphore
+func (service *asyncTaskService) runReadyTasks() {
+	for _, task := range service.scheduler.Next() {
+		service.logger.Debug("Task Service", "Starting task #%s (%s)", task.ID, task.Method)
+
+		task.StartedAt = service.timeService.Now()
+		service.currentTasks[task.ID] = task
+
+		// Progress channel is never closed since the action may still
+		// report progress after it returned; reading stops once task is done
+		doneChan := make(chan struct{})
+
+		go service.processProgress(task, doneChan)
+		go service.processTask(task, doneChan)
+	}
+}
+
+func (service *asyncTaskService) processProgress(task Task, doneChan <-chan struct{}) {
+	defer service.logger.HandlePanic("Task Service Process Progress")
+
+	if task.ProgressChan == nil {
+		return
+	}
 
 	for {
-		task := <-service.taskChan
-
-		value, err := task.Func()
-		if err != nil {
-			task.Error = err
-			task.State = StateFailed
-			service.logger.Error("Task Service", "Failed processing task #%s got: %s", task.ID, err.Error())
-		} else {
-			task.Value = value
-			task.State = StateDone
-		}
+		select {
+		case progress := <-task.ProgressChan:
+			service.taskSem <- func() {
+				currentTask, found := service.currentTasks[task.ID]
 
-		if task.EndFunc != nil {
-			task.EndFunc(task)
+				// Updates may arrive after the task has been marked as finished
+				if found && currentTask.State == StateRunning {
+					currentTask.Progress = &progress
+					service.currentTasks[task.ID] = currentTask
+				}
+			}
+		case <-doneChan:
+			return
 		}
+	}
+}
 
-		// Nil to prevent to memory leaks in case these are closures.
-		task.Func = nil
-		task.CancelFunc = nil
-		task.EndFunc = nil
+// evictExpiredTasks must be called from within the semaphore
+func (service *asyncTaskService) evictExpiredTasks() {
+	cutoff := service.timeService.Now().Add(-service.retention)
 
-		service.taskSem <- func() {
-			service.currentTasks[task.ID] = task
+	for id, task := range service.currentTasks {
+		if task.State != StateRunning && task.FinishedAt.Before(cutoff) {
+			delete(service.currentTasks, id)
 		}
 	}
 }
+
+func (service *asyncTaskService) processTask(task Task, doneChan chan struct{}) {
+	defer service.logger.HandlePanic("Task Service Process Task")
+
+	value, err := task.Func()
+	if err != nil {
+		task.Error = err
+		task.State = StateFailed
+		service.logger.Error("Task Service", "Failed processing task #%s got: %s", task.ID, err.Error())
+	} else {
+		task.Value = value
+		task.State = StateDone
+	}
+
+	task.FinishedAt = service.timeService.Now()
+
+	close(doneChan)
+
+	if task.EndFunc != nil {
+		task.EndFunc(task)
+	}
+
+	// Nil to prevent to memory leaks in case these are closures.
+	task.Func = nil
+	task.CancelFunc = nil
+	task.EndFunc = nil
+	task.ProgressChan = nil
+
+	err = service.history.AddRecord(task.Record())
+	if err != nil {
+		service.logger.Error("Task Service", "Failed recording task #%s in history: %s", task.ID, err.Error())
+	}
+
+	service.taskSem <- func() {
+		service.currentTasks[task.ID] = task
+		service.scheduler.Finished(task)
+		service.evictExpiredTasks()
+		service.runReadyTasks()
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
 	. "github.com/cloudfoundry/bosh-agent/agent/task"
+	faketask "github.com/cloudfoundry/bosh-agent/agent/task/fakes"
 	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	fakeuuid "github.com/cloudfoundry/bosh-utils/uuid/fakes"
 )
@@ -16,13 +18,23 @@ import (
 func init() {
 	Describe("asyncTaskService", func() {
 		var (
-			uuidGen *fakeuuid.FakeGenerator
-			service Service
+			uuidGen     *fakeuuid.FakeGenerator
+			history     *faketask.FakeHistory
+			timeService *fakeclock.FakeClock
+			startTime   time.Time
+			service     Service
 		)
 
 		BeforeEach(func() {
 			uuidGen = &fakeuuid.FakeGenerator{}
-			service = NewAsyncTaskService(uuidGen, boshlog.NewLogger(boshlog.LevelNone))
+			history = faketask.NewFakeHistory()
+			startTime = time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC)
+			timeService = fakeclock.NewFakeClock(startTime)
+			policy := NewConcurrencyPolicy(Options{
+				MaxWorkers:      3,
+				ParallelMethods: map[string]int{"fake-read": 2, "fake-other-read": 2},
+			})
+			service = NewAsyncTaskService(uuidGen, policy, history, time.Hour, timeService, boshlog.NewLogger(boshlog.LevelNone))
 		})
 
 		Describe("StartTask", func() {
@@ -184,6 +196,329 @@ func init() {
 			})
 		})
 
+		Describe("history", func() {
+			startAndWaitForTaskCompletion := func(id, method string) Task {
+				task := service.CreateTaskWithID(id, func() (interface{}, error) { return nil, nil }, nil, nil)
+				task.Method = method
+				service.StartTask(task)
+
+				Eventually(func() State {
+					task, _ = service.FindTaskWithID(id)
+					return task.State
+				}).Should(Equal(StateDone))
+
+				return task
+			}
+
+			It("records start and finish times on finished tasks", func() {
+				task := startAndWaitForTaskCompletion("fake-task-id", "fake-method")
+				Expect(task.StartedAt).To(Equal(startTime))
+				Expect(task.FinishedAt).To(Equal(startTime))
+			})
+
+			It("adds finished tasks to the history", func() {
+				startAndWaitForTaskCompletion("fake-task-id", "fake-method")
+
+				Eventually(func() []Record { return history.AddedRecords }).Should(Equal([]Record{
+					{
+						TaskID:     "fake-task-id",
+						Method:     "fake-method",
+						State:      StateDone,
+						StartedAt:  startTime,
+						FinishedAt: startTime,
+					},
+				}))
+			})
+
+			It("lists running tasks, finished tasks and tasks only found in history", func() {
+				history.AddedRecords = []Record{
+					{
+						TaskID:     "fake-old-task-id",
+						Method:     "fake-old-method",
+						State:      StateFailed,
+						StartedAt:  startTime.Add(-time.Minute),
+						FinishedAt: startTime.Add(-30 * time.Second),
+						Duration:   30 * time.Second,
+					},
+				}
+
+				release := make(chan struct{})
+				defer close(release)
+
+				startAndWaitForTaskCompletion("fake-task-id-1", "fake-method-1")
+				timeService.Increment(time.Second)
+
+				runningTask := service.CreateTaskWithID("fake-task-id-2", func() (interface{}, error) {
+					<-release
+					return nil, nil
+				}, nil, nil)
+				runningTask.Method = "fake-method-2"
+				service.StartTask(runningTask)
+
+				timeService.Increment(time.Minute)
+
+				records, err := service.ListTasks()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(records).To(Equal([]Record{
+					{
+						TaskID:     "fake-old-task-id",
+						Method:     "fake-old-method",
+						State:      StateFailed,
+						StartedAt:  startTime.Add(-time.Minute),
+						FinishedAt: startTime.Add(-30 * time.Second),
+						Duration:   30 * time.Second,
+					},
+					{
+						TaskID:     "fake-task-id-1",
+						Method:     "fake-method-1",
+						State:      StateDone,
+						StartedAt:  startTime,
+						FinishedAt: startTime,
+					},
+					{
+						TaskID:    "fake-task-id-2",
+						Method:    "fake-method-2",
+						State:     StateRunning,
+						StartedAt: startTime.Add(time.Second),
+						Duration:  time.Minute,
+					},
+				}))
+			})
+
+			It("forgets finished tasks once they are older than the retention window", func() {
+				startAndWaitForTaskCompletion("fake-task-id", "fake-method")
+				history.AddedRecords = nil
+
+				timeService.Increment(2 * time.Hour)
+
+				records, err := service.ListTasks()
+				Expect(err).ToNot(HaveOccurred())
+				Expect(records).To(BeEmpty())
+
+				_, found := service.FindTaskWithID("fake-task-id")
+				Expect(found).To(BeFalse())
+			})
+
+			It("returns error when history cannot be read", func() {
+				history.RecordsErr = errors.New("fake-records-error")
+
+				_, err := service.ListTasks()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-records-error"))
+			})
+		})
+
+		Describe("progress", func() {
+			It("exposes the latest progress reported by a running task", func() {
+				release := make(chan struct{})
+				defer close(release)
+
+				var task Task
+				task = service.CreateTaskWithID("fake-task-id", func() (interface{}, error) {
+					task.ReportProgress(Progress{Phase: "fake-phase-1", Percent: 10})
+					task.ReportProgress(Progress{Phase: "fake-phase-2", Percent: 50, BytesTransferred: 100})
+					<-release
+					return nil, nil
+				}, nil, nil)
+				service.StartTask(task)
+
+				Eventually(func() *Progress {
+					task, _ := service.FindTaskWithID("fake-task-id")
+					return task.Progress
+				}).Should(Equal(&Progress{Phase: "fake-phase-2", Percent: 50, BytesTransferred: 100}))
+			})
+
+			It("does not block or panic when progress is reported after the task finished", func() {
+				var task Task
+				task = service.CreateTaskWithID("fake-task-id", func() (interface{}, error) {
+					return nil, nil
+				}, nil, nil)
+				service.StartTask(task)
+
+				Eventually(func() State {
+					task, _ := service.FindTaskWithID("fake-task-id")
+					return task.State
+				}).Should(Equal(StateDone))
+
+				task.ReportProgress(Progress{Phase: "fake-phase-1"})
+				task.ReportProgress(Progress{Phase: "fake-phase-2"})
+
+				finishedTask, _ := service.FindTaskWithID("fake-task-id")
+				Expect(finishedTask.Progress).To(BeNil())
+			})
+
+			It("clears progress channel once the task finishes", func() {
+				var task Task
+				task = service.CreateTaskWithID("fake-task-id", func() (interface{}, error) {
+					task.ReportProgress(Progress{Phase: "fake-phase", Percent: 10})
+					return nil, nil
+				}, nil, nil)
+				service.StartTask(task)
+
+				Eventually(func() State {
+					task, _ := service.FindTaskWithID("fake-task-id")
+					return task.State
+				}).Should(Equal(StateDone))
+
+				Consistently(func() State {
+					task, _ := service.FindTaskWithID("fake-task-id")
+					return task.State
+				}).Should(Equal(StateDone))
+
+				task, _ = service.FindTaskWithID("fake-task-id")
+				Expect(task.ProgressChan).To(BeNil())
+			})
+		})
+
+		Describe("concurrency", func() {
+			var (
+				started chan string
+				release chan struct{}
+			)
+
+			BeforeEach(func() {
+				started = make(chan string, 10)
+				release = make(chan struct{})
+			})
+
+			startTaskReleasedBy := func(id, method string, releaseCh chan struct{}) {
+				taskFunc := func() (interface{}, error) {
+					started <- id
+					<-releaseCh
+					return nil, nil
+				}
+
+				task := service.CreateTaskWithID(id, taskFunc, nil, nil)
+				task.Method = method
+				service.StartTask(task)
+			}
+
+			startBlockingTask := func(id, method string) {
+				startTaskReleasedBy(id, method, release)
+			}
+
+			receiveStarted := func(count int) []string {
+				var ids []string
+				for i := 0; i < count; i++ {
+					var id string
+					Eventually(started).Should(Receive(&id))
+					ids = append(ids, id)
+				}
+				return ids
+			}
+
+			It("runs tasks for parallel methods at the same time", func() {
+				startBlockingTask("fake-task-id-1", "fake-read")
+				startBlockingTask("fake-task-id-2", "fake-read")
+
+				Eventually(started).Should(Receive(Equal("fake-task-id-1")))
+				Eventually(started).Should(Receive(Equal("fake-task-id-2")))
+
+				close(release)
+			})
+
+			It("runs tasks for parallel methods while a mutating task is running", func() {
+				startBlockingTask("fake-task-id-1", "fake-write")
+				startBlockingTask("fake-task-id-2", "fake-read")
+
+				Expect(receiveStarted(2)).To(ConsistOf("fake-task-id-1", "fake-task-id-2"))
+
+				close(release)
+			})
+
+			It("runs a mutating task while tasks for parallel methods are running", func() {
+				startBlockingTask("fake-task-id-1", "fake-read")
+				startBlockingTask("fake-task-id-2", "fake-write")
+				startBlockingTask("fake-task-id-3", "fake-read")
+
+				Expect(receiveStarted(3)).To(ConsistOf("fake-task-id-1", "fake-task-id-2", "fake-task-id-3"))
+
+				close(release)
+			})
+
+			It("runs mutating tasks one at a time next to tasks for parallel methods", func() {
+				releaseFirstWrite := make(chan struct{})
+				releaseRead := make(chan struct{})
+
+				startTaskReleasedBy("fake-task-id-1", "fake-read", releaseRead)
+				startTaskReleasedBy("fake-task-id-2", "fake-write", releaseFirstWrite)
+				startBlockingTask("fake-task-id-3", "fake-other-write")
+				startBlockingTask("fake-task-id-4", "fake-read")
+
+				Expect(receiveStarted(3)).To(ConsistOf("fake-task-id-1", "fake-task-id-2", "fake-task-id-4"))
+				Consistently(started).ShouldNot(Receive())
+
+				close(releaseRead)
+				Consistently(started).ShouldNot(Receive())
+
+				close(releaseFirstWrite)
+				Eventually(started).Should(Receive(Equal("fake-task-id-3")))
+
+				close(release)
+			})
+
+			It("runs tasks for mutating methods one at a time in order", func() {
+				startBlockingTask("fake-task-id-1", "fake-write")
+				startBlockingTask("fake-task-id-2", "fake-other-write")
+				startBlockingTask("fake-task-id-3", "fake-write")
+
+				Eventually(started).Should(Receive(Equal("fake-task-id-1")))
+				Consistently(started).ShouldNot(Receive())
+
+				release <- struct{}{}
+				Eventually(started).Should(Receive(Equal("fake-task-id-2")))
+				Consistently(started).ShouldNot(Receive())
+
+				release <- struct{}{}
+				Eventually(started).Should(Receive(Equal("fake-task-id-3")))
+
+				close(release)
+			})
+
+			It("treats tasks without a method as mutating", func() {
+				startBlockingTask("fake-task-id-1", "")
+				startBlockingTask("fake-task-id-2", "")
+
+				Eventually(started).Should(Receive(Equal("fake-task-id-1")))
+				Consistently(started).ShouldNot(Receive())
+
+				close(release)
+				Eventually(started).Should(Receive(Equal("fake-task-id-2")))
+			})
+
+			It("limits the number of running tasks per parallel method", func() {
+				startBlockingTask("fake-task-id-1", "fake-read")
+				startBlockingTask("fake-task-id-2", "fake-read")
+				startBlockingTask("fake-task-id-3", "fake-read")
+
+				Eventually(started).Should(Receive())
+				Eventually(started).Should(Receive())
+				Consistently(started).ShouldNot(Receive())
+
+				release <- struct{}{}
+				Eventually(started).Should(Receive(Equal("fake-task-id-3")))
+
+				close(release)
+			})
+
+			It("limits the total number of running tasks", func() {
+				startBlockingTask("fake-task-id-1", "fake-read")
+				startBlockingTask("fake-task-id-2", "fake-read")
+				startBlockingTask("fake-task-id-3", "fake-other-read")
+				startBlockingTask("fake-task-id-4", "fake-other-read")
+
+				Eventually(started).Should(Receive())
+				Eventually(started).Should(Receive())
+				Eventually(started).Should(Receive())
+				Consistently(started).ShouldNot(Receive())
+
+				release <- struct{}{}
+				Eventually(started).Should(Receive(Equal("fake-task-id-4")))
+
+				close(release)
+			})
+		})
+
 		Describe("CreateTask", func() {
 			It("creates a task with auto-assigned id", func() {
 				uuidGen.GeneratedUUID = "fake-uuid"
@@ -253,6 +588,35 @@ func init() {
 				task.EndFunc(task)
 				Expect(endFuncCalled).To(BeTrue())
 			})
+
+			It("creates tasks that are cancelled separately", func() {
+				firstTask := service.CreateTaskWithID("fake-task-id-1", nil, nil, nil)
+				secondTask := service.CreateTaskWithID("fake-task-id-2", nil, nil, nil)
+
+				Expect(firstTask.Cancel()).ToNot(HaveOccurred())
+
+				Expect(firstTask.CancelSignal()).To(BeClosed())
+				Expect(secondTask.CancelSignal()).ToNot(BeClosed())
+			})
+
+			It("can cancel copies of the task multiple times", func() {
+				task := service.CreateTaskWithID("fake-task-id", nil, nil, nil)
+				taskCopy := task
+
+				Expect(task.Cancel()).ToNot(HaveOccurred())
+				Expect(taskCopy.Cancel()).ToNot(HaveOccurred())
+
+				Expect(taskCopy.CancelSignal()).To(BeClosed())
+			})
+
+			It("does not signal cancellation when cancel function fails", func() {
+				cancelFunc := func(_ Task) error { return errors.New("fake-cancel-err") }
+
+				task := service.CreateTaskWithID("fake-task-id", nil, cancelFunc, nil)
+
+				Expect(task.Cancel()).To(HaveOccurred())
+				Expect(task.CancelSignal()).ToNot(BeClosed())
+			})
 		})
 	})
 }
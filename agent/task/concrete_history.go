@@ -0,0 +1,148 @@
+package task
+
+import (
+	"encoding/json"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type concreteHistory struct {
+	logger boshlog.Logger
+
+	fs          boshsys.FileSystem
+	fsSem       chan func()
+	historyPath string
+
+	retention   time.Duration
+	timeService clock.Clock
+
+	// Access to records must be synchronized via fsSem
+	records       []Record
+	recordsLoaded bool
+}
+
+func NewHistory(
+	logger boshlog.Logger,
+	fs boshsys.FileSystem,
+	historyPath string,
+	retention time.Duration,
+	timeService clock.Clock,
+) History {
+	h := &concreteHistory{
+		logger:      logger,
+		fs:          fs,
+		fsSem:       make(chan func()),
+		historyPath: historyPath,
+		retention:   retention,
+		timeService: timeService,
+	}
+
+	go h.processFsFuncs()
+
+	return h
+}
+
+func (h *concreteHistory) Records() ([]Record, error) {
+	recordsChan := make(chan []Record)
+	errCh := make(chan error)
+
+	h.fsSem <- func() {
+		err := h.loadRecords()
+		recordsChan <- h.evictExpired(h.records)
+		errCh <- err
+	}
+
+	records := <-recordsChan
+	err := <-errCh
+
+	if err != nil {
+		return nil, err
+	}
+
+	return records, nil
+}
+
+func (h *concreteHistory) AddRecord(record Record) error {
+	errCh := make(chan error)
+
+	h.fsSem <- func() {
+		err := h.loadRecords()
+		if err != nil {
+			// Start a new history instead of losing new records because of a corrupted file
+			h.logger.Warn("Task History", "Discarding unreadable task history: %s", err.Error())
+			h.records = nil
+			h.recordsLoaded = true
+		}
+
+		h.records = h.evictExpired(append(h.records, record))
+		errCh <- h.writeRecords(h.records)
+	}
+
+	return <-errCh
+}
+
+func (h *concreteHistory) processFsFuncs() {
+	defer h.logger.HandlePanic("Task History Process Fs Funcs")
+
+	for {
+		do := <-h.fsSem
+		do()
+	}
+}
+
+func (h *concreteHistory) evictExpired(records []Record) []Record {
+	cutoff := h.timeService.Now().Add(-h.retention)
+
+	var kept []Record
+	for _, record := range records {
+		if record.FinishedAt.After(cutoff) {
+			kept = append(kept, record)
+		}
+	}
+
+	return kept
+}
+
+func (h *concreteHistory) loadRecords() error {
+	if h.recordsLoaded {
+		return nil
+	}
+
+	var records []Record
+
+	if h.fs.FileExists(h.historyPath) {
+		historyJSON, err := h.fs.ReadFile(h.historyPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Reading task history json")
+		}
+
+		err = json.Unmarshal(historyJSON, &records)
+		if err != nil {
+			return bosherr.WrapError(err, "Unmarshaling task history json")
+		}
+	}
+
+	h.records = records
+	h.recordsLoaded = true
+
+	return nil
+}
+
+func (h *concreteHistory) writeRecords(records []Record) error {
+	historyJSON, err := json.Marshal(records)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling task history json")
+	}
+
+	err = h.fs.WriteFile(h.historyPath, historyJSON)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing task history json")
+	}
+
+	return nil
+}
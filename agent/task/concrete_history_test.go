@@ -0,0 +1,147 @@
+package task_test
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("concreteHistory", func() {
+	var (
+		logger      boshlog.Logger
+		fs          *fakesys.FakeFileSystem
+		timeService *fakeclock.FakeClock
+		now         time.Time
+		history     boshtask.History
+	)
+
+	BeforeEach(func() {
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		fs = fakesys.NewFakeFileSystem()
+		now = time.Date(2017, time.March, 1, 10, 0, 0, 0, time.UTC)
+		timeService = fakeclock.NewFakeClock(now)
+		history = boshtask.NewHistory(logger, fs, "/dir/tasks_history.json", 24*time.Hour, timeService)
+	})
+
+	recordFinishedAt := func(id string, finishedAt time.Time) boshtask.Record {
+		return boshtask.Record{
+			TaskID:     id,
+			Method:     "fake-method",
+			State:      boshtask.StateDone,
+			StartedAt:  finishedAt.Add(-time.Minute),
+			FinishedAt: finishedAt,
+			Duration:   time.Minute,
+		}
+	}
+
+	Describe("AddRecord", func() {
+		It("persists records to the history file", func() {
+			record := recordFinishedAt("fake-task-id", now)
+
+			err := history.AddRecord(record)
+			Expect(err).ToNot(HaveOccurred())
+
+			content, err := fs.ReadFile("/dir/tasks_history.json")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(content).To(MatchJSON(`[{
+				"agent_task_id": "fake-task-id",
+				"method": "fake-method",
+				"state": "done",
+				"started_at": "2017-03-01T09:59:00Z",
+				"finished_at": "2017-03-01T10:00:00Z",
+				"duration": 60000000000
+			}]`))
+
+			var records []boshtask.Record
+			err = json.Unmarshal(content, &records)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal([]boshtask.Record{record}))
+		})
+
+		It("evicts records finished before the retention window", func() {
+			err := history.AddRecord(recordFinishedAt("fake-task-id-1", now))
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.Increment(25 * time.Hour)
+
+			newRecord := recordFinishedAt("fake-task-id-2", timeService.Now())
+			err = history.AddRecord(newRecord)
+			Expect(err).ToNot(HaveOccurred())
+
+			otherHistory := boshtask.NewHistory(logger, fs, "/dir/tasks_history.json", 1000*time.Hour, timeService)
+
+			records, err := otherHistory.Records()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal([]boshtask.Record{newRecord}))
+		})
+
+		It("replaces unreadable history", func() {
+			fs.WriteFileString("/dir/tasks_history.json", "fake-invalid-json")
+
+			record := recordFinishedAt("fake-task-id", now)
+			err := history.AddRecord(record)
+			Expect(err).ToNot(HaveOccurred())
+
+			records, err := history.Records()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal([]boshtask.Record{record}))
+		})
+
+		It("returns an error when failing to write history", func() {
+			fs.WriteFileError = errors.New("fake-write-error")
+
+			err := history.AddRecord(recordFinishedAt("fake-task-id", now))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-write-error"))
+		})
+	})
+
+	Describe("Records", func() {
+		It("loads records written by a previous agent run", func() {
+			record := recordFinishedAt("fake-task-id", now)
+			err := history.AddRecord(record)
+			Expect(err).ToNot(HaveOccurred())
+
+			reloadedHistory := boshtask.NewHistory(logger, fs, "/dir/tasks_history.json", 24*time.Hour, timeService)
+
+			records, err := reloadedHistory.Records()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(Equal([]boshtask.Record{record}))
+		})
+
+		It("does not return records finished before the retention window", func() {
+			err := history.AddRecord(recordFinishedAt("fake-task-id", now))
+			Expect(err).ToNot(HaveOccurred())
+
+			timeService.Increment(25 * time.Hour)
+
+			records, err := history.Records()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(BeEmpty())
+		})
+
+		It("succeeds when history file is not present", func() {
+			records, err := history.Records()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(records).To(BeEmpty())
+		})
+
+		It("returns an error when failing to read history file", func() {
+			fs.WriteFileString("/dir/tasks_history.json", "[]")
+			fs.ReadFileError = errors.New("fake-read-error")
+
+			_, err := history.Records()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-read-error"))
+		})
+	})
+})
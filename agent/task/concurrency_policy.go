@@ -0,0 +1,146 @@
+package task
+
+import (
+	"time"
+)
+
+const (
+	DefaultMaxWorkers            = 8
+	DefaultHistoryRetentionHours = 72
+)
+
+// DefaultParallelMethods lists read-only asynchronous actions which therefore
+// may run next to each other and next to mutating tasks. Value is the maximum
+// number of tasks of that method that may run at the same time.
+var DefaultParallelMethods = map[string]int{
+	"fetch_logs": 2,
+}
+
+type Options struct {
+	// Maximum number of tasks running at the same time regardless of method
+	MaxWorkers int
+
+	// Methods that may run alongside other tasks mapped to their concurrency limit.
+	// Tasks for any other method are considered mutating and run one at a time.
+	ParallelMethods map[string]int
+
+	// How long finished tasks are kept in memory and in the task history
+	HistoryRetentionHours int
+}
+
+// HistoryRetention is how long finished tasks are kept in memory and in the task history
+func (o Options) HistoryRetention() time.Duration {
+	if o.HistoryRetentionHours <= 0 {
+		return DefaultHistoryRetentionHours * time.Hour
+	}
+	return time.Duration(o.HistoryRetentionHours) * time.Hour
+}
+
+type ConcurrencyPolicy struct {
+	maxWorkers      int
+	parallelMethods map[string]int
+}
+
+func NewConcurrencyPolicy(opts Options) ConcurrencyPolicy {
+	policy := ConcurrencyPolicy{
+		maxWorkers:      opts.MaxWorkers,
+		parallelMethods: opts.ParallelMethods,
+	}
+
+	if policy.maxWorkers <= 0 {
+		policy.maxWorkers = DefaultMaxWorkers
+	}
+
+	if policy.parallelMethods == nil {
+		policy.parallelMethods = DefaultParallelMethods
+	}
+
+	return policy
+}
+
+func (p ConcurrencyPolicy) IsParallel(method string) bool {
+	limit, found := p.parallelMethods[method]
+	return found && limit > 0
+}
+
+// taskScheduler keeps pending tasks in arrival order and hands out the ones
+// allowed to run. Mutating (serial) tasks never overlap with each other and
+// start in arrival order; read-only parallel tasks run next to them and
+// only obey their per-method limit and the overall worker limit.
+type taskScheduler struct {
+	policy ConcurrencyPolicy
+
+	pending       []Task
+	running       map[string]int
+	totalRunning  int
+	serialRunning bool
+}
+
+func newTaskScheduler(policy ConcurrencyPolicy) *taskScheduler {
+	return &taskScheduler{
+		policy:  policy,
+		running: make(map[string]int),
+	}
+}
+
+func (s *taskScheduler) Enqueue(task Task) {
+	s.pending = append(s.pending, task)
+}
+
+// Next removes tasks that may start right now from the pending queue
+// and marks them as running
+func (s *taskScheduler) Next() []Task {
+	var ready, waiting []Task
+
+	serialBlocked := s.serialRunning
+
+	for _, task := range s.pending {
+		parallel := s.policy.IsParallel(task.Method)
+
+		if s.canStart(task.Method, parallel, serialBlocked) {
+			s.markStarted(task.Method, parallel)
+			ready = append(ready, task)
+		} else {
+			waiting = append(waiting, task)
+		}
+
+		if !parallel {
+			serialBlocked = true
+		}
+	}
+
+	s.pending = waiting
+
+	return ready
+}
+
+func (s *taskScheduler) Finished(task Task) {
+	s.totalRunning--
+	s.running[task.Method]--
+
+	if !s.policy.IsParallel(task.Method) {
+		s.serialRunning = false
+	}
+}
+
+func (s *taskScheduler) canStart(method string, parallel, serialBlocked bool) bool {
+	if s.totalRunning >= s.policy.maxWorkers {
+		return false
+	}
+
+	if parallel {
+		return s.running[method] < s.policy.parallelMethods[method]
+	}
+
+	// Serial task waits for running and earlier serial tasks
+	return !serialBlocked
+}
+
+func (s *taskScheduler) markStarted(method string, parallel bool) {
+	s.totalRunning++
+	s.running[method]++
+
+	if !parallel {
+		s.serialRunning = true
+	}
+}
@@ -0,0 +1,37 @@
+package task_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/agent/task"
+)
+
+var _ = Describe("ConcurrencyPolicy", func() {
+	Describe("IsParallel", func() {
+		It("uses default parallel methods when none are configured", func() {
+			policy := NewConcurrencyPolicy(Options{})
+			Expect(policy.IsParallel("fetch_logs")).To(BeTrue())
+			Expect(policy.IsParallel("list_disk")).To(BeFalse())
+			Expect(policy.IsParallel("compile_package")).To(BeFalse())
+			Expect(policy.IsParallel("run_errand")).To(BeFalse())
+			Expect(policy.IsParallel("apply")).To(BeFalse())
+			Expect(policy.IsParallel("mount_disk")).To(BeFalse())
+		})
+
+		It("uses configured parallel methods", func() {
+			policy := NewConcurrencyPolicy(Options{
+				ParallelMethods: map[string]int{"fake-read": 1, "fake-disabled": 0},
+			})
+			Expect(policy.IsParallel("fake-read")).To(BeTrue())
+			Expect(policy.IsParallel("fake-disabled")).To(BeFalse())
+			Expect(policy.IsParallel("fetch_logs")).To(BeFalse())
+		})
+
+		It("considers unknown methods mutating", func() {
+			policy := NewConcurrencyPolicy(Options{})
+			Expect(policy.IsParallel("fake-unknown")).To(BeFalse())
+			Expect(policy.IsParallel("")).To(BeFalse())
+		})
+	})
+})
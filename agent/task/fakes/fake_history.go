@@ -0,0 +1,35 @@
+package fakes
+
+import (
+	"sync"
+
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+)
+
+type FakeHistory struct {
+	AddedRecords []boshtask.Record
+
+	RecordsErr   error
+	AddRecordErr error
+
+	lock sync.Mutex
+}
+
+func NewFakeHistory() *FakeHistory {
+	return &FakeHistory{}
+}
+
+func (h *FakeHistory) Records() ([]boshtask.Record, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	return h.AddedRecords, h.RecordsErr
+}
+
+func (h *FakeHistory) AddRecord(record boshtask.Record) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	h.AddedRecords = append(h.AddedRecords, record)
+	return h.AddRecordErr
+}
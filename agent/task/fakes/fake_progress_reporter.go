@@ -0,0 +1,19 @@
+package fakes
+
+import boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
+
+type FakeProgressReporter struct {
+	Reported []boshtask.Progress
+}
+
+func (r *FakeProgressReporter) ReportProgress(progress boshtask.Progress) {
+	r.Reported = append(r.Reported, progress)
+}
+
+func (r *FakeProgressReporter) Phases() []string {
+	var phases []string
+	for _, progress := range r.Reported {
+		phases = append(phases, progress.Phase)
+	}
+	return phases
+}
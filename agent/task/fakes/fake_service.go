@@ -8,6 +8,9 @@ type FakeService struct {
 	StartedTasks        map[string]boshtask.Task
 	CreateTaskErr       error
 	CreateTaskWithIDErr error
+
+	ListTasksRecords []boshtask.Record
+	ListTasksErr     error
 }
 
 func NewFakeService() *FakeService {
@@ -50,3 +53,7 @@ func (s *FakeService) FindTaskWithID(id string) (boshtask.Task, bool) {
 	task, found := s.StartedTasks[id]
 	return task, found
 }
+
+func (s *FakeService) ListTasks() ([]boshtask.Record, error) {
+	return s.ListTasksRecords, s.ListTasksErr
+}
@@ -0,0 +1,21 @@
+package task
+
+import (
+	"time"
+)
+
+// Record is a summary of a task that is kept after the task finishes
+type Record struct {
+	TaskID     string        `json:"agent_task_id"`
+	Method     string        `json:"method"`
+	State      State         `json:"state"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at"`
+	Duration   time.Duration `json:"duration"`
+}
+
+type History interface {
+	// Records returns all records that are still within the retention window
+	Records() ([]Record, error)
+	AddRecord(record Record) error
+}
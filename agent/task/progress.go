@@ -0,0 +1,26 @@
+package task
+
+// Progress describes how far along a running task is.
+// Actions fill in whichever fields make sense for them.
+type Progress struct {
+	Phase            string  `json:"phase"`
+	Percent          float64 `json:"percent"`
+	BytesTransferred uint64  `json:"bytes_transferred,omitempty"`
+
+	// Latest output of a long running command, e.g. errand
+	Output *Output `json:"output,omitempty"`
+}
+
+// Output holds the tail of command's stdout/stderr. Offsets are the number
+// of bytes written to the stream before the tail, so that consumers
+// polling get_task can stitch chunks together without duplicates.
+type Output struct {
+	Stdout       string `json:"stdout"`
+	StdoutOffset int64  `json:"stdout_offset"`
+	Stderr       string `json:"stderr"`
+	StderrOffset int64  `json:"stderr_offset"`
+}
+
+type ProgressReporter interface {
+	ReportProgress(Progress)
+}
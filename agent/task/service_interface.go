@@ -8,4 +8,7 @@ type Service interface {
 	// Records that task to run later
 	StartTask(Task)
 	FindTaskWithID(string) (Task, bool)
+
+	// Lists running tasks and finished tasks still within retention window
+	ListTasks() ([]Record, error)
 }
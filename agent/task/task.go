@@ -1,11 +1,35 @@
 package task
 
+import (
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
 type Func func() (value interface{}, err error)
 
 type CancelFunc func(task Task) error
 
 type EndFunc func(task Task)
 
+// CancelSignal is closed once the task running an action is cancelled;
+// nil signal is never closed
+type CancelSignal <-chan struct{}
+
+// CancelledErr is returned by work that stopped because its task was cancelled
+var CancelledErr = bosherr.Error("Task was cancelled")
+
+// IsCancelled checks without blocking whether cancel signal was already closed
+func IsCancelled(cancelSignal <-chan struct{}) bool {
+	select {
+	case <-cancelSignal:
+		return true
+	default:
+		return false
+	}
+}
+
 type State string
 
 const (
@@ -15,24 +39,106 @@ const (
 )
 
 type Task struct {
-	ID    string
-	State State
-	Value interface{}
-	Error error
+	ID     string
+	Method string
+	State  State
+	Value  interface{}
+	Error  error
+
+	StartedAt  time.Time
+	FinishedAt time.Time
+
+	// Latest progress reported by the task while it is running
+	Progress *Progress
+
+	// Receives progress updates from the running task;
+	// updates that are not read in time are replaced by newer ones
+	ProgressChan chan Progress
 
 	Func       Func
 	CancelFunc CancelFunc
 	EndFunc    EndFunc
+
+	// Shared by all copies of the task so that each task is cancelled separately
+	canceller *canceller
+}
+
+type canceller struct {
+	cancelCh chan struct{}
+	once     sync.Once
+}
+
+func newCanceller() *canceller {
+	return &canceller{cancelCh: make(chan struct{})}
+}
+
+func (c *canceller) cancel() {
+	c.once.Do(func() { close(c.cancelCh) })
+}
+
+func (t Task) Record() Record {
+	record := Record{
+		TaskID:     t.ID,
+		Method:     t.Method,
+		State:      t.State,
+		StartedAt:  t.StartedAt,
+		FinishedAt: t.FinishedAt,
+	}
+
+	if !t.StartedAt.IsZero() && !t.FinishedAt.IsZero() {
+		record.Duration = t.FinishedAt.Sub(t.StartedAt)
+	}
+
+	return record
+}
+
+// ReportProgress allows Task to be used as ProgressReporter for the action it runs;
+// it never blocks so that action is not slowed down when nobody reads progress
+func (t Task) ReportProgress(progress Progress) {
+	if t.ProgressChan == nil {
+		return
+	}
+
+	for {
+		select {
+		case t.ProgressChan <- progress:
+			return
+		default:
+		}
+
+		// Drop the stale update to make room for the latest one
+		select {
+		case <-t.ProgressChan:
+		default:
+		}
+	}
+}
+
+// CancelSignal allows action run by the task to stop its work once the task is cancelled
+func (t Task) CancelSignal() CancelSignal {
+	if t.canceller == nil {
+		return nil
+	}
+	return t.canceller.cancelCh
 }
 
 func (t Task) Cancel() error {
 	if t.CancelFunc != nil {
-		return t.CancelFunc(t)
+		err := t.CancelFunc(t)
+		if err != nil {
+			return err
+		}
+	}
+
+	if t.canceller != nil {
+		t.canceller.cancel()
 	}
+
 	return nil
 }
 
 type StateValue struct {
-	AgentTaskID string `json:"agent_task_id"`
-	State       State  `json:"state"`
+	AgentTaskID string    `json:"agent_task_id"`
+	State       State     `json:"state"`
+	Progress    *Progress `json:"progress,omitempty"`
 }
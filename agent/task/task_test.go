@@ -18,6 +18,29 @@ var _ = Describe("Task", func() {
 		task = Task{}
 	})
 
+	Describe("ReportProgress", func() {
+		It("sends progress to progress channel", func() {
+			task.ProgressChan = make(chan Progress, 1)
+
+			task.ReportProgress(Progress{Phase: "fake-phase", Percent: 5})
+			Expect(task.ProgressChan).To(Receive(Equal(Progress{Phase: "fake-phase", Percent: 5})))
+		})
+
+		It("replaces unread progress with the latest one instead of blocking", func() {
+			task.ProgressChan = make(chan Progress, 1)
+
+			task.ReportProgress(Progress{Phase: "fake-phase-1"})
+			task.ReportProgress(Progress{Phase: "fake-phase-2"})
+
+			Expect(task.ProgressChan).To(Receive(Equal(Progress{Phase: "fake-phase-2"})))
+			Expect(task.ProgressChan).ToNot(Receive())
+		})
+
+		It("does nothing when progress channel is not set", func() {
+			task.ReportProgress(Progress{Phase: "fake-phase"})
+		})
+	})
+
 	Describe("Cancel", func() {
 		It("runs cancel function", func() {
 			cancelCalled := false
@@ -40,5 +63,24 @@ var _ = Describe("Task", func() {
 			err := task.Cancel()
 			Expect(err).ToNot(HaveOccurred())
 		})
+
+		It("has no cancel signal when task was not created by task service", func() {
+			Expect(task.Cancel()).ToNot(HaveOccurred())
+			Expect(task.CancelSignal()).To(BeNil())
+		})
+	})
+
+	Describe("IsCancelled", func() {
+		It("returns true only once cancel signal is closed", func() {
+			cancelCh := make(chan struct{})
+			Expect(IsCancelled(cancelCh)).To(BeFalse())
+
+			close(cancelCh)
+			Expect(IsCancelled(cancelCh)).To(BeTrue())
+		})
+
+		It("returns false for nil cancel signal", func() {
+			Expect(IsCancelled(nil)).To(BeFalse())
+		})
 	})
 })
@@ -11,6 +11,7 @@ import (
 
 	boshagent "github.com/cloudfoundry/bosh-agent/agent"
 	boshaction "github.com/cloudfoundry/bosh-agent/agent/action"
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
 	boshapplier "github.com/cloudfoundry/bosh-agent/agent/applier"
 	boshas "github.com/cloudfoundry/bosh-agent/agent/applier/applyspec"
 	boshbc "github.com/cloudfoundry/bosh-agent/agent/applier/bundlecollection"
@@ -25,8 +26,10 @@ import (
 	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
 	boshmonit "github.com/cloudfoundry/bosh-agent/jobsupervisor/monit"
 	boshmbus "github.com/cloudfoundry/bosh-agent/mbus"
+	boshmetrics "github.com/cloudfoundry/bosh-agent/metrics"
 	boshnotif "github.com/cloudfoundry/bosh-agent/notification"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshsigar "github.com/cloudfoundry/bosh-agent/sigar"
@@ -45,12 +48,13 @@ type App interface {
 }
 
 type app struct {
-	logger      boshlog.Logger
-	agent       boshagent.Agent
-	platform    boshplatform.Platform
-	fs          boshsys.FileSystem
-	logTag      string
-	dirProvider boshdirs.Provider
+	logger        boshlog.Logger
+	agent         boshagent.Agent
+	platform      boshplatform.Platform
+	fs            boshsys.FileSystem
+	logTag        string
+	dirProvider   boshdirs.Provider
+	metricsServer boshmetrics.Server
 }
 
 func New(logger boshlog.Logger, fs boshsys.FileSystem) App {
@@ -71,7 +75,7 @@ func (app *app) Setup(opts Options) error {
 	app.dirProvider = boshdirs.NewProvider(opts.BaseDirectory)
 	app.logStemcellInfo()
 
-	statsCollector := boshsigar.NewSigarStatsCollector(&sigar.ConcreteSigar{})
+	statsCollector := boshsigar.NewSigarStatsCollector(&sigar.ConcreteSigar{}, app.fs)
 	auditLoggerProvider := boshplatform.NewAuditLoggerProvider()
 	auditLogger := boshplatform.NewDelayedAuditLogger(auditLoggerProvider, app.logger)
 
@@ -160,7 +164,22 @@ func (app *app) Setup(opts Options) error {
 
 	uuidGen := boshuuid.NewGenerator()
 
-	taskService := boshtask.NewAsyncTaskService(uuidGen, app.logger)
+	taskHistory := boshtask.NewHistory(
+		app.logger,
+		app.platform.GetFs(),
+		filepath.Join(app.dirProvider.BoshDir(), "tasks_history.json"),
+		config.Task.HistoryRetention(),
+		timeService,
+	)
+
+	taskService := boshtask.NewAsyncTaskService(
+		uuidGen,
+		boshtask.NewConcurrencyPolicy(config.Task),
+		taskHistory,
+		config.Task.HistoryRetention(),
+		timeService,
+		app.logger,
+	)
 
 	taskManager := boshtask.NewManagerProvider().NewManager(
 		app.logger,
@@ -188,6 +207,7 @@ func (app *app) Setup(opts Options) error {
 		jobSupervisor,
 		specService,
 		jobScriptProvider,
+		timeService,
 		app.logger,
 	)
 
@@ -201,9 +221,34 @@ func (app *app) Setup(opts Options) error {
 		actionRunner,
 	)
 
+	if config.Metrics.Enabled() {
+		app.metricsServer = boshmetrics.NewServer(
+			config.Metrics,
+			boshmetrics.NewHandler(app.platform.GetVitalsService(), jobSupervisor, app.logger),
+			app.platform.GetFs(),
+			app.logger,
+		)
+	}
+
+	alertSink, err := boshalert.NewSinkFactory(app.platform.GetFs(), app.logger).New(config.Alerts)
+	if err != nil {
+		return bosherr.WrapError(err, "Building alert sinks")
+	}
+
+	networkDriftReconciler := boshagent.NewNetworkDriftReconciler(
+		app.platform,
+		settingsService,
+		boship.NewInterfaceAddressesValidator(boship.NewSystemInterfaceAddressesProvider()),
+		time.Duration(config.Platform.Linux.NetworkDriftCheckIntervalInSeconds)*time.Second,
+		config.Platform.Linux.RepairNetworkDrift,
+		timeService,
+		app.logger,
+	)
+
 	app.agent = boshagent.New(
 		app.logger,
 		mbusHandler,
+		alertSink,
 		app.platform,
 		actionDispatcher,
 		jobSupervisor,
@@ -212,12 +257,23 @@ func (app *app) Setup(opts Options) error {
 		settingsService,
 		uuidGen,
 		timeService,
+		networkDriftReconciler,
 	)
 
 	return nil
 }
 
 func (app *app) Run() error {
+	if app.metricsServer != nil {
+		// Metrics are optional so agent keeps running without them
+		err := app.metricsServer.Start()
+		if err != nil {
+			app.logger.Error(app.logTag, "Starting metrics server: %s", err.Error())
+		} else {
+			defer app.metricsServer.Stop()
+		}
+	}
+
 	err := app.agent.Run()
 	if err != nil {
 		return bosherr.WrapError(err, "Running agent")
@@ -4,7 +4,10 @@ import (
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshtask "github.com/cloudfoundry/bosh-agent/agent/task"
 	boshinf "github.com/cloudfoundry/bosh-agent/infrastructure"
+	boshmetrics "github.com/cloudfoundry/bosh-agent/metrics"
 	boshplatform "github.com/cloudfoundry/bosh-agent/platform"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
@@ -63,6 +66,35 @@ var _ = Describe("LoadConfigFromPath", func() {
 				  "UseServerName": true,
 				  "UseRegistry": true
 				}
+			},
+			"Task": {
+				"MaxWorkers": 4,
+				"ParallelMethods": {"fetch_logs": 2}
+			},
+			"Metrics": {
+				"Address": "127.0.0.1:9100",
+				"TLS": {
+					"CertPath": "/fake-cert-path",
+					"KeyPath": "/fake-key-path",
+					"CACertPath": "/fake-ca-cert-path"
+				}
+			},
+			"Alerts": {
+				"Sinks": [
+					{
+						"Type": "syslog",
+						"MinSeverity": "critical",
+						"Syslog": {"Network": "udp", "Address": "10.0.0.5:514", "Format": "cef"}
+					},
+					{
+						"Type": "webhook",
+						"Webhook": {"URL": "https://fake-webhook", "CACertPath": "/fake-webhook-ca-path"}
+					},
+					{
+						"Type": "spool",
+						"Spool": {"Path": "/var/vcap/data/alerts.jsonl"}
+					}
+				]
 			}
 		}`)
 
@@ -108,6 +140,35 @@ var _ = Describe("LoadConfigFromPath", func() {
 					UseRegistry:   true,
 				},
 			},
+			Task: boshtask.Options{
+				MaxWorkers:      4,
+				ParallelMethods: map[string]int{"fetch_logs": 2},
+			},
+			Metrics: boshmetrics.Options{
+				Address: "127.0.0.1:9100",
+				TLS: boshmetrics.TLSOptions{
+					CertPath:   "/fake-cert-path",
+					KeyPath:    "/fake-key-path",
+					CACertPath: "/fake-ca-cert-path",
+				},
+			},
+			Alerts: boshalert.Options{
+				Sinks: []boshalert.SinkOptions{
+					{
+						Type:        "syslog",
+						MinSeverity: "critical",
+						Syslog:      boshalert.SyslogSinkOptions{Network: "udp", Address: "10.0.0.5:514", Format: "cef"},
+					},
+					{
+						Type:    "webhook",
+						Webhook: boshalert.WebhookSinkOptions{URL: "https://fake-webhook", CACertPath: "/fake-webhook-ca-path"},
+					},
+					{
+						Type:  "spool",
+						Spool: boshalert.SpoolSinkOptions{Path: "/var/vcap/data/alerts.jsonl"},
+					},
+				},
+			},
 		}))
 	})
 
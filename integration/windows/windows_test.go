@@ -151,11 +151,18 @@ var _ = Describe("An Agent running on Windows", func() {
 		runErrandResponse, err := natsClient.RunErrand()
 		Expect(err).NotTo(HaveOccurred())
 
+		var result action.ErrandResult
+
 		runErrandCheck := natsClient.CheckErrandResultStatus(runErrandResponse["value"]["agent_task_id"])
-		Eventually(runErrandCheck, DefaultTimeout, DefaultInterval).Should(Equal(action.ErrandResult{
-			Stdout:     "hello world\r\n",
-			ExitStatus: 0,
-		}))
+		Eventually(func() (string, error) {
+			var err error
+			result, err = runErrandCheck()
+			return result.Stdout, err
+		}, DefaultTimeout, DefaultInterval).Should(Equal("hello world\r\n"))
+
+		Expect(result.Stderr).To(BeEmpty())
+		Expect(result.ExitStatus).To(Equal(0))
+		Expect(result.Logs).ToNot(BeNil())
 	})
 
 	It("can start a job", func() {
@@ -48,6 +48,7 @@ func NewProvider(
 
 	p.supervisors = map[string]JobSupervisor{
 		"monit":      NewWrapperJobSupervisor(monitJobSupervisor, fs, dirProvider, logger),
+		"systemd":    NewWrapperJobSupervisor(NewSystemdJobSupervisor(fs, runner, logger, dirProvider, timeService), fs, dirProvider, logger),
 		"dummy":      NewDummyJobSupervisor(),
 		"dummy-nats": NewDummyNatsJobSupervisor(handler),
 	}
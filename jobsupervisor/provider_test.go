@@ -84,6 +84,24 @@ func init() {
 			}
 		})
 
+		It("provides a systemd job supervisor", func() {
+			if runtime.GOOS == "windows" {
+				Skip("systemd is only available on Linux")
+			}
+
+			actualSupervisor, err := provider.Get("systemd")
+			Expect(err).ToNot(HaveOccurred())
+
+			expectedSupervisor := NewWrapperJobSupervisor(
+				NewSystemdJobSupervisor(platform.Fs, platform.Runner, logger, dirProvider, timeService),
+				platform.Fs,
+				dirProvider,
+				logger,
+			)
+
+			Expect(actualSupervisor).To(Equal(expectedSupervisor))
+		})
+
 		It("provides a dummy job supervisor", func() {
 			actualSupervisor, err := provider.Get("dummy")
 			Expect(err).ToNot(HaveOccurred())
@@ -0,0 +1,577 @@
+package jobsupervisor
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	systemdJobSupervisorLogTag = "systemdJobSupervisor"
+
+	SystemdUnitsDir        = "/etc/systemd/system"
+	SystemdRuntimeUnitsDir = "/run/systemd/system"
+
+	// Runtime drop-in disables restarts until next Start
+	systemdUnmonitorDropIn = "50-bosh-unmonitor.conf"
+
+	systemdFailurePollInterval = 5 * time.Second
+	systemdStopTimeout         = 5 * time.Minute
+
+	systemdTimestampLayout = "Mon 2006-01-02 15:04:05 MST"
+)
+
+type systemdJobSupervisor struct {
+	fs          boshsys.FileSystem
+	runner      boshsys.CmdRunner
+	logger      boshlog.Logger
+	dirProvider boshdir.Provider
+	timeService clock.Clock
+}
+
+type systemdUnitState struct {
+	Name         string
+	ActiveState  string
+	SubState     string
+	Result       string
+	MainPID      string
+	NRestarts    int
+	ActiveSince  time.Time
+	MemoryBytes  uint64
+	CPUUsageNSec uint64
+}
+
+func NewSystemdJobSupervisor(
+	fs boshsys.FileSystem,
+	runner boshsys.CmdRunner,
+	logger boshlog.Logger,
+	dirProvider boshdir.Provider,
+	timeService clock.Clock,
+) JobSupervisor {
+	return &systemdJobSupervisor{
+		fs:          fs,
+		runner:      runner,
+		logger:      logger,
+		dirProvider: dirProvider,
+		timeService: timeService,
+	}
+}
+
+func (s systemdJobSupervisor) Reload() error {
+	err := s.systemctl("daemon-reload")
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading systemd")
+	}
+
+	units, err := s.units()
+	if err != nil {
+		return err
+	}
+
+	if len(units) == 0 {
+		return nil
+	}
+
+	// Enabled units start on boot similar to monit
+	err = s.systemctl(append([]string{"enable"}, units...)...)
+	if err != nil {
+		return bosherr.WrapError(err, "Enabling job units")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) Start() error {
+	units, err := s.units()
+	if err != nil {
+		return err
+	}
+
+	err = s.remonitor(units)
+	if err != nil {
+		return err
+	}
+
+	if len(units) > 0 {
+		s.logger.Debug(systemdJobSupervisorLogTag, "Starting units %v", units)
+
+		err = s.systemctl(append([]string{"start"}, units...)...)
+		if err != nil {
+			return bosherr.WrapError(err, "Starting job units")
+		}
+	}
+
+	err = s.fs.RemoveAll(s.stoppedFilePath())
+	if err != nil {
+		return bosherr.WrapError(err, "Removing stopped File")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) Stop() error {
+	err := s.stopUnits()
+	if err != nil {
+		return err
+	}
+
+	err = s.fs.WriteFileString(s.stoppedFilePath(), "")
+	if err != nil {
+		return bosherr.WrapError(err, "Creating stopped File")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) StopAndWait() error {
+	err := s.Stop()
+	if err != nil {
+		return err
+	}
+
+	timer := s.timeService.NewTimer(systemdStopTimeout)
+
+	s.logger.Debug(systemdJobSupervisorLogTag, "Waiting for units to stop")
+
+	for {
+		states, err := s.unitStates()
+		if err != nil {
+			return err
+		}
+
+		var failedUnits, unitsToStop []string
+
+		for _, state := range states {
+			switch state.ActiveState {
+			case "inactive":
+			case "failed":
+				failedUnits = append(failedUnits, state.Name)
+			default:
+				unitsToStop = append(unitsToStop, state.Name)
+			}
+		}
+
+		if len(failedUnits) > 0 {
+			return bosherr.Errorf("Stopping units '%s' errored", strings.Join(failedUnits, ", "))
+		}
+
+		if len(unitsToStop) == 0 {
+			s.logger.Debug(systemdJobSupervisorLogTag, "Successfully stopped all units")
+			return nil
+		}
+
+		select {
+		case <-timer.C():
+			return bosherr.Errorf("Timed out waiting for units '%s' to stop after 5 minutes", strings.Join(unitsToStop, ", "))
+		default:
+		}
+
+		s.logger.Debug(systemdJobSupervisorLogTag, "Waiting for '%v' to stop", unitsToStop)
+		s.timeService.Sleep(500 * time.Millisecond)
+	}
+}
+
+func (s systemdJobSupervisor) Unmonitor() error {
+	units, err := s.units()
+	if err != nil {
+		return err
+	}
+
+	if len(units) == 0 {
+		return nil
+	}
+
+	for _, unit := range units {
+		s.logger.Debug(systemdJobSupervisorLogTag, "Unmonitoring unit %s", unit)
+
+		err = s.fs.WriteFileString(s.unmonitorDropInPath(unit), "[Service]\nRestart=no\n")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Unmonitoring unit %s", unit)
+		}
+	}
+
+	err = s.systemctl("daemon-reload")
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading systemd")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) Status() (status string) {
+	if s.fs.FileExists(s.stoppedFilePath()) {
+		return "stopped"
+	}
+
+	states, err := s.unitStates()
+	if err != nil {
+		s.logger.Error(systemdJobSupervisorLogTag, "Getting unit states: %s", err.Error())
+		return "unknown"
+	}
+
+	status = "running"
+
+	for _, state := range states {
+		switch processState(state) {
+		case "starting":
+			return "starting"
+		case "running":
+		default:
+			status = "failing"
+		}
+	}
+
+	return status
+}
+
+func (s systemdJobSupervisor) Processes() ([]Process, error) {
+	processes := []Process{}
+
+	states, err := s.unitStates()
+	if err != nil {
+		return processes, bosherr.WrapError(err, "Getting unit states")
+	}
+
+	for _, state := range states {
+		process := Process{
+			Name:  strings.TrimSuffix(strings.TrimPrefix(state.Name, systemdUnitPrefix), ".service"),
+			State: processState(state),
+			Memory: MemoryVitals{
+				Kb: int(state.MemoryBytes / 1024),
+			},
+		}
+
+		if !state.ActiveSince.IsZero() && state.ActiveState == "active" {
+			uptime := s.timeService.Now().Sub(state.ActiveSince)
+			process.Uptime.Secs = int(uptime.Seconds())
+
+			// CPU usage is cumulative since activation, report it averaged over uptime
+			if uptime > 0 {
+				process.CPU.Total = float64(state.CPUUsageNSec) / float64(uptime.Nanoseconds()) * 100
+			}
+		}
+
+		processes = append(processes, process)
+	}
+
+	return processes, nil
+}
+
+func (s systemdJobSupervisor) AddJob(jobName string, jobIndex int, configPath string) error {
+	config, err := s.fs.ReadFile(configPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Reading job config from file")
+	}
+
+	units, err := SystemdUnitsFromConfig(jobName, config)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Generating units for job %s", jobName)
+	}
+
+	if len(units) == 0 {
+		s.logger.Debug(systemdJobSupervisorLogTag, "Skipping job configuration for %q, no processes in %q", jobName, configPath)
+		return nil
+	}
+
+	for _, unit := range units {
+		err = s.fs.WriteFileString(path.Join(SystemdUnitsDir, unit.Name()), unit.Contents())
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing unit %s", unit.Name())
+		}
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) RemoveAllJobs() error {
+	units, err := s.units()
+	if err != nil {
+		return err
+	}
+
+	if len(units) == 0 {
+		return nil
+	}
+
+	err = s.systemctl(append([]string{"disable"}, units...)...)
+	if err != nil {
+		return bosherr.WrapError(err, "Disabling job units")
+	}
+
+	for _, unit := range units {
+		err = s.fs.RemoveAll(path.Join(SystemdUnitsDir, unit))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing unit %s", unit)
+		}
+
+		err = s.fs.RemoveAll(path.Dir(s.unmonitorDropInPath(unit)))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Removing drop-ins for unit %s", unit)
+		}
+	}
+
+	err = s.systemctl("daemon-reload")
+	if err != nil {
+		return bosherr.WrapError(err, "Reloading systemd")
+	}
+
+	return nil
+}
+
+// MonitorJobFailures polls units since systemd restarts failed
+// processes itself and only needs to report them
+func (s systemdJobSupervisor) MonitorJobFailures(handler JobFailureHandler) error {
+	previous := map[string]systemdUnitState{}
+
+	ticker := s.timeService.NewTicker(systemdFailurePollInterval)
+	defer ticker.Stop()
+
+	for {
+		states, err := s.unitStates()
+		if err != nil {
+			s.logger.Error(systemdJobSupervisorLogTag, "Getting unit states: %s", err.Error())
+		} else {
+			current := map[string]systemdUnitState{}
+
+			for _, state := range states {
+				current[state.Name] = state
+
+				prevState, found := previous[state.Name]
+				if !found {
+					continue
+				}
+
+				alert, failed := s.failureAlert(prevState, state)
+				if !failed {
+					continue
+				}
+
+				err = handler(alert)
+				if err != nil {
+					s.logger.Error(systemdJobSupervisorLogTag, "Handling failure of unit %s: %s", state.Name, err.Error())
+				}
+			}
+
+			previous = current
+		}
+
+		<-ticker.C()
+	}
+}
+
+func (s systemdJobSupervisor) HealthRecorder(status string) {
+}
+
+func (s systemdJobSupervisor) failureAlert(prev, curr systemdUnitState) (boshalert.MonitAlert, bool) {
+	now := s.timeService.Now()
+	service := strings.TrimSuffix(strings.TrimPrefix(curr.Name, systemdUnitPrefix), ".service")
+
+	alert := boshalert.MonitAlert{
+		ID:      fmt.Sprintf("%d.%s@localhost", now.UnixNano(), curr.Name),
+		Service: service,
+		Date:    now.Format(time.RFC1123Z),
+	}
+
+	switch {
+	case curr.ActiveState == "failed" && prev.ActiveState != "failed":
+		alert.Event = "does not exist"
+		alert.Action = "alert"
+		alert.Description = fmt.Sprintf("process is not running (result: %s)", curr.Result)
+		return alert, true
+
+	case curr.NRestarts > prev.NRestarts:
+		alert.Event = "pid changed"
+		alert.Action = "restart"
+		alert.Description = fmt.Sprintf("process was restarted %d time(s)", curr.NRestarts-prev.NRestarts)
+		return alert, true
+	}
+
+	return alert, false
+}
+
+func (s systemdJobSupervisor) stopUnits() error {
+	units, err := s.units()
+	if err != nil {
+		return err
+	}
+
+	if len(units) == 0 {
+		return nil
+	}
+
+	s.logger.Debug(systemdJobSupervisorLogTag, "Stopping units %v", units)
+
+	err = s.systemctl(append([]string{"stop"}, units...)...)
+	if err != nil {
+		return bosherr.WrapError(err, "Stopping job units")
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) remonitor(units []string) error {
+	removed := false
+
+	for _, unit := range units {
+		dropInPath := s.unmonitorDropInPath(unit)
+		if !s.fs.FileExists(dropInPath) {
+			continue
+		}
+
+		err := s.fs.RemoveAll(dropInPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Monitoring unit %s", unit)
+		}
+
+		removed = true
+	}
+
+	if removed {
+		err := s.systemctl("daemon-reload")
+		if err != nil {
+			return bosherr.WrapError(err, "Reloading systemd")
+		}
+	}
+
+	return nil
+}
+
+func (s systemdJobSupervisor) units() ([]string, error) {
+	paths, err := s.fs.Glob(path.Join(SystemdUnitsDir, systemdUnitPrefix+"*.service"))
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Listing job units")
+	}
+
+	units := make([]string, 0, len(paths))
+	for _, unitPath := range paths {
+		units = append(units, path.Base(unitPath))
+	}
+	sort.Strings(units)
+
+	return units, nil
+}
+
+func (s systemdJobSupervisor) unitStates() ([]systemdUnitState, error) {
+	units, err := s.units()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(units) == 0 {
+		return nil, nil
+	}
+
+	args := []string{
+		"show",
+		"--property=Id,ActiveState,SubState,Result,MainPID,NRestarts,ActiveEnterTimestamp,MemoryCurrent,CPUUsageNSec",
+	}
+
+	stdout, stderr, _, err := s.runner.RunCommand("systemctl", append(args, units...)...)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Showing job units: %s", stderr)
+	}
+
+	return parseSystemdShow(stdout), nil
+}
+
+func (s systemdJobSupervisor) systemctl(args ...string) error {
+	_, stderr, _, err := s.runner.RunCommand("systemctl", args...)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Running systemctl %s: %s", args[0], stderr)
+	}
+	return nil
+}
+
+func (s systemdJobSupervisor) stoppedFilePath() string {
+	return path.Join(s.dirProvider.BoshDir(), "systemd_jobs_stopped")
+}
+
+func (s systemdJobSupervisor) unmonitorDropInPath(unit string) string {
+	return path.Join(SystemdRuntimeUnitsDir, unit+".d", systemdUnmonitorDropIn)
+}
+
+// parseSystemdShow parses `systemctl show` output
+// which separates properties of each unit with empty line
+func parseSystemdShow(output string) []systemdUnitState {
+	var states []systemdUnitState
+	var current *systemdUnitState
+
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			if current != nil {
+				states = append(states, *current)
+				current = nil
+			}
+			continue
+		}
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		if current == nil {
+			current = &systemdUnitState{}
+		}
+
+		key, value := parts[0], parts[1]
+
+		switch key {
+		case "Id":
+			current.Name = value
+		case "ActiveState":
+			current.ActiveState = value
+		case "SubState":
+			current.SubState = value
+		case "Result":
+			current.Result = value
+		case "MainPID":
+			current.MainPID = value
+		case "NRestarts":
+			current.NRestarts, _ = strconv.Atoi(value)
+		case "ActiveEnterTimestamp":
+			current.ActiveSince, _ = time.Parse(systemdTimestampLayout, value)
+		case "MemoryCurrent":
+			// Value is [not set] when memory accounting is disabled
+			current.MemoryBytes, _ = strconv.ParseUint(value, 10, 64)
+		case "CPUUsageNSec":
+			// Value is [not set] when CPU accounting is disabled
+			current.CPUUsageNSec, _ = strconv.ParseUint(value, 10, 64)
+		}
+	}
+
+	if current != nil {
+		states = append(states, *current)
+	}
+
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+
+	return states
+}
+
+func processState(state systemdUnitState) string {
+	switch state.ActiveState {
+	case "active", "reloading":
+		return "running"
+	case "activating":
+		return "starting"
+	case "deactivating":
+		return "stopping"
+	case "inactive":
+		return "stopped"
+	default:
+		return "failing"
+	}
+}
@@ -0,0 +1,311 @@
+package jobsupervisor_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshalert "github.com/cloudfoundry/bosh-agent/agent/alert"
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+const systemdShowCmd = "systemctl show --property=Id,ActiveState,SubState,Result,MainPID,NRestarts,ActiveEnterTimestamp,MemoryCurrent,CPUUsageNSec bosh-job-a.service bosh-job-b.service"
+
+func systemdShowOutput(aState, bState string, bRestarts string) string {
+	return `Id=bosh-job-b.service
+ActiveState=` + bState + `
+SubState=running
+Result=success
+MainPID=200
+NRestarts=` + bRestarts + `
+ActiveEnterTimestamp=Sun 2017-01-01 00:00:00 UTC
+MemoryCurrent=[not set]
+CPUUsageNSec=[not set]
+
+Id=bosh-job-a.service
+ActiveState=` + aState + `
+SubState=running
+Result=exit-code
+MainPID=100
+NRestarts=0
+ActiveEnterTimestamp=Sun 2017-01-01 00:00:00 UTC
+MemoryCurrent=2048000
+CPUUsageNSec=25000000000
+`
+}
+
+var _ = Describe("systemdJobSupervisor", func() {
+	var (
+		fs          *fakesys.FakeFileSystem
+		runner      *fakesys.FakeCmdRunner
+		timeService *fakeclock.FakeClock
+		supervisor  JobSupervisor
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		runner = fakesys.NewFakeCmdRunner()
+		timeService = fakeclock.NewFakeClock(time.Date(2017, 1, 1, 0, 1, 40, 0, time.UTC))
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		dirProvider := boshdir.NewProvider("/var/vcap")
+
+		supervisor = NewSystemdJobSupervisor(fs, runner, logger, dirProvider, timeService)
+
+		fs.SetGlob("/etc/systemd/system/bosh-job-*.service", []string{
+			"/etc/systemd/system/bosh-job-b.service",
+			"/etc/systemd/system/bosh-job-a.service",
+		})
+	})
+
+	Describe("Reload", func() {
+		It("reloads systemd and enables job units", func() {
+			err := supervisor.Reload()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"systemctl", "daemon-reload"},
+				{"systemctl", "enable", "bosh-job-a.service", "bosh-job-b.service"},
+			}))
+		})
+
+		It("returns error when reloading fails", func() {
+			runner.AddCmdResult("systemctl daemon-reload", fakesys.FakeCmdResult{Error: errors.New("fake-err")})
+
+			err := supervisor.Reload()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-err"))
+		})
+	})
+
+	Describe("Start", func() {
+		It("starts job units and removes stopped file", func() {
+			err := fs.WriteFileString("/var/vcap/bosh/systemd_jobs_stopped", "")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = supervisor.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"systemctl", "start", "bosh-job-a.service", "bosh-job-b.service"},
+			}))
+			Expect(fs.FileExists("/var/vcap/bosh/systemd_jobs_stopped")).To(BeFalse())
+		})
+
+		It("removes unmonitor drop-ins before starting", func() {
+			dropIn := "/run/systemd/system/bosh-job-a.service.d/50-bosh-unmonitor.conf"
+			err := fs.WriteFileString(dropIn, "")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = supervisor.Start()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists(dropIn)).To(BeFalse())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"systemctl", "daemon-reload"},
+				{"systemctl", "start", "bosh-job-a.service", "bosh-job-b.service"},
+			}))
+		})
+
+		It("returns error when starting fails", func() {
+			runner.AddCmdResult("systemctl start bosh-job-a.service bosh-job-b.service", fakesys.FakeCmdResult{Error: errors.New("fake-err")})
+
+			err := supervisor.Start()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Starting job units"))
+		})
+	})
+
+	Describe("Stop", func() {
+		It("stops job units and creates stopped file", func() {
+			err := supervisor.Stop()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"systemctl", "stop", "bosh-job-a.service", "bosh-job-b.service"},
+			}))
+			Expect(fs.FileExists("/var/vcap/bosh/systemd_jobs_stopped")).To(BeTrue())
+			Expect(supervisor.Status()).To(Equal("stopped"))
+		})
+	})
+
+	Describe("StopAndWait", func() {
+		It("waits for all units to become inactive", func() {
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("inactive", "deactivating", "0")})
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("inactive", "inactive", "0"), Sticky: true})
+
+			errCh := make(chan error)
+			go func() { errCh <- supervisor.StopAndWait() }()
+
+			timeService.WaitForNWatchersAndIncrement(500*time.Millisecond, 2)
+
+			Eventually(errCh).Should(Receive(BeNil()))
+		})
+
+		It("returns error when units fail to stop", func() {
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("failed", "inactive", "0"), Sticky: true})
+
+			err := supervisor.StopAndWait()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Stopping units 'bosh-job-a.service' errored"))
+		})
+
+		It("returns error when units do not stop in time", func() {
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("inactive", "deactivating", "0"), Sticky: true})
+
+			errCh := make(chan error)
+			go func() { errCh <- supervisor.StopAndWait() }()
+
+			timeService.WaitForNWatchersAndIncrement(6*time.Minute, 2)
+
+			var err error
+			Eventually(errCh).Should(Receive(&err))
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Timed out waiting for units 'bosh-job-b.service' to stop"))
+		})
+	})
+
+	Describe("Unmonitor", func() {
+		It("disables restarts for job units until next start", func() {
+			err := supervisor.Unmonitor()
+			Expect(err).ToNot(HaveOccurred())
+
+			for _, unit := range []string{"bosh-job-a.service", "bosh-job-b.service"} {
+				contents, err := fs.ReadFileString("/run/systemd/system/" + unit + ".d/50-bosh-unmonitor.conf")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(contents).To(Equal("[Service]\nRestart=no\n"))
+			}
+
+			Expect(runner.RunCommands).To(Equal([][]string{{"systemctl", "daemon-reload"}}))
+		})
+	})
+
+	Describe("Status", func() {
+		It("returns running when all units are active", func() {
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("active", "active", "0")})
+			Expect(supervisor.Status()).To(Equal("running"))
+		})
+
+		It("returns starting when any unit is activating", func() {
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("failed", "activating", "0")})
+			Expect(supervisor.Status()).To(Equal("starting"))
+		})
+
+		It("returns failing when any unit is not running", func() {
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("failed", "active", "0")})
+			Expect(supervisor.Status()).To(Equal("failing"))
+		})
+
+		It("returns unknown when unit states cannot be retrieved", func() {
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Error: errors.New("fake-err")})
+			Expect(supervisor.Status()).To(Equal("unknown"))
+		})
+	})
+
+	Describe("Processes", func() {
+		It("returns processes from unit states", func() {
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("active", "failed", "0")})
+
+			processes, err := supervisor.Processes()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(processes).To(Equal([]Process{
+				{
+					Name:   "a",
+					State:  "running",
+					Uptime: UptimeVitals{Secs: 100},
+					Memory: MemoryVitals{Kb: 2000},
+					CPU:    CPUVitals{Total: 25},
+				},
+				{
+					Name:  "b",
+					State: "failing",
+				},
+			}))
+		})
+	})
+
+	Describe("AddJob", func() {
+		It("writes units generated from job config", func() {
+			err := fs.WriteFileString("/var/vcap/jobs/nats/monit", `check process nats
+  with pidfile /var/vcap/sys/run/nats/nats.pid
+  start program "/var/vcap/jobs/nats/bin/nats_ctl start"
+`)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = supervisor.AddJob("nats", 0, "/var/vcap/jobs/nats/monit")
+			Expect(err).ToNot(HaveOccurred())
+
+			contents, err := fs.ReadFileString("/etc/systemd/system/bosh-job-nats.service")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(contents).To(ContainSubstring("ExecStart=/var/vcap/jobs/nats/bin/nats_ctl start\n"))
+		})
+
+		It("returns error when job config is invalid", func() {
+			err := fs.WriteFileString("/var/vcap/jobs/nats/monit", "check process nats\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = supervisor.AddJob("nats", 0, "/var/vcap/jobs/nats/monit")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Generating units for job nats"))
+		})
+	})
+
+	Describe("RemoveAllJobs", func() {
+		It("disables and removes job units", func() {
+			err := fs.WriteFileString("/etc/systemd/system/bosh-job-a.service", "")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString("/run/systemd/system/bosh-job-a.service.d/50-bosh-unmonitor.conf", "")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = supervisor.RemoveAllJobs()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/systemd/system/bosh-job-a.service")).To(BeFalse())
+			Expect(fs.FileExists("/run/systemd/system/bosh-job-a.service.d")).To(BeFalse())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"systemctl", "disable", "bosh-job-a.service", "bosh-job-b.service"},
+				{"systemctl", "daemon-reload"},
+			}))
+		})
+	})
+
+	Describe("MonitorJobFailures", func() {
+		It("reports units that failed or were restarted", func() {
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("active", "active", "0")})
+			runner.AddCmdResult(systemdShowCmd, fakesys.FakeCmdResult{Stdout: systemdShowOutput("failed", "active", "2"), Sticky: true})
+
+			alerts := make(chan boshalert.MonitAlert, 10)
+
+			go supervisor.MonitorJobFailures(func(alert boshalert.MonitAlert) error {
+				alerts <- alert
+				return nil
+			})
+
+			timeService.WaitForWatcherAndIncrement(5 * time.Second)
+
+			var alert boshalert.MonitAlert
+
+			Eventually(alerts).Should(Receive(&alert))
+			Expect(alert.Service).To(Equal("a"))
+			Expect(alert.Event).To(Equal("does not exist"))
+			Expect(alert.Action).To(Equal("alert"))
+			Expect(alert.Description).To(Equal("process is not running (result: exit-code)"))
+
+			Eventually(alerts).Should(Receive(&alert))
+			Expect(alert.Service).To(Equal("b"))
+			Expect(alert.Event).To(Equal("pid changed"))
+			Expect(alert.Action).To(Equal("restart"))
+
+			timeService.WaitForWatcherAndIncrement(5 * time.Second)
+			Consistently(alerts).ShouldNot(Receive())
+		})
+	})
+})
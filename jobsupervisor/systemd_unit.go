@@ -0,0 +1,270 @@
+package jobsupervisor
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+// SystemdProcessConfig uses the same format as job process specs on Windows
+type SystemdProcessConfig struct {
+	Processes []SystemdProcess `json:"processes"`
+}
+
+type SystemdProcess struct {
+	Name       string            `json:"name"`
+	Executable string            `json:"executable"`
+	Args       []string          `json:"args"`
+	Env        map[string]string `json:"env"`
+
+	// Type is systemd service type; defaults to simple.
+	// Processes that daemonize should use forking with PIDFile.
+	Type    string `json:"type"`
+	PIDFile string `json:"pid_file"`
+
+	Stop *struct {
+		Executable string   `json:"executable"`
+		Args       []string `json:"args"`
+	} `json:"stop,omitempty"`
+}
+
+// SystemdUnit describes a single job process supervised by systemd
+type SystemdUnit struct {
+	JobName     string
+	ProcessName string
+
+	// Type is systemd service type; simple when empty
+	Type    string
+	PIDFile string
+
+	ExecStart string
+	ExecStop  string
+	Env       map[string]string
+}
+
+// Name is unique per process since monit also requires unique process names
+func (u SystemdUnit) Name() string {
+	return systemdUnitPrefix + systemdUnitNameReplacer.ReplaceAllString(u.ProcessName, "_") + ".service"
+}
+
+func (u SystemdUnit) Contents() string {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "[Unit]\n")
+	fmt.Fprintf(&buf, "Description=BOSH job %s process %s\n", u.JobName, u.ProcessName)
+	fmt.Fprintf(&buf, "After=network.target\n")
+	fmt.Fprintf(&buf, "\n[Service]\n")
+
+	serviceType := u.Type
+	if serviceType == "" {
+		serviceType = SystemdServiceTypeSimple
+	}
+
+	fmt.Fprintf(&buf, "Type=%s\n", serviceType)
+	if u.PIDFile != "" {
+		fmt.Fprintf(&buf, "PIDFile=%s\n", u.PIDFile)
+	}
+
+	envNames := make([]string, 0, len(u.Env))
+	for name := range u.Env {
+		envNames = append(envNames, name)
+	}
+	sort.Strings(envNames)
+
+	for _, name := range envNames {
+		fmt.Fprintf(&buf, "Environment=%s\n", quoteSystemdEnv(name+"="+u.Env[name]))
+	}
+
+	fmt.Fprintf(&buf, "ExecStart=%s\n", u.ExecStart)
+	if u.ExecStop != "" {
+		fmt.Fprintf(&buf, "ExecStop=%s\n", u.ExecStop)
+	}
+
+	fmt.Fprintf(&buf, "Restart=on-failure\n")
+	fmt.Fprintf(&buf, "RestartSec=%d\n", systemdRestartSec)
+	fmt.Fprintf(&buf, "\n[Install]\n")
+	fmt.Fprintf(&buf, "WantedBy=multi-user.target\n")
+
+	return buf.String()
+}
+
+const (
+	SystemdServiceTypeSimple  = "simple"
+	SystemdServiceTypeExec    = "exec"
+	SystemdServiceTypeForking = "forking"
+	SystemdServiceTypeNotify  = "notify"
+
+	systemdUnitPrefix = "bosh-job-"
+	systemdRestartSec = 5
+)
+
+var systemdServiceTypes = map[string]bool{
+	"":                        true,
+	SystemdServiceTypeSimple:  true,
+	SystemdServiceTypeExec:    true,
+	SystemdServiceTypeForking: true,
+	SystemdServiceTypeNotify:  true,
+}
+
+var (
+	systemdUnitNameReplacer = regexp.MustCompile(`[^a-zA-Z0-9:_.\-]`)
+
+	monitCheckProcessRegexp = regexp.MustCompile(`^check\s+process\s+(\S+)`)
+	monitPIDFileRegexp      = regexp.MustCompile(`\bpidfile\s+(\S+)`)
+	monitProgramRegexp      = regexp.MustCompile(`\b(start|stop)\s+program\s*=?\s*("(?:[^"\\]|\\.)*"|'[^']*')`)
+)
+
+// SystemdUnitsFromConfig accepts either job's monit config
+// or process spec
+func SystemdUnitsFromConfig(jobName string, config []byte) ([]SystemdUnit, error) {
+	trimmed := bytes.TrimSpace(config)
+	if len(trimmed) == 0 {
+		return nil, nil
+	}
+
+	if trimmed[0] == '{' {
+		return systemdUnitsFromProcessSpec(jobName, trimmed)
+	}
+
+	return systemdUnitsFromMonit(jobName, string(config))
+}
+
+func systemdUnitsFromProcessSpec(jobName string, config []byte) ([]SystemdUnit, error) {
+	var processConfig SystemdProcessConfig
+
+	err := json.Unmarshal(config, &processConfig)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Unmarshalling process spec")
+	}
+
+	var units []SystemdUnit
+
+	for _, process := range processConfig.Processes {
+		if process.Name == "" || process.Executable == "" {
+			return nil, bosherr.Errorf("Process in job %s requires name and executable", jobName)
+		}
+
+		if !systemdServiceTypes[process.Type] {
+			return nil, bosherr.Errorf("Process %s in job %s has unsupported type '%s'", process.Name, jobName, process.Type)
+		}
+
+		unit := SystemdUnit{
+			JobName:     jobName,
+			ProcessName: process.Name,
+			Type:        process.Type,
+			PIDFile:     escapeSystemdSpecifiers(process.PIDFile),
+			ExecStart:   systemdCommand(process.Executable, process.Args),
+			Env:         process.Env,
+		}
+
+		if process.Stop != nil {
+			unit.ExecStop = systemdCommand(process.Stop.Executable, process.Stop.Args)
+		}
+
+		units = append(units, unit)
+	}
+
+	return units, nil
+}
+
+func systemdUnitsFromMonit(jobName string, config string) ([]SystemdUnit, error) {
+	var units []SystemdUnit
+	var current *SystemdUnit
+
+	for _, line := range strings.Split(config, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "check ") {
+			if current != nil {
+				units = append(units, *current)
+				current = nil
+			}
+
+			match := monitCheckProcessRegexp.FindStringSubmatch(line)
+			if match == nil {
+				// Other checks such as files do not map to processes
+				continue
+			}
+
+			// Monit waits for start programs to return so they have to daemonize;
+			// jobs can ship process spec instead to run in foreground
+			current = &SystemdUnit{JobName: jobName, ProcessName: match[1], Type: SystemdServiceTypeForking}
+		}
+
+		if current == nil {
+			continue
+		}
+
+		if match := monitPIDFileRegexp.FindStringSubmatch(line); match != nil {
+			current.PIDFile = escapeSystemdSpecifiers(match[1])
+		}
+
+		for _, match := range monitProgramRegexp.FindAllStringSubmatch(line, -1) {
+			command := escapeMonitProgram(unquoteMonitProgram(match[2]))
+			if match[1] == "start" {
+				current.ExecStart = command
+			} else {
+				current.ExecStop = command
+			}
+		}
+	}
+
+	if current != nil {
+		units = append(units, *current)
+	}
+
+	for _, unit := range units {
+		if unit.ExecStart == "" {
+			return nil, bosherr.Errorf("Monit process %s in job %s does not have start program", unit.ProcessName, jobName)
+		}
+	}
+
+	return units, nil
+}
+
+func unquoteMonitProgram(quoted string) string {
+	unquoted := quoted[1 : len(quoted)-1]
+	if quoted[0] == '"' {
+		unquoted = strings.Replace(unquoted, `\"`, `"`, -1)
+	}
+	return unquoted
+}
+
+func systemdCommand(executable string, args []string) string {
+	quoted := []string{quoteSystemdArg(executable)}
+	for _, arg := range args {
+		quoted = append(quoted, quoteSystemdArg(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// quoteSystemdArg quotes values so that systemd does not split them
+// and does not expand specifiers or variables in them
+func quoteSystemdArg(arg string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `%`, `%%`, `$`, `$$`).Replace(arg) + `"`
+}
+
+// escapeSystemdSpecifiers keeps systemd from expanding specifiers such as %h
+func escapeSystemdSpecifiers(value string) string {
+	return strings.Replace(value, `%`, `%%`, -1)
+}
+
+// escapeMonitProgram keeps monit programs as they are since
+// monit neither expands specifiers nor variables in them
+func escapeMonitProgram(program string) string {
+	return strings.Replace(escapeSystemdSpecifiers(program), `$`, `$$`, -1)
+}
+
+// quoteSystemdEnv is similar to quoteSystemdArg except that
+// variables are not expanded in environment assignments
+func quoteSystemdEnv(assignment string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, `%`, `%%`).Replace(assignment) + `"`
+}
@@ -0,0 +1,183 @@
+package jobsupervisor_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+)
+
+var _ = Describe("SystemdUnitsFromConfig", func() {
+	It("generates forking units from monit config", func() {
+		units, err := SystemdUnitsFromConfig("fake-job", []byte(`
+check process nats
+  with pidfile /var/vcap/sys/run/nats/nats.pid
+  start program "/var/vcap/jobs/nats/bin/nats_ctl start" with timeout 60 seconds
+  stop program "/bin/bash -c \"/var/vcap/jobs/nats/bin/nats_ctl stop\""
+  group vcap
+
+check file nats_config with path /var/vcap/jobs/nats/config/nats.conf
+  if changed checksum then alert
+
+# comment
+check process health_monitor
+  start program '/var/vcap/jobs/hm/bin/hm_ctl start'
+  group vcap
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(units).To(Equal([]SystemdUnit{
+			{
+				JobName:     "fake-job",
+				ProcessName: "nats",
+				Type:        SystemdServiceTypeForking,
+				PIDFile:     "/var/vcap/sys/run/nats/nats.pid",
+				ExecStart:   "/var/vcap/jobs/nats/bin/nats_ctl start",
+				ExecStop:    `/bin/bash -c "/var/vcap/jobs/nats/bin/nats_ctl stop"`,
+			},
+			{
+				JobName:     "fake-job",
+				ProcessName: "health_monitor",
+				Type:        SystemdServiceTypeForking,
+				ExecStart:   "/var/vcap/jobs/hm/bin/hm_ctl start",
+			},
+		}))
+	})
+
+	It("escapes specifiers and variables in monit programs", func() {
+		units, err := SystemdUnitsFromConfig("fake-job", []byte(`
+check process web
+  with pidfile /var/vcap/sys/run/web/100%.pid
+  start program "/bin/bash -c 'date +%s > /tmp/started; echo $HOME'"
+  stop program "/bin/kill %1"
+`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(units[0].PIDFile).To(Equal("/var/vcap/sys/run/web/100%%.pid"))
+		Expect(units[0].ExecStart).To(Equal("/bin/bash -c 'date +%%s > /tmp/started; echo $$HOME'"))
+		Expect(units[0].ExecStop).To(Equal("/bin/kill %%1"))
+	})
+
+	It("returns error when monit process does not have start program", func() {
+		_, err := SystemdUnitsFromConfig("fake-job", []byte("check process nats\n  group vcap\n"))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Monit process nats in job fake-job does not have start program"))
+	})
+
+	It("generates simple units from process spec", func() {
+		units, err := SystemdUnitsFromConfig("fake-job", []byte(`{
+			"processes": [
+				{
+					"name": "web",
+					"executable": "/var/vcap/packages/web/bin/web",
+					"args": ["--port", "8080", "--greeting", "hello world"],
+					"env": {"HOME": "/var/vcap/data/web"},
+					"stop": {"executable": "/var/vcap/packages/web/bin/stop", "args": ["now"]}
+				}
+			]
+		}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(units).To(Equal([]SystemdUnit{
+			{
+				JobName:     "fake-job",
+				ProcessName: "web",
+				ExecStart:   `"/var/vcap/packages/web/bin/web" "--port" "8080" "--greeting" "hello world"`,
+				ExecStop:    `"/var/vcap/packages/web/bin/stop" "now"`,
+				Env:         map[string]string{"HOME": "/var/vcap/data/web"},
+			},
+		}))
+	})
+
+	It("generates units of configured type from process spec", func() {
+		units, err := SystemdUnitsFromConfig("fake-job", []byte(`{
+			"processes": [
+				{
+					"name": "web",
+					"executable": "/var/vcap/jobs/web/bin/web_ctl",
+					"type": "forking",
+					"pid_file": "/var/vcap/sys/run/web/web.pid"
+				}
+			]
+		}`))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(units).To(Equal([]SystemdUnit{
+			{
+				JobName:     "fake-job",
+				ProcessName: "web",
+				Type:        SystemdServiceTypeForking,
+				PIDFile:     "/var/vcap/sys/run/web/web.pid",
+				ExecStart:   `"/var/vcap/jobs/web/bin/web_ctl"`,
+			},
+		}))
+	})
+
+	It("returns error when process spec has unsupported type", func() {
+		_, err := SystemdUnitsFromConfig("fake-job", []byte(`{"processes": [{"name": "web", "executable": "/bin/web", "type": "oneshot"}]}`))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Process web in job fake-job has unsupported type 'oneshot'"))
+	})
+
+	It("returns error when process spec is invalid", func() {
+		_, err := SystemdUnitsFromConfig("fake-job", []byte(`{"processes": [{"name": "web"}]}`))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("requires name and executable"))
+
+		_, err = SystemdUnitsFromConfig("fake-job", []byte(`{"processes": `))
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unmarshalling process spec"))
+	})
+
+	It("returns no units for empty config", func() {
+		units, err := SystemdUnitsFromConfig("fake-job", []byte("  \n"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(units).To(BeEmpty())
+	})
+})
+
+var _ = Describe("SystemdUnit", func() {
+	It("renders forking unit", func() {
+		unit := SystemdUnit{
+			JobName:     "fake-job",
+			ProcessName: "nats",
+			Type:        SystemdServiceTypeForking,
+			PIDFile:     "/var/vcap/sys/run/nats/nats.pid",
+			ExecStart:   "/var/vcap/jobs/nats/bin/nats_ctl start",
+			ExecStop:    "/var/vcap/jobs/nats/bin/nats_ctl stop",
+		}
+
+		Expect(unit.Name()).To(Equal("bosh-job-nats.service"))
+		Expect(unit.Contents()).To(Equal(`[Unit]
+Description=BOSH job fake-job process nats
+After=network.target
+
+[Service]
+Type=forking
+PIDFile=/var/vcap/sys/run/nats/nats.pid
+ExecStart=/var/vcap/jobs/nats/bin/nats_ctl start
+ExecStop=/var/vcap/jobs/nats/bin/nats_ctl stop
+Restart=on-failure
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+`))
+	})
+
+	It("renders simple unit with escaped environment", func() {
+		unit := SystemdUnit{
+			JobName:     "fake-job",
+			ProcessName: "web server",
+			ExecStart:   `"/bin/web"`,
+			Env:         map[string]string{"B": `100% "$HOME"`, "A": "1"},
+		}
+
+		Expect(unit.Name()).To(Equal("bosh-job-web_server.service"))
+		Expect(unit.Contents()).To(ContainSubstring("Type=simple\n" +
+			`Environment="A=1"` + "\n" +
+			`Environment="B=100%% \"$HOME\""` + "\n" +
+			`ExecStart="/bin/web"` + "\n" +
+			"Restart=on-failure\n"))
+	})
+})
@@ -0,0 +1,91 @@
+package mbus
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	"github.com/cloudfoundry/yagnats"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+// BackoffConnectionProvider delays connection attempts that follow a failed attempt.
+// yagnats reconnects through the same provider, so reconnects after a lost
+// connection back off the same way as the initial connect.
+type BackoffConnectionProvider struct {
+	ConnectionProvider yagnats.ConnectionProvider
+
+	initialDelay time.Duration
+	maxDelay     time.Duration
+	clock        clock.Clock
+	logger       boshlog.Logger
+	logTag       string
+
+	// delay is zero until an attempt fails and is reset once an attempt succeeds
+	delay     time.Duration
+	delayLock sync.Mutex
+}
+
+// NewBackoffConnectionProvider doubles the delay after each failed attempt up to maxDelay
+func NewBackoffConnectionProvider(
+	connectionProvider yagnats.ConnectionProvider,
+	initialDelay time.Duration,
+	maxDelay time.Duration,
+	clock clock.Clock,
+	logger boshlog.Logger,
+) *BackoffConnectionProvider {
+	return &BackoffConnectionProvider{
+		ConnectionProvider: connectionProvider,
+
+		initialDelay: initialDelay,
+		maxDelay:     maxDelay,
+		clock:        clock,
+		logger:       logger,
+		logTag:       "backoffConnectionProvider",
+	}
+}
+
+func (p *BackoffConnectionProvider) ProvideConnection() (*yagnats.Connection, error) {
+	p.delayLock.Lock()
+	delay := p.delay
+	p.delayLock.Unlock()
+
+	if delay > 0 {
+		delay = jitteredDelay(delay)
+		p.logger.Debug(p.logTag, "Waiting %s before connecting", delay)
+		p.clock.Sleep(delay)
+	}
+
+	conn, err := p.ConnectionProvider.ProvideConnection()
+
+	p.delayLock.Lock()
+	defer p.delayLock.Unlock()
+
+	switch {
+	case err == nil:
+		p.delay = 0
+	case p.delay == 0:
+		p.delay = p.initialDelay
+	default:
+		p.delay = nextBackoffDelay(p.delay, p.maxDelay)
+	}
+
+	return conn, err
+}
+
+func nextBackoffDelay(delay, maxDelay time.Duration) time.Duration {
+	delay *= 2
+	if delay > maxDelay {
+		return maxDelay
+	}
+	return delay
+}
+
+// jitteredDelay picks a delay between half and all of the given delay
+// so that agents disconnected at the same time do not reconnect at the same time
+func jitteredDelay(delay time.Duration) time.Duration {
+	half := delay / 2
+	return half + time.Duration(rand.Int63n(int64(delay-half)+1))
+}
@@ -0,0 +1,142 @@
+package mbus_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	"github.com/cloudfoundry/yagnats"
+
+	. "github.com/cloudfoundry/bosh-agent/mbus"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type fakeConnectionProvider struct {
+	errs     []error
+	attempts chan int
+	count    int
+}
+
+func (p *fakeConnectionProvider) ProvideConnection() (*yagnats.Connection, error) {
+	p.count++
+	p.attempts <- p.count
+
+	if len(p.errs) > 0 {
+		err := p.errs[0]
+		p.errs = p.errs[1:]
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+var _ = Describe("BackoffConnectionProvider", func() {
+	var (
+		clock              *fakeclock.FakeClock
+		connectionProvider *fakeConnectionProvider
+		provider           *BackoffConnectionProvider
+	)
+
+	BeforeEach(func() {
+		clock = fakeclock.NewFakeClock(time.Now())
+		connectionProvider = &fakeConnectionProvider{attempts: make(chan int, 10)}
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		provider = NewBackoffConnectionProvider(connectionProvider, 1*time.Second, 2*time.Second, clock, logger)
+	})
+
+	provideConnection := func() chan error {
+		errCh := make(chan error, 1)
+		go func() {
+			_, err := provider.ProvideConnection()
+			errCh <- err
+		}()
+		return errCh
+	}
+
+	It("connects without waiting when previous attempt did not fail", func() {
+		_, err := provider.ProvideConnection()
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = provider.ProvideConnection()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(connectionProvider.attempts).To(HaveLen(2))
+		Expect(clock.WatcherCount()).To(Equal(0))
+	})
+
+	It("waits between half and all of the delay after a failed attempt", func() {
+		connectionProvider.errs = []error{errors.New("fake-connect-err")}
+
+		_, err := provider.ProvideConnection()
+		Expect(err).To(MatchError("fake-connect-err"))
+		Eventually(connectionProvider.attempts).Should(Receive(Equal(1)))
+
+		errCh := provideConnection()
+
+		clock.WaitForWatcherAndIncrement(499 * time.Millisecond)
+		Consistently(connectionProvider.attempts).ShouldNot(Receive())
+
+		clock.Increment(501 * time.Millisecond)
+		Eventually(connectionProvider.attempts).Should(Receive(Equal(2)))
+		Eventually(errCh).Should(Receive(BeNil()))
+	})
+
+	It("doubles the delay after each failed attempt up to max delay", func() {
+		connectionProvider.errs = []error{
+			errors.New("fake-connect-err-1"),
+			errors.New("fake-connect-err-2"),
+			errors.New("fake-connect-err-3"),
+		}
+
+		_, err := provider.ProvideConnection()
+		Expect(err).To(MatchError("fake-connect-err-1"))
+		Eventually(connectionProvider.attempts).Should(Receive(Equal(1)))
+
+		errCh := provideConnection()
+		clock.WaitForWatcherAndIncrement(1 * time.Second)
+		Eventually(connectionProvider.attempts).Should(Receive(Equal(2)))
+		Eventually(errCh).Should(Receive(MatchError("fake-connect-err-2")))
+
+		errCh = provideConnection()
+		clock.WaitForWatcherAndIncrement(999 * time.Millisecond)
+		Consistently(connectionProvider.attempts).ShouldNot(Receive())
+		clock.Increment(1001 * time.Millisecond)
+		Eventually(connectionProvider.attempts).Should(Receive(Equal(3)))
+		Eventually(errCh).Should(Receive(MatchError("fake-connect-err-3")))
+
+		errCh = provideConnection()
+		clock.WaitForWatcherAndIncrement(999 * time.Millisecond)
+		Consistently(connectionProvider.attempts).ShouldNot(Receive())
+		clock.Increment(1001 * time.Millisecond)
+		Eventually(connectionProvider.attempts).Should(Receive(Equal(4)))
+		Eventually(errCh).Should(Receive(BeNil()))
+	})
+
+	It("resets the delay once an attempt succeeds", func() {
+		connectionProvider.errs = []error{errors.New("fake-connect-err-1")}
+
+		_, err := provider.ProvideConnection()
+		Expect(err).To(HaveOccurred())
+
+		errCh := provideConnection()
+		clock.WaitForWatcherAndIncrement(1 * time.Second)
+		Eventually(errCh).Should(Receive(BeNil()))
+
+		_, err = provider.ProvideConnection()
+		Expect(err).ToNot(HaveOccurred())
+
+		connectionProvider.errs = []error{errors.New("fake-connect-err-2")}
+
+		_, err = provider.ProvideConnection()
+		Expect(err).To(HaveOccurred())
+
+		errCh = provideConnection()
+		clock.WaitForWatcherAndIncrement(1 * time.Second)
+		Eventually(errCh).Should(Receive(BeNil()))
+
+		Expect(connectionProvider.attempts).To(HaveLen(5))
+	})
+})
@@ -0,0 +1,223 @@
+package mbus
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	bufferedHandlerLogTag = "bufferedHandler"
+
+	OutboundQueueMaxSize = 1000
+
+	outboundRetryInitialDelay = 1 * time.Second
+	outboundRetryMaxDelay     = 1 * time.Minute
+)
+
+type outboundMessage struct {
+	id      uint64
+	target  boshhandler.Target
+	topic   boshhandler.Topic
+	message interface{}
+}
+
+// bufferedHandler queues heartbeats and alerts in front of another handler
+// so that message bus outages neither fail senders nor lose alerts.
+// Queued alerts are replayed in order once sending succeeds again;
+// only the latest heartbeat is kept.
+type bufferedHandler struct {
+	delegate boshhandler.Handler
+	maxSize  int
+	clock    clock.Clock
+	logger   boshlog.Logger
+
+	queue     []outboundMessage
+	lastID    uint64
+	queueLock sync.Mutex
+
+	queuedCh  chan struct{}
+	stopCh    chan struct{}
+	startOnce sync.Once
+	stopOnce  sync.Once
+}
+
+func NewBufferedHandler(
+	delegate boshhandler.Handler,
+	maxSize int,
+	clock clock.Clock,
+	logger boshlog.Logger,
+) boshhandler.Handler {
+	return &bufferedHandler{
+		delegate: delegate,
+		maxSize:  maxSize,
+		clock:    clock,
+		logger:   logger,
+
+		queuedCh: make(chan struct{}, 1),
+		stopCh:   make(chan struct{}),
+	}
+}
+
+func (h *bufferedHandler) Run(handlerFunc boshhandler.Func) error {
+	h.startSending()
+	defer h.stopSending()
+
+	return h.delegate.Run(handlerFunc)
+}
+
+func (h *bufferedHandler) Start(handlerFunc boshhandler.Func) error {
+	err := h.delegate.Start(handlerFunc)
+	if err != nil {
+		return err
+	}
+
+	h.startSending()
+
+	return nil
+}
+
+func (h *bufferedHandler) RegisterAdditionalFunc(handlerFunc boshhandler.Func) {
+	h.delegate.RegisterAdditionalFunc(handlerFunc)
+}
+
+func (h *bufferedHandler) Send(target boshhandler.Target, topic boshhandler.Topic, message interface{}) error {
+	if !h.isBuffered(target, topic) {
+		return h.delegate.Send(target, topic, message)
+	}
+
+	h.enqueue(target, topic, message)
+
+	return nil
+}
+
+func (h *bufferedHandler) Stop() {
+	h.stopSending()
+	h.delegate.Stop()
+}
+
+func (h *bufferedHandler) isBuffered(target boshhandler.Target, topic boshhandler.Topic) bool {
+	// Other messages such as shutdown notifications must be delivered before returning
+	return target == boshhandler.HealthMonitor && (topic == boshhandler.Heartbeat || topic == boshhandler.Alert)
+}
+
+func (h *bufferedHandler) enqueue(target boshhandler.Target, topic boshhandler.Topic, message interface{}) {
+	h.queueLock.Lock()
+
+	h.lastID++
+	queued := outboundMessage{id: h.lastID, target: target, topic: topic, message: message}
+
+	replaced := false
+
+	if topic == boshhandler.Heartbeat {
+		for i, msg := range h.queue {
+			if msg.target == target && msg.topic == topic {
+				h.queue[i] = queued
+				replaced = true
+				break
+			}
+		}
+	}
+
+	if !replaced {
+		if len(h.queue) >= h.maxSize {
+			dropIdx := h.dropIndex()
+			h.logger.Warn(bufferedHandlerLogTag, "Outbound queue is full, dropping %s message", h.queue[dropIdx].topic)
+			h.queue = append(h.queue[:dropIdx], h.queue[dropIdx+1:]...)
+		}
+
+		h.queue = append(h.queue, queued)
+	}
+
+	h.queueLock.Unlock()
+
+	select {
+	case h.queuedCh <- struct{}{}:
+	default:
+	}
+}
+
+// dropIndex prefers dropping heartbeat over oldest alert
+func (h *bufferedHandler) dropIndex() int {
+	for i, msg := range h.queue {
+		if msg.topic == boshhandler.Heartbeat {
+			return i
+		}
+	}
+	return 0
+}
+
+func (h *bufferedHandler) peek() (outboundMessage, bool) {
+	h.queueLock.Lock()
+	defer h.queueLock.Unlock()
+
+	if len(h.queue) == 0 {
+		return outboundMessage{}, false
+	}
+
+	return h.queue[0], true
+}
+
+func (h *bufferedHandler) remove(id uint64) {
+	h.queueLock.Lock()
+	defer h.queueLock.Unlock()
+
+	// Message might have been coalesced or dropped while it was being sent
+	for i, msg := range h.queue {
+		if msg.id == id {
+			h.queue = append(h.queue[:i], h.queue[i+1:]...)
+			return
+		}
+	}
+}
+
+func (h *bufferedHandler) startSending() {
+	h.startOnce.Do(func() {
+		go h.sendQueued()
+	})
+}
+
+func (h *bufferedHandler) stopSending() {
+	h.stopOnce.Do(func() {
+		close(h.stopCh)
+	})
+}
+
+func (h *bufferedHandler) sendQueued() {
+	defer h.logger.HandlePanic("Buffered Handler Send")
+
+	delay := outboundRetryInitialDelay
+
+	for {
+		msg, found := h.peek()
+		if !found {
+			select {
+			case <-h.queuedCh:
+				continue
+			case <-h.stopCh:
+				return
+			}
+		}
+
+		err := h.delegate.Send(msg.target, msg.topic, msg.message)
+		if err != nil {
+			h.logger.Warn(bufferedHandlerLogTag, "Sending %s message failed, retrying in %s: %s", msg.topic, delay, err.Error())
+
+			select {
+			case <-h.clock.After(delay):
+			case <-h.stopCh:
+				return
+			}
+
+			delay = nextBackoffDelay(delay, outboundRetryMaxDelay)
+			continue
+		}
+
+		h.remove(msg.id)
+
+		delay = outboundRetryInitialDelay
+	}
+}
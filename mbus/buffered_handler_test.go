@@ -0,0 +1,144 @@
+package mbus_test
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	boshhandler "github.com/cloudfoundry/bosh-agent/handler"
+	. "github.com/cloudfoundry/bosh-agent/mbus"
+	fakembus "github.com/cloudfoundry/bosh-agent/mbus/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("bufferedHandler", func() {
+	var (
+		delegate *fakembus.FakeHandler
+		clock    *fakeclock.FakeClock
+		logger   boshlog.Logger
+		handler  boshhandler.Handler
+	)
+
+	BeforeEach(func() {
+		delegate = fakembus.NewFakeHandler()
+		clock = fakeclock.NewFakeClock(time.Now())
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		handler = NewBufferedHandler(delegate, 3, clock, logger)
+	})
+
+	AfterEach(func() {
+		handler.Stop()
+	})
+
+	heartbeat := func(message string) fakembus.SendInput {
+		return fakembus.SendInput{Target: boshhandler.HealthMonitor, Topic: boshhandler.Heartbeat, Message: message}
+	}
+
+	alert := func(message string) fakembus.SendInput {
+		return fakembus.SendInput{Target: boshhandler.HealthMonitor, Topic: boshhandler.Alert, Message: message}
+	}
+
+	send := func(inputs ...fakembus.SendInput) {
+		for _, input := range inputs {
+			err := handler.Send(input.Target, input.Topic, input.Message)
+			Expect(err).ToNot(HaveOccurred())
+		}
+	}
+
+	It("sends other messages directly and returns their errors", func() {
+		delegate.SendErr = errors.New("fake-send-err")
+
+		err := handler.Send(boshhandler.HealthMonitor, boshhandler.Shutdown, nil)
+		Expect(err).To(MatchError("fake-send-err"))
+
+		Expect(delegate.SendInputs()).To(Equal([]fakembus.SendInput{
+			{Target: boshhandler.HealthMonitor, Topic: boshhandler.Shutdown},
+		}))
+	})
+
+	It("queues heartbeats and alerts until handler is started", func() {
+		send(heartbeat("hb-1"), alert("alert-1"))
+		Expect(delegate.SendInputs()).To(BeEmpty())
+
+		err := handler.Start(func(boshhandler.Request) boshhandler.Response { return nil })
+		Expect(err).ToNot(HaveOccurred())
+		Expect(delegate.ReceivedStart).To(BeTrue())
+
+		Eventually(delegate.SendInputs).Should(Equal([]fakembus.SendInput{heartbeat("hb-1"), alert("alert-1")}))
+	})
+
+	It("keeps only the latest queued heartbeat", func() {
+		send(heartbeat("hb-1"), alert("alert-1"), heartbeat("hb-2"), heartbeat("hb-3"))
+
+		err := handler.Start(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(delegate.SendInputs).Should(Equal([]fakembus.SendInput{heartbeat("hb-3"), alert("alert-1")}))
+	})
+
+	It("drops heartbeats before alerts when queue is full", func() {
+		send(heartbeat("hb-1"), alert("alert-1"), alert("alert-2"), alert("alert-3"), alert("alert-4"))
+
+		err := handler.Start(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		Eventually(delegate.SendInputs).Should(Equal([]fakembus.SendInput{alert("alert-2"), alert("alert-3"), alert("alert-4")}))
+	})
+
+	It("replays queued alerts in order with exponential backoff once sending succeeds again", func() {
+		var lock sync.Mutex
+		failures := 2
+
+		delegate.SendCallback = func(input fakembus.SendInput) {
+			lock.Lock()
+			defer lock.Unlock()
+
+			if failures > 0 {
+				failures--
+				delegate.SendErr = errors.New("fake-send-err")
+			} else {
+				delegate.SendErr = nil
+			}
+		}
+
+		err := handler.Start(nil)
+		Expect(err).ToNot(HaveOccurred())
+
+		send(alert("alert-1"), alert("alert-2"))
+		Eventually(delegate.SendInputs).Should(HaveLen(1))
+
+		clock.WaitForWatcherAndIncrement(1 * time.Second)
+		Eventually(delegate.SendInputs).Should(HaveLen(2))
+
+		clock.WaitForWatcherAndIncrement(1 * time.Second)
+		Consistently(delegate.SendInputs).Should(HaveLen(2))
+
+		clock.Increment(1 * time.Second)
+		Eventually(delegate.SendInputs).Should(Equal([]fakembus.SendInput{
+			alert("alert-1"),
+			alert("alert-1"),
+			alert("alert-1"),
+			alert("alert-2"),
+		}))
+	})
+
+	It("starts sending when running and stops delegate when stopped", func() {
+		delegate.RunCallBack = func() { send(alert("alert-1")) }
+
+		err := handler.Run(nil)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(delegate.ReceivedRun).To(BeTrue())
+
+		handler.Stop()
+		Expect(delegate.ReceivedStop).To(BeTrue())
+	})
+
+	It("registers additional funcs with delegate", func() {
+		handler.RegisterAdditionalFunc(func(boshhandler.Request) boshhandler.Response { return nil })
+		Expect(delegate.RegisteredAdditionalFunc).ToNot(BeNil())
+	})
+})
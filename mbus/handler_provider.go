@@ -50,7 +50,8 @@ func (p HandlerProvider) Get(
 	switch mbusURL.Scheme {
 	case "nats":
 		natsClient := NewTimeoutNatsClient(yagnats.NewClient(), clock.NewClock())
-		handler = NewNatsHandler(p.settingsService, natsClient, p.logger, platform)
+		natsHandler := NewNatsHandler(p.settingsService, natsClient, p.logger, platform)
+		handler = NewBufferedHandler(natsHandler, OutboundQueueMaxSize, clock.NewClock(), p.logger)
 	case "https":
 		mbusKeyPair := p.settingsService.GetSettings().Env.Bosh.Mbus.Cert
 		handler = NewHTTPSHandler(mbusURL, mbusKeyPair, p.logger, platform.GetFs(), dirProvider, p.auditLogger)
@@ -4,6 +4,7 @@ import (
 	gourl "net/url"
 	"reflect"
 
+	"code.cloudfoundry.org/clock"
 	"github.com/cloudfoundry/yagnats"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -34,13 +35,14 @@ var _ = Describe("HandlerProvider", func() {
 	})
 
 	Describe("Get", func() {
-		It("returns nats handler", func() {
+		It("returns buffered nats handler", func() {
 			settingsService.Settings.Mbus = "nats://lol"
 			handler, err := provider.Get(platform, dirProvider)
 			Expect(err).ToNot(HaveOccurred())
 
 			// yagnats.NewClient returns new object every time
-			expectedHandler := NewNatsHandler(settingsService, yagnats.NewClient(), logger, platform)
+			natsHandler := NewNatsHandler(settingsService, yagnats.NewClient(), logger, platform)
+			expectedHandler := NewBufferedHandler(natsHandler, OutboundQueueMaxSize, clock.NewClock(), logger)
 			Expect(reflect.TypeOf(handler)).To(Equal(reflect.TypeOf(expectedHandler)))
 		})
 
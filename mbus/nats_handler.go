@@ -12,6 +12,7 @@ import (
 	"sync"
 	"syscall"
 
+	"code.cloudfoundry.org/clock"
 	"github.com/cloudfoundry/yagnats"
 
 	"crypto/x509"
@@ -31,6 +32,11 @@ const (
 	responseMaxLength        = 1024 * 1024
 	natsHandlerLogTag        = "NATS Handler"
 	natsConnectionMaxRetries = 4
+
+	// Delay between connection attempts doubles after each failure,
+	// including attempts to reconnect after the connection was lost
+	natsConnectionInitialDelay = 500 * time.Millisecond
+	natsConnectionMaxDelay     = 30 * time.Second
 )
 
 type Handler interface {
@@ -106,15 +112,24 @@ func (h *natsHandler) Start(handlerFunc boshhandler.Func) error {
 		}
 	})
 
+	backoffConnProvider := NewBackoffConnectionProvider(
+		connProvider,
+		natsConnectionInitialDelay,
+		natsConnectionMaxDelay,
+		clock.NewClock(),
+		h.logger,
+	)
+
 	natsRetryable := boshretry.NewRetryable(func() (bool, error) {
-		err := h.client.Connect(connProvider)
+		err := h.client.Connect(backoffConnProvider)
 		if err != nil {
 			return true, bosherr.WrapError(err, "Connecting to NATS")
 		}
 		return false, nil
 	})
 
-	attemptRetryStrategy := boshretry.NewAttemptRetryStrategy(natsConnectionMaxRetries, time.Second, natsRetryable, h.logger)
+	// Delay between attempts comes from backoffConnProvider
+	attemptRetryStrategy := boshretry.NewAttemptRetryStrategy(natsConnectionMaxRetries, 0, natsRetryable, h.logger)
 	err = attemptRetryStrategy.Try()
 	if err != nil {
 		return bosherr.WrapError(err, "Connecting")
@@ -224,7 +224,7 @@ func init() {
 				Expect(err).ToNot(HaveOccurred())
 				defer handler.Stop()
 
-				Expect(client.ConnectedConnectionProvider()).To(Equal(&yagnats.ConnectionInfo{
+				Expect(connectedConnectionInfo(client)).To(Equal(&yagnats.ConnectionInfo{
 					Addr:     "127.0.0.1:1234",
 					Username: "fake-username",
 					Password: "fake-password",
@@ -344,7 +344,7 @@ func init() {
 
 					Expect(err, BeNil())
 
-					result := client.ConnectedConnectionProvider().(*yagnats.ConnectionInfo)
+					result := connectedConnectionInfo(client)
 					expected := &yagnats.ConnectionInfo{
 						Addr:     "127.0.0.1:1234",
 						Username: "fake-username",
@@ -428,7 +428,7 @@ func init() {
 						clientCert, err := tls.LoadX509KeyPair("./test_assets/client-cert.pem", "./test_assets/client-pkey.pem")
 						Expect(err, BeNil())
 
-						result := client.ConnectedConnectionProvider().(*yagnats.ConnectionInfo)
+						result := connectedConnectionInfo(client)
 						expected := &yagnats.ConnectionInfo{
 							Addr:     "127.0.0.1:1234",
 							Username: "fake-username",
@@ -543,6 +543,11 @@ func init() {
 	})
 }
 
+func connectedConnectionInfo(client *fakeyagnats.FakeYagnats) *yagnats.ConnectionInfo {
+	backoffConnProvider := client.ConnectedConnectionProvider().(*BackoffConnectionProvider)
+	return backoffConnProvider.ConnectionProvider.(*yagnats.ConnectionInfo)
+}
+
 func testVerifyPeerCertificateCallback(client *fakeyagnats.FakeYagnats, handler boshhandler.Handler, certPath string, caPath string) error {
 	ValidCA, _ := ioutil.ReadFile("./test_assets/ca.pem")
 
@@ -564,7 +569,7 @@ func testVerifyPeerCertificateCallback(client *fakeyagnats.FakeYagnats, handler
 	ok := certPool.AppendCertsFromPEM(ValidCA)
 	Expect(ok).To(BeTrue())
 
-	result := client.ConnectedConnectionProvider().(*yagnats.ConnectionInfo)
+	result := connectedConnectionInfo(client)
 	callback := result.TLSInfo.VerifyPeerCertificate
 
 	raw := [][]byte{correctCnCert, correctCa}
@@ -0,0 +1,239 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"sort"
+	"strconv"
+
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const handlerLogTag = "metricsHandler"
+
+var loadPeriods = []string{"1m", "5m", "15m"}
+
+type handler struct {
+	vitalsService boshvitals.Service
+	jobSupervisor boshjobsuper.JobSupervisor
+	logger        boshlog.Logger
+}
+
+// NewHandler serves VM vitals and job processes stats in OpenMetrics format
+func NewHandler(
+	vitalsService boshvitals.Service,
+	jobSupervisor boshjobsuper.JobSupervisor,
+	logger boshlog.Logger,
+) http.Handler {
+	return handler{
+		vitalsService: vitalsService,
+		jobSupervisor: jobSupervisor,
+		logger:        logger,
+	}
+}
+
+func (h handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	families, err := h.collect()
+	if err != nil {
+		h.logger.Error(handlerLogTag, err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	var buf bytes.Buffer
+
+	// Families are rendered fully before responding so that
+	// a failure does not result in a partially written response
+	err = WriteOpenMetrics(&buf, families)
+	if err != nil {
+		h.logger.Error(handlerLogTag, "Rendering metrics: %s", err.Error())
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", OpenMetricsContentType)
+
+	_, err = w.Write(buf.Bytes())
+	if err != nil {
+		h.logger.Error(handlerLogTag, "Writing response: %s", err.Error())
+	}
+}
+
+func (h handler) collect() ([]*Family, error) {
+	vitals, err := h.vitalsService.Get()
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Getting vitals")
+	}
+
+	// Processes are not available e.g. before the first apply
+	processes, err := h.jobSupervisor.Processes()
+	if err != nil {
+		h.logger.Warn(handlerLogTag, "Getting processes: %s", err.Error())
+		processes = nil
+	}
+
+	return append(vitalsFamilies(vitals), processFamilies(processes)...), nil
+}
+
+func vitalsFamilies(vitals boshvitals.Vitals) []*Family {
+	cpu := NewGauge("bosh_agent_cpu_percent", "CPU usage by mode")
+	addParsed(cpu, Labels{"mode": "user"}, vitals.CPU.User)
+	addParsed(cpu, Labels{"mode": "sys"}, vitals.CPU.Sys)
+	addParsed(cpu, Labels{"mode": "wait"}, vitals.CPU.Wait)
+
+	load := NewGauge("bosh_agent_load_average", "System load average")
+	for i, value := range vitals.Load {
+		if i < len(loadPeriods) {
+			addParsed(load, Labels{"period": loadPeriods[i]}, value)
+		}
+	}
+
+	memUsed := NewGauge("bosh_agent_memory_used_bytes", "Used memory")
+	addParsedKb(memUsed, nil, vitals.Mem.Kb)
+
+	memPercent := NewGauge("bosh_agent_memory_used_percent", "Used memory in percent of total memory")
+	addParsed(memPercent, nil, vitals.Mem.Percent)
+
+	swapUsed := NewGauge("bosh_agent_swap_used_bytes", "Used swap")
+	addParsedKb(swapUsed, nil, vitals.Swap.Kb)
+
+	swapPercent := NewGauge("bosh_agent_swap_used_percent", "Used swap in percent of total swap")
+	addParsed(swapPercent, nil, vitals.Swap.Percent)
+
+	diskPercent := NewGauge("bosh_agent_disk_used_percent", "Used disk space in percent")
+	inodePercent := NewGauge("bosh_agent_disk_inodes_used_percent", "Used inodes in percent")
+
+	for _, name := range sortedDiskNames(vitals.Disk) {
+		disk := vitals.Disk[name]
+		addParsed(diskPercent, Labels{"disk": name}, disk.Percent)
+		addParsed(inodePercent, Labels{"disk": name}, disk.InodePercent)
+	}
+
+	uptime := NewGauge("bosh_agent_uptime_seconds", "System uptime")
+	uptime.Add(nil, float64(vitals.Uptime.Secs))
+
+	families := []*Family{cpu, load, memUsed, memPercent, swapUsed, swapPercent, diskPercent, inodePercent, uptime}
+	families = append(families, diskIOFamilies(vitals.DiskIO)...)
+	families = append(families, networkFamilies(vitals.Network)...)
+
+	return families
+}
+
+func diskIOFamilies(diskIO boshvitals.DiskIOVitals) []*Family {
+	readOps := NewGauge("bosh_agent_disk_io_read_ops_per_second", "Completed reads per second")
+	writeOps := NewGauge("bosh_agent_disk_io_write_ops_per_second", "Completed writes per second")
+	readBytes := NewGauge("bosh_agent_disk_io_read_bytes_per_second", "Bytes read per second")
+	writeBytes := NewGauge("bosh_agent_disk_io_write_bytes_per_second", "Bytes written per second")
+	busyPercent := NewGauge("bosh_agent_disk_io_busy_percent", "Time spent doing I/O in percent")
+
+	for _, name := range sortedDiskIONames(diskIO) {
+		device := diskIO[name]
+		labels := Labels{"device": name}
+
+		addParsed(readOps, labels, device.ReadOpsPerSec)
+		addParsed(writeOps, labels, device.WriteOpsPerSec)
+		addParsed(readBytes, labels, device.ReadBytesPerSec)
+		addParsed(writeBytes, labels, device.WriteBytesPerSec)
+		addParsed(busyPercent, labels, device.BusyPercent)
+	}
+
+	return []*Family{readOps, writeOps, readBytes, writeBytes, busyPercent}
+}
+
+func networkFamilies(network boshvitals.NetworkVitals) []*Family {
+	rxBytes := NewGauge("bosh_agent_network_receive_bytes_per_second", "Bytes received per second")
+	rxPackets := NewGauge("bosh_agent_network_receive_packets_per_second", "Packets received per second")
+	rxErrors := NewCounter("bosh_agent_network_receive_errors", "Receive errors since interface came up")
+	rxDropped := NewCounter("bosh_agent_network_receive_dropped", "Dropped received packets since interface came up")
+	txBytes := NewGauge("bosh_agent_network_transmit_bytes_per_second", "Bytes transmitted per second")
+	txPackets := NewGauge("bosh_agent_network_transmit_packets_per_second", "Packets transmitted per second")
+	txErrors := NewCounter("bosh_agent_network_transmit_errors", "Transmit errors since interface came up")
+	txDropped := NewCounter("bosh_agent_network_transmit_dropped", "Dropped transmitted packets since interface came up")
+
+	for _, name := range sortedNetworkNames(network) {
+		iface := network[name]
+		labels := Labels{"interface": name}
+
+		addParsed(rxBytes, labels, iface.RxBytesPerSec)
+		addParsed(rxPackets, labels, iface.RxPacketsPerSec)
+		addParsed(rxErrors, labels, iface.RxErrors)
+		addParsed(rxDropped, labels, iface.RxDropped)
+		addParsed(txBytes, labels, iface.TxBytesPerSec)
+		addParsed(txPackets, labels, iface.TxPacketsPerSec)
+		addParsed(txErrors, labels, iface.TxErrors)
+		addParsed(txDropped, labels, iface.TxDropped)
+	}
+
+	return []*Family{rxBytes, rxPackets, rxErrors, rxDropped, txBytes, txPackets, txErrors, txDropped}
+}
+
+func processFamilies(processes []boshjobsuper.Process) []*Family {
+	state := NewGauge("bosh_agent_process_state", "Process state as reported by the job supervisor")
+	uptime := NewGauge("bosh_agent_process_uptime_seconds", "Process uptime")
+	memUsed := NewGauge("bosh_agent_process_memory_used_bytes", "Memory used by process")
+	memPercent := NewGauge("bosh_agent_process_memory_used_percent", "Memory used by process in percent of total memory")
+	cpu := NewGauge("bosh_agent_process_cpu_percent", "CPU used by process")
+
+	for _, process := range processes {
+		labels := Labels{"process": process.Name}
+
+		state.Add(Labels{"process": process.Name, "state": process.State}, 1)
+		uptime.Add(labels, float64(process.Uptime.Secs))
+		memUsed.Add(labels, float64(process.Memory.Kb)*1024)
+		memPercent.Add(labels, process.Memory.Percent)
+		cpu.Add(labels, process.CPU.Total)
+	}
+
+	return []*Family{state, uptime, memUsed, memPercent, cpu}
+}
+
+// Vitals are formatted for heartbeats so values
+// that are missing or not numbers are skipped
+func addParsed(family *Family, labels Labels, value string) {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		family.Add(labels, parsed)
+	}
+}
+
+func addParsedKb(family *Family, labels Labels, value string) {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err == nil {
+		family.Add(labels, parsed*1024)
+	}
+}
+
+func sortedDiskNames(disks boshvitals.DiskVitals) []string {
+	names := make([]string, 0, len(disks))
+	for name := range disks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedDiskIONames(diskIO boshvitals.DiskIOVitals) []string {
+	names := make([]string, 0, len(diskIO))
+	for name := range diskIO {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sortedNetworkNames(network boshvitals.NetworkVitals) []string {
+	names := make([]string, 0, len(network))
+	for name := range network {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,147 @@
+package metrics_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boshjobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor"
+	fakejobsuper "github.com/cloudfoundry/bosh-agent/jobsupervisor/fakes"
+	. "github.com/cloudfoundry/bosh-agent/metrics"
+	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	fakevitals "github.com/cloudfoundry/bosh-agent/platform/vitals/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("Handler", func() {
+	var (
+		vitalsService *fakevitals.FakeService
+		jobSupervisor *fakejobsuper.FakeJobSupervisor
+		handler       http.Handler
+		recorder      *httptest.ResponseRecorder
+	)
+
+	BeforeEach(func() {
+		vitalsService = fakevitals.NewFakeService()
+		jobSupervisor = fakejobsuper.NewFakeJobSupervisor()
+		logger := boshlog.NewLogger(boshlog.LevelNone)
+		handler = NewHandler(vitalsService, jobSupervisor, logger)
+		recorder = httptest.NewRecorder()
+
+		vitalsService.GetVitals = boshvitals.Vitals{
+			CPU:  boshvitals.CPUVitals{User: "1.5", Sys: "2.5", Wait: "0.5"},
+			Load: []string{"0.10", "0.20", "0.30"},
+			Mem:  boshvitals.MemoryVitals{Kb: "1024", Percent: "40"},
+			Swap: boshvitals.MemoryVitals{Kb: "2", Percent: "1"},
+			Disk: boshvitals.DiskVitals{
+				"system":    boshvitals.SpecificDiskVitals{Percent: "50", InodePercent: "10"},
+				"ephemeral": boshvitals.SpecificDiskVitals{Percent: "60", InodePercent: "20"},
+			},
+			DiskIO: boshvitals.DiskIOVitals{
+				"sda": boshvitals.SpecificDiskIOVitals{
+					ReadOpsPerSec:    "12.5",
+					WriteOpsPerSec:   "3.0",
+					ReadBytesPerSec:  "4096",
+					WriteBytesPerSec: "1024",
+					BusyPercent:      "42.5",
+				},
+			},
+			Network: boshvitals.NetworkVitals{
+				"eth0": boshvitals.SpecificNetworkVitals{
+					RxBytesPerSec:   "2048",
+					RxPacketsPerSec: "10.0",
+					RxErrors:        "1",
+					RxDropped:       "2",
+					TxBytesPerSec:   "512",
+					TxPacketsPerSec: "5.5",
+					TxErrors:        "3",
+					TxDropped:       "4",
+				},
+			},
+			Uptime: boshvitals.UptimeVitals{Secs: 3600},
+		}
+
+		jobSupervisor.ProcessesStatus = []boshjobsuper.Process{
+			{
+				Name:   "fake-process",
+				State:  "running",
+				Uptime: boshjobsuper.UptimeVitals{Secs: 120},
+				Memory: boshjobsuper.MemoryVitals{Kb: 10, Percent: 0.5},
+				CPU:    boshjobsuper.CPUVitals{Total: 3.5},
+			},
+		}
+	})
+
+	It("serves vitals and processes in OpenMetrics format", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Header().Get("Content-Type")).To(Equal("application/openmetrics-text; version=1.0.0; charset=utf-8"))
+
+		body := recorder.Body.String()
+		Expect(body).To(ContainSubstring(`bosh_agent_cpu_percent{mode="user"} 1.5` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_cpu_percent{mode="sys"} 2.5` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_cpu_percent{mode="wait"} 0.5` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_load_average{period="15m"} 0.3` + "\n"))
+		Expect(body).To(ContainSubstring("bosh_agent_memory_used_bytes 1.048576e+06\n"))
+		Expect(body).To(ContainSubstring("bosh_agent_memory_used_percent 40\n"))
+		Expect(body).To(ContainSubstring("bosh_agent_swap_used_bytes 2048\n"))
+		Expect(body).To(ContainSubstring("bosh_agent_swap_used_percent 1\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_disk_used_percent{disk="ephemeral"} 60` + "\n" + `bosh_agent_disk_used_percent{disk="system"} 50` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_disk_inodes_used_percent{disk="system"} 10` + "\n"))
+		Expect(body).To(ContainSubstring("bosh_agent_uptime_seconds 3600\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_disk_io_read_ops_per_second{device="sda"} 12.5` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_disk_io_write_bytes_per_second{device="sda"} 1024` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_disk_io_busy_percent{device="sda"} 42.5` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_network_receive_bytes_per_second{interface="eth0"} 2048` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_network_receive_dropped_total{interface="eth0"} 2` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_network_transmit_packets_per_second{interface="eth0"} 5.5` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_network_transmit_errors_total{interface="eth0"} 3` + "\n"))
+		Expect(body).To(ContainSubstring("# TYPE bosh_agent_network_transmit_errors counter\n"))
+
+		Expect(body).To(ContainSubstring(`bosh_agent_process_state{process="fake-process",state="running"} 1` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_process_uptime_seconds{process="fake-process"} 120` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_process_memory_used_bytes{process="fake-process"} 10240` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_process_memory_used_percent{process="fake-process"} 0.5` + "\n"))
+		Expect(body).To(ContainSubstring(`bosh_agent_process_cpu_percent{process="fake-process"} 3.5` + "\n"))
+
+		Expect(body).To(HaveSuffix("# EOF\n"))
+	})
+
+	It("skips vitals that are not available", func() {
+		vitalsService.GetVitals = boshvitals.Vitals{}
+
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).ToNot(ContainSubstring("bosh_agent_cpu_percent"))
+		Expect(recorder.Body.String()).To(ContainSubstring("bosh_agent_uptime_seconds 0\n"))
+	})
+
+	It("serves vitals without process metrics when processes cannot be retrieved", func() {
+		jobSupervisor.ProcessesError = errors.New("fake-processes-error")
+
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		Expect(recorder.Body.String()).To(ContainSubstring("bosh_agent_uptime_seconds 3600\n"))
+		Expect(recorder.Body.String()).ToNot(ContainSubstring("bosh_agent_process_"))
+	})
+
+	It("responds with internal server error when vitals cannot be retrieved", func() {
+		vitalsService.GetErr = errors.New("fake-vitals-error")
+
+		handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+	})
+
+	It("does not allow methods other than GET", func() {
+		handler.ServeHTTP(recorder, httptest.NewRequest("POST", "/metrics", nil))
+
+		Expect(recorder.Code).To(Equal(http.StatusMethodNotAllowed))
+	})
+})
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+type Labels map[string]string
+
+type Sample struct {
+	Labels Labels
+	Value  float64
+}
+
+// Family is a set of samples sharing metric name, type and help text
+type Family struct {
+	Name    string
+	Type    string
+	Help    string
+	Samples []Sample
+}
+
+func NewGauge(name, help string) *Family {
+	return &Family{Name: name, Type: "gauge", Help: help}
+}
+
+// NewCounter is for monotonic values; samples get _total suffix
+// while family name is used without it as OpenMetrics requires
+func NewCounter(name, help string) *Family {
+	return &Family{Name: name, Type: "counter", Help: help}
+}
+
+func (f *Family) Add(labels Labels, value float64) {
+	f.Samples = append(f.Samples, Sample{Labels: labels, Value: value})
+}
+
+// WriteOpenMetrics writes families in OpenMetrics text exposition format
+// skipping families without samples
+func WriteOpenMetrics(w io.Writer, families []*Family) error {
+	for _, family := range families {
+		if len(family.Samples) == 0 {
+			continue
+		}
+
+		_, err := fmt.Fprintf(w, "# TYPE %s %s\n# HELP %s %s\n", family.Name, family.Type, family.Name, escapeHelp(family.Help))
+		if err != nil {
+			return err
+		}
+
+		sampleName := family.Name
+		if family.Type == "counter" {
+			sampleName += "_total"
+		}
+
+		for _, sample := range family.Samples {
+			_, err = fmt.Fprintf(w, "%s%s %s\n", sampleName, formatLabels(sample.Labels), strconv.FormatFloat(sample.Value, 'g', -1, 64))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	_, err := io.WriteString(w, "# EOF\n")
+	return err
+}
+
+func formatLabels(labels Labels) string {
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, name, escapeLabelValue(labels[name])))
+	}
+
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeLabelValue(value string) string {
+	return labelValueReplacer.Replace(value)
+}
+
+var helpReplacer = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(help string) string {
+	return helpReplacer.Replace(help)
+}
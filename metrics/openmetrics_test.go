@@ -0,0 +1,64 @@
+package metrics_test
+
+import (
+	"bytes"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/metrics"
+)
+
+var _ = Describe("WriteOpenMetrics", func() {
+	It("writes families with sorted labels and EOF marker", func() {
+		gauge := NewGauge("fake_metric", "Fake help")
+		gauge.Add(Labels{"b": "2", "a": "1"}, 1.5)
+		gauge.Add(nil, 3)
+
+		var buf bytes.Buffer
+		err := WriteOpenMetrics(&buf, []*Family{gauge})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buf.String()).To(Equal(
+			"# TYPE fake_metric gauge\n" +
+				"# HELP fake_metric Fake help\n" +
+				"fake_metric{a=\"1\",b=\"2\"} 1.5\n" +
+				"fake_metric 3\n" +
+				"# EOF\n",
+		))
+	})
+
+	It("writes counter samples with _total suffix", func() {
+		counter := NewCounter("fake_errors", "Fake help")
+		counter.Add(Labels{"a": "1"}, 4)
+
+		var buf bytes.Buffer
+		err := WriteOpenMetrics(&buf, []*Family{counter})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buf.String()).To(Equal(
+			"# TYPE fake_errors counter\n" +
+				"# HELP fake_errors Fake help\n" +
+				"fake_errors_total{a=\"1\"} 4\n" +
+				"# EOF\n",
+		))
+	})
+
+	It("escapes label values", func() {
+		gauge := NewGauge("fake_metric", "Fake help")
+		gauge.Add(Labels{"name": "a\"b\\c\nd"}, 1)
+
+		var buf bytes.Buffer
+		err := WriteOpenMetrics(&buf, []*Family{gauge})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(buf.String()).To(ContainSubstring(`fake_metric{name="a\"b\\c\nd"} 1` + "\n"))
+	})
+
+	It("skips families without samples", func() {
+		var buf bytes.Buffer
+		err := WriteOpenMetrics(&buf, []*Family{NewGauge("fake_metric", "Fake help")})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(buf.String()).To(Equal("# EOF\n"))
+	})
+})
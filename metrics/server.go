@@ -0,0 +1,157 @@
+package metrics
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	serverLogTag = "metricsServer"
+
+	MetricsPath = "/metrics"
+)
+
+type Options struct {
+	// Address to listen on, e.g. "127.0.0.1:9100";
+	// metrics endpoint is disabled when empty
+	Address string
+
+	TLS TLSOptions
+}
+
+type TLSOptions struct {
+	// Serve over HTTPS when both are set
+	CertPath string
+	KeyPath  string
+
+	// Require scrapers to present client certificate signed by this CA
+	CACertPath string
+}
+
+func (o Options) Enabled() bool {
+	return o.Address != ""
+}
+
+type Server interface {
+	// Start returns once server is listening
+	Start() error
+	Stop()
+}
+
+type server struct {
+	opts       Options
+	handler    http.Handler
+	fs         boshsys.FileSystem
+	logger     boshlog.Logger
+	httpServer *http.Server
+}
+
+func NewServer(opts Options, handler http.Handler, fs boshsys.FileSystem, logger boshlog.Logger) Server {
+	return &server{
+		opts:    opts,
+		handler: handler,
+		fs:      fs,
+		logger:  logger,
+	}
+}
+
+func (s *server) Start() error {
+	tlsConfig, err := s.tlsConfig()
+	if err != nil {
+		return bosherr.WrapError(err, "Building TLS config")
+	}
+
+	listener, err := net.Listen("tcp", s.opts.Address)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Listening on %s", s.opts.Address)
+	}
+
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(MetricsPath, s.handler)
+
+	s.httpServer = &http.Server{
+		Handler:      mux,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+
+	s.logger.Info(serverLogTag, "Serving metrics on %s", listener.Addr().String())
+
+	go func() {
+		err := s.httpServer.Serve(listener)
+		if err != nil && err != http.ErrServerClosed {
+			s.logger.Error(serverLogTag, "Serving metrics: %s", err.Error())
+		}
+	}()
+
+	return nil
+}
+
+func (s *server) Stop() {
+	if s.httpServer != nil {
+		_ = s.httpServer.Close()
+	}
+}
+
+func (s *server) tlsConfig() (*tls.Config, error) {
+	tlsOpts := s.opts.TLS
+
+	if tlsOpts.CertPath == "" && tlsOpts.KeyPath == "" {
+		if tlsOpts.CACertPath != "" {
+			return nil, bosherr.Error("Client CA requires server certificate and key")
+		}
+		return nil, nil
+	}
+
+	if tlsOpts.CertPath == "" || tlsOpts.KeyPath == "" {
+		return nil, bosherr.Error("Both certificate and key paths must be specified")
+	}
+
+	certPEM, err := s.fs.ReadFile(tlsOpts.CertPath)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading certificate")
+	}
+
+	keyPEM, err := s.fs.ReadFile(tlsOpts.KeyPath)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Reading key")
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, bosherr.WrapError(err, "Parsing certificate and key")
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if tlsOpts.CACertPath != "" {
+		caPEM, err := s.fs.ReadFile(tlsOpts.CACertPath)
+		if err != nil {
+			return nil, bosherr.WrapError(err, "Reading client CA certificate")
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caPEM) {
+			return nil, bosherr.Error("Parsing client CA certificate")
+		}
+
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	return config, nil
+}
@@ -0,0 +1,238 @@
+package metrics_test
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/metrics"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *rsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func generateCert(commonName string, parent *testCert, isCA bool) testCert {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	Expect(err).ToNot(HaveOccurred())
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  isCA,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	Expect(err).ToNot(HaveOccurred())
+
+	cert, err := x509.ParseCertificate(der)
+	Expect(err).ToNot(HaveOccurred())
+
+	return testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}),
+	}
+}
+
+func freeAddress() string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	Expect(err).ToNot(HaveOccurred())
+	defer listener.Close()
+	return listener.Addr().String()
+}
+
+var _ = Describe("Server", func() {
+	var (
+		fs      *fakesys.FakeFileSystem
+		logger  boshlog.Logger
+		handler http.Handler
+		opts    Options
+		server  Server
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, "fake-metrics")
+		})
+		opts = Options{Address: freeAddress()}
+		server = nil
+	})
+
+	AfterEach(func() {
+		if server != nil {
+			server.Stop()
+		}
+	})
+
+	Describe("Enabled", func() {
+		It("is enabled only when address is configured", func() {
+			Expect(Options{}.Enabled()).To(BeFalse())
+			Expect(Options{Address: "127.0.0.1:9100"}.Enabled()).To(BeTrue())
+		})
+	})
+
+	Context("without TLS", func() {
+		It("serves metrics over HTTP", func() {
+			server = NewServer(opts, handler, fs, logger)
+			Expect(server.Start()).To(Succeed())
+
+			resp, err := http.Get("http://" + opts.Address + "/metrics")
+			Expect(err).ToNot(HaveOccurred())
+			defer resp.Body.Close()
+
+			body, err := ioutil.ReadAll(resp.Body)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(string(body)).To(Equal("fake-metrics"))
+		})
+
+		It("does not serve other paths", func() {
+			server = NewServer(opts, handler, fs, logger)
+			Expect(server.Start()).To(Succeed())
+
+			resp, err := http.Get("http://" + opts.Address + "/other")
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusNotFound))
+		})
+
+		It("returns error when address is already in use", func() {
+			listener, err := net.Listen("tcp", opts.Address)
+			Expect(err).ToNot(HaveOccurred())
+			defer listener.Close()
+
+			server = NewServer(opts, handler, fs, logger)
+			err = server.Start()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Listening on " + opts.Address))
+		})
+	})
+
+	Context("with TLS", func() {
+		var (
+			ca         testCert
+			serverCert testCert
+			caPool     *x509.CertPool
+		)
+
+		BeforeEach(func() {
+			ca = generateCert("fake-ca", nil, true)
+			serverCert = generateCert("127.0.0.1", &ca, false)
+
+			caPool = x509.NewCertPool()
+			caPool.AddCert(ca.cert)
+
+			fs.WriteFile("/fake-cert", serverCert.certPEM)
+			fs.WriteFile("/fake-key", serverCert.keyPEM)
+			fs.WriteFile("/fake-ca", ca.certPEM)
+
+			opts.TLS = TLSOptions{CertPath: "/fake-cert", KeyPath: "/fake-key"}
+		})
+
+		It("serves metrics over HTTPS", func() {
+			server = NewServer(opts, handler, fs, logger)
+			Expect(server.Start()).To(Succeed())
+
+			client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: caPool}}}
+
+			resp, err := client.Get("https://" + opts.Address + "/metrics")
+			Expect(err).ToNot(HaveOccurred())
+			resp.Body.Close()
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		})
+
+		Context("when client CA is configured", func() {
+			BeforeEach(func() {
+				opts.TLS.CACertPath = "/fake-ca"
+			})
+
+			It("accepts clients with certificate signed by the CA", func() {
+				server = NewServer(opts, handler, fs, logger)
+				Expect(server.Start()).To(Succeed())
+
+				clientCert := generateCert("fake-client", &ca, false)
+				keyPair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+				Expect(err).ToNot(HaveOccurred())
+
+				client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+					RootCAs:      caPool,
+					Certificates: []tls.Certificate{keyPair},
+				}}}
+
+				resp, err := client.Get("https://" + opts.Address + "/metrics")
+				Expect(err).ToNot(HaveOccurred())
+				resp.Body.Close()
+				Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			})
+
+			It("rejects clients without certificate", func() {
+				server = NewServer(opts, handler, fs, logger)
+				Expect(server.Start()).To(Succeed())
+
+				client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: caPool}}}
+
+				_, err := client.Get("https://" + opts.Address + "/metrics")
+				Expect(err).To(HaveOccurred())
+			})
+
+			It("returns error when client CA cannot be parsed", func() {
+				fs.WriteFileString("/fake-ca", "fake-invalid-ca")
+
+				server = NewServer(opts, handler, fs, logger)
+				err := server.Start()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Parsing client CA certificate"))
+			})
+		})
+
+		It("returns error when only certificate path is configured", func() {
+			opts.TLS.KeyPath = ""
+
+			server = NewServer(opts, handler, fs, logger)
+			err := server.Start()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Both certificate and key paths must be specified"))
+		})
+
+		It("returns error when certificate cannot be read", func() {
+			fs.ReadFileError = errors.New("fake-read-error")
+
+			server = NewServer(opts, handler, fs, logger)
+			err := server.Start()
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-read-error"))
+		})
+	})
+})
@@ -0,0 +1,14 @@
+package disk
+
+type Encryptor interface {
+	// Format initializes encryption on a blank device;
+	// already encrypted devices are left untouched
+	Format(devicePath, key string) (err error)
+
+	// Open unlocks device and returns path to the decrypted device
+	Open(devicePath, name, key string) (decryptedPath string, err error)
+	Close(name string) (err error)
+
+	// Resize extends opened device to the size of underlying device
+	Resize(name, key string) (err error)
+}
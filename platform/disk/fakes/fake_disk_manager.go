@@ -10,10 +10,17 @@ type FakeDiskManager struct {
 	FakePartitioner           *FakePartitioner
 	FakePartedPartitioner     *FakePartitioner
 	FakeFormatter             *FakeFormatter
+	FakeFilesystemChecker     *FakeFilesystemChecker
+	FakeEncryptor             *FakeEncryptor
 	FakeMounter               *FakeMounter
 	FakeMountsSearcher        *FakeMountsSearcher
+	FakeTreeCopier            *FakeTreeCopier
+	FakeTrimmer               *FakeTrimmer
+	FakeSwapper               *FakeSwapper
+	FakeLogicalVolumeManager  *FakeLogicalVolumeManager
 	FakeRootDevicePartitioner *FakePartitioner
 	FakeDiskUtil              *fakedevutil.FakeDeviceUtil
+	FakeDiskUtils             map[string]*fakedevutil.FakeDeviceUtil
 	DiskUtilDiskPath          string
 	PartedPartitionerCalled   bool
 	PartitionerCalled         bool
@@ -24,8 +31,14 @@ func NewFakeDiskManager() *FakeDiskManager {
 		FakePartitioner:           NewFakePartitioner(),
 		FakePartedPartitioner:     NewFakePartitioner(),
 		FakeFormatter:             &FakeFormatter{},
+		FakeFilesystemChecker:     &FakeFilesystemChecker{},
+		FakeEncryptor:             &FakeEncryptor{},
 		FakeMounter:               &FakeMounter{},
 		FakeMountsSearcher:        &FakeMountsSearcher{},
+		FakeTreeCopier:            &FakeTreeCopier{},
+		FakeTrimmer:               &FakeTrimmer{},
+		FakeSwapper:               &FakeSwapper{},
+		FakeLogicalVolumeManager:  &FakeLogicalVolumeManager{},
 		FakeRootDevicePartitioner: NewFakePartitioner(),
 		FakeDiskUtil:              fakedevutil.NewFakeDeviceUtil(),
 		PartedPartitionerCalled:   false,
@@ -51,6 +64,14 @@ func (m *FakeDiskManager) GetFormatter() boshdisk.Formatter {
 	return m.FakeFormatter
 }
 
+func (m *FakeDiskManager) GetFilesystemChecker() boshdisk.FilesystemChecker {
+	return m.FakeFilesystemChecker
+}
+
+func (m *FakeDiskManager) GetEncryptor() boshdisk.Encryptor {
+	return m.FakeEncryptor
+}
+
 func (m *FakeDiskManager) GetMounter() boshdisk.Mounter {
 	return m.FakeMounter
 }
@@ -59,7 +80,26 @@ func (m *FakeDiskManager) GetMountsSearcher() boshdisk.MountsSearcher {
 	return m.FakeMountsSearcher
 }
 
+func (m *FakeDiskManager) GetTreeCopier() boshdisk.TreeCopier {
+	return m.FakeTreeCopier
+}
+
+func (m *FakeDiskManager) GetTrimmer() boshdisk.Trimmer {
+	return m.FakeTrimmer
+}
+
+func (m *FakeDiskManager) GetSwapper() boshdisk.Swapper {
+	return m.FakeSwapper
+}
+
+func (m *FakeDiskManager) GetLogicalVolumeManager() boshdisk.LogicalVolumeManager {
+	return m.FakeLogicalVolumeManager
+}
+
 func (m *FakeDiskManager) GetDiskUtil(diskPath string) boshdevutil.DeviceUtil {
 	m.DiskUtilDiskPath = diskPath
+	if diskUtil, found := m.FakeDiskUtils[diskPath]; found {
+		return diskUtil
+	}
 	return m.FakeDiskUtil
 }
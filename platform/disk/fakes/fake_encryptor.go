@@ -0,0 +1,46 @@
+package fakes
+
+type FakeEncryptor struct {
+	FormatDevicePaths []string
+	FormatKeys        []string
+	FormatErr         error
+
+	OpenDevicePaths []string
+	OpenNames       []string
+	OpenKeys        []string
+	OpenErr         error
+
+	CloseNames []string
+	CloseErr   error
+
+	ResizeNames []string
+	ResizeKeys  []string
+	ResizeErr   error
+}
+
+func (e *FakeEncryptor) Format(devicePath, key string) error {
+	e.FormatDevicePaths = append(e.FormatDevicePaths, devicePath)
+	e.FormatKeys = append(e.FormatKeys, key)
+	return e.FormatErr
+}
+
+func (e *FakeEncryptor) Open(devicePath, name, key string) (string, error) {
+	e.OpenDevicePaths = append(e.OpenDevicePaths, devicePath)
+	e.OpenNames = append(e.OpenNames, name)
+	e.OpenKeys = append(e.OpenKeys, key)
+	if e.OpenErr != nil {
+		return "", e.OpenErr
+	}
+	return "/dev/mapper/" + name, nil
+}
+
+func (e *FakeEncryptor) Close(name string) error {
+	e.CloseNames = append(e.CloseNames, name)
+	return e.CloseErr
+}
+
+func (e *FakeEncryptor) Resize(name, key string) error {
+	e.ResizeNames = append(e.ResizeNames, name)
+	e.ResizeKeys = append(e.ResizeKeys, key)
+	return e.ResizeErr
+}
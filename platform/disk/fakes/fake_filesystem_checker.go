@@ -0,0 +1,18 @@
+package fakes
+
+import (
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+)
+
+type FakeFilesystemChecker struct {
+	CheckPartitionPaths []string
+	CheckModes          []boshdisk.FilesystemCheckMode
+	CheckResult         boshdisk.FilesystemCheckResult
+	CheckErr            error
+}
+
+func (c *FakeFilesystemChecker) Check(partitionPath string, mode boshdisk.FilesystemCheckMode) (boshdisk.FilesystemCheckResult, error) {
+	c.CheckPartitionPaths = append(c.CheckPartitionPaths, partitionPath)
+	c.CheckModes = append(c.CheckModes, mode)
+	return c.CheckResult, c.CheckErr
+}
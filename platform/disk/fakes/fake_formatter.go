@@ -8,15 +8,34 @@ type FakeFormatter struct {
 	FormatCalled         bool
 	FormatPartitionPaths []string
 	FormatFsTypes        []boshdisk.FileSystemType
+	FormatSpecs          []boshdisk.FormatSpec
 	FormatError          error
+
+	FileSystemTypes   map[string]boshdisk.FileSystemType
+	FileSystemTypeErr error
+
+	GrowFilesystemPartitionPath string
+	GrowFilesystemMountPoint    string
+	GrowFilesystemErr           error
 }
 
-func (p *FakeFormatter) Format(partitionPath string, fsType boshdisk.FileSystemType) (err error) {
+func (p *FakeFormatter) Format(partitionPath string, spec boshdisk.FormatSpec) (err error) {
 	if p.FormatError != nil {
 		return p.FormatError
 	}
 	p.FormatCalled = true
 	p.FormatPartitionPaths = append(p.FormatPartitionPaths, partitionPath)
-	p.FormatFsTypes = append(p.FormatFsTypes, fsType)
+	p.FormatFsTypes = append(p.FormatFsTypes, spec.FileSystemType)
+	p.FormatSpecs = append(p.FormatSpecs, spec)
 	return
 }
+
+func (p *FakeFormatter) FileSystemType(partitionPath string) (boshdisk.FileSystemType, error) {
+	return p.FileSystemTypes[partitionPath], p.FileSystemTypeErr
+}
+
+func (p *FakeFormatter) GrowFilesystem(partitionPath, mountPoint string) error {
+	p.GrowFilesystemPartitionPath = partitionPath
+	p.GrowFilesystemMountPoint = mountPoint
+	return p.GrowFilesystemErr
+}
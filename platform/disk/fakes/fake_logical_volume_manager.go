@@ -0,0 +1,16 @@
+package fakes
+
+import (
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+)
+
+type FakeLogicalVolumeManager struct {
+	SetupSpecs      []boshdisk.LogicalVolumeSpec
+	SetupDevicePath string
+	SetupErr        error
+}
+
+func (m *FakeLogicalVolumeManager) Setup(spec boshdisk.LogicalVolumeSpec) (string, error) {
+	m.SetupSpecs = append(m.SetupSpecs, spec)
+	return m.SetupDevicePath, m.SetupErr
+}
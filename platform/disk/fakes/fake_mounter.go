@@ -31,14 +31,16 @@ type FakeMounter struct {
 	SwapOnPartitionPaths []string
 	SwapOnErr            error
 
-	UnmountPartitionPathOrMountPoint string
-	UnmountDidUnmount                bool
-	UnmountErr                       error
+	UnmountPartitionPathOrMountPoint   string
+	UnmountPartitionPathsOrMountPoints []string
+	UnmountDidUnmount                  bool
+	UnmountErr                         error
 
 	IsMountPointPath          string
 	IsMountPointPartitionPath string
 	IsMountPointResult        bool
 	IsMountPointErr           error
+	IsMountPointStub          func(string) (string, bool, error)
 
 	IsMountedResult bool
 	IsMountedErr    error
@@ -83,11 +85,15 @@ func (m *FakeMounter) SwapOn(partitionPath string) (err error) {
 
 func (m *FakeMounter) Unmount(partitionPathOrMountPoint string) (didUnmount bool, err error) {
 	m.UnmountPartitionPathOrMountPoint = partitionPathOrMountPoint
+	m.UnmountPartitionPathsOrMountPoints = append(m.UnmountPartitionPathsOrMountPoints, partitionPathOrMountPoint)
 	return m.UnmountDidUnmount, m.UnmountErr
 }
 
 func (m *FakeMounter) IsMountPoint(path string) (partitionPath string, result bool, err error) {
 	m.IsMountPointPath = path
+	if m.IsMountPointStub != nil {
+		return m.IsMountPointStub(path)
+	}
 	return m.IsMountPointPartitionPath, m.IsMountPointResult, m.IsMountPointErr
 }
 
@@ -13,6 +13,10 @@ type FakePartitioner struct {
 	GetDeviceSizeInBytesDevicePath string
 	GetDeviceSizeInBytesSizes      map[string]uint64
 	GetDeviceSizeInBytesErr        error
+
+	GrowPartitionDevicePath      string
+	GrowPartitionPartitionNumber int
+	GrowPartitionErr             error
 }
 
 func NewFakePartitioner() *FakePartitioner {
@@ -32,3 +36,9 @@ func (p *FakePartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, error
 	p.GetDeviceSizeInBytesDevicePath = devicePath
 	return p.GetDeviceSizeInBytesSizes[devicePath], p.GetDeviceSizeInBytesErr
 }
+
+func (p *FakePartitioner) GrowPartition(devicePath string, partitionNumber int) error {
+	p.GrowPartitionDevicePath = devicePath
+	p.GrowPartitionPartitionNumber = partitionNumber
+	return p.GrowPartitionErr
+}
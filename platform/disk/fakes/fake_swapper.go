@@ -0,0 +1,15 @@
+package fakes
+
+import (
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+)
+
+type FakeSwapper struct {
+	ReconcileSpecs []boshdisk.SwapSpec
+	ReconcileErr   error
+}
+
+func (s *FakeSwapper) Reconcile(spec boshdisk.SwapSpec) error {
+	s.ReconcileSpecs = append(s.ReconcileSpecs, spec)
+	return s.ReconcileErr
+}
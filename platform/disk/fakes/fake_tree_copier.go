@@ -0,0 +1,34 @@
+package fakes
+
+import (
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+)
+
+type FakeTreeCopier struct {
+	CopyFromDir string
+	CopyToDir   string
+	CopyOptions boshdisk.TreeCopyOptions
+	CopyErr     error
+	CopyStub    func()
+
+	// Reported to progress callback as copied and total bytes
+	CopyProgress [][2]uint64
+}
+
+func (c *FakeTreeCopier) Copy(fromDir, toDir string, options boshdisk.TreeCopyOptions) error {
+	c.CopyFromDir = fromDir
+	c.CopyToDir = toDir
+	c.CopyOptions = options
+
+	if c.CopyStub != nil {
+		c.CopyStub()
+	}
+
+	if options.Progress != nil {
+		for _, progress := range c.CopyProgress {
+			options.Progress(progress[0], progress[1])
+		}
+	}
+
+	return c.CopyErr
+}
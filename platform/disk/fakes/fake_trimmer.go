@@ -0,0 +1,12 @@
+package fakes
+
+type FakeTrimmer struct {
+	TrimMountPoints  []string
+	TrimTrimmedBytes map[string]uint64
+	TrimErrs         map[string]error
+}
+
+func (t *FakeTrimmer) Trim(mountPoint string) (uint64, error) {
+	t.TrimMountPoints = append(t.TrimMountPoints, mountPoint)
+	return t.TrimTrimmedBytes[mountPoint], t.TrimErrs[mountPoint]
+}
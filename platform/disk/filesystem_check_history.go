@@ -0,0 +1,104 @@
+package disk
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const filesystemChecksFileName = "filesystem_checks.json"
+
+// FilesystemCheck is the outcome of the last check of persistent disk filesystem
+type FilesystemCheck struct {
+	DiskCID        string         `json:"disk_cid"`
+	CheckedAt      int64          `json:"checked_at"`
+	FileSystemType FileSystemType `json:"file_system_type"`
+	ErrorsFound    bool           `json:"errors_found"`
+	Repaired       bool           `json:"repaired"`
+
+	// Alerted is set once health monitor was told about found errors
+	Alerted bool `json:"alerted"`
+}
+
+// FilesystemCheckHistory keeps the last check of each persistent disk
+// so that it can be reported after the agent restarts
+type FilesystemCheckHistory struct {
+	fs   boshsys.FileSystem
+	path string
+}
+
+func NewFilesystemCheckHistory(fs boshsys.FileSystem, boshDir string) FilesystemCheckHistory {
+	return FilesystemCheckHistory{
+		fs:   fs,
+		path: filepath.Join(boshDir, filesystemChecksFileName),
+	}
+}
+
+func (h FilesystemCheckHistory) List() ([]FilesystemCheck, error) {
+	var checks []FilesystemCheck
+
+	if !h.fs.FileExists(h.path) {
+		return checks, nil
+	}
+
+	contents, err := h.fs.ReadFile(h.path)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading %s", filesystemChecksFileName)
+	}
+
+	err = json.Unmarshal(contents, &checks)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Unmarshalling %s", filesystemChecksFileName)
+	}
+
+	return checks, nil
+}
+
+// Record replaces previous check of the same disk
+func (h FilesystemCheckHistory) Record(check FilesystemCheck) error {
+	checks, err := h.List()
+	if err != nil {
+		return err
+	}
+
+	updatedChecks := []FilesystemCheck{}
+
+	for _, existingCheck := range checks {
+		if existingCheck.DiskCID != check.DiskCID {
+			updatedChecks = append(updatedChecks, existingCheck)
+		}
+	}
+
+	return h.save(append(updatedChecks, check))
+}
+
+func (h FilesystemCheckHistory) MarkAlerted(diskCID string) error {
+	checks, err := h.List()
+	if err != nil {
+		return err
+	}
+
+	for i := range checks {
+		if checks[i].DiskCID == diskCID {
+			checks[i].Alerted = true
+		}
+	}
+
+	return h.save(checks)
+}
+
+func (h FilesystemCheckHistory) save(checks []FilesystemCheck) error {
+	contents, err := json.Marshal(checks)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Marshalling %s", filesystemChecksFileName)
+	}
+
+	err = h.fs.WriteFile(h.path, contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing %s", filesystemChecksFileName)
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package disk_test
+
+import (
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("FilesystemCheckHistory", func() {
+	var (
+		fs      *fakesys.FakeFileSystem
+		history FilesystemCheckHistory
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		history = NewFilesystemCheckHistory(fs, "/fake-bosh")
+	})
+
+	It("returns no checks when nothing was recorded yet", func() {
+		checks, err := history.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checks).To(BeEmpty())
+	})
+
+	It("keeps only the last check of each disk", func() {
+		Expect(history.Record(FilesystemCheck{DiskCID: "fake-cid-1", CheckedAt: 1, ErrorsFound: true})).To(Succeed())
+		Expect(history.Record(FilesystemCheck{DiskCID: "fake-cid-2", CheckedAt: 2})).To(Succeed())
+		Expect(history.Record(FilesystemCheck{DiskCID: "fake-cid-1", CheckedAt: 3})).To(Succeed())
+
+		contents, err := fs.ReadFileString("/fake-bosh/filesystem_checks.json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contents).To(MatchJSON(`[
+			{"disk_cid":"fake-cid-2","checked_at":2,"file_system_type":"","errors_found":false,"repaired":false,"alerted":false},
+			{"disk_cid":"fake-cid-1","checked_at":3,"file_system_type":"","errors_found":false,"repaired":false,"alerted":false}
+		]`))
+	})
+
+	It("marks checks as alerted", func() {
+		Expect(history.Record(FilesystemCheck{DiskCID: "fake-cid-1", ErrorsFound: true})).To(Succeed())
+		Expect(history.Record(FilesystemCheck{DiskCID: "fake-cid-2", ErrorsFound: true})).To(Succeed())
+
+		Expect(history.MarkAlerted("fake-cid-2")).To(Succeed())
+
+		checks, err := history.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(checks).To(Equal([]FilesystemCheck{
+			{DiskCID: "fake-cid-1", ErrorsFound: true},
+			{DiskCID: "fake-cid-2", ErrorsFound: true, Alerted: true},
+		}))
+	})
+})
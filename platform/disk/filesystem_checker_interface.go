@@ -0,0 +1,27 @@
+package disk
+
+type FilesystemCheckMode string
+
+const (
+	// FilesystemCheckModeCheck only reports problems found on filesystem
+	FilesystemCheckModeCheck FilesystemCheckMode = "check"
+
+	// FilesystemCheckModeRepair fixes problems found on filesystem
+	FilesystemCheckModeRepair FilesystemCheckMode = "repair"
+)
+
+type FilesystemCheckResult struct {
+	FileSystemType FileSystemType
+
+	// ErrorsFound is set when filesystem was not clean
+	ErrorsFound bool
+
+	// Repaired is set when all found errors were fixed
+	Repaired bool
+}
+
+type FilesystemChecker interface {
+	// Check checks unmounted filesystem on a partition;
+	// partitions without supported filesystem are not checked
+	Check(partitionPath string, mode FilesystemCheckMode) (FilesystemCheckResult, error)
+}
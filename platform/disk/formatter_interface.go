@@ -4,11 +4,30 @@ type FileSystemType string
 
 const (
 	FileSystemSwap    FileSystemType = "swap"
+	FileSystemExt3    FileSystemType = "ext3"
 	FileSystemExt4    FileSystemType = "ext4"
 	FileSystemXFS     FileSystemType = "xfs"
+	FileSystemBtrfs   FileSystemType = "btrfs"
 	FileSystemDefault FileSystemType = ""
 )
 
+// FormatSpec describes filesystem created on a partition
+type FormatSpec struct {
+	FileSystemType FileSystemType
+
+	// Passed to mkfs as is, e.g. ["-i", "4096"] to get more inodes on ext4
+	MkfsOptions []string
+	Label       string
+}
+
 type Formatter interface {
-	Format(partitionPath string, fsType FileSystemType) (err error)
+	// Format creates filesystem unless partition is already
+	// formatted with one of the supported filesystems
+	Format(partitionPath string, spec FormatSpec) (err error)
+
+	// FileSystemType is empty when partition is not formatted
+	FileSystemType(partitionPath string) (FileSystemType, error)
+
+	// GrowFilesystem extends mounted filesystem to the size of its partition
+	GrowFilesystem(partitionPath, mountPoint string) (err error)
 }
@@ -16,8 +16,14 @@ type linuxDiskManager struct {
 	rootDevicePartitioner Partitioner
 	partedPartitioner     Partitioner
 	formatter             Formatter
+	filesystemChecker     FilesystemChecker
+	encryptor             Encryptor
 	mounter               Mounter
 	mountsSearcher        MountsSearcher
+	treeCopier            TreeCopier
+	trimmer               Trimmer
+	swapper               Swapper
+	logicalVolumeManager  LogicalVolumeManager
 	fs                    boshsys.FileSystem
 	logger                boshlog.Logger
 	runner                boshsys.CmdRunner
@@ -70,9 +76,15 @@ func NewLinuxDiskManager(
 		partitioner:           partitioner,
 		rootDevicePartitioner: NewRootDevicePartitioner(logger, runner, uint64(20*1024*1024)),
 		partedPartitioner:     NewPartedPartitioner(logger, runner, clock.NewClock()),
-		formatter:             NewLinuxFormatter(runner, fs),
+		formatter:             NewLinuxFormatter(runner, fs, logger),
+		filesystemChecker:     NewLinuxFilesystemChecker(runner),
+		encryptor:             NewLinuxLuksEncryptor(runner, fs),
 		mounter:               mounter,
 		mountsSearcher:        mountsSearcher,
+		treeCopier:            NewLinuxTreeCopier(logger),
+		trimmer:               NewLinuxTrimmer(runner),
+		swapper:               NewLinuxSwapper(runner, fs, logger),
+		logicalVolumeManager:  NewLinuxLogicalVolumeManager(runner, logger),
 		fs:                    fs,
 		logger:                logger,
 		runner:                runner,
@@ -84,8 +96,18 @@ func (m linuxDiskManager) GetPartedPartitioner() Partitioner     { return m.part
 func (m linuxDiskManager) GetRootDevicePartitioner() Partitioner { return m.rootDevicePartitioner }
 
 func (m linuxDiskManager) GetFormatter() Formatter           { return m.formatter }
+func (m linuxDiskManager) GetEncryptor() Encryptor           { return m.encryptor }
 func (m linuxDiskManager) GetMounter() Mounter               { return m.mounter }
 func (m linuxDiskManager) GetMountsSearcher() MountsSearcher { return m.mountsSearcher }
+func (m linuxDiskManager) GetTreeCopier() TreeCopier         { return m.treeCopier }
+func (m linuxDiskManager) GetTrimmer() Trimmer               { return m.trimmer }
+func (m linuxDiskManager) GetSwapper() Swapper               { return m.swapper }
+
+func (m linuxDiskManager) GetFilesystemChecker() FilesystemChecker { return m.filesystemChecker }
+
+func (m linuxDiskManager) GetLogicalVolumeManager() LogicalVolumeManager {
+	return m.logicalVolumeManager
+}
 
 func (m linuxDiskManager) GetDiskUtil(diskPath string) boshdevutil.DeviceUtil {
 	return NewDiskUtil(diskPath, m.runner, m.mounter, m.fs, m.logger)
@@ -0,0 +1,139 @@
+package disk
+
+import (
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// Exit statuses shared by e2fsck and xfs_repair;
+// e2fsck exit status is a bitmask of the fsck* bits
+const (
+	fsckExitClean          = 0
+	fsckExitCorrected      = 1
+	fsckExitCorrectedBoot  = 2
+	fsckExitUncorrected    = 4
+	fsckExitOperational    = 8
+	fsckExitUsage          = 16
+	fsckExitCancelled      = 32
+	fsckExitLibrary        = 128
+	xfsRepairExitCorrupted = 1
+
+	fsckExitFailed = fsckExitOperational | fsckExitUsage | fsckExitCancelled | fsckExitLibrary
+)
+
+type linuxFilesystemChecker struct {
+	runner boshsys.CmdRunner
+}
+
+func NewLinuxFilesystemChecker(runner boshsys.CmdRunner) FilesystemChecker {
+	return linuxFilesystemChecker{runner: runner}
+}
+
+func (c linuxFilesystemChecker) Check(partitionPath string, mode FilesystemCheckMode) (FilesystemCheckResult, error) {
+	fsType, err := partitionFileSystemType(c.runner, partitionPath)
+	if err != nil {
+		return FilesystemCheckResult{}, bosherr.WrapError(err, "Checking filesystem type")
+	}
+
+	result := FilesystemCheckResult{FileSystemType: fsType}
+
+	switch fsType {
+	case FileSystemExt3, FileSystemExt4:
+		err = c.checkExt(partitionPath, mode, &result)
+	case FileSystemXFS:
+		err = c.checkXFS(partitionPath, mode, &result)
+	case FileSystemBtrfs:
+		err = c.checkBtrfs(partitionPath, &result)
+	}
+
+	if err != nil {
+		return FilesystemCheckResult{}, bosherr.WrapErrorf(err, "Checking %s filesystem on '%s'", fsType, partitionPath)
+	}
+
+	return result, nil
+}
+
+func (c linuxFilesystemChecker) checkExt(partitionPath string, mode FilesystemCheckMode, result *FilesystemCheckResult) error {
+	// Without -f only filesystems that were not cleanly unmounted are fully checked
+	flag := "-n"
+	if mode == FilesystemCheckModeRepair {
+		flag = "-y"
+	}
+
+	exitStatus, err := c.run("e2fsck", flag, partitionPath)
+	if err != nil {
+		return err
+	}
+
+	// Uncorrected errors are reported even if e2fsck also failed
+	// since they are what decides whether disk can be mounted
+	switch {
+	case exitStatus&fsckExitUncorrected != 0:
+		result.ErrorsFound = true
+	case exitStatus&fsckExitFailed != 0:
+		return bosherr.Errorf("e2fsck exited with %d", exitStatus)
+	case exitStatus&(fsckExitCorrected|fsckExitCorrectedBoot) != 0:
+		result.ErrorsFound = true
+		result.Repaired = true
+	}
+
+	return nil
+}
+
+func (c linuxFilesystemChecker) checkXFS(partitionPath string, mode FilesystemCheckMode, result *FilesystemCheckResult) error {
+	// xfs_repair exits with 0 whether or not it fixed anything,
+	// hence it is always asked to look for problems first
+	exitStatus, err := c.run("xfs_repair", "-n", partitionPath)
+	if err != nil {
+		return err
+	}
+
+	switch exitStatus {
+	case fsckExitClean:
+		return nil
+	case xfsRepairExitCorrupted:
+		result.ErrorsFound = true
+	default:
+		return bosherr.Errorf("xfs_repair exited with %d", exitStatus)
+	}
+
+	if mode != FilesystemCheckModeRepair {
+		return nil
+	}
+
+	exitStatus, err = c.run("xfs_repair", partitionPath)
+	if err != nil {
+		return err
+	}
+
+	if exitStatus != fsckExitClean {
+		return bosherr.Errorf("xfs_repair exited with %d", exitStatus)
+	}
+
+	result.Repaired = true
+
+	return nil
+}
+
+// checkBtrfs never repairs since btrfs check --repair may cause further damage
+func (c linuxFilesystemChecker) checkBtrfs(partitionPath string, result *FilesystemCheckResult) error {
+	exitStatus, err := c.run("btrfs", "check", "--readonly", partitionPath)
+	if err != nil {
+		return err
+	}
+
+	result.ErrorsFound = exitStatus != fsckExitClean
+
+	return nil
+}
+
+// run returns exit status of a command that exited on its own
+// since non-zero statuses describe the state of filesystem
+func (c linuxFilesystemChecker) run(cmdName string, args ...string) (int, error) {
+	_, _, exitStatus, err := c.runner.RunCommand(cmdName, args...)
+	if err != nil && exitStatus < 0 {
+		return exitStatus, bosherr.WrapErrorf(err, "Running %s", cmdName)
+	}
+
+	return exitStatus, nil
+}
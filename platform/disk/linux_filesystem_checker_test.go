@@ -0,0 +1,160 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/ginkgo/extensions/table"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxFilesystemChecker", func() {
+	var (
+		runner  *fakesys.FakeCmdRunner
+		checker FilesystemChecker
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		checker = NewLinuxFilesystemChecker(runner)
+	})
+
+	setFileSystemType := func(fsType string) {
+		runner.AddCmdResult("blkid -p /dev/sdb1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="` + fsType + `" yyyy zzzz`})
+	}
+
+	Context("when partition has ext4 filesystem", func() {
+		BeforeEach(func() {
+			setFileSystemType("ext4")
+		})
+
+		It("only reports errors in check mode", func() {
+			runner.AddCmdResult("e2fsck -n /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 4, Error: errors.New("fake-exit-4")})
+
+			result, err := checker.Check("/dev/sdb1", FilesystemCheckModeCheck)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(FilesystemCheckResult{FileSystemType: FileSystemExt4, ErrorsFound: true}))
+			Expect(runner.RunCommands).To(ContainElement([]string{"e2fsck", "-n", "/dev/sdb1"}))
+		})
+
+		It("reports repaired errors in repair mode", func() {
+			runner.AddCmdResult("e2fsck -y /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")})
+
+			result, err := checker.Check("/dev/sdb1", FilesystemCheckModeRepair)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(FilesystemCheckResult{FileSystemType: FileSystemExt4, ErrorsFound: true, Repaired: true}))
+		})
+
+		It("reports clean filesystem", func() {
+			runner.AddCmdResult("e2fsck -y /dev/sdb1", fakesys.FakeCmdResult{})
+
+			result, err := checker.Check("/dev/sdb1", FilesystemCheckModeRepair)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(FilesystemCheckResult{FileSystemType: FileSystemExt4}))
+		})
+
+		It("returns error when e2fsck fails on its own", func() {
+			runner.AddCmdResult("e2fsck -n /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 8, Error: errors.New("fake-exit-8")})
+
+			_, err := checker.Check("/dev/sdb1", FilesystemCheckModeCheck)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Checking ext4 filesystem on '/dev/sdb1': e2fsck exited with 8"))
+		})
+
+		DescribeTable("interprets e2fsck exit status as a bitmask",
+			func(exitStatus int, expectedResult FilesystemCheckResult) {
+				runner.AddCmdResult("e2fsck -y /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: exitStatus, Error: errors.New("fake-exit")})
+
+				result, err := checker.Check("/dev/sdb1", FilesystemCheckModeRepair)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(result).To(Equal(expectedResult))
+			},
+			Entry("corrected", 1, FilesystemCheckResult{FileSystemType: FileSystemExt4, ErrorsFound: true, Repaired: true}),
+			Entry("corrected and reboot needed", 3, FilesystemCheckResult{FileSystemType: FileSystemExt4, ErrorsFound: true, Repaired: true}),
+			Entry("uncorrected", 4, FilesystemCheckResult{FileSystemType: FileSystemExt4, ErrorsFound: true}),
+			Entry("partially corrected", 5, FilesystemCheckResult{FileSystemType: FileSystemExt4, ErrorsFound: true}),
+			Entry("uncorrected with operational error", 12, FilesystemCheckResult{FileSystemType: FileSystemExt4, ErrorsFound: true}),
+		)
+
+		DescribeTable("returns error when e2fsck fails without finding uncorrected errors",
+			func(exitStatus int) {
+				runner.AddCmdResult("e2fsck -y /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: exitStatus, Error: errors.New("fake-exit")})
+
+				_, err := checker.Check("/dev/sdb1", FilesystemCheckModeRepair)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("e2fsck exited with"))
+			},
+			Entry("operational error", 8),
+			Entry("corrected with operational error", 9),
+			Entry("usage error", 16),
+			Entry("cancelled", 32),
+			Entry("shared library error", 128),
+		)
+
+		It("returns error when e2fsck cannot be run", func() {
+			runner.AddCmdResult("e2fsck -n /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: -1, Error: errors.New("fake-run-err")})
+
+			_, err := checker.Check("/dev/sdb1", FilesystemCheckModeCheck)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-run-err"))
+		})
+	})
+
+	Context("when partition has xfs filesystem", func() {
+		BeforeEach(func() {
+			setFileSystemType("xfs")
+		})
+
+		It("does not repair clean filesystem", func() {
+			runner.AddCmdResult("xfs_repair -n /dev/sdb1", fakesys.FakeCmdResult{})
+
+			result, err := checker.Check("/dev/sdb1", FilesystemCheckModeRepair)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(FilesystemCheckResult{FileSystemType: FileSystemXFS}))
+			Expect(runner.RunCommands).ToNot(ContainElement([]string{"xfs_repair", "/dev/sdb1"}))
+		})
+
+		It("only reports errors in check mode", func() {
+			runner.AddCmdResult("xfs_repair -n /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")})
+
+			result, err := checker.Check("/dev/sdb1", FilesystemCheckModeCheck)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(FilesystemCheckResult{FileSystemType: FileSystemXFS, ErrorsFound: true}))
+			Expect(runner.RunCommands).ToNot(ContainElement([]string{"xfs_repair", "/dev/sdb1"}))
+		})
+
+		It("repairs found errors in repair mode", func() {
+			runner.AddCmdResult("xfs_repair -n /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")})
+			runner.AddCmdResult("xfs_repair /dev/sdb1", fakesys.FakeCmdResult{})
+
+			result, err := checker.Check("/dev/sdb1", FilesystemCheckModeRepair)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(FilesystemCheckResult{FileSystemType: FileSystemXFS, ErrorsFound: true, Repaired: true}))
+		})
+	})
+
+	Context("when partition has btrfs filesystem", func() {
+		BeforeEach(func() {
+			setFileSystemType("btrfs")
+		})
+
+		It("reports errors without repairing them", func() {
+			runner.AddCmdResult("btrfs check --readonly /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 1, Error: errors.New("fake-exit-1")})
+
+			result, err := checker.Check("/dev/sdb1", FilesystemCheckModeRepair)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(result).To(Equal(FilesystemCheckResult{FileSystemType: FileSystemBtrfs, ErrorsFound: true}))
+		})
+	})
+
+	It("returns error when filesystem type cannot be determined", func() {
+		runner.AddCmdResult("blkid -p /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: -1, Error: errors.New("fake-blkid-err")})
+
+		_, err := checker.Check("/dev/sdb1", FilesystemCheckModeCheck)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Checking filesystem type"))
+	})
+})
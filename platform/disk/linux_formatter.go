@@ -2,6 +2,7 @@ package disk
 
 import (
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	boshsys "github.com/cloudfoundry/bosh-utils/system"
 	"regexp"
 	"strings"
@@ -10,43 +11,51 @@ import (
 type linuxFormatter struct {
 	runner boshsys.CmdRunner
 	fs     boshsys.FileSystem
+	logTag string
+	logger boshlog.Logger
 }
 
-func NewLinuxFormatter(runner boshsys.CmdRunner, fs boshsys.FileSystem) Formatter {
+func NewLinuxFormatter(runner boshsys.CmdRunner, fs boshsys.FileSystem, logger boshlog.Logger) Formatter {
 	return linuxFormatter{
 		runner: runner,
 		fs:     fs,
+		logTag: "linuxFormatter",
+		logger: logger,
 	}
 }
 
-func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err error) {
-	existingFsType, err := f.getPartitionFormatType(partitionPath)
+func (f linuxFormatter) Format(partitionPath string, spec FormatSpec) (err error) {
+	existingFsType, err := partitionFileSystemType(f.runner, partitionPath)
 	if err != nil {
 		return bosherr.WrapError(err, "Checking filesystem format of partition")
 	}
 
-	if fsType == FileSystemSwap {
+	if spec.FileSystemType == FileSystemSwap {
 		if existingFsType == FileSystemSwap {
 			return
 		}
 		// swap is not user-configured, so we're not concerned about reformatting
-	} else if existingFsType == FileSystemExt4 || existingFsType == FileSystemXFS {
+	} else if f.isSupportedFileSystem(existingFsType) {
 		// never reformat if it is already formatted in a supported format
+		if existingFsType != spec.FileSystemType {
+			f.logger.Warn(f.logTag, "Keeping %s filesystem of partition '%s' although %s was requested", existingFsType, partitionPath, spec.FileSystemType)
+		}
+
 		return
 	}
 
-	switch fsType {
+	switch spec.FileSystemType {
 	case FileSystemSwap:
-		_, _, _, err = f.runner.RunCommand("mkswap", partitionPath)
+		_, _, _, err = f.runner.RunCommand("mkswap", f.mkfsArgs(spec, partitionPath)...)
 		if err != nil {
 			err = bosherr.WrapError(err, "Shelling out to mkswap")
 		}
 
-	case FileSystemExt4:
-		err = f.makeFileSystemExt4(partitionPath)
+	case FileSystemExt3, FileSystemExt4:
+		err = f.makeFileSystemExt(partitionPath, spec)
 		if err != nil {
 			if strings.Contains(err.Error(), "apparently in use by the system") {
-				err = f.makeFileSystemExt4(partitionPath)
+				err = f.makeFileSystemExt(partitionPath, spec)
 			}
 		}
 		if err != nil {
@@ -54,26 +63,97 @@ func (f linuxFormatter) Format(partitionPath string, fsType FileSystemType) (err
 		}
 
 	case FileSystemXFS:
-		_, _, _, err = f.runner.RunCommand("mkfs.xfs", partitionPath)
+		_, _, _, err = f.runner.RunCommand("mkfs.xfs", f.mkfsArgs(spec, partitionPath)...)
 		if err != nil {
 			err = bosherr.WrapError(err, "Shelling out to mkfs.xfs")
 		}
+
+	case FileSystemBtrfs:
+		_, _, _, err = f.runner.RunCommand("mkfs.btrfs", f.mkfsArgs(spec, partitionPath)...)
+		if err != nil {
+			err = bosherr.WrapError(err, "Shelling out to mkfs.btrfs")
+		}
+
+	default:
+		err = bosherr.Errorf("Formatting partition with '%s' is not supported", spec.FileSystemType)
 	}
 	return
 }
 
-func (f linuxFormatter) makeFileSystemExt4(partitionPath string) error {
-	var err error
+func (f linuxFormatter) FileSystemType(partitionPath string) (FileSystemType, error) {
+	fsType, err := partitionFileSystemType(f.runner, partitionPath)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Checking filesystem format of partition")
+	}
+
+	return fsType, nil
+}
+
+func (f linuxFormatter) GrowFilesystem(partitionPath, mountPoint string) error {
+	fsType, err := partitionFileSystemType(f.runner, partitionPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking filesystem format of partition")
+	}
+
+	switch fsType {
+	case FileSystemExt3, FileSystemExt4:
+		_, _, _, err = f.runner.RunCommand("resize2fs", partitionPath)
+		if err != nil {
+			return bosherr.WrapError(err, "Shelling out to resize2fs")
+		}
+
+	case FileSystemXFS:
+		// xfs can only be grown through its mount point
+		_, _, _, err = f.runner.RunCommand("xfs_growfs", mountPoint)
+		if err != nil {
+			return bosherr.WrapError(err, "Shelling out to xfs_growfs")
+		}
+
+	case FileSystemBtrfs:
+		_, _, _, err = f.runner.RunCommand("btrfs", "filesystem", "resize", "max", mountPoint)
+		if err != nil {
+			return bosherr.WrapError(err, "Shelling out to btrfs")
+		}
+
+	default:
+		return bosherr.Errorf("Growing filesystem '%s' of partition '%s' is not supported", fsType, partitionPath)
+	}
+
+	return nil
+}
+
+func (f linuxFormatter) makeFileSystemExt(partitionPath string, spec FormatSpec) error {
+	args := []string{"-t", string(spec.FileSystemType), "-j"}
 	if f.fs.FileExists("/sys/fs/ext4/features/lazy_itable_init") {
-		_, _, _, err = f.runner.RunCommand("mke2fs", "-t", string(FileSystemExt4), "-j", "-E", "lazy_itable_init=1", partitionPath)
-	} else {
-		_, _, _, err = f.runner.RunCommand("mke2fs", "-t", string(FileSystemExt4), "-j", partitionPath)
+		args = append(args, "-E", "lazy_itable_init=1")
 	}
+
+	_, _, _, err := f.runner.RunCommand("mke2fs", append(args, f.mkfsArgs(spec, partitionPath)...)...)
 	return err
 }
 
-func (f linuxFormatter) getPartitionFormatType(partitionPath string) (FileSystemType, error) {
-	stdout, stderr, exitStatus, err := f.runner.RunCommand("blkid", "-p", partitionPath)
+// mkfsArgs places user provided options before partition
+// so that they can override defaults chosen by the agent
+func (f linuxFormatter) mkfsArgs(spec FormatSpec, partitionPath string) []string {
+	var args []string
+	if spec.Label != "" {
+		args = append(args, "-L", spec.Label)
+	}
+	args = append(args, spec.MkfsOptions...)
+	return append(args, partitionPath)
+}
+
+func (f linuxFormatter) isSupportedFileSystem(fsType FileSystemType) bool {
+	switch fsType {
+	case FileSystemExt3, FileSystemExt4, FileSystemXFS, FileSystemBtrfs:
+		return true
+	}
+	return false
+}
+
+// partitionFileSystemType is shared with filesystem checker
+func partitionFileSystemType(runner boshsys.CmdRunner, partitionPath string) (FileSystemType, error) {
+	stdout, stderr, exitStatus, err := runner.RunCommand("blkid", "-p", partitionPath)
 
 	if err != nil {
 		if exitStatus == 2 && stderr == "" {
@@ -7,18 +7,21 @@ import (
 	"errors"
 	"fmt"
 	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
 
 var _ = Describe("Linux Formatter", func() {
+	logger := boshlog.NewLogger(boshlog.LevelNone)
+
 	Describe("when using swap", func() {
 		It("format as swap disk if partition has not been formatted", func() {
 			fakeRunner := fakesys.NewFakeCmdRunner()
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemSwap)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemSwap})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkswap", "/dev/xvda2"}))
@@ -29,8 +32,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext4" yyyy zzzz`})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemSwap)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda1", FormatSpec{FileSystemType: FileSystemSwap})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkswap", "/dev/xvda1"}))
@@ -41,8 +44,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="swap" yyyy zzzz`})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemSwap)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda1", FormatSpec{FileSystemType: FileSystemSwap})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
@@ -56,8 +59,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs.WriteFile("/sys/fs/ext4/features/lazy_itable_init", []byte{})
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext2" yyyy zzzz`})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemExt4)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemExt4})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1", "/dev/xvda2"}))
@@ -86,8 +89,8 @@ var _ = Describe("Linux Formatter", func() {
 				fakeRunner.AddCmdResult(mkeCmd, fakesys.FakeCmdResult{
 					ExitStatus: 0,
 				})
-				formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-				formatter.Format("/dev/xvda2", FileSystemExt4)
+				formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+				formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemExt4})
 
 				Expect(3).To(Equal(len(fakeRunner.RunCommands)))
 				Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1", "/dev/xvda2"}))
@@ -98,8 +101,8 @@ var _ = Describe("Linux Formatter", func() {
 				fakeRunner.AddCmdResult(mkeCmd, fakesys.FakeCmdResult{
 					Error: errors.New(`some other error`),
 				})
-				formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-				formatter.Format("/dev/xvda2", FileSystemExt4)
+				formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+				formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemExt4})
 
 				Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 				Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1", "/dev/xvda2"}))
@@ -111,8 +114,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext2" yyyy zzzz`})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemExt4)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemExt4})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext4", "-j", "/dev/xvda2"}))
@@ -123,8 +126,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext4" yyyy zzzz`})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemExt4)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda1", FormatSpec{FileSystemType: FileSystemExt4})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
@@ -135,8 +138,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="xfs" yyyy zzzz`})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemExt4)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemExt4})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda2"}))
@@ -147,8 +150,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="somethingelse" yyyy zzzz`})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemExt4)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemExt4})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda2"}))
@@ -161,8 +164,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda2", FileSystemXFS)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemXFS})
 
 			Expect(2).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkfs.xfs", "/dev/xvda2"}))
@@ -173,8 +176,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext4" yyyy zzzz`})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemXFS)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda1", FormatSpec{FileSystemType: FileSystemXFS})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
@@ -185,8 +188,8 @@ var _ = Describe("Linux Formatter", func() {
 			fakeFs := fakesys.NewFakeFileSystem()
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="xfs" yyyy zzzz`})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			formatter.Format("/dev/xvda1", FileSystemXFS)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda1", FormatSpec{FileSystemType: FileSystemXFS})
 
 			Expect(1).To(Equal(len(fakeRunner.RunCommands)))
 			Expect(fakeRunner.RunCommands[0]).To(Equal([]string{"blkid", "-p", "/dev/xvda1"}))
@@ -198,11 +201,197 @@ var _ = Describe("Linux Formatter", func() {
 			fakeRunner.AddCmdResult("mkfs.xfs /dev/xvda2", fakesys.FakeCmdResult{Error: errors.New("Sadness")})
 			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stderr: "", ExitStatus: 2})
 
-			formatter := NewLinuxFormatter(fakeRunner, fakeFs)
-			err := formatter.Format("/dev/xvda2", FileSystemXFS)
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			err := formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemXFS})
 
 			Expect(err).To(HaveOccurred())
 			Expect(err.Error()).To(Equal("Shelling out to mkfs.xfs: Sadness"))
 		})
 	})
+
+	Describe("when using ext3", func() {
+		It("formats a blank disk with type ext3", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			err := formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemExt3})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mke2fs", "-t", "ext3", "-j", "/dev/xvda2"}))
+		})
+
+		It("does not re-format if fs is already ext3", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext3" yyyy zzzz`})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda1", FormatSpec{FileSystemType: FileSystemExt4})
+
+			Expect(fakeRunner.RunCommands).To(Equal([][]string{{"blkid", "-p", "/dev/xvda1"}}))
+		})
+	})
+
+	Describe("when using btrfs", func() {
+		It("formats a blank disk with type btrfs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			err := formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemBtrfs})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkfs.btrfs", "/dev/xvda2"}))
+		})
+
+		It("does not re-format if fs is already btrfs", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="btrfs" yyyy zzzz`})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			formatter.Format("/dev/xvda1", FormatSpec{FileSystemType: FileSystemBtrfs})
+
+			Expect(fakeRunner.RunCommands).To(Equal([][]string{{"blkid", "-p", "/dev/xvda1"}}))
+		})
+
+		It("throws an error if formatting filesystem fails", func() {
+			fakeRunner := fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeRunner.AddCmdResult("mkfs.btrfs /dev/xvda2", fakesys.FakeCmdResult{Error: errors.New("Sadness")})
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{Stderr: "", ExitStatus: 2})
+
+			formatter := NewLinuxFormatter(fakeRunner, fakeFs, logger)
+			err := formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemBtrfs})
+
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Shelling out to mkfs.btrfs: Sadness"))
+		})
+	})
+
+	Describe("with mkfs options and label", func() {
+		var (
+			fakeRunner *fakesys.FakeCmdRunner
+			formatter  Formatter
+		)
+
+		BeforeEach(func() {
+			fakeRunner = fakesys.NewFakeCmdRunner()
+			fakeFs := fakesys.NewFakeFileSystem()
+			fakeFs.WriteFile("/sys/fs/ext4/features/lazy_itable_init", []byte{})
+			fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+
+			formatter = NewLinuxFormatter(fakeRunner, fakeFs, logger)
+		})
+
+		It("passes them to mke2fs after default options", func() {
+			err := formatter.Format("/dev/xvda2", FormatSpec{
+				FileSystemType: FileSystemExt4,
+				MkfsOptions:    []string{"-i", "4096", "-m", "0"},
+				Label:          "fake-label",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{
+				"mke2fs", "-t", "ext4", "-j", "-E", "lazy_itable_init=1", "-L", "fake-label", "-i", "4096", "-m", "0", "/dev/xvda2",
+			}))
+		})
+
+		It("passes them to mkfs.xfs", func() {
+			err := formatter.Format("/dev/xvda2", FormatSpec{
+				FileSystemType: FileSystemXFS,
+				MkfsOptions:    []string{"-K"},
+				Label:          "fake-label",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkfs.xfs", "-L", "fake-label", "-K", "/dev/xvda2"}))
+		})
+
+		It("passes them to mkfs.btrfs", func() {
+			err := formatter.Format("/dev/xvda2", FormatSpec{
+				FileSystemType: FileSystemBtrfs,
+				MkfsOptions:    []string{"--mixed"},
+				Label:          "fake-label",
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"mkfs.btrfs", "-L", "fake-label", "--mixed", "/dev/xvda2"}))
+		})
+	})
+
+	It("returns error for unsupported filesystem", func() {
+		fakeRunner := fakesys.NewFakeCmdRunner()
+		fakeRunner.AddCmdResult("blkid -p /dev/xvda2", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+
+		formatter := NewLinuxFormatter(fakeRunner, fakesys.NewFakeFileSystem(), logger)
+		err := formatter.Format("/dev/xvda2", FormatSpec{FileSystemType: FileSystemType("vfat")})
+
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Formatting partition with 'vfat' is not supported"))
+	})
+
+	Describe("GrowFilesystem", func() {
+		var (
+			fakeRunner *fakesys.FakeCmdRunner
+			formatter  Formatter
+		)
+
+		BeforeEach(func() {
+			fakeRunner = fakesys.NewFakeCmdRunner()
+			formatter = NewLinuxFormatter(fakeRunner, fakesys.NewFakeFileSystem(), logger)
+		})
+
+		It("grows ext4 filesystem through its partition", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdf1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext4" yyyy zzzz`})
+
+			err := formatter.GrowFilesystem("/dev/sdf1", "/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"resize2fs", "/dev/sdf1"}))
+		})
+
+		It("grows xfs filesystem through its mount point", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdf1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="xfs" yyyy zzzz`})
+
+			err := formatter.GrowFilesystem("/dev/sdf1", "/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"xfs_growfs", "/var/vcap/store"}))
+		})
+
+		It("grows ext3 filesystem through its partition", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdf1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext3" yyyy zzzz`})
+
+			err := formatter.GrowFilesystem("/dev/sdf1", "/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"resize2fs", "/dev/sdf1"}))
+		})
+
+		It("grows btrfs filesystem through its mount point", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdf1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="btrfs" yyyy zzzz`})
+
+			err := formatter.GrowFilesystem("/dev/sdf1", "/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(fakeRunner.RunCommands[1]).To(Equal([]string{"btrfs", "filesystem", "resize", "max", "/var/vcap/store"}))
+		})
+
+		It("returns error for unsupported filesystem", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdf1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="vfat" yyyy zzzz`})
+
+			err := formatter.GrowFilesystem("/dev/sdf1", "/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Growing filesystem 'vfat' of partition '/dev/sdf1' is not supported"))
+		})
+
+		It("returns error when growing filesystem fails", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdf1", fakesys.FakeCmdResult{Stdout: `xxxxx TYPE="ext4" yyyy zzzz`})
+			fakeRunner.AddCmdResult("resize2fs /dev/sdf1", fakesys.FakeCmdResult{Error: errors.New("fake-resize2fs-err")})
+
+			err := formatter.GrowFilesystem("/dev/sdf1", "/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-resize2fs-err"))
+		})
+	})
 })
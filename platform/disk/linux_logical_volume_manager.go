@@ -0,0 +1,145 @@
+package disk
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+type linuxLogicalVolumeManager struct {
+	runner boshsys.CmdRunner
+	logTag string
+	logger boshlog.Logger
+}
+
+func NewLinuxLogicalVolumeManager(runner boshsys.CmdRunner, logger boshlog.Logger) LogicalVolumeManager {
+	return linuxLogicalVolumeManager{
+		runner: runner,
+		logTag: "linuxLogicalVolumeManager",
+		logger: logger,
+	}
+}
+
+func (m linuxLogicalVolumeManager) Setup(spec LogicalVolumeSpec) (string, error) {
+	devicePath := filepath.Join("/dev", spec.VolumeGroup, spec.Name)
+
+	err := m.setupVolumeGroup(spec)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Setting up volume group '%s'", spec.VolumeGroup)
+	}
+
+	err = m.setupLogicalVolume(spec)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Setting up logical volume '%s'", spec.Name)
+	}
+
+	return devicePath, nil
+}
+
+func (m linuxLogicalVolumeManager) setupVolumeGroup(spec LogicalVolumeSpec) error {
+	stdout, err := m.run("vgs", "--noheadings", "-o", "vg_name")
+	if err != nil {
+		return err
+	}
+
+	if m.containsName(stdout, spec.VolumeGroup) {
+		m.logger.Debug(m.logTag, "Volume group '%s' already exists", spec.VolumeGroup)
+
+		_, err = m.run("vgchange", "-ay", spec.VolumeGroup)
+		return err
+	}
+
+	if len(spec.Devices) == 0 {
+		return bosherr.Error("No devices to create volume group from")
+	}
+
+	m.logger.Info(m.logTag, "Creating volume group '%s' from %s", spec.VolumeGroup, spec.Devices)
+
+	for _, device := range spec.Devices {
+		err = m.wipeDevice(device)
+		if err != nil {
+			return err
+		}
+
+		_, err = m.run("pvcreate", "-y", device)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = m.run("vgcreate", append([]string{spec.VolumeGroup}, spec.Devices...)...)
+
+	return err
+}
+
+func (m linuxLogicalVolumeManager) setupLogicalVolume(spec LogicalVolumeSpec) error {
+	stdout, err := m.run("lvs", "--noheadings", "-o", "lv_name", spec.VolumeGroup)
+	if err != nil {
+		return err
+	}
+
+	if m.containsName(stdout, spec.Name) {
+		m.logger.Debug(m.logTag, "Logical volume '%s' already exists", spec.Name)
+		return nil
+	}
+
+	args := []string{"-y", "-n", spec.Name, "-l", "100%FREE"}
+
+	if spec.Striped && len(spec.Devices) > 1 {
+		args = append(args, "-i", strconv.Itoa(len(spec.Devices)))
+
+		if spec.StripeSizeInKB > 0 {
+			args = append(args, "-I", fmt.Sprintf("%dk", spec.StripeSizeInKB))
+		}
+	}
+
+	_, err = m.run("lvcreate", append(args, spec.VolumeGroup)...)
+
+	return err
+}
+
+// wipeDevice removes previous partition tables and filesystems which prevent
+// pvcreate from claiming devices; devices that still have partitions or
+// volumes on them are refused since they might hold data of someone else
+func (m linuxLogicalVolumeManager) wipeDevice(device string) error {
+	stdout, err := m.run("lsblk", "-nr", "-o", "TYPE", device)
+	if err != nil {
+		return err
+	}
+
+	// First line describes device itself, following lines its partitions and volumes
+	types := strings.Fields(stdout)
+	if len(types) > 1 {
+		return bosherr.Errorf("Refusing to wipe device '%s' since it has %s on it", device, strings.Join(types[1:], ", "))
+	}
+
+	_, err = m.run("wipefs", "-a", device)
+
+	return err
+}
+
+// containsName looks for a name in output of vgs or lvs
+// which prints one indented name per line
+func (m linuxLogicalVolumeManager) containsName(stdout, name string) bool {
+	for _, line := range strings.Split(stdout, "\n") {
+		if strings.TrimSpace(line) == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m linuxLogicalVolumeManager) run(cmdName string, args ...string) (string, error) {
+	stdout, _, _, err := m.runner.RunCommand(cmdName, args...)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Shelling out to %s", cmdName)
+	}
+
+	return stdout, nil
+}
@@ -0,0 +1,124 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxLogicalVolumeManager", func() {
+	var (
+		runner  *fakesys.FakeCmdRunner
+		manager LogicalVolumeManager
+		spec    LogicalVolumeSpec
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		manager = NewLinuxLogicalVolumeManager(runner, boshlog.NewLogger(boshlog.LevelNone))
+		spec = LogicalVolumeSpec{
+			VolumeGroup: "fake-vg",
+			Name:        "fake-lv",
+			Devices:     []string{"/dev/nvme1n1", "/dev/nvme2n1"},
+		}
+	})
+
+	It("pools devices into volume group and creates logical volume spanning it", func() {
+		devicePath, err := manager.Setup(spec)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(devicePath).To(Equal("/dev/fake-vg/fake-lv"))
+
+		Expect(runner.RunCommands).To(Equal([][]string{
+			{"vgs", "--noheadings", "-o", "vg_name"},
+			{"lsblk", "-nr", "-o", "TYPE", "/dev/nvme1n1"},
+			{"wipefs", "-a", "/dev/nvme1n1"},
+			{"pvcreate", "-y", "/dev/nvme1n1"},
+			{"lsblk", "-nr", "-o", "TYPE", "/dev/nvme2n1"},
+			{"wipefs", "-a", "/dev/nvme2n1"},
+			{"pvcreate", "-y", "/dev/nvme2n1"},
+			{"vgcreate", "fake-vg", "/dev/nvme1n1", "/dev/nvme2n1"},
+			{"lvs", "--noheadings", "-o", "lv_name", "fake-vg"},
+			{"lvcreate", "-y", "-n", "fake-lv", "-l", "100%FREE", "fake-vg"},
+		}))
+	})
+
+	It("stripes logical volume across all devices", func() {
+		spec.Striped = true
+		spec.StripeSizeInKB = 256
+
+		_, err := manager.Setup(spec)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(runner.RunCommands).To(ContainElement([]string{
+			"lvcreate", "-y", "-n", "fake-lv", "-l", "100%FREE", "-i", "2", "-I", "256k", "fake-vg",
+		}))
+	})
+
+	It("does not stripe logical volume on a single device", func() {
+		spec.Striped = true
+		spec.Devices = []string{"/dev/nvme1n1"}
+
+		_, err := manager.Setup(spec)
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(runner.RunCommands).To(ContainElement([]string{
+			"lvcreate", "-y", "-n", "fake-lv", "-l", "100%FREE", "fake-vg",
+		}))
+	})
+
+	It("activates existing volume group and keeps existing logical volume", func() {
+		runner.AddCmdResult("vgs --noheadings -o vg_name", fakesys.FakeCmdResult{Stdout: "  other-vg\n  fake-vg\n"})
+		runner.AddCmdResult("lvs --noheadings -o lv_name fake-vg", fakesys.FakeCmdResult{Stdout: "  fake-lv\n"})
+
+		devicePath, err := manager.Setup(spec)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(devicePath).To(Equal("/dev/fake-vg/fake-lv"))
+
+		Expect(runner.RunCommands).To(Equal([][]string{
+			{"vgs", "--noheadings", "-o", "vg_name"},
+			{"vgchange", "-ay", "fake-vg"},
+			{"lvs", "--noheadings", "-o", "lv_name", "fake-vg"},
+		}))
+	})
+
+	It("returns error when there are no devices to pool", func() {
+		spec.Devices = nil
+
+		_, err := manager.Setup(spec)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("No devices to create volume group from"))
+	})
+
+	It("refuses to wipe devices that have partitions", func() {
+		runner.AddCmdResult("lsblk -nr -o TYPE /dev/nvme1n1", fakesys.FakeCmdResult{Stdout: "disk\n"})
+		runner.AddCmdResult("lsblk -nr -o TYPE /dev/nvme2n1", fakesys.FakeCmdResult{Stdout: "disk\npart\npart\n"})
+
+		_, err := manager.Setup(spec)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Refusing to wipe device '/dev/nvme2n1' since it has part, part on it"))
+
+		Expect(runner.RunCommands).ToNot(ContainElement([]string{"wipefs", "-a", "/dev/nvme2n1"}))
+		Expect(runner.RunCommands).ToNot(ContainElement(ContainElement("vgcreate")))
+	})
+
+	It("returns error when physical volume cannot be created", func() {
+		runner.AddCmdResult("pvcreate -y /dev/nvme2n1", fakesys.FakeCmdResult{Error: errors.New("fake-pvcreate-err")})
+
+		_, err := manager.Setup(spec)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Setting up volume group 'fake-vg': Shelling out to pvcreate: fake-pvcreate-err"))
+	})
+
+	It("returns error when logical volume cannot be created", func() {
+		runner.AddCmdResult("lvcreate -y -n fake-lv -l 100%FREE fake-vg", fakesys.FakeCmdResult{Error: errors.New("fake-lvcreate-err")})
+
+		_, err := manager.Setup(spec)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Setting up logical volume 'fake-lv': Shelling out to lvcreate: fake-lvcreate-err"))
+	})
+})
@@ -0,0 +1,92 @@
+package disk
+
+import (
+	"path"
+	"regexp"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	luksFsType = "crypto_LUKS"
+	mapperDir  = "/dev/mapper"
+)
+
+var blkidTypeRegexp = regexp.MustCompile(` TYPE="([^"]+)"`)
+
+type linuxLuksEncryptor struct {
+	runner boshsys.CmdRunner
+	fs     boshsys.FileSystem
+}
+
+func NewLinuxLuksEncryptor(runner boshsys.CmdRunner, fs boshsys.FileSystem) Encryptor {
+	return linuxLuksEncryptor{
+		runner: runner,
+		fs:     fs,
+	}
+}
+
+func (e linuxLuksEncryptor) Format(devicePath, key string) error {
+	stdout, stderr, exitStatus, err := e.runner.RunCommand("blkid", "-p", devicePath)
+	if err != nil && (exitStatus != 2 || stderr != "") {
+		return bosherr.WrapError(err, "Checking format of device")
+	}
+
+	// blkid exits with 2 when device does not have any signature
+	if err == nil {
+		match := blkidTypeRegexp.FindStringSubmatch(stdout)
+		if match != nil {
+			if match[1] == luksFsType {
+				return nil
+			}
+
+			// never wipe data that was written without encryption
+			return bosherr.Errorf("Refusing to encrypt device '%s' already formatted with '%s'", devicePath, match[1])
+		}
+	}
+
+	_, _, _, err = e.runner.RunCommandWithInput(key, "cryptsetup", "luksFormat", "--batch-mode", "--key-file", "-", devicePath)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to cryptsetup luksFormat")
+	}
+
+	return nil
+}
+
+func (e linuxLuksEncryptor) Open(devicePath, name, key string) (string, error) {
+	decryptedPath := path.Join(mapperDir, name)
+
+	if e.fs.FileExists(decryptedPath) {
+		return decryptedPath, nil
+	}
+
+	_, _, _, err := e.runner.RunCommandWithInput(key, "cryptsetup", "luksOpen", "--key-file", "-", devicePath, name)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Shelling out to cryptsetup luksOpen")
+	}
+
+	return decryptedPath, nil
+}
+
+func (e linuxLuksEncryptor) Close(name string) error {
+	if !e.fs.FileExists(path.Join(mapperDir, name)) {
+		return nil
+	}
+
+	_, _, _, err := e.runner.RunCommand("cryptsetup", "luksClose", name)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to cryptsetup luksClose")
+	}
+
+	return nil
+}
+
+func (e linuxLuksEncryptor) Resize(name, key string) error {
+	_, _, _, err := e.runner.RunCommandWithInput(key, "cryptsetup", "resize", "--key-file", "-", name)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to cryptsetup resize")
+	}
+
+	return nil
+}
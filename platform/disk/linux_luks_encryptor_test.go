@@ -0,0 +1,143 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("Linux LUKS Encryptor", func() {
+	var (
+		fakeRunner *fakesys.FakeCmdRunner
+		fakeFs     *fakesys.FakeFileSystem
+		encryptor  Encryptor
+	)
+
+	BeforeEach(func() {
+		fakeRunner = fakesys.NewFakeCmdRunner()
+		fakeFs = fakesys.NewFakeFileSystem()
+		encryptor = NewLinuxLuksEncryptor(fakeRunner, fakeFs)
+	})
+
+	Describe("Format", func() {
+		It("formats blank device with LUKS", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+
+			err := encryptor.Format("/dev/sdb1", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommandsWithInput).To(Equal([][]string{
+				{"fake-key", "cryptsetup", "luksFormat", "--batch-mode", "--key-file", "-", "/dev/sdb1"},
+			}))
+		})
+
+		It("does not reformat device that is already encrypted", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdb1", fakesys.FakeCmdResult{Stdout: `/dev/sdb1: UUID="fake-uuid" VERSION="1" TYPE="crypto_LUKS" USAGE="crypto"`})
+
+			err := encryptor.Format("/dev/sdb1", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommandsWithInput).To(BeEmpty())
+		})
+
+		It("refuses to encrypt device that contains unencrypted filesystem", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdb1", fakesys.FakeCmdResult{Stdout: `/dev/sdb1: UUID="fake-uuid" TYPE="ext4" USAGE="filesystem"`})
+
+			err := encryptor.Format("/dev/sdb1", "fake-key")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Refusing to encrypt device '/dev/sdb1' already formatted with 'ext4'"))
+
+			Expect(fakeRunner.RunCommandsWithInput).To(BeEmpty())
+		})
+
+		It("returns error when checking device fails", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 4, Stderr: "fake-stderr", Error: errors.New("fake-err")})
+
+			err := encryptor.Format("/dev/sdb1", "fake-key")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Checking format of device"))
+		})
+
+		It("returns error when luksFormat fails", func() {
+			fakeRunner.AddCmdResult("blkid -p /dev/sdb1", fakesys.FakeCmdResult{ExitStatus: 2, Error: errors.New("Exit code 2")})
+			fakeRunner.AddCmdResult("fake-key cryptsetup luksFormat --batch-mode --key-file - /dev/sdb1", fakesys.FakeCmdResult{Error: errors.New("fake-err")})
+
+			err := encryptor.Format("/dev/sdb1", "fake-key")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-err"))
+		})
+	})
+
+	Describe("Open", func() {
+		It("unlocks device and returns mapper path", func() {
+			path, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal("/dev/mapper/fake-name"))
+
+			Expect(fakeRunner.RunCommandsWithInput).To(Equal([][]string{
+				{"fake-key", "cryptsetup", "luksOpen", "--key-file", "-", "/dev/sdb1", "fake-name"},
+			}))
+		})
+
+		It("does not unlock device that is already open", func() {
+			err := fakeFs.WriteFileString("/dev/mapper/fake-name", "")
+			Expect(err).ToNot(HaveOccurred())
+
+			path, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(path).To(Equal("/dev/mapper/fake-name"))
+
+			Expect(fakeRunner.RunCommandsWithInput).To(BeEmpty())
+		})
+
+		It("returns error when luksOpen fails", func() {
+			fakeRunner.AddCmdResult("fake-key cryptsetup luksOpen --key-file - /dev/sdb1 fake-name", fakesys.FakeCmdResult{Error: errors.New("fake-err")})
+
+			_, err := encryptor.Open("/dev/sdb1", "fake-name", "fake-key")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-err"))
+		})
+	})
+
+	Describe("Close", func() {
+		It("locks device that is open", func() {
+			err := fakeFs.WriteFileString("/dev/mapper/fake-name", "")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = encryptor.Close("fake-name")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands).To(Equal([][]string{{"cryptsetup", "luksClose", "fake-name"}}))
+		})
+
+		It("does nothing when device is not open", func() {
+			err := encryptor.Close("fake-name")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommands).To(BeEmpty())
+		})
+	})
+
+	Describe("Resize", func() {
+		It("resizes opened device", func() {
+			err := encryptor.Resize("fake-name", "fake-key")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeRunner.RunCommandsWithInput).To(Equal([][]string{
+				{"fake-key", "cryptsetup", "resize", "--key-file", "-", "fake-name"},
+			}))
+		})
+
+		It("returns error when resize fails", func() {
+			fakeRunner.AddCmdResult("fake-key cryptsetup resize --key-file - fake-name", fakesys.FakeCmdResult{Error: errors.New("fake-err")})
+
+			err := encryptor.Resize("fake-name", "fake-key")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-err"))
+		})
+	})
+})
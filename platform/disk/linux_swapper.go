@@ -0,0 +1,266 @@
+package disk
+
+import (
+	"strconv"
+	"strings"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	procSwapsPath    = "/proc/swaps"
+	zramDevicePath   = "/dev/zram0"
+	zramDiskSizePath = "/sys/block/zram0/disksize"
+
+	// Compressed memory is preferred over swap on disk
+	zramSwapPriority = "100"
+)
+
+type linuxSwapper struct {
+	runner boshsys.CmdRunner
+	fs     boshsys.FileSystem
+	logTag string
+	logger boshlog.Logger
+}
+
+func NewLinuxSwapper(runner boshsys.CmdRunner, fs boshsys.FileSystem, logger boshlog.Logger) Swapper {
+	return linuxSwapper{
+		runner: runner,
+		fs:     fs,
+		logTag: "linuxSwapper",
+		logger: logger,
+	}
+}
+
+func (s linuxSwapper) Reconcile(spec SwapSpec) error {
+	activeSwaps, err := s.activeSwaps()
+	if err != nil {
+		return err
+	}
+
+	// Swap partition is only turned off since ephemeral disk layout is kept
+	// and it is left to ephemeral disk partitioning to turn it on
+	if spec.Type != SwapTypePartition {
+		err = s.turnOffPartitions(activeSwaps)
+		if err != nil {
+			return bosherr.WrapError(err, "Turning off swap partitions")
+		}
+	}
+
+	var fileSizeInBytes, zramSizeInBytes uint64
+
+	switch spec.Type {
+	case SwapTypeFile:
+		fileSizeInBytes = spec.SizeInBytes
+	case SwapTypeZram:
+		zramSizeInBytes = spec.SizeInBytes
+	}
+
+	if spec.FilePath != "" {
+		err = s.reconcileFile(spec.FilePath, fileSizeInBytes, activeSwaps[spec.FilePath] != "")
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reconciling swap file '%s'", spec.FilePath)
+		}
+	}
+
+	err = s.reconcileZram(zramSizeInBytes, activeSwaps[zramDevicePath] != "")
+	if err != nil {
+		return bosherr.WrapError(err, "Reconciling zram swap")
+	}
+
+	return nil
+}
+
+func (s linuxSwapper) turnOffPartitions(activeSwaps map[string]string) error {
+	for path, swapType := range activeSwaps {
+		// zram device is listed as a partition too
+		if swapType != "partition" || path == zramDevicePath {
+			continue
+		}
+
+		s.logger.Info(s.logTag, "Turning off swap partition '%s'", path)
+
+		err := s.run("swapoff", path)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s linuxSwapper) reconcileFile(path string, sizeInBytes uint64, isActive bool) error {
+	sizeMatches := sizeInBytes > 0 && s.fileSize(path) == sizeInBytes
+
+	if isActive && sizeMatches {
+		s.logger.Debug(s.logTag, "Swap file '%s' is already active", path)
+		return nil
+	}
+
+	// Swap file is kept across reboots so it only has to be turned on again
+	if !isActive && sizeMatches {
+		err := s.run("swapon", path)
+		if err == nil {
+			return nil
+		}
+
+		s.logger.Warn(s.logTag, "Failed to turn on existing swap file '%s', recreating it: %s", path, err.Error())
+	}
+
+	if isActive {
+		err := s.run("swapoff", path)
+		if err != nil {
+			return err
+		}
+	}
+
+	err := s.fs.RemoveAll(path)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Removing '%s'", path)
+	}
+
+	if sizeInBytes == 0 {
+		return nil
+	}
+
+	s.logger.Info(s.logTag, "Creating %d bytes swap file '%s'", sizeInBytes, path)
+
+	// Swap file must not be readable by others even before it is allocated
+	err = s.fs.WriteFileString(path, "")
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating '%s'", path)
+	}
+
+	err = s.fs.Chmod(path, 0600)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Changing permissions of '%s'", path)
+	}
+
+	err = s.run("fallocate", "-l", strconv.FormatUint(sizeInBytes, 10), path)
+	if err != nil {
+		return err
+	}
+
+	err = s.run("mkswap", path)
+	if err != nil {
+		return err
+	}
+
+	return s.run("swapon", path)
+}
+
+func (s linuxSwapper) reconcileZram(sizeInBytes uint64, isActive bool) error {
+	if !isActive && sizeInBytes == 0 {
+		return nil
+	}
+
+	currentSizeInBytes := s.zramSize()
+
+	if isActive && sizeInBytes > 0 && currentSizeInBytes == sizeInBytes {
+		s.logger.Debug(s.logTag, "Zram swap is already active")
+		return nil
+	}
+
+	if isActive {
+		err := s.run("swapoff", zramDevicePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	if sizeInBytes == 0 {
+		return s.run("zramctl", "--reset", zramDevicePath)
+	}
+
+	s.logger.Info(s.logTag, "Creating %d bytes zram swap", sizeInBytes)
+
+	err := s.run("modprobe", "zram")
+	if err != nil {
+		return err
+	}
+
+	// Size of initialized device cannot be changed
+	if currentSizeInBytes > 0 {
+		err = s.run("zramctl", "--reset", zramDevicePath)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = s.run("zramctl", "--size", strconv.FormatUint(sizeInBytes, 10), zramDevicePath)
+	if err != nil {
+		return err
+	}
+
+	err = s.run("mkswap", zramDevicePath)
+	if err != nil {
+		return err
+	}
+
+	return s.run("swapon", "-p", zramSwapPriority, zramDevicePath)
+}
+
+// activeSwaps reads swap types by path from /proc/swaps which looks like:
+// Filename                Type       Size     Used  Priority
+// /dev/sda2               partition  1048572  0     -2
+func (s linuxSwapper) activeSwaps() (map[string]string, error) {
+	contents, err := s.fs.ReadFileString(procSwapsPath)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading %s", procSwapsPath)
+	}
+
+	activeSwaps := map[string]string{}
+
+	for i, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if i == 0 || len(fields) < 2 {
+			continue
+		}
+
+		activeSwaps[fields[0]] = fields[1]
+	}
+
+	return activeSwaps, nil
+}
+
+func (s linuxSwapper) fileSize(path string) uint64 {
+	if !s.fs.FileExists(path) {
+		return 0
+	}
+
+	info, err := s.fs.Stat(path)
+	if err != nil {
+		return 0
+	}
+
+	return uint64(info.Size())
+}
+
+func (s linuxSwapper) zramSize() uint64 {
+	if !s.fs.FileExists(zramDiskSizePath) {
+		return 0
+	}
+
+	contents, err := s.fs.ReadFileString(zramDiskSizePath)
+	if err != nil {
+		return 0
+	}
+
+	size, err := strconv.ParseUint(strings.TrimSpace(contents), 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return size
+}
+
+func (s linuxSwapper) run(cmdName string, args ...string) error {
+	_, _, _, err := s.runner.RunCommand(cmdName, args...)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Shelling out to %s", cmdName)
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+const procSwapsHeader = "Filename\t\t\t\tType\t\tSize\tUsed\tPriority\n"
+
+var _ = Describe("linuxSwapper", func() {
+	var (
+		runner  *fakesys.FakeCmdRunner
+		fs      *fakesys.FakeFileSystem
+		swapper Swapper
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		fs = fakesys.NewFakeFileSystem()
+		swapper = NewLinuxSwapper(runner, fs, boshlog.NewLogger(boshlog.LevelNone))
+
+		Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader)).To(Succeed())
+	})
+
+	Context("when swap file is requested", func() {
+		spec := SwapSpec{Type: SwapTypeFile, SizeInBytes: 8, FilePath: "/data/swapfile"}
+
+		It("creates and turns on swap file", func() {
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"fallocate", "-l", "8", "/data/swapfile"},
+				{"mkswap", "/data/swapfile"},
+				{"swapon", "/data/swapfile"},
+			}))
+		})
+
+		It("leaves active swap file of the same size alone", func() {
+			Expect(fs.WriteFileString("/data/swapfile", "12345678")).To(Succeed())
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/data/swapfile file 4 0 -2\n")).To(Succeed())
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(BeEmpty())
+			Expect(fs.FileExists("/data/swapfile")).To(BeTrue())
+		})
+
+		It("turns on existing swap file of the same size without recreating it", func() {
+			Expect(fs.WriteFileString("/data/swapfile", "12345678")).To(Succeed())
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"swapon", "/data/swapfile"},
+			}))
+		})
+
+		It("recreates existing swap file of the same size when it cannot be turned on", func() {
+			Expect(fs.WriteFileString("/data/swapfile", "12345678")).To(Succeed())
+			runner.AddCmdResult("swapon /data/swapfile", fakesys.FakeCmdResult{Error: errors.New("fake-swapon-err")})
+			runner.AddCmdResult("swapon /data/swapfile", fakesys.FakeCmdResult{})
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"swapon", "/data/swapfile"},
+				{"fallocate", "-l", "8", "/data/swapfile"},
+				{"mkswap", "/data/swapfile"},
+				{"swapon", "/data/swapfile"},
+			}))
+		})
+
+		It("recreates active swap file of a different size", func() {
+			Expect(fs.WriteFileString("/data/swapfile", "1234")).To(Succeed())
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/data/swapfile file 4 0 -2\n")).To(Succeed())
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"swapoff", "/data/swapfile"},
+				{"fallocate", "-l", "8", "/data/swapfile"},
+				{"mkswap", "/data/swapfile"},
+				{"swapon", "/data/swapfile"},
+			}))
+		})
+
+		It("turns off zram swap", func() {
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/dev/zram0 partition 4 0 100\n")).To(Succeed())
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(ContainElement([]string{"swapoff", "/dev/zram0"}))
+			Expect(runner.RunCommands).To(ContainElement([]string{"zramctl", "--reset", "/dev/zram0"}))
+		})
+
+		It("turns off swap partition without turning off zram swap", func() {
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/dev/sdb1 partition 4 0 -2\n")).To(Succeed())
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands[0]).To(Equal([]string{"swapoff", "/dev/sdb1"}))
+			Expect(runner.RunCommands).ToNot(ContainElement([]string{"swapoff", "/dev/zram0"}))
+		})
+
+		It("returns error when swap partition cannot be turned off", func() {
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/dev/sdb1 partition 4 0 -2\n")).To(Succeed())
+			runner.AddCmdResult("swapoff /dev/sdb1", fakesys.FakeCmdResult{Error: errors.New("fake-swapoff-err")})
+
+			err := swapper.Reconcile(spec)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Turning off swap partitions"))
+			Expect(err.Error()).To(ContainSubstring("fake-swapoff-err"))
+		})
+
+		It("returns error when swap file cannot be created", func() {
+			runner.AddCmdResult("fallocate -l 8 /data/swapfile", fakesys.FakeCmdResult{Error: errors.New("fake-fallocate-err")})
+
+			err := swapper.Reconcile(spec)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reconciling swap file '/data/swapfile'"))
+			Expect(err.Error()).To(ContainSubstring("fake-fallocate-err"))
+		})
+	})
+
+	Context("when zram swap is requested", func() {
+		spec := SwapSpec{Type: SwapTypeZram, SizeInBytes: 1024, FilePath: "/data/swapfile"}
+
+		It("turns off and removes swap file", func() {
+			Expect(fs.WriteFileString("/data/swapfile", "1234")).To(Succeed())
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/data/swapfile file 4 0 -2\n")).To(Succeed())
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands[0]).To(Equal([]string{"swapoff", "/data/swapfile"}))
+			Expect(fs.FileExists("/data/swapfile")).To(BeFalse())
+		})
+
+		It("turns off swap partition", func() {
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/dev/sdb1 partition 4 0 -2\n")).To(Succeed())
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands[0]).To(Equal([]string{"swapoff", "/dev/sdb1"}))
+		})
+
+		It("creates and turns on zram swap with higher priority", func() {
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"modprobe", "zram"},
+				{"zramctl", "--size", "1024", "/dev/zram0"},
+				{"mkswap", "/dev/zram0"},
+				{"swapon", "-p", "100", "/dev/zram0"},
+			}))
+		})
+
+		It("leaves active zram swap of the same size alone", func() {
+			Expect(fs.WriteFileString("/sys/block/zram0/disksize", "1024\n")).To(Succeed())
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/dev/zram0 partition 1 0 100\n")).To(Succeed())
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+
+		It("resizes active zram swap of a different size", func() {
+			Expect(fs.WriteFileString("/sys/block/zram0/disksize", "512\n")).To(Succeed())
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/dev/zram0 partition 1 0 100\n")).To(Succeed())
+
+			err := swapper.Reconcile(spec)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{
+				{"swapoff", "/dev/zram0"},
+				{"modprobe", "zram"},
+				{"zramctl", "--reset", "/dev/zram0"},
+				{"zramctl", "--size", "1024", "/dev/zram0"},
+				{"mkswap", "/dev/zram0"},
+				{"swapon", "-p", "100", "/dev/zram0"},
+			}))
+		})
+	})
+
+	Context("when swap partition is requested", func() {
+		It("does not touch zram when it is not in use", func() {
+			err := swapper.Reconcile(SwapSpec{Type: SwapTypePartition, SizeInBytes: 1024, FilePath: "/data/swapfile"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+
+		It("leaves active swap partition alone", func() {
+			Expect(fs.WriteFileString("/proc/swaps", procSwapsHeader+"/dev/sdb1 partition 4 0 -2\n")).To(Succeed())
+
+			err := swapper.Reconcile(SwapSpec{Type: SwapTypePartition, SizeInBytes: 1024, FilePath: "/data/swapfile"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+	})
+
+	It("returns error when active swaps cannot be read", func() {
+		fs.ReadFileError = errors.New("fake-read-err")
+
+		err := swapper.Reconcile(SwapSpec{Type: SwapTypeFile, SizeInBytes: 8})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Reading /proc/swaps"))
+	})
+})
@@ -0,0 +1,472 @@
+package disk
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const (
+	treeCopyBlockSize = 64 * 1024
+
+	// Checkpoints require flushing all written data
+	// so they are recorded only every so often
+	treeCopyCheckpointEntries = 1000
+	treeCopyCheckpointBytes   = 256 * 1024 * 1024
+)
+
+type linuxTreeCopier struct {
+	logTag string
+	logger boshlog.Logger
+}
+
+type treeEntryKey struct {
+	dev uint64
+	ino uint64
+}
+
+type treeCopyCheckpoint struct {
+	FromDir     string `json:"from_dir"`
+	ToDir       string `json:"to_dir"`
+	Entries     int    `json:"entries"`
+	LastPath    string `json:"last_path"`
+	CopiedBytes uint64 `json:"copied_bytes"`
+}
+
+type treeCopy struct {
+	fromDir string
+	toDir   string
+	options TreeCopyOptions
+
+	checkpoint  treeCopyCheckpoint
+	totalBytes  uint64
+	linkTargets map[treeEntryKey]string
+	dirs        []string
+
+	entriesSinceCheckpoint int
+	bytesSinceCheckpoint   uint64
+}
+
+func NewLinuxTreeCopier(logger boshlog.Logger) TreeCopier {
+	return linuxTreeCopier{logTag: "linuxTreeCopier", logger: logger}
+}
+
+func (c linuxTreeCopier) Copy(fromDir, toDir string, options TreeCopyOptions) error {
+	tc := &treeCopy{
+		fromDir:     fromDir,
+		toDir:       toDir,
+		options:     options,
+		checkpoint:  treeCopyCheckpoint{FromDir: fromDir, ToDir: toDir},
+		linkTargets: map[treeEntryKey]string{},
+	}
+
+	err := c.loadCheckpoint(tc)
+	if err != nil {
+		return err
+	}
+
+	err = c.measure(tc)
+	if err != nil {
+		return err
+	}
+
+	c.reportProgress(tc)
+
+	index := 0
+
+	err = filepath.Walk(fromDir, func(fromPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Walking '%s'", fromPath)
+		}
+
+		relPath, err := filepath.Rel(fromDir, fromPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Relativizing '%s'", fromPath)
+		}
+
+		index++
+
+		if index <= tc.checkpoint.Entries {
+			c.skipEntry(tc, relPath, info)
+			return nil
+		}
+
+		err = c.copyEntry(tc, relPath, info)
+		if err != nil {
+			return err
+		}
+
+		tc.checkpoint.Entries = index
+		tc.checkpoint.LastPath = relPath
+		tc.entriesSinceCheckpoint++
+
+		if tc.entriesSinceCheckpoint >= treeCopyCheckpointEntries || tc.bytesSinceCheckpoint >= treeCopyCheckpointBytes {
+			return c.saveCheckpoint(tc)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Copying '%s' to '%s'", fromDir, toDir)
+	}
+
+	err = c.saveCheckpoint(tc)
+	if err != nil {
+		return err
+	}
+
+	if options.Verify {
+		err = c.verify(tc)
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.CheckpointPath != "" {
+		err = os.Remove(options.CheckpointPath)
+		if err != nil && !os.IsNotExist(err) {
+			return bosherr.WrapError(err, "Removing copy checkpoint")
+		}
+	}
+
+	// Directory timestamps change while their contents are copied,
+	// so they are restored last and deepest first
+	for i := len(tc.dirs) - 1; i >= 0; i-- {
+		relPath := tc.dirs[i]
+
+		info, err := os.Lstat(filepath.Join(fromDir, relPath))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reading directory '%s'", relPath)
+		}
+
+		err = copyTreeEntryMetadata(filepath.Join(fromDir, relPath), filepath.Join(toDir, relPath), info)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c linuxTreeCopier) loadCheckpoint(tc *treeCopy) error {
+	if tc.options.CheckpointPath == "" {
+		return nil
+	}
+
+	contents, err := ioutil.ReadFile(tc.options.CheckpointPath)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return bosherr.WrapError(err, "Reading copy checkpoint")
+	}
+
+	var checkpoint treeCopyCheckpoint
+
+	err = json.Unmarshal(contents, &checkpoint)
+	if err != nil {
+		c.logger.Warn(c.logTag, "Ignoring unreadable copy checkpoint: %s", err.Error())
+		return nil
+	}
+
+	if checkpoint.FromDir != tc.fromDir || checkpoint.ToDir != tc.toDir {
+		c.logger.Warn(c.logTag, "Ignoring copy checkpoint for '%s' to '%s'", checkpoint.FromDir, checkpoint.ToDir)
+		return nil
+	}
+
+	tc.checkpoint = checkpoint
+
+	return nil
+}
+
+// measure sums up sizes of all regular files and makes sure
+// that checkpoint still matches contents of source directory
+func (c linuxTreeCopier) measure(tc *treeCopy) error {
+	index := 0
+	checkpointMatches := tc.checkpoint.Entries == 0
+	seen := map[treeEntryKey]bool{}
+
+	err := filepath.Walk(tc.fromDir, func(fromPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Walking '%s'", fromPath)
+		}
+
+		index++
+
+		if index == tc.checkpoint.Entries {
+			relPath, err := filepath.Rel(tc.fromDir, fromPath)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Relativizing '%s'", fromPath)
+			}
+
+			checkpointMatches = relPath == tc.checkpoint.LastPath
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		if key, linked := treeEntryLinkKey(info); linked {
+			if seen[key] {
+				return nil
+			}
+			seen[key] = true
+		}
+
+		tc.totalBytes += uint64(info.Size())
+
+		return nil
+	})
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Measuring '%s'", tc.fromDir)
+	}
+
+	if !checkpointMatches {
+		c.logger.Warn(c.logTag, "Copy checkpoint does not match '%s', starting over", tc.fromDir)
+		tc.checkpoint = treeCopyCheckpoint{FromDir: tc.fromDir, ToDir: tc.toDir}
+	} else if tc.checkpoint.Entries > 0 {
+		c.logger.Info(c.logTag, "Resuming copy of '%s' after '%s'", tc.fromDir, tc.checkpoint.LastPath)
+	}
+
+	return nil
+}
+
+// skipEntry records information about already copied entry
+// that is needed for copying remaining entries
+func (c linuxTreeCopier) skipEntry(tc *treeCopy, relPath string, info os.FileInfo) {
+	if info.IsDir() {
+		tc.dirs = append(tc.dirs, relPath)
+		return
+	}
+
+	if key, linked := treeEntryLinkKey(info); linked {
+		if _, found := tc.linkTargets[key]; !found {
+			tc.linkTargets[key] = filepath.Join(tc.toDir, relPath)
+		}
+	}
+}
+
+func (c linuxTreeCopier) copyEntry(tc *treeCopy, relPath string, info os.FileInfo) error {
+	fromPath := filepath.Join(tc.fromDir, relPath)
+	toPath := filepath.Join(tc.toDir, relPath)
+
+	if info.IsDir() {
+		tc.dirs = append(tc.dirs, relPath)
+
+		err := os.MkdirAll(toPath, 0700)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating directory '%s'", toPath)
+		}
+
+		return nil
+	}
+
+	// Entry might be left over from interrupted copy
+	err := os.Remove(toPath)
+	if err != nil && !os.IsNotExist(err) {
+		return bosherr.WrapErrorf(err, "Removing '%s'", toPath)
+	}
+
+	key, linked := treeEntryLinkKey(info)
+	if linked {
+		if linkTarget, found := tc.linkTargets[key]; found {
+			err = os.Link(linkTarget, toPath)
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Linking '%s' to '%s'", toPath, linkTarget)
+			}
+
+			return nil
+		}
+
+		tc.linkTargets[key] = toPath
+	}
+
+	mode := info.Mode()
+
+	switch {
+	case mode.IsRegular():
+		err = c.copyFileContents(fromPath, toPath)
+		if err != nil {
+			return err
+		}
+
+		tc.checkpoint.CopiedBytes += uint64(info.Size())
+		tc.bytesSinceCheckpoint += uint64(info.Size())
+		c.reportProgress(tc)
+
+	case mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(fromPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reading symlink '%s'", fromPath)
+		}
+
+		err = os.Symlink(target, toPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating symlink '%s'", toPath)
+		}
+
+	case mode&os.ModeSocket != 0:
+		// Sockets are recreated by processes that listen on them
+		c.logger.Debug(c.logTag, "Skipping socket '%s'", fromPath)
+		return nil
+
+	default:
+		err = makeTreeSpecialFile(toPath, info)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating special file '%s'", toPath)
+		}
+	}
+
+	return copyTreeEntryMetadata(fromPath, toPath, info)
+}
+
+// copyFileContents skips writing blocks of zeros
+// so that sparse files stay sparse
+func (c linuxTreeCopier) copyFileContents(fromPath, toPath string) error {
+	fromFile, err := os.Open(fromPath)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Opening '%s'", fromPath)
+	}
+
+	defer fromFile.Close()
+
+	toFile, err := os.OpenFile(toPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Creating '%s'", toPath)
+	}
+
+	defer toFile.Close()
+
+	block := make([]byte, treeCopyBlockSize)
+	zeros := make([]byte, treeCopyBlockSize)
+
+	var size int64
+
+	for {
+		n, err := io.ReadFull(fromFile, block)
+		if n > 0 {
+			if bytes.Equal(block[:n], zeros[:n]) {
+				_, err = toFile.Seek(int64(n), io.SeekCurrent)
+			} else {
+				_, err = toFile.Write(block[:n])
+			}
+
+			if err != nil {
+				return bosherr.WrapErrorf(err, "Writing '%s'", toPath)
+			}
+
+			size += int64(n)
+			continue
+		}
+
+		if err == io.EOF {
+			break
+		} else if err != nil && err != io.ErrUnexpectedEOF {
+			return bosherr.WrapErrorf(err, "Reading '%s'", fromPath)
+		}
+	}
+
+	// Trailing holes are not allocated by seeking alone
+	err = toFile.Truncate(size)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Truncating '%s'", toPath)
+	}
+
+	return toFile.Close()
+}
+
+func (c linuxTreeCopier) saveCheckpoint(tc *treeCopy) error {
+	tc.entriesSinceCheckpoint = 0
+	tc.bytesSinceCheckpoint = 0
+
+	if tc.options.CheckpointPath == "" {
+		return nil
+	}
+
+	// Copied data must reach disk before checkpoint claims it was copied
+	syncTreeCopy()
+
+	contents, err := json.Marshal(tc.checkpoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Marshalling copy checkpoint")
+	}
+
+	tmpPath := tc.options.CheckpointPath + ".tmp"
+
+	err = ioutil.WriteFile(tmpPath, contents, 0600)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing copy checkpoint")
+	}
+
+	err = os.Rename(tmpPath, tc.options.CheckpointPath)
+	if err != nil {
+		return bosherr.WrapError(err, "Writing copy checkpoint")
+	}
+
+	return nil
+}
+
+func (c linuxTreeCopier) reportProgress(tc *treeCopy) {
+	if tc.options.Progress != nil {
+		tc.options.Progress(tc.checkpoint.CopiedBytes, tc.totalBytes)
+	}
+}
+
+func (c linuxTreeCopier) verify(tc *treeCopy) error {
+	c.logger.Info(c.logTag, "Verifying copy of '%s' to '%s'", tc.fromDir, tc.toDir)
+
+	return filepath.Walk(tc.fromDir, func(fromPath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Walking '%s'", fromPath)
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(tc.fromDir, fromPath)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Relativizing '%s'", fromPath)
+		}
+
+		fromChecksum, err := c.checksum(fromPath)
+		if err != nil {
+			return err
+		}
+
+		toChecksum, err := c.checksum(filepath.Join(tc.toDir, relPath))
+		if err != nil {
+			return err
+		}
+
+		if !bytes.Equal(fromChecksum, toChecksum) {
+			return bosherr.Errorf("Verifying copy of '%s': checksums do not match", relPath)
+		}
+
+		return nil
+	})
+}
+
+func (c linuxTreeCopier) checksum(path string) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Opening '%s'", path)
+	}
+
+	defer file.Close()
+
+	hash := sha256.New()
+
+	_, err = io.Copy(hash, file)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Calculating checksum of '%s'", path)
+	}
+
+	return hash.Sum(nil), nil
+}
@@ -0,0 +1,266 @@
+// +build linux
+
+package disk_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+var _ = Describe("linuxTreeCopier", func() {
+	var (
+		tmpDir  string
+		fromDir string
+		toDir   string
+		copier  TreeCopier
+	)
+
+	BeforeEach(func() {
+		var err error
+
+		tmpDir, err = ioutil.TempDir("", "tree-copier")
+		Expect(err).ToNot(HaveOccurred())
+
+		fromDir = filepath.Join(tmpDir, "from")
+		toDir = filepath.Join(tmpDir, "to")
+		Expect(os.Mkdir(fromDir, 0750)).To(Succeed())
+		Expect(os.Mkdir(toDir, 0755)).To(Succeed())
+
+		copier = NewLinuxTreeCopier(boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	AfterEach(func() {
+		Expect(os.RemoveAll(tmpDir)).To(Succeed())
+	})
+
+	writeFile := func(relPath, contents string, mode os.FileMode) {
+		path := filepath.Join(fromDir, relPath)
+		Expect(os.MkdirAll(filepath.Dir(path), 0755)).To(Succeed())
+		Expect(ioutil.WriteFile(path, []byte(contents), mode)).To(Succeed())
+		Expect(os.Chmod(path, mode)).To(Succeed())
+	}
+
+	readFile := func(relPath string) string {
+		contents, err := ioutil.ReadFile(filepath.Join(toDir, relPath))
+		Expect(err).ToNot(HaveOccurred())
+		return string(contents)
+	}
+
+	It("copies files, directories and symlinks with their permissions and timestamps", func() {
+		writeFile("a.txt", "fake-a", 0640)
+		writeFile("dir/b.txt", "fake-b", 0755|os.ModeSetgid)
+		Expect(os.Symlink("../a.txt", filepath.Join(fromDir, "dir", "link"))).To(Succeed())
+
+		mtime := time.Date(2016, 2, 3, 4, 5, 6, 0, time.UTC)
+		Expect(os.Chtimes(filepath.Join(fromDir, "dir", "b.txt"), mtime, mtime)).To(Succeed())
+		Expect(os.Chtimes(filepath.Join(fromDir, "dir"), mtime, mtime)).To(Succeed())
+
+		err := copier.Copy(fromDir, toDir, TreeCopyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(readFile("a.txt")).To(Equal("fake-a"))
+		Expect(readFile("dir/b.txt")).To(Equal("fake-b"))
+
+		info, err := os.Stat(filepath.Join(toDir, "a.txt"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode()).To(Equal(os.FileMode(0640)))
+
+		info, err = os.Stat(filepath.Join(toDir, "dir", "b.txt"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode()).To(Equal(0755 | os.ModeSetgid))
+		Expect(info.ModTime().UTC()).To(Equal(mtime))
+
+		info, err = os.Stat(filepath.Join(toDir, "dir"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.ModTime().UTC()).To(Equal(mtime))
+
+		info, err = os.Stat(toDir)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Mode().Perm()).To(Equal(os.FileMode(0750)))
+
+		target, err := os.Readlink(filepath.Join(toDir, "dir", "link"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(target).To(Equal("../a.txt"))
+	})
+
+	It("preserves hardlinks", func() {
+		writeFile("a.txt", "fake-a", 0644)
+		Expect(os.Link(filepath.Join(fromDir, "a.txt"), filepath.Join(fromDir, "b.txt"))).To(Succeed())
+
+		err := copier.Copy(fromDir, toDir, TreeCopyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		aInfo, err := os.Stat(filepath.Join(toDir, "a.txt"))
+		Expect(err).ToNot(HaveOccurred())
+
+		bInfo, err := os.Stat(filepath.Join(toDir, "b.txt"))
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(os.SameFile(aInfo, bInfo)).To(BeTrue())
+	})
+
+	It("keeps sparse files sparse", func() {
+		file, err := os.Create(filepath.Join(fromDir, "sparse"))
+		Expect(err).ToNot(HaveOccurred())
+		_, err = file.WriteAt([]byte("fake-data"), 10*1024*1024)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(file.Truncate(20 * 1024 * 1024)).To(Succeed())
+		Expect(file.Close()).To(Succeed())
+
+		err = copier.Copy(fromDir, toDir, TreeCopyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		info, err := os.Stat(filepath.Join(toDir, "sparse"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Size()).To(Equal(int64(20 * 1024 * 1024)))
+		Expect(info.Sys().(*syscall.Stat_t).Blocks * 512).To(BeNumerically("<", 1024*1024))
+	})
+
+	It("preserves extended attributes", func() {
+		writeFile("a.txt", "fake-a", 0644)
+
+		err := unix.Lsetxattr(filepath.Join(fromDir, "a.txt"), "user.fake-attr", []byte("fake-value"), 0)
+		if err == unix.ENOTSUP || err == unix.EPERM {
+			Skip("Extended attributes are not supported by temporary directory")
+		}
+		Expect(err).ToNot(HaveOccurred())
+
+		err = copier.Copy(fromDir, toDir, TreeCopyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		value := make([]byte, 64)
+		size, err := unix.Lgetxattr(filepath.Join(toDir, "a.txt"), "user.fake-attr", value)
+		Expect(err).ToNot(HaveOccurred())
+		Expect(string(value[:size])).To(Equal("fake-value"))
+	})
+
+	It("preserves ownership", func() {
+		if os.Geteuid() != 0 {
+			Skip("Changing ownership requires root")
+		}
+
+		writeFile("a.txt", "fake-a", 0644)
+		Expect(os.Lchown(filepath.Join(fromDir, "a.txt"), 1234, 5678)).To(Succeed())
+
+		err := copier.Copy(fromDir, toDir, TreeCopyOptions{})
+		Expect(err).ToNot(HaveOccurred())
+
+		info, err := os.Lstat(filepath.Join(toDir, "a.txt"))
+		Expect(err).ToNot(HaveOccurred())
+		Expect(info.Sys().(*syscall.Stat_t).Uid).To(Equal(uint32(1234)))
+		Expect(info.Sys().(*syscall.Stat_t).Gid).To(Equal(uint32(5678)))
+	})
+
+	It("reports progress in bytes counting hardlinked files once", func() {
+		writeFile("a.txt", "12345", 0644)
+		writeFile("b.txt", "1234567890", 0644)
+		Expect(os.Link(filepath.Join(fromDir, "b.txt"), filepath.Join(fromDir, "c.txt"))).To(Succeed())
+
+		var reported [][2]uint64
+
+		err := copier.Copy(fromDir, toDir, TreeCopyOptions{
+			Progress: func(copiedBytes, totalBytes uint64) {
+				reported = append(reported, [2]uint64{copiedBytes, totalBytes})
+			},
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(reported).To(Equal([][2]uint64{{0, 15}, {5, 15}, {15, 15}}))
+	})
+
+	Context("when checkpoint path is given", func() {
+		var checkpointPath string
+
+		BeforeEach(func() {
+			checkpointPath = filepath.Join(toDir, ".checkpoint")
+
+			writeFile("a.txt", "fake-a", 0644)
+			writeFile("b.txt", "fake-b", 0644)
+			writeFile("c.txt", "fake-c", 0644)
+		})
+
+		writeCheckpoint := func(entries int, lastPath string) {
+			contents, err := json.Marshal(map[string]interface{}{
+				"from_dir":     fromDir,
+				"to_dir":       toDir,
+				"entries":      entries,
+				"last_path":    lastPath,
+				"copied_bytes": 6,
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(ioutil.WriteFile(checkpointPath, contents, 0600)).To(Succeed())
+		}
+
+		It("removes checkpoint after copy succeeds", func() {
+			err := copier.Copy(fromDir, toDir, TreeCopyOptions{CheckpointPath: checkpointPath})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(readFile("c.txt")).To(Equal("fake-c"))
+			Expect(checkpointPath).ToNot(BeAnExistingFile())
+		})
+
+		It("continues copy after last checkpointed entry", func() {
+			writeCheckpoint(2, "a.txt")
+
+			var reported [][2]uint64
+
+			err := copier.Copy(fromDir, toDir, TreeCopyOptions{
+				CheckpointPath: checkpointPath,
+				Progress: func(copiedBytes, totalBytes uint64) {
+					reported = append(reported, [2]uint64{copiedBytes, totalBytes})
+				},
+			})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(filepath.Join(toDir, "a.txt")).ToNot(BeAnExistingFile())
+			Expect(readFile("b.txt")).To(Equal("fake-b"))
+			Expect(readFile("c.txt")).To(Equal("fake-c"))
+			Expect(reported).To(Equal([][2]uint64{{6, 18}, {12, 18}, {18, 18}}))
+		})
+
+		It("starts over when checkpoint does not match source directory", func() {
+			writeCheckpoint(2, "other.txt")
+
+			err := copier.Copy(fromDir, toDir, TreeCopyOptions{CheckpointPath: checkpointPath})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(readFile("a.txt")).To(Equal("fake-a"))
+			Expect(readFile("b.txt")).To(Equal("fake-b"))
+		})
+	})
+
+	Context("when verification is requested", func() {
+		BeforeEach(func() {
+			writeFile("a.txt", "fake-a", 0644)
+		})
+
+		It("succeeds when copied files match", func() {
+			err := copier.Copy(fromDir, toDir, TreeCopyOptions{Verify: true})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("returns error when copied file does not match", func() {
+			err := copier.Copy(fromDir, toDir, TreeCopyOptions{
+				Verify: true,
+				Progress: func(copiedBytes, totalBytes uint64) {
+					if copiedBytes > 0 {
+						Expect(ioutil.WriteFile(filepath.Join(toDir, "a.txt"), []byte("fake-corrupted"), 0644)).To(Succeed())
+					}
+				},
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Verifying copy of 'a.txt': checksums do not match"))
+		})
+	})
+})
@@ -0,0 +1,39 @@
+package disk
+
+import (
+	"regexp"
+	"strconv"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// fstrim -v prints e.g. "/var/vcap/data: 1.4 GiB (1503238553 bytes) trimmed"
+var fstrimTrimmedBytesRegexp = regexp.MustCompile(`\((\d+) bytes\) trimmed`)
+
+type linuxTrimmer struct {
+	runner boshsys.CmdRunner
+}
+
+func NewLinuxTrimmer(runner boshsys.CmdRunner) Trimmer {
+	return linuxTrimmer{runner: runner}
+}
+
+func (t linuxTrimmer) Trim(mountPoint string) (uint64, error) {
+	stdout, _, _, err := t.runner.RunCommand("fstrim", "-v", mountPoint)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Trimming filesystem at '%s'", mountPoint)
+	}
+
+	match := fstrimTrimmedBytesRegexp.FindStringSubmatch(stdout)
+	if match == nil {
+		return 0, nil
+	}
+
+	trimmedBytes, err := strconv.ParseUint(match[1], 10, 64)
+	if err != nil {
+		return 0, bosherr.WrapErrorf(err, "Parsing trimmed bytes for '%s'", mountPoint)
+	}
+
+	return trimmedBytes, nil
+}
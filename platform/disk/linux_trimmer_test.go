@@ -0,0 +1,50 @@
+package disk_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("linuxTrimmer", func() {
+	var (
+		runner  *fakesys.FakeCmdRunner
+		trimmer Trimmer
+	)
+
+	BeforeEach(func() {
+		runner = fakesys.NewFakeCmdRunner()
+		trimmer = NewLinuxTrimmer(runner)
+	})
+
+	It("runs fstrim and returns number of trimmed bytes", func() {
+		runner.AddCmdResult("fstrim -v /fake-mount", fakesys.FakeCmdResult{
+			Stdout: "/fake-mount: 1.4 GiB (1503238553 bytes) trimmed on /dev/sdb1\n",
+		})
+
+		trimmedBytes, err := trimmer.Trim("/fake-mount")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(trimmedBytes).To(Equal(uint64(1503238553)))
+		Expect(runner.RunCommands).To(Equal([][]string{{"fstrim", "-v", "/fake-mount"}}))
+	})
+
+	It("returns zero bytes when fstrim output cannot be parsed", func() {
+		runner.AddCmdResult("fstrim -v /fake-mount", fakesys.FakeCmdResult{Stdout: "unexpected"})
+
+		trimmedBytes, err := trimmer.Trim("/fake-mount")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(trimmedBytes).To(BeZero())
+	})
+
+	It("returns error when fstrim fails", func() {
+		runner.AddCmdResult("fstrim -v /fake-mount", fakesys.FakeCmdResult{Error: errors.New("fake-fstrim-err")})
+
+		_, err := trimmer.Trim("/fake-mount")
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("Trimming filesystem at '/fake-mount': fake-fstrim-err"))
+	})
+})
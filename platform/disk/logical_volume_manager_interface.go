@@ -0,0 +1,19 @@
+package disk
+
+type LogicalVolumeSpec struct {
+	VolumeGroup string
+	Name        string
+
+	// Devices are pooled into volume group when it is created
+	Devices []string
+
+	// Striped spreads logical volume across all devices for throughput
+	Striped        bool
+	StripeSizeInKB uint64
+}
+
+type LogicalVolumeManager interface {
+	// Setup creates volume group and logical volume that spans all of it
+	// unless they already exist and returns device path of logical volume
+	Setup(spec LogicalVolumeSpec) (devicePath string, err error)
+}
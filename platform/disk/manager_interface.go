@@ -9,7 +9,13 @@ type Manager interface {
 	GetRootDevicePartitioner() Partitioner
 	GetPartedPartitioner() Partitioner
 	GetFormatter() Formatter
+	GetFilesystemChecker() FilesystemChecker
+	GetEncryptor() Encryptor
 	GetMounter() Mounter
 	GetMountsSearcher() MountsSearcher
+	GetTreeCopier() TreeCopier
+	GetTrimmer() Trimmer
+	GetSwapper() Swapper
+	GetLogicalVolumeManager() LogicalVolumeManager
 	GetDiskUtil(diskPath string) boshdevutil.DeviceUtil
 }
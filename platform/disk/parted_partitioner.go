@@ -75,6 +75,10 @@ func (p partedPartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, erro
 	return uint64(deviceSize), nil
 }
 
+func (p partedPartitioner) GrowPartition(devicePath string, partitionNumber int) error {
+	return growPartitionWithParted(p.cmdRunner, devicePath, partitionNumber)
+}
+
 func (p partedPartitioner) partitionsMatch(existingPartitions []existingPartition, desiredPartitions []Partition, deviceSizeInBytes uint64) bool {
 	if len(existingPartitions) < len(desiredPartitions) {
 		return false
@@ -336,3 +340,45 @@ func (p partedPartitioner) createMapperPartition(devicePath string) error {
 	detectPartitionRetryStrategy := NewPartitionStrategy(detectPartitionRetryable, p.timeService, p.logger)
 	return detectPartitionRetryStrategy.Try()
 }
+
+func growPartitionWithParted(cmdRunner boshsys.CmdRunner, devicePath string, partitionNumber int) error {
+	// GPT keeps backup header at the old end of the device until parted is allowed to fix it
+	_, _, _, err := cmdRunner.RunCommandWithInput("Fix\n", "parted", "---pretend-input-tty", devicePath, "print")
+	if err != nil {
+		return bosherr.WrapError(err, "Fixing partition table using parted")
+	}
+
+	// parted asks for confirmation when partition is mounted
+	_, _, _, err = cmdRunner.RunCommandWithInput(
+		"Yes\n",
+		"parted",
+		"---pretend-input-tty",
+		devicePath,
+		"resizepart",
+		strconv.Itoa(partitionNumber),
+		"100%",
+	)
+	if err != nil {
+		return bosherr.WrapError(err, "Resizing partition using parted")
+	}
+
+	return refreshPartitions(cmdRunner, devicePath)
+}
+
+// refreshPartitions makes kernel pick up new sizes of partitions
+// which are in use and therefore cannot be re-read as a whole
+func refreshPartitions(cmdRunner boshsys.CmdRunner, devicePath string) error {
+	var err error
+
+	if strings.Contains(devicePath, "/dev/mapper/") {
+		_, _, _, err = cmdRunner.RunCommand("kpartx", "-u", devicePath)
+	} else {
+		_, _, _, err = cmdRunner.RunCommand("partx", "-u", devicePath)
+	}
+
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Refreshing partitions of `%s'", devicePath)
+	}
+
+	return nil
+}
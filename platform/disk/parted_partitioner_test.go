@@ -625,4 +625,30 @@ var _ = Describe("PartedPartitioner", func() {
 			Expect(num).To(Equal(uint64(123)))
 		})
 	})
+
+	Describe("GrowPartition", func() {
+		It("fixes partition table, resizes partition and refreshes partitions", func() {
+			err := partitioner.GrowPartition("/dev/sdf", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCmdRunner.RunCommandsWithInput).To(Equal([][]string{
+				{"Fix\n", "parted", "---pretend-input-tty", "/dev/sdf", "print"},
+				{"Yes\n", "parted", "---pretend-input-tty", "/dev/sdf", "resizepart", "1", "100%"},
+			}))
+			Expect(fakeCmdRunner.RunCommands).To(Equal([][]string{{"partx", "-u", "/dev/sdf"}}))
+		})
+
+		It("returns error when resizing partition fails", func() {
+			fakeCmdRunner.AddCmdResult(
+				"Yes\n parted ---pretend-input-tty /dev/sdf resizepart 1 100%",
+				fakesys.FakeCmdResult{Error: errors.New("fake-parted-err")},
+			)
+
+			err := partitioner.GrowPartition("/dev/sdf", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Resizing partition using parted"))
+			Expect(err.Error()).To(ContainSubstring("fake-parted-err"))
+			Expect(fakeCmdRunner.RunCommands).To(BeEmpty())
+		})
+	})
 })
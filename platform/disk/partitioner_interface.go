@@ -19,6 +19,10 @@ type Partition struct {
 type Partitioner interface {
 	Partition(devicePath string, partitions []Partition) (err error)
 	GetDeviceSizeInBytes(devicePath string) (size uint64, err error)
+
+	// GrowPartition extends partition to the end of the device
+	// without moving its start so that data on it is preserved
+	GrowPartition(devicePath string, partitionNumber int) (err error)
 }
 
 func (p Partition) String() string {
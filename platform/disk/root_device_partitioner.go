@@ -118,6 +118,10 @@ func (p rootDevicePartitioner) GetDeviceSizeInBytes(devicePath string) (uint64,
 	return remainingSizeInBytes, nil
 }
 
+func (p rootDevicePartitioner) GrowPartition(devicePath string, partitionNumber int) error {
+	return growPartitionWithParted(p.cmdRunner, devicePath, partitionNumber)
+}
+
 func (p rootDevicePartitioner) getPartitions(devicePath string) (
 	partitions []existingPartition,
 	deviceFullSizeInBytes uint64,
@@ -445,4 +445,15 @@ var _ = Describe("rootDevicePartitioner", func() {
 			})
 		})
 	})
+
+	Describe("GrowPartition", func() {
+		It("resizes partition using parted", func() {
+			err := partitioner.GrowPartition("/dev/sda", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fakeCmdRunner.RunCommandsWithInput).To(ContainElement(
+				[]string{"Yes\n", "parted", "---pretend-input-tty", "/dev/sda", "resizepart", "1", "100%"},
+			))
+		})
+	})
 })
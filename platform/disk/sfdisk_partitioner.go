@@ -124,6 +124,15 @@ func (p sfdiskPartitioner) GetDeviceSizeInBytes(devicePath string) (uint64, erro
 	return p.convertFromKbToBytes(sizeInKb), nil
 }
 
+func (p sfdiskPartitioner) GrowPartition(devicePath string, partitionNumber int) error {
+	_, _, _, err := p.cmdRunner.RunCommandWithInput(",+\n", "sfdisk", "--no-reread", "-N", strconv.Itoa(partitionNumber), devicePath)
+	if err != nil {
+		return bosherr.WrapError(err, "Shelling out to sfdisk")
+	}
+
+	return refreshPartitions(p.cmdRunner, devicePath)
+}
+
 func (p sfdiskPartitioner) diskMatchesPartitions(devicePath string, partitionsToMatch []Partition) (bool, error) {
 	existingPartitions, err := p.getPartitions(devicePath)
 	if err != nil {
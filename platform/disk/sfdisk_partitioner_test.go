@@ -90,6 +90,42 @@ var _ = Describe("sfdiskPartitioner", func() {
 		Expect(runner.RunCommandsWithInput[0]).To(Equal([]string{",512,S\n,1024,L\n,,L\n", "sfdisk", "-uM", "/dev/sda"}))
 	})
 
+	Describe("GrowPartition", func() {
+		It("extends partition to the end of device and refreshes partitions", func() {
+			err := partitioner.GrowPartition("/dev/sda", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommandsWithInput).To(Equal([][]string{
+				{",+\n", "sfdisk", "--no-reread", "-N", "1", "/dev/sda"},
+			}))
+			Expect(runner.RunCommands).To(Equal([][]string{{"partx", "-u", "/dev/sda"}}))
+		})
+
+		It("refreshes multipath partitions with kpartx", func() {
+			err := partitioner.GrowPartition("/dev/mapper/xxxxxx", 1)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(runner.RunCommands).To(Equal([][]string{{"kpartx", "-u", "/dev/mapper/xxxxxx"}}))
+		})
+
+		It("returns error when sfdisk fails", func() {
+			runner.AddCmdResult(",+\n sfdisk --no-reread -N 1 /dev/sda", fakesys.FakeCmdResult{Error: errors.New("fake-sfdisk-err")})
+
+			err := partitioner.GrowPartition("/dev/sda", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-sfdisk-err"))
+			Expect(runner.RunCommands).To(BeEmpty())
+		})
+
+		It("returns error when refreshing partitions fails", func() {
+			runner.AddCmdResult("partx -u /dev/sda", fakesys.FakeCmdResult{Error: errors.New("fake-partx-err")})
+
+			err := partitioner.GrowPartition("/dev/sda", 1)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-partx-err"))
+		})
+	})
+
 	Context("when we get an error occurs", func() {
 		Context("during get partitions", func() {
 			It("raises error", func() {
@@ -0,0 +1,30 @@
+package disk
+
+type SwapType string
+
+const (
+	// SwapTypePartition swap is carved out of ephemeral disk when it is partitioned
+	SwapTypePartition SwapType = "partition"
+
+	// SwapTypeFile swap is backed by a file on ephemeral data directory
+	SwapTypeFile SwapType = "file"
+
+	// SwapTypeZram swap is backed by compressed memory
+	SwapTypeZram SwapType = "zram"
+)
+
+type SwapSpec struct {
+	Type        SwapType
+	SizeInBytes uint64
+
+	// FilePath is where swap file is kept regardless of swap type
+	// so that it can be removed when other type is chosen
+	FilePath string
+}
+
+type Swapper interface {
+	// Reconcile sets up file or zram swap according to spec
+	// and turns off those that are no longer wanted including swap partitions;
+	// swap partitions are only turned on by ephemeral disk partitioning
+	Reconcile(spec SwapSpec) error
+}
@@ -0,0 +1,20 @@
+package disk
+
+type TreeCopyOptions struct {
+	// When set copy progress is periodically recorded in this file
+	// so that interrupted copy continues where it left off
+	CheckpointPath string
+
+	// When set to true contents of copied regular files
+	// are compared by checksum after everything is copied
+	Verify bool
+
+	// Called after each copied regular file
+	Progress func(copiedBytes, totalBytes uint64)
+}
+
+type TreeCopier interface {
+	// Copy copies directory tree preserving ownership, permissions,
+	// timestamps, extended attributes (including ACLs), hardlinks and sparse files
+	Copy(fromDir, toDir string, options TreeCopyOptions) (err error)
+}
@@ -0,0 +1,121 @@
+package disk
+
+import (
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+)
+
+func treeEntryLinkKey(info os.FileInfo) (treeEntryKey, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok || info.IsDir() || uint64(stat.Nlink) < 2 {
+		return treeEntryKey{}, false
+	}
+
+	return treeEntryKey{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
+
+func makeTreeSpecialFile(toPath string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return bosherr.Errorf("Reading device number of '%s'", info.Name())
+	}
+
+	return unix.Mknod(toPath, stat.Mode, int(stat.Rdev))
+}
+
+func copyTreeEntryMetadata(fromPath, toPath string, info os.FileInfo) error {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return bosherr.Errorf("Reading ownership of '%s'", fromPath)
+	}
+
+	// Changing owner clears setuid and setgid bits so it must happen before chmod
+	err := os.Lchown(toPath, int(stat.Uid), int(stat.Gid))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Changing owner of '%s'", toPath)
+	}
+
+	if info.Mode()&os.ModeSymlink == 0 {
+		err = os.Chmod(toPath, info.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky))
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Changing permissions of '%s'", toPath)
+		}
+	}
+
+	err = copyTreeEntryXattrs(fromPath, toPath)
+	if err != nil {
+		return err
+	}
+
+	times := []unix.Timespec{
+		unix.NsecToTimespec(syscall.TimespecToNsec(stat.Atim)),
+		unix.NsecToTimespec(syscall.TimespecToNsec(stat.Mtim)),
+	}
+
+	err = unix.UtimesNanoAt(unix.AT_FDCWD, toPath, times, unix.AT_SYMLINK_NOFOLLOW)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Changing timestamps of '%s'", toPath)
+	}
+
+	return nil
+}
+
+// copyTreeEntryXattrs also copies POSIX ACLs
+// since they are stored as system.posix_acl_* attributes
+func copyTreeEntryXattrs(fromPath, toPath string) error {
+	size, err := unix.Llistxattr(fromPath, nil)
+	if err == unix.ENOTSUP || size == 0 {
+		return nil
+	} else if err != nil {
+		return bosherr.WrapErrorf(err, "Listing extended attributes of '%s'", fromPath)
+	}
+
+	names := make([]byte, size)
+
+	size, err = unix.Llistxattr(fromPath, names)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Listing extended attributes of '%s'", fromPath)
+	}
+
+	for _, name := range splitXattrNames(names[:size]) {
+		valueSize, err := unix.Lgetxattr(fromPath, name, nil)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reading extended attribute '%s' of '%s'", name, fromPath)
+		}
+
+		value := make([]byte, valueSize)
+
+		valueSize, err = unix.Lgetxattr(fromPath, name, value)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Reading extended attribute '%s' of '%s'", name, fromPath)
+		}
+
+		err = unix.Lsetxattr(toPath, name, value[:valueSize], 0)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing extended attribute '%s' of '%s'", name, toPath)
+		}
+	}
+
+	return nil
+}
+
+func splitXattrNames(names []byte) []string {
+	var result []string
+
+	for _, name := range strings.Split(string(names), "\x00") {
+		if name != "" {
+			result = append(result, name)
+		}
+	}
+
+	return result
+}
+
+func syncTreeCopy() {
+	unix.Sync()
+}
@@ -0,0 +1,22 @@
+// +build !linux
+
+package disk
+
+import (
+	"errors"
+	"os"
+)
+
+func treeEntryLinkKey(info os.FileInfo) (treeEntryKey, bool) {
+	return treeEntryKey{}, false
+}
+
+func makeTreeSpecialFile(toPath string, info os.FileInfo) error {
+	return errors.New("Copying special files is only supported on linux")
+}
+
+func copyTreeEntryMetadata(fromPath, toPath string, info os.FileInfo) error {
+	return errors.New("Copying file metadata is only supported on linux")
+}
+
+func syncTreeCopy() {}
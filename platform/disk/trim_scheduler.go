@@ -0,0 +1,139 @@
+package disk
+
+import (
+	"sync"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+const trimSchedulerLogTag = "TrimScheduler"
+
+// TrimResult describes the last trim of a mount point
+type TrimResult struct {
+	TrimmedAt    time.Time
+	TrimmedBytes uint64
+	Err          error
+}
+
+// TrimScheduler periodically discards unused blocks so that
+// thin-provisioned volumes give space back to the IaaS
+type TrimScheduler struct {
+	trimmer     Trimmer
+	mounter     Mounter
+	timeService clock.Clock
+	logger      boshlog.Logger
+
+	resultsLock sync.RWMutex
+	results     map[string]TrimResult
+}
+
+func NewTrimScheduler(
+	trimmer Trimmer,
+	mounter Mounter,
+	timeService clock.Clock,
+	logger boshlog.Logger,
+) *TrimScheduler {
+	return &TrimScheduler{
+		trimmer:     trimmer,
+		mounter:     mounter,
+		timeService: timeService,
+		logger:      logger,
+		results:     map[string]TrimResult{},
+	}
+}
+
+// Start trims mount points every interval until stopCh is closed;
+// mount points are listed on every run since persistent disks
+// are attached and detached while agent is running
+func (s *TrimScheduler) Start(interval time.Duration, mountPoints func() ([]string, error), stopCh chan struct{}) {
+	go func() {
+		ticker := s.timeService.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C():
+				currentMountPoints, err := mountPoints()
+				if err != nil {
+					s.logger.Error(trimSchedulerLogTag, "Failed to list mount points to trim: %s", err.Error())
+					continue
+				}
+
+				s.Trim(currentMountPoints)
+			case <-stopCh:
+				return
+			}
+		}
+	}()
+}
+
+// Trim trims mount points that have a filesystem mounted;
+// directories that are not mount points are skipped since
+// fstrim would otherwise trim filesystem containing them.
+// Results of detached disks are forgotten.
+func (s *TrimScheduler) Trim(mountPoints []string) {
+	attempted := map[string]struct{}{}
+
+	for _, mountPoint := range mountPoints {
+		_, isMountPoint, err := s.mounter.IsMountPoint(mountPoint)
+		if err != nil {
+			s.recordResult(mountPoint, TrimResult{TrimmedAt: s.timeService.Now(), Err: err})
+			attempted[mountPoint] = struct{}{}
+			continue
+		}
+
+		if !isMountPoint {
+			s.logger.Debug(trimSchedulerLogTag, "Skipping trim of '%s' since it is not a mount point", mountPoint)
+			continue
+		}
+
+		trimmedBytes, err := s.trimmer.Trim(mountPoint)
+		if err != nil {
+			s.logger.Error(trimSchedulerLogTag, "Failed to trim '%s': %s", mountPoint, err.Error())
+		} else {
+			s.logger.Info(trimSchedulerLogTag, "Trimmed %d bytes from '%s'", trimmedBytes, mountPoint)
+		}
+
+		s.recordResult(mountPoint, TrimResult{
+			TrimmedAt:    s.timeService.Now(),
+			TrimmedBytes: trimmedBytes,
+			Err:          err,
+		})
+		attempted[mountPoint] = struct{}{}
+	}
+
+	s.keepResults(attempted)
+}
+
+// Results are keyed by mount point
+func (s *TrimScheduler) Results() map[string]TrimResult {
+	s.resultsLock.RLock()
+	defer s.resultsLock.RUnlock()
+
+	results := make(map[string]TrimResult, len(s.results))
+	for mountPoint, result := range s.results {
+		results[mountPoint] = result
+	}
+
+	return results
+}
+
+func (s *TrimScheduler) recordResult(mountPoint string, result TrimResult) {
+	s.resultsLock.Lock()
+	defer s.resultsLock.Unlock()
+
+	s.results[mountPoint] = result
+}
+
+func (s *TrimScheduler) keepResults(mountPoints map[string]struct{}) {
+	s.resultsLock.Lock()
+	defer s.resultsLock.Unlock()
+
+	for mountPoint := range s.results {
+		if _, found := mountPoints[mountPoint]; !found {
+			delete(s.results, mountPoint)
+		}
+	}
+}
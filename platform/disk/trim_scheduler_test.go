@@ -0,0 +1,121 @@
+package disk_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock/fakeclock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/disk"
+	fakedisk "github.com/cloudfoundry/bosh-agent/platform/disk/fakes"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type trimSchedulerMounter struct {
+	fakedisk.FakeMounter
+	mountPoints map[string]bool
+}
+
+func (m *trimSchedulerMounter) IsMountPoint(path string) (string, bool, error) {
+	return "", m.mountPoints[path], nil
+}
+
+var _ = Describe("TrimScheduler", func() {
+	var (
+		trimmer     *fakedisk.FakeTrimmer
+		mounter     *trimSchedulerMounter
+		timeService *fakeclock.FakeClock
+		scheduler   *TrimScheduler
+	)
+
+	BeforeEach(func() {
+		trimmer = &fakedisk.FakeTrimmer{
+			TrimTrimmedBytes: map[string]uint64{"/fake-data": 1024},
+			TrimErrs:         map[string]error{},
+		}
+		mounter = &trimSchedulerMounter{
+			mountPoints: map[string]bool{"/fake-data": true, "/fake-store": true},
+		}
+		timeService = fakeclock.NewFakeClock(time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC))
+		scheduler = NewTrimScheduler(trimmer, mounter, timeService, boshlog.NewLogger(boshlog.LevelNone))
+	})
+
+	Describe("Trim", func() {
+		It("trims mount points and records results", func() {
+			trimmer.TrimErrs["/fake-store"] = errors.New("fake-trim-err")
+
+			scheduler.Trim([]string{"/fake-data", "/fake-store"})
+
+			Expect(trimmer.TrimMountPoints).To(Equal([]string{"/fake-data", "/fake-store"}))
+			Expect(scheduler.Results()).To(Equal(map[string]TrimResult{
+				"/fake-data": {
+					TrimmedAt:    timeService.Now(),
+					TrimmedBytes: 1024,
+				},
+				"/fake-store": {
+					TrimmedAt: timeService.Now(),
+					Err:       errors.New("fake-trim-err"),
+				},
+			}))
+		})
+
+		It("skips directories that are not mount points", func() {
+			mounter.mountPoints["/fake-store"] = false
+
+			scheduler.Trim([]string{"/fake-data", "/fake-store"})
+
+			Expect(trimmer.TrimMountPoints).To(Equal([]string{"/fake-data"}))
+			Expect(scheduler.Results()).ToNot(HaveKey("/fake-store"))
+		})
+
+		It("forgets results of disks that were detached", func() {
+			scheduler.Trim([]string{"/fake-data", "/fake-store"})
+			Expect(scheduler.Results()).To(HaveKey("/fake-store"))
+
+			mounter.mountPoints["/fake-store"] = false
+			scheduler.Trim([]string{"/fake-data", "/fake-store"})
+			Expect(scheduler.Results()).ToNot(HaveKey("/fake-store"))
+
+			scheduler.Trim([]string{})
+			Expect(scheduler.Results()).To(BeEmpty())
+		})
+	})
+
+	Describe("Start", func() {
+		It("trims mount points listed on every run until stopped", func() {
+			mountPoints := make(chan []string, 2)
+			mountPoints <- []string{"/fake-data"}
+			mountPoints <- []string{"/fake-data", "/fake-store"}
+
+			stopCh := make(chan struct{})
+			scheduler.Start(time.Hour, func() ([]string, error) { return <-mountPoints, nil }, stopCh)
+
+			timeService.WaitForWatcherAndIncrement(time.Hour)
+			Eventually(func() map[string]TrimResult { return scheduler.Results() }).Should(HaveKey("/fake-data"))
+			Expect(scheduler.Results()).ToNot(HaveKey("/fake-store"))
+
+			timeService.WaitForWatcherAndIncrement(time.Hour)
+			Eventually(func() map[string]TrimResult { return scheduler.Results() }).Should(HaveKey("/fake-store"))
+
+			close(stopCh)
+		})
+
+		It("skips runs when mount points cannot be listed", func() {
+			listed := make(chan struct{}, 1)
+
+			stopCh := make(chan struct{})
+			scheduler.Start(time.Hour, func() ([]string, error) {
+				listed <- struct{}{}
+				return nil, errors.New("fake-list-err")
+			}, stopCh)
+
+			timeService.WaitForWatcherAndIncrement(time.Hour)
+			Eventually(listed).Should(Receive())
+			Consistently(func() map[string]TrimResult { return scheduler.Results() }).Should(BeEmpty())
+
+			close(stopCh)
+		})
+	})
+})
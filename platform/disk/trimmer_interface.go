@@ -0,0 +1,7 @@
+package disk
+
+type Trimmer interface {
+	// Trim discards unused blocks of filesystem mounted at mountPoint
+	// and returns number of discarded bytes
+	Trim(mountPoint string) (trimmedBytes uint64, err error)
+}
@@ -9,6 +9,9 @@ import (
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -69,7 +72,7 @@ func NewDummyPlatform(
 		copier:             boshcmd.NewGenericCpCopier(fs, logger),
 		dirProvider:        dirProvider,
 		devicePathResolver: devicePathResolver,
-		vitalsService:      boshvitals.NewService(collector, dirProvider),
+		vitalsService:      boshvitals.NewService(collector, dirProvider, boshsettings.NewManagedDisks(fs, dirProvider.BoshDir()), nil, logger),
 		certManager:        boshcert.NewDummyCertManager(fs, cmdRunner, 0, logger),
 		logger:             logger,
 		auditLogger:        auditLogger,
@@ -104,6 +107,14 @@ func (p dummyPlatform) GetVitalsService() (service boshvitals.Service) {
 	return p.vitalsService
 }
 
+func (p dummyPlatform) GetManagedDisks() boshsettings.ManagedDisks {
+	return boshsettings.NewManagedDisks(p.fs, p.dirProvider.BoshDir())
+}
+
+func (p dummyPlatform) GetFilesystemCheckHistory() boshdisk.FilesystemCheckHistory {
+	return boshdisk.NewFilesystemCheckHistory(p.fs, p.dirProvider.BoshDir())
+}
+
 func (p dummyPlatform) GetDevicePathResolver() (devicePathResolver boshdpresolv.DevicePathResolver) {
 	return p.devicePathResolver
 }
@@ -160,6 +171,14 @@ func (p dummyPlatform) SetupNetworking(networks boshsettings.Networks) (err erro
 	return
 }
 
+func (p dummyPlatform) DetectNetworkDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (drift boshnet.Drift, err error) {
+	return
+}
+
+func (p dummyPlatform) RepairNetworking(networks boshsettings.Networks) (err error) {
+	return
+}
+
 func (p dummyPlatform) GetConfiguredNetworkInterfaces() (interfaces []string, err error) {
 	return
 }
@@ -176,7 +195,7 @@ func (p dummyPlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
 
-func (p dummyPlatform) SetupEphemeralDiskWithPath(devicePath string, desiredSwapSizeInBytes *uint64) (err error) {
+func (p dummyPlatform) SetupEphemeralDiskWithPath(devicePath string, desiredSwapSizeInBytes *uint64, mountOptions ...string) (err error) {
 	return
 }
 
@@ -184,6 +203,14 @@ func (p dummyPlatform) SetupRawEphemeralDisks(devices []boshsettings.DiskSetting
 	return
 }
 
+func (p dummyPlatform) SetupPooledEphemeralDisk(ephemeralDiskPath string, rawDevices []boshsettings.DiskSettings, pool boshsettings.EphemeralDiskPool, mountOptions ...string) (err error) {
+	return
+}
+
+func (p dummyPlatform) SetupSwap(swap boshsettings.Swap) (err error) {
+	return
+}
+
 func (p dummyPlatform) SetupDataDir() error {
 	dataDir := p.dirProvider.DataDir()
 
@@ -242,6 +269,17 @@ func (p dummyPlatform) MountPersistentDisk(diskSettings boshsettings.DiskSetting
 	}
 
 	managedSettingsPath := filepath.Join(p.dirProvider.BoshDir(), "managed_disk_settings.json")
+	isNamedDisk := filepath.Dir(mountPoint) == p.dirProvider.StoreDir()
+
+	if isMountPoint && isNamedDisk {
+		for _, existingMount := range mounts {
+			if existingMount.MountDir == mountPoint && existingMount.DiskCid == diskSettings.ID {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("Mount point '%s' is already used by another disk", mountPoint)
+	}
 
 	if isMountPoint {
 		currentManagedDisk, err := p.fs.ReadFileString(managedSettingsPath)
@@ -274,7 +312,18 @@ func (p dummyPlatform) MountPersistentDisk(diskSettings boshsettings.DiskSetting
 		return err
 	}
 
-	p.fs.WriteFileString(managedSettingsPath, diskSettings.ID)
+	if isNamedDisk {
+		err = p.GetManagedDisks().Add(boshsettings.ManagedDisk{
+			DiskCID:    diskSettings.ID,
+			Name:       filepath.Base(mountPoint),
+			MountPoint: mountPoint,
+		})
+		if err != nil {
+			return err
+		}
+	} else {
+		p.fs.WriteFileString(managedSettingsPath, diskSettings.ID)
+	}
 
 	return p.fs.WriteFile(p.mountsPath(), mountsJSON)
 }
@@ -302,6 +351,11 @@ func (p dummyPlatform) UnmountPersistentDisk(diskSettings boshsettings.DiskSetti
 		return false, err
 	}
 
+	err = p.GetManagedDisks().Remove(diskSettings.ID)
+	if err != nil {
+		return false, err
+	}
+
 	return true, nil
 }
 
@@ -317,7 +371,19 @@ func (p dummyPlatform) GetFilesContentsFromDisk(diskPath string, fileNames []str
 	return
 }
 
-func (p dummyPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p dummyPlatform) ResizePersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) (err error) {
+	return
+}
+
+func (p dummyPlatform) FreezePersistentDisk(mountPoint string) (err error) {
+	return
+}
+
+func (p dummyPlatform) ThawPersistentDisk(mountPoint string) (err error) {
+	return
+}
+
+func (p dummyPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string, progress func(copiedBytes, totalBytes uint64)) (err error) {
 	diskMigrationsPath := filepath.Join(p.dirProvider.BoshDir(), "disk_migrations.json")
 	var diskMigrations []diskMigration
 	if p.fs.FileExists(diskMigrationsPath) {
@@ -496,3 +562,5 @@ func (p dummyPlatform) existingMounts() ([]mount, error) {
 func (p dummyPlatform) SetupRecordsJSONPermission(path string) error {
 	return nil
 }
+
+func (p dummyPlatform) StartDiskTrimming(stopCh chan struct{}) {}
@@ -8,6 +8,9 @@ import (
 	"github.com/cloudfoundry/bosh-agent/platform"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	fakecert "github.com/cloudfoundry/bosh-agent/platform/cert/fakes"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	fakevitals "github.com/cloudfoundry/bosh-agent/platform/vitals/fakes"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -57,14 +60,24 @@ type FakePlatform struct {
 
 	SetTimeWithNtpServersServers []string
 
-	SetupEphemeralDiskWithPathDevicePath string
-	SetupEphemeralDiskWithPathSwapSize   *uint64
-	SetupEphemeralDiskWithPathErr        error
+	SetupEphemeralDiskWithPathDevicePath   string
+	SetupEphemeralDiskWithPathSwapSize     *uint64
+	SetupEphemeralDiskWithPathMountOptions []string
+	SetupEphemeralDiskWithPathErr          error
 
 	SetupRawEphemeralDisksDevices   []boshsettings.DiskSettings
 	SetupRawEphemeralDisksErr       error
 	SetupRawEphemeralDisksCallCount int
 
+	SetupPooledEphemeralDiskEphemeralDiskPath string
+	SetupPooledEphemeralDiskRawDevices        []boshsettings.DiskSettings
+	SetupPooledEphemeralDiskPool              boshsettings.EphemeralDiskPool
+	SetupPooledEphemeralDiskMountOptions      []string
+	SetupPooledEphemeralDiskErr               error
+
+	SetupSwapSwaps []boshsettings.Swap
+	SetupSwapErr   error
+
 	SetupDataDirCalled bool
 	SetupDataDirErr    error
 
@@ -87,6 +100,15 @@ type FakePlatform struct {
 	SetupNetworkingNetworks boshsettings.Networks
 	SetupNetworkingErr      error
 
+	DetectNetworkDriftNetworks  boshsettings.Networks
+	DetectNetworkDriftValidator boship.InterfaceAddressesValidator
+	DetectNetworkDriftDrift     boshnet.Drift
+	DetectNetworkDriftErr       error
+
+	RepairNetworkingCalled   bool
+	RepairNetworkingNetworks boshsettings.Networks
+	RepairNetworkingErr      error
+
 	MountPersistentDiskCalled     bool
 	MountPersistentDiskSettings   boshsettings.DiskSettings
 	MountPersistentDiskMountPoint string
@@ -95,6 +117,18 @@ type FakePlatform struct {
 	UnmountPersistentDiskDidUnmount bool
 	UnmountPersistentDiskSettings   boshsettings.DiskSettings
 
+	ResizePersistentDiskSettings   boshsettings.DiskSettings
+	ResizePersistentDiskMountPoint string
+	ResizePersistentDiskErr        error
+
+	FreezePersistentDiskMountPoints []string
+	FreezePersistentDiskErr         error
+	FreezePersistentDiskCallBack    func()
+
+	ThawPersistentDiskMountPoints []string
+	ThawPersistentDiskErr         error
+	ThawPersistentDiskCallBack    func()
+
 	GetFileContentsFromCDROMPath        string
 	GetFileContentsFromCDROMContents    []byte
 	GetFileContentsFromCDROMErr         error
@@ -114,6 +148,8 @@ type FakePlatform struct {
 
 	MigratePersistentDiskFromMountPoint string
 	MigratePersistentDiskToMountPoint   string
+	MigratePersistentDiskProgress       [][2]uint64
+	MigratePersistentDiskErr            error
 
 	IsPersistentDiskMountableResult bool
 	IsPersistentDiskMountableErr    error
@@ -165,6 +201,8 @@ type FakePlatform struct {
 
 	SetupRecordsJSONPermissionPath string
 	SetupRecordsJSONPermissionErr  error
+
+	StartDiskTrimmingStopCh chan struct{}
 }
 
 func NewFakePlatform() (platform *FakePlatform) {
@@ -223,6 +261,14 @@ func (p *FakePlatform) GetVitalsService() (service boshvitals.Service) {
 	return p.FakeVitalsService
 }
 
+func (p *FakePlatform) GetManagedDisks() boshsettings.ManagedDisks {
+	return boshsettings.NewManagedDisks(p.Fs, p.GetDirProvider().BoshDir())
+}
+
+func (p *FakePlatform) GetFilesystemCheckHistory() boshdisk.FilesystemCheckHistory {
+	return boshdisk.NewFilesystemCheckHistory(p.Fs, p.GetDirProvider().BoshDir())
+}
+
 func (p *FakePlatform) GetDevicePathResolver() (devicePathResolver boshdpresolv.DevicePathResolver) {
 	return p.DevicePathResolver
 }
@@ -295,6 +341,18 @@ func (p *FakePlatform) SetupNetworking(networks boshsettings.Networks) error {
 	return p.SetupNetworkingErr
 }
 
+func (p *FakePlatform) DetectNetworkDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (boshnet.Drift, error) {
+	p.DetectNetworkDriftNetworks = networks
+	p.DetectNetworkDriftValidator = interfaceAddressesValidator
+	return p.DetectNetworkDriftDrift, p.DetectNetworkDriftErr
+}
+
+func (p *FakePlatform) RepairNetworking(networks boshsettings.Networks) error {
+	p.RepairNetworkingCalled = true
+	p.RepairNetworkingNetworks = networks
+	return p.RepairNetworkingErr
+}
+
 func (p *FakePlatform) GetConfiguredNetworkInterfaces() ([]string, error) {
 	return p.GetConfiguredNetworkInterfacesInterfaces, p.GetConfiguredNetworkInterfacesErr
 }
@@ -312,9 +370,10 @@ func (p *FakePlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
 
-func (p *FakePlatform) SetupEphemeralDiskWithPath(devicePath string, desiredSwapSizeInBytes *uint64) (err error) {
+func (p *FakePlatform) SetupEphemeralDiskWithPath(devicePath string, desiredSwapSizeInBytes *uint64, mountOptions ...string) (err error) {
 	p.SetupEphemeralDiskWithPathDevicePath = devicePath
 	p.SetupEphemeralDiskWithPathSwapSize = desiredSwapSizeInBytes
+	p.SetupEphemeralDiskWithPathMountOptions = mountOptions
 	return p.SetupEphemeralDiskWithPathErr
 }
 
@@ -324,6 +383,19 @@ func (p *FakePlatform) SetupRawEphemeralDisks(devices []boshsettings.DiskSetting
 	return p.SetupRawEphemeralDisksErr
 }
 
+func (p *FakePlatform) SetupPooledEphemeralDisk(ephemeralDiskPath string, rawDevices []boshsettings.DiskSettings, pool boshsettings.EphemeralDiskPool, mountOptions ...string) (err error) {
+	p.SetupPooledEphemeralDiskEphemeralDiskPath = ephemeralDiskPath
+	p.SetupPooledEphemeralDiskRawDevices = rawDevices
+	p.SetupPooledEphemeralDiskPool = pool
+	p.SetupPooledEphemeralDiskMountOptions = mountOptions
+	return p.SetupPooledEphemeralDiskErr
+}
+
+func (p *FakePlatform) SetupSwap(swap boshsettings.Swap) (err error) {
+	p.SetupSwapSwaps = append(p.SetupSwapSwaps, swap)
+	return p.SetupSwapErr
+}
+
 func (p *FakePlatform) SetupDataDir() error {
 	p.SetupDataDirCalled = true
 	return p.SetupDataDirErr
@@ -367,6 +439,28 @@ func (p *FakePlatform) UnmountPersistentDisk(diskSettings boshsettings.DiskSetti
 	return
 }
 
+func (p *FakePlatform) ResizePersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) (err error) {
+	p.ResizePersistentDiskSettings = diskSettings
+	p.ResizePersistentDiskMountPoint = mountPoint
+	return p.ResizePersistentDiskErr
+}
+
+func (p *FakePlatform) FreezePersistentDisk(mountPoint string) (err error) {
+	p.FreezePersistentDiskMountPoints = append(p.FreezePersistentDiskMountPoints, mountPoint)
+	if p.FreezePersistentDiskCallBack != nil {
+		p.FreezePersistentDiskCallBack()
+	}
+	return p.FreezePersistentDiskErr
+}
+
+func (p *FakePlatform) ThawPersistentDisk(mountPoint string) (err error) {
+	p.ThawPersistentDiskMountPoints = append(p.ThawPersistentDiskMountPoints, mountPoint)
+	if p.ThawPersistentDiskCallBack != nil {
+		p.ThawPersistentDiskCallBack()
+	}
+	return p.ThawPersistentDiskErr
+}
+
 func (p *FakePlatform) GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string {
 	p.GetEphemeralDiskPathCalled = true
 	p.GetEphemeralDiskPathSettings = diskSettings
@@ -404,10 +498,13 @@ func (p *FakePlatform) SetGetFilesContentsFromDisk(fileName string, contents []b
 	p.GetFileContentsFromDiskErrs[fileName] = err
 }
 
-func (p *FakePlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p *FakePlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string, progress func(copiedBytes, totalBytes uint64)) (err error) {
 	p.MigratePersistentDiskFromMountPoint = fromMountPoint
 	p.MigratePersistentDiskToMountPoint = toMountPoint
-	return
+	for _, reported := range p.MigratePersistentDiskProgress {
+		progress(reported[0], reported[1])
+	}
+	return p.MigratePersistentDiskErr
 }
 
 func (p *FakePlatform) IsMountPoint(path string) (string, bool, error) {
@@ -500,3 +597,7 @@ func (p *FakePlatform) SetupRecordsJSONPermission(path string) error {
 	p.SetupRecordsJSONPermissionPath = path
 	return p.SetupRecordsJSONPermissionErr
 }
+
+func (p *FakePlatform) StartDiskTrimming(stopCh chan struct{}) {
+	p.StartDiskTrimmingStopCh = stopCh
+}
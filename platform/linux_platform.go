@@ -9,13 +9,16 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"text/template"
+	"time"
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
 	boshdevutil "github.com/cloudfoundry/bosh-agent/platform/deviceutil"
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -48,8 +51,17 @@ const (
 
 	minRootEphemeralSpaceInBytes = uint64(1024 * 1024 * 1024)
 	maxFdiskPartitionSize        = uint64(2 * 1024 * 1024 * 1024 * 1024)
+
+	persistentDiskMapperPrefix    = "bosh-persistent-"
+	persistentDiskResizeThreshold = uint64(100 * 1024 * 1024)
+	migrationCheckpointFileName   = ".bosh_migration_checkpoint.json"
+
+	ephemeralVolumeGroupName   = "bosh-ephemeral"
+	ephemeralLogicalVolumeName = "data"
 )
 
+var mapperNameReplacer = regexp.MustCompile(`[^a-zA-Z0-9_.\-]`)
+
 type LinuxOptions struct {
 	// When set to true loop back device
 	// is not going to be overlayed over /tmp to limit /tmp dir size
@@ -81,6 +93,31 @@ type LinuxOptions struct {
 	// Strategy for resolving ephemeral & persistent disk partitioners;
 	// possible values: parted, "" (default is sfdisk if disk < 2TB, parted otherwise)
 	PartitionerType string
+
+	// When set to true checksums of files copied during persistent disk
+	// migration are compared before new disk replaces the old one
+	VerifyPersistentDiskMigration bool
+
+	// When set to a positive number fstrim is run against ephemeral and
+	// persistent disks every so many seconds so that thin-provisioned
+	// volumes give unused blocks back to the IaaS
+	DiskTrimIntervalInSeconds int
+
+	// When set persistent disk filesystem is checked right before mounting;
+	// possible values: check, repair, "" (default is to skip the check)
+	PersistentDiskFilesystemCheck string
+
+	// Strategy for configuring networking on Ubuntu;
+	// possible values: netplan, networkd, "" (default is ifupdown)
+	NetManagerType string
+
+	// When set to a positive number configuration files and addresses are
+	// compared every so many seconds with networks configured during bootstrap;
+	// drift is reported to health monitor
+	NetworkDriftCheckIntervalInSeconds int
+
+	// When set to true networking is reapplied once drift is detected
+	RepairNetworkDrift bool
 }
 
 type linux struct {
@@ -93,6 +130,7 @@ type linux struct {
 	vitalsService          boshvitals.Service
 	cdutil                 boshdevutil.DeviceUtil
 	diskManager            boshdisk.Manager
+	diskTrimScheduler      *boshdisk.TrimScheduler
 	netManager             boshnet.Manager
 	certManager            boshcert.Manager
 	monitRetryStrategy     boshretry.RetryStrategy
@@ -103,6 +141,10 @@ type linux struct {
 	defaultNetworkResolver boshsettings.DefaultNetworkResolver
 	uuidGenerator          boshuuid.Generator
 	auditLogger            AuditLogger
+
+	// networkingLock keeps drift repair from rewriting network
+	// configuration while networking is being set up and vice versa
+	networkingLock *sync.Mutex
 }
 
 func NewLinuxPlatform(
@@ -115,6 +157,7 @@ func NewLinuxPlatform(
 	vitalsService boshvitals.Service,
 	cdutil boshdevutil.DeviceUtil,
 	diskManager boshdisk.Manager,
+	diskTrimScheduler *boshdisk.TrimScheduler,
 	netManager boshnet.Manager,
 	certManager boshcert.Manager,
 	monitRetryStrategy boshretry.RetryStrategy,
@@ -136,6 +179,7 @@ func NewLinuxPlatform(
 		vitalsService:          vitalsService,
 		cdutil:                 cdutil,
 		diskManager:            diskManager,
+		diskTrimScheduler:      diskTrimScheduler,
 		netManager:             netManager,
 		certManager:            certManager,
 		monitRetryStrategy:     monitRetryStrategy,
@@ -146,6 +190,7 @@ func NewLinuxPlatform(
 		defaultNetworkResolver: defaultNetworkResolver,
 		uuidGenerator:          uuidGenerator,
 		auditLogger:            auditLogger,
+		networkingLock:         &sync.Mutex{},
 	}
 }
 
@@ -192,6 +237,35 @@ func (p linux) GetVitalsService() (service boshvitals.Service) {
 	return p.vitalsService
 }
 
+func (p linux) GetManagedDisks() boshsettings.ManagedDisks {
+	return boshsettings.NewManagedDisks(p.fs, p.dirProvider.BoshDir())
+}
+
+func (p linux) GetFilesystemCheckHistory() boshdisk.FilesystemCheckHistory {
+	return boshdisk.NewFilesystemCheckHistory(p.fs, p.dirProvider.BoshDir())
+}
+
+// StartDiskTrimming trims ephemeral and persistent disks every configured
+// interval until stopCh is closed; it is expected to be started once disks are mounted
+func (p linux) StartDiskTrimming(stopCh chan struct{}) {
+	if p.options.DiskTrimIntervalInSeconds <= 0 {
+		return
+	}
+
+	interval := time.Duration(p.options.DiskTrimIntervalInSeconds) * time.Second
+
+	p.diskTrimScheduler.Start(interval, p.diskTrimMountPoints, stopCh)
+}
+
+func (p linux) diskTrimMountPoints() ([]string, error) {
+	namedDiskMountPoints, err := p.GetManagedDisks().MountPoints()
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]string{p.dirProvider.DataDir(), p.dirProvider.StoreDir()}, namedDiskMountPoints...), nil
+}
+
 func (p linux) GetFileContentsFromCDROM(fileName string) (content []byte, err error) {
 	contents, err := p.cdutil.GetFilesContents([]string{fileName})
 	if err != nil {
@@ -214,9 +288,36 @@ func (p linux) GetAuditLogger() AuditLogger {
 }
 
 func (p linux) SetupNetworking(networks boshsettings.Networks) (err error) {
+	p.networkingLock.Lock()
+	defer p.networkingLock.Unlock()
+
 	return p.netManager.SetupNetworking(networks, nil)
 }
 
+func (p linux) DetectNetworkDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (boshnet.Drift, error) {
+	driftDetector, ok := p.netManager.(boshnet.DriftDetector)
+	if !ok {
+		return boshnet.Drift{}, bosherr.Error("Detecting network drift is not supported by network manager")
+	}
+
+	p.networkingLock.Lock()
+	defer p.networkingLock.Unlock()
+
+	return driftDetector.DetectDrift(networks, interfaceAddressesValidator)
+}
+
+func (p linux) RepairNetworking(networks boshsettings.Networks) error {
+	driftDetector, ok := p.netManager.(boshnet.DriftDetector)
+	if !ok {
+		return bosherr.Error("Repairing networking is not supported by network manager")
+	}
+
+	p.networkingLock.Lock()
+	defer p.networkingLock.Unlock()
+
+	return driftDetector.RepairNetworking(networks)
+}
+
 func (p linux) GetConfiguredNetworkInterfaces() ([]string, error) {
 	return p.netManager.GetConfiguredNetworkInterfaces()
 }
@@ -555,7 +656,7 @@ func (p linux) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
 
-func (p linux) SetupEphemeralDiskWithPath(realPath string, desiredSwapSizeInBytes *uint64) error {
+func (p linux) SetupEphemeralDiskWithPath(realPath string, desiredSwapSizeInBytes *uint64, mountOptions ...string) error {
 	p.logger.Info(logTag, "Setting up ephemeral disk...")
 	mountPoint := p.dirProvider.DataDir()
 
@@ -608,14 +709,14 @@ func (p linux) SetupEphemeralDiskWithPath(realPath string, desiredSwapSizeInByte
 
 	if len(swapPartitionPath) > 0 {
 		p.logger.Info(logTag, "Formatting `%s' as swap", swapPartitionPath)
-		err = p.diskManager.GetFormatter().Format(swapPartitionPath, boshdisk.FileSystemSwap)
+		err = p.diskManager.GetFormatter().Format(swapPartitionPath, boshdisk.FormatSpec{FileSystemType: boshdisk.FileSystemSwap})
 		if err != nil {
 			return bosherr.WrapError(err, "Formatting swap")
 		}
 	}
 
 	p.logger.Info(logTag, "Formatting `%s' as ext4", dataPartitionPath)
-	err = p.diskManager.GetFormatter().Format(dataPartitionPath, boshdisk.FileSystemExt4)
+	err = p.diskManager.GetFormatter().Format(dataPartitionPath, boshdisk.FormatSpec{FileSystemType: boshdisk.FileSystemExt4})
 	if err != nil {
 		return bosherr.WrapError(err, "Formatting data partition with ext4")
 	}
@@ -629,21 +730,132 @@ func (p linux) SetupEphemeralDiskWithPath(realPath string, desiredSwapSizeInByte
 	}
 
 	p.logger.Info(logTag, "Mounting `%s' at `%s'", dataPartitionPath, mountPoint)
-	err = p.diskManager.GetMounter().Mount(dataPartitionPath, mountPoint)
+	err = p.diskManager.GetMounter().Mount(dataPartitionPath, mountPoint, mountOptions...)
 	if err != nil {
 		return bosherr.WrapError(err, "Mounting data partition")
 	}
 
-	if p.options.ScrubEphemeralDisk {
-		contents, err := p.fs.Glob(mountPointGlob)
+	return p.scrubMountedEphemeralDisk()
+}
+
+// SetupPooledEphemeralDisk is used instead of SetupEphemeralDiskWithPath
+// and SetupRawEphemeralDisks; swap cannot be carved out of the pool
+// so it has to be backed by file or zram
+func (p linux) SetupPooledEphemeralDisk(ephemeralDiskPath string, rawDevices []boshsettings.DiskSettings, pool boshsettings.EphemeralDiskPool, mountOptions ...string) error {
+	p.logger.Info(logTag, "Setting up pooled ephemeral disk...")
+	mountPoint := p.dirProvider.DataDir()
+
+	err := p.fs.MkdirAll(mountPoint, ephemeralDiskPermissions)
+	if err != nil {
+		return bosherr.WrapError(err, "Creating data dir")
+	}
+
+	if p.options.SkipDiskSetup {
+		return nil
+	}
+
+	var devicePaths []string
+
+	if ephemeralDiskPath != "" {
+		devicePaths = append(devicePaths, ephemeralDiskPath)
+	}
+
+	for _, device := range rawDevices {
+		realPath, timedOut, err := p.devicePathResolver.GetRealDevicePath(device)
+		if timedOut {
+			// Pooling fewer disks would shrink data dir on every reboot that misses one
+			return bosherr.Errorf("Timed out resolving raw ephemeral disk '%s'", device.Path)
+		}
 		if err != nil {
-			return bosherr.WrapErrorf(err, "Globbing ephemeral disk mount point '%s'", mountPointGlob)
+			return bosherr.WrapError(err, "Getting real device path")
 		}
 
-		err = p.scrubEphemeralDisk(contents)
+		devicePaths = append(devicePaths, realPath)
+	}
+
+	if len(devicePaths) == 0 {
+		return bosherr.Error("No ephemeral disks found to pool")
+	}
+
+	spec := boshdisk.LogicalVolumeSpec{
+		VolumeGroup:    ephemeralVolumeGroupName,
+		Name:           ephemeralLogicalVolumeName,
+		Devices:        devicePaths,
+		Striped:        pool.Striped,
+		StripeSizeInKB: pool.StripeSizeInKB,
+	}
+
+	logicalVolumePath, err := p.diskManager.GetLogicalVolumeManager().Setup(spec)
+	if err != nil {
+		return bosherr.WrapError(err, "Pooling ephemeral disks")
+	}
+
+	p.logger.Info(logTag, "Formatting `%s' as ext4", logicalVolumePath)
+	err = p.diskManager.GetFormatter().Format(logicalVolumePath, boshdisk.FormatSpec{FileSystemType: boshdisk.FileSystemExt4})
+	if err != nil {
+		return bosherr.WrapError(err, "Formatting logical volume with ext4")
+	}
+
+	p.logger.Info(logTag, "Mounting `%s' at `%s'", logicalVolumePath, mountPoint)
+	err = p.diskManager.GetMounter().Mount(logicalVolumePath, mountPoint, mountOptions...)
+	if err != nil {
+		return bosherr.WrapError(err, "Mounting logical volume")
+	}
+
+	return p.scrubMountedEphemeralDisk()
+}
+
+func (p linux) scrubMountedEphemeralDisk() error {
+	if !p.options.ScrubEphemeralDisk {
+		return nil
+	}
+
+	mountPointGlob := path.Join(p.dirProvider.DataDir(), "*")
+
+	contents, err := p.fs.Glob(mountPointGlob)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Globbing ephemeral disk mount point '%s'", mountPointGlob)
+	}
+
+	err = p.scrubEphemeralDisk(contents)
+	if err != nil {
+		return bosherr.WrapError(err, "Scrubbing ephemeral disk")
+	}
+
+	return nil
+}
+
+// SetupSwap can be called repeatedly since file and zram swap
+// are resized without touching ephemeral disk partitions
+func (p linux) SetupSwap(swap boshsettings.Swap) error {
+	switch swap.Type {
+	case boshdisk.SwapTypePartition, boshdisk.SwapTypeFile, boshdisk.SwapTypeZram:
+	default:
+		return bosherr.Errorf("Unknown swap type '%s'", swap.Type)
+	}
+
+	var sizeInBytes uint64
+
+	if desiredSizeInBytes := swap.GetSizeInBytes(); desiredSizeInBytes != nil {
+		sizeInBytes = *desiredSizeInBytes
+	} else {
+		memStats, err := p.collector.GetMemStats()
 		if err != nil {
-			return bosherr.WrapError(err, "Scrubbing ephemeral disk")
+			return bosherr.WrapError(err, "Getting mem stats")
 		}
+
+		sizeInBytes = memStats.Total
+	}
+
+	spec := boshdisk.SwapSpec{
+		Type:        swap.Type,
+		SizeInBytes: sizeInBytes,
+		FilePath:    filepath.Join(p.dirProvider.DataDir(), "swapfile"),
+	}
+
+	err := p.diskManager.GetSwapper().Reconcile(spec)
+	if err != nil {
+		return bosherr.WrapError(err, "Reconciling swap")
 	}
 
 	return nil
@@ -1064,12 +1276,24 @@ func (p linux) MountPersistentDisk(diskSetting boshsettings.DiskSettings, mountP
 		partitionPath = realPath + "-part1"
 	}
 
+	mountedDevicePath := partitionPath
+	if diskSetting.Encryption.IsEnabled() {
+		mountedDevicePath = p.persistentDiskMapperPath(diskSetting)
+	}
+
+	// Unlike disk mounted at store directory named disks cannot be migrated
+	isNamedDisk := filepath.Dir(mountPoint) == p.dirProvider.StoreDir()
+
 	if isMountPoint {
-		if partitionPath == devicePath {
+		if mountedDevicePath == devicePath {
 			p.logger.Info(logTag, "device: %s is already mounted on %s, skipping mounting", devicePath, mountPoint)
 			return nil
 		}
 
+		if isNamedDisk {
+			return bosherr.Errorf("Mount point '%s' is already used by device '%s'", mountPoint, devicePath)
+		}
+
 		mountPoint = p.dirProvider.StoreMigrationDir()
 	}
 
@@ -1101,27 +1325,74 @@ func (p linux) MountPersistentDisk(diskSetting boshsettings.DiskSettings, mountP
 
 		persistentDiskFS := diskSetting.FileSystemType
 		switch persistentDiskFS {
-		case boshdisk.FileSystemExt4, boshdisk.FileSystemXFS:
+		case boshdisk.FileSystemExt3, boshdisk.FileSystemExt4, boshdisk.FileSystemXFS, boshdisk.FileSystemBtrfs:
 		case boshdisk.FileSystemDefault:
 			persistentDiskFS = boshdisk.FileSystemExt4
 		default:
 			return bosherr.Error(fmt.Sprintf(`The filesystem type "%s" is not supported`, diskSetting.FileSystemType))
 		}
 
-		err = p.diskManager.GetFormatter().Format(partitionPath, persistentDiskFS)
+		if diskSetting.Encryption.IsEnabled() {
+			partitionPath, err = p.openEncryptedPersistentDisk(partitionPath, diskSetting, true)
+			if err != nil {
+				return err
+			}
+		}
+
+		formatSpec := boshdisk.FormatSpec{
+			FileSystemType: persistentDiskFS,
+			MkfsOptions:    diskSetting.MkfsOptions,
+			Label:          diskSetting.FileSystemLabel,
+		}
+
+		err = p.diskManager.GetFormatter().Format(partitionPath, formatSpec)
 		if err != nil {
 			return bosherr.WrapError(err, fmt.Sprintf("Formatting partition with %s", diskSetting.FileSystemType))
 		}
 
 		realPath = partitionPath
+	} else if diskSetting.Encryption.IsEnabled() {
+		realPath, err = p.openEncryptedPersistentDisk(realPath, diskSetting, false)
+		if err != nil {
+			return err
+		}
 	}
 
-	err = p.diskManager.GetMounter().Mount(realPath, mountPoint, diskSetting.MountOptions...)
+	err = p.checkPersistentDiskFilesystem(realPath, diskSetting)
+	if err != nil {
+		return err
+	}
+
+	mount := func() error {
+		return p.diskManager.GetMounter().Mount(realPath, mountPoint, diskSetting.MountOptions...)
+	}
+
+	if mountPoint == p.dirProvider.StoreDir() {
+		err = p.withNamedDisksUnmounted(mount)
+	} else {
+		err = mount()
+	}
 
 	if err != nil {
 		return bosherr.WrapError(err, "Mounting partition")
 	}
 
+	if isNamedDisk {
+		managedDisk := boshsettings.ManagedDisk{
+			DiskCID:      diskSetting.ID,
+			Name:         filepath.Base(mountPoint),
+			MountPoint:   mountPoint,
+			MountOptions: diskSetting.MountOptions,
+		}
+
+		err = p.GetManagedDisks().Add(managedDisk)
+		if err != nil {
+			return bosherr.WrapError(err, "Tracking managed disk")
+		}
+
+		return nil
+	}
+
 	managedSettingsPath := filepath.Join(p.dirProvider.BoshDir(), "managed_disk_settings.json")
 
 	err = p.fs.WriteFileString(managedSettingsPath, diskSetting.ID)
@@ -1133,7 +1404,141 @@ func (p linux) MountPersistentDisk(diskSetting boshsettings.DiskSettings, mountP
 	return nil
 }
 
+// checkPersistentDiskFilesystem records the outcome of the check so that
+// found errors are reported even if the agent restarts before alerting
+func (p linux) checkPersistentDiskFilesystem(partitionPath string, diskSetting boshsettings.DiskSettings) error {
+	mode := boshdisk.FilesystemCheckMode(p.options.PersistentDiskFilesystemCheck)
+
+	switch mode {
+	case "":
+		return nil
+	case boshdisk.FilesystemCheckModeCheck, boshdisk.FilesystemCheckModeRepair:
+	default:
+		return bosherr.Errorf("Unknown persistent disk filesystem check mode '%s'", mode)
+	}
+
+	result, err := p.diskManager.GetFilesystemChecker().Check(partitionPath, mode)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking persistent disk filesystem")
+	}
+
+	check := boshdisk.FilesystemCheck{
+		DiskCID:        diskSetting.ID,
+		CheckedAt:      time.Now().Unix(),
+		FileSystemType: result.FileSystemType,
+		ErrorsFound:    result.ErrorsFound,
+		Repaired:       result.Repaired,
+	}
+
+	err = p.GetFilesystemCheckHistory().Record(check)
+	if err != nil {
+		return bosherr.WrapError(err, "Recording persistent disk filesystem check")
+	}
+
+	if result.ErrorsFound {
+		p.logger.Warn(logTag, "Found errors on %s filesystem of persistent disk '%s' (repaired: %t)", result.FileSystemType, diskSetting.ID, result.Repaired)
+	}
+
+	if mode == boshdisk.FilesystemCheckModeRepair && result.ErrorsFound && !result.Repaired {
+		return bosherr.Errorf("Filesystem on persistent disk '%s' has errors that could not be repaired", diskSetting.ID)
+	}
+
+	return nil
+}
+
 func (p linux) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (bool, error) {
+	_, isNamedDisk, err := p.GetManagedDisks().Find(diskSettings.ID)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Finding managed disk")
+	}
+
+	if isNamedDisk {
+		didUnmount, err := p.unmountPersistentDisk(diskSettings)
+		if err != nil {
+			return false, err
+		}
+
+		err = p.GetManagedDisks().Remove(diskSettings.ID)
+		if err != nil {
+			return didUnmount, bosherr.WrapError(err, "Untracking managed disk")
+		}
+
+		return didUnmount, nil
+	}
+
+	var didUnmount bool
+
+	err = p.withNamedDisksUnmounted(func() (err error) {
+		didUnmount, err = p.unmountPersistentDisk(diskSettings)
+		return
+	})
+
+	return didUnmount, err
+}
+
+type namedDiskMount struct {
+	disk       boshsettings.ManagedDisk
+	devicePath string
+}
+
+// withNamedDisksUnmounted runs fn while named disks are unmounted from their directories under store directory;
+// otherwise disk at store directory cannot be unmounted (busy), copying it during migration
+// would descend into named disks and mounting another disk there would hide them.
+// Named disks are mounted back afterwards even if fn fails
+func (p linux) withNamedDisksUnmounted(fn func() error) error {
+	managedDisks, err := p.GetManagedDisks().List()
+	if err != nil {
+		return bosherr.WrapError(err, "Listing managed disks")
+	}
+
+	var unmounted []namedDiskMount
+
+	for _, disk := range managedDisks {
+		devicePath, isMountPoint, err := p.IsMountPoint(disk.MountPoint)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Checking mount point of named disk '%s'", disk.Name)
+		}
+
+		if !isMountPoint {
+			continue
+		}
+
+		_, err = p.diskManager.GetMounter().Unmount(disk.MountPoint)
+		if err != nil {
+			_ = p.mountNamedDisks(unmounted)
+			return bosherr.WrapErrorf(err, "Unmounting named disk '%s'", disk.Name)
+		}
+
+		unmounted = append(unmounted, namedDiskMount{disk: disk, devicePath: devicePath})
+	}
+
+	fnErr := fn()
+
+	err = p.mountNamedDisks(unmounted)
+	if fnErr != nil {
+		return fnErr
+	}
+
+	return err
+}
+
+func (p linux) mountNamedDisks(mounts []namedDiskMount) error {
+	for _, mount := range mounts {
+		err := p.fs.MkdirAll(mount.disk.MountPoint, persistentDiskPermissions)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Creating directory %s", mount.disk.MountPoint)
+		}
+
+		err = p.diskManager.GetMounter().Mount(mount.devicePath, mount.disk.MountPoint, mount.disk.MountOptions...)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Mounting named disk '%s' back", mount.disk.Name)
+		}
+	}
+
+	return nil
+}
+
+func (p linux) unmountPersistentDisk(diskSettings boshsettings.DiskSettings) (bool, error) {
 	p.logger.Debug(logTag, "Unmounting persistent disk %+v", diskSettings)
 
 	realPath, timedOut, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
@@ -1144,6 +1549,20 @@ func (p linux) UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (bo
 		return false, bosherr.WrapError(err, "Getting real device path")
 	}
 
+	if diskSettings.Encryption.IsEnabled() {
+		didUnmount, err := p.diskManager.GetMounter().Unmount(p.persistentDiskMapperPath(diskSettings))
+		if err != nil {
+			return false, err
+		}
+
+		err = p.diskManager.GetEncryptor().Close(p.persistentDiskMapperName(diskSettings))
+		if err != nil {
+			return false, bosherr.WrapError(err, "Closing encrypted persistent disk")
+		}
+
+		return didUnmount, nil
+	}
+
 	if !p.options.UsePreformattedPersistentDisk {
 		if strings.Contains(realPath, "/dev/mapper/") {
 			realPath = realPath + "-part1"
@@ -1183,19 +1602,36 @@ func (p linux) IsMountPoint(path string) (string, bool, error) {
 	return p.diskManager.GetMounter().IsMountPoint(path)
 }
 
-func (p linux) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p linux) MigratePersistentDisk(fromMountPoint, toMountPoint string, progress func(copiedBytes, totalBytes uint64)) error {
 	p.logger.Debug(logTag, "Migrating persistent disk %v to %v", fromMountPoint, toMountPoint)
 
+	// Named disks are not copied and end up mounted on the new disk
+	return p.withNamedDisksUnmounted(func() error {
+		return p.migratePersistentDisk(fromMountPoint, toMountPoint, progress)
+	})
+}
+
+func (p linux) migratePersistentDisk(fromMountPoint, toMountPoint string, progress func(copiedBytes, totalBytes uint64)) (err error) {
+
+	fromDevicePath, _, err := p.IsMountPoint(fromMountPoint)
+	if err != nil {
+		err = bosherr.WrapError(err, "Checking mount point")
+		return
+	}
+
 	err = p.diskManager.GetMounter().RemountAsReadonly(fromMountPoint)
 	if err != nil {
 		err = bosherr.WrapError(err, "Remounting persistent disk as readonly")
 		return
 	}
 
-	// Golang does not implement a file copy that would allow us to preserve dates...
-	// So we have to shell out to tar to perform the copy instead of delegating to the FileSystem
-	tarCopy := fmt.Sprintf("(tar -C %s -cf - .) | (tar -C %s -xpf -)", fromMountPoint, toMountPoint)
-	_, _, _, err = p.cmdRunner.RunCommand("sh", "-c", tarCopy)
+	// Checkpoint is kept on the new disk so that copy resumes
+	// only if migration is retried with the same disk
+	err = p.diskManager.GetTreeCopier().Copy(fromMountPoint, toMountPoint, boshdisk.TreeCopyOptions{
+		CheckpointPath: path.Join(toMountPoint, migrationCheckpointFileName),
+		Verify:         p.options.VerifyPersistentDiskMigration,
+		Progress:       progress,
+	})
 	if err != nil {
 		err = bosherr.WrapError(err, "Copying files from old disk to new disk")
 		return
@@ -1207,6 +1643,15 @@ func (p linux) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err e
 		return
 	}
 
+	// Old disk stays unlocked until it is detached otherwise
+	if strings.HasPrefix(fromDevicePath, path.Join("/dev/mapper", persistentDiskMapperPrefix)) {
+		err = p.diskManager.GetEncryptor().Close(path.Base(fromDevicePath))
+		if err != nil {
+			err = bosherr.WrapError(err, "Closing old encrypted persistent disk")
+			return
+		}
+	}
+
 	err = p.diskManager.GetMounter().Remount(toMountPoint, fromMountPoint)
 	if err != nil {
 		err = bosherr.WrapError(err, "Remounting new disk on original mountpoint")
@@ -1214,6 +1659,125 @@ func (p linux) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err e
 	return
 }
 
+// ResizePersistentDisk grows partition and filesystem of mounted persistent disk
+// after the IaaS has resized the underlying volume
+func (p linux) ResizePersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error {
+	p.logger.Debug(logTag, "Resizing persistent disk %+v mounted at %s", diskSettings, mountPoint)
+
+	realPath, _, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
+	if err != nil {
+		return bosherr.WrapError(err, "Getting real device path")
+	}
+
+	isMounted, err := p.IsPersistentDiskMounted(diskSettings)
+	if err != nil {
+		return bosherr.WrapError(err, "Checking whether persistent disk is mounted")
+	}
+
+	if !isMounted {
+		return bosherr.Errorf("Persistent disk '%s' must be mounted to be resized", diskSettings.ID)
+	}
+
+	filesystemPath := realPath
+
+	if !p.options.UsePreformattedPersistentDisk {
+		partitionPath := realPath + "1"
+		if strings.Contains(realPath, "/dev/mapper/") {
+			partitionPath = realPath + "-part1"
+		}
+
+		err = p.growPersistentDiskPartition(realPath, partitionPath)
+		if err != nil {
+			return err
+		}
+
+		filesystemPath = partitionPath
+	}
+
+	if diskSettings.Encryption.IsEnabled() {
+		key, err := p.persistentDiskEncryptionKey(diskSettings.Encryption)
+		if err != nil {
+			return err
+		}
+
+		err = p.diskManager.GetEncryptor().Resize(p.persistentDiskMapperName(diskSettings), key)
+		if err != nil {
+			return bosherr.WrapError(err, "Resizing encrypted persistent disk")
+		}
+
+		filesystemPath = p.persistentDiskMapperPath(diskSettings)
+	}
+
+	err = p.diskManager.GetFormatter().GrowFilesystem(filesystemPath, mountPoint)
+	if err != nil {
+		return bosherr.WrapError(err, "Growing filesystem")
+	}
+
+	return nil
+}
+
+func (p linux) growPersistentDiskPartition(devicePath, partitionPath string) error {
+	diskSize, err := p.diskManager.GetDiskUtil(devicePath).GetBlockDeviceSize()
+	if err != nil {
+		return bosherr.WrapError(err, "Getting persistent disk size")
+	}
+
+	partitionSize, err := p.diskManager.GetDiskUtil(partitionPath).GetBlockDeviceSize()
+	if err != nil {
+		return bosherr.WrapError(err, "Getting persistent disk partition size")
+	}
+
+	p.logger.Debug(logTag, "Persistent disk size is %d, partition size is %d", diskSize, partitionSize)
+
+	// Partition table and alignment take up some space even on disks that were not resized
+	if diskSize < partitionSize+persistentDiskResizeThreshold {
+		p.logger.Info(logTag, "Persistent disk partition %s already spans the disk, skipping growing it", partitionPath)
+		return nil
+	}
+
+	// Partitioner is chosen the same way it was when the disk was first partitioned
+	var partitioner boshdisk.Partitioner
+
+	if partitionSize < maxFdiskPartitionSize {
+		if diskSize >= maxFdiskPartitionSize && p.options.PartitionerType != "parted" {
+			return bosherr.Errorf("Persistent disk partitioned with fdisk cannot grow beyond %d bytes; migrate it to a new disk instead", maxFdiskPartitionSize)
+		}
+
+		partitioner = p.diskManager.GetPartitioner()
+	} else {
+		partitioner = p.diskManager.GetPartedPartitioner()
+	}
+
+	err = partitioner.GrowPartition(devicePath, 1)
+	if err != nil {
+		return bosherr.WrapError(err, "Growing persistent disk partition")
+	}
+
+	return nil
+}
+
+func (p linux) FreezePersistentDisk(mountPoint string) error {
+	p.logger.Debug(logTag, "Freezing persistent disk mounted at %s", mountPoint)
+
+	_, _, _, err := p.cmdRunner.RunCommand("fsfreeze", "--freeze", mountPoint)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Freezing filesystem at '%s'", mountPoint)
+	}
+
+	return nil
+}
+
+func (p linux) ThawPersistentDisk(mountPoint string) error {
+	p.logger.Debug(logTag, "Thawing persistent disk mounted at %s", mountPoint)
+
+	_, _, _, err := p.cmdRunner.RunCommand("fsfreeze", "--unfreeze", mountPoint)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Thawing filesystem at '%s'", mountPoint)
+	}
+
+	return nil
+}
+
 func (p linux) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (bool, error) {
 	p.logger.Debug(logTag, "Checking whether persistent disk %+v is mounted", diskSettings)
 	realPath, timedOut, err := p.devicePathResolver.GetRealDevicePath(diskSettings)
@@ -1225,7 +1789,9 @@ func (p linux) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (
 		return false, bosherr.WrapError(err, "Getting real device path")
 	}
 
-	if !p.options.UsePreformattedPersistentDisk {
+	if diskSettings.Encryption.IsEnabled() {
+		realPath = p.persistentDiskMapperPath(diskSettings)
+	} else if !p.options.UsePreformattedPersistentDisk {
 		if strings.Contains(realPath, "/dev/mapper/") {
 			realPath = realPath + "-part1"
 		} else {
@@ -1236,6 +1802,65 @@ func (p linux) IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (
 	return p.diskManager.GetMounter().IsMounted(realPath)
 }
 
+// openEncryptedPersistentDisk unlocks LUKS device placed between
+// the partition and the filesystem. Devices are encrypted only when
+// agent is also responsible for formatting them.
+func (p linux) openEncryptedPersistentDisk(devicePath string, diskSettings boshsettings.DiskSettings, format bool) (string, error) {
+	if diskSettings.Encryption.Type != boshsettings.DiskEncryptionLUKS {
+		return "", bosherr.Errorf("The persistent disk encryption type \"%s\" is not supported", diskSettings.Encryption.Type)
+	}
+
+	key, err := p.persistentDiskEncryptionKey(diskSettings.Encryption)
+	if err != nil {
+		return "", err
+	}
+
+	encryptor := p.diskManager.GetEncryptor()
+
+	if format {
+		err = encryptor.Format(devicePath, key)
+		if err != nil {
+			return "", bosherr.WrapError(err, "Encrypting persistent disk")
+		}
+	}
+
+	decryptedPath, err := encryptor.Open(devicePath, p.persistentDiskMapperName(diskSettings), key)
+	if err != nil {
+		return "", bosherr.WrapError(err, "Opening encrypted persistent disk")
+	}
+
+	return decryptedPath, nil
+}
+
+func (p linux) persistentDiskEncryptionKey(encryption boshsettings.DiskEncryption) (string, error) {
+	if encryption.Key != "" {
+		return encryption.Key, nil
+	}
+
+	if encryption.KeyPath == "" {
+		return "", bosherr.Error("Persistent disk encryption requires key or key_path")
+	}
+
+	key, err := p.fs.ReadFileString(encryption.KeyPath)
+	if err != nil {
+		return "", bosherr.WrapErrorf(err, "Reading persistent disk encryption key from %s", encryption.KeyPath)
+	}
+
+	if key == "" {
+		return "", bosherr.Errorf("Persistent disk encryption key %s is empty", encryption.KeyPath)
+	}
+
+	return key, nil
+}
+
+func (p linux) persistentDiskMapperName(diskSettings boshsettings.DiskSettings) string {
+	return persistentDiskMapperPrefix + mapperNameReplacer.ReplaceAllString(diskSettings.ID, "_")
+}
+
+func (p linux) persistentDiskMapperPath(diskSettings boshsettings.DiskSettings) string {
+	return path.Join("/dev/mapper", p.persistentDiskMapperName(diskSettings))
+}
+
 func (p linux) StartMonit() error {
 	err := p.fs.Symlink(path.Join("/etc", "sv", "monit"), path.Join("/etc", "service", "monit"))
 	if err != nil {
@@ -1418,6 +2043,21 @@ func (p linux) partitionDisk(availableSize uint64, desiredSwapSizeInBytes *uint6
 	var swapPartitionPath string
 	var dataPartitionPath string
 
+	// Disk is not repartitioned (and wiped) when swap is moved to file or zram;
+	// swap partition is just left unused and turned off by swapper
+	if swapSizeInBytes == 0 {
+		hasSwap, err := p.hasSwapAndDataPartitions(partitionPath, partitionStartCount)
+		if err != nil {
+			return "", "", err
+		}
+
+		if hasSwap {
+			dataPartitionPath = partitionPath + strconv.Itoa(partitionStartCount+1)
+			p.logger.Info(logTag, "Keeping existing swap partition of `%s', using `%s' for data", partitionPath, dataPartitionPath)
+			return "", dataPartitionPath, nil
+		}
+	}
+
 	if swapSizeInBytes == 0 {
 		partitions = []boshdisk.Partition{
 			{SizeInBytes: linuxSizeInBytes, Type: boshdisk.PartitionTypeLinux},
@@ -1439,6 +2079,22 @@ func (p linux) partitionDisk(availableSize uint64, desiredSwapSizeInBytes *uint6
 	return swapPartitionPath, dataPartitionPath, err
 }
 
+func (p linux) hasSwapAndDataPartitions(partitionPath string, partitionStartCount int) (bool, error) {
+	swapPartitionPath := partitionPath + strconv.Itoa(partitionStartCount)
+	dataPartitionPath := partitionPath + strconv.Itoa(partitionStartCount+1)
+
+	if !p.fs.FileExists(swapPartitionPath) || !p.fs.FileExists(dataPartitionPath) {
+		return false, nil
+	}
+
+	fsType, err := p.diskManager.GetFormatter().FileSystemType(swapPartitionPath)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Checking existing swap partition `%s'", swapPartitionPath)
+	}
+
+	return fsType == boshdisk.FileSystemSwap, nil
+}
+
 func (p linux) RemoveDevTools(packageFileListPath string) error {
 	content, err := p.fs.ReadFileString(packageFileListPath)
 	if err != nil {
@@ -8,7 +8,9 @@ import (
 	"path"
 	"path/filepath"
 	"strings"
+	"time"
 
+	"code.cloudfoundry.org/clock/fakeclock"
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
@@ -20,12 +22,15 @@ import (
 	fakedisk "github.com/cloudfoundry/bosh-agent/platform/disk/fakes"
 	fakeplat "github.com/cloudfoundry/bosh-agent/platform/fakes"
 	fakenet "github.com/cloudfoundry/bosh-agent/platform/net/fakes"
+	fakeip "github.com/cloudfoundry/bosh-agent/platform/net/ip/fakes"
 	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
 	fakeretry "github.com/cloudfoundry/bosh-utils/retrystrategy/fakes"
 	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 	fakeuuidgen "github.com/cloudfoundry/bosh-utils/uuid/fakes"
 
 	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -41,6 +46,8 @@ func describeLinuxPlatform() {
 		fs                         *fakesys.FakeFileSystem
 		cmdRunner                  *fakesys.FakeCmdRunner
 		diskManager                *fakedisk.FakeDiskManager
+		diskTrimClock              *fakeclock.FakeClock
+		diskTrimScheduler          *boshdisk.TrimScheduler
 		dirProvider                boshdirs.Provider
 		devicePathResolver         *fakedpresolv.FakeDevicePathResolver
 		platform                   Platform
@@ -70,11 +77,13 @@ func describeLinuxPlatform() {
 		fs = fakesys.NewFakeFileSystem()
 		cmdRunner = fakesys.NewFakeCmdRunner()
 		diskManager = fakedisk.NewFakeDiskManager()
+		diskTrimClock = fakeclock.NewFakeClock(time.Now())
+		diskTrimScheduler = boshdisk.NewTrimScheduler(diskManager.FakeTrimmer, diskManager.FakeMounter, diskTrimClock, logger)
 		dirProvider = boshdirs.NewProvider("/fake-dir")
 		cdutil = fakedevutil.NewFakeDeviceUtil()
 		compressor = boshcmd.NewTarballCompressor(cmdRunner, fs)
 		copier = boshcmd.NewGenericCpCopier(fs, logger)
-		vitalsService = boshvitals.NewService(collector, dirProvider)
+		vitalsService = boshvitals.NewService(collector, dirProvider, boshsettings.NewManagedDisks(fs, dirProvider.BoshDir()), nil, logger)
 		netManager = &fakenet.FakeManager{}
 		certManager = new(fakecert.FakeManager)
 		monitRetryStrategy = fakeretry.NewFakeRetryStrategy()
@@ -111,6 +120,7 @@ func describeLinuxPlatform() {
 			vitalsService,
 			cdutil,
 			diskManager,
+			diskTrimScheduler,
 			netManager,
 			certManager,
 			monitRetryStrategy,
@@ -319,6 +329,7 @@ bosh_foobar:...`
 					vitalsService,
 					cdutil,
 					diskManager,
+					diskTrimScheduler,
 					netManager,
 					certManager,
 					monitRetryStrategy,
@@ -603,6 +614,14 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/.*.log fake-base-p
 
 			itSetsUpEphemeralDisk(act)
 
+			It("mounts data partition with given mount options", func() {
+				err := platform.SetupEphemeralDiskWithPath("/dev/xvda", nil, "discard")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/data"}))
+				Expect(mounter.MountMountOptions).To(Equal([][]string{{"discard"}}))
+			})
+
 			It("returns error if creating data dir fails", func() {
 				fs.MkdirAllError = errors.New("fake-mkdir-all-err")
 
@@ -745,6 +764,58 @@ fake-base-path/data/sys/log/*.log fake-base-path/data/sys/log/.*.log fake-base-p
 						Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/xvda1"}))
 						Expect(len(mounter.SwapOnPartitionPaths)).To(Equal(0))
 					})
+
+					It("keeps existing swap partition without turning it on", func() {
+						var desiredSwapSize uint64
+						act = func() error {
+							return platform.SetupEphemeralDiskWithPath("/dev/xvda", &desiredSwapSize)
+						}
+						partitioner.GetDeviceSizeInBytesSizes["/dev/xvda"] = diskSizeInBytes
+						Expect(fs.WriteFileString("/dev/xvda1", "")).To(Succeed())
+						Expect(fs.WriteFileString("/dev/xvda2", "")).To(Succeed())
+						formatter.FileSystemTypes = map[string]boshdisk.FileSystemType{"/dev/xvda1": boshdisk.FileSystemSwap}
+
+						err := act()
+						Expect(err).NotTo(HaveOccurred())
+						Expect(partitioner.PartitionCalled).To(BeFalse())
+
+						Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/xvda2"}))
+						Expect(len(mounter.SwapOnPartitionPaths)).To(Equal(0))
+						Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/xvda2"}))
+					})
+
+					It("partitions disk when first partition is not swap", func() {
+						var desiredSwapSize uint64
+						act = func() error {
+							return platform.SetupEphemeralDiskWithPath("/dev/xvda", &desiredSwapSize)
+						}
+						partitioner.GetDeviceSizeInBytesSizes["/dev/xvda"] = diskSizeInBytes
+						Expect(fs.WriteFileString("/dev/xvda1", "")).To(Succeed())
+						Expect(fs.WriteFileString("/dev/xvda2", "")).To(Succeed())
+						formatter.FileSystemTypes = map[string]boshdisk.FileSystemType{"/dev/xvda1": boshdisk.FileSystemExt4}
+
+						err := act()
+						Expect(err).NotTo(HaveOccurred())
+						Expect(partitioner.PartitionPartitions).To(Equal([]boshdisk.Partition{
+							{SizeInBytes: diskSizeInBytes, Type: boshdisk.PartitionTypeLinux},
+						}))
+					})
+
+					It("returns error when existing swap partition cannot be checked", func() {
+						var desiredSwapSize uint64
+						act = func() error {
+							return platform.SetupEphemeralDiskWithPath("/dev/xvda", &desiredSwapSize)
+						}
+						partitioner.GetDeviceSizeInBytesSizes["/dev/xvda"] = diskSizeInBytes
+						Expect(fs.WriteFileString("/dev/xvda1", "")).To(Succeed())
+						Expect(fs.WriteFileString("/dev/xvda2", "")).To(Succeed())
+						formatter.FileSystemTypeErr = errors.New("fake-blkid-err")
+
+						err := act()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-blkid-err"))
+						Expect(partitioner.PartitionCalled).To(BeFalse())
+					})
 				})
 
 			})
@@ -1493,6 +1564,188 @@ Number  Start   End     Size    File system  Name             Flags
 		})
 	})
 
+	Describe("SetupPooledEphemeralDisk", func() {
+		var (
+			rawDevices []boshsettings.DiskSettings
+			pool       boshsettings.EphemeralDiskPool
+		)
+
+		BeforeEach(func() {
+			rawDevices = []boshsettings.DiskSettings{{Path: "/dev/nvme1n1"}}
+			pool = boshsettings.EphemeralDiskPool{Enabled: true, Striped: true, StripeSizeInKB: 64}
+			devicePathResolver.RealDevicePath = "/dev/nvme1n1"
+			diskManager.FakeLogicalVolumeManager.SetupDevicePath = "/dev/bosh-ephemeral/data"
+		})
+
+		It("pools ephemeral disks into logical volume and mounts it as data dir", func() {
+			err := platform.SetupPooledEphemeralDisk("/dev/sdb", rawDevices, pool, "discard")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(diskManager.FakeLogicalVolumeManager.SetupSpecs).To(Equal([]boshdisk.LogicalVolumeSpec{{
+				VolumeGroup:    "bosh-ephemeral",
+				Name:           "data",
+				Devices:        []string{"/dev/sdb", "/dev/nvme1n1"},
+				Striped:        true,
+				StripeSizeInKB: 64,
+			}}))
+
+			Expect(diskManager.FakeFormatter.FormatPartitionPaths).To(Equal([]string{"/dev/bosh-ephemeral/data"}))
+			Expect(diskManager.FakeFormatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemExt4}))
+			Expect(diskManager.FakeMounter.MountPartitionPaths).To(Equal([]string{"/dev/bosh-ephemeral/data"}))
+			Expect(diskManager.FakeMounter.MountMountPoints).To(Equal([]string{"/fake-dir/data"}))
+			Expect(diskManager.FakeMounter.MountMountOptions).To(Equal([][]string{{"discard"}}))
+
+			Expect(fs.GetFileTestStat("/fake-dir/data").FileMode).To(Equal(os.FileMode(0750)))
+		})
+
+		It("pools only raw ephemeral disks when there is no ephemeral disk", func() {
+			err := platform.SetupPooledEphemeralDisk("", rawDevices, pool)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskManager.FakeLogicalVolumeManager.SetupSpecs[0].Devices).To(Equal([]string{"/dev/nvme1n1"}))
+		})
+
+		It("returns error when there are no disks to pool", func() {
+			err := platform.SetupPooledEphemeralDisk("", nil, pool)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("No ephemeral disks found to pool"))
+		})
+
+		It("returns error when disks cannot be pooled", func() {
+			diskManager.FakeLogicalVolumeManager.SetupErr = errors.New("fake-lvm-err")
+
+			err := platform.SetupPooledEphemeralDisk("/dev/sdb", rawDevices, pool)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Pooling ephemeral disks: fake-lvm-err"))
+			Expect(diskManager.FakeMounter.MountCalled).To(BeFalse())
+		})
+
+		It("returns error when raw ephemeral disk path cannot be resolved", func() {
+			devicePathResolver.GetRealDevicePathErr = errors.New("fake-resolve-err")
+
+			err := platform.SetupPooledEphemeralDisk("/dev/sdb", rawDevices, pool)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-resolve-err"))
+		})
+
+		It("returns error instead of pooling fewer disks when raw ephemeral disk path times out", func() {
+			devicePathResolver.GetRealDevicePathErr = errors.New("fake-timeout-err")
+			devicePathResolver.GetRealDevicePathTimedOut = true
+
+			err := platform.SetupPooledEphemeralDisk("/dev/sdb", rawDevices, pool)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Timed out resolving raw ephemeral disk '/dev/nvme1n1'"))
+			Expect(diskManager.FakeLogicalVolumeManager.SetupSpecs).To(BeEmpty())
+		})
+
+		Context("when SkipDiskSetup is set", func() {
+			BeforeEach(func() {
+				options.SkipDiskSetup = true
+			})
+
+			It("only creates data dir", func() {
+				err := platform.SetupPooledEphemeralDisk("/dev/sdb", rawDevices, pool)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(diskManager.FakeLogicalVolumeManager.SetupSpecs).To(BeEmpty())
+				Expect(fs.FileExists("/fake-dir/data")).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("StartDiskTrimming", func() {
+		var stopCh chan struct{}
+
+		BeforeEach(func() {
+			stopCh = make(chan struct{})
+			diskManager.FakeMounter.IsMountPointResult = true
+
+			err := boshsettings.NewManagedDisks(fs, dirProvider.BoshDir()).Add(boshsettings.ManagedDisk{
+				DiskCID:    "fake-named-cid",
+				Name:       "wal",
+				MountPoint: "/fake-dir/store/wal",
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		AfterEach(func() {
+			close(stopCh)
+		})
+
+		Context("when trim interval is configured", func() {
+			BeforeEach(func() {
+				options.DiskTrimIntervalInSeconds = 60
+			})
+
+			It("trims data, store and named disk mount points every interval", func() {
+				platform.StartDiskTrimming(stopCh)
+
+				Eventually(diskTrimClock.WatcherCount).Should(Equal(1))
+				diskTrimClock.Increment(60 * time.Second)
+
+				Eventually(func() []string {
+					var mountPoints []string
+					for mountPoint := range diskTrimScheduler.Results() {
+						mountPoints = append(mountPoints, mountPoint)
+					}
+					return mountPoints
+				}).Should(ConsistOf("/fake-dir/data", "/fake-dir/store", "/fake-dir/store/wal"))
+			})
+		})
+
+		Context("when trim interval is not configured", func() {
+			It("does not trim disks", func() {
+				platform.StartDiskTrimming(stopCh)
+
+				Consistently(diskTrimClock.WatcherCount).Should(Equal(0))
+			})
+		})
+	})
+
+	Describe("SetupSwap", func() {
+		It("reconciles swap with swap file kept in data dir", func() {
+			swapSizeInMB := uint64(512)
+
+			err := platform.SetupSwap(boshsettings.Swap{Type: boshdisk.SwapTypeFile, SizeInMB: &swapSizeInMB})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(diskManager.FakeSwapper.ReconcileSpecs).To(Equal([]boshdisk.SwapSpec{{
+				Type:        boshdisk.SwapTypeFile,
+				SizeInBytes: 512 * 1024 * 1024,
+				FilePath:    "/fake-dir/data/swapfile",
+			}}))
+		})
+
+		It("sizes swap after memory when size is not given", func() {
+			collector.MemStats.Total = 2048
+
+			err := platform.SetupSwap(boshsettings.Swap{Type: boshdisk.SwapTypeZram})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskManager.FakeSwapper.ReconcileSpecs[0].SizeInBytes).To(Equal(uint64(2048)))
+		})
+
+		It("returns error when memory size cannot be determined", func() {
+			collector.MemStatsErr = errors.New("fake-memstats-error")
+
+			err := platform.SetupSwap(boshsettings.Swap{Type: boshdisk.SwapTypeZram})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-memstats-error"))
+		})
+
+		It("returns error for unknown swap type", func() {
+			err := platform.SetupSwap(boshsettings.Swap{Type: "fake-type"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Unknown swap type 'fake-type'"))
+			Expect(diskManager.FakeSwapper.ReconcileSpecs).To(BeEmpty())
+		})
+
+		It("returns error when swap cannot be reconciled", func() {
+			diskManager.FakeSwapper.ReconcileErr = errors.New("fake-reconcile-err")
+
+			err := platform.SetupSwap(boshsettings.Swap{Type: boshdisk.SwapTypePartition})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Reconciling swap: fake-reconcile-err"))
+		})
+	})
+
 	Describe("SetupDataDir", func() {
 		var mounter *fakedisk.FakeMounter
 		BeforeEach(func() {
@@ -2416,6 +2669,30 @@ Number  Start   End     Size    File system  Name             Flags
 						})
 					})
 
+					Context("with btrfs", func() {
+						It("formats in using the given format", func() {
+							err := platform.MountPersistentDisk(
+								boshsettings.DiskSettings{Path: "fake-volume-id", FileSystemType: boshdisk.FileSystemBtrfs},
+								"/mnt/point",
+							)
+
+							Expect(err).ToNot(HaveOccurred())
+							Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemBtrfs}))
+						})
+					})
+
+					Context("with ext3", func() {
+						It("formats in using the given format", func() {
+							err := platform.MountPersistentDisk(
+								boshsettings.DiskSettings{Path: "fake-volume-id", FileSystemType: boshdisk.FileSystemExt3},
+								"/mnt/point",
+							)
+
+							Expect(err).ToNot(HaveOccurred())
+							Expect(formatter.FormatFsTypes).To(Equal([]boshdisk.FileSystemType{boshdisk.FileSystemExt3}))
+						})
+					})
+
 					Context("with an unsupported type", func() {
 						It("it errors", func() {
 							err := platform.MountPersistentDisk(
@@ -2429,6 +2706,25 @@ Number  Start   End     Size    File system  Name             Flags
 					})
 				})
 
+				It("passes mkfs options and label to formatter", func() {
+					err := platform.MountPersistentDisk(
+						boshsettings.DiskSettings{
+							Path:            "fake-volume-id",
+							FileSystemType:  boshdisk.FileSystemExt4,
+							MkfsOptions:     []string{"-i", "4096"},
+							FileSystemLabel: "fake-label",
+						},
+						"/mnt/point",
+					)
+
+					Expect(err).ToNot(HaveOccurred())
+					Expect(formatter.FormatSpecs).To(Equal([]boshdisk.FormatSpec{{
+						FileSystemType: boshdisk.FileSystemExt4,
+						MkfsOptions:    []string{"-i", "4096"},
+						Label:          "fake-label",
+					}}))
+				})
+
 				It("returns an error when disk could not be formatted", func() {
 					formatter.FormatError = errors.New("Oh noes!")
 					err := platform.MountPersistentDisk(
@@ -2459,6 +2755,173 @@ Number  Start   End     Size    File system  Name             Flags
 					Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
 					Expect(mounter.MountMountOptions).To(Equal([][]string{{"mntOpt1", "mntOpt2"}}))
 				})
+
+				Context("when mounting named disk under store directory", func() {
+					actNamed := func() error {
+						return platform.MountPersistentDisk(
+							boshsettings.DiskSettings{ID: "fake-named-id", Path: "fake-volume-id"},
+							"/fake-dir/store/wal",
+						)
+					}
+
+					It("tracks disk in managed disks instead of managed disk settings", func() {
+						err := actNamed()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/store/wal"}))
+
+						managedDisks, err := platform.GetManagedDisks().List()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(managedDisks).To(Equal([]boshsettings.ManagedDisk{
+							{DiskCID: "fake-named-id", Name: "wal", MountPoint: "/fake-dir/store/wal"},
+						}))
+
+						Expect(fs.FileExists("/fake-dir/bosh/managed_disk_settings.json")).To(BeFalse())
+					})
+
+					It("returns error instead of migrating when another device is mounted there", func() {
+						mounter.IsMountPointResult = true
+						mounter.IsMountPointPartitionPath = "/dev/another-device1"
+
+						err := actNamed()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(Equal("Mount point '/fake-dir/store/wal' is already used by device '/dev/another-device1'"))
+						Expect(mounter.MountCalled).To(BeFalse())
+					})
+				})
+
+				Context("when named disks are mounted under store directory", func() {
+					JustBeforeEach(func() {
+						err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{
+							DiskCID:      "fake-named-id",
+							Name:         "wal",
+							MountPoint:   "/fake-dir/store/wal",
+							MountOptions: []string{"noatime"},
+						})
+						Expect(err).ToNot(HaveOccurred())
+					})
+
+					BeforeEach(func() {
+						mounter.IsMountPointStub = func(path string) (string, bool, error) {
+							if path == "/fake-dir/store/wal" {
+								return "/dev/fake-named-device1", true, nil
+							}
+							return "", false, nil
+						}
+					})
+
+					It("mounts disk at store directory without hiding named disks", func() {
+						err := platform.MountPersistentDisk(
+							boshsettings.DiskSettings{ID: "fake-unique-id", Path: "fake-volume-id"},
+							"/fake-dir/store",
+						)
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(mounter.UnmountPartitionPathsOrMountPoints).To(Equal([]string{"/fake-dir/store/wal"}))
+						Expect(mounter.MountPartitionPaths).To(Equal([]string{"fake-real-device-path1", "/dev/fake-named-device1"}))
+						Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/store", "/fake-dir/store/wal"}))
+						Expect(mounter.MountMountOptions[1]).To(Equal([]string{"noatime"}))
+						Expect(fs.FileExists("/fake-dir/store/wal")).To(BeTrue())
+					})
+
+					It("mounts named disks back when mounting disk at store directory fails", func() {
+						mounter.MountErr = errors.New("fake-mount-err")
+
+						err := platform.MountPersistentDisk(
+							boshsettings.DiskSettings{ID: "fake-unique-id", Path: "fake-volume-id"},
+							"/fake-dir/store",
+						)
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(ContainSubstring("fake-mount-err"))
+
+						Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/store", "/fake-dir/store/wal"}))
+					})
+
+					It("does not touch named disks when mounting disk elsewhere", func() {
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+
+						Expect(mounter.UnmountPartitionPathsOrMountPoints).To(BeEmpty())
+						Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
+					})
+				})
+			})
+
+			Context("when persistent disk filesystem check is enabled", func() {
+				var checker *fakedisk.FakeFilesystemChecker
+
+				BeforeEach(func() {
+					options.PersistentDiskFilesystemCheck = "check"
+					checker = diskManager.FakeFilesystemChecker
+				})
+
+				It("checks partition before mounting it and records the outcome", func() {
+					checker.CheckResult = boshdisk.FilesystemCheckResult{FileSystemType: boshdisk.FileSystemExt4, ErrorsFound: true}
+
+					err := act()
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(checker.CheckPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+					Expect(checker.CheckModes).To(Equal([]boshdisk.FilesystemCheckMode{boshdisk.FilesystemCheckModeCheck}))
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"fake-real-device-path1"}))
+
+					checks, err := platform.GetFilesystemCheckHistory().List()
+					Expect(err).ToNot(HaveOccurred())
+					Expect(checks).To(HaveLen(1))
+					Expect(checks[0].DiskCID).To(Equal("fake-unique-id"))
+					Expect(checks[0].FileSystemType).To(Equal(boshdisk.FileSystemExt4))
+					Expect(checks[0].ErrorsFound).To(BeTrue())
+					Expect(checks[0].CheckedAt).To(BeNumerically(">", 0))
+				})
+
+				It("does not mount partition when check fails", func() {
+					checker.CheckErr = errors.New("fake-check-err")
+
+					err := act()
+					Expect(err).To(HaveOccurred())
+					Expect(err.Error()).To(ContainSubstring("Checking persistent disk filesystem: fake-check-err"))
+					Expect(mounter.MountCalled).To(BeFalse())
+				})
+
+				Context("in repair mode", func() {
+					BeforeEach(func() {
+						options.PersistentDiskFilesystemCheck = "repair"
+					})
+
+					It("mounts partition with repaired filesystem", func() {
+						checker.CheckResult = boshdisk.FilesystemCheckResult{ErrorsFound: true, Repaired: true}
+
+						err := act()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(checker.CheckModes).To(Equal([]boshdisk.FilesystemCheckMode{boshdisk.FilesystemCheckModeRepair}))
+						Expect(mounter.MountCalled).To(BeTrue())
+					})
+
+					It("does not mount partition with errors that could not be repaired", func() {
+						checker.CheckResult = boshdisk.FilesystemCheckResult{ErrorsFound: true}
+
+						err := act()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(Equal("Filesystem on persistent disk 'fake-unique-id' has errors that could not be repaired"))
+						Expect(mounter.MountCalled).To(BeFalse())
+
+						checks, err := platform.GetFilesystemCheckHistory().List()
+						Expect(err).ToNot(HaveOccurred())
+						Expect(checks).To(HaveLen(1))
+					})
+				})
+
+				Context("with unknown mode", func() {
+					BeforeEach(func() {
+						options.PersistentDiskFilesystemCheck = "fake-mode"
+					})
+
+					It("returns error", func() {
+						err := act()
+						Expect(err).To(HaveOccurred())
+						Expect(err.Error()).To(Equal("Unknown persistent disk filesystem check mode 'fake-mode'"))
+						Expect(mounter.MountCalled).To(BeFalse())
+					})
+				})
 			})
 
 			Context("when UsePreformattedPersistentDisk set to true", func() {
@@ -2515,6 +2978,109 @@ Number  Start   End     Size    File system  Name             Flags
 			})
 		})
 
+		Context("when persistent disk encryption is enabled", func() {
+			var (
+				encryptor    *fakedisk.FakeEncryptor
+				diskSettings boshsettings.DiskSettings
+			)
+
+			BeforeEach(func() {
+				encryptor = diskManager.FakeEncryptor
+				devicePathResolver.RealDevicePath = "fake-real-device-path"
+				diskSettings = boshsettings.DiskSettings{
+					ID:           "fake/unique-id",
+					Path:         "fake-volume-id",
+					MountOptions: []string{"mntOpt1"},
+					Encryption:   boshsettings.DiskEncryption{Type: "luks", Key: "fake-key"},
+				}
+			})
+
+			It("encrypts partition and formats decrypted device", func() {
+				err := platform.MountPersistentDisk(diskSettings, "/mnt/point")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(encryptor.FormatDevicePaths).To(Equal([]string{"fake-real-device-path1"}))
+				Expect(encryptor.FormatKeys).To(Equal([]string{"fake-key"}))
+				Expect(encryptor.OpenDevicePaths).To(Equal([]string{"fake-real-device-path1"}))
+				Expect(encryptor.OpenNames).To(Equal([]string{"bosh-persistent-fake_unique-id"}))
+				Expect(encryptor.OpenKeys).To(Equal([]string{"fake-key"}))
+
+				Expect(formatter.FormatPartitionPaths).To(Equal([]string{"/dev/mapper/bosh-persistent-fake_unique-id"}))
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/bosh-persistent-fake_unique-id"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/mnt/point"}))
+			})
+
+			It("reads key from key path", func() {
+				diskSettings.Encryption = boshsettings.DiskEncryption{Type: "luks", KeyPath: "/fake-key-path"}
+				err := fs.WriteFileString("/fake-key-path", "fake-file-key")
+				Expect(err).ToNot(HaveOccurred())
+
+				err = platform.MountPersistentDisk(diskSettings, "/mnt/point")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(encryptor.OpenKeys).To(Equal([]string{"fake-file-key"}))
+			})
+
+			It("returns error when key is not provided", func() {
+				diskSettings.Encryption = boshsettings.DiskEncryption{Type: "luks"}
+
+				err := platform.MountPersistentDisk(diskSettings, "/mnt/point")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Persistent disk encryption requires key or key_path"))
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+
+			It("returns error when encryption type is not supported", func() {
+				diskSettings.Encryption = boshsettings.DiskEncryption{Type: "fake-type", Key: "fake-key"}
+
+				err := platform.MountPersistentDisk(diskSettings, "/mnt/point")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(Equal(`The persistent disk encryption type "fake-type" is not supported`))
+			})
+
+			It("returns error when partition cannot be encrypted", func() {
+				encryptor.FormatErr = errors.New("fake-format-err")
+
+				err := platform.MountPersistentDisk(diskSettings, "/mnt/point")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-format-err"))
+				Expect(formatter.FormatCalled).To(BeFalse())
+			})
+
+			It("returns error when decrypted device cannot be opened", func() {
+				encryptor.OpenErr = errors.New("fake-open-err")
+
+				err := platform.MountPersistentDisk(diskSettings, "/mnt/point")
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-open-err"))
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+
+			It("skips mounting when decrypted device is already mounted", func() {
+				mounter.IsMountPointResult = true
+				mounter.IsMountPointPartitionPath = "/dev/mapper/bosh-persistent-fake_unique-id"
+
+				err := platform.MountPersistentDisk(diskSettings, "/mnt/point")
+				Expect(err).ToNot(HaveOccurred())
+				Expect(mounter.MountCalled).To(BeFalse())
+			})
+
+			Context("when UsePreformattedPersistentDisk set to true", func() {
+				BeforeEach(func() {
+					options.UsePreformattedPersistentDisk = true
+				})
+
+				It("opens encrypted device without formatting it", func() {
+					err := platform.MountPersistentDisk(diskSettings, "/mnt/point")
+					Expect(err).ToNot(HaveOccurred())
+
+					Expect(encryptor.FormatDevicePaths).To(BeEmpty())
+					Expect(encryptor.OpenDevicePaths).To(Equal([]string{"fake-real-device-path"}))
+					Expect(formatter.FormatCalled).To(BeFalse())
+					Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/mapper/bosh-persistent-fake_unique-id"}))
+				})
+			})
+		})
+
 		Context("when device path is not successfully resolved", func() {
 			It("return an error", func() {
 				devicePathResolver.GetRealDevicePathErr = errors.New("fake-get-real-device-path-err")
@@ -2578,6 +3144,45 @@ Number  Start   End     Size    File system  Name             Flags
 
 		})
 
+		It("unmounts named disks while unmounting disk at store directory and mounts them back", func() {
+			devicePathResolver.RealDevicePath = "fake-real-device-path"
+			mounter.UnmountDidUnmount = true
+			mounter.IsMountPointStub = func(path string) (string, bool, error) {
+				return "/dev/fake-named-device1", path == "/fake-dir/store/wal", nil
+			}
+
+			err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{DiskCID: "fake-named-id", Name: "wal", MountPoint: "/fake-dir/store/wal"})
+			Expect(err).ToNot(HaveOccurred())
+
+			didUnmount, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "fake-store-id", Path: "fake-device-path"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(didUnmount).To(BeTrue())
+
+			Expect(mounter.UnmountPartitionPathsOrMountPoints).To(Equal([]string{"/fake-dir/store/wal", "fake-real-device-path1"}))
+			Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/fake-named-device1"}))
+			Expect(mounter.MountMountPoints).To(Equal([]string{"/fake-dir/store/wal"}))
+
+			managedDisks, err := platform.GetManagedDisks().List()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(managedDisks).To(HaveLen(1))
+		})
+
+		It("stops tracking named disk after unmounting it", func() {
+			devicePathResolver.RealDevicePath = "fake-real-device-path"
+			mounter.UnmountDidUnmount = true
+
+			err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{DiskCID: "fake-named-id", Name: "wal", MountPoint: "/fake-dir/store/wal"})
+			Expect(err).ToNot(HaveOccurred())
+
+			didUnmount, err := platform.UnmountPersistentDisk(boshsettings.DiskSettings{ID: "fake-named-id", Path: "fake-device-path"})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(didUnmount).To(BeTrue())
+
+			managedDisks, err := platform.GetManagedDisks().List()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(managedDisks).To(BeEmpty())
+		})
+
 		Context("when device path can be resolved", func() {
 			BeforeEach(func() {
 				devicePathResolver.RealDevicePath = "fake-real-device-path"
@@ -2653,6 +3258,47 @@ Number  Start   End     Size    File system  Name             Flags
 				Expect(isMounted).To(BeFalse())
 			})
 		})
+
+		Context("when persistent disk encryption is enabled", func() {
+			BeforeEach(func() {
+				devicePathResolver.RealDevicePath = "fake-real-device-path"
+			})
+
+			encryptedAct := func() (bool, error) {
+				return platform.UnmountPersistentDisk(boshsettings.DiskSettings{
+					ID:         "fake-disk-id",
+					Path:       "fake-device-path",
+					Encryption: boshsettings.DiskEncryption{Type: "luks", Key: "fake-key"},
+				})
+			}
+
+			It("unmounts decrypted device and closes it", func() {
+				mounter.UnmountDidUnmount = true
+
+				didUnmount, err := encryptedAct()
+				Expect(err).NotTo(HaveOccurred())
+				Expect(didUnmount).To(BeTrue())
+				Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/dev/mapper/bosh-persistent-fake-disk-id"))
+				Expect(diskManager.FakeEncryptor.CloseNames).To(Equal([]string{"bosh-persistent-fake-disk-id"}))
+			})
+
+			It("does not close decrypted device if unmounting fails", func() {
+				mounter.UnmountErr = errors.New("fake-unmount-err")
+
+				_, err := encryptedAct()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-unmount-err"))
+				Expect(diskManager.FakeEncryptor.CloseNames).To(BeEmpty())
+			})
+
+			It("returns error if closing decrypted device fails", func() {
+				diskManager.FakeEncryptor.CloseErr = errors.New("fake-close-err")
+
+				_, err := encryptedAct()
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("fake-close-err"))
+			})
+		})
 	})
 
 	Describe("AssociateDisk", func() {
@@ -2763,17 +3409,277 @@ Number  Start   End     Size    File system  Name             Flags
 		})
 
 		It("migrate persistent disk", func() {
-			err := platform.MigratePersistentDisk("/from/path", "/to/path")
+			err := platform.MigratePersistentDisk("/from/path", "/to/path", func(uint64, uint64) {})
 			Expect(err).ToNot(HaveOccurred())
 
 			Expect(mounter.RemountAsReadonlyPath).To(Equal("/from/path"))
 
-			Expect(len(cmdRunner.RunCommands)).To(Equal(1))
-			Expect(cmdRunner.RunCommands[0]).To(Equal([]string{"sh", "-c", "(tar -C /from/path -cf - .) | (tar -C /to/path -xpf -)"}))
+			treeCopier := diskManager.FakeTreeCopier
+			Expect(treeCopier.CopyFromDir).To(Equal("/from/path"))
+			Expect(treeCopier.CopyToDir).To(Equal("/to/path"))
+			Expect(treeCopier.CopyOptions.CheckpointPath).To(Equal("/to/path/.bosh_migration_checkpoint.json"))
+			Expect(treeCopier.CopyOptions.Verify).To(BeFalse())
 
 			Expect(mounter.UnmountPartitionPathOrMountPoint).To(Equal("/from/path"))
 			Expect(mounter.RemountFromMountPoint).To(Equal("/to/path"))
 			Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+
+			Expect(diskManager.FakeEncryptor.CloseNames).To(BeEmpty())
+		})
+
+		It("reports copy progress", func() {
+			diskManager.FakeTreeCopier.CopyProgress = [][2]uint64{{0, 20}, {10, 20}, {20, 20}}
+
+			var reported [][2]uint64
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path", func(copiedBytes, totalBytes uint64) {
+				reported = append(reported, [2]uint64{copiedBytes, totalBytes})
+			})
+			Expect(err).ToNot(HaveOccurred())
+			Expect(reported).To(Equal([][2]uint64{{0, 20}, {10, 20}, {20, 20}}))
+		})
+
+		Context("when verification of migrated disk is configured", func() {
+			BeforeEach(func() {
+				options.VerifyPersistentDiskMigration = true
+			})
+
+			It("verifies copied files", func() {
+				err := platform.MigratePersistentDisk("/from/path", "/to/path", func(uint64, uint64) {})
+				Expect(err).ToNot(HaveOccurred())
+				Expect(diskManager.FakeTreeCopier.CopyOptions.Verify).To(BeTrue())
+			})
+		})
+
+		Context("when named disks are mounted under old disk", func() {
+			JustBeforeEach(func() {
+				err := platform.GetManagedDisks().Add(boshsettings.ManagedDisk{DiskCID: "fake-named-id", Name: "wal", MountPoint: "/from/path/wal"})
+				Expect(err).ToNot(HaveOccurred())
+			})
+
+			BeforeEach(func() {
+				mounter.IsMountPointStub = func(path string) (string, bool, error) {
+					return "/dev/fake-named-device1", path == "/from/path/wal", nil
+				}
+			})
+
+			It("does not copy named disks and mounts them on the new disk", func() {
+				var unmountedBeforeCopy []string
+				diskManager.FakeTreeCopier.CopyStub = func() {
+					unmountedBeforeCopy = append([]string{}, mounter.UnmountPartitionPathsOrMountPoints...)
+				}
+
+				err := platform.MigratePersistentDisk("/from/path", "/to/path", func(uint64, uint64) {})
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(unmountedBeforeCopy).To(Equal([]string{"/from/path/wal"}))
+				Expect(mounter.UnmountPartitionPathsOrMountPoints).To(Equal([]string{"/from/path/wal", "/from/path"}))
+				Expect(mounter.RemountToMountPoint).To(Equal("/from/path"))
+				Expect(mounter.MountPartitionPaths).To(Equal([]string{"/dev/fake-named-device1"}))
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/from/path/wal"}))
+			})
+
+			It("mounts named disks back when migration fails", func() {
+				diskManager.FakeTreeCopier.CopyErr = errors.New("fake-copy-err")
+
+				err := platform.MigratePersistentDisk("/from/path", "/to/path", func(uint64, uint64) {})
+				Expect(err).To(HaveOccurred())
+
+				Expect(mounter.MountMountPoints).To(Equal([]string{"/from/path/wal"}))
+			})
+		})
+
+		It("does not replace old disk when copying fails", func() {
+			diskManager.FakeTreeCopier.CopyErr = errors.New("fake-copy-err")
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path", func(uint64, uint64) {})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-copy-err"))
+
+			Expect(mounter.UnmountPartitionPathOrMountPoint).To(BeEmpty())
+			Expect(mounter.RemountFromMountPoint).To(BeEmpty())
+		})
+
+		It("closes old encrypted persistent disk after unmounting it", func() {
+			mounter.IsMountPointResult = true
+			mounter.IsMountPointPartitionPath = "/dev/mapper/bosh-persistent-fake-old-disk-id"
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path", func(uint64, uint64) {})
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(mounter.IsMountPointPath).To(Equal("/from/path"))
+			Expect(diskManager.FakeEncryptor.CloseNames).To(Equal([]string{"bosh-persistent-fake-old-disk-id"}))
+		})
+
+		It("returns error when closing old encrypted persistent disk fails", func() {
+			mounter.IsMountPointResult = true
+			mounter.IsMountPointPartitionPath = "/dev/mapper/bosh-persistent-fake-old-disk-id"
+			diskManager.FakeEncryptor.CloseErr = errors.New("fake-close-err")
+
+			err := platform.MigratePersistentDisk("/from/path", "/to/path", func(uint64, uint64) {})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-close-err"))
+			Expect(mounter.RemountFromMountPoint).To(BeEmpty())
+		})
+	})
+
+	Describe("ResizePersistentDisk", func() {
+		var (
+			mounter      *fakedisk.FakeMounter
+			diskSettings boshsettings.DiskSettings
+			diskUtil     *fakedevutil.FakeDeviceUtil
+			partUtil     *fakedevutil.FakeDeviceUtil
+		)
+
+		BeforeEach(func() {
+			mounter = diskManager.FakeMounter
+			mounter.IsMountedResult = true
+
+			devicePathResolver.RealDevicePath = "/dev/sdf"
+			diskSettings = boshsettings.DiskSettings{ID: "fake-disk-id", Path: "fake-device-path"}
+
+			diskUtil = fakedevutil.NewFakeDeviceUtil()
+			diskUtil.GetBlockDeviceSizeSize = 20 * 1024 * 1024 * 1024
+			partUtil = fakedevutil.NewFakeDeviceUtil()
+			partUtil.GetBlockDeviceSizeSize = 10 * 1024 * 1024 * 1024
+
+			diskManager.FakeDiskUtils = map[string]*fakedevutil.FakeDeviceUtil{
+				"/dev/sdf":  diskUtil,
+				"/dev/sdf1": partUtil,
+			}
+		})
+
+		It("grows partition and filesystem when disk is larger than partition", func() {
+			err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(diskManager.FakePartitioner.GrowPartitionDevicePath).To(Equal("/dev/sdf"))
+			Expect(diskManager.FakePartitioner.GrowPartitionPartitionNumber).To(Equal(1))
+			Expect(diskManager.PartedPartitionerCalled).To(BeFalse())
+
+			Expect(diskManager.FakeFormatter.GrowFilesystemPartitionPath).To(Equal("/dev/sdf1"))
+			Expect(diskManager.FakeFormatter.GrowFilesystemMountPoint).To(Equal("/var/vcap/store"))
+		})
+
+		It("uses parted partitioner when partition is larger than fdisk supports", func() {
+			diskUtil.GetBlockDeviceSizeSize = 4 * 1024 * 1024 * 1024 * 1024
+			partUtil.GetBlockDeviceSizeSize = 3 * 1024 * 1024 * 1024 * 1024
+
+			err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(diskManager.FakePartedPartitioner.GrowPartitionDevicePath).To(Equal("/dev/sdf"))
+			Expect(diskManager.PartitionerCalled).To(BeFalse())
+		})
+
+		It("returns error when fdisk partition would grow beyond 2TB", func() {
+			diskUtil.GetBlockDeviceSizeSize = 3 * 1024 * 1024 * 1024 * 1024
+
+			err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("cannot grow beyond"))
+			Expect(diskManager.FakeFormatter.GrowFilesystemPartitionPath).To(BeEmpty())
+		})
+
+		It("only grows filesystem when partition already spans the disk", func() {
+			diskUtil.GetBlockDeviceSizeSize = partUtil.GetBlockDeviceSizeSize + 2*1024*1024
+
+			err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(diskManager.FakePartitioner.GrowPartitionDevicePath).To(BeEmpty())
+			Expect(diskManager.FakeFormatter.GrowFilesystemPartitionPath).To(Equal("/dev/sdf1"))
+		})
+
+		It("returns error when disk is not mounted", func() {
+			mounter.IsMountedResult = false
+
+			err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(Equal("Persistent disk 'fake-disk-id' must be mounted to be resized"))
+		})
+
+		It("returns error when growing partition fails", func() {
+			diskManager.FakePartitioner.GrowPartitionErr = errors.New("fake-grow-partition-err")
+
+			err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-grow-partition-err"))
+			Expect(diskManager.FakeFormatter.GrowFilesystemPartitionPath).To(BeEmpty())
+		})
+
+		It("returns error when getting disk size fails", func() {
+			diskUtil.GetBlockDeviceSizeError = errors.New("fake-size-err")
+
+			err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-size-err"))
+		})
+
+		It("returns error when growing filesystem fails", func() {
+			diskManager.FakeFormatter.GrowFilesystemErr = errors.New("fake-grow-fs-err")
+
+			err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-grow-fs-err"))
+		})
+
+		It("resizes decrypted device before growing filesystem when disk is encrypted", func() {
+			diskSettings.Encryption = boshsettings.DiskEncryption{Type: "luks", Key: "fake-key"}
+
+			err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(diskManager.FakePartitioner.GrowPartitionDevicePath).To(Equal("/dev/sdf"))
+			Expect(diskManager.FakeEncryptor.ResizeNames).To(Equal([]string{"bosh-persistent-fake-disk-id"}))
+			Expect(diskManager.FakeEncryptor.ResizeKeys).To(Equal([]string{"fake-key"}))
+			Expect(diskManager.FakeFormatter.GrowFilesystemPartitionPath).To(Equal("/dev/mapper/bosh-persistent-fake-disk-id"))
+		})
+
+		Context("when UsePreformattedPersistentDisk set to true", func() {
+			BeforeEach(func() {
+				options.UsePreformattedPersistentDisk = true
+			})
+
+			It("only grows filesystem on the device", func() {
+				err := platform.ResizePersistentDisk(diskSettings, "/var/vcap/store")
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(diskManager.FakePartitioner.GrowPartitionDevicePath).To(BeEmpty())
+				Expect(diskManager.FakeFormatter.GrowFilesystemPartitionPath).To(Equal("/dev/sdf"))
+			})
+		})
+	})
+
+	Describe("FreezePersistentDisk", func() {
+		It("freezes filesystem at mount point", func() {
+			err := platform.FreezePersistentDisk("/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"fsfreeze", "--freeze", "/var/vcap/store"}))
+		})
+
+		It("returns error when freezing fails", func() {
+			cmdRunner.AddCmdResult("fsfreeze --freeze /var/vcap/store", fakesys.FakeCmdResult{Error: errors.New("fake-freeze-err")})
+
+			err := platform.FreezePersistentDisk("/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-freeze-err"))
+		})
+	})
+
+	Describe("ThawPersistentDisk", func() {
+		It("thaws filesystem at mount point", func() {
+			err := platform.ThawPersistentDisk("/var/vcap/store")
+			Expect(err).ToNot(HaveOccurred())
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"fsfreeze", "--unfreeze", "/var/vcap/store"}))
+		})
+
+		It("returns error when thawing fails", func() {
+			cmdRunner.AddCmdResult("fsfreeze --unfreeze /var/vcap/store", fakesys.FakeCmdResult{Error: errors.New("fake-thaw-err")})
+
+			err := platform.ThawPersistentDisk("/var/vcap/store")
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("fake-thaw-err"))
 		})
 	})
 
@@ -2911,6 +3817,22 @@ Number  Start   End     Size    File system  Name             Flags
 				Expect(isMounted).To(BeFalse())
 			})
 		})
+
+		Context("when persistent disk encryption is enabled", func() {
+			It("checks whether decrypted device is mounted", func() {
+				devicePathResolver.RealDevicePath = "fake-real-device-path"
+				mounter.IsMountedResult = true
+
+				isMounted, err := platform.IsPersistentDiskMounted(boshsettings.DiskSettings{
+					ID:         "fake-disk-id",
+					Path:       "fake-device-path",
+					Encryption: boshsettings.DiskEncryption{Type: "luks", Key: "fake-key"},
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(isMounted).To(BeTrue())
+				Expect(mounter.IsMountedArgsForCall(0)).To(Equal("/dev/mapper/bosh-persistent-fake-disk-id"))
+			})
+		})
 	})
 
 	Describe("IsPersistentDiskMountable", func() {
@@ -3094,6 +4016,55 @@ unit: sectors
 		})
 	})
 
+	Describe("DetectNetworkDrift", func() {
+		It("delegates to the NetManager", func() {
+			networks := boshsettings.Networks{"fake-net": boshsettings.Network{IP: "1.2.3.4"}}
+			validator := boship.NewInterfaceAddressesValidator(&fakeip.FakeInterfaceAddressesProvider{})
+			netManager.DetectDriftDrift = boshnet.Drift{ConfigsChanged: true}
+
+			drift, err := platform.DetectNetworkDrift(networks, validator)
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift).To(Equal(boshnet.Drift{ConfigsChanged: true}))
+
+			Expect(netManager.DetectDriftNetworks).To(Equal(networks))
+			Expect(netManager.DetectDriftValidator).To(Equal(validator))
+		})
+	})
+
+	Describe("RepairNetworking", func() {
+		It("delegates to the NetManager", func() {
+			networks := boshsettings.Networks{"fake-net": boshsettings.Network{IP: "1.2.3.4"}}
+			netManager.RepairNetworkingErr = errors.New("fake-repair-err")
+
+			err := platform.RepairNetworking(networks)
+			Expect(err).To(MatchError("fake-repair-err"))
+
+			Expect(netManager.RepairNetworkingNetworks).To(Equal(networks))
+		})
+
+		It("waits for networking that is being set up", func() {
+			networks := boshsettings.Networks{"fake-net": boshsettings.Network{IP: "1.2.3.4"}}
+
+			setupStarted := make(chan struct{})
+			finishSetup := make(chan struct{})
+			netManager.SetupNetworkingCallBack = func() {
+				close(setupStarted)
+				<-finishSetup
+			}
+
+			go platform.SetupNetworking(networks)
+			Eventually(setupStarted).Should(BeClosed())
+
+			repairErrCh := make(chan error, 1)
+			go func() { repairErrCh <- platform.RepairNetworking(networks) }()
+
+			Consistently(repairErrCh).ShouldNot(Receive())
+
+			close(finishSetup)
+			Eventually(repairErrCh).Should(Receive(BeNil()))
+		})
+	})
+
 	Describe("GetConfiguredNetworkInterfaces", func() {
 		It("delegates to the NetManager", func() {
 			netmanagerInterfaces := []string{"fake-eth0", "fake-eth1"}
@@ -1,6 +1,7 @@
 package arp
 
 import (
+	"net"
 	"path"
 	"sync"
 	"time"
@@ -86,6 +87,12 @@ func (a arping) broadcastMACAddress(address boship.InterfaceAddress) {
 
 	ifaceName := address.GetInterfaceName()
 
+	// IPv6 neighbours are announced by the kernel via unsolicited neighbour advertisements
+	if parsedIP := net.ParseIP(ip); parsedIP != nil && parsedIP.To4() == nil {
+		a.logger.Debug(arpingLogTag, "Skipping arping of IPv6 address '%s' on '%s'", ip, ifaceName)
+		return
+	}
+
 	_, _, _, err = a.cmdRunner.RunCommand("arping", "-c", "1", "-U", "-I", ifaceName, ip)
 	if err != nil {
 		a.logger.Info(arpingLogTag, "Ignoring arping failure: %s", err.Error())
@@ -73,6 +73,20 @@ var _ = Describe("arping", func() {
 			Expect(countB).To(Equal(arpingIterations))
 		})
 
+		It("runs arping commands for each IPv4 address of interface", func() {
+			addresses := []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "192.168.195.6"),
+				boship.NewSimpleInterfaceAddress("eth0", "192.168.195.7"),
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8::6"),
+			}
+
+			arping.BroadcastMACAddresses(addresses)
+
+			Expect(cmdRunner.RunCommands).To(HaveLen(arpingIterations * 2))
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"arping", "-c", "1", "-U", "-I", "eth0", "192.168.195.6"}))
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"arping", "-c", "1", "-U", "-I", "eth0", "192.168.195.7"}))
+		})
+
 		It("does not run arping command if failed to get interface IP address", func() {
 			addresses := []boship.InterfaceAddress{failingInterfaceAddress{}}
 
@@ -53,40 +53,21 @@ func NewCentosNetManager(
 func (net centosNetManager) SetupIPv6(_ boshsettings.IPv6, _ <-chan struct{}) error { return nil }
 
 func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
-	nonVipNetworks := boshsettings.Networks{}
-	for networkName, networkSettings := range networks {
-		if networkSettings.IsVIP() {
-			continue
-		}
-		nonVipNetworks[networkName] = networkSettings
-	}
-
-	staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := net.buildInterfaces(nonVipNetworks)
+	staticInterfaceConfigurations, dhcpInterfaceConfigurations, dnsServers, err := net.computeNetworkConfig(networks)
 	if err != nil {
 		return err
 	}
 
-	dnsNetwork, _ := nonVipNetworks.DefaultNetworkFor("dns")
-	dnsServers := dnsNetwork.DNS
-
-	interfacesChanged, err := net.writeNetworkInterfaces(dhcpInterfaceConfigurations, staticInterfaceConfigurations, dnsServers)
+	changed, err := net.writeNetConfigs(dhcpInterfaceConfigurations, staticInterfaceConfigurations, dnsServers, boshsys.ConvergeFileContentsOpts{})
 	if err != nil {
-		return bosherr.WrapError(err, "Writing network configuration")
-	}
-
-	dhcpChanged := false
-	if len(dhcpInterfaceConfigurations) > 0 {
-		dhcpChanged, err = net.writeDHCPConfiguration(dnsServers, dhcpInterfaceConfigurations)
-		if err != nil {
-			return err
-		}
+		return err
 	}
 
-	if interfacesChanged || dhcpChanged {
+	if changed {
 		net.restartNetworkingInterfaces()
 	}
 
-	staticAddresses, dynamicAddresses := net.ifaceAddresses(staticInterfaceConfigurations, dhcpInterfaceConfigurations)
+	staticAddresses, dynamicAddresses := ifaceAddresses(staticInterfaceConfigurations, dhcpInterfaceConfigurations, net.ipResolver)
 
 	err = net.interfaceAddressesValidator.Validate(staticAddresses)
 	if err != nil {
@@ -98,15 +79,95 @@ func (net centosNetManager) SetupNetworking(networks boshsettings.Networks, errC
 		return bosherr.WrapError(err, "Validating dns configuration")
 	}
 
-	net.broadcastIps(append(staticAddresses, dynamicAddresses...), errCh)
+	broadcastIps(net.addressBroadcaster, append(staticAddresses, dynamicAddresses...), errCh)
 
 	return nil
 }
 
+// DetectDrift checks preconfigured networks like any other network
+// since SetupNetworking writes their configuration as well
+func (net centosNetManager) DetectDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (Drift, error) {
+	staticConfigs, dhcpConfigs, dnsServers, err := net.computeNetworkConfig(networks)
+	if err != nil {
+		return Drift{}, bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	changed, err := net.writeNetConfigs(dhcpConfigs, staticConfigs, dnsServers, boshsys.ConvergeFileContentsOpts{DryRun: true})
+	if err != nil {
+		return Drift{}, bosherr.WrapError(err, "Determining if network configs have changed")
+	}
+
+	staticAddresses, _ := ifaceAddresses(staticConfigs, dhcpConfigs, net.ipResolver)
+
+	return Drift{
+		ConfigsChanged: changed,
+		AddressesErr:   interfaceAddressesValidator.Validate(staticAddresses),
+		DNSErr:         net.dnsValidator.Validate(dnsServers),
+	}, nil
+}
+
+func (net centosNetManager) RepairNetworking(networks boshsettings.Networks) error {
+	staticConfigs, dhcpConfigs, dnsServers, err := net.computeNetworkConfig(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	_, err = net.writeNetConfigs(dhcpConfigs, staticConfigs, dnsServers, boshsys.ConvergeFileContentsOpts{})
+	if err != nil {
+		return bosherr.WrapError(err, "Updating network configs")
+	}
+
+	net.restartNetworkingInterfaces()
+
+	return nil
+}
+
+func (net centosNetManager) computeNetworkConfig(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, []string, error) {
+	nonVipNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if networkSettings.IsVIP() {
+			continue
+		}
+		nonVipNetworks[networkName] = networkSettings
+	}
+
+	staticConfigs, dhcpConfigs, err := net.buildInterfaces(nonVipNetworks)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	dnsNetwork, _ := nonVipNetworks.DefaultNetworkFor("dns")
+	dnsServers := dnsNetwork.DNS
+	return staticConfigs, dhcpConfigs, dnsServers, nil
+}
+
+func (net centosNetManager) writeNetConfigs(
+	dhcpConfigs []DHCPInterfaceConfiguration,
+	staticConfigs []StaticInterfaceConfiguration,
+	dnsServers []string,
+	opts boshsys.ConvergeFileContentsOpts) (bool, error) {
+
+	interfacesChanged, err := net.writeNetworkInterfaces(dhcpConfigs, staticConfigs, dnsServers, opts)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Writing network configuration")
+	}
+
+	dhcpChanged := false
+
+	if len(dhcpConfigs) > 0 {
+		dhcpChanged, err = net.writeDHCPConfiguration(dnsServers, dhcpConfigs, opts)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return (interfacesChanged || dhcpChanged), nil
+}
+
 func (net centosNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 	interfaces := []string{}
 
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return interfaces, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -120,28 +181,119 @@ func (net centosNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 	return interfaces, nil
 }
 
-const centosDHCPIfcfgTemplate = `DEVICE={{ .Name }}
+const centosDHCPIfcfgTemplate = `DEVICE={{ .Name }}{{ template "virtual-interface" .VirtualInterface }}
 BOOTPROTO=dhcp
 ONBOOT=yes
 PEERDNS=yes
 `
 
-const centosStaticIfcfgTemplate = `DEVICE={{ .Name }}
+const centosStaticIfcfgTemplate = `DEVICE={{ .Name }}{{ template "virtual-interface" .VirtualInterface }}
 BOOTPROTO=static
 IPADDR={{ .Address }}
 NETMASK={{ .Netmask }}
 BROADCAST={{ .Broadcast }}{{if .IsDefaultForGateway}}
-GATEWAY={{ .Gateway }}{{end}}
+GATEWAY={{ .Gateway }}{{end}}{{ if .MTU }}
+MTU={{ .MTU }}{{ end }}{{ range .IPv4SecondaryAddresses }}
+IPADDR{{ .Index }}={{ .Address }}
+PREFIX{{ .Index }}={{ .PrefixLength }}{{ end }}{{ with .IPv6SecondaryAddresses }}
+IPV6INIT=yes
+IPV6ADDR={{ index . 0 }}{{ if gt (len .) 1 }}
+IPV6ADDR_SECONDARIES="{{ range $i, $address := slice . 1 }}{{ if $i }} {{ end }}{{ $address }}{{ end }}"{{ end }}{{ end }}
 ONBOOT=yes
 PEERDNS=no{{ range .DNSServers }}
 DNS{{ .Index }}={{ .Address }}{{ end }}
 `
 
+const centosVirtualInterfaceTemplate = `{{ define "virtual-interface" }}{{ if .IsVLAN }}
+VLAN=yes
+VLAN_ID={{ .VLANID }}
+PHYSDEV={{ .Parent }}{{ else if .IsBond }}
+TYPE=Bond
+BONDING_MASTER=yes
+BONDING_OPTS="mode={{ .BondMode }} miimon=100"{{ else if .IsBridge }}
+TYPE=Bridge
+STP=no
+DELAY={{ .ForwardDelaySec }}{{ end }}{{ end }}`
+
+const centosMemberIfcfgTemplate = `DEVICE={{ .Name }}
+BOOTPROTO=none
+ONBOOT=yes{{ if .IsBond }}
+MASTER={{ .Master }}
+SLAVE=yes{{ else }}
+BRIDGE={{ .Master }}{{ end }}
+`
+
+// Routes are written in ip command format since it is the only format
+// that allows to specify routing table
+const centosStaticRoutesTemplate = `{{ range .VersionRoutes }}{{ .To }} via {{ .Gateway }} dev {{ $.Name }}
+{{ end }}{{ if .RoutingTable }}{{ if .HasVersionAddress }}{{ .NetworkCIDR }} dev {{ .Name }} src {{ .Address }} table {{ .RoutingTable }}
+{{ if .Gateway }}default via {{ .Gateway }} dev {{ .Name }} table {{ .RoutingTable }}
+{{ end }}{{ end }}{{ range .VersionRoutes }}{{ .To }} via {{ .Gateway }} dev {{ $.Name }} table {{ $.RoutingTable }}
+{{ end }}{{ end }}`
+
+const centosStaticRulesTemplate = `{{ if .RoutingTable }}{{ if .HasVersionAddress }}from {{ .Address }} table {{ .RoutingTable }}
+{{ end }}{{ end }}`
+
+// centosRoutes selects routes of a single IP version since initscripts
+// pass route-<iface> to 'ip route' and route6-<iface> to 'ip -6 route'
+type centosRoutes struct {
+	*StaticInterfaceConfiguration
+	ForVersion6 bool
+}
+
+func (r centosRoutes) VersionRoutes() []StaticRouteConfiguration {
+	routes := []StaticRouteConfiguration{}
+	for _, route := range r.Routes {
+		if route.IsVersion6() == r.ForVersion6 {
+			routes = append(routes, route)
+		}
+	}
+	return routes
+}
+
+func (r centosRoutes) HasVersionAddress() bool {
+	return r.IsVersion6() == r.ForVersion6
+}
+
+func (r centosRoutes) filePrefix(prefix string) string {
+	if r.ForVersion6 {
+		return prefix + "6"
+	}
+	return prefix
+}
+
 type centosStaticIfcfg struct {
 	*StaticInterfaceConfiguration
 	DNSServers []dnsConfig
 }
 
+type centosSecondaryAddress struct {
+	Index int
+	StaticAddressConfiguration
+}
+
+// IPv4SecondaryAddresses are numbered from 1 since IPADDR0 is the same as IPADDR
+func (c centosStaticIfcfg) IPv4SecondaryAddresses() []centosSecondaryAddress {
+	addresses := []centosSecondaryAddress{}
+	for _, address := range c.SecondaryAddresses {
+		if !address.IsVersion6() {
+			addresses = append(addresses, centosSecondaryAddress{Index: len(addresses) + 1, StaticAddressConfiguration: address})
+		}
+	}
+	return addresses
+}
+
+// IPv6SecondaryAddresses are in CIDR notation since initscripts take prefix length with the address
+func (c centosStaticIfcfg) IPv6SecondaryAddresses() []string {
+	addresses := []string{}
+	for _, address := range c.SecondaryAddresses {
+		if address.IsVersion6() {
+			addresses = append(addresses, address.CIDR())
+		}
+	}
+	return addresses
+}
+
 type dnsConfig struct {
 	Index   int
 	Address string
@@ -156,10 +308,49 @@ func newDNSConfigs(dnsServers []string) []dnsConfig {
 }
 
 func ifcfgFilePath(name string) string {
-	return path.Join("/etc/sysconfig/network-scripts", "ifcfg-"+name)
+	return networkScriptFilePath("ifcfg", name)
+}
+
+func networkScriptFilePath(prefix, name string) string {
+	return path.Join("/etc/sysconfig/network-scripts", prefix+"-"+name)
+}
+
+// convergeOptionalFileContents removes previously written file
+// when there is nothing to write to it anymore
+func convergeOptionalFileContents(fs boshsys.FileSystem, filePath string, t *template.Template, config interface{}, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	err := t.Execute(buffer, config)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Generating '%s' from template", filePath)
+	}
+
+	if buffer.Len() == 0 {
+		if !fs.FileExists(filePath) {
+			return false, nil
+		}
+
+		if opts.DryRun {
+			return true, nil
+		}
+
+		err = fs.RemoveAll(filePath)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Removing '%s'", filePath)
+		}
+
+		return true, nil
+	}
+
+	changed, err := fs.ConvergeFileContents(filePath, buffer.Bytes(), opts)
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Writing config to '%s'", filePath)
+	}
+
+	return changed, nil
 }
 
-func (net centosNetManager) writeIfcfgFile(name string, t *template.Template, config interface{}) (bool, error) {
+func (net centosNetManager) writeIfcfgFile(name string, t *template.Template, config interface{}, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
 	buffer := bytes.NewBuffer([]byte{})
 
 	err := t.Execute(buffer, config)
@@ -168,7 +359,7 @@ func (net centosNetManager) writeIfcfgFile(name string, t *template.Template, co
 	}
 
 	filePath := ifcfgFilePath(name)
-	changed, err := net.fs.ConvergeFileContents(filePath, buffer.Bytes())
+	changed, err := net.fs.ConvergeFileContents(filePath, buffer.Bytes(), opts)
 	if err != nil {
 		return false, bosherr.WrapErrorf(err, "Writing config to '%s'", filePath)
 	}
@@ -176,30 +367,49 @@ func (net centosNetManager) writeIfcfgFile(name string, t *template.Template, co
 	return changed, nil
 }
 
-func (net centosNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsServers []string) (bool, error) {
+func (net centosNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsServers []string, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
 	anyInterfaceChanged := false
 
 	staticConfig := centosStaticIfcfg{}
 	staticConfig.DNSServers = newDNSConfigs(dnsServers)
-	staticTemplate := template.Must(template.New("ifcfg").Parse(centosStaticIfcfgTemplate))
+	staticTemplate := template.Must(template.Must(template.New("ifcfg").Parse(centosStaticIfcfgTemplate)).Parse(centosVirtualInterfaceTemplate))
+	routesTemplate := template.Must(template.New("route").Parse(centosStaticRoutesTemplate))
+	rulesTemplate := template.Must(template.New("rule").Parse(centosStaticRulesTemplate))
 
 	for i := range staticInterfaceConfigurations {
 		staticConfig.StaticInterfaceConfiguration = &staticInterfaceConfigurations[i]
+		name := staticConfig.StaticInterfaceConfiguration.Name
 
-		changed, err := net.writeIfcfgFile(staticConfig.StaticInterfaceConfiguration.Name, staticTemplate, staticConfig)
+		changed, err := net.writeIfcfgFile(name, staticTemplate, staticConfig, opts)
 		if err != nil {
 			return false, bosherr.WrapError(err, "Writing static config")
 		}
 
 		anyInterfaceChanged = anyInterfaceChanged || changed
+
+		for _, forVersion6 := range []bool{false, true} {
+			routes := centosRoutes{StaticInterfaceConfiguration: staticConfig.StaticInterfaceConfiguration, ForVersion6: forVersion6}
+
+			routesChanged, err := convergeOptionalFileContents(net.fs, networkScriptFilePath(routes.filePrefix("route"), name), routesTemplate, routes, opts)
+			if err != nil {
+				return false, bosherr.WrapError(err, "Writing static routes")
+			}
+
+			rulesChanged, err := convergeOptionalFileContents(net.fs, networkScriptFilePath(routes.filePrefix("rule"), name), rulesTemplate, routes, opts)
+			if err != nil {
+				return false, bosherr.WrapError(err, "Writing routing rules")
+			}
+
+			anyInterfaceChanged = anyInterfaceChanged || routesChanged || rulesChanged
+		}
 	}
 
-	dhcpTemplate := template.Must(template.New("ifcfg").Parse(centosDHCPIfcfgTemplate))
+	dhcpTemplate := template.Must(template.Must(template.New("ifcfg").Parse(centosDHCPIfcfgTemplate)).Parse(centosVirtualInterfaceTemplate))
 
 	for i := range dhcpInterfaceConfigurations {
 		config := &dhcpInterfaceConfigurations[i]
 
-		changed, err := net.writeIfcfgFile(config.Name, dhcpTemplate, config)
+		changed, err := net.writeIfcfgFile(config.Name, dhcpTemplate, config, opts)
 		if err != nil {
 			return false, bosherr.WrapError(err, "Writing dhcp config")
 		}
@@ -207,11 +417,22 @@ func (net centosNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations [
 		anyInterfaceChanged = anyInterfaceChanged || changed
 	}
 
+	memberTemplate := template.Must(template.New("ifcfg").Parse(centosMemberIfcfgTemplate))
+
+	for _, config := range MemberInterfaceConfigurations(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		changed, err := net.writeIfcfgFile(config.Name, memberTemplate, config, opts)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Writing member config")
+		}
+
+		anyInterfaceChanged = anyInterfaceChanged || changed
+	}
+
 	return anyInterfaceChanged, nil
 }
 
 func (net centosNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return nil, nil, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -225,15 +446,6 @@ func (net centosNetManager) buildInterfaces(networks boshsettings.Networks) ([]S
 	return staticInterfaceConfigurations, dhcpInterfaceConfigurations, nil
 }
 
-func (net centosNetManager) broadcastIps(addresses []boship.InterfaceAddress, errCh chan error) {
-	go func() {
-		net.addressBroadcaster.BroadcastMACAddresses(addresses)
-		if errCh != nil {
-			errCh <- nil
-		}
-	}()
-}
-
 func (net centosNetManager) restartNetworkingInterfaces() {
 	net.logger.Debug(centosNetManagerLogTag, "Restarting network interfaces")
 
@@ -258,7 +470,7 @@ request subnet-mask, broadcast-address, time-offset, routers,
 prepend domain-name-servers {{ . }};{{ end }}
 `
 
-func (net centosNetManager) writeDHCPConfiguration(dnsServers []string, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) (bool, error) {
+func (net centosNetManager) writeDHCPConfiguration(dnsServers []string, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
 	buffer := bytes.NewBuffer([]byte{})
 	t := template.Must(template.New("dhcp-config").Parse(centosDHCPConfigTemplate))
 
@@ -270,12 +482,16 @@ func (net centosNetManager) writeDHCPConfiguration(dnsServers []string, dhcpInte
 		return false, bosherr.WrapError(err, "Generating config from template")
 	}
 	dhclientConfigFile := "/etc/dhcp/dhclient.conf"
-	changed, err := net.fs.ConvergeFileContents(dhclientConfigFile, buffer.Bytes())
+	changed, err := net.fs.ConvergeFileContents(dhclientConfigFile, buffer.Bytes(), opts)
 
 	if err != nil {
 		return changed, bosherr.WrapErrorf(err, "Writing to %s", dhclientConfigFile)
 	}
 
+	if opts.DryRun {
+		return changed, nil
+	}
+
 	for i := range dhcpInterfaceConfigurations {
 		name := dhcpInterfaceConfigurations[i].Name
 		interfaceDhclientConfigFile := path.Join("/etc/dhcp/", "dhclient-"+name+".conf")
@@ -287,44 +503,3 @@ func (net centosNetManager) writeDHCPConfiguration(dnsServers []string, dhcpInte
 
 	return changed, nil
 }
-
-func (net centosNetManager) detectMacAddresses() (map[string]string, error) {
-	addresses := map[string]string{}
-
-	filePaths, err := net.fs.Glob("/sys/class/net/*")
-	if err != nil {
-		return addresses, bosherr.WrapError(err, "Getting file list from /sys/class/net")
-	}
-
-	var macAddress string
-	for _, filePath := range filePaths {
-		isPhysicalDevice := net.fs.FileExists(path.Join(filePath, "device"))
-
-		if isPhysicalDevice {
-			macAddress, err = net.fs.ReadFileString(path.Join(filePath, "address"))
-			if err != nil {
-				return addresses, bosherr.WrapError(err, "Reading mac address from file")
-			}
-
-			macAddress = strings.Trim(macAddress, "\n")
-
-			interfaceName := path.Base(filePath)
-			addresses[macAddress] = interfaceName
-		}
-	}
-
-	return addresses, nil
-}
-
-func (net centosNetManager) ifaceAddresses(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) ([]boship.InterfaceAddress, []boship.InterfaceAddress) {
-	staticAddresses := []boship.InterfaceAddress{}
-	for _, iface := range staticConfigs {
-		staticAddresses = append(staticAddresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.Address))
-	}
-	dynamicAddresses := []boship.InterfaceAddress{}
-	for _, iface := range dhcpConfigs {
-		dynamicAddresses = append(dynamicAddresses, boship.NewResolvingInterfaceAddress(iface.Name, net.ipResolver))
-	}
-
-	return staticAddresses, dynamicAddresses
-}
@@ -161,6 +161,235 @@ prepend domain-name-servers 8.8.8.8, 9.9.9.9;
 			Expect(dhcpConfig.StringContents()).To(Equal(expectedNetworkConfigurationForDHCP))
 		})
 
+		It("writes network scripts for bond and its members and for VLAN interfaces", func() {
+			bondNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:     boshsettings.VirtualInterfaceTypeBond,
+					Name:     "bond0",
+					Members:  []string{"fake-mac-1", "fake-mac-2"},
+					BondMode: "802.3ad",
+				},
+			}
+			vlanNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeVLAN,
+					Members: []string{"fake-mac-0"},
+					VLANID:  100,
+				},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": {Mac: "fake-mac-0"},
+				"eth1": {Mac: "fake-mac-1"},
+				"eth2": {Mac: "fake-mac-2"},
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("bond0", "5.6.7.8"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"bond": bondNetwork, "vlan": vlanNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			bondConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-bond0")
+			Expect(bondConfig).ToNot(BeNil())
+			Expect(bondConfig.StringContents()).To(Equal(`DEVICE=bond0
+TYPE=Bond
+BONDING_MASTER=yes
+BONDING_OPTS="mode=802.3ad miimon=100"
+BOOTPROTO=static
+IPADDR=5.6.7.8
+NETMASK=255.255.255.0
+BROADCAST=5.6.7.255
+ONBOOT=yes
+PEERDNS=no
+`))
+
+			memberConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-eth2")
+			Expect(memberConfig).ToNot(BeNil())
+			Expect(memberConfig.StringContents()).To(Equal(`DEVICE=eth2
+BOOTPROTO=none
+ONBOOT=yes
+MASTER=bond0
+SLAVE=yes
+`))
+
+			vlanConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-eth0.100")
+			Expect(vlanConfig).ToNot(BeNil())
+			Expect(vlanConfig.StringContents()).To(Equal(`DEVICE=eth0.100
+VLAN=yes
+VLAN_ID=100
+PHYSDEV=eth0
+BOOTPROTO=dhcp
+ONBOOT=yes
+PEERDNS=yes
+`))
+
+			Expect(fs.FileExists("/etc/sysconfig/network-scripts/ifcfg-eth0")).To(BeFalse())
+		})
+
+		It("writes secondary addresses of static network to ifcfg", func() {
+			staticNetwork.SecondaryAddresses = []boshsettings.Address{
+				{IP: "1.2.3.5", Netmask: "255.255.255.0"},
+				{IP: "2001:db8::5", Netmask: "ffff:ffff:ffff:ffff::"},
+				{IP: "1.2.3.6", Netmask: "255.255.0.0"},
+				{IP: "2001:db8::6", Netmask: "ffff:ffff:ffff:ffff::"},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.5"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.6"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::5"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::6"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+			Expect(staticConfig).ToNot(BeNil())
+			Expect(staticConfig.StringContents()).To(Equal(`DEVICE=ethstatic
+BOOTPROTO=static
+IPADDR=1.2.3.4
+NETMASK=255.255.255.0
+BROADCAST=1.2.3.255
+IPADDR1=1.2.3.5
+PREFIX1=24
+IPADDR2=1.2.3.6
+PREFIX2=16
+IPV6INIT=yes
+IPV6ADDR=2001:db8::5/64
+IPV6ADDR_SECONDARIES="2001:db8::6/64"
+ONBOOT=yes
+PEERDNS=no
+`))
+		})
+
+		It("writes default route of the other address family for dual-stack interface to route6 file", func() {
+			staticNetwork.Default = []string{"gateway"}
+			ipv6Network := boshsettings.Network{
+				Type:    "manual",
+				IP:      "2001:db8::5",
+				Netmask: "ffff:ffff:ffff:ffff::",
+				Gateway: "2001:db8::1",
+				Mac:     "fake-static-mac-address",
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::5"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork, "ipv6-network": ipv6Network}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+			Expect(staticConfig).ToNot(BeNil())
+			Expect(staticConfig.StringContents()).To(ContainSubstring("\nIPV6INIT=yes\nIPV6ADDR=2001:db8::5/64\n"))
+
+			route6Config := fs.GetFileTestStat("/etc/sysconfig/network-scripts/route6-ethstatic")
+			Expect(route6Config).ToNot(BeNil())
+			Expect(route6Config.StringContents()).To(Equal("default via 2001:db8::1 dev ethstatic\n"))
+
+			Expect(fs.FileExists("/etc/sysconfig/network-scripts/route-ethstatic")).To(BeFalse())
+		})
+
+		Context("when static network has mtu, static routes and routing table", func() {
+			BeforeEach(func() {
+				staticNetwork.MTU = 9000
+				staticNetwork.Routes = boshsettings.Routes{
+					{Destination: "10.10.0.0", Netmask: "255.255.0.0", Gateway: "1.2.3.254"},
+				}
+				staticNetwork.RoutingTable = 100
+
+				stubInterfaces(map[string]boshsettings.Network{
+					"ethstatic": staticNetwork,
+				})
+			})
+
+			It("writes mtu to ifcfg and routes and rules to their own files", func() {
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				staticConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic")
+				Expect(staticConfig).ToNot(BeNil())
+				Expect(staticConfig.StringContents()).To(Equal(`DEVICE=ethstatic
+BOOTPROTO=static
+IPADDR=1.2.3.4
+NETMASK=255.255.255.0
+BROADCAST=1.2.3.255
+MTU=9000
+ONBOOT=yes
+PEERDNS=no
+`))
+
+				routeConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/route-ethstatic")
+				Expect(routeConfig).ToNot(BeNil())
+				Expect(routeConfig.StringContents()).To(Equal(`10.10.0.0/16 via 1.2.3.254 dev ethstatic
+1.2.3.0/24 dev ethstatic src 1.2.3.4 table 100
+default via 3.4.5.6 dev ethstatic table 100
+10.10.0.0/16 via 1.2.3.254 dev ethstatic table 100
+`))
+
+				ruleConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/rule-ethstatic")
+				Expect(ruleConfig).ToNot(BeNil())
+				Expect(ruleConfig.StringContents()).To(Equal("from 1.2.3.4 table 100\n"))
+			})
+
+			It("writes IPv6 routes to route6 file", func() {
+				staticNetwork.Routes = append(staticNetwork.Routes, boshsettings.Route{
+					Destination: "2001:db8:1::", Netmask: "ffff:ffff:ffff::", Gateway: "2001:db8::1",
+				})
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				routeConfig := fs.GetFileTestStat("/etc/sysconfig/network-scripts/route-ethstatic")
+				Expect(routeConfig).ToNot(BeNil())
+				Expect(routeConfig.StringContents()).To(Equal(`10.10.0.0/16 via 1.2.3.254 dev ethstatic
+1.2.3.0/24 dev ethstatic src 1.2.3.4 table 100
+default via 3.4.5.6 dev ethstatic table 100
+10.10.0.0/16 via 1.2.3.254 dev ethstatic table 100
+`))
+
+				route6Config := fs.GetFileTestStat("/etc/sysconfig/network-scripts/route6-ethstatic")
+				Expect(route6Config).ToNot(BeNil())
+				Expect(route6Config.StringContents()).To(Equal(`2001:db8:1::/48 via 2001:db8::1 dev ethstatic
+2001:db8:1::/48 via 2001:db8::1 dev ethstatic table 100
+`))
+
+				Expect(fs.FileExists("/etc/sysconfig/network-scripts/rule6-ethstatic")).To(BeFalse())
+			})
+
+			It("removes route and rule files when routes and routing table are no longer configured", func() {
+				fs.WriteFileString("/etc/sysconfig/network-scripts/route-ethstatic", "fake-routes")
+				fs.WriteFileString("/etc/sysconfig/network-scripts/rule-ethstatic", "fake-rules")
+
+				staticNetwork.Routes = nil
+				staticNetwork.RoutingTable = 0
+
+				err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+				Expect(err).ToNot(HaveOccurred())
+
+				Expect(fs.FileExists("/etc/sysconfig/network-scripts/route-ethstatic")).To(BeFalse())
+				Expect(fs.FileExists("/etc/sysconfig/network-scripts/rule-ethstatic")).To(BeFalse())
+				Expect(cmdRunner.RunCommands).To(ContainElement([]string{"service", "network", "restart"}))
+			})
+		})
+
 		It("returns errors from glob /sys/class/net/", func() {
 			fs.GlobErr = errors.New("fs-glob-error")
 			err := netManager.SetupNetworking(boshsettings.Networks{"dhcp-network": dhcpNetwork, "static-network": staticNetwork}, nil)
@@ -586,6 +815,102 @@ DNS1=8.8.8.8
 
 	})
 
+	Describe("DetectDrift", func() {
+		var networks boshsettings.Networks
+
+		BeforeEach(func() {
+			networks = boshsettings.Networks{
+				"manual": boshsettings.Network{
+					Type:    "manual",
+					IP:      "1.2.3.4",
+					Default: []string{"dns", "gateway"},
+					DNS:     []string{"8.8.8.8"},
+					Netmask: "255.255.255.0",
+					Gateway: "3.4.5.6",
+					Mac:     "fake-static-mac-address",
+				},
+			}
+			fs.SetGlob("/sys/class/net/*", []string{writeNetworkDevice("ethstatic", "fake-static-mac-address", true)})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+			}
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 8.8.8.8\n")
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			cmdRunner.RunCommands = [][]string{}
+		})
+
+		It("does not detect drift right after networking was set up", func() {
+			drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Detected()).To(BeFalse())
+		})
+
+		It("detects modified ifcfg file without rewriting it", func() {
+			fs.WriteFileString("/etc/sysconfig/network-scripts/ifcfg-ethstatic", "modified")
+
+			drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeTrue())
+
+			Expect(fs.GetFileTestStat("/etc/sysconfig/network-scripts/ifcfg-ethstatic").StringContents()).To(Equal("modified"))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("detects added route file without removing it", func() {
+			fs.WriteFileString("/etc/sysconfig/network-scripts/route-ethstatic", "10.0.0.0/8 via 1.2.3.1 dev ethstatic\n")
+
+			drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeTrue())
+
+			Expect(fs.FileExists("/etc/sysconfig/network-scripts/route-ethstatic")).To(BeTrue())
+		})
+
+		It("detects dropped addresses and rewritten /etc/resolv.conf", func() {
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{}
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 127.0.0.1\n")
+
+			drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeFalse())
+			Expect(drift.AddressesErr).To(HaveOccurred())
+			Expect(drift.DNSErr).To(HaveOccurred())
+		})
+	})
+
+	Describe("RepairNetworking", func() {
+		It("restarts networking even if configuration did not change", func() {
+			networks := boshsettings.Networks{
+				"manual": boshsettings.Network{
+					Type:    "manual",
+					IP:      "1.2.3.4",
+					Default: []string{"dns", "gateway"},
+					DNS:     []string{"8.8.8.8"},
+					Netmask: "255.255.255.0",
+					Gateway: "3.4.5.6",
+					Mac:     "fake-static-mac-address",
+				},
+			}
+			fs.SetGlob("/sys/class/net/*", []string{writeNetworkDevice("ethstatic", "fake-static-mac-address", true)})
+
+			err := netManager.(DriftDetector).RepairNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = netManager.(DriftDetector).RepairNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/sysconfig/network-scripts/ifcfg-ethstatic")).To(BeTrue())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"service", "network", "restart"},
+				{"service", "network", "restart"},
+			}))
+		})
+	})
+
 	Describe("GetConfiguredNetworkInterfaces", func() {
 		Context("when there are network devices", func() {
 			BeforeEach(func() {
@@ -12,13 +12,29 @@ type DNSValidator interface {
 	Validate([]string) error
 }
 
+const (
+	resolvConfPath         = "/etc/resolv.conf"
+	resolvedResolvConfPath = "/run/systemd/resolve/resolv.conf"
+)
+
 type dnsValidator struct {
-	fs boshsys.FileSystem
+	fs             boshsys.FileSystem
+	resolvConfPath string
 }
 
 func NewDNSValidator(fs boshsys.FileSystem) DNSValidator {
 	return &dnsValidator{
-		fs: fs,
+		fs:             fs,
+		resolvConfPath: resolvConfPath,
+	}
+}
+
+// NewResolvedDNSValidator checks DNS servers known to systemd-resolved
+// since /etc/resolv.conf only points at its local stub resolver
+func NewResolvedDNSValidator(fs boshsys.FileSystem) DNSValidator {
+	return &dnsValidator{
+		fs:             fs,
+		resolvConfPath: resolvedResolvConfPath,
 	}
 }
 
@@ -27,9 +43,9 @@ func (d *dnsValidator) Validate(dnsServers []string) error {
 		return nil
 	}
 
-	resolvConfContents, err := d.fs.ReadFileString("/etc/resolv.conf")
+	resolvConfContents, err := d.fs.ReadFileString(d.resolvConfPath)
 	if err != nil {
-		return bosherr.WrapError(err, "Reading /etc/resolv.conf")
+		return bosherr.WrapErrorf(err, "Reading %s", d.resolvConfPath)
 	}
 
 	for _, dnsServer := range dnsServers {
@@ -46,5 +62,5 @@ func (d *dnsValidator) Validate(dnsServers []string) error {
 		}
 	}
 
-	return bosherr.WrapError(err, "None of the DNS servers that were specified in the manifest were found in /etc/resolv.conf.")
+	return bosherr.WrapErrorf(err, "None of the DNS servers that were specified in the manifest were found in %s.", d.resolvConfPath)
 }
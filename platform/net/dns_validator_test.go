@@ -52,4 +52,22 @@ var _ = Describe("DNSValidator", func() {
 			Expect(err.Error()).To(ContainSubstring("None of the DNS servers that were specified in the manifest were found in /etc/resolv.conf."))
 		})
 	})
+
+	Context("when validating DNS servers known to systemd-resolved", func() {
+		BeforeEach(func() {
+			dnsValidator = NewResolvedDNSValidator(fs)
+
+			fs.WriteFileString("/etc/resolv.conf", `nameserver 127.0.0.53`)
+			fs.WriteFileString("/run/systemd/resolve/resolv.conf", `nameserver 8.8.8.8`)
+		})
+
+		It("checks resolv.conf generated by systemd-resolved", func() {
+			err := dnsValidator.Validate([]string{"8.8.8.8"})
+			Expect(err).ToNot(HaveOccurred())
+
+			err = dnsValidator.Validate([]string{"9.9.9.9"})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("were found in /run/systemd/resolve/resolv.conf."))
+		})
+	})
 })
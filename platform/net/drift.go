@@ -0,0 +1,68 @@
+package net
+
+import (
+	"strings"
+
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+)
+
+// Drift describes how networking on the box differs from
+// the configuration that agent applied for networks
+type Drift struct {
+	// ConfigsChanged is set when configuration files on disk
+	// differ from the ones that would be written for networks
+	ConfigsChanged bool
+
+	AddressesErr error
+	DNSErr       error
+}
+
+func (d Drift) Detected() bool {
+	return d.ConfigsChanged || d.AddressesErr != nil || d.DNSErr != nil
+}
+
+func (d Drift) String() string {
+	problems := []string{}
+
+	if d.ConfigsChanged {
+		problems = append(problems, "network configuration files were modified")
+	}
+
+	if d.AddressesErr != nil {
+		problems = append(problems, d.AddressesErr.Error())
+	}
+
+	if d.DNSErr != nil {
+		problems = append(problems, d.DNSErr.Error())
+	}
+
+	return strings.Join(problems, "; ")
+}
+
+// DriftCheck is the outcome of comparing networking with
+// the configuration applied during bootstrap
+type DriftCheck struct {
+	CheckedAt int64
+	Drift     Drift
+	Repaired  bool
+	RepairErr error
+
+	// Alerted is set once health monitor was told about drift
+	Alerted bool
+}
+
+// DriftDetector is implemented by managers that can tell whether
+// networking still matches networks without touching the box
+type DriftDetector interface {
+	// DetectDrift only compares configuration files (no files are written);
+	// addresses are checked with given validator so that caller
+	// can decide whether to wait for addresses to show up.
+	// Managers that leave interfaces of preconfigured networks alone
+	// only check DNS for them
+	DetectDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (Drift, error)
+
+	// RepairNetworking rewrites configuration and restarts networking
+	// even if configuration files did not change
+	RepairNetworking(networks boshsettings.Networks) error
+}
@@ -1,6 +1,8 @@
 package fakes
 
 import (
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 )
 
@@ -9,6 +11,7 @@ type FakeManager struct {
 
 	SetupNetworkingNetworks boshsettings.Networks
 	SetupNetworkingErr      error
+	SetupNetworkingCallBack func()
 
 	SetupIPv6Config boshsettings.IPv6
 	SetupIPv6StopCh <-chan struct{}
@@ -19,6 +22,14 @@ type FakeManager struct {
 
 	SetupDhcpNetworks boshsettings.Networks
 	SetupDhcpErr      error
+
+	DetectDriftNetworks  boshsettings.Networks
+	DetectDriftValidator boship.InterfaceAddressesValidator
+	DetectDriftDrift     boshnet.Drift
+	DetectDriftErr       error
+
+	RepairNetworkingNetworks boshsettings.Networks
+	RepairNetworkingErr      error
 }
 
 func (net *FakeManager) SetupIPv6(config boshsettings.IPv6, stopCh <-chan struct{}) error {
@@ -29,6 +40,9 @@ func (net *FakeManager) SetupIPv6(config boshsettings.IPv6, stopCh <-chan struct
 
 func (net *FakeManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
 	net.SetupNetworkingNetworks = networks
+	if net.SetupNetworkingCallBack != nil {
+		net.SetupNetworkingCallBack()
+	}
 	return net.SetupNetworkingErr
 }
 
@@ -40,3 +54,14 @@ func (net *FakeManager) SetupDhcp(networks boshsettings.Networks, errCh chan err
 	net.SetupDhcpNetworks = networks
 	return net.SetupDhcpErr
 }
+
+func (net *FakeManager) DetectDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (boshnet.Drift, error) {
+	net.DetectDriftNetworks = networks
+	net.DetectDriftValidator = interfaceAddressesValidator
+	return net.DetectDriftDrift, net.DetectDriftErr
+}
+
+func (net *FakeManager) RepairNetworking(networks boshsettings.Networks) error {
+	net.RepairNetworkingNetworks = networks
+	return net.RepairNetworkingErr
+}
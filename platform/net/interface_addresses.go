@@ -0,0 +1,74 @@
+package net
+
+import (
+	"path"
+	"strings"
+
+	bosharp "github.com/cloudfoundry/bosh-agent/platform/net/arp"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+// ifaceAddresses returns addresses expected on configured interfaces;
+// addresses of DHCP interfaces are only known once they are resolved
+func ifaceAddresses(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration, ipResolver boship.Resolver) ([]boship.InterfaceAddress, []boship.InterfaceAddress) {
+	staticAddresses := []boship.InterfaceAddress{}
+	for _, iface := range staticConfigs {
+		staticAddresses = append(staticAddresses, interfaceAddress(iface.VirtualInterface, boship.NewSimpleInterfaceAddress(iface.Name, iface.Address)))
+		for _, address := range iface.SecondaryAddresses {
+			staticAddresses = append(staticAddresses, interfaceAddress(iface.VirtualInterface, boship.NewSimpleInterfaceAddress(iface.Name, address.Address)))
+		}
+	}
+	dynamicAddresses := []boship.InterfaceAddress{}
+	for _, iface := range dhcpConfigs {
+		dynamicAddresses = append(dynamicAddresses, interfaceAddress(iface.VirtualInterface, boship.NewResolvingInterfaceAddress(iface.Name, ipResolver)))
+	}
+
+	return staticAddresses, dynamicAddresses
+}
+
+func interfaceAddress(virtualInterface VirtualInterfaceConfiguration, address boship.InterfaceAddress) boship.InterfaceAddress {
+	if virtualInterface.IsVirtual() {
+		return boship.NewVirtualInterfaceAddress(address)
+	}
+	return address
+}
+
+func broadcastIps(addressBroadcaster bosharp.AddressBroadcaster, addresses []boship.InterfaceAddress, errCh chan error) {
+	go func() {
+		addressBroadcaster.BroadcastMACAddresses(addresses)
+		if errCh != nil {
+			errCh <- nil
+		}
+	}()
+}
+
+// detectMacAddresses maps MAC addresses of physical devices to their interface names
+func detectMacAddresses(fs boshsys.FileSystem) (map[string]string, error) {
+	addresses := map[string]string{}
+
+	filePaths, err := fs.Glob("/sys/class/net/*")
+	if err != nil {
+		return addresses, bosherr.WrapError(err, "Getting file list from /sys/class/net")
+	}
+
+	var macAddress string
+	for _, filePath := range filePaths {
+		isPhysicalDevice := fs.FileExists(path.Join(filePath, "device"))
+
+		if isPhysicalDevice {
+			macAddress, err = fs.ReadFileString(path.Join(filePath, "address"))
+			if err != nil {
+				return addresses, bosherr.WrapError(err, "Reading mac address from file")
+			}
+
+			macAddress = strings.Trim(macAddress, "\n")
+
+			interfaceName := path.Base(filePath)
+			addresses[macAddress] = interfaceName
+		}
+	}
+
+	return addresses, nil
+}
@@ -1,7 +1,9 @@
 package net
 
 import (
+	"fmt"
 	"net"
+	"sort"
 	"strconv"
 
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -19,6 +21,11 @@ type StaticInterfaceConfiguration struct {
 	IsDefaultForGateway bool
 	Mac                 string
 	Gateway             string
+	MTU                 int
+	Routes              []StaticRouteConfiguration
+	RoutingTable        int
+	VirtualInterface    VirtualInterfaceConfiguration
+	SecondaryAddresses  []StaticAddressConfiguration
 }
 
 func (c StaticInterfaceConfiguration) Version6() string {
@@ -40,6 +47,173 @@ func (c StaticInterfaceConfiguration) NetmaskOrLen() string {
 	return c.Netmask
 }
 
+// NetworkCIDR is the connected subnet of the interface, e.g. 10.0.0.0/24
+func (c StaticInterfaceConfiguration) NetworkCIDR() string {
+	return cidr(c.Address, c.Netmask)
+}
+
+func (c StaticInterfaceConfiguration) hasVersion6SecondaryAddress() bool {
+	for _, address := range c.SecondaryAddresses {
+		if address.IsVersion6() {
+			return true
+		}
+	}
+	return false
+}
+
+type StaticAddressConfiguration struct {
+	Address string
+	Netmask string
+}
+
+// CIDR is the address with its prefix length, e.g. 10.0.0.5/24
+func (a StaticAddressConfiguration) CIDR() string {
+	return a.Address + "/" + a.PrefixLength()
+}
+
+func (a StaticAddressConfiguration) PrefixLength() string {
+	return prefixLength(a.Netmask)
+}
+
+func (a StaticAddressConfiguration) IsVersion6() bool {
+	return net.ParseIP(a.Address).To4() == nil
+}
+
+type StaticRouteConfiguration struct {
+	Destination string
+	Netmask     string
+	Gateway     string
+}
+
+func (r StaticRouteConfiguration) CIDR() string {
+	return cidr(r.Destination, r.Netmask)
+}
+
+// To is the route destination with default routes spelled as 'default'
+// since ifroute files do not take 0.0.0.0/0 or ::/0 for them
+func (r StaticRouteConfiguration) To() string {
+	if r.IsDefault() {
+		return "default"
+	}
+	return r.CIDR()
+}
+
+func (r StaticRouteConfiguration) IsDefault() bool {
+	ones, _ := ipMask(r.Netmask).Size()
+	return ones == 0
+}
+
+func (r StaticRouteConfiguration) IsVersion6() bool {
+	return net.ParseIP(r.Destination).To4() == nil
+}
+
+func cidr(address, netmask string) string {
+	mask := ipMask(netmask)
+	ipNet := net.IPNet{IP: net.ParseIP(address).Mask(mask), Mask: mask}
+	return ipNet.String()
+}
+
+func prefixLength(netmask string) string {
+	ones, _ := ipMask(netmask).Size()
+	return strconv.Itoa(ones)
+}
+
+func ipMask(netmask string) net.IPMask {
+	ip := net.ParseIP(netmask)
+	if ip4 := ip.To4(); ip4 != nil {
+		return net.IPMask(ip4)
+	}
+	return net.IPMask(ip)
+}
+
+// VirtualInterfaceConfiguration is empty for physical interfaces
+type VirtualInterfaceConfiguration struct {
+	Type boshsettings.VirtualInterfaceType
+
+	// Members are names of physical interfaces virtual interface is built on
+	Members []string
+
+	VLANID   int
+	BondMode string
+}
+
+func (c VirtualInterfaceConfiguration) IsVirtual() bool {
+	return c.Type != ""
+}
+
+func (c VirtualInterfaceConfiguration) IsVLAN() bool {
+	return c.Type == boshsettings.VirtualInterfaceTypeVLAN
+}
+
+func (c VirtualInterfaceConfiguration) IsBond() bool {
+	return c.Type == boshsettings.VirtualInterfaceTypeBond
+}
+
+func (c VirtualInterfaceConfiguration) IsBridge() bool {
+	return c.Type == boshsettings.VirtualInterfaceTypeBridge
+}
+
+// Bridge forwarding delay is disabled so that bridge starts forwarding
+// as soon as it comes up instead of waiting for spanning tree
+const bridgeForwardDelaySec = 0
+
+func (c VirtualInterfaceConfiguration) ForwardDelaySec() int {
+	return bridgeForwardDelaySec
+}
+
+// Parent is the interface VLAN is tagged on
+func (c VirtualInterfaceConfiguration) Parent() string {
+	if len(c.Members) == 0 {
+		return ""
+	}
+	return c.Members[0]
+}
+
+// MemberInterfaceConfiguration is a physical interface enslaved to a bond or a bridge
+type MemberInterfaceConfiguration struct {
+	Name   string
+	Master string
+	Type   boshsettings.VirtualInterfaceType
+}
+
+func (c MemberInterfaceConfiguration) IsBond() bool {
+	return c.Type == boshsettings.VirtualInterfaceTypeBond
+}
+
+// MemberInterfaceConfigurations does not include VLAN parents
+// since they are either configured by their own network or brought up with VLAN
+func MemberInterfaceConfigurations(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) []MemberInterfaceConfiguration {
+	memberConfigs := []MemberInterfaceConfiguration{}
+
+	addMembers := func(master string, virtualInterface VirtualInterfaceConfiguration) {
+		if !virtualInterface.IsBond() && !virtualInterface.IsBridge() {
+			return
+		}
+
+		for _, member := range virtualInterface.Members {
+			memberConfigs = append(memberConfigs, MemberInterfaceConfiguration{
+				Name:   member,
+				Master: master,
+				Type:   virtualInterface.Type,
+			})
+		}
+	}
+
+	for _, config := range staticConfigs {
+		addMembers(config.Name, config.VirtualInterface)
+	}
+
+	for _, config := range dhcpConfigs {
+		addMembers(config.Name, config.VirtualInterface)
+	}
+
+	sort.Slice(memberConfigs, func(i, j int) bool {
+		return memberConfigs[i].Name < memberConfigs[j].Name
+	})
+
+	return memberConfigs
+}
+
 type StaticInterfaceConfigurations []StaticInterfaceConfiguration
 
 func (configs StaticInterfaceConfigurations) Len() int {
@@ -56,7 +230,7 @@ func (configs StaticInterfaceConfigurations) Swap(i, j int) {
 
 func (configs StaticInterfaceConfigurations) HasVersion6() bool {
 	for _, config := range configs {
-		if config.IsVersion6() {
+		if config.IsVersion6() || config.hasVersion6SecondaryAddress() {
 			return true
 		}
 	}
@@ -64,8 +238,9 @@ func (configs StaticInterfaceConfigurations) HasVersion6() bool {
 }
 
 type DHCPInterfaceConfiguration struct {
-	Name    string
-	Address string
+	Name             string
+	Address          string
+	VirtualInterface VirtualInterfaceConfiguration
 }
 
 func (c DHCPInterfaceConfiguration) Version6() string {
@@ -106,6 +281,32 @@ func (configs DHCPInterfaceConfigurations) HasVersion6() bool {
 	return false
 }
 
+// Tables 253-255 are reserved by the kernel for default, main and local tables
+const maxRoutingTable = 252
+
+const (
+	// Smallest MTUs every IPv4 and IPv6 link has to support (RFC 791 and RFC 8200)
+	minMTU     = 68
+	minIPv6MTU = 1280
+	maxMTU     = 65535
+)
+
+const (
+	maxVLANID           = 4094
+	maxInterfaceNameLen = 15
+	defaultBondMode     = "active-backup"
+)
+
+var bondModes = map[string]bool{
+	"balance-rr":    true,
+	"active-backup": true,
+	"balance-xor":   true,
+	"broadcast":     true,
+	"802.3ad":       true,
+	"balance-tlb":   true,
+	"balance-alb":   true,
+}
+
 type InterfaceConfigurationCreator interface {
 	CreateInterfaceConfigurations(boshsettings.Networks, map[string]string) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error)
 }
@@ -122,14 +323,25 @@ func NewInterfaceConfigurationCreator(logger boshlog.Logger) InterfaceConfigurat
 	}
 }
 
-func (creator interfaceConfigurationCreator) createInterfaceConfiguration(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration, ifaceName string, networkSettings boshsettings.Network) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
+func (creator interfaceConfigurationCreator) createInterfaceConfiguration(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration, ifaceName string, networkSettings boshsettings.Network, virtualInterface VirtualInterfaceConfiguration) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
 	creator.logger.Debug(creator.logTag, "Creating network configuration with settings: %s", networkSettings)
 
-	if networkSettings.IsDHCP() || networkSettings.Mac == "" {
+	if networkSettings.IsDHCP() || (networkSettings.Mac == "" && !networkSettings.IsVirtual()) {
 		creator.logger.Debug(creator.logTag, "Using dhcp networking")
+
+		// Interfaces configured by DHCP get their routes and MTU from DHCP server
+		if len(networkSettings.Routes) > 0 || networkSettings.MTU != 0 || networkSettings.RoutingTable != 0 {
+			return nil, nil, bosherr.Errorf("Routes, MTU and routing table cannot be configured for DHCP interface '%s'", ifaceName)
+		}
+
+		if len(networkSettings.SecondaryAddresses) > 0 {
+			return nil, nil, bosherr.Errorf("Secondary addresses cannot be configured for DHCP interface '%s'", ifaceName)
+		}
+
 		dhcpConfigs = append(dhcpConfigs, DHCPInterfaceConfiguration{
-			Name:    ifaceName,
-			Address: networkSettings.IP,
+			Name:             ifaceName,
+			Address:          networkSettings.IP,
+			VirtualInterface: virtualInterface,
 		})
 	} else {
 		creator.logger.Debug(creator.logTag, "Using static networking")
@@ -138,6 +350,20 @@ func (creator interfaceConfigurationCreator) createInterfaceConfiguration(static
 			return nil, nil, bosherr.WrapError(err, "Calculating Network and Broadcast")
 		}
 
+		routes, err := creator.createRouteConfigurations(networkSettings.Routes)
+		if err != nil {
+			return nil, nil, bosherr.WrapErrorf(err, "Creating routes for interface '%s'", ifaceName)
+		}
+
+		secondaryAddresses, err := creator.createSecondaryAddressConfigurations(networkSettings.SecondaryAddresses)
+		if err != nil {
+			return nil, nil, bosherr.WrapErrorf(err, "Creating secondary addresses for interface '%s'", ifaceName)
+		}
+
+		if networkSettings.RoutingTable < 0 || networkSettings.RoutingTable > maxRoutingTable {
+			return nil, nil, bosherr.Errorf("Routing table '%d' must be between 1 and %d", networkSettings.RoutingTable, maxRoutingTable)
+		}
+
 		conf := StaticInterfaceConfiguration{
 			Name:                ifaceName,
 			Address:             networkSettings.IP,
@@ -147,21 +373,99 @@ func (creator interfaceConfigurationCreator) createInterfaceConfiguration(static
 			Broadcast:           broadcastAddress,
 			Mac:                 networkSettings.Mac,
 			Gateway:             networkSettings.Gateway,
+			MTU:                 networkSettings.MTU,
+			Routes:              routes,
+			RoutingTable:        networkSettings.RoutingTable,
+			VirtualInterface:    virtualInterface,
+			SecondaryAddresses:  secondaryAddresses,
+		}
+
+		err = creator.validateMTU(conf)
+		if err != nil {
+			return nil, nil, err
 		}
+
 		staticConfigs = append(staticConfigs, conf)
 	}
 	return staticConfigs, dhcpConfigs, nil
 }
 
+// validateMTU leaves MTU of 0 alone since it keeps MTU the link already has
+func (creator interfaceConfigurationCreator) validateMTU(conf StaticInterfaceConfiguration) error {
+	if conf.MTU == 0 {
+		return nil
+	}
+
+	minInterfaceMTU := minMTU
+	if conf.IsVersion6() || conf.hasVersion6SecondaryAddress() {
+		minInterfaceMTU = minIPv6MTU
+	}
+
+	if conf.MTU < minInterfaceMTU || conf.MTU > maxMTU {
+		return bosherr.Errorf("MTU '%d' for interface '%s' must be between %d and %d", conf.MTU, conf.Name, minInterfaceMTU, maxMTU)
+	}
+
+	return nil
+}
+
+func (creator interfaceConfigurationCreator) createRouteConfigurations(routes boshsettings.Routes) ([]StaticRouteConfiguration, error) {
+	var routeConfigs []StaticRouteConfiguration
+
+	for _, route := range routes {
+		if net.ParseIP(route.Destination) == nil {
+			return nil, bosherr.Errorf("Invalid route destination '%s'", route.Destination)
+		}
+
+		if net.ParseIP(route.Netmask) == nil {
+			return nil, bosherr.Errorf("Invalid netmask '%s' for route to '%s'", route.Netmask, route.Destination)
+		}
+
+		if net.ParseIP(route.Gateway) == nil {
+			return nil, bosherr.Errorf("Invalid gateway '%s' for route to '%s'", route.Gateway, route.Destination)
+		}
+
+		routeConfigs = append(routeConfigs, StaticRouteConfiguration{
+			Destination: route.Destination,
+			Netmask:     route.Netmask,
+			Gateway:     route.Gateway,
+		})
+	}
+
+	return routeConfigs, nil
+}
+
+func (creator interfaceConfigurationCreator) createSecondaryAddressConfigurations(addresses []boshsettings.Address) ([]StaticAddressConfiguration, error) {
+	var addressConfigs []StaticAddressConfiguration
+
+	for _, address := range addresses {
+		ip := net.ParseIP(address.IP)
+		if ip == nil {
+			return nil, bosherr.Errorf("Invalid secondary address '%s'", address.IP)
+		}
+
+		netmask := net.ParseIP(address.Netmask)
+		if netmask == nil || (ip.To4() == nil) != (netmask.To4() == nil) {
+			return nil, bosherr.Errorf("Invalid netmask '%s' for secondary address '%s'", address.Netmask, address.IP)
+		}
+
+		addressConfigs = append(addressConfigs, StaticAddressConfiguration{
+			Address: address.IP,
+			Netmask: address.Netmask,
+		})
+	}
+
+	return addressConfigs, nil
+}
+
 func (creator interfaceConfigurationCreator) CreateInterfaceConfigurations(networks boshsettings.Networks, interfacesByMAC map[string]string) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
 	// In cases where we only have one network and it has no MAC address (either because the IAAS doesn't give us one or
 	// it's an old CPI), if we only have one interface, we should map them
 	if len(networks) == 1 && len(interfacesByMAC) == 1 {
 		networkSettings := creator.getFirstNetwork(networks)
-		if networkSettings.Mac == "" {
+		if networkSettings.Mac == "" && !networkSettings.IsVirtual() {
 			var ifaceName string
 			networkSettings.Mac, ifaceName = creator.getFirstInterface(interfacesByMAC)
-			return creator.createInterfaceConfiguration([]StaticInterfaceConfiguration{}, []DHCPInterfaceConfiguration{}, ifaceName, networkSettings)
+			return creator.createInterfaceConfiguration([]StaticInterfaceConfiguration{}, []DHCPInterfaceConfiguration{}, ifaceName, networkSettings, VirtualInterfaceConfiguration{})
 		}
 	}
 
@@ -169,28 +473,137 @@ func (creator interfaceConfigurationCreator) CreateInterfaceConfigurations(netwo
 }
 
 func (creator interfaceConfigurationCreator) createMultipleInterfaceConfigurations(networks boshsettings.Networks, interfacesByMAC map[string]string) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
-	if len(interfacesByMAC) < len(networks) {
-		return nil, nil, bosherr.Errorf("Number of network settings '%d' is greater than the number of network devices '%d'", len(networks), len(interfacesByMAC))
+	physicalNetworks := boshsettings.Networks{}
+	virtualNetworks := boshsettings.Networks{}
+
+	for name, networkSettings := range networks {
+		if networkSettings.IsVirtual() {
+			virtualNetworks[name] = networkSettings
+		} else {
+			physicalNetworks[name] = networkSettings
+		}
 	}
 
-	for name := range networks {
-		if mac := networks[name].Mac; mac != "" {
+	// Networks sharing MAC address are configured on the same interface
+	requiredInterfaces := 0
+	networkMACs := map[string]bool{}
+
+	for name := range physicalNetworks {
+		mac := physicalNetworks[name].Mac
+		if mac == "" || !networkMACs[mac] {
+			requiredInterfaces++
+		}
+		if mac != "" {
+			networkMACs[mac] = true
+		}
+	}
+
+	if len(interfacesByMAC) < requiredInterfaces {
+		return nil, nil, bosherr.Errorf("Number of network settings '%d' is greater than the number of network devices '%d'", requiredInterfaces, len(interfacesByMAC))
+	}
+
+	for name := range physicalNetworks {
+		if mac := physicalNetworks[name].Mac; mac != "" {
 			if _, ok := interfacesByMAC[mac]; !ok {
 				return nil, nil, bosherr.Errorf("No device found for network '%s' with MAC address '%s'", name, mac)
 			}
 		}
 	}
 
+	// Bond and bridge members are keyed by MAC address and point to their master
+	enslavedByMAC := map[string]string{}
+	vlanParentMACs := map[string]bool{}
+	virtualInterfaces := map[string]VirtualInterfaceConfiguration{}
+	virtualInterfaceNames := map[string]string{}
+	networksByVirtualInterfaceName := map[string]string{}
+
+	physicalInterfaceNames := map[string]bool{}
+	for _, ifaceName := range interfacesByMAC {
+		physicalInterfaceNames[ifaceName] = true
+	}
+
+	// Networks are walked in order so that the same duplicate is reported every time
+	virtualNetworkNames := []string{}
+	for name := range virtualNetworks {
+		virtualNetworkNames = append(virtualNetworkNames, name)
+	}
+	sort.Strings(virtualNetworkNames)
+
+	for _, name := range virtualNetworkNames {
+		networkSettings := virtualNetworks[name]
+
+		ifaceName, virtualInterface, err := creator.createVirtualInterfaceConfiguration(name, networkSettings.VirtualInterface, interfacesByMAC)
+		if err != nil {
+			return nil, nil, bosherr.WrapError(err, "Creating virtual interface configuration")
+		}
+
+		if otherName, found := networksByVirtualInterfaceName[ifaceName]; found {
+			return nil, nil, bosherr.Errorf("Interface '%s' is configured for both network '%s' and '%s'", ifaceName, otherName, name)
+		}
+
+		if physicalInterfaceNames[ifaceName] {
+			return nil, nil, bosherr.Errorf("Interface '%s' for network '%s' has the same name as a physical interface", ifaceName, name)
+		}
+
+		networksByVirtualInterfaceName[ifaceName] = name
+
+		for _, mac := range networkSettings.VirtualInterface.Members {
+			if virtualInterface.IsVLAN() {
+				vlanParentMACs[mac] = true
+				continue
+			}
+
+			if master, found := enslavedByMAC[mac]; found {
+				return nil, nil, bosherr.Errorf("Interface '%s' cannot be a member of both '%s' and '%s'", interfacesByMAC[mac], master, ifaceName)
+			}
+
+			if physicalNetworkName, found := creator.networkNameForMac(physicalNetworks, mac); found {
+				return nil, nil, bosherr.Errorf("Interface '%s' is a member of '%s' and cannot be configured for network '%s'", interfacesByMAC[mac], ifaceName, physicalNetworkName)
+			}
+
+			enslavedByMAC[mac] = ifaceName
+		}
+
+		virtualInterfaces[name] = virtualInterface
+		virtualInterfaceNames[name] = ifaceName
+	}
+
+	for mac := range vlanParentMACs {
+		if master, found := enslavedByMAC[mac]; found {
+			return nil, nil, bosherr.Errorf("Interface '%s' is a member of '%s' and cannot be a VLAN parent", interfacesByMAC[mac], master)
+		}
+	}
+
 	// Configure interfaces with network settings matching MAC address.
 	// If we cannot find a network setting with a matching MAC address, configure that interface as DHCP
-	var networkSettings boshsettings.Network
+	// unless it is a bond or bridge member or a VLAN parent
 	var err error
 	staticConfigs := []StaticInterfaceConfiguration{}
 	dhcpConfigs := []DHCPInterfaceConfiguration{}
 
 	for mac, ifaceName := range interfacesByMAC {
-		networkSettings, _ = networks.NetworkForMac(mac)
-		staticConfigs, dhcpConfigs, err = creator.createInterfaceConfiguration(staticConfigs, dhcpConfigs, ifaceName, networkSettings)
+		if _, enslaved := enslavedByMAC[mac]; enslaved {
+			continue
+		}
+
+		macNetworks := physicalNetworks.NetworksForMac(mac)
+		if len(macNetworks) == 0 && vlanParentMACs[mac] {
+			continue
+		}
+
+		networkSettings, err := creator.mergeNetworks(ifaceName, macNetworks)
+		if err != nil {
+			return nil, nil, bosherr.WrapError(err, "Creating interface configuration")
+		}
+
+		staticConfigs, dhcpConfigs, err = creator.createInterfaceConfiguration(staticConfigs, dhcpConfigs, ifaceName, networkSettings, VirtualInterfaceConfiguration{})
+		if err != nil {
+			return nil, nil, bosherr.WrapError(err, "Creating interface configuration")
+		}
+	}
+
+	for name, networkSettings := range virtualNetworks {
+		staticConfigs, dhcpConfigs, err = creator.createInterfaceConfiguration(staticConfigs, dhcpConfigs, virtualInterfaceNames[name], networkSettings, virtualInterfaces[name])
 		if err != nil {
 			return nil, nil, bosherr.WrapError(err, "Creating interface configuration")
 		}
@@ -199,6 +612,147 @@ func (creator interfaceConfigurationCreator) createMultipleInterfaceConfiguratio
 	return staticConfigs, dhcpConfigs, nil
 }
 
+func (creator interfaceConfigurationCreator) createVirtualInterfaceConfiguration(networkName string, virtualInterface boshsettings.VirtualInterface, interfacesByMAC map[string]string) (string, VirtualInterfaceConfiguration, error) {
+	if len(virtualInterface.Members) == 0 {
+		return "", VirtualInterfaceConfiguration{}, bosherr.Errorf("Virtual interface for network '%s' has no members", networkName)
+	}
+
+	members := []string{}
+
+	for _, mac := range virtualInterface.Members {
+		ifaceName, found := interfacesByMAC[mac]
+		if !found {
+			return "", VirtualInterfaceConfiguration{}, bosherr.Errorf("No device found for member of network '%s' with MAC address '%s'", networkName, mac)
+		}
+		members = append(members, ifaceName)
+	}
+
+	config := VirtualInterfaceConfiguration{
+		Type:    virtualInterface.Type,
+		Members: members,
+	}
+	ifaceName := virtualInterface.Name
+
+	switch virtualInterface.Type {
+	case boshsettings.VirtualInterfaceTypeVLAN:
+		if len(members) != 1 {
+			return "", VirtualInterfaceConfiguration{}, bosherr.Errorf("VLAN interface for network '%s' must have exactly one member", networkName)
+		}
+
+		if virtualInterface.VLANID < 1 || virtualInterface.VLANID > maxVLANID {
+			return "", VirtualInterfaceConfiguration{}, bosherr.Errorf("VLAN ID '%d' for network '%s' must be between 1 and %d", virtualInterface.VLANID, networkName, maxVLANID)
+		}
+
+		config.VLANID = virtualInterface.VLANID
+
+		if ifaceName == "" {
+			ifaceName = fmt.Sprintf("%s.%d", members[0], virtualInterface.VLANID)
+		}
+
+	case boshsettings.VirtualInterfaceTypeBond:
+		config.BondMode = virtualInterface.BondMode
+		if config.BondMode == "" {
+			config.BondMode = defaultBondMode
+		}
+
+		if !bondModes[config.BondMode] {
+			return "", VirtualInterfaceConfiguration{}, bosherr.Errorf("Unknown bond mode '%s' for network '%s'", config.BondMode, networkName)
+		}
+
+	case boshsettings.VirtualInterfaceTypeBridge:
+
+	default:
+		return "", VirtualInterfaceConfiguration{}, bosherr.Errorf("Unknown virtual interface type '%s' for network '%s'", virtualInterface.Type, networkName)
+	}
+
+	if ifaceName == "" {
+		return "", VirtualInterfaceConfiguration{}, bosherr.Errorf("Name of %s interface for network '%s' is required", virtualInterface.Type, networkName)
+	}
+
+	if len(ifaceName) > maxInterfaceNameLen {
+		return "", VirtualInterfaceConfiguration{}, bosherr.Errorf("Interface name '%s' for network '%s' is longer than %d characters", ifaceName, networkName, maxInterfaceNameLen)
+	}
+
+	return ifaceName, config, nil
+}
+
+// mergeNetworks configures networks sharing an interface as a single network
+// with IP of the network that is default for gateway (or the first one by name);
+// IPs of other networks become its secondary addresses
+func (creator interfaceConfigurationCreator) mergeNetworks(ifaceName string, networks boshsettings.Networks) (boshsettings.Network, error) {
+	names := []string{}
+	for name := range networks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if len(names) == 0 {
+		return boshsettings.Network{}, nil
+	}
+
+	if len(names) == 1 {
+		return networks[names[0]], nil
+	}
+
+	primaryName := ""
+
+	for _, name := range names {
+		if networks[name].IsDHCP() {
+			return boshsettings.Network{}, bosherr.Errorf("Network '%s' shares interface '%s' with other networks and must be static", name, ifaceName)
+		}
+
+		if primaryName == "" && networks[name].IsDefaultFor("gateway") {
+			primaryName = name
+		}
+	}
+
+	if primaryName == "" {
+		primaryName = names[0]
+	}
+
+	merged := networks[primaryName]
+	merged.SecondaryAddresses = append([]boshsettings.Address{}, merged.SecondaryAddresses...)
+	merged.Routes = append(boshsettings.Routes{}, merged.Routes...)
+
+	primaryIsVersion6 := net.ParseIP(merged.IP).To4() == nil
+	hasOtherDefaultRoute := false
+
+	for _, name := range names {
+		if name == primaryName {
+			continue
+		}
+
+		network := networks[name]
+
+		merged.SecondaryAddresses = append(merged.SecondaryAddresses, boshsettings.Address{IP: network.IP, Netmask: network.Netmask})
+		merged.SecondaryAddresses = append(merged.SecondaryAddresses, network.SecondaryAddresses...)
+		merged.Routes = append(merged.Routes, network.Routes...)
+
+		// Dual-stack interface also needs default route for the other address family
+		isVersion6 := net.ParseIP(network.IP).To4() == nil
+		if merged.IsDefaultFor("gateway") && network.Gateway != "" && isVersion6 != primaryIsVersion6 && !hasOtherDefaultRoute {
+			destination := "0.0.0.0"
+			if isVersion6 {
+				destination = "::"
+			}
+
+			merged.Routes = append(merged.Routes, boshsettings.Route{Destination: destination, Netmask: destination, Gateway: network.Gateway})
+			hasOtherDefaultRoute = true
+		}
+	}
+
+	return merged, nil
+}
+
+func (creator interfaceConfigurationCreator) networkNameForMac(networks boshsettings.Networks, mac string) (string, bool) {
+	for name := range networks {
+		if networks[name].Mac == mac {
+			return name, true
+		}
+	}
+	return "", false
+}
+
 func (creator interfaceConfigurationCreator) getFirstNetwork(networks boshsettings.Networks) boshsettings.Network {
 	for networkName := range networks {
 		return networks[networkName]
@@ -273,6 +273,412 @@ func describeInterfaceConfigurationCreator() {
 		Expect(err).To(HaveOccurred())
 		Expect(err.Error()).To(ContainSubstring("Invalid IP 'not an ip'"))
 	})
+
+	Context("when networks are configured on virtual interfaces", func() {
+		var interfacesByMAC map[string]string
+
+		BeforeEach(func() {
+			interfacesByMAC = map[string]string{
+				"fake-mac-0": "eth0",
+				"fake-mac-1": "eth1",
+				"fake-mac-2": "eth2",
+			}
+		})
+
+		It("creates VLAN interface on top of physical interface carrying its own network", func() {
+			untaggedNetwork := boshsettings.Network{Type: "manual", IP: "1.2.3.4", Netmask: "255.255.255.0", Mac: "fake-mac-0"}
+			vlanNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "10.0.0.4",
+				Netmask: "255.255.255.0",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeVLAN,
+					Members: []string{"fake-mac-0"},
+					VLANID:  100,
+				},
+			}
+
+			staticConfigs, dhcpConfigs, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(
+				boshsettings.Networks{"untagged": untaggedNetwork, "vlan": vlanNetwork}, interfacesByMAC)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(staticConfigs).To(HaveLen(2))
+			Expect(staticConfigs).To(ContainElement(StaticInterfaceConfiguration{
+				Name:      "eth0.100",
+				Address:   "10.0.0.4",
+				Netmask:   "255.255.255.0",
+				Network:   "10.0.0.0",
+				Broadcast: "10.0.0.255",
+				VirtualInterface: VirtualInterfaceConfiguration{
+					Type:    boshsettings.VirtualInterfaceTypeVLAN,
+					Members: []string{"eth0"},
+					VLANID:  100,
+				},
+			}))
+			Expect(dhcpConfigs).To(ConsistOf(
+				DHCPInterfaceConfiguration{Name: "eth1"},
+				DHCPInterfaceConfiguration{Name: "eth2"},
+			))
+		})
+
+		It("creates bond over its members without configuring members themselves", func() {
+			bondNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeBond,
+					Name:    "bond0",
+					Members: []string{"fake-mac-1", "fake-mac-2"},
+				},
+			}
+
+			staticConfigs, dhcpConfigs, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(
+				boshsettings.Networks{"bond": bondNetwork}, interfacesByMAC)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(staticConfigs).To(BeEmpty())
+			Expect(dhcpConfigs).To(ConsistOf(
+				DHCPInterfaceConfiguration{Name: "eth0"},
+				DHCPInterfaceConfiguration{
+					Name: "bond0",
+					VirtualInterface: VirtualInterfaceConfiguration{
+						Type:     boshsettings.VirtualInterfaceTypeBond,
+						Members:  []string{"eth1", "eth2"},
+						BondMode: "active-backup",
+					},
+				},
+			))
+
+			Expect(MemberInterfaceConfigurations(staticConfigs, dhcpConfigs)).To(Equal([]MemberInterfaceConfiguration{
+				{Name: "eth1", Master: "bond0", Type: boshsettings.VirtualInterfaceTypeBond},
+				{Name: "eth2", Master: "bond0", Type: boshsettings.VirtualInterfaceTypeBond},
+			}))
+		})
+
+		It("returns error when bond member is also configured for another network", func() {
+			physicalNetwork := boshsettings.Network{Type: "manual", IP: "1.2.3.4", Netmask: "255.255.255.0", Mac: "fake-mac-1"}
+			bridgeNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeBridge,
+					Name:    "br0",
+					Members: []string{"fake-mac-1"},
+				},
+			}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(
+				boshsettings.Networks{"physical": physicalNetwork, "bridge": bridgeNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Interface 'eth1' is a member of 'br0' and cannot be configured for network 'physical'"))
+		})
+
+		It("returns error when member device cannot be found", func() {
+			bondNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeBond,
+					Name:    "bond0",
+					Members: []string{"fake-mac-1", "fake-missing-mac"},
+				},
+			}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(
+				boshsettings.Networks{"bond": bondNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("No device found for member of network 'bond' with MAC address 'fake-missing-mac'"))
+		})
+
+		It("returns error when bond mode is unknown", func() {
+			bondNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:     boshsettings.VirtualInterfaceTypeBond,
+					Name:     "bond0",
+					Members:  []string{"fake-mac-1"},
+					BondMode: "fake-mode",
+				},
+			}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(
+				boshsettings.Networks{"bond": bondNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Unknown bond mode 'fake-mode' for network 'bond'"))
+		})
+
+		It("returns error when VLAN ID is out of range", func() {
+			vlanNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeVLAN,
+					Members: []string{"fake-mac-0"},
+					VLANID:  4095,
+				},
+			}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(
+				boshsettings.Networks{"vlan": vlanNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("VLAN ID '4095' for network 'vlan' must be between 1 and 4094"))
+		})
+
+		It("returns error when bridge has no name", func() {
+			bridgeNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeBridge,
+					Members: []string{"fake-mac-0"},
+				},
+			}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(
+				boshsettings.Networks{"bridge": bridgeNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Name of bridge interface for network 'bridge' is required"))
+		})
+
+		It("returns error when two networks use the same virtual interface name", func() {
+			firstVLANNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeVLAN,
+					Members: []string{"fake-mac-0"},
+					VLANID:  100,
+				},
+			}
+			secondVLANNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeVLAN,
+					Name:    "eth0.100",
+					Members: []string{"fake-mac-1"},
+					VLANID:  200,
+				},
+			}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(
+				boshsettings.Networks{"vlan-a": firstVLANNetwork, "vlan-b": secondVLANNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Interface 'eth0.100' is configured for both network 'vlan-a' and 'vlan-b'"))
+		})
+
+		It("returns error when virtual interface has the same name as a physical interface", func() {
+			bridgeNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeBridge,
+					Name:    "eth0",
+					Members: []string{"fake-mac-1"},
+				},
+			}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(
+				boshsettings.Networks{"bridge": bridgeNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Interface 'eth0' for network 'bridge' has the same name as a physical interface"))
+		})
+	})
+
+	Context("when network has static routes, mtu and routing table", func() {
+		var interfacesByMAC map[string]string
+
+		BeforeEach(func() {
+			staticNetwork.MTU = 9000
+			staticNetwork.RoutingTable = 100
+			staticNetwork.Routes = boshsettings.Routes{
+				{Destination: "10.10.0.0", Netmask: "255.255.0.0", Gateway: "5.6.7.1"},
+			}
+			interfacesByMAC = map[string]string{staticNetwork.Mac: "static-interface-name"}
+		})
+
+		It("carries them into static interface configuration", func() {
+			staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(staticInterfaceConfigurations).To(HaveLen(1))
+			Expect(staticInterfaceConfigurations[0].MTU).To(Equal(9000))
+			Expect(staticInterfaceConfigurations[0].RoutingTable).To(Equal(100))
+			Expect(staticInterfaceConfigurations[0].Routes).To(Equal([]StaticRouteConfiguration{
+				{Destination: "10.10.0.0", Netmask: "255.255.0.0", Gateway: "5.6.7.1"},
+			}))
+		})
+
+		It("returns error when route gateway is not an IP", func() {
+			staticNetwork.Routes[0].Gateway = "not-an-ip"
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid gateway 'not-an-ip' for route to '10.10.0.0'"))
+		})
+
+		It("returns error when routing table is reserved", func() {
+			staticNetwork.RoutingTable = 254
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Routing table '254' must be between 1 and 252"))
+		})
+
+		It("returns error when mtu is negative", func() {
+			staticNetwork.MTU = -1
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("MTU '-1' for interface 'static-interface-name' must be between 68 and 65535"))
+		})
+
+		It("returns error when mtu is too large", func() {
+			staticNetwork.MTU = 65536
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("MTU '65536' for interface 'static-interface-name' must be between 68 and 65535"))
+		})
+
+		It("returns error when mtu is too small for IPv6", func() {
+			staticNetwork.MTU = 1000
+			staticNetwork.SecondaryAddresses = []boshsettings.Address{{IP: "2001:db8::5", Netmask: "ffff:ffff:ffff:ffff::"}}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("MTU '1000' for interface 'static-interface-name' must be between 1280 and 65535"))
+		})
+	})
+
+	Context("when dhcp network has static routes, mtu, routing table or secondary addresses", func() {
+		var interfacesByMAC map[string]string
+
+		BeforeEach(func() {
+			interfacesByMAC = map[string]string{dhcpNetwork.Mac: "dhcp-interface-name"}
+		})
+
+		It("returns error when dhcp network has routes", func() {
+			dhcpNetwork.Routes = boshsettings.Routes{
+				{Destination: "10.10.0.0", Netmask: "255.255.0.0", Gateway: "5.6.7.1"},
+			}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": dhcpNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Routes, MTU and routing table cannot be configured for DHCP interface 'dhcp-interface-name'"))
+		})
+
+		It("returns error when dhcp network has mtu", func() {
+			dhcpNetwork.MTU = 9000
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": dhcpNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Routes, MTU and routing table cannot be configured for DHCP interface 'dhcp-interface-name'"))
+		})
+
+		It("returns error when dhcp network has routing table", func() {
+			dhcpNetwork.RoutingTable = 100
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": dhcpNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Routes, MTU and routing table cannot be configured for DHCP interface 'dhcp-interface-name'"))
+		})
+
+		It("returns error when dhcp network has secondary addresses", func() {
+			dhcpNetwork.SecondaryAddresses = []boshsettings.Address{{IP: "1.2.3.5", Netmask: "255.255.255.0"}}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": dhcpNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Secondary addresses cannot be configured for DHCP interface 'dhcp-interface-name'"))
+		})
+	})
+
+	Context("when interface has several addresses", func() {
+		var interfacesByMAC map[string]string
+
+		BeforeEach(func() {
+			interfacesByMAC = map[string]string{
+				staticNetwork.Mac: "static-interface-name",
+				dhcpNetwork.Mac:   "dhcp-interface-name",
+			}
+		})
+
+		It("carries secondary addresses into static interface configuration", func() {
+			staticNetwork.SecondaryAddresses = []boshsettings.Address{
+				{IP: "1.2.3.5", Netmask: "255.255.255.0"},
+				{IP: "2001:db8::5", Netmask: "ffff:ffff:ffff:ffff::"},
+			}
+
+			staticInterfaceConfigurations, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(staticInterfaceConfigurations).To(HaveLen(1))
+			Expect(staticInterfaceConfigurations[0].SecondaryAddresses).To(Equal([]StaticAddressConfiguration{
+				{Address: "1.2.3.5", Netmask: "255.255.255.0"},
+				{Address: "2001:db8::5", Netmask: "ffff:ffff:ffff:ffff::"},
+			}))
+		})
+
+		It("returns error when netmask of secondary address does not match its address family", func() {
+			staticNetwork.SecondaryAddresses = []boshsettings.Address{
+				{IP: "2001:db8::5", Netmask: "255.255.255.0"},
+			}
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Invalid netmask '255.255.255.0' for secondary address '2001:db8::5'"))
+		})
+
+		It("configures networks sharing MAC address on the same interface", func() {
+			staticNetwork.Default = []string{"gateway"}
+			ipv6Network := boshsettings.Network{
+				IP:      "2001:db8::5",
+				Netmask: "ffff:ffff:ffff:ffff::",
+				Gateway: "2001:db8::1",
+				Mac:     staticNetwork.Mac,
+				Routes:  boshsettings.Routes{{Destination: "2001:db8:1::", Netmask: "ffff:ffff:ffff::", Gateway: "2001:db8::2"}},
+			}
+			secondaryNetwork := boshsettings.Network{
+				IP:                 "1.2.3.6",
+				Netmask:            "255.255.255.0",
+				Mac:                staticNetwork.Mac,
+				SecondaryAddresses: []boshsettings.Address{{IP: "1.2.3.7", Netmask: "255.255.255.0"}},
+			}
+
+			networks := boshsettings.Networks{
+				"a-secondary": secondaryNetwork,
+				"b-primary":   staticNetwork,
+				"c-ipv6":      ipv6Network,
+				"dhcp":        dhcpNetwork,
+			}
+
+			staticInterfaceConfigurations, dhcpInterfaceConfigurations, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMAC)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(dhcpInterfaceConfigurations).To(Equal([]DHCPInterfaceConfiguration{{Name: "dhcp-interface-name"}}))
+			Expect(staticInterfaceConfigurations).To(Equal([]StaticInterfaceConfiguration{
+				{
+					Name:                "static-interface-name",
+					Address:             "1.2.3.4",
+					Netmask:             "255.255.255.0",
+					Network:             "1.2.3.0",
+					Broadcast:           "1.2.3.255",
+					IsDefaultForGateway: true,
+					Mac:                 staticNetwork.Mac,
+					Gateway:             "3.4.5.6",
+					SecondaryAddresses: []StaticAddressConfiguration{
+						{Address: "1.2.3.6", Netmask: "255.255.255.0"},
+						{Address: "1.2.3.7", Netmask: "255.255.255.0"},
+						{Address: "2001:db8::5", Netmask: "ffff:ffff:ffff:ffff::"},
+					},
+					Routes: []StaticRouteConfiguration{
+						{Destination: "2001:db8:1::", Netmask: "ffff:ffff:ffff::", Gateway: "2001:db8::2"},
+						{Destination: "::", Netmask: "::", Gateway: "2001:db8::1"},
+					},
+				},
+			}))
+		})
+
+		It("returns error when network sharing MAC address is dynamic", func() {
+			dhcpNetwork.Mac = staticNetwork.Mac
+
+			_, _, err := interfaceConfigurationCreator.CreateInterfaceConfigurations(boshsettings.Networks{"foo": staticNetwork, "bar": dhcpNetwork}, interfacesByMAC)
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Network 'bar' shares interface 'static-interface-name' with other networks and must be static"))
+		})
+	})
 }
 
 var _ = Describe("StaticInterfaceConfiguration", func() {
@@ -308,6 +714,33 @@ var _ = Describe("StaticInterfaceConfiguration", func() {
 			Expect(StaticInterfaceConfiguration{Netmask: "netmask", Broadcast: "broadcast"}.NetmaskOrLen()).To(Equal("netmask"))
 		})
 	})
+
+	Describe("NetworkCIDR", func() {
+		It("returns connected subnet for IPv4 and IPv6 addresses", func() {
+			Expect(StaticInterfaceConfiguration{Address: "1.2.3.4", Netmask: "255.255.255.0"}.NetworkCIDR()).To(Equal("1.2.3.0/24"))
+			Expect(StaticInterfaceConfiguration{Address: "2601:646:100:e8e8::103", Netmask: "ffff:ffff:ffff:ffff::"}.NetworkCIDR()).To(Equal("2601:646:100:e8e8::/64"))
+		})
+	})
+})
+
+var _ = Describe("StaticAddressConfiguration", func() {
+	It("returns address with prefix length", func() {
+		Expect(StaticAddressConfiguration{Address: "10.10.0.5", Netmask: "255.255.0.0"}.CIDR()).To(Equal("10.10.0.5/16"))
+		Expect(StaticAddressConfiguration{Address: "2001:db8::5", Netmask: "ffff:ffff:ffff:ffff::"}.CIDR()).To(Equal("2001:db8::5/64"))
+	})
+})
+
+var _ = Describe("StaticRouteConfiguration", func() {
+	It("returns route destination in CIDR notation", func() {
+		Expect(StaticRouteConfiguration{Destination: "10.10.0.0", Netmask: "255.255.0.0"}.CIDR()).To(Equal("10.10.0.0/16"))
+		Expect(StaticRouteConfiguration{Destination: "0.0.0.0", Netmask: "0.0.0.0"}.CIDR()).To(Equal("0.0.0.0/0"))
+	})
+
+	It("spells default routes of both address families as default", func() {
+		Expect(StaticRouteConfiguration{Destination: "0.0.0.0", Netmask: "0.0.0.0"}.To()).To(Equal("default"))
+		Expect(StaticRouteConfiguration{Destination: "::", Netmask: "::"}.To()).To(Equal("default"))
+		Expect(StaticRouteConfiguration{Destination: "2001:db8:1::", Netmask: "ffff:ffff:ffff::"}.To()).To(Equal("2001:db8:1::/48"))
+	})
 })
 
 var _ = Describe("StaticInterfaceConfigurations", func() {
@@ -323,6 +756,13 @@ var _ = Describe("StaticInterfaceConfigurations", func() {
 			Expect(StaticInterfaceConfigurations{
 				StaticInterfaceConfiguration{Network: "network"},
 			}.HasVersion6()).To(BeFalse())
+
+			Expect(StaticInterfaceConfigurations{
+				StaticInterfaceConfiguration{
+					Network:            "network",
+					SecondaryAddresses: []StaticAddressConfiguration{{Address: "2001:db8::5"}},
+				},
+			}.HasVersion6()).To(BeTrue())
 		})
 	})
 })
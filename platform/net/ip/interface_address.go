@@ -33,6 +33,21 @@ func (s simpleInterfaceAddress) GetIP() (string, error) {
 	return fmtIP(ip2), nil
 }
 
+type virtualInterfaceAddress struct {
+	InterfaceAddress
+}
+
+// NewVirtualInterfaceAddress marks address expected on a VLAN, bond or bridge
+// since those take a while to come up after they are configured
+func NewVirtualInterfaceAddress(address InterfaceAddress) InterfaceAddress {
+	return virtualInterfaceAddress{InterfaceAddress: address}
+}
+
+func IsVirtualInterfaceAddress(address InterfaceAddress) bool {
+	_, isVirtual := address.(virtualInterfaceAddress)
+	return isVirtual
+}
+
 type resolvingInterfaceAddress struct {
 	interfaceName string
 	ipResolver    Resolver
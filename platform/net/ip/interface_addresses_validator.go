@@ -1,6 +1,8 @@
 package ip
 
 import (
+	"strings"
+
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
 )
 
@@ -27,28 +29,41 @@ func (i *interfaceAddressesValidator) Validate(desiredInterfaceAddresses []Inter
 	for _, desiredInterfaceAddress := range desiredInterfaceAddresses {
 		ifaceName := desiredInterfaceAddress.GetInterfaceName()
 
-		iface, found := i.findInterfaceByName(ifaceName, systemInterfaceAddresses)
-		if !found {
+		actualIPs := i.findIPsByInterfaceName(ifaceName, systemInterfaceAddresses)
+		if len(actualIPs) == 0 {
 			return bosherr.Errorf("Validating network interface '%s' IP addresses, no interface configured with that name", ifaceName)
 		}
 
 		desiredIP, _ := desiredInterfaceAddress.GetIP()
-		actualIP, _ := iface.GetIP()
 
-		if desiredIP != actualIP {
-			return bosherr.Errorf("Validating network interface '%s' IP addresses, expected: '%s', actual: '%s'", ifaceName, desiredIP, actualIP)
+		if !containsIP(actualIPs, desiredIP) {
+			return bosherr.Errorf("Validating network interface '%s' IP addresses, expected: '%s', actual: '%s'", ifaceName, desiredIP, strings.Join(actualIPs, ", "))
 		}
 	}
 
 	return nil
 }
 
-func (i *interfaceAddressesValidator) findInterfaceByName(ifaceName string, ifaces []InterfaceAddress) (InterfaceAddress, bool) {
+// findIPsByInterfaceName returns all addresses since interface
+// may have secondary addresses in addition to its primary one
+func (i *interfaceAddressesValidator) findIPsByInterfaceName(ifaceName string, ifaces []InterfaceAddress) []string {
+	ips := []string{}
+
 	for _, iface := range ifaces {
 		if iface.GetInterfaceName() == ifaceName {
-			return iface, true
+			ip, _ := iface.GetIP()
+			ips = append(ips, ip)
 		}
 	}
 
-	return nil, false
+	return ips
+}
+
+func containsIP(ips []string, ip string) bool {
+	for _, actualIP := range ips {
+		if actualIP == ip {
+			return true
+		}
+	}
+	return false
 }
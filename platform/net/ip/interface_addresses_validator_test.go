@@ -84,6 +84,33 @@ var _ = Describe("InterfaceAddressesValidator", func() {
 		})
 	})
 
+	Context("when interface has multiple addresses", func() {
+		BeforeEach(func() {
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.5"),
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8::5"),
+			}
+		})
+
+		It("returns nil when all desired addresses are configured", func() {
+			err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.5"),
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8::5"),
+			})
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("fails when one of desired addresses is missing", func() {
+			err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.6"),
+			})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring("Validating network interface 'eth0' IP addresses, expected: '1.2.3.6', actual: '1.2.3.4, 1.2.3.5, 2001:0db8:0000:0000:0000:0000:0000:0005'"))
+		})
+	})
+
 	Context("when resolv.conf has valid dns configurations", func() {
 		It("fails", func() {
 
@@ -0,0 +1,57 @@
+package ip
+
+import (
+	"time"
+
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshretry "github.com/cloudfoundry/bosh-utils/retrystrategy"
+)
+
+type waitingInterfaceAddressesValidator struct {
+	validator   InterfaceAddressesValidator
+	timeout     time.Duration
+	delay       time.Duration
+	timeService boshretry.Clock
+	logger      boshlog.Logger
+}
+
+// NewWaitingInterfaceAddressesValidator keeps validating until timeout passes
+// when addresses are expected on VLANs, bonds or bridges
+// since those take a while to come up and get their addresses
+func NewWaitingInterfaceAddressesValidator(
+	validator InterfaceAddressesValidator,
+	timeout time.Duration,
+	delay time.Duration,
+	timeService boshretry.Clock,
+	logger boshlog.Logger,
+) InterfaceAddressesValidator {
+	return &waitingInterfaceAddressesValidator{
+		validator:   validator,
+		timeout:     timeout,
+		delay:       delay,
+		timeService: timeService,
+		logger:      logger,
+	}
+}
+
+func (v *waitingInterfaceAddressesValidator) Validate(desiredInterfaceAddresses []InterfaceAddress) error {
+	if !hasVirtualInterfaceAddress(desiredInterfaceAddresses) {
+		return v.validator.Validate(desiredInterfaceAddresses)
+	}
+
+	validateRetryable := boshretry.NewRetryable(func() (bool, error) {
+		err := v.validator.Validate(desiredInterfaceAddresses)
+		return true, err
+	})
+
+	return boshretry.NewTimeoutRetryStrategy(v.timeout, v.delay, validateRetryable, v.timeService, v.logger).Try()
+}
+
+func hasVirtualInterfaceAddress(addresses []InterfaceAddress) bool {
+	for _, address := range addresses {
+		if IsVirtualInterfaceAddress(address) {
+			return true
+		}
+	}
+	return false
+}
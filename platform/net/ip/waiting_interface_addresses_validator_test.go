@@ -0,0 +1,86 @@
+package ip_test
+
+import (
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/clock"
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+)
+
+type slowInterfaceAddressesValidator struct {
+	failedAttempts int
+	attempts       int
+}
+
+func (v *slowInterfaceAddressesValidator) Validate([]boship.InterfaceAddress) error {
+	v.attempts++
+	if v.attempts <= v.failedAttempts {
+		return errors.New("fake-not-up-yet")
+	}
+	return nil
+}
+
+var _ = Describe("WaitingInterfaceAddressesValidator", func() {
+	var (
+		validator               *slowInterfaceAddressesValidator
+		interfaceAddrsValidator boship.InterfaceAddressesValidator
+	)
+
+	BeforeEach(func() {
+		validator = &slowInterfaceAddressesValidator{}
+		interfaceAddrsValidator = boship.NewWaitingInterfaceAddressesValidator(
+			validator,
+			100*time.Millisecond,
+			time.Millisecond,
+			clock.NewClock(),
+			boshlog.NewLogger(boshlog.LevelNone),
+		)
+	})
+
+	It("succeeds once interfaces come up with desired addresses", func() {
+		validator.failedAttempts = 3
+
+		err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+			boship.NewVirtualInterfaceAddress(boship.NewSimpleInterfaceAddress("bond0", "1.2.3.4")),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(validator.attempts).To(Equal(4))
+	})
+
+	It("returns last validation error when interfaces do not come up in time", func() {
+		validator.failedAttempts = 1000000
+
+		err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+			boship.NewVirtualInterfaceAddress(boship.NewSimpleInterfaceAddress("bond0", "1.2.3.4")),
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("fake-not-up-yet"))
+	})
+
+	It("waits when any of addresses is expected on a virtual interface", func() {
+		validator.failedAttempts = 1
+
+		err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+			boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+			boship.NewVirtualInterfaceAddress(boship.NewSimpleInterfaceAddress("bond0", "1.2.3.5")),
+		})
+		Expect(err).ToNot(HaveOccurred())
+		Expect(validator.attempts).To(Equal(2))
+	})
+
+	It("validates only once when no address is expected on a virtual interface", func() {
+		validator.failedAttempts = 1
+
+		err := interfaceAddrsValidator.Validate([]boship.InterfaceAddress{
+			boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+		})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(Equal("fake-not-up-yet"))
+		Expect(validator.attempts).To(Equal(1))
+	})
+})
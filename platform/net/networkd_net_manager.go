@@ -0,0 +1,624 @@
+package net
+
+import (
+	"bytes"
+	"path"
+	"sort"
+	"strings"
+	"text/template"
+
+	bosharp "github.com/cloudfoundry/bosh-agent/platform/net/arp"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const networkdNetManagerLogTag = "networkdNetManager"
+
+// NetworkdBackend is the way configuration is handed over to systemd-networkd
+type NetworkdBackend string
+
+const (
+	NetworkdBackendNetplan  NetworkdBackend = "netplan"
+	NetworkdBackendNetworkd NetworkdBackend = "networkd"
+)
+
+const (
+	netplanConfigPath        = "/etc/netplan/90-bosh-agent.yaml"
+	netplanGeneratedDir      = "/run/systemd/network"
+	networkdConfigDir        = "/etc/systemd/network"
+	networkdConfigFilePrefix = "10-bosh-agent-"
+	resolvedConfigPath       = "/etc/systemd/resolved.conf.d/bosh-agent.conf"
+)
+
+type networkdNetManager struct {
+	backend                       NetworkdBackend
+	fs                            boshsys.FileSystem
+	cmdRunner                     boshsys.CmdRunner
+	ipResolver                    boship.Resolver
+	interfaceConfigurationCreator InterfaceConfigurationCreator
+	interfaceAddressesValidator   boship.InterfaceAddressesValidator
+	dnsValidator                  DNSValidator
+	addressBroadcaster            bosharp.AddressBroadcaster
+	kernelIPv6                    KernelIPv6
+	logger                        boshlog.Logger
+}
+
+// NewNetworkdNetManager configures networking on stemcells that ship
+// systemd-networkd instead of ifupdown, either through netplan or directly
+func NewNetworkdNetManager(
+	backend NetworkdBackend,
+	fs boshsys.FileSystem,
+	cmdRunner boshsys.CmdRunner,
+	ipResolver boship.Resolver,
+	interfaceConfigurationCreator InterfaceConfigurationCreator,
+	interfaceAddressesValidator boship.InterfaceAddressesValidator,
+	dnsValidator DNSValidator,
+	addressBroadcaster bosharp.AddressBroadcaster,
+	kernelIPv6 KernelIPv6,
+	logger boshlog.Logger,
+) Manager {
+	return networkdNetManager{
+		backend:                       backend,
+		fs:                            fs,
+		cmdRunner:                     cmdRunner,
+		ipResolver:                    ipResolver,
+		interfaceConfigurationCreator: interfaceConfigurationCreator,
+		interfaceAddressesValidator:   interfaceAddressesValidator,
+		dnsValidator:                  dnsValidator,
+		addressBroadcaster:            addressBroadcaster,
+		kernelIPv6:                    kernelIPv6,
+		logger:                        logger,
+	}
+}
+
+func (net networkdNetManager) SetupIPv6(config boshsettings.IPv6, stopCh <-chan struct{}) error {
+	if config.Enable {
+		return net.kernelIPv6.Enable(stopCh)
+	}
+	return nil
+}
+
+func (net networkdNetManager) SetupNetworking(networks boshsettings.Networks, errCh chan error) error {
+	if networks.IsPreconfigured() {
+		// Note in this case IPs are not broadcast
+		return net.writeResolvedConf(net.dnsServers(networks))
+	}
+
+	staticConfigs, dhcpConfigs, dnsServers, err := net.computeNetworkConfig(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	if StaticInterfaceConfigurations(staticConfigs).HasVersion6() {
+		err := net.kernelIPv6.Enable(make(chan struct{}))
+		if err != nil {
+			return bosherr.WrapError(err, "Enabling IPv6 in kernel")
+		}
+	}
+
+	config := newNetworkdConfig(staticConfigs, dhcpConfigs, dnsServers)
+
+	changed, err := net.writeNetConfigs(config, boshsys.ConvergeFileContentsOpts{DryRun: true})
+	if err != nil {
+		return bosherr.WrapError(err, "Determining if network configs have changed")
+	}
+
+	if changed {
+		_, err = net.writeNetConfigs(config, boshsys.ConvergeFileContentsOpts{})
+		if err != nil {
+			return bosherr.WrapError(err, "Updating network configs")
+		}
+
+		err = net.applyNetConfigs()
+		if err != nil {
+			return bosherr.WrapError(err, "Applying network configs")
+		}
+	}
+
+	staticAddresses, dynamicAddresses := ifaceAddresses(staticConfigs, dhcpConfigs, net.ipResolver)
+
+	err = net.interfaceAddressesValidator.Validate(staticAddresses)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating static network configuration")
+	}
+
+	err = net.dnsValidator.Validate(dnsServers)
+	if err != nil {
+		return bosherr.WrapError(err, "Validating dns configuration")
+	}
+
+	broadcastIps(net.addressBroadcaster, append(staticAddresses, dynamicAddresses...), errCh)
+
+	return nil
+}
+
+func (net networkdNetManager) DetectDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (Drift, error) {
+	if networks.IsPreconfigured() {
+		return Drift{DNSErr: net.dnsValidator.Validate(net.dnsServers(networks))}, nil
+	}
+
+	staticConfigs, dhcpConfigs, dnsServers, err := net.computeNetworkConfig(networks)
+	if err != nil {
+		return Drift{}, bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	config := newNetworkdConfig(staticConfigs, dhcpConfigs, dnsServers)
+
+	changed, err := net.writeNetConfigs(config, boshsys.ConvergeFileContentsOpts{DryRun: true})
+	if err != nil {
+		return Drift{}, bosherr.WrapError(err, "Determining if network configs have changed")
+	}
+
+	staticAddresses, _ := ifaceAddresses(staticConfigs, dhcpConfigs, net.ipResolver)
+
+	return Drift{
+		ConfigsChanged: changed,
+		AddressesErr:   interfaceAddressesValidator.Validate(staticAddresses),
+		DNSErr:         net.dnsValidator.Validate(dnsServers),
+	}, nil
+}
+
+// RepairNetworking reapplies configuration even if it did not change
+// since networkd does not bring back addresses removed by hand
+func (net networkdNetManager) RepairNetworking(networks boshsettings.Networks) error {
+	if networks.IsPreconfigured() {
+		return net.writeResolvedConf(net.dnsServers(networks))
+	}
+
+	staticConfigs, dhcpConfigs, dnsServers, err := net.computeNetworkConfig(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	_, err = net.writeNetConfigs(newNetworkdConfig(staticConfigs, dhcpConfigs, dnsServers), boshsys.ConvergeFileContentsOpts{})
+	if err != nil {
+		return bosherr.WrapError(err, "Updating network configs")
+	}
+
+	err = net.applyNetConfigs()
+	if err != nil {
+		return bosherr.WrapError(err, "Applying network configs")
+	}
+
+	return nil
+}
+
+// GetConfiguredNetworkInterfaces relies on netplan generating networkd
+// configuration for every interface it configures when netplan backend is used
+func (net networkdNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
+	interfaces := []string{}
+
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
+	if err != nil {
+		return interfaces, bosherr.WrapError(err, "Getting network interfaces")
+	}
+
+	for _, iface := range interfacesByMacAddress {
+		configPath := path.Join(networkdConfigDir, networkdConfigFilePrefix+iface+".network")
+		if net.backend == NetworkdBackendNetplan {
+			configPath = path.Join(netplanGeneratedDir, "10-netplan-"+iface+".network")
+		}
+
+		if net.fs.FileExists(configPath) {
+			interfaces = append(interfaces, iface)
+		}
+	}
+
+	return interfaces, nil
+}
+
+func (net networkdNetManager) writeNetConfigs(config networkdConfig, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
+	if net.backend == NetworkdBackendNetplan {
+		return net.writeNetplanConfig(config, opts)
+	}
+	return net.writeNetworkdConfigs(config, opts)
+}
+
+func (net networkdNetManager) applyNetConfigs() error {
+	net.logger.Debug(networkdNetManagerLogTag, "Applying network configuration")
+
+	var err error
+
+	if net.backend == NetworkdBackendNetplan {
+		_, _, _, err = net.cmdRunner.RunCommand("netplan", "apply")
+	} else {
+		_, _, _, err = net.cmdRunner.RunCommand("systemctl", "restart", "systemd-networkd")
+	}
+
+	return err
+}
+
+func (net networkdNetManager) writeNetplanConfig(config networkdConfig, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
+	buffer := bytes.NewBuffer([]byte{})
+
+	t := template.Must(template.New("netplan").Funcs(networkdTemplateFuncs).Parse(netplanTemplate))
+
+	err := t.Execute(buffer, config)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Generating config from template")
+	}
+
+	changed, err := net.fs.ConvergeFileContents(netplanConfigPath, buffer.Bytes(), opts)
+	if err != nil {
+		return changed, bosherr.WrapErrorf(err, "Writing to %s", netplanConfigPath)
+	}
+
+	return changed, nil
+}
+
+func (net networkdNetManager) writeNetworkdConfigs(config networkdConfig, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
+	networkTemplate := template.Must(template.New("network").Parse(networkdNetworkTemplate))
+	netdevTemplate := template.Must(template.New("netdev").Parse(networkdNetdevTemplate))
+
+	anyChanged := false
+	desiredPaths := map[string]bool{}
+
+	writeFile := func(name string, t *template.Template, iface networkdInterface) error {
+		buffer := bytes.NewBuffer([]byte{})
+
+		err := t.Execute(buffer, iface)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Generating '%s' config from template", name)
+		}
+
+		filePath := path.Join(networkdConfigDir, networkdConfigFilePrefix+name)
+		desiredPaths[filePath] = true
+
+		changed, err := net.fs.ConvergeFileContents(filePath, buffer.Bytes(), opts)
+		if err != nil {
+			return bosherr.WrapErrorf(err, "Writing to %s", filePath)
+		}
+
+		anyChanged = anyChanged || changed
+
+		return nil
+	}
+
+	for _, iface := range config.Interfaces {
+		if iface.VirtualInterface.Type != "" {
+			err := writeFile(iface.Name+".netdev", netdevTemplate, iface)
+			if err != nil {
+				return false, err
+			}
+		}
+
+		err := writeFile(iface.Name+".network", networkTemplate, iface)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	// Configuration of interfaces that are no longer desired is removed
+	// so that networkd stops managing them
+	existingPaths, err := net.fs.Glob(path.Join(networkdConfigDir, networkdConfigFilePrefix+"*"))
+	if err != nil {
+		return false, bosherr.WrapErrorf(err, "Getting file list from %s", networkdConfigDir)
+	}
+
+	for _, existingPath := range existingPaths {
+		if desiredPaths[existingPath] {
+			continue
+		}
+
+		anyChanged = true
+
+		if opts.DryRun {
+			continue
+		}
+
+		err = net.fs.RemoveAll(existingPath)
+		if err != nil {
+			return false, bosherr.WrapErrorf(err, "Removing %s", existingPath)
+		}
+	}
+
+	return anyChanged, nil
+}
+
+func (net networkdNetManager) writeResolvedConf(dnsServers []string) error {
+	if len(dnsServers) == 0 {
+		return nil
+	}
+
+	contents := "# Generated by bosh-agent\n[Resolve]\nDNS=" + strings.Join(dnsServers, " ") + "\n"
+
+	changed, err := net.fs.ConvergeFileContents(resolvedConfigPath, []byte(contents))
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing to %s", resolvedConfigPath)
+	}
+
+	if changed {
+		_, _, _, err = net.cmdRunner.RunCommand("systemctl", "restart", "systemd-resolved")
+		if err != nil {
+			return bosherr.WrapError(err, "Restarting systemd-resolved")
+		}
+	}
+
+	return nil
+}
+
+func (net networkdNetManager) computeNetworkConfig(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, []string, error) {
+	staticConfigs, dhcpConfigs, err := net.buildInterfaces(net.nonVipNetworks(networks))
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return staticConfigs, dhcpConfigs, net.dnsServers(networks), nil
+}
+
+func (net networkdNetManager) nonVipNetworks(networks boshsettings.Networks) boshsettings.Networks {
+	nonVipNetworks := boshsettings.Networks{}
+	for networkName, networkSettings := range networks {
+		if networkSettings.IsVIP() {
+			continue
+		}
+		nonVipNetworks[networkName] = networkSettings
+	}
+	return nonVipNetworks
+}
+
+func (net networkdNetManager) dnsServers(networks boshsettings.Networks) []string {
+	dnsNetwork, _ := net.nonVipNetworks(networks).DefaultNetworkFor("dns")
+	return dnsNetwork.DNS
+}
+
+func (net networkdNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Getting network interfaces")
+	}
+
+	staticConfigs, dhcpConfigs, err := net.interfaceConfigurationCreator.CreateInterfaceConfigurations(networks, interfacesByMacAddress)
+	if err != nil {
+		return nil, nil, bosherr.WrapError(err, "Creating interface configurations")
+	}
+
+	return staticConfigs, dhcpConfigs, nil
+}
+
+type networkdRoute struct {
+	To    string
+	Via   string
+	Scope string
+	Table int
+}
+
+// networkdInterface is a link managed by networkd; it is either configured
+// by a network or only brought up since other interfaces are built on top of it
+type networkdInterface struct {
+	Name             string
+	DHCP             string
+	Addresses        []string
+	MTU              int
+	DNSServers       []string
+	Routes           []networkdRoute
+	RoutingTable     int
+	RoutingFrom      string
+	VirtualInterface VirtualInterfaceConfiguration
+
+	// VLANs built on top of this link
+	VLANs  []string
+	Member MemberInterfaceConfiguration
+}
+
+func (i networkdInterface) IsLinkOnly() bool {
+	return i.DHCP == "" && len(i.Addresses) == 0 && i.MTU == 0
+}
+
+func (i networkdInterface) DHCP4() bool {
+	return i.DHCP == "ipv4"
+}
+
+func (i networkdInterface) DHCP6() bool {
+	return i.DHCP == "ipv6"
+}
+
+type networkdConfig struct {
+	Interfaces []networkdInterface
+}
+
+func (c networkdConfig) interfacesOfType(interfaceType boshsettings.VirtualInterfaceType) []networkdInterface {
+	ifaces := []networkdInterface{}
+	for _, iface := range c.Interfaces {
+		if iface.VirtualInterface.Type == interfaceType {
+			ifaces = append(ifaces, iface)
+		}
+	}
+	return ifaces
+}
+
+func (c networkdConfig) Ethernets() []networkdInterface {
+	return c.interfacesOfType("")
+}
+
+func (c networkdConfig) VLANs() []networkdInterface {
+	return c.interfacesOfType(boshsettings.VirtualInterfaceTypeVLAN)
+}
+
+func (c networkdConfig) Bonds() []networkdInterface {
+	return c.interfacesOfType(boshsettings.VirtualInterfaceTypeBond)
+}
+
+func (c networkdConfig) Bridges() []networkdInterface {
+	return c.interfacesOfType(boshsettings.VirtualInterfaceTypeBridge)
+}
+
+func newNetworkdConfig(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration, dnsServers []string) networkdConfig {
+	ifacesByName := map[string]*networkdInterface{}
+
+	getIface := func(name string) *networkdInterface {
+		iface, found := ifacesByName[name]
+		if !found {
+			iface = &networkdInterface{Name: name}
+			ifacesByName[name] = iface
+		}
+		return iface
+	}
+
+	for _, config := range dhcpConfigs {
+		iface := getIface(config.Name)
+		iface.DHCP = "ipv4"
+		if config.IsVersion6() {
+			iface.DHCP = "ipv6"
+		}
+		iface.DNSServers = dnsServers
+		iface.VirtualInterface = config.VirtualInterface
+	}
+
+	for _, config := range staticConfigs {
+		iface := getIface(config.Name)
+		iface.Addresses = []string{config.Address + "/" + prefixLength(config.Netmask)}
+		for _, address := range config.SecondaryAddresses {
+			iface.Addresses = append(iface.Addresses, address.CIDR())
+		}
+		iface.MTU = config.MTU
+		iface.DNSServers = dnsServers
+		iface.VirtualInterface = config.VirtualInterface
+
+		defaultDestination := "0.0.0.0/0"
+		if config.IsVersion6() {
+			defaultDestination = "::/0"
+		}
+
+		if config.IsDefaultForGateway && config.Gateway != "" {
+			iface.Routes = append(iface.Routes, networkdRoute{To: defaultDestination, Via: config.Gateway})
+		}
+
+		for _, route := range config.Routes {
+			iface.Routes = append(iface.Routes, networkdRoute{To: route.CIDR(), Via: route.Gateway})
+		}
+
+		if config.RoutingTable > 0 {
+			iface.RoutingTable = config.RoutingTable
+			iface.RoutingFrom = config.Address
+
+			iface.Routes = append(iface.Routes, networkdRoute{To: config.NetworkCIDR(), Scope: "link", Table: config.RoutingTable})
+
+			if config.Gateway != "" {
+				iface.Routes = append(iface.Routes, networkdRoute{To: defaultDestination, Via: config.Gateway, Table: config.RoutingTable})
+			}
+
+			for _, route := range config.Routes {
+				iface.Routes = append(iface.Routes, networkdRoute{To: route.CIDR(), Via: route.Gateway, Table: config.RoutingTable})
+			}
+		}
+	}
+
+	for _, member := range MemberInterfaceConfigurations(staticConfigs, dhcpConfigs) {
+		getIface(member.Name).Member = member
+	}
+
+	for _, config := range staticConfigs {
+		if config.VirtualInterface.IsVLAN() {
+			parent := getIface(config.VirtualInterface.Parent())
+			parent.VLANs = append(parent.VLANs, config.Name)
+		}
+	}
+
+	for _, config := range dhcpConfigs {
+		if config.VirtualInterface.IsVLAN() {
+			parent := getIface(config.VirtualInterface.Parent())
+			parent.VLANs = append(parent.VLANs, config.Name)
+		}
+	}
+
+	config := networkdConfig{}
+
+	for _, iface := range ifacesByName {
+		sort.Strings(iface.VLANs)
+		config.Interfaces = append(config.Interfaces, *iface)
+	}
+
+	sort.Slice(config.Interfaces, func(i, j int) bool {
+		return config.Interfaces[i].Name < config.Interfaces[j].Name
+	})
+
+	return config
+}
+
+var networkdTemplateFuncs = template.FuncMap{
+	"join": func(values []string) string { return strings.Join(values, ", ") },
+}
+
+// DHCP is always set explicitly since netplan merges this file with
+// stemcell and cloud-init configs that might enable DHCP on the same interfaces
+const netplanTemplate = `# Generated by bosh-agent
+network:
+  version: 2
+  renderer: networkd{{ if .Ethernets }}
+  ethernets:{{ range .Ethernets }}{{ template "interface" . }}{{ end }}{{ end }}{{ if .VLANs }}
+  vlans:{{ range .VLANs }}{{ template "interface" . }}{{ end }}{{ end }}{{ if .Bonds }}
+  bonds:{{ range .Bonds }}{{ template "interface" . }}{{ end }}{{ end }}{{ if .Bridges }}
+  bridges:{{ range .Bridges }}{{ template "interface" . }}{{ end }}{{ end }}
+{{ define "interface" }}
+    {{ .Name }}:{{ if .VirtualInterface.IsVLAN }}
+      id: {{ .VirtualInterface.VLANID }}
+      link: {{ .VirtualInterface.Parent }}{{ else if .VirtualInterface.IsBond }}
+      interfaces: [{{ join .VirtualInterface.Members }}]
+      parameters:
+        mode: {{ .VirtualInterface.BondMode }}
+        mii-monitor-interval: 100{{ else if .VirtualInterface.IsBridge }}
+      interfaces: [{{ join .VirtualInterface.Members }}]
+      parameters:
+        stp: false
+        forward-delay: 0{{ end }}
+      dhcp4: {{ .DHCP4 }}
+      dhcp6: {{ .DHCP6 }}{{ if .Addresses }}
+      addresses: [{{ join .Addresses }}]{{ end }}{{ if .MTU }}
+      mtu: {{ .MTU }}{{ end }}{{ if and .DNSServers (not .IsLinkOnly) }}
+      nameservers:
+        addresses: [{{ join .DNSServers }}]{{ end }}{{ if .Routes }}
+      routes:{{ range .Routes }}
+        - to: {{ .To }}{{ if .Via }}
+          via: {{ .Via }}{{ end }}{{ if .Scope }}
+          scope: {{ .Scope }}{{ end }}{{ if .Table }}
+          table: {{ .Table }}{{ end }}{{ end }}{{ end }}{{ if .RoutingTable }}
+      routing-policy:
+        - from: {{ .RoutingFrom }}
+          table: {{ .RoutingTable }}{{ end }}{{ end }}`
+
+const networkdNetworkTemplate = `# Generated by bosh-agent
+[Match]
+Name={{ .Name }}
+{{ if .MTU }}
+[Link]
+MTUBytes={{ .MTU }}
+{{ end }}
+[Network]{{ if .DHCP }}
+DHCP={{ .DHCP }}{{ end }}{{ range .Addresses }}
+Address={{ . }}{{ end }}{{ if not .IsLinkOnly }}{{ range .DNSServers }}
+DNS={{ . }}{{ end }}{{ end }}{{ range .VLANs }}
+VLAN={{ . }}{{ end }}{{ if .Member.Master }}{{ if .Member.IsBond }}
+Bond={{ .Member.Master }}{{ else }}
+Bridge={{ .Member.Master }}{{ end }}{{ end }}
+{{ range .Routes }}
+[Route]
+Destination={{ .To }}{{ if .Via }}
+Gateway={{ .Via }}{{ end }}{{ if .Scope }}
+Scope={{ .Scope }}{{ end }}{{ if .Table }}
+Table={{ .Table }}{{ end }}
+{{ end }}{{ if .RoutingTable }}
+[RoutingPolicyRule]
+From={{ .RoutingFrom }}
+Table={{ .RoutingTable }}
+{{ end }}`
+
+const networkdNetdevTemplate = `# Generated by bosh-agent
+[NetDev]
+Name={{ .Name }}
+Kind={{ .VirtualInterface.Type }}
+{{ if .VirtualInterface.IsVLAN }}
+[VLAN]
+Id={{ .VirtualInterface.VLANID }}
+{{ else if .VirtualInterface.IsBond }}
+[Bond]
+Mode={{ .VirtualInterface.BondMode }}
+MIIMonitorSec=100ms
+{{ else if .VirtualInterface.IsBridge }}
+[Bridge]
+STP=no
+ForwardDelaySec={{ .VirtualInterface.ForwardDelaySec }}
+{{ end }}`
@@ -0,0 +1,656 @@
+package net_test
+
+import (
+	"fmt"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/net"
+	fakearp "github.com/cloudfoundry/bosh-agent/platform/net/arp/fakes"
+	fakenet "github.com/cloudfoundry/bosh-agent/platform/net/fakes"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
+	fakeip "github.com/cloudfoundry/bosh-agent/platform/net/ip/fakes"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("networkdNetManager", func() {
+	var (
+		fs                            *fakesys.FakeFileSystem
+		cmdRunner                     *fakesys.FakeCmdRunner
+		ipResolver                    *fakeip.FakeResolver
+		addressBroadcaster            *fakearp.FakeAddressBroadcaster
+		interfaceAddrsProvider        *fakeip.FakeInterfaceAddressesProvider
+		kernelIPv6                    *fakenet.FakeKernelIPv6
+		interfaceConfigurationCreator InterfaceConfigurationCreator
+		logger                        boshlog.Logger
+	)
+
+	writeNetworkDevice := func(iface string, macAddress string) string {
+		interfacePath := fmt.Sprintf("/sys/class/net/%s", iface)
+		fs.WriteFile(interfacePath, []byte{})
+		fs.WriteFile(fmt.Sprintf("/sys/class/net/%s/device", iface), []byte{})
+		fs.WriteFileString(fmt.Sprintf("/sys/class/net/%s/address", iface), fmt.Sprintf("%s\n", macAddress))
+
+		return interfacePath
+	}
+
+	stubInterfaces := func(macAddressesByInterface map[string]string) {
+		interfacePaths := []string{}
+
+		for iface, macAddress := range macAddressesByInterface {
+			interfacePaths = append(interfacePaths, writeNetworkDevice(iface, macAddress))
+		}
+
+		fs.SetGlob("/sys/class/net/*", interfacePaths)
+	}
+
+	newNetManager := func(backend NetworkdBackend) Manager {
+		return NewNetworkdNetManager(
+			backend,
+			fs,
+			cmdRunner,
+			ipResolver,
+			interfaceConfigurationCreator,
+			boship.NewInterfaceAddressesValidator(interfaceAddrsProvider),
+			NewResolvedDNSValidator(fs),
+			addressBroadcaster,
+			kernelIPv6,
+			logger,
+		)
+	}
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		cmdRunner = fakesys.NewFakeCmdRunner()
+		ipResolver = &fakeip.FakeResolver{}
+		logger = boshlog.NewLogger(boshlog.LevelNone)
+		interfaceConfigurationCreator = NewInterfaceConfigurationCreator(logger)
+		addressBroadcaster = &fakearp.FakeAddressBroadcaster{}
+		interfaceAddrsProvider = &fakeip.FakeInterfaceAddressesProvider{}
+		kernelIPv6 = &fakenet.FakeKernelIPv6{}
+	})
+
+	Describe("SetupNetworking", func() {
+		var (
+			networks boshsettings.Networks
+			errCh    chan error
+		)
+
+		BeforeEach(func() {
+			networks = boshsettings.Networks{
+				"dynamic": boshsettings.Network{
+					Type:    "dynamic",
+					Default: []string{"dns"},
+					DNS:     []string{"8.8.8.8", "9.9.9.9"},
+					Mac:     "fake-dhcp-mac-address",
+				},
+				"manual": boshsettings.Network{
+					Type:    "manual",
+					IP:      "1.2.3.4",
+					Default: []string{"gateway"},
+					Netmask: "255.255.255.0",
+					Gateway: "1.2.3.1",
+					Mac:     "fake-static-mac-address",
+				},
+			}
+
+			stubInterfaces(map[string]string{
+				"ethdhcp":   "fake-dhcp-mac-address",
+				"ethstatic": "fake-static-mac-address",
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+			}
+
+			fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 8.8.8.8\nnameserver 9.9.9.9\n")
+
+			errCh = make(chan error)
+		})
+
+		Context("when netplan backend is used", func() {
+			var netManager Manager
+
+			expectedNetplanConfig := `# Generated by bosh-agent
+network:
+  version: 2
+  renderer: networkd
+  ethernets:
+    ethdhcp:
+      dhcp4: true
+      dhcp6: false
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+    ethstatic:
+      dhcp4: false
+      dhcp6: false
+      addresses: [1.2.3.4/24]
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+      routes:
+        - to: 0.0.0.0/0
+          via: 1.2.3.1
+`
+
+			BeforeEach(func() {
+				netManager = newNetManager(NetworkdBackendNetplan)
+			})
+
+			It("writes netplan config and applies it", func() {
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred())
+
+				netplanConfig := fs.GetFileTestStat("/etc/netplan/90-bosh-agent.yaml")
+				Expect(netplanConfig).ToNot(BeNil())
+				Expect(netplanConfig.StringContents()).To(Equal(expectedNetplanConfig))
+
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{{"netplan", "apply"}}))
+			})
+
+			It("does not apply netplan config when it has not changed", func() {
+				fs.WriteFileString("/etc/netplan/90-bosh-agent.yaml", expectedNetplanConfig)
+
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred())
+
+				Expect(cmdRunner.RunCommands).To(BeEmpty())
+			})
+
+			It("configures bonds, bridges and VLANs on top of their members", func() {
+				networks = boshsettings.Networks{
+					"bonded": boshsettings.Network{
+						Type:    "dynamic",
+						Default: []string{"dns"},
+						DNS:     []string{"8.8.8.8"},
+						VirtualInterface: boshsettings.VirtualInterface{
+							Type:    boshsettings.VirtualInterfaceTypeBond,
+							Name:    "bond0",
+							Members: []string{"fake-mac-1", "fake-mac-2"},
+						},
+					},
+					"bridged": boshsettings.Network{
+						Type:    "manual",
+						IP:      "10.0.0.5",
+						Netmask: "255.255.255.0",
+						VirtualInterface: boshsettings.VirtualInterface{
+							Type:    boshsettings.VirtualInterfaceTypeBridge,
+							Name:    "br0",
+							Members: []string{"fake-mac-3"},
+						},
+					},
+					"tagged": boshsettings.Network{
+						Type:    "manual",
+						IP:      "10.0.1.5",
+						Netmask: "255.255.255.0",
+						MTU:     9000,
+						VirtualInterface: boshsettings.VirtualInterface{
+							Type:    boshsettings.VirtualInterfaceTypeVLAN,
+							Members: []string{"fake-mac-4"},
+							VLANID:  100,
+						},
+					},
+				}
+
+				stubInterfaces(map[string]string{
+					"eth0": "fake-mac-1",
+					"eth1": "fake-mac-2",
+					"eth2": "fake-mac-3",
+					"eth3": "fake-mac-4",
+				})
+
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+					boship.NewSimpleInterfaceAddress("br0", "10.0.0.5"),
+					boship.NewSimpleInterfaceAddress("eth3.100", "10.0.1.5"),
+				}
+
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred())
+
+				netplanConfig := fs.GetFileTestStat("/etc/netplan/90-bosh-agent.yaml")
+				Expect(netplanConfig).ToNot(BeNil())
+				Expect(netplanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+network:
+  version: 2
+  renderer: networkd
+  ethernets:
+    eth0:
+      dhcp4: false
+      dhcp6: false
+    eth1:
+      dhcp4: false
+      dhcp6: false
+    eth2:
+      dhcp4: false
+      dhcp6: false
+    eth3:
+      dhcp4: false
+      dhcp6: false
+  vlans:
+    eth3.100:
+      id: 100
+      link: eth3
+      dhcp4: false
+      dhcp6: false
+      addresses: [10.0.1.5/24]
+      mtu: 9000
+      nameservers:
+        addresses: [8.8.8.8]
+  bonds:
+    bond0:
+      interfaces: [eth0, eth1]
+      parameters:
+        mode: active-backup
+        mii-monitor-interval: 100
+      dhcp4: true
+      dhcp6: false
+      nameservers:
+        addresses: [8.8.8.8]
+  bridges:
+    br0:
+      interfaces: [eth2]
+      parameters:
+        stp: false
+        forward-delay: 0
+      dhcp4: false
+      dhcp6: false
+      addresses: [10.0.0.5/24]
+      nameservers:
+        addresses: [8.8.8.8]
+`))
+			})
+
+			It("assigns secondary addresses to the same interface", func() {
+				manualNetwork := networks["manual"]
+				manualNetwork.SecondaryAddresses = []boshsettings.Address{
+					{IP: "1.2.3.5", Netmask: "255.255.255.0"},
+					{IP: "2001:db8::5", Netmask: "ffff:ffff:ffff:ffff::"},
+				}
+				networks["manual"] = manualNetwork
+
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+					boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+					boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.5"),
+					boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::5"),
+				}
+
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred())
+
+				netplanConfig := fs.GetFileTestStat("/etc/netplan/90-bosh-agent.yaml")
+				Expect(netplanConfig).ToNot(BeNil())
+				Expect(netplanConfig.StringContents()).To(ContainSubstring("\n      addresses: [1.2.3.4/24, 1.2.3.5/24, 2001:db8::5/64]\n"))
+
+				Expect(kernelIPv6.Enabled).To(BeTrue())
+				Expect(addressBroadcaster.BroadcastMACAddressesAddresses).To(ConsistOf(
+					boship.NewResolvingInterfaceAddress("ethdhcp", ipResolver),
+					boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+					boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.5"),
+					boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::5"),
+				))
+			})
+
+			It("routes traffic from network address through its own routing table", func() {
+				manualNetwork := networks["manual"]
+				manualNetwork.RoutingTable = 100
+				manualNetwork.Routes = boshsettings.Routes{
+					{Destination: "10.10.0.0", Netmask: "255.255.0.0", Gateway: "1.2.3.254"},
+				}
+				networks["manual"] = manualNetwork
+
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred())
+
+				netplanConfig := fs.GetFileTestStat("/etc/netplan/90-bosh-agent.yaml")
+				Expect(netplanConfig).ToNot(BeNil())
+				Expect(netplanConfig.StringContents()).To(ContainSubstring(`
+    ethstatic:
+      dhcp4: false
+      dhcp6: false
+      addresses: [1.2.3.4/24]
+      nameservers:
+        addresses: [8.8.8.8, 9.9.9.9]
+      routes:
+        - to: 0.0.0.0/0
+          via: 1.2.3.1
+        - to: 10.10.0.0/16
+          via: 1.2.3.254
+        - to: 1.2.3.0/24
+          scope: link
+          table: 100
+        - to: 0.0.0.0/0
+          via: 1.2.3.1
+          table: 100
+        - to: 10.10.0.0/16
+          via: 1.2.3.254
+          table: 100
+      routing-policy:
+        - from: 1.2.3.4
+          table: 100
+`))
+			})
+		})
+
+		Context("when networkd backend is used", func() {
+			var netManager Manager
+
+			BeforeEach(func() {
+				netManager = newNetManager(NetworkdBackendNetworkd)
+			})
+
+			It("writes networkd configs and restarts networkd", func() {
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred())
+
+				dhcpConfig := fs.GetFileTestStat("/etc/systemd/network/10-bosh-agent-ethdhcp.network")
+				Expect(dhcpConfig).ToNot(BeNil())
+				Expect(dhcpConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethdhcp
+
+[Network]
+DHCP=ipv4
+DNS=8.8.8.8
+DNS=9.9.9.9
+`))
+
+				staticConfig := fs.GetFileTestStat("/etc/systemd/network/10-bosh-agent-ethstatic.network")
+				Expect(staticConfig).ToNot(BeNil())
+				Expect(staticConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+Address=1.2.3.4/24
+DNS=8.8.8.8
+DNS=9.9.9.9
+
+[Route]
+Destination=0.0.0.0/0
+Gateway=1.2.3.1
+`))
+
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{{"systemctl", "restart", "systemd-networkd"}}))
+			})
+
+			It("writes netdev configs for virtual interfaces", func() {
+				networks = boshsettings.Networks{
+					"tagged": boshsettings.Network{
+						Type:    "manual",
+						IP:      "10.0.1.5",
+						Netmask: "255.255.255.0",
+						MTU:     9000,
+						VirtualInterface: boshsettings.VirtualInterface{
+							Type:    boshsettings.VirtualInterfaceTypeVLAN,
+							Members: []string{"fake-static-mac-address"},
+							VLANID:  100,
+						},
+					},
+				}
+
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+					boship.NewSimpleInterfaceAddress("ethstatic.100", "10.0.1.5"),
+				}
+
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred())
+
+				netdevConfig := fs.GetFileTestStat("/etc/systemd/network/10-bosh-agent-ethstatic.100.netdev")
+				Expect(netdevConfig).ToNot(BeNil())
+				Expect(netdevConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+[NetDev]
+Name=ethstatic.100
+Kind=vlan
+
+[VLAN]
+Id=100
+`))
+
+				vlanConfig := fs.GetFileTestStat("/etc/systemd/network/10-bosh-agent-ethstatic.100.network")
+				Expect(vlanConfig).ToNot(BeNil())
+				Expect(vlanConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic.100
+
+[Link]
+MTUBytes=9000
+
+[Network]
+Address=10.0.1.5/24
+`))
+
+				parentConfig := fs.GetFileTestStat("/etc/systemd/network/10-bosh-agent-ethstatic.network")
+				Expect(parentConfig).ToNot(BeNil())
+				Expect(parentConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+[Match]
+Name=ethstatic
+
+[Network]
+VLAN=ethstatic.100
+`))
+			})
+
+			It("removes configs of interfaces that are no longer configured", func() {
+				fs.WriteFileString("/etc/systemd/network/10-bosh-agent-eth9.network", "fake-stale-config")
+				fs.WriteFileString("/etc/systemd/network/99-other.network", "fake-other-config")
+				fs.SetGlob("/etc/systemd/network/10-bosh-agent-*", []string{
+					"/etc/systemd/network/10-bosh-agent-eth9.network",
+					"/etc/systemd/network/10-bosh-agent-ethdhcp.network",
+				})
+
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+				Expect(<-errCh).ToNot(HaveOccurred())
+
+				Expect(fs.FileExists("/etc/systemd/network/10-bosh-agent-eth9.network")).To(BeFalse())
+				Expect(fs.FileExists("/etc/systemd/network/10-bosh-agent-ethdhcp.network")).To(BeTrue())
+				Expect(fs.FileExists("/etc/systemd/network/99-other.network")).To(BeTrue())
+			})
+
+			It("returns error when DNS servers are not known to systemd-resolved", func() {
+				fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 127.0.0.53\n")
+
+				err := netManager.SetupNetworking(networks, errCh)
+				Expect(err).To(HaveOccurred())
+				Expect(err.Error()).To(ContainSubstring("Validating dns configuration"))
+			})
+		})
+
+		Context("when networks are preconfigured", func() {
+			BeforeEach(func() {
+				for name, network := range networks {
+					network.Preconfigured = true
+					networks[name] = network
+				}
+			})
+
+			It("only configures DNS servers in systemd-resolved", func() {
+				err := newNetManager(NetworkdBackendNetplan).SetupNetworking(networks, errCh)
+				Expect(err).ToNot(HaveOccurred())
+
+				resolvedConfig := fs.GetFileTestStat("/etc/systemd/resolved.conf.d/bosh-agent.conf")
+				Expect(resolvedConfig).ToNot(BeNil())
+				Expect(resolvedConfig.StringContents()).To(Equal("# Generated by bosh-agent\n[Resolve]\nDNS=8.8.8.8 9.9.9.9\n"))
+
+				Expect(fs.FileExists("/etc/netplan/90-bosh-agent.yaml")).To(BeFalse())
+				Expect(cmdRunner.RunCommands).To(Equal([][]string{{"systemctl", "restart", "systemd-resolved"}}))
+			})
+		})
+	})
+
+	Describe("DetectDrift", func() {
+		var (
+			networks   boshsettings.Networks
+			netManager DriftDetector
+		)
+
+		expectedNetplanConfig := `# Generated by bosh-agent
+network:
+  version: 2
+  renderer: networkd
+  ethernets:
+    ethstatic:
+      dhcp4: false
+      dhcp6: false
+      addresses: [1.2.3.4/24]
+      nameservers:
+        addresses: [8.8.8.8]
+      routes:
+        - to: 0.0.0.0/0
+          via: 1.2.3.1
+`
+
+		BeforeEach(func() {
+			networks = boshsettings.Networks{
+				"manual": boshsettings.Network{
+					Type:    "manual",
+					IP:      "1.2.3.4",
+					Default: []string{"dns", "gateway"},
+					DNS:     []string{"8.8.8.8"},
+					Netmask: "255.255.255.0",
+					Gateway: "1.2.3.1",
+					Mac:     "fake-static-mac-address",
+				},
+			}
+
+			stubInterfaces(map[string]string{"ethstatic": "fake-static-mac-address"})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+			}
+
+			fs.WriteFileString("/etc/netplan/90-bosh-agent.yaml", expectedNetplanConfig)
+			fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 8.8.8.8\n")
+
+			netManager = newNetManager(NetworkdBackendNetplan).(DriftDetector)
+		})
+
+		It("does not detect drift when networking matches networks", func() {
+			drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Detected()).To(BeFalse())
+
+			Expect(fs.GetFileTestStat("/etc/netplan/90-bosh-agent.yaml").StringContents()).To(Equal(expectedNetplanConfig))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("detects modified configuration without rewriting it", func() {
+			fs.WriteFileString("/etc/netplan/90-bosh-agent.yaml", "modified")
+
+			drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeTrue())
+			Expect(drift.AddressesErr).ToNot(HaveOccurred())
+			Expect(drift.DNSErr).ToNot(HaveOccurred())
+
+			Expect(fs.GetFileTestStat("/etc/netplan/90-bosh-agent.yaml").StringContents()).To(Equal("modified"))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("detects dropped addresses and DNS servers", func() {
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{}
+			fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 9.9.9.9\n")
+
+			drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeFalse())
+			Expect(drift.AddressesErr).To(HaveOccurred())
+			Expect(drift.DNSErr).To(HaveOccurred())
+		})
+
+		Context("when networks are preconfigured", func() {
+			BeforeEach(func() {
+				networks = boshsettings.Networks{
+					"manual": boshsettings.Network{Preconfigured: true, DNS: []string{"8.8.8.8"}},
+				}
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{}
+			})
+
+			It("ignores configuration files and addresses", func() {
+				fs.WriteFileString("/etc/netplan/90-bosh-agent.yaml", "modified")
+
+				drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(drift.Detected()).To(BeFalse())
+			})
+
+			It("detects dropped DNS servers", func() {
+				fs.WriteFileString("/run/systemd/resolve/resolv.conf", "nameserver 9.9.9.9\n")
+
+				drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(drift.ConfigsChanged).To(BeFalse())
+				Expect(drift.AddressesErr).ToNot(HaveOccurred())
+				Expect(drift.DNSErr).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("RepairNetworking", func() {
+		BeforeEach(func() {
+			stubInterfaces(map[string]string{"ethstatic": "fake-static-mac-address"})
+		})
+
+		It("reapplies configuration even if it did not change", func() {
+			networks := boshsettings.Networks{
+				"manual": boshsettings.Network{
+					Type:    "manual",
+					IP:      "1.2.3.4",
+					Default: []string{"dns", "gateway"},
+					DNS:     []string{"8.8.8.8"},
+					Netmask: "255.255.255.0",
+					Gateway: "1.2.3.1",
+					Mac:     "fake-static-mac-address",
+				},
+			}
+
+			netManager := newNetManager(NetworkdBackendNetworkd).(DriftDetector)
+
+			err := netManager.RepairNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = netManager.RepairNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/systemd/network/10-bosh-agent-ethstatic.network")).To(BeTrue())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"systemctl", "restart", "systemd-networkd"},
+				{"systemctl", "restart", "systemd-networkd"},
+			}))
+		})
+	})
+
+	Describe("GetConfiguredNetworkInterfaces", func() {
+		BeforeEach(func() {
+			stubInterfaces(map[string]string{
+				"eth0": "aa:bb",
+				"eth1": "cc:dd",
+			})
+		})
+
+		It("returns interfaces netplan generated networkd configs for", func() {
+			fs.WriteFileString("/run/systemd/network/10-netplan-eth1.network", "")
+
+			interfaces, err := newNetManager(NetworkdBackendNetplan).GetConfiguredNetworkInterfaces()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(interfaces).To(Equal([]string{"eth1"}))
+		})
+
+		It("returns interfaces that have networkd configs", func() {
+			fs.WriteFileString("/etc/systemd/network/10-bosh-agent-eth0.network", "")
+
+			interfaces, err := newNetManager(NetworkdBackendNetworkd).GetConfiguredNetworkInterfaces()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(interfaces).To(Equal([]string{"eth0"}))
+		})
+	})
+})
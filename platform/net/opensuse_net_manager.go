@@ -32,6 +32,7 @@ type opensuseNetManager struct {
 type OpenSuseDHCPInterfaceConfiguration struct {
 	Name              string
 	SetupDefaultRoute bool
+	VirtualInterface  VirtualInterfaceConfiguration
 }
 
 func NewOpensuseNetManager(
@@ -69,24 +70,16 @@ func (net opensuseNetManager) SetupNetworking(networks boshsettings.Networks, er
 		return bosherr.WrapError(err, "Computing network configuration")
 	}
 
-	interfacesChanged, err := net.writeNetworkInterfaces(dhcpConfigs, staticConfigs, dnsServers)
+	changed, err := net.writeNetConfigs(dhcpConfigs, staticConfigs, dnsServers, boshsys.ConvergeFileContentsOpts{})
 	if err != nil {
-		return bosherr.WrapError(err, "Writing network configuration")
+		return err
 	}
 
-	dhcpChanged := false
-	if len(dhcpConfigs) > 0 {
-		dhcpChanged, err = net.writeDHCPConfiguration(dnsServers, dhcpConfigs)
-		if err != nil {
-			return err
-		}
-	}
-
-	if interfacesChanged || dhcpChanged {
+	if changed {
 		net.restartNetworkingInterfaces()
 	}
 
-	staticAddresses, dynamicAddresses := net.ifaceAddresses(staticConfigs, dhcpConfigs)
+	staticAddresses, dynamicAddresses := ifaceAddresses(staticConfigs, dhcpConfigs, net.ipResolver)
 
 	err = net.interfaceAddressesValidator.Validate(staticAddresses)
 	if err != nil {
@@ -98,11 +91,79 @@ func (net opensuseNetManager) SetupNetworking(networks boshsettings.Networks, er
 		return bosherr.WrapError(err, "Validating dns configuration")
 	}
 
-	net.broadcastIps(append(staticAddresses, dynamicAddresses...), errCh)
+	broadcastIps(net.addressBroadcaster, append(staticAddresses, dynamicAddresses...), errCh)
+
+	return nil
+}
+
+func (net opensuseNetManager) DetectDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (Drift, error) {
+	if networks.IsPreconfigured() {
+		dnsNetwork, _ := networks.DefaultNetworkFor("dns")
+		return Drift{DNSErr: net.dnsValidator.Validate(dnsNetwork.DNS)}, nil
+	}
+
+	staticConfigs, dhcpConfigs, dnsServers, err := net.computeNetworkConfig(networks)
+	if err != nil {
+		return Drift{}, bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	changed, err := net.writeNetConfigs(dhcpConfigs, staticConfigs, dnsServers, boshsys.ConvergeFileContentsOpts{DryRun: true})
+	if err != nil {
+		return Drift{}, bosherr.WrapError(err, "Determining if network configs have changed")
+	}
+
+	staticAddresses, _ := ifaceAddresses(staticConfigs, dhcpConfigs, net.ipResolver)
+
+	return Drift{
+		ConfigsChanged: changed,
+		AddressesErr:   interfaceAddressesValidator.Validate(staticAddresses),
+		DNSErr:         net.dnsValidator.Validate(dnsServers),
+	}, nil
+}
+
+func (net opensuseNetManager) RepairNetworking(networks boshsettings.Networks) error {
+	if networks.IsPreconfigured() {
+		return net.writeResolvConf(networks)
+	}
+
+	staticConfigs, dhcpConfigs, dnsServers, err := net.computeNetworkConfig(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	_, err = net.writeNetConfigs(dhcpConfigs, staticConfigs, dnsServers, boshsys.ConvergeFileContentsOpts{})
+	if err != nil {
+		return bosherr.WrapError(err, "Updating network configs")
+	}
+
+	net.restartNetworkingInterfaces()
 
 	return nil
 }
 
+func (net opensuseNetManager) writeNetConfigs(
+	dhcpConfigs []DHCPInterfaceConfiguration,
+	staticConfigs []StaticInterfaceConfiguration,
+	dnsServers []string,
+	opts boshsys.ConvergeFileContentsOpts) (bool, error) {
+
+	interfacesChanged, err := net.writeNetworkInterfaces(dhcpConfigs, staticConfigs, dnsServers, opts)
+	if err != nil {
+		return false, bosherr.WrapError(err, "Writing network configuration")
+	}
+
+	dhcpChanged := false
+
+	if len(dhcpConfigs) > 0 {
+		dhcpChanged, err = net.writeDHCPConfiguration(dnsServers, dhcpConfigs, opts)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return (interfacesChanged || dhcpChanged), nil
+}
+
 func (net opensuseNetManager) computeNetworkConfig(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, []string, error) {
 	nonVipNetworks := boshsettings.Networks{}
 	for networkName, networkSettings := range networks {
@@ -158,7 +219,7 @@ func (net opensuseNetManager) writeResolvConf(networks boshsettings.Networks) er
 func (net opensuseNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 	interfaces := []string{}
 
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return interfaces, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -174,30 +235,62 @@ func (net opensuseNetManager) GetConfiguredNetworkInterfaces() ([]string, error)
 
 const opensuseDHCPIfcfgTemplate = `DEVICE={{ .Name }}
 BOOTPROTO=dhcp
-STARTMODE='auto'
+STARTMODE='auto'{{ template "virtual-interface" .VirtualInterface }}
 DHCLIENT_SET_DEFAULT_ROUTE={{ if .SetupDefaultRoute }}yes{{ else }}no{{ end }}
 `
 
 const opensuseStaticIfcfgTemplate = `DEVICE={{ .Name }}
 BOOTPROTO=static
-STARTMODE='auto'
+STARTMODE='auto'{{ template "virtual-interface" .VirtualInterface }}
 IPADDR={{ .Address }}
 NETMASK={{ .Netmask }}
 BROADCAST={{ .Broadcast }}
-GATEWAY={{ .Gateway }}{{ range .DNSServers }}
+GATEWAY={{ .Gateway }}{{ if .MTU }}
+MTU='{{ .MTU }}'{{ end }}{{ range $i, $address := .SecondaryAddresses }}
+IPADDR_{{ $i }}='{{ $address.CIDR }}'{{ end }}{{ range .DNSServers }}
 DNS{{ .Index }}={{ .Address }}{{ end }}
 `
 
+const opensuseVirtualInterfaceTemplate = `{{ define "virtual-interface" }}{{ if .IsVLAN }}
+ETHERDEVICE='{{ .Parent }}'
+VLAN_ID='{{ .VLANID }}'{{ else if .IsBond }}
+BONDING_MASTER='yes'
+BONDING_MODULE_OPTS='mode={{ .BondMode }} miimon=100'{{ range $i, $member := .Members }}
+BONDING_SLAVE{{ $i }}='{{ $member }}'{{ end }}{{ else if .IsBridge }}
+BRIDGE='yes'
+BRIDGE_PORTS='{{ range $i, $member := .Members }}{{ if $i }} {{ end }}{{ $member }}{{ end }}'
+BRIDGE_STP='off'
+BRIDGE_FORWARDDELAY='{{ .ForwardDelaySec }}'{{ end }}{{ end }}`
+
+// Bond members are brought up by the bond itself
+const opensuseMemberIfcfgTemplate = `DEVICE={{ .Name }}
+BOOTPROTO='none'
+STARTMODE='{{ if .IsBond }}hotplug{{ else }}auto{{ end }}'
+`
+
+const opensuseStaticRoutesTemplate = `{{ range .Routes }}{{ .To }} {{ .Gateway }} - {{ $.Name }}
+{{ end }}{{ if .RoutingTable }}{{ .NetworkCIDR }} - - {{ .Name }} src {{ .Address }} table {{ .RoutingTable }}
+{{ if .Gateway }}default {{ .Gateway }} - {{ .Name }} table {{ .RoutingTable }}
+{{ end }}{{ range .Routes }}{{ .To }} {{ .Gateway }} - {{ $.Name }} table {{ $.RoutingTable }}
+{{ end }}{{ end }}`
+
+const opensuseStaticRulesTemplate = `{{ if .RoutingTable }}from {{ .Address }} table {{ .RoutingTable }}
+{{ end }}`
+
 type opensuseStaticIfcfg struct {
 	*StaticInterfaceConfiguration
 	DNSServers []dnsConfig
 }
 
 func (net opensuseNetManager) ifcfgFilePath(name string) string {
-	return path.Join("/etc/sysconfig/network", "ifcfg-"+name)
+	return net.configFilePath("ifcfg", name)
+}
+
+func (net opensuseNetManager) configFilePath(prefix, name string) string {
+	return path.Join("/etc/sysconfig/network", prefix+"-"+name)
 }
 
-func (net opensuseNetManager) writeIfcfgFile(name string, t *template.Template, config interface{}) (bool, error) {
+func (net opensuseNetManager) writeIfcfgFile(name string, t *template.Template, config interface{}, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
 	buffer := bytes.NewBuffer([]byte{})
 
 	err := t.Execute(buffer, config)
@@ -206,7 +299,7 @@ func (net opensuseNetManager) writeIfcfgFile(name string, t *template.Template,
 	}
 
 	filePath := net.ifcfgFilePath(name)
-	changed, err := net.fs.ConvergeFileContents(filePath, buffer.Bytes())
+	changed, err := net.fs.ConvergeFileContents(filePath, buffer.Bytes(), opts)
 	if err != nil {
 		return false, bosherr.WrapErrorf(err, "Writing config to '%s'", filePath)
 	}
@@ -214,36 +307,50 @@ func (net opensuseNetManager) writeIfcfgFile(name string, t *template.Template,
 	return changed, nil
 }
 
-func (net opensuseNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsServers []string) (bool, error) {
+func (net opensuseNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, staticInterfaceConfigurations []StaticInterfaceConfiguration, dnsServers []string, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
 	anyInterfaceChanged := false
 
 	staticConfig := opensuseStaticIfcfg{}
 	staticConfig.DNSServers = newDNSConfigs(dnsServers)
-	staticTemplate := template.Must(template.New("ifcfg").Parse(opensuseStaticIfcfgTemplate))
+	staticTemplate := template.Must(template.Must(template.New("ifcfg").Parse(opensuseStaticIfcfgTemplate)).Parse(opensuseVirtualInterfaceTemplate))
+	routesTemplate := template.Must(template.New("ifroute").Parse(opensuseStaticRoutesTemplate))
+	rulesTemplate := template.Must(template.New("ifrule").Parse(opensuseStaticRulesTemplate))
 
 	for i := range staticInterfaceConfigurations {
 		staticConfig.StaticInterfaceConfiguration = &staticInterfaceConfigurations[i]
+		name := staticConfig.StaticInterfaceConfiguration.Name
 
-		changed, err := net.writeIfcfgFile(staticConfig.StaticInterfaceConfiguration.Name, staticTemplate, staticConfig)
+		changed, err := net.writeIfcfgFile(name, staticTemplate, staticConfig, opts)
 		if err != nil {
 			return false, bosherr.WrapError(err, "Writing static config")
 		}
 
-		anyInterfaceChanged = anyInterfaceChanged || changed
+		routesChanged, err := convergeOptionalFileContents(net.fs, net.configFilePath("ifroute", name), routesTemplate, staticConfig.StaticInterfaceConfiguration, opts)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Writing static routes")
+		}
+
+		rulesChanged, err := convergeOptionalFileContents(net.fs, net.configFilePath("ifrule", name), rulesTemplate, staticConfig.StaticInterfaceConfiguration, opts)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Writing routing rules")
+		}
+
+		anyInterfaceChanged = anyInterfaceChanged || changed || routesChanged || rulesChanged
 	}
 
-	dhcpTemplate := template.Must(template.New("ifcfg").Parse(opensuseDHCPIfcfgTemplate))
+	dhcpTemplate := template.Must(template.Must(template.New("ifcfg").Parse(opensuseDHCPIfcfgTemplate)).Parse(opensuseVirtualInterfaceTemplate))
 
 	setupDefaultRoute := true
 	for i := range dhcpInterfaceConfigurations {
 		config := OpenSuseDHCPInterfaceConfiguration{
 			Name:              dhcpInterfaceConfigurations[i].Name,
 			SetupDefaultRoute: setupDefaultRoute,
+			VirtualInterface:  dhcpInterfaceConfigurations[i].VirtualInterface,
 		}
 
 		setupDefaultRoute = false
 
-		changed, err := net.writeIfcfgFile(config.Name, dhcpTemplate, config)
+		changed, err := net.writeIfcfgFile(config.Name, dhcpTemplate, config, opts)
 		if err != nil {
 			return false, bosherr.WrapError(err, "Writing dhcp config")
 		}
@@ -251,11 +358,22 @@ func (net opensuseNetManager) writeNetworkInterfaces(dhcpInterfaceConfigurations
 		anyInterfaceChanged = anyInterfaceChanged || changed
 	}
 
+	memberTemplate := template.Must(template.New("ifcfg").Parse(opensuseMemberIfcfgTemplate))
+
+	for _, config := range MemberInterfaceConfigurations(staticInterfaceConfigurations, dhcpInterfaceConfigurations) {
+		changed, err := net.writeIfcfgFile(config.Name, memberTemplate, config, opts)
+		if err != nil {
+			return false, bosherr.WrapError(err, "Writing member config")
+		}
+
+		anyInterfaceChanged = anyInterfaceChanged || changed
+	}
+
 	return anyInterfaceChanged, nil
 }
 
 func (net opensuseNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return nil, nil, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -269,15 +387,6 @@ func (net opensuseNetManager) buildInterfaces(networks boshsettings.Networks) ([
 	return staticInterfaceConfigurations, dhcpInterfaceConfigurations, nil
 }
 
-func (net opensuseNetManager) broadcastIps(addresses []boship.InterfaceAddress, errCh chan error) {
-	go func() {
-		net.addressBroadcaster.BroadcastMACAddresses(addresses)
-		if errCh != nil {
-			errCh <- nil
-		}
-	}()
-}
-
 func (net opensuseNetManager) restartNetworkingInterfaces() {
 	net.logger.Debug(opensuseNetManagerLogTag, "Restarting network interfaces")
 
@@ -317,7 +426,7 @@ NETCONFIG_NIS_STATIC_SERVERS=""
 WIRELESS_REGULATORY_DOMAIN=''
 `
 
-func (net opensuseNetManager) writeDHCPConfiguration(newServers []string, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration) (bool, error) {
+func (net opensuseNetManager) writeDHCPConfiguration(newServers []string, dhcpInterfaceConfigurations []DHCPInterfaceConfiguration, opts boshsys.ConvergeFileContentsOpts) (bool, error) {
 	changed := false
 	dnsServers := []string{}
 	buffer := bytes.NewBuffer([]byte{})
@@ -364,12 +473,12 @@ func (net opensuseNetManager) writeDHCPConfiguration(newServers []string, dhcpIn
 		return false, bosherr.WrapError(err, "Generating config from template")
 	}
 
-	changed, err = net.fs.ConvergeFileContents(dhclientConfigFile, buffer.Bytes())
+	changed, err = net.fs.ConvergeFileContents(dhclientConfigFile, buffer.Bytes(), opts)
 	if err != nil {
 		return changed, bosherr.WrapErrorf(err, "Writing to %s", dhclientConfigFile)
 	}
 
-	if changed {
+	if changed && !opts.DryRun {
 		_, _, _, err := net.cmdRunner.RunCommand("rm", "/etc/resolv.conf")
 		if err != nil {
 			net.logger.Error(opensuseNetManagerLogTag, "Failed to remove /etc/resolv.conf: %s", err.Error())
@@ -378,44 +487,3 @@ func (net opensuseNetManager) writeDHCPConfiguration(newServers []string, dhcpIn
 
 	return changed, nil
 }
-
-func (net opensuseNetManager) detectMacAddresses() (map[string]string, error) {
-	addresses := map[string]string{}
-
-	filePaths, err := net.fs.Glob("/sys/class/net/*")
-	if err != nil {
-		return addresses, bosherr.WrapError(err, "Getting file list from /sys/class/net")
-	}
-
-	var macAddress string
-	for _, filePath := range filePaths {
-		isPhysicalDevice := net.fs.FileExists(path.Join(filePath, "device"))
-
-		if isPhysicalDevice {
-			macAddress, err = net.fs.ReadFileString(path.Join(filePath, "address"))
-			if err != nil {
-				return addresses, bosherr.WrapError(err, "Reading mac address from file")
-			}
-
-			macAddress = strings.Trim(macAddress, "\n")
-
-			interfaceName := path.Base(filePath)
-			addresses[macAddress] = interfaceName
-		}
-	}
-
-	return addresses, nil
-}
-
-func (net opensuseNetManager) ifaceAddresses(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) ([]boship.InterfaceAddress, []boship.InterfaceAddress) {
-	staticAddresses := []boship.InterfaceAddress{}
-	for _, iface := range staticConfigs {
-		staticAddresses = append(staticAddresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.Address))
-	}
-	dynamicAddresses := []boship.InterfaceAddress{}
-	for _, iface := range dhcpConfigs {
-		dynamicAddresses = append(dynamicAddresses, boship.NewResolvingInterfaceAddress(iface.Name, net.ipResolver))
-	}
-
-	return staticAddresses, dynamicAddresses
-}
@@ -261,6 +261,193 @@ nameserver 9.9.9.9
 			Expect(dhcpConfig.StringContents()).To(Equal(expectedNetworkConfigurationForDHCP))
 		})
 
+		It("writes secondary addresses of static network", func() {
+			staticNetwork.SecondaryAddresses = []boshsettings.Address{
+				{IP: "1.2.3.5", Netmask: "255.255.255.0"},
+				{IP: "2001:db8::5", Netmask: "ffff:ffff:ffff:ffff::"},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.5"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::5"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifcfg-ethstatic")
+			Expect(staticConfig).ToNot(BeNil())
+			Expect(staticConfig.StringContents()).To(ContainSubstring("\nIPADDR_0='1.2.3.5/24'\nIPADDR_1='2001:db8::5/64'\n"))
+		})
+
+		It("writes default route of the other address family for dual-stack interface", func() {
+			staticNetwork.Default = []string{"gateway"}
+			ipv6Network := boshsettings.Network{
+				Type:    "manual",
+				IP:      "2001:db8::5",
+				Netmask: "ffff:ffff:ffff:ffff::",
+				Gateway: "2001:db8::1",
+				Mac:     "fake-static-mac-address",
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("ethstatic", "2001:db8::5"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork, "ipv6-network": ipv6Network}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			routeConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifroute-ethstatic")
+			Expect(routeConfig).ToNot(BeNil())
+			Expect(routeConfig.StringContents()).To(Equal("default 2001:db8::1 - ethstatic\n"))
+		})
+
+		It("writes mtu, static routes and routing rules for static networks", func() {
+			staticNetwork.MTU = 9000
+			staticNetwork.Routes = boshsettings.Routes{
+				{Destination: "10.10.0.0", Netmask: "255.255.0.0", Gateway: "1.2.3.254"},
+			}
+			staticNetwork.RoutingTable = 100
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"ethstatic": staticNetwork,
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-network": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			staticConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifcfg-ethstatic")
+			Expect(staticConfig).ToNot(BeNil())
+			Expect(staticConfig.StringContents()).To(ContainSubstring("\nMTU='9000'\n"))
+
+			routeConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifroute-ethstatic")
+			Expect(routeConfig).ToNot(BeNil())
+			Expect(routeConfig.StringContents()).To(Equal(`10.10.0.0/16 1.2.3.254 - ethstatic
+1.2.3.0/24 - - ethstatic src 1.2.3.4 table 100
+default 3.4.5.6 - ethstatic table 100
+10.10.0.0/16 1.2.3.254 - ethstatic table 100
+`))
+
+			ruleConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifrule-ethstatic")
+			Expect(ruleConfig).ToNot(BeNil())
+			Expect(ruleConfig.StringContents()).To(Equal("from 1.2.3.4 table 100\n"))
+		})
+
+		It("writes network scripts for bridge and its members", func() {
+			bridgeNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeBridge,
+					Name:    "br0",
+					Members: []string{"fake-mac-1", "fake-mac-2"},
+				},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth1": {Mac: "fake-mac-1"},
+				"eth2": {Mac: "fake-mac-2"},
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"bridge": bridgeNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			bridgeConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifcfg-br0")
+			Expect(bridgeConfig).ToNot(BeNil())
+			Expect(bridgeConfig.StringContents()).To(Equal(`DEVICE=br0
+BOOTPROTO=dhcp
+STARTMODE='auto'
+BRIDGE='yes'
+BRIDGE_PORTS='eth1 eth2'
+BRIDGE_STP='off'
+BRIDGE_FORWARDDELAY='0'
+DHCLIENT_SET_DEFAULT_ROUTE=yes
+`))
+
+			memberConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifcfg-eth1")
+			Expect(memberConfig).ToNot(BeNil())
+			Expect(memberConfig.StringContents()).To(Equal(`DEVICE=eth1
+BOOTPROTO='none'
+STARTMODE='auto'
+`))
+		})
+
+		It("writes network scripts for bond and VLAN interfaces", func() {
+			bondNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Netmask: "255.255.255.0",
+				Gateway: "3.4.5.6",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeBond,
+					Name:    "bond0",
+					Members: []string{"fake-mac-1", "fake-mac-2"},
+				},
+			}
+			vlanNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeVLAN,
+					Members: []string{"fake-mac-0"},
+					VLANID:  100,
+				},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": {Mac: "fake-mac-0"},
+				"eth1": {Mac: "fake-mac-1"},
+				"eth2": {Mac: "fake-mac-2"},
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("bond0", "1.2.3.4"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"bond": bondNetwork, "vlan": vlanNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			bondConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifcfg-bond0")
+			Expect(bondConfig).ToNot(BeNil())
+			Expect(bondConfig.StringContents()).To(Equal(`DEVICE=bond0
+BOOTPROTO=static
+STARTMODE='auto'
+BONDING_MASTER='yes'
+BONDING_MODULE_OPTS='mode=active-backup miimon=100'
+BONDING_SLAVE0='eth1'
+BONDING_SLAVE1='eth2'
+IPADDR=1.2.3.4
+NETMASK=255.255.255.0
+BROADCAST=1.2.3.255
+GATEWAY=3.4.5.6
+`))
+
+			memberConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifcfg-eth2")
+			Expect(memberConfig).ToNot(BeNil())
+			Expect(memberConfig.StringContents()).To(Equal(`DEVICE=eth2
+BOOTPROTO='none'
+STARTMODE='hotplug'
+`))
+
+			vlanConfig := fs.GetFileTestStat("/etc/sysconfig/network/ifcfg-eth0.100")
+			Expect(vlanConfig).ToNot(BeNil())
+			Expect(vlanConfig.StringContents()).To(Equal(`DEVICE=eth0.100
+BOOTPROTO=dhcp
+STARTMODE='auto'
+ETHERDEVICE='eth0'
+VLAN_ID='100'
+DHCLIENT_SET_DEFAULT_ROUTE=yes
+`))
+		})
+
 		It("only sets up one default route", func() {
 			stubInterfaces(map[string]boshsettings.Network{
 				"eth1": dhcpNetwork,
@@ -691,6 +878,148 @@ WIRELESS_REGULATORY_DOMAIN=''
 		})
 	})
 
+	Describe("DetectDrift", func() {
+		var networks boshsettings.Networks
+
+		BeforeEach(func() {
+			networks = boshsettings.Networks{
+				"dynamic": boshsettings.Network{
+					Type:    "dynamic",
+					Default: []string{"dns"},
+					DNS:     []string{"8.8.8.8"},
+					Mac:     "fake-dhcp-mac-address",
+				},
+				"manual": boshsettings.Network{
+					Type:    "manual",
+					IP:      "1.2.3.4",
+					Default: []string{"gateway"},
+					Netmask: "255.255.255.0",
+					Gateway: "3.4.5.6",
+					Mac:     "fake-static-mac-address",
+				},
+			}
+			fs.SetGlob("/sys/class/net/*", []string{
+				writeNetworkDevice("ethdhcp", "fake-dhcp-mac-address", true),
+				writeNetworkDevice("ethstatic", "fake-static-mac-address", true),
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+			}
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 8.8.8.8\n")
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			cmdRunner.RunCommands = [][]string{}
+		})
+
+		It("does not detect drift right after networking was set up", func() {
+			drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Detected()).To(BeFalse())
+		})
+
+		It("detects modified ifcfg file without rewriting it", func() {
+			fs.WriteFileString("/etc/sysconfig/network/ifcfg-ethstatic", "modified")
+
+			drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeTrue())
+
+			Expect(fs.GetFileTestStat("/etc/sysconfig/network/ifcfg-ethstatic").StringContents()).To(Equal("modified"))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("detects modified dhcp config without removing /etc/resolv.conf", func() {
+			fs.WriteFileString("/etc/sysconfig/network/config", "modified")
+
+			drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeTrue())
+
+			Expect(fs.GetFileTestStat("/etc/sysconfig/network/config").StringContents()).To(Equal("modified"))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("detects dropped addresses and rewritten /etc/resolv.conf", func() {
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{}
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 127.0.0.1\n")
+
+			drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeFalse())
+			Expect(drift.AddressesErr).To(HaveOccurred())
+			Expect(drift.DNSErr).To(HaveOccurred())
+		})
+
+		Context("when networks are preconfigured", func() {
+			BeforeEach(func() {
+				networks = boshsettings.Networks{
+					"manual": boshsettings.Network{Preconfigured: true, DNS: []string{"8.8.8.8"}},
+				}
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{}
+			})
+
+			It("ignores configuration files and addresses", func() {
+				fs.WriteFileString("/etc/sysconfig/network/ifcfg-ethstatic", "modified")
+
+				drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(drift.Detected()).To(BeFalse())
+			})
+
+			It("detects rewritten /etc/resolv.conf", func() {
+				fs.WriteFileString("/etc/resolv.conf", "nameserver 127.0.0.1\n")
+
+				drift, err := netManager.(DriftDetector).DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(drift.DNSErr).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("RepairNetworking", func() {
+		It("restarts networking even if configuration did not change", func() {
+			networks := boshsettings.Networks{
+				"manual": boshsettings.Network{
+					Type:    "manual",
+					IP:      "1.2.3.4",
+					Default: []string{"dns", "gateway"},
+					DNS:     []string{"8.8.8.8"},
+					Netmask: "255.255.255.0",
+					Gateway: "3.4.5.6",
+					Mac:     "fake-static-mac-address",
+				},
+			}
+			fs.SetGlob("/sys/class/net/*", []string{writeNetworkDevice("ethstatic", "fake-static-mac-address", true)})
+
+			err := netManager.(DriftDetector).RepairNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = netManager.(DriftDetector).RepairNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.FileExists("/etc/sysconfig/network/ifcfg-ethstatic")).To(BeTrue())
+			Expect(cmdRunner.RunCommands).To(Equal([][]string{
+				{"service", "network", "restart"},
+				{"service", "network", "restart"},
+			}))
+		})
+
+		It("rewrites /etc/resolv.conf for preconfigured networks", func() {
+			networks := boshsettings.Networks{
+				"manual": boshsettings.Network{Preconfigured: true, DNS: []string{"8.8.8.8"}},
+			}
+
+			err := netManager.(DriftDetector).RepairNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(fs.GetFileTestStat("/etc/resolv.conf").StringContents()).To(ContainSubstring("nameserver 8.8.8.8"))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+	})
+
 	Describe("GetConfiguredNetworkInterfaces", func() {
 		Context("when there are network devices", func() {
 			BeforeEach(func() {
@@ -2,7 +2,6 @@ package net
 
 import (
 	"bytes"
-	"path"
 	"regexp"
 	"sort"
 	"strings"
@@ -120,22 +119,13 @@ func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, errC
 	}
 
 	if changed {
-		err = net.removeDhcpDNSConfiguration()
+		err = net.applyNetConfigs(dhcpConfigs, staticConfigs, dnsServers)
 		if err != nil {
 			return err
 		}
-
-		net.stopNetworkingInterfaces(dhcpConfigs, staticConfigs)
-
-		_, err = net.writeNetConfigs(dhcpConfigs, staticConfigs, dnsServers, boshsys.ConvergeFileContentsOpts{})
-		if err != nil {
-			return bosherr.WrapError(err, "Updating network configs")
-		}
-
-		net.startNetworkingInterfaces(dhcpConfigs, staticConfigs)
 	}
 
-	staticAddresses, dynamicAddresses := net.ifaceAddresses(staticConfigs, dhcpConfigs)
+	staticAddresses, dynamicAddresses := ifaceAddresses(staticConfigs, dhcpConfigs, net.ipResolver)
 
 	err = net.interfaceAddressesValidator.Validate(staticAddresses)
 	if err != nil {
@@ -147,7 +137,67 @@ func (net UbuntuNetManager) SetupNetworking(networks boshsettings.Networks, errC
 		return bosherr.WrapError(err, "Validating dns configuration")
 	}
 
-	net.broadcastIps(append(staticAddresses, dynamicAddresses...), errCh)
+	broadcastIps(net.addressBroadcaster, append(staticAddresses, dynamicAddresses...), errCh)
+
+	return nil
+}
+
+func (net UbuntuNetManager) DetectDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (Drift, error) {
+	if networks.IsPreconfigured() {
+		dnsNetwork, _ := networks.DefaultNetworkFor("dns")
+		return Drift{DNSErr: net.dnsValidator.Validate(dnsNetwork.DNS)}, nil
+	}
+
+	staticConfigs, dhcpConfigs, dnsServers, err := net.ComputeNetworkConfig(networks)
+	if err != nil {
+		return Drift{}, bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	changed, err := net.writeNetConfigs(dhcpConfigs, staticConfigs, dnsServers, boshsys.ConvergeFileContentsOpts{DryRun: true})
+	if err != nil {
+		return Drift{}, bosherr.WrapError(err, "Determining if network configs have changed")
+	}
+
+	staticAddresses, _ := ifaceAddresses(staticConfigs, dhcpConfigs, net.ipResolver)
+
+	return Drift{
+		ConfigsChanged: changed,
+		AddressesErr:   interfaceAddressesValidator.Validate(staticAddresses),
+		DNSErr:         net.dnsValidator.Validate(dnsServers),
+	}, nil
+}
+
+func (net UbuntuNetManager) RepairNetworking(networks boshsettings.Networks) error {
+	if networks.IsPreconfigured() {
+		return net.writeResolvConf(networks)
+	}
+
+	staticConfigs, dhcpConfigs, dnsServers, err := net.ComputeNetworkConfig(networks)
+	if err != nil {
+		return bosherr.WrapError(err, "Computing network configuration")
+	}
+
+	return net.applyNetConfigs(dhcpConfigs, staticConfigs, dnsServers)
+}
+
+func (net UbuntuNetManager) applyNetConfigs(
+	dhcpConfigs DHCPInterfaceConfigurations,
+	staticConfigs StaticInterfaceConfigurations,
+	dnsServers []string) error {
+
+	err := net.removeDhcpDNSConfiguration()
+	if err != nil {
+		return err
+	}
+
+	net.stopNetworkingInterfaces(dhcpConfigs, staticConfigs)
+
+	_, err = net.writeNetConfigs(dhcpConfigs, staticConfigs, dnsServers, boshsys.ConvergeFileContentsOpts{})
+	if err != nil {
+		return bosherr.WrapError(err, "Updating network configs")
+	}
+
+	net.startNetworkingInterfaces(dhcpConfigs, staticConfigs)
 
 	return nil
 }
@@ -178,7 +228,7 @@ func (net UbuntuNetManager) writeNetConfigs(
 func (net UbuntuNetManager) GetConfiguredNetworkInterfaces() ([]string, error) {
 	interfaces := []string{}
 
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return interfaces, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -208,7 +258,7 @@ func (net UbuntuNetManager) removeDhcpDNSConfiguration() error {
 		net.logger.Error(UbuntuNetManagerLogTag, "Ignoring failure calling 'pkill dhclient': %s", err)
 	}
 
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return err
 	}
@@ -227,7 +277,7 @@ func (net UbuntuNetManager) removeDhcpDNSConfiguration() error {
 }
 
 func (net UbuntuNetManager) buildInterfaces(networks boshsettings.Networks) ([]StaticInterfaceConfiguration, []DHCPInterfaceConfiguration, error) {
-	interfacesByMacAddress, err := net.detectMacAddresses()
+	interfacesByMacAddress, err := detectMacAddresses(net.fs)
 	if err != nil {
 		return nil, nil, bosherr.WrapError(err, "Getting network interfaces")
 	}
@@ -244,28 +294,6 @@ func (net UbuntuNetManager) buildInterfaces(networks boshsettings.Networks) ([]S
 	return staticConfigs, dhcpConfigs, nil
 }
 
-func (net UbuntuNetManager) ifaceAddresses(staticConfigs []StaticInterfaceConfiguration, dhcpConfigs []DHCPInterfaceConfiguration) ([]boship.InterfaceAddress, []boship.InterfaceAddress) {
-	staticAddresses := []boship.InterfaceAddress{}
-	for _, iface := range staticConfigs {
-		staticAddresses = append(staticAddresses, boship.NewSimpleInterfaceAddress(iface.Name, iface.Address))
-	}
-	dynamicAddresses := []boship.InterfaceAddress{}
-	for _, iface := range dhcpConfigs {
-		dynamicAddresses = append(dynamicAddresses, boship.NewResolvingInterfaceAddress(iface.Name, net.ipResolver))
-	}
-
-	return staticAddresses, dynamicAddresses
-}
-
-func (net UbuntuNetManager) broadcastIps(addresses []boship.InterfaceAddress, errCh chan error) {
-	go func() {
-		net.addressBroadcaster.BroadcastMACAddresses(addresses)
-		if errCh != nil {
-			errCh <- nil
-		}
-	}()
-}
-
 func (net UbuntuNetManager) stopNetworkingInterfaces(dhcpConfigs []DHCPInterfaceConfiguration, staticConfigs []StaticInterfaceConfiguration) {
 	net.logger.Debug(UbuntuNetManagerLogTag, "Stopping network interfaces")
 
@@ -314,6 +342,7 @@ type networkInterfaceConfig struct {
 	DNSServers        []string
 	StaticConfigs     StaticInterfaceConfigurations
 	DHCPConfigs       DHCPInterfaceConfigurations
+	MemberConfigs     []MemberInterfaceConfiguration
 	HasDNSNameServers bool
 }
 
@@ -333,6 +362,7 @@ func (net UbuntuNetManager) writeNetworkInterfaces(
 	networkInterfaceValues := networkInterfaceConfig{
 		DHCPConfigs:       dhcpConfigs,
 		StaticConfigs:     staticConfigs,
+		MemberConfigs:     MemberInterfaceConfigurations(staticConfigs, dhcpConfigs),
 		HasDNSNameServers: true,
 		DNSServers:        dnsServers,
 	}
@@ -340,6 +370,7 @@ func (net UbuntuNetManager) writeNetworkInterfaces(
 	buffer := bytes.NewBuffer([]byte{})
 
 	t := template.Must(template.New("network-interfaces").Parse(networkInterfacesTemplate))
+	t = template.Must(t.Parse(networkInterfacesVirtualInterfaceTemplate))
 
 	err := t.Execute(buffer, networkInterfaceValues)
 	if err != nil {
@@ -357,51 +388,51 @@ func (net UbuntuNetManager) writeNetworkInterfaces(
 const networkInterfacesTemplate = `# Generated by bosh-agent
 auto lo
 iface lo inet loopback
-{{ range .DHCPConfigs }}
+{{ range .MemberConfigs }}{{ if .IsBond }}
+auto {{ .Name }}
+iface {{ .Name }} inet manual
+    bond-master {{ .Master }}
+{{ end }}{{ end }}{{ range .DHCPConfigs }}
 auto {{ .Name }}
-iface {{ .Name }} inet dhcp
-{{ end }}{{ range .StaticConfigs }}
+iface {{ .Name }} inet dhcp{{ template "virtual-interface" .VirtualInterface }}
+{{ end }}{{ range $iface := .StaticConfigs }}
 auto {{ .Name }}
-iface {{ .Name }} inet{{ .Version6 }} static
+iface {{ .Name }} inet{{ .Version6 }} static{{ template "virtual-interface" .VirtualInterface }}
     address {{ .Address }}{{ if not .IsVersion6 }}
     network {{ .Network }}{{ end }}
     netmask {{ .NetmaskOrLen }}{{ if .IsDefaultForGateway }}{{ if not .IsVersion6 }}
     broadcast {{ .Broadcast }}{{ end }}
-    gateway {{ .Gateway }}{{ end }}
+    gateway {{ .Gateway }}{{ end }}{{ if .MTU }}
+    mtu {{ .MTU }}{{ end }}{{ range .SecondaryAddresses }}
+    post-up ip{{ if .IsVersion6 }} -6{{ end }} addr add {{ .CIDR }} dev {{ $iface.Name }}
+    pre-down ip{{ if .IsVersion6 }} -6{{ end }} addr del {{ .CIDR }} dev {{ $iface.Name }}{{ end }}{{ range .Routes }}
+    post-up ip{{ if .IsVersion6 }} -6{{ end }} route add {{ .To }} via {{ .Gateway }} dev {{ $iface.Name }}{{ end }}{{ if .RoutingTable }}
+    post-up ip{{ if .IsVersion6 }} -6{{ end }} route add {{ .NetworkCIDR }} dev {{ .Name }} src {{ .Address }} table {{ .RoutingTable }}{{ if .Gateway }}
+    post-up ip{{ if .IsVersion6 }} -6{{ end }} route add default via {{ .Gateway }} dev {{ .Name }} table {{ .RoutingTable }}{{ end }}{{ range .Routes }}
+    post-up ip{{ if .IsVersion6 }} -6{{ end }} route add {{ .To }} via {{ .Gateway }} dev {{ $iface.Name }} table {{ $iface.RoutingTable }}{{ end }}
+    post-up ip{{ if .IsVersion6 }} -6{{ end }} rule add from {{ .Address }} table {{ .RoutingTable }}
+    pre-down ip{{ if .IsVersion6 }} -6{{ end }} rule del from {{ .Address }} table {{ .RoutingTable }}{{ end }}
 {{ end }}{{ if .HasVersion6 }}
 accept_ra 1{{ end }}{{ if .DNSServers }}
 dns-nameservers{{ range .DNSServers }} {{ . }}{{ end }}{{ end }}`
 
-func (net UbuntuNetManager) detectMacAddresses() (map[string]string, error) {
-	addresses := map[string]string{}
-
-	filePaths, err := net.fs.Glob("/sys/class/net/*")
-	if err != nil {
-		return addresses, bosherr.WrapError(err, "Getting file list from /sys/class/net")
-	}
-
-	var macAddress string
-	for _, filePath := range filePaths {
-		isPhysicalDevice := net.fs.FileExists(path.Join(filePath, "device"))
-
-		if isPhysicalDevice {
-			macAddress, err = net.fs.ReadFileString(path.Join(filePath, "address"))
-			if err != nil {
-				return addresses, bosherr.WrapError(err, "Reading mac address from file")
-			}
-
-			macAddress = strings.Trim(macAddress, "\n")
-
-			interfaceName := path.Base(filePath)
-			addresses[macAddress] = interfaceName
-		}
-	}
-
-	return addresses, nil
-}
+const networkInterfacesVirtualInterfaceTemplate = `{{ define "virtual-interface" }}{{ if .IsVLAN }}
+    vlan-raw-device {{ .Parent }}{{ else if .IsBond }}
+    bond-slaves none
+    bond-mode {{ .BondMode }}
+    bond-miimon 100{{ else if .IsBridge }}
+    bridge_ports{{ range .Members }} {{ . }}{{ end }}
+    bridge_stp off
+    bridge_fd {{ .ForwardDelaySec }}{{ end }}{{ end }}`
 
+// Bond members come first since bond is not usable until they are enslaved
 func (net UbuntuNetManager) ifaceNames(dhcpConfigs DHCPInterfaceConfigurations, staticConfigs StaticInterfaceConfigurations) []string {
 	ifaceNames := []string{}
+	for _, config := range MemberInterfaceConfigurations(staticConfigs, dhcpConfigs) {
+		if config.IsBond() {
+			ifaceNames = append(ifaceNames, config.Name)
+		}
+	}
 	for _, config := range dhcpConfigs {
 		ifaceNames = append(ifaceNames, config.Name)
 	}
@@ -462,6 +462,232 @@ dns-nameservers 8.8.8.8`))
 
 		})
 
+		It("renders mtu, static routes and policy routing table for static networks", func() {
+			staticNetwork = boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Netmask: "255.255.255.0",
+				Gateway: "1.2.3.1",
+				Mac:     "fake-static-mac-address",
+				MTU:     9000,
+				Routes: boshsettings.Routes{
+					{Destination: "10.10.0.0", Netmask: "255.255.0.0", Gateway: "1.2.3.254"},
+				},
+				RoutingTable: 100,
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": staticNetwork,
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"static-1": staticNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto eth0
+iface eth0 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    mtu 9000
+    post-up ip route add 10.10.0.0/16 via 1.2.3.254 dev eth0
+    post-up ip route add 1.2.3.0/24 dev eth0 src 1.2.3.4 table 100
+    post-up ip route add default via 1.2.3.1 dev eth0 table 100
+    post-up ip route add 10.10.0.0/16 via 1.2.3.254 dev eth0 table 100
+    post-up ip rule add from 1.2.3.4 table 100
+    pre-down ip rule del from 1.2.3.4 table 100
+`))
+		})
+
+		It("adds secondary addresses and default route of the other address family for dual-stack interface", func() {
+			ipv4Network := boshsettings.Network{
+				Type:               "manual",
+				IP:                 "1.2.3.4",
+				Netmask:            "255.255.255.0",
+				Gateway:            "1.2.3.1",
+				Default:            []string{"gateway"},
+				Mac:                "fake-static-mac-address",
+				SecondaryAddresses: []boshsettings.Address{{IP: "1.2.3.5", Netmask: "255.255.255.0"}},
+			}
+			ipv6Network := boshsettings.Network{
+				Type:    "manual",
+				IP:      "2001:db8::5",
+				Netmask: "ffff:ffff:ffff:ffff::",
+				Gateway: "2001:db8::1",
+				Mac:     "fake-static-mac-address",
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": ipv4Network,
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.5"),
+				boship.NewSimpleInterfaceAddress("eth0", "2001:db8::5"),
+			}
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"ipv4": ipv4Network, "ipv6": ipv6Network}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto eth0
+iface eth0 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+    broadcast 1.2.3.255
+    gateway 1.2.3.1
+    post-up ip addr add 1.2.3.5/24 dev eth0
+    pre-down ip addr del 1.2.3.5/24 dev eth0
+    post-up ip -6 addr add 2001:db8::5/64 dev eth0
+    pre-down ip -6 addr del 2001:db8::5/64 dev eth0
+    post-up ip -6 route add default via 2001:db8::1 dev eth0
+
+accept_ra 1`))
+
+			Expect(kernelIPv6.Enabled).To(BeTrue())
+		})
+
+		It("renders VLAN and bond interfaces and brings bond members up", func() {
+			untaggedNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "1.2.3.4",
+				Netmask: "255.255.255.0",
+				Mac:     "fake-mac-0",
+			}
+			vlanNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeVLAN,
+					Members: []string{"fake-mac-0"},
+					VLANID:  100,
+				},
+			}
+			bondNetwork := boshsettings.Network{
+				Type:    "manual",
+				IP:      "5.6.7.8",
+				Netmask: "255.255.255.0",
+				Gateway: "5.6.7.1",
+				Default: []string{"gateway"},
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:     boshsettings.VirtualInterfaceTypeBond,
+					Name:     "bond0",
+					Members:  []string{"fake-mac-1", "fake-mac-2"},
+					BondMode: "802.3ad",
+				},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": untaggedNetwork,
+				"eth1": {Mac: "fake-mac-1"},
+				"eth2": {Mac: "fake-mac-2"},
+			})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewSimpleInterfaceAddress("bond0", "5.6.7.8"),
+			}
+
+			errCh := make(chan error)
+			err := netManager.SetupNetworking(boshsettings.Networks{
+				"untagged": untaggedNetwork,
+				"vlan":     vlanNetwork,
+				"bond":     bondNetwork,
+			}, errCh)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(<-errCh).ToNot(HaveOccurred())
+			Expect(addressBroadcaster.BroadcastMACAddressesAddresses).To(Equal([]boship.InterfaceAddress{
+				boship.NewVirtualInterfaceAddress(boship.NewSimpleInterfaceAddress("bond0", "5.6.7.8")),
+				boship.NewSimpleInterfaceAddress("eth0", "1.2.3.4"),
+				boship.NewVirtualInterfaceAddress(boship.NewResolvingInterfaceAddress("eth0.100", ipResolver)),
+			}))
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto eth1
+iface eth1 inet manual
+    bond-master bond0
+
+auto eth2
+iface eth2 inet manual
+    bond-master bond0
+
+auto eth0.100
+iface eth0.100 inet dhcp
+    vlan-raw-device eth0
+
+auto bond0
+iface bond0 inet static
+    bond-slaves none
+    bond-mode 802.3ad
+    bond-miimon 100
+    address 5.6.7.8
+    network 5.6.7.0
+    netmask 255.255.255.0
+    broadcast 5.6.7.255
+    gateway 5.6.7.1
+
+auto eth0
+iface eth0 inet static
+    address 1.2.3.4
+    network 1.2.3.0
+    netmask 255.255.255.0
+`))
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ifup", "--force", "eth1", "eth2", "eth0.100", "bond0", "eth0"}))
+		})
+
+		It("renders bridge interfaces", func() {
+			bridgeNetwork := boshsettings.Network{
+				Type: "dynamic",
+				VirtualInterface: boshsettings.VirtualInterface{
+					Type:    boshsettings.VirtualInterfaceTypeBridge,
+					Name:    "br0",
+					Members: []string{"fake-mac-0"},
+				},
+			}
+
+			stubInterfaces(map[string]boshsettings.Network{
+				"eth0": {Mac: "fake-mac-0"},
+			})
+
+			err := netManager.SetupNetworking(boshsettings.Networks{"bridge": bridgeNetwork}, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			networkConfig := fs.GetFileTestStat("/etc/network/interfaces")
+			Expect(networkConfig).ToNot(BeNil())
+			Expect(networkConfig.StringContents()).To(Equal(`# Generated by bosh-agent
+auto lo
+iface lo inet loopback
+
+auto br0
+iface br0 inet dhcp
+    bridge_ports eth0
+    bridge_stp off
+    bridge_fd 0
+`))
+		})
+
 		It("writes /etc/network/interfaces without dns-namservers if there are no dns servers", func() {
 			staticNetworkWithoutDNS := boshsettings.Network{
 				Type:    "manual",
@@ -906,6 +1132,118 @@ iface ethstatic inet static
 		})
 	})
 
+	Describe("DetectDrift", func() {
+		var networks boshsettings.Networks
+
+		BeforeEach(func() {
+			networks = boshsettings.Networks{
+				"manual": boshsettings.Network{
+					Type:    "manual",
+					IP:      "1.2.3.4",
+					Default: []string{"dns", "gateway"},
+					DNS:     []string{"8.8.8.8"},
+					Netmask: "255.255.255.0",
+					Gateway: "3.4.5.6",
+					Mac:     "fake-static-mac-address",
+				},
+			}
+			stubInterfaces(map[string]boshsettings.Network{"ethstatic": networks["manual"]})
+
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{
+				boship.NewSimpleInterfaceAddress("ethstatic", "1.2.3.4"),
+			}
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 8.8.8.8\n")
+
+			err := netManager.SetupNetworking(networks, nil)
+			Expect(err).ToNot(HaveOccurred())
+
+			cmdRunner.RunCommands = [][]string{}
+		})
+
+		It("does not detect drift right after networking was set up", func() {
+			drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.Detected()).To(BeFalse())
+		})
+
+		It("detects modified /etc/network/interfaces without rewriting it", func() {
+			fs.WriteFileString("/etc/network/interfaces", "modified")
+
+			drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeTrue())
+
+			Expect(fs.GetFileTestStat("/etc/network/interfaces").StringContents()).To(Equal("modified"))
+			Expect(cmdRunner.RunCommands).To(BeEmpty())
+		})
+
+		It("detects dropped addresses and rewritten /etc/resolv.conf", func() {
+			interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{}
+			fs.WriteFileString("/etc/resolv.conf", "nameserver 127.0.0.1\n")
+
+			drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+			Expect(err).ToNot(HaveOccurred())
+			Expect(drift.ConfigsChanged).To(BeFalse())
+			Expect(drift.AddressesErr).To(HaveOccurred())
+			Expect(drift.DNSErr).To(HaveOccurred())
+		})
+
+		Context("when networks are preconfigured", func() {
+			BeforeEach(func() {
+				networks = boshsettings.Networks{
+					"manual": boshsettings.Network{Preconfigured: true, DNS: []string{"8.8.8.8"}},
+				}
+				interfaceAddrsProvider.GetInterfaceAddresses = []boship.InterfaceAddress{}
+			})
+
+			It("ignores configuration files and addresses", func() {
+				fs.WriteFileString("/etc/network/interfaces", "modified")
+
+				drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(drift.Detected()).To(BeFalse())
+			})
+
+			It("detects rewritten /etc/resolv.conf", func() {
+				fs.WriteFileString("/etc/resolv.conf", "nameserver 127.0.0.1\n")
+
+				drift, err := netManager.DetectDrift(networks, boship.NewInterfaceAddressesValidator(interfaceAddrsProvider))
+				Expect(err).ToNot(HaveOccurred())
+				Expect(drift.ConfigsChanged).To(BeFalse())
+				Expect(drift.AddressesErr).ToNot(HaveOccurred())
+				Expect(drift.DNSErr).To(HaveOccurred())
+			})
+		})
+	})
+
+	Describe("RepairNetworking", func() {
+		It("restarts interfaces even if configuration did not change", func() {
+			networks := boshsettings.Networks{
+				"manual": boshsettings.Network{
+					Type:    "manual",
+					IP:      "1.2.3.4",
+					Default: []string{"dns", "gateway"},
+					DNS:     []string{"8.8.8.8"},
+					Netmask: "255.255.255.0",
+					Gateway: "3.4.5.6",
+					Mac:     "fake-static-mac-address",
+				},
+			}
+			stubInterfaces(map[string]boshsettings.Network{"ethstatic": networks["manual"]})
+
+			err := netManager.RepairNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			cmdRunner.RunCommands = [][]string{}
+
+			err = netManager.RepairNetworking(networks)
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ifdown", "--force", "ethstatic"}))
+			Expect(cmdRunner.RunCommands).To(ContainElement([]string{"ifup", "--force", "ethstatic"}))
+		})
+	})
+
 	Describe("GetConfiguredNetworkInterfaces", func() {
 		Context("when there are network devices", func() {
 			BeforeEach(func() {
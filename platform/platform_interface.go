@@ -6,6 +6,9 @@ import (
 	"log"
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
+	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdir "github.com/cloudfoundry/bosh-agent/settings/directories"
@@ -31,6 +34,8 @@ type Platform interface {
 	GetCopier() boshcmd.Copier
 	GetDirProvider() boshdir.Provider
 	GetVitalsService() boshvitals.Service
+	GetManagedDisks() boshsettings.ManagedDisks
+	GetFilesystemCheckHistory() boshdisk.FilesystemCheckHistory
 	GetAuditLogger() AuditLogger
 	GetDevicePathResolver() (devicePathResolver boshdpresolv.DevicePathResolver)
 
@@ -46,10 +51,14 @@ type Platform interface {
 	SetupIPv6(boshsettings.IPv6) error
 	SetupHostname(hostname string) (err error)
 	SetupNetworking(networks boshsettings.Networks) (err error)
+	DetectNetworkDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (boshnet.Drift, error)
+	RepairNetworking(networks boshsettings.Networks) (err error)
 	SetupLogrotate(groupName, basePath, size string) (err error)
 	SetTimeWithNtpServers(servers []string) (err error)
-	SetupEphemeralDiskWithPath(devicePath string, desiredSwapSizeInBytes *uint64) (err error)
+	SetupEphemeralDiskWithPath(devicePath string, desiredSwapSizeInBytes *uint64, mountOptions ...string) (err error)
 	SetupRawEphemeralDisks(devices []boshsettings.DiskSettings) (err error)
+	SetupPooledEphemeralDisk(ephemeralDiskPath string, rawDevices []boshsettings.DiskSettings, pool boshsettings.EphemeralDiskPool, mountOptions ...string) (err error)
+	SetupSwap(swap boshsettings.Swap) (err error)
 	SetupDataDir() (err error)
 	SetupTmpDir() (err error)
 	SetupHomeDir() (err error)
@@ -60,11 +69,15 @@ type Platform interface {
 	SetupLogDir() (err error)
 	SetupLoggingAndAuditing() (err error)
 	SetupRecordsJSONPermission(path string) error
+	StartDiskTrimming(stopCh chan struct{})
 
 	// Disk management
 	MountPersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) error
 	UnmountPersistentDisk(diskSettings boshsettings.DiskSettings) (didUnmount bool, err error)
-	MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error)
+	MigratePersistentDisk(fromMountPoint, toMountPoint string, progress func(copiedBytes, totalBytes uint64)) (err error)
+	ResizePersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) (err error)
+	FreezePersistentDisk(mountPoint string) (err error)
+	ThawPersistentDisk(mountPoint string) (err error)
 	GetEphemeralDiskPath(diskSettings boshsettings.DiskSettings) string
 	IsMountPoint(path string) (partitionPath string, result bool, err error)
 	IsPersistentDiskMounted(diskSettings boshsettings.DiskSettings) (result bool, err error)
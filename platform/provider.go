@@ -15,6 +15,7 @@ import (
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshudev "github.com/cloudfoundry/bosh-agent/platform/udevdevice"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	bosherror "github.com/cloudfoundry/bosh-utils/errors"
 	boshcmd "github.com/cloudfoundry/bosh-utils/fileutil"
@@ -30,6 +31,11 @@ const (
 	ArpInterfaceCheckDelay = 100 * time.Millisecond
 )
 
+const (
+	InterfaceAddressesValidationTimeout = 2 * time.Minute
+	InterfaceAddressesValidationDelay   = 1 * time.Second
+)
+
 const (
 	SigarStatsCollectionInterval = 10 * time.Second
 )
@@ -67,7 +73,10 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 	// Kick of stats collection as soon as possible
 	statsCollector.StartCollecting(SigarStatsCollectionInterval, nil)
 
-	vitalsService := boshvitals.NewService(statsCollector, dirProvider)
+	managedDisks := boshsettings.NewManagedDisks(fs, dirProvider.BoshDir())
+	diskTrimScheduler := boshdisk.NewTrimScheduler(linuxDiskManager.GetTrimmer(), linuxDiskManager.GetMounter(), clock, logger)
+
+	vitalsService := boshvitals.NewService(statsCollector, dirProvider, managedDisks, diskTrimScheduler, logger)
 
 	ipResolver := boship.NewResolver(boship.NetworkInterfaceToAddrsFunc)
 
@@ -75,12 +84,26 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 	interfaceConfigurationCreator := boshnet.NewInterfaceConfigurationCreator(logger)
 
 	interfaceAddressesProvider := boship.NewSystemInterfaceAddressesProvider()
-	interfaceAddressesValidator := boship.NewInterfaceAddressesValidator(interfaceAddressesProvider)
+	interfaceAddressesValidator := boship.NewWaitingInterfaceAddressesValidator(
+		boship.NewInterfaceAddressesValidator(interfaceAddressesProvider),
+		InterfaceAddressesValidationTimeout,
+		InterfaceAddressesValidationDelay,
+		clock,
+		logger,
+	)
 	dnsValidator := boshnet.NewDNSValidator(fs)
 	kernelIPv6 := boshnet.NewKernelIPv6Impl(fs, runner, logger)
 
 	centosNetManager := boshnet.NewCentosNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, dnsValidator, arping, logger)
-	ubuntuNetManager := boshnet.NewUbuntuNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, dnsValidator, arping, kernelIPv6, logger)
+
+	var ubuntuNetManager boshnet.Manager
+	switch options.Linux.NetManagerType {
+	case string(boshnet.NetworkdBackendNetplan), string(boshnet.NetworkdBackendNetworkd):
+		ubuntuNetManager = boshnet.NewNetworkdNetManager(boshnet.NetworkdBackend(options.Linux.NetManagerType), fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, boshnet.NewResolvedDNSValidator(fs), arping, kernelIPv6, logger)
+	default:
+		ubuntuNetManager = boshnet.NewUbuntuNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, dnsValidator, arping, kernelIPv6, logger)
+	}
+
 	opensuseNetManager := boshnet.NewOpensuseNetManager(fs, runner, ipResolver, interfaceConfigurationCreator, interfaceAddressesValidator, dnsValidator, arping, logger)
 
 	windowsNetManager := boshnet.NewWindowsNetManager(
@@ -135,6 +158,7 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 			vitalsService,
 			linuxCdutil,
 			linuxDiskManager,
+			diskTrimScheduler,
 			centosNetManager,
 			centosCertManager,
 			monitRetryStrategy,
@@ -159,6 +183,7 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 			vitalsService,
 			linuxCdutil,
 			linuxDiskManager,
+			diskTrimScheduler,
 			ubuntuNetManager,
 			ubuntuCertManager,
 			monitRetryStrategy,
@@ -211,6 +236,7 @@ func NewProvider(logger boshlog.Logger, dirProvider boshdirs.Provider, statsColl
 			vitalsService,
 			linuxCdutil,
 			linuxDiskManager,
+			diskTrimScheduler,
 			opensuseNetManager,
 			opensuseCertManager,
 			monitRetryStrategy,
@@ -42,3 +42,11 @@ func (p dummyStatsCollector) GetUptimeStats() (stats UptimeStats, err error) {
 	stats.Secs = 5
 	return
 }
+
+func (p dummyStatsCollector) GetDiskIOStats() (stats map[string]DiskIOStats, err error) {
+	return map[string]DiskIOStats{}, nil
+}
+
+func (p dummyStatsCollector) GetNetworkStats() (stats map[string]NetworkStats, err error) {
+	return map[string]NetworkStats{}, nil
+}
@@ -20,6 +20,12 @@ type FakeCollector struct {
 	DiskStats map[string]boshstats.DiskStats
 
 	UptimeStats boshstats.UptimeStats
+
+	DiskIOStats    map[string]boshstats.DiskIOStats
+	DiskIOStatsErr error
+
+	NetworkStats    map[string]boshstats.NetworkStats
+	NetworkStatsErr error
 }
 
 func (c *FakeCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
@@ -57,3 +63,11 @@ func (c *FakeCollector) GetUptimeStats() (stats boshstats.UptimeStats, err error
 	stats = c.UptimeStats
 	return
 }
+
+func (c *FakeCollector) GetDiskIOStats() (map[string]boshstats.DiskIOStats, error) {
+	return c.DiskIOStats, c.DiskIOStatsErr
+}
+
+func (c *FakeCollector) GetNetworkStats() (map[string]boshstats.NetworkStats, error) {
+	return c.NetworkStats, c.NetworkStatsErr
+}
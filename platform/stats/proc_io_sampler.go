@@ -0,0 +1,280 @@
+package stats
+
+import (
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const (
+	procDiskStatsPath = "/proc/diskstats"
+	procNetDevPath    = "/proc/net/dev"
+	sysBlockPath      = "/sys/block"
+
+	// /proc/diskstats always counts 512-byte sectors regardless of device sector size
+	diskStatsSectorSize = 512
+)
+
+// ProcIOSampler calculates disk and network throughput
+// from the difference between two consecutive samples
+// of /proc/diskstats and /proc/net/dev
+type ProcIOSampler struct {
+	fs boshsys.FileSystem
+
+	prevSampledAt time.Time
+	prevDisks     map[string]diskCounters
+	prevNetworks  map[string]networkCounters
+
+	latestDisks    map[string]DiskIOStats
+	latestNetworks map[string]NetworkStats
+	latestLock     sync.RWMutex
+}
+
+type diskCounters struct {
+	reads        uint64
+	sectorsRead  uint64
+	writes       uint64
+	sectorsWrite uint64
+	ioMillis     uint64
+}
+
+type networkCounters struct {
+	rxBytes   uint64
+	rxPackets uint64
+	rxErrors  uint64
+	rxDropped uint64
+	txBytes   uint64
+	txPackets uint64
+	txErrors  uint64
+	txDropped uint64
+}
+
+func NewProcIOSampler(fs boshsys.FileSystem) *ProcIOSampler {
+	return &ProcIOSampler{fs: fs}
+}
+
+// Sample is expected to be called periodically; throughput becomes
+// available after the second successful sample
+func (s *ProcIOSampler) Sample(sampledAt time.Time) error {
+	disks, err := s.readDiskCounters()
+	if err != nil {
+		return err
+	}
+
+	networks, err := s.readNetworkCounters()
+	if err != nil {
+		return err
+	}
+
+	if s.prevDisks != nil {
+		seconds := sampledAt.Sub(s.prevSampledAt).Seconds()
+
+		if seconds > 0 {
+			s.latestLock.Lock()
+			s.latestDisks = diskIORates(s.prevDisks, disks, seconds)
+			s.latestNetworks = networkRates(s.prevNetworks, networks, seconds)
+			s.latestLock.Unlock()
+		}
+	}
+
+	s.prevSampledAt = sampledAt
+	s.prevDisks = disks
+	s.prevNetworks = networks
+
+	return nil
+}
+
+func (s *ProcIOSampler) DiskIOStats() (map[string]DiskIOStats, error) {
+	s.latestLock.RLock()
+	defer s.latestLock.RUnlock()
+
+	if s.latestDisks == nil {
+		return nil, bosherr.Error("Disk I/O has not been sampled yet")
+	}
+
+	return s.latestDisks, nil
+}
+
+func (s *ProcIOSampler) NetworkStats() (map[string]NetworkStats, error) {
+	s.latestLock.RLock()
+	defer s.latestLock.RUnlock()
+
+	if s.latestNetworks == nil {
+		return nil, bosherr.Error("Network I/O has not been sampled yet")
+	}
+
+	return s.latestNetworks, nil
+}
+
+func (s *ProcIOSampler) readDiskCounters() (map[string]diskCounters, error) {
+	contents, err := s.fs.ReadFileString(procDiskStatsPath)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading %s", procDiskStatsPath)
+	}
+
+	disks := map[string]diskCounters{}
+
+	// major minor name reads merged sectors ms writes merged sectors ms in-flight io_ms weighted_ms ...
+	for _, line := range strings.Split(contents, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 14 {
+			continue
+		}
+
+		name := fields[2]
+		if !s.isWholeDisk(name) {
+			continue
+		}
+
+		values, err := parseCounters(fields[3:14])
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing %s stats for %s", procDiskStatsPath, name)
+		}
+
+		disks[name] = diskCounters{
+			reads:        values[0],
+			sectorsRead:  values[2],
+			writes:       values[4],
+			sectorsWrite: values[6],
+			ioMillis:     values[9],
+		}
+	}
+
+	return disks, nil
+}
+
+// isWholeDisk skips partitions and device mapper volumes whose I/O
+// is already counted by disks underneath them, as well as memory backed devices.
+// Only whole disks are listed in /sys/block next to dm and loop devices.
+func (s *ProcIOSampler) isWholeDisk(name string) bool {
+	for _, prefix := range []string{"loop", "ram", "dm-"} {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+
+	return s.fs.FileExists(path.Join(sysBlockPath, name))
+}
+
+func (s *ProcIOSampler) readNetworkCounters() (map[string]networkCounters, error) {
+	contents, err := s.fs.ReadFileString(procNetDevPath)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading %s", procNetDevPath)
+	}
+
+	networks := map[string]networkCounters{}
+
+	// First two lines are headers; interface name is separated by colon
+	// which may not be followed by space when counters are large
+	for _, line := range strings.Split(contents, "\n") {
+		colonIdx := strings.Index(line, ":")
+		if colonIdx < 0 {
+			continue
+		}
+
+		name := strings.TrimSpace(line[:colonIdx])
+		if name == "lo" {
+			continue
+		}
+
+		fields := strings.Fields(line[colonIdx+1:])
+		if len(fields) < 12 {
+			continue
+		}
+
+		values, err := parseCounters(fields[:12])
+		if err != nil {
+			return nil, bosherr.WrapErrorf(err, "Parsing %s stats for %s", procNetDevPath, name)
+		}
+
+		networks[name] = networkCounters{
+			rxBytes:   values[0],
+			rxPackets: values[1],
+			rxErrors:  values[2],
+			rxDropped: values[3],
+			txBytes:   values[8],
+			txPackets: values[9],
+			txErrors:  values[10],
+			txDropped: values[11],
+		}
+	}
+
+	return networks, nil
+}
+
+func diskIORates(prev, current map[string]diskCounters, seconds float64) map[string]DiskIOStats {
+	rates := map[string]DiskIOStats{}
+
+	for name, curr := range current {
+		prevCounters, found := prev[name]
+		if !found {
+			continue
+		}
+
+		busyPercent := float64(delta(prevCounters.ioMillis, curr.ioMillis)) / (seconds * 1000) * 100
+		if busyPercent > 100 {
+			busyPercent = 100
+		}
+
+		rates[name] = DiskIOStats{
+			ReadsPerSecond:      float64(delta(prevCounters.reads, curr.reads)) / seconds,
+			WritesPerSecond:     float64(delta(prevCounters.writes, curr.writes)) / seconds,
+			ReadBytesPerSecond:  float64(delta(prevCounters.sectorsRead, curr.sectorsRead)*diskStatsSectorSize) / seconds,
+			WriteBytesPerSecond: float64(delta(prevCounters.sectorsWrite, curr.sectorsWrite)*diskStatsSectorSize) / seconds,
+			BusyPercent:         busyPercent,
+		}
+	}
+
+	return rates
+}
+
+func networkRates(prev, current map[string]networkCounters, seconds float64) map[string]NetworkStats {
+	rates := map[string]NetworkStats{}
+
+	for name, curr := range current {
+		prevCounters, found := prev[name]
+		if !found {
+			continue
+		}
+
+		rates[name] = NetworkStats{
+			RxBytesPerSecond:   float64(delta(prevCounters.rxBytes, curr.rxBytes)) / seconds,
+			RxPacketsPerSecond: float64(delta(prevCounters.rxPackets, curr.rxPackets)) / seconds,
+			TxBytesPerSecond:   float64(delta(prevCounters.txBytes, curr.txBytes)) / seconds,
+			TxPacketsPerSecond: float64(delta(prevCounters.txPackets, curr.txPackets)) / seconds,
+			RxErrors:           curr.rxErrors,
+			RxDropped:          curr.rxDropped,
+			TxErrors:           curr.txErrors,
+			TxDropped:          curr.txDropped,
+		}
+	}
+
+	return rates
+}
+
+// delta treats counter wrap around or reset as no activity
+func delta(prev, curr uint64) uint64 {
+	if curr < prev {
+		return 0
+	}
+	return curr - prev
+}
+
+func parseCounters(fields []string) ([]uint64, error) {
+	values := make([]uint64, len(fields))
+
+	for i, field := range fields {
+		value, err := strconv.ParseUint(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = value
+	}
+
+	return values, nil
+}
@@ -0,0 +1,161 @@
+package stats_test
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/platform/stats"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+const firstDiskStats = `   7       0 loop0 100 0 200 0 0 0 0 0 0 0 0
+   8       0 sda 1000 10 20000 300 500 20 8000 400 0 1000 700
+   8       1 sda1 900 10 18000 250 400 20 6000 300 0 900 550
+ 252       0 dm-0 900 0 18000 250 400 0 6000 300 0 900 550
+`
+
+const secondDiskStats = `   7       0 loop0 500 0 900 0 0 0 0 0 0 0 0
+   8       0 sda 1100 10 24000 310 700 20 12000 420 0 1500 730
+   8       1 sda1 950 10 20000 255 400 20 6000 300 0 3000 560
+ 252       0 dm-0 950 0 20000 255 400 0 6000 300 0 3000 560
+   8      16 sdb 10 0 80 1 0 0 0 0 0 5 1
+`
+
+const firstNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 5000 50 0 0 0 0 0 0 5000 50 0 0 0 0 0 0
+  eth0: 10000 100 1 2 0 0 0 0 20000 200 3 4 0 0 0 0
+`
+
+const secondNetDev = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+    lo: 9000 90 0 0 0 0 0 0 9000 90 0 0 0 0 0 0
+  eth0:30000 140 1 5 0 0 0 0 21000 210 3 4 0 0 0 0
+`
+
+var _ = Describe("ProcIOSampler", func() {
+	var (
+		fs      *fakesys.FakeFileSystem
+		sampler *ProcIOSampler
+		start   time.Time
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		sampler = NewProcIOSampler(fs)
+		start = time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		// Partitions are not listed in /sys/block
+		for _, name := range []string{"loop0", "sda", "sdb", "dm-0"} {
+			err := fs.MkdirAll("/sys/block/"+name, 0755)
+			Expect(err).ToNot(HaveOccurred())
+		}
+
+		err := fs.WriteFileString("/proc/diskstats", firstDiskStats)
+		Expect(err).ToNot(HaveOccurred())
+
+		err = fs.WriteFileString("/proc/net/dev", firstNetDev)
+		Expect(err).ToNot(HaveOccurred())
+	})
+
+	It("returns errors until two samples have been taken", func() {
+		_, err := sampler.DiskIOStats()
+		Expect(err).To(HaveOccurred())
+
+		_, err = sampler.NetworkStats()
+		Expect(err).To(HaveOccurred())
+
+		err = sampler.Sample(start)
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = sampler.DiskIOStats()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Disk I/O has not been sampled yet"))
+
+		_, err = sampler.NetworkStats()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Network I/O has not been sampled yet"))
+	})
+
+	Context("when sampled twice", func() {
+		BeforeEach(func() {
+			err := sampler.Sample(start)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString("/proc/diskstats", secondDiskStats)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString("/proc/net/dev", secondNetDev)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = sampler.Sample(start.Add(2 * time.Second))
+			Expect(err).ToNot(HaveOccurred())
+		})
+
+		It("calculates disk throughput per second of whole disks, skipping partitions, dm, loop and new devices", func() {
+			stats, err := sampler.DiskIOStats()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(stats).To(Equal(map[string]DiskIOStats{
+				"sda": DiskIOStats{
+					ReadsPerSecond:      50,
+					WritesPerSecond:     100,
+					ReadBytesPerSecond:  1024000,
+					WriteBytesPerSecond: 1024000,
+					BusyPercent:         25,
+				},
+			}))
+		})
+
+		It("calculates network throughput per second, skipping loopback", func() {
+			stats, err := sampler.NetworkStats()
+			Expect(err).ToNot(HaveOccurred())
+
+			Expect(stats).To(Equal(map[string]NetworkStats{
+				"eth0": NetworkStats{
+					RxBytesPerSecond:   10000,
+					RxPacketsPerSecond: 20,
+					RxErrors:           1,
+					RxDropped:          5,
+					TxBytesPerSecond:   500,
+					TxPacketsPerSecond: 5,
+					TxErrors:           3,
+					TxDropped:          4,
+				},
+			}))
+		})
+
+		It("treats counters that went backwards as no activity", func() {
+			err := fs.WriteFileString("/proc/diskstats", firstDiskStats)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = sampler.Sample(start.Add(4 * time.Second))
+			Expect(err).ToNot(HaveOccurred())
+
+			stats, err := sampler.DiskIOStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(stats["sda"]).To(Equal(DiskIOStats{}))
+		})
+	})
+
+	It("returns error when reading /proc/diskstats fails", func() {
+		fs.RegisterReadFileError("/proc/diskstats", errors.New("fake-read-err"))
+
+		err := sampler.Sample(start)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Reading /proc/diskstats"))
+		Expect(err.Error()).To(ContainSubstring("fake-read-err"))
+	})
+
+	It("returns error when /proc/net/dev contains malformed counters", func() {
+		err := fs.WriteFileString("/proc/net/dev", "  eth0: 1 2 3 4 5 6 7 8 nan 10 11 12 13 14 15 16\n")
+		Expect(err).ToNot(HaveOccurred())
+
+		err = sampler.Sample(start)
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Parsing /proc/net/dev stats for eth0"))
+	})
+})
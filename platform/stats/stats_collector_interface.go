@@ -30,6 +30,31 @@ type UptimeStats struct {
 	Secs uint64
 }
 
+// DiskIOStats is throughput of a block device averaged over the last sampling interval
+type DiskIOStats struct {
+	ReadsPerSecond      float64
+	WritesPerSecond     float64
+	ReadBytesPerSecond  float64
+	WriteBytesPerSecond float64
+
+	// Percent of time device was busy doing I/O
+	BusyPercent float64
+}
+
+// NetworkStats is throughput of a network interface averaged over the last
+// sampling interval; errors and dropped packets are totals since boot
+type NetworkStats struct {
+	RxBytesPerSecond   float64
+	RxPacketsPerSecond float64
+	TxBytesPerSecond   float64
+	TxPacketsPerSecond float64
+
+	RxErrors  uint64
+	RxDropped uint64
+	TxErrors  uint64
+	TxDropped uint64
+}
+
 type Collector interface {
 	StartCollecting(time.Duration, chan struct{})
 
@@ -41,6 +66,11 @@ type Collector interface {
 	GetDiskStats(mountedPath string) (stats DiskStats, err error)
 
 	GetUptimeStats() (stats UptimeStats, err error)
+
+	// Keyed by device/interface name; available only once collecting
+	// has been running for at least one collection interval
+	GetDiskIOStats() (stats map[string]DiskIOStats, err error)
+	GetNetworkStats() (stats map[string]NetworkStats, err error)
 }
 
 func (cpuStats CPUStats) UserPercent() Percentage {
@@ -2,27 +2,51 @@ package vitals
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/cloudfoundry/gosigar"
 
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
 )
 
 type Service interface {
 	Get() (vitals Vitals, err error)
 }
 
+// DiskTrimResults are keyed by mount point
+type DiskTrimResults interface {
+	Results() map[string]boshdisk.TrimResult
+}
+
 type concreteService struct {
-	statsCollector boshstats.Collector
-	dirProvider    boshdirs.Provider
+	statsCollector  boshstats.Collector
+	dirProvider     boshdirs.Provider
+	managedDisks    boshsettings.ManagedDisks
+	diskTrimResults DiskTrimResults
+	logTag          string
+	logger          boshlog.Logger
 }
 
-func NewService(statsCollector boshstats.Collector, dirProvider boshdirs.Provider) Service {
+// NewService accepts nil diskTrimResults on platforms that do not trim disks
+func NewService(
+	statsCollector boshstats.Collector,
+	dirProvider boshdirs.Provider,
+	managedDisks boshsettings.ManagedDisks,
+	diskTrimResults DiskTrimResults,
+	logger boshlog.Logger,
+) Service {
 	return concreteService{
-		statsCollector: statsCollector,
-		dirProvider:    dirProvider,
+		statsCollector:  statsCollector,
+		dirProvider:     dirProvider,
+		managedDisks:    managedDisks,
+		diskTrimResults: diskTrimResults,
+		logTag:          "vitalsService",
+		logger:          logger,
 	}
 }
 
@@ -60,7 +84,9 @@ func (s concreteService) Get() (vitals Vitals, err error) {
 		return
 	}
 
-	diskStats, err = s.getDiskStats()
+	disks := s.getDiskNames()
+
+	diskStats, err = s.getDiskStats(disks)
 	if err != nil {
 		err = bosherr.WrapError(err, "Getting Disk Stats")
 		return
@@ -84,15 +110,49 @@ func (s concreteService) Get() (vitals Vitals, err error) {
 		Disk:   diskStats,
 		Uptime: UptimeVitals{Secs: uptimeStats.Secs},
 	}
+
+	// Throughput is reported only after enough samples were collected
+	diskIOStats, diskIOErr := s.statsCollector.GetDiskIOStats()
+	if diskIOErr == nil {
+		vitals.DiskIO = createDiskIOVitals(diskIOStats)
+	}
+
+	networkStats, networkErr := s.statsCollector.GetNetworkStats()
+	if networkErr == nil {
+		vitals.Network = createNetworkVitals(networkStats)
+	}
+
+	if s.diskTrimResults != nil {
+		vitals.DiskTrim = createDiskTrimVitals(s.diskTrimResults.Results(), disks)
+	}
+
 	return
 }
 
-func (s concreteService) getDiskStats() (diskStats DiskVitals, err error) {
+// getDiskNames returns disk names keyed by mount point
+func (s concreteService) getDiskNames() map[string]string {
 	disks := map[string]string{
 		"/": "system",
 		s.dirProvider.DataDir():  "ephemeral",
 		s.dirProvider.StoreDir(): "persistent",
 	}
+
+	// Named persistent disks are reported next to the disk mounted at store directory;
+	// heartbeat is still sent without them when they cannot be listed
+	managedDisks, err := s.managedDisks.List()
+	if err != nil {
+		s.logger.Error(s.logTag, "Skipping named persistent disks: %s", err.Error())
+		return disks
+	}
+
+	for _, managedDisk := range managedDisks {
+		disks[managedDisk.MountPoint] = "persistent_" + managedDisk.Name
+	}
+
+	return disks
+}
+
+func (s concreteService) getDiskStats(disks map[string]string) (diskStats DiskVitals, err error) {
 	diskStats = make(DiskVitals, len(disks))
 
 	for path, name := range disks {
@@ -129,3 +189,62 @@ func createMemVitals(memUsage boshstats.Usage) MemoryVitals {
 		Kb:      fmt.Sprintf("%d", memUsage.Used/1024),
 	}
 }
+
+func createDiskIOVitals(diskIOStats map[string]boshstats.DiskIOStats) DiskIOVitals {
+	diskIO := make(DiskIOVitals, len(diskIOStats))
+
+	for name, stats := range diskIOStats {
+		diskIO[name] = SpecificDiskIOVitals{
+			ReadOpsPerSec:    fmt.Sprintf("%.1f", stats.ReadsPerSecond),
+			WriteOpsPerSec:   fmt.Sprintf("%.1f", stats.WritesPerSecond),
+			ReadBytesPerSec:  fmt.Sprintf("%.0f", stats.ReadBytesPerSecond),
+			WriteBytesPerSec: fmt.Sprintf("%.0f", stats.WriteBytesPerSecond),
+			BusyPercent:      fmt.Sprintf("%.1f", stats.BusyPercent),
+		}
+	}
+
+	return diskIO
+}
+
+func createDiskTrimVitals(trimResults map[string]boshdisk.TrimResult, disks map[string]string) DiskTrimVitals {
+	diskTrim := DiskTrimVitals{}
+
+	for mountPoint, result := range trimResults {
+		name, found := disks[mountPoint]
+		if !found {
+			continue
+		}
+
+		trimVitals := SpecificDiskTrimVitals{
+			LastTrimmedAt: result.TrimmedAt.UTC().Format(time.RFC3339),
+			TrimmedBytes:  fmt.Sprintf("%d", result.TrimmedBytes),
+		}
+
+		if result.Err != nil {
+			trimVitals.Error = result.Err.Error()
+		}
+
+		diskTrim[name] = trimVitals
+	}
+
+	return diskTrim
+}
+
+func createNetworkVitals(networkStats map[string]boshstats.NetworkStats) NetworkVitals {
+	network := make(NetworkVitals, len(networkStats))
+
+	for name, stats := range networkStats {
+		network[name] = SpecificNetworkVitals{
+			RxBytesPerSec:   fmt.Sprintf("%.0f", stats.RxBytesPerSecond),
+			RxPacketsPerSec: fmt.Sprintf("%.1f", stats.RxPacketsPerSecond),
+			RxErrors:        fmt.Sprintf("%d", stats.RxErrors),
+			RxDropped:       fmt.Sprintf("%d", stats.RxDropped),
+			TxBytesPerSec:   fmt.Sprintf("%.0f", stats.TxBytesPerSecond),
+			TxPacketsPerSec: fmt.Sprintf("%.1f", stats.TxPacketsPerSecond),
+			TxErrors:        fmt.Sprintf("%d", stats.TxErrors),
+			TxDropped:       fmt.Sprintf("%d", stats.TxDropped),
+		}
+	}
+
+	return network
+}
@@ -1,21 +1,32 @@
 package vitals_test
 
 import (
+	"errors"
 	"runtime"
 	"time"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
 
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	fakestats "github.com/cloudfoundry/bosh-agent/platform/stats/fakes"
 	. "github.com/cloudfoundry/bosh-agent/platform/vitals"
+	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
 	boshdirs "github.com/cloudfoundry/bosh-agent/settings/directories"
 	boshassert "github.com/cloudfoundry/bosh-utils/assert"
+	boshlog "github.com/cloudfoundry/bosh-utils/logger"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
 
 const Windows = runtime.GOOS == "windows"
 
+type fakeDiskTrimResults map[string]boshdisk.TrimResult
+
+func (r fakeDiskTrimResults) Results() map[string]boshdisk.TrimResult {
+	return r
+}
+
 func buildVitalsService() (statsCollector *fakestats.FakeCollector, service Service) {
 	dirProvider := boshdirs.NewProvider("/fake/base/dir")
 	statsCollector = &fakestats.FakeCollector{
@@ -57,7 +68,7 @@ func buildVitalsService() (statsCollector *fakestats.FakeCollector, service Serv
 		},
 	}
 
-	service = NewService(statsCollector, dirProvider)
+	service = NewService(statsCollector, dirProvider, boshsettings.NewManagedDisks(fakesys.NewFakeFileSystem(), dirProvider.BoshDir()), nil, boshlog.NewLogger(boshlog.LevelNone))
 	statsCollector.StartCollecting(1*time.Millisecond, nil)
 	return
 }
@@ -126,6 +137,145 @@ var _ = Describe("Vitals service", func() {
 		boshassert.LacksJSONKey(GinkgoT(), vitals.Disk, "ephemeral")
 		boshassert.LacksJSONKey(GinkgoT(), vitals.Disk, "persistent")
 	})
+
+	It("includes named persistent disks", func() {
+		statsCollector, _ := buildVitalsService()
+		statsCollector.DiskStats["/fake/base/dir/store/wal"] = boshstats.DiskStats{
+			DiskUsage:  boshstats.Usage{Used: 1, Total: 4},
+			InodeUsage: boshstats.Usage{Used: 1, Total: 2},
+		}
+
+		dirProvider := boshdirs.NewProvider("/fake/base/dir")
+		managedDisks := boshsettings.NewManagedDisks(fakesys.NewFakeFileSystem(), dirProvider.BoshDir())
+		err := managedDisks.Add(boshsettings.ManagedDisk{
+			DiskCID:    "fake-disk-cid",
+			Name:       "wal",
+			MountPoint: "/fake/base/dir/store/wal",
+		})
+		Expect(err).ToNot(HaveOccurred())
+
+		vitals, err := NewService(statsCollector, dirProvider, managedDisks, nil, boshlog.NewLogger(boshlog.LevelNone)).Get()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(vitals.Disk["persistent"]).To(Equal(SpecificDiskVitals{Percent: "100", InodePercent: "75"}))
+		Expect(vitals.Disk["persistent_wal"]).To(Equal(SpecificDiskVitals{Percent: "25", InodePercent: "50"}))
+	})
+
+	It("skips named persistent disks when they cannot be listed", func() {
+		statsCollector, _ := buildVitalsService()
+
+		dirProvider := boshdirs.NewProvider("/fake/base/dir")
+		fs := fakesys.NewFakeFileSystem()
+		managedDisks := boshsettings.NewManagedDisks(fs, dirProvider.BoshDir())
+		err := managedDisks.Add(boshsettings.ManagedDisk{
+			DiskCID:    "fake-disk-cid",
+			Name:       "wal",
+			MountPoint: "/fake/base/dir/store/wal",
+		})
+		Expect(err).ToNot(HaveOccurred())
+		fs.ReadFileError = errors.New("fake-read-err")
+
+		vitals, err := NewService(statsCollector, dirProvider, managedDisks, nil, boshlog.NewLogger(boshlog.LevelNone)).Get()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(vitals.Disk["persistent"]).To(Equal(SpecificDiskVitals{Percent: "100", InodePercent: "75"}))
+		Expect(vitals.Disk).ToNot(HaveKey("persistent_wal"))
+	})
+
+	It("includes results of disk trimming keyed by disk name", func() {
+		statsCollector, _ := buildVitalsService()
+		dirProvider := boshdirs.NewProvider("/fake/base/dir")
+		trimmedAt := time.Date(2017, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		trimResults := fakeDiskTrimResults{
+			dirProvider.DataDir(): boshdisk.TrimResult{TrimmedAt: trimmedAt, TrimmedBytes: 4096},
+			dirProvider.StoreDir(): boshdisk.TrimResult{
+				TrimmedAt: trimmedAt,
+				Err:       errors.New("fake-trim-err"),
+			},
+			"/unknown": boshdisk.TrimResult{TrimmedAt: trimmedAt},
+		}
+
+		managedDisks := boshsettings.NewManagedDisks(fakesys.NewFakeFileSystem(), dirProvider.BoshDir())
+
+		vitals, err := NewService(statsCollector, dirProvider, managedDisks, trimResults, boshlog.NewLogger(boshlog.LevelNone)).Get()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(vitals.DiskTrim).To(Equal(DiskTrimVitals{
+			"ephemeral": SpecificDiskTrimVitals{
+				LastTrimmedAt: "2017-01-02T03:04:05Z",
+				TrimmedBytes:  "4096",
+			},
+			"persistent": SpecificDiskTrimVitals{
+				LastTrimmedAt: "2017-01-02T03:04:05Z",
+				TrimmedBytes:  "0",
+				Error:         "fake-trim-err",
+			},
+		}))
+	})
+
+	It("includes disk and network throughput when available", func() {
+		statsCollector, service := buildVitalsService()
+		statsCollector.DiskIOStats = map[string]boshstats.DiskIOStats{
+			"sda": boshstats.DiskIOStats{
+				ReadsPerSecond:      12.5,
+				WritesPerSecond:     3,
+				ReadBytesPerSecond:  4096,
+				WriteBytesPerSecond: 1024.4,
+				BusyPercent:         42.25,
+			},
+		}
+		statsCollector.NetworkStats = map[string]boshstats.NetworkStats{
+			"eth0": boshstats.NetworkStats{
+				RxBytesPerSecond:   2048,
+				RxPacketsPerSecond: 10,
+				RxErrors:           1,
+				RxDropped:          2,
+				TxBytesPerSecond:   512.6,
+				TxPacketsPerSecond: 5.25,
+				TxErrors:           3,
+				TxDropped:          4,
+			},
+		}
+
+		vitals, err := service.Get()
+		Expect(err).ToNot(HaveOccurred())
+
+		Expect(vitals.DiskIO).To(Equal(DiskIOVitals{
+			"sda": SpecificDiskIOVitals{
+				ReadOpsPerSec:    "12.5",
+				WriteOpsPerSec:   "3.0",
+				ReadBytesPerSec:  "4096",
+				WriteBytesPerSec: "1024",
+				BusyPercent:      "42.2",
+			},
+		}))
+		Expect(vitals.Network).To(Equal(NetworkVitals{
+			"eth0": SpecificNetworkVitals{
+				RxBytesPerSec:   "2048",
+				RxPacketsPerSec: "10.0",
+				RxErrors:        "1",
+				RxDropped:       "2",
+				TxBytesPerSec:   "513",
+				TxPacketsPerSec: "5.2",
+				TxErrors:        "3",
+				TxDropped:       "4",
+			},
+		}))
+	})
+
+	It("omits disk and network throughput when it has not been sampled yet", func() {
+		statsCollector, service := buildVitalsService()
+		statsCollector.DiskIOStatsErr = errors.New("fake-disk-io-err")
+		statsCollector.NetworkStatsErr = errors.New("fake-network-err")
+
+		vitals, err := service.Get()
+		Expect(err).ToNot(HaveOccurred())
+
+		boshassert.LacksJSONKey(GinkgoT(), vitals, "disk_io")
+		boshassert.LacksJSONKey(GinkgoT(), vitals, "network")
+	})
+
 	It("get getting vitals on system disk error", func() {
 
 		statsCollector, service := buildVitalsService()
@@ -1,12 +1,15 @@
 package vitals
 
 type Vitals struct {
-	CPU    CPUVitals    `json:"cpu"`
-	Disk   DiskVitals   `json:"disk,omitempty"`
-	Load   []string     `json:"load,omitempty"`
-	Mem    MemoryVitals `json:"mem"`
-	Swap   MemoryVitals `json:"swap"`
-	Uptime UptimeVitals `json:"uptime"`
+	CPU      CPUVitals      `json:"cpu"`
+	Disk     DiskVitals     `json:"disk,omitempty"`
+	DiskIO   DiskIOVitals   `json:"disk_io,omitempty"`
+	DiskTrim DiskTrimVitals `json:"disk_trim,omitempty"`
+	Load     []string       `json:"load,omitempty"`
+	Mem      MemoryVitals   `json:"mem"`
+	Network  NetworkVitals  `json:"network,omitempty"`
+	Swap     MemoryVitals   `json:"swap"`
+	Uptime   UptimeVitals   `json:"uptime"`
 }
 
 type CPUVitals struct {
@@ -22,6 +25,40 @@ type SpecificDiskVitals struct {
 	Percent      string `json:"percent,omitempty"`
 }
 
+// DiskIOVitals are keyed by block device name, e.g. sda
+type DiskIOVitals map[string]SpecificDiskIOVitals
+
+type SpecificDiskIOVitals struct {
+	ReadOpsPerSec    string `json:"read_ops_per_sec"`
+	WriteOpsPerSec   string `json:"write_ops_per_sec"`
+	ReadBytesPerSec  string `json:"read_bytes_per_sec"`
+	WriteBytesPerSec string `json:"write_bytes_per_sec"`
+	BusyPercent      string `json:"busy_percent"`
+}
+
+// DiskTrimVitals are keyed by disk name, e.g. ephemeral
+type DiskTrimVitals map[string]SpecificDiskTrimVitals
+
+type SpecificDiskTrimVitals struct {
+	LastTrimmedAt string `json:"last_trimmed_at"`
+	TrimmedBytes  string `json:"trimmed_bytes"`
+	Error         string `json:"error,omitempty"`
+}
+
+// NetworkVitals are keyed by network interface name, e.g. eth0
+type NetworkVitals map[string]SpecificNetworkVitals
+
+type SpecificNetworkVitals struct {
+	RxBytesPerSec   string `json:"rx_bytes_per_sec"`
+	RxPacketsPerSec string `json:"rx_packets_per_sec"`
+	RxErrors        string `json:"rx_errors"`
+	RxDropped       string `json:"rx_dropped"`
+	TxBytesPerSec   string `json:"tx_bytes_per_sec"`
+	TxPacketsPerSec string `json:"tx_packets_per_sec"`
+	TxErrors        string `json:"tx_errors"`
+	TxDropped       string `json:"tx_dropped"`
+}
+
 type MemoryVitals struct {
 	Kb      string `json:"kb,omitempty"`
 	Percent string `json:"percent,omitempty"`
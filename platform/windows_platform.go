@@ -12,7 +12,9 @@ import (
 
 	boshdpresolv "github.com/cloudfoundry/bosh-agent/infrastructure/devicepathresolver"
 	boshcert "github.com/cloudfoundry/bosh-agent/platform/cert"
+	boshdisk "github.com/cloudfoundry/bosh-agent/platform/disk"
 	boshnet "github.com/cloudfoundry/bosh-agent/platform/net"
+	boship "github.com/cloudfoundry/bosh-agent/platform/net/ip"
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	boshvitals "github.com/cloudfoundry/bosh-agent/platform/vitals"
 	boshsettings "github.com/cloudfoundry/bosh-agent/settings"
@@ -67,7 +69,7 @@ func NewWindowsPlatform(
 		dirProvider:            dirProvider,
 		netManager:             netManager,
 		devicePathResolver:     devicePathResolver,
-		vitalsService:          boshvitals.NewService(collector, dirProvider),
+		vitalsService:          boshvitals.NewService(collector, dirProvider, boshsettings.NewManagedDisks(fs, dirProvider.BoshDir()), nil, logger),
 		certManager:            certManager,
 		defaultNetworkResolver: defaultNetworkResolver,
 		auditLogger:            auditLogger,
@@ -103,6 +105,14 @@ func (p WindowsPlatform) GetVitalsService() (service boshvitals.Service) {
 	return p.vitalsService
 }
 
+func (p WindowsPlatform) GetManagedDisks() boshsettings.ManagedDisks {
+	return boshsettings.NewManagedDisks(p.fs, p.dirProvider.BoshDir())
+}
+
+func (p WindowsPlatform) GetFilesystemCheckHistory() boshdisk.FilesystemCheckHistory {
+	return boshdisk.NewFilesystemCheckHistory(p.fs, p.dirProvider.BoshDir())
+}
+
 func (p WindowsPlatform) GetDevicePathResolver() (devicePathResolver boshdpresolv.DevicePathResolver) {
 	return p.devicePathResolver
 }
@@ -272,6 +282,14 @@ func (p WindowsPlatform) SetupNetworking(networks boshsettings.Networks) (err er
 	return p.netManager.SetupNetworking(networks, nil)
 }
 
+func (p WindowsPlatform) DetectNetworkDrift(networks boshsettings.Networks, interfaceAddressesValidator boship.InterfaceAddressesValidator) (boshnet.Drift, error) {
+	return boshnet.Drift{}, nil
+}
+
+func (p WindowsPlatform) RepairNetworking(networks boshsettings.Networks) error {
+	return bosherr.Error("Repairing networking is not supported on windows")
+}
+
 func (p WindowsPlatform) GetConfiguredNetworkInterfaces() (interfaces []string, err error) {
 	return p.netManager.GetConfiguredNetworkInterfaces()
 }
@@ -325,7 +343,7 @@ func (p WindowsPlatform) SetTimeWithNtpServers(servers []string) (err error) {
 	return
 }
 
-func (p WindowsPlatform) SetupEphemeralDiskWithPath(devicePath string, desiredSwapSizeInBytes *uint64) (err error) {
+func (p WindowsPlatform) SetupEphemeralDiskWithPath(devicePath string, desiredSwapSizeInBytes *uint64, mountOptions ...string) (err error) {
 	return
 }
 
@@ -333,6 +351,14 @@ func (p WindowsPlatform) SetupRawEphemeralDisks(devices []boshsettings.DiskSetti
 	return
 }
 
+func (p WindowsPlatform) SetupPooledEphemeralDisk(ephemeralDiskPath string, rawDevices []boshsettings.DiskSettings, pool boshsettings.EphemeralDiskPool, mountOptions ...string) (err error) {
+	return
+}
+
+func (p WindowsPlatform) SetupSwap(swap boshsettings.Swap) (err error) {
+	return
+}
+
 func (p WindowsPlatform) SetupDataDir() error {
 	dataDir := p.dirProvider.DataDir()
 	sysDataDir := filepath.Join(dataDir, "sys")
@@ -414,7 +440,19 @@ func (p WindowsPlatform) GetFilesContentsFromDisk(diskPath string, fileNames []s
 	return
 }
 
-func (p WindowsPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string) (err error) {
+func (p WindowsPlatform) MigratePersistentDisk(fromMountPoint, toMountPoint string, progress func(copiedBytes, totalBytes uint64)) (err error) {
+	return
+}
+
+func (p WindowsPlatform) ResizePersistentDisk(diskSettings boshsettings.DiskSettings, mountPoint string) (err error) {
+	return
+}
+
+func (p WindowsPlatform) FreezePersistentDisk(mountPoint string) (err error) {
+	return
+}
+
+func (p WindowsPlatform) ThawPersistentDisk(mountPoint string) (err error) {
 	return
 }
 
@@ -506,3 +544,5 @@ func (p WindowsPlatform) DeleteARPEntryWithIP(ip string) error {
 func (p WindowsPlatform) SetupRecordsJSONPermission(path string) error {
 	return nil
 }
+
+func (p WindowsPlatform) StartDiskTrimming(stopCh chan struct{}) {}
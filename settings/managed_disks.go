@@ -0,0 +1,156 @@
+package settings
+
+import (
+	"encoding/json"
+	"path/filepath"
+
+	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
+)
+
+const managedDisksFileName = "managed_disks.json"
+
+// ManagedDisk is a persistent disk mounted by the agent at its own mount point
+// in addition to the disk mounted at store directory
+type ManagedDisk struct {
+	DiskCID    string `json:"disk_cid"`
+	Name       string `json:"name"`
+	MountPoint string `json:"mount_point"`
+
+	// Used to mount disk back after disk at store directory was swapped underneath it
+	MountOptions []string `json:"mount_options,omitempty"`
+}
+
+// ManagedDisks keeps track of named persistent disks so that
+// they are mounted at the same place after reboot.
+// Disk mounted at store directory is still tracked in managed_disk_settings.json
+type ManagedDisks struct {
+	fs   boshsys.FileSystem
+	path string
+}
+
+func NewManagedDisks(fs boshsys.FileSystem, boshDir string) ManagedDisks {
+	return ManagedDisks{
+		fs:   fs,
+		path: filepath.Join(boshDir, managedDisksFileName),
+	}
+}
+
+func (d ManagedDisks) List() ([]ManagedDisk, error) {
+	var disks []ManagedDisk
+
+	if !d.fs.FileExists(d.path) {
+		return disks, nil
+	}
+
+	contents, err := d.fs.ReadFile(d.path)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Reading %s", managedDisksFileName)
+	}
+
+	err = json.Unmarshal(contents, &disks)
+	if err != nil {
+		return nil, bosherr.WrapErrorf(err, "Unmarshalling %s", managedDisksFileName)
+	}
+
+	return disks, nil
+}
+
+func (d ManagedDisks) Find(diskCID string) (ManagedDisk, bool, error) {
+	disks, err := d.List()
+	if err != nil {
+		return ManagedDisk{}, false, err
+	}
+
+	for _, disk := range disks {
+		if disk.DiskCID == diskCID {
+			return disk, true, nil
+		}
+	}
+
+	return ManagedDisk{}, false, nil
+}
+
+// FindByName finds disk by the name it was mounted with
+func (d ManagedDisks) FindByName(name string) (ManagedDisk, bool, error) {
+	disks, err := d.List()
+	if err != nil {
+		return ManagedDisk{}, false, err
+	}
+
+	for _, disk := range disks {
+		if disk.Name == name {
+			return disk, true, nil
+		}
+	}
+
+	return ManagedDisk{}, false, nil
+}
+
+func (d ManagedDisks) MountPoints() ([]string, error) {
+	disks, err := d.List()
+	if err != nil {
+		return nil, err
+	}
+
+	var mountPoints []string
+
+	for _, disk := range disks {
+		mountPoints = append(mountPoints, disk.MountPoint)
+	}
+
+	return mountPoints, nil
+}
+
+// Add replaces previously tracked disk with the same disk CID
+func (d ManagedDisks) Add(disk ManagedDisk) error {
+	disks, err := d.List()
+	if err != nil {
+		return err
+	}
+
+	updatedDisks := []ManagedDisk{}
+
+	for _, existingDisk := range disks {
+		if existingDisk.DiskCID != disk.DiskCID {
+			updatedDisks = append(updatedDisks, existingDisk)
+		}
+	}
+
+	return d.save(append(updatedDisks, disk))
+}
+
+func (d ManagedDisks) Remove(diskCID string) error {
+	disks, err := d.List()
+	if err != nil {
+		return err
+	}
+
+	updatedDisks := []ManagedDisk{}
+
+	for _, existingDisk := range disks {
+		if existingDisk.DiskCID != diskCID {
+			updatedDisks = append(updatedDisks, existingDisk)
+		}
+	}
+
+	if len(updatedDisks) == len(disks) {
+		return nil
+	}
+
+	return d.save(updatedDisks)
+}
+
+func (d ManagedDisks) save(disks []ManagedDisk) error {
+	contents, err := json.Marshal(disks)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Marshalling %s", managedDisksFileName)
+	}
+
+	err = d.fs.WriteFile(d.path, contents)
+	if err != nil {
+		return bosherr.WrapErrorf(err, "Writing %s", managedDisksFileName)
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package settings_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	. "github.com/cloudfoundry/bosh-agent/settings"
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
+)
+
+var _ = Describe("ManagedDisks", func() {
+	var (
+		fs           *fakesys.FakeFileSystem
+		managedDisks ManagedDisks
+	)
+
+	BeforeEach(func() {
+		fs = fakesys.NewFakeFileSystem()
+		managedDisks = NewManagedDisks(fs, "/fake-bosh")
+	})
+
+	It("returns no disks when nothing was tracked yet", func() {
+		disks, err := managedDisks.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(disks).To(BeEmpty())
+	})
+
+	It("adds, finds and removes disks", func() {
+		err := managedDisks.Add(ManagedDisk{DiskCID: "fake-cid-1", Name: "wal", MountPoint: "/store/wal"})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = managedDisks.Add(ManagedDisk{DiskCID: "fake-cid-2", Name: "data", MountPoint: "/store/data"})
+		Expect(err).ToNot(HaveOccurred())
+
+		contents, err := fs.ReadFileString("/fake-bosh/managed_disks.json")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(contents).To(MatchJSON(`[
+			{"disk_cid":"fake-cid-1","name":"wal","mount_point":"/store/wal"},
+			{"disk_cid":"fake-cid-2","name":"data","mount_point":"/store/data"}
+		]`))
+
+		disk, found, err := managedDisks.Find("fake-cid-2")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(disk).To(Equal(ManagedDisk{DiskCID: "fake-cid-2", Name: "data", MountPoint: "/store/data"}))
+
+		disk, found, err = managedDisks.FindByName("wal")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeTrue())
+		Expect(disk).To(Equal(ManagedDisk{DiskCID: "fake-cid-1", Name: "wal", MountPoint: "/store/wal"}))
+
+		_, found, err = managedDisks.FindByName("fake-cid-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+
+		err = managedDisks.Remove("fake-cid-1")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, found, err = managedDisks.Find("fake-cid-1")
+		Expect(err).ToNot(HaveOccurred())
+		Expect(found).To(BeFalse())
+
+		disks, err := managedDisks.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(disks).To(Equal([]ManagedDisk{{DiskCID: "fake-cid-2", Name: "data", MountPoint: "/store/data"}}))
+	})
+
+	It("lists mount points of disks", func() {
+		err := managedDisks.Add(ManagedDisk{DiskCID: "fake-cid-1", Name: "wal", MountPoint: "/store/wal"})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = managedDisks.Add(ManagedDisk{DiskCID: "fake-cid-2", Name: "data", MountPoint: "/store/data"})
+		Expect(err).ToNot(HaveOccurred())
+
+		mountPoints, err := managedDisks.MountPoints()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(mountPoints).To(Equal([]string{"/store/wal", "/store/data"}))
+	})
+
+	It("replaces disk with the same disk CID", func() {
+		err := managedDisks.Add(ManagedDisk{DiskCID: "fake-cid", Name: "wal", MountPoint: "/store/wal"})
+		Expect(err).ToNot(HaveOccurred())
+
+		err = managedDisks.Add(ManagedDisk{DiskCID: "fake-cid", Name: "data", MountPoint: "/store/data"})
+		Expect(err).ToNot(HaveOccurred())
+
+		disks, err := managedDisks.List()
+		Expect(err).ToNot(HaveOccurred())
+		Expect(disks).To(Equal([]ManagedDisk{{DiskCID: "fake-cid", Name: "data", MountPoint: "/store/data"}}))
+	})
+
+	It("returns error when writing fails", func() {
+		fs.WriteFileError = errors.New("fake-write-err")
+
+		err := managedDisks.Add(ManagedDisk{DiskCID: "fake-cid"})
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Writing managed_disks.json"))
+	})
+
+	It("returns error when file cannot be parsed", func() {
+		err := fs.WriteFileString("/fake-bosh/managed_disks.json", "bad-json")
+		Expect(err).ToNot(HaveOccurred())
+
+		_, err = managedDisks.List()
+		Expect(err).To(HaveOccurred())
+		Expect(err.Error()).To(ContainSubstring("Unmarshalling managed_disks.json"))
+	})
+})
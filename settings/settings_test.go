@@ -2,6 +2,7 @@ package settings_test
 
 import (
 	"encoding/json"
+	"fmt"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/ginkgo/extensions/table"
@@ -75,6 +76,32 @@ var _ = Describe("Settings", func() {
 					}))
 				})
 
+				It("gets persistent disk formatting options from env", func() {
+					settingsJSON := `{"env": {"persistent_disk_fs": "btrfs", "persistent_disk_mkfs_options": ["-K"], "persistent_disk_label": "fake-label"}}`
+
+					err := json.Unmarshal([]byte(settingsJSON), &settings)
+					Expect(err).NotTo(HaveOccurred())
+					diskSettings, _ := settings.PersistentDiskSettings("fake-disk-id")
+					Expect(diskSettings.FileSystemType).To(Equal(disk.FileSystemBtrfs))
+					Expect(diskSettings.MkfsOptions).To(Equal([]string{"-K"}))
+					Expect(diskSettings.FileSystemLabel).To(Equal("fake-label"))
+				})
+
+				It("gets persistent disk encryption from env", func() {
+					settingsJSON := `{"env": {"persistent_disk_encryption": {"type": "luks", "key": "fake-secret", "key_path": "/fake-key-path"}}}`
+
+					err := json.Unmarshal([]byte(settingsJSON), &settings)
+					Expect(err).NotTo(HaveOccurred())
+					diskSettings, _ := settings.PersistentDiskSettings("fake-disk-id")
+					Expect(diskSettings.Encryption).To(Equal(DiskEncryption{
+						Type:    DiskEncryptionLUKS,
+						Key:     "fake-secret",
+						KeyPath: "/fake-key-path",
+					}))
+					Expect(diskSettings.Encryption.IsEnabled()).To(BeTrue())
+					Expect(fmt.Sprintf("%+v", diskSettings)).ToNot(ContainSubstring("fake-secret"))
+				})
+
 				It("does not crash if env does not have a filesystem type", func() {
 					settingsJSON := `{"env": {"bosh": {"password": "secret"}}}`
 
@@ -316,6 +343,23 @@ var _ = Describe("Settings", func() {
 				}))
 			})
 		})
+
+		Context("when mount options are specified in env", func() {
+			BeforeEach(func() {
+				settings = Settings{
+					Disks: Disks{
+						Ephemeral: "fake-disk-value",
+					},
+					Env: Env{
+						EphemeralDiskMountOptions: []string{"discard"},
+					},
+				}
+			})
+
+			It("sets mount options", func() {
+				Expect(settings.EphemeralDiskSettings().MountOptions).To(Equal([]string{"discard"}))
+			})
+		})
 	})
 
 	Describe("DefaultNetworkFor", func() {
@@ -570,6 +614,57 @@ var _ = Describe("Settings", func() {
 				})
 			})
 		})
+
+		It("unmarshals static routes, mtu and routing table", func() {
+			networkJSON := `{
+				"ip": "10.0.0.5",
+				"netmask": "255.255.255.0",
+				"routes": [{"destination": "10.10.0.0", "netmask": "255.255.0.0", "gateway": "10.0.0.1"}],
+				"mtu": 9000,
+				"routing_table": 100
+			}`
+
+			err := json.Unmarshal([]byte(networkJSON), &network)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(network.Routes).To(Equal(Routes{{Destination: "10.10.0.0", Netmask: "255.255.0.0", Gateway: "10.0.0.1"}}))
+			Expect(network.MTU).To(Equal(9000))
+			Expect(network.RoutingTable).To(Equal(100))
+		})
+
+		It("unmarshals virtual interface", func() {
+			networkJSON := `{
+				"ip": "10.0.0.5",
+				"netmask": "255.255.255.0",
+				"virtual_interface": {
+					"type": "bond",
+					"name": "bond0",
+					"members": ["aa:bb:cc:dd:ee:01", "aa:bb:cc:dd:ee:02"],
+					"bond_mode": "802.3ad"
+				}
+			}`
+
+			err := json.Unmarshal([]byte(networkJSON), &network)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(network.IsVirtual()).To(BeTrue())
+			Expect(network.VirtualInterface).To(Equal(VirtualInterface{
+				Type:     VirtualInterfaceTypeBond,
+				Name:     "bond0",
+				Members:  []string{"aa:bb:cc:dd:ee:01", "aa:bb:cc:dd:ee:02"},
+				BondMode: "802.3ad",
+			}))
+		})
+
+		It("unmarshals secondary addresses", func() {
+			networkJSON := `{
+				"ip": "10.0.0.5",
+				"netmask": "255.255.255.0",
+				"secondary_addresses": [{"ip": "2001:db8::5", "netmask": "ffff:ffff:ffff:ffff::"}]
+			}`
+
+			err := json.Unmarshal([]byte(networkJSON), &network)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(network.SecondaryAddresses).To(Equal([]Address{{IP: "2001:db8::5", Netmask: "ffff:ffff:ffff:ffff::"}}))
+		})
 	})
 
 	Describe("Networks", func() {
@@ -637,6 +732,35 @@ var _ = Describe("Settings", func() {
 				})
 			})
 		})
+
+		Describe("NetworksForMac", func() {
+			It("returns all networks configured on the interface", func() {
+				networks = Networks{
+					"ipv4":  Network{IP: "10.0.0.5", Mac: "aa:bb"},
+					"ipv6":  Network{IP: "2001:db8::5", Mac: "aa:bb"},
+					"other": Network{IP: "10.0.1.5", Mac: "cc:dd"},
+				}
+
+				Expect(networks.NetworksForMac("aa:bb")).To(Equal(Networks{
+					"ipv4": networks["ipv4"],
+					"ipv6": networks["ipv6"],
+				}))
+				Expect(networks.NetworksForMac("ee:ff")).To(BeEmpty())
+			})
+		})
+
+		Describe("IPs", func() {
+			It("includes secondary addresses", func() {
+				networks = Networks{
+					"first": Network{
+						IP:                 "10.0.0.5",
+						SecondaryAddresses: []Address{{IP: "10.0.0.6", Netmask: "255.255.255.0"}},
+					},
+				}
+
+				Expect(networks.IPs()).To(ConsistOf("10.0.0.5", "10.0.0.6"))
+			})
+		})
 	})
 
 	Describe("Env", func() {
@@ -758,6 +882,93 @@ var _ = Describe("Settings", func() {
 			})
 		})
 
+		It("unmarshals ephemeral disk pool", func() {
+			var env Env
+			err := json.Unmarshal([]byte(`{"ephemeral_disk_pool": {"enabled": true, "striped": true, "stripe_size_kb": 256}}`), &env)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(env.EphemeralDiskPool).To(Equal(EphemeralDiskPool{Enabled: true, Striped: true, StripeSizeInKB: 256}))
+		})
+
+		Describe("GetSwap", func() {
+			It("returns swap type and size from env", func() {
+				var env Env
+				err := json.Unmarshal([]byte(`{"bosh": {"swap_size": 1024, "swap_type": "zram"}}`), &env)
+				Expect(err).NotTo(HaveOccurred())
+
+				swap := env.GetSwap()
+				Expect(swap.Type).To(Equal(disk.SwapTypeZram))
+				Expect(*swap.GetSizeInBytes()).To(Equal(uint64(1024 * 1024 * 1024)))
+			})
+
+			It("defaults to swap partition sized after memory", func() {
+				swap := Env{}.GetSwap()
+				Expect(swap.Type).To(Equal(disk.SwapTypePartition))
+				Expect(swap.GetSizeInBytes()).To(BeNil())
+			})
+		})
+
+		Describe("ResolveSwap", func() {
+			var (
+				env        Env
+				envSize    uint64
+				updateSize uint64
+			)
+
+			BeforeEach(func() {
+				envSize = 1024
+				updateSize = 512
+				env = Env{Bosh: BoshEnv{SwapSizeInMB: &envSize, SwapType: disk.SwapTypeFile}}
+			})
+
+			It("returns swap from env when it was not updated", func() {
+				Expect(env.ResolveSwap(nil)).To(Equal(Swap{Type: disk.SwapTypeFile, SizeInMB: &envSize}))
+			})
+
+			It("returns updated swap", func() {
+				swap := env.ResolveSwap(&Swap{Type: disk.SwapTypeZram, SizeInMB: &updateSize})
+				Expect(swap).To(Equal(Swap{Type: disk.SwapTypeZram, SizeInMB: &updateSize}))
+			})
+
+			It("takes swap type from env when updated swap does not have one", func() {
+				var update Swap
+				err := json.Unmarshal([]byte(`{"size": 512}`), &update)
+				Expect(err).NotTo(HaveOccurred())
+
+				swap := env.ResolveSwap(&update)
+				Expect(swap.Type).To(Equal(disk.SwapTypeFile))
+				Expect(*swap.SizeInMB).To(Equal(uint64(512)))
+			})
+
+			It("takes swap size from env when updated swap does not have one", func() {
+				swap := env.ResolveSwap(&Swap{Type: disk.SwapTypeZram})
+				Expect(swap).To(Equal(Swap{Type: disk.SwapTypeZram, SizeInMB: &envSize}))
+			})
+
+			It("defaults to swap partition when neither env nor updated swap have a type", func() {
+				swap := Env{}.ResolveSwap(&Swap{SizeInMB: &updateSize})
+				Expect(swap).To(Equal(Swap{Type: disk.SwapTypePartition, SizeInMB: &updateSize}))
+			})
+
+			Context("when ephemeral disks are pooled", func() {
+				BeforeEach(func() {
+					env.EphemeralDiskPool = EphemeralDiskPool{Enabled: true}
+				})
+
+				It("falls back to swap file instead of swap partition", func() {
+					env.Bosh.SwapType = ""
+					Expect(env.ResolveSwap(nil)).To(Equal(Swap{Type: disk.SwapTypeFile, SizeInMB: &envSize}))
+
+					swap := env.ResolveSwap(&Swap{Type: disk.SwapTypePartition})
+					Expect(swap.Type).To(Equal(disk.SwapTypeFile))
+				})
+
+				It("keeps zram swap", func() {
+					swap := env.ResolveSwap(&Swap{Type: disk.SwapTypeZram})
+					Expect(swap.Type).To(Equal(disk.SwapTypeZram))
+				})
+			})
+		})
+
 		Context("when parallel is not specified in the json", func() {
 			It("sets to the default value", func() {
 				var env Env
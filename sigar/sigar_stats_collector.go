@@ -1,6 +1,7 @@
 package sigar
 
 import (
+	"runtime"
 	"sync"
 	"time"
 
@@ -8,23 +9,33 @@ import (
 
 	boshstats "github.com/cloudfoundry/bosh-agent/platform/stats"
 	bosherr "github.com/cloudfoundry/bosh-utils/errors"
+	boshsys "github.com/cloudfoundry/bosh-utils/system"
 )
 
 type sigarStatsCollector struct {
 	statsSigar         sigar.Sigar
 	latestCPUStats     boshstats.CPUStats
 	latestCPUStatsLock sync.RWMutex
+
+	// Sigar does not provide disk and network throughput
+	ioSampler *boshstats.ProcIOSampler
 }
 
-func NewSigarStatsCollector(sigar sigar.Sigar) boshstats.Collector {
+func NewSigarStatsCollector(sigar sigar.Sigar, fs boshsys.FileSystem) boshstats.Collector {
 	return &sigarStatsCollector{
 		statsSigar: sigar,
+		ioSampler:  boshstats.NewProcIOSampler(fs),
 	}
 }
 
 func (s *sigarStatsCollector) StartCollecting(collectionInterval time.Duration, latestGotUpdated chan struct{}) {
 	cpuSamplesCh, _ := s.statsSigar.CollectCpuStats(collectionInterval)
 
+	// Throughput is sampled from /proc which only exists on Linux
+	if runtime.GOOS == "linux" {
+		go s.collectIOStats(collectionInterval)
+	}
+
 	go func() {
 		for cpuSample := range cpuSamplesCh {
 			s.latestCPUStatsLock.Lock()
@@ -42,6 +53,19 @@ func (s *sigarStatsCollector) StartCollecting(collectionInterval time.Duration,
 	}()
 }
 
+func (s *sigarStatsCollector) collectIOStats(collectionInterval time.Duration) {
+	ticker := time.NewTicker(collectionInterval)
+	defer ticker.Stop()
+
+	// Errors are ignored since throughput is simply
+	// not reported when /proc cannot be read
+	_ = s.ioSampler.Sample(time.Now())
+
+	for sampledAt := range ticker.C {
+		_ = s.ioSampler.Sample(sampledAt)
+	}
+}
+
 func (s *sigarStatsCollector) GetCPULoad() (load boshstats.CPULoad, err error) {
 	l, err := s.statsSigar.GetLoadAverage()
 	if err != nil {
@@ -122,3 +146,11 @@ func (s *sigarStatsCollector) GetUptimeStats() (stats boshstats.UptimeStats, err
 	stats.Secs = uint64(uptime.Length)
 	return
 }
+
+func (s *sigarStatsCollector) GetDiskIOStats() (map[string]boshstats.DiskIOStats, error) {
+	return s.ioSampler.DiskIOStats()
+}
+
+func (s *sigarStatsCollector) GetNetworkStats() (map[string]boshstats.NetworkStats, error) {
+	return s.ioSampler.NetworkStats()
+}
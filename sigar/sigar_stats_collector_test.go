@@ -10,17 +10,21 @@ import (
 	boshsigar "github.com/cloudfoundry/bosh-agent/sigar"
 	sigar "github.com/cloudfoundry/gosigar"
 	fakesigar "github.com/cloudfoundry/gosigar/fakes"
+
+	fakesys "github.com/cloudfoundry/bosh-utils/system/fakes"
 )
 
 var _ = Describe("sigarStatsCollector", func() {
 	var (
 		collector Collector
 		fakeSigar *fakesigar.FakeSigar
+		fs        *fakesys.FakeFileSystem
 	)
 
 	BeforeEach(func() {
 		fakeSigar = fakesigar.NewFakeSigar()
-		collector = boshsigar.NewSigarStatsCollector(fakeSigar)
+		fs = fakesys.NewFakeFileSystem()
+		collector = boshsigar.NewSigarStatsCollector(fakeSigar, fs)
 	})
 
 	Describe("GetCPULoad", func() {
@@ -135,4 +139,40 @@ var _ = Describe("sigarStatsCollector", func() {
 			Expect(stats.InodeUsage.Used).To(Equal(uint64(400)))
 		})
 	})
+
+	Describe("GetDiskIOStats and GetNetworkStats", func() {
+		It("returns errors before collection has started", func() {
+			_, err := collector.GetDiskIOStats()
+			Expect(err).To(HaveOccurred())
+
+			_, err = collector.GetNetworkStats()
+			Expect(err).To(HaveOccurred())
+		})
+
+		It("returns throughput sampled from /proc after collection has started", func() {
+			err := fs.MkdirAll("/sys/block/sda", 0755)
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString("/proc/diskstats", "   8       0 sda 1 0 2 0 3 0 4 0 0 5 6\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			err = fs.WriteFileString("/proc/net/dev", "  eth0: 1 2 0 0 0 0 0 0 3 4 0 0 0 0 0 0\n")
+			Expect(err).ToNot(HaveOccurred())
+
+			collector.StartCollecting(10*time.Millisecond, nil)
+
+			Eventually(func() error {
+				_, err := collector.GetDiskIOStats()
+				return err
+			}).ShouldNot(HaveOccurred())
+
+			diskIOStats, err := collector.GetDiskIOStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(diskIOStats).To(HaveKey("sda"))
+
+			networkStats, err := collector.GetNetworkStats()
+			Expect(err).ToNot(HaveOccurred())
+			Expect(networkStats).To(HaveKey("eth0"))
+		})
+	})
 })